@@ -19,18 +19,28 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 
 	_ "github.com/ddteam/drink-master/docs/swagger" // swagger docs
 	"github.com/ddteam/drink-master/internal/config"
+	"github.com/ddteam/drink-master/internal/middleware"
 	"github.com/ddteam/drink-master/internal/models"
 	"github.com/ddteam/drink-master/internal/routes"
 )
 
+// 优雅退出时等待处理中请求完成的最长时间
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	// 加载环境变量
 	if err := godotenv.Load(); err != nil {
@@ -42,6 +52,13 @@ func main() {
 		gin.SetMode(gin.DebugMode)
 	}
 
+	// 加载JWT密钥，release模式下使用默认密钥时拒绝启动
+	jwtKeys, err := config.NewJWTConfig().NewKeyManager(gin.Mode() == gin.ReleaseMode)
+	if err != nil {
+		log.Fatal("Failed to load JWT keys:", err)
+	}
+	middleware.SetJWTKeyManager(jwtKeys)
+
 	dbConfig := config.LoadDatabaseConfig()
 	db, err := config.NewDatabase(dbConfig)
 	if err != nil {
@@ -54,14 +71,41 @@ func main() {
 	}
 
 	// 设置路由
-	router := routes.SetupRoutes(db)
+	app, err := routes.NewApp(db)
+	if err != nil {
+		log.Fatal("Failed to setup routes:", err)
+	}
 
 	// 获取端口配置
 	port := getEnvOrDefault("PORT", "8080")
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           app.Router,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// 后台任务随服务启动，收到退出信号后先停止接收请求再停止后台任务
+	app.Start()
+	go func() {
+		log.Printf("Server starting on port %s", port)
+		log.Printf("Database connected: %s", dbConfig.DSN())
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Server failed:", err)
+		}
+	}()
 
-	log.Printf("Server starting on port %s", port)
-	log.Printf("Database connected: %s", dbConfig.DSN())
-	log.Fatal(router.Run(":" + port))
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down server...")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println("Server forced to shutdown:", err)
+	}
+	app.Stop()
+	log.Println("Server exited")
 }
 
 // getEnvOrDefault 获取环境变量或默认值
@@ -0,0 +1,27 @@
+package config
+
+import "os"
+
+// FuiouConfig represents Fuiou payment channel configuration (institution level)
+// 商户号、密钥与订单前缀按机主配置，不在此处
+type FuiouConfig struct {
+	BaseURL  string
+	InsCd    string
+	TermID   string
+	SubAppID string
+}
+
+// NewFuiouConfig creates Fuiou configuration from environment variables
+func NewFuiouConfig() *FuiouConfig {
+	return &FuiouConfig{
+		BaseURL:  os.Getenv("FUIOU_BASE_URL"),
+		InsCd:    os.Getenv("FUIOU_INS_CD"),
+		TermID:   getEnv("FUIOU_TERM_ID", "88888888"),
+		SubAppID: os.Getenv("WECHAT_APP_ID"),
+	}
+}
+
+// Enabled reports whether the Fuiou institution number has been configured
+func (c *FuiouConfig) Enabled() bool {
+	return c.InsCd != ""
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestNewFuiouConfig(t *testing.T) {
+	os.Setenv("FUIOU_BASE_URL", "http://localhost:9000")
+	os.Setenv("FUIOU_INS_CD", "08A9999999")
+	os.Setenv("WECHAT_APP_ID", "wx123")
+	defer func() {
+		os.Unsetenv("FUIOU_BASE_URL")
+		os.Unsetenv("FUIOU_INS_CD")
+		os.Unsetenv("WECHAT_APP_ID")
+	}()
+
+	config := NewFuiouConfig()
+
+	if !config.Enabled() {
+		t.Error("expected Fuiou to be enabled")
+	}
+	if config.BaseURL != "http://localhost:9000" {
+		t.Errorf("expected BaseURL 'http://localhost:9000', got '%s'", config.BaseURL)
+	}
+	if config.TermID != "88888888" {
+		t.Errorf("expected default TermID '88888888', got '%s'", config.TermID)
+	}
+	if config.SubAppID != "wx123" {
+		t.Errorf("expected SubAppID 'wx123', got '%s'", config.SubAppID)
+	}
+}
+
+func TestNewFuiouConfig_Disabled(t *testing.T) {
+	os.Unsetenv("FUIOU_INS_CD")
+
+	if NewFuiouConfig().Enabled() {
+		t.Error("expected Fuiou to be disabled without institution number")
+	}
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ddteam/drink-master/pkg/jwtkeys"
+)
+
+// DefaultJWTSecret 开发环境使用的默认JWT密钥，release模式下禁止使用
+// #nosec G101 - This is a fallback for development only
+const DefaultJWTSecret = "default_jwt_secret_change_this_in_production"
+
+// JWT签名算法
+const (
+	JWTAlgorithmHS256 = "HS256"
+	JWTAlgorithmRS256 = "RS256"
+	JWTAlgorithmEdDSA = "EdDSA"
+)
+
+// ErrDefaultJWTSecret release模式下未配置JWT密钥或使用了默认密钥
+var ErrDefaultJWTSecret = errors.New("JWT_SECRET must be set to a non-default value in release mode")
+
+// JWTConfig represents JWT signing key configuration
+type JWTConfig struct {
+	// Algorithm 签名算法：HS256（默认，共享密钥）、RS256 或 EdDSA（私钥签名，公钥通过JWKS发布）
+	Algorithm string
+	// SigningKeyID 当前签名密钥的kid
+	SigningKeyID string
+	// Secret HS256 当前签名密钥
+	Secret string
+	// PreviousSecrets HS256 轮换前的密钥，格式 kid:secret,kid:secret，仅用于验证
+	PreviousSecrets string
+	// PrivateKeyFile RS256/EdDSA 当前签名私钥（PEM）文件
+	PrivateKeyFile string
+	// PreviousPublicKeyFiles RS256/EdDSA 轮换前的公钥，格式 kid:path,kid:path，仅用于验证
+	PreviousPublicKeyFiles string
+}
+
+// NewJWTConfig creates JWT configuration from environment variables
+func NewJWTConfig() *JWTConfig {
+	return &JWTConfig{
+		Algorithm:              getEnv("JWT_ALGORITHM", JWTAlgorithmHS256),
+		SigningKeyID:           getEnv("JWT_SIGNING_KEY_ID", "default"),
+		Secret:                 os.Getenv("JWT_SECRET"),
+		PreviousSecrets:        os.Getenv("JWT_PREVIOUS_SECRETS"),
+		PrivateKeyFile:         os.Getenv("JWT_PRIVATE_KEY_FILE"),
+		PreviousPublicKeyFiles: os.Getenv("JWT_PREVIOUS_PUBLIC_KEY_FILES"),
+	}
+}
+
+// NewKeyManager builds the JWT key manager from the configuration
+// release模式下 HS256 未配置密钥或使用默认密钥时返回 ErrDefaultJWTSecret，服务应拒绝启动
+func (c *JWTConfig) NewKeyManager(release bool) (*jwtkeys.Manager, error) {
+	switch c.Algorithm {
+	case JWTAlgorithmHS256:
+		return c.hmacKeyManager(release)
+	case JWTAlgorithmRS256, JWTAlgorithmEdDSA:
+		return c.asymmetricKeyManager()
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALGORITHM %q", c.Algorithm)
+	}
+}
+
+// hmacKeyManager 使用共享密钥签名，轮换前的密钥仅用于验证
+func (c *JWTConfig) hmacKeyManager(release bool) (*jwtkeys.Manager, error) {
+	secret := c.Secret
+	if secret == "" || secret == DefaultJWTSecret {
+		if release {
+			return nil, ErrDefaultJWTSecret
+		}
+		secret = DefaultJWTSecret
+	}
+
+	signing, err := jwtkeys.NewHMACKey(c.SigningKeyID, []byte(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := parseKeyList(c.PreviousSecrets, "JWT_PREVIOUS_SECRETS")
+	if err != nil {
+		return nil, err
+	}
+	others := make([]*jwtkeys.Key, 0, len(previous))
+	for _, entry := range previous {
+		key, keyErr := jwtkeys.NewHMACKey(entry[0], []byte(entry[1]))
+		if keyErr != nil {
+			return nil, keyErr
+		}
+		others = append(others, key)
+	}
+	return jwtkeys.NewManager(signing, others...)
+}
+
+// asymmetricKeyManager 使用私钥签名，轮换前的公钥仅用于验证
+func (c *JWTConfig) asymmetricKeyManager() (*jwtkeys.Manager, error) {
+	if c.PrivateKeyFile == "" {
+		return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE is required for %s", c.Algorithm)
+	}
+	signing, err := loadPEMKey(c.SigningKeyID, c.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if !signing.CanSign() || signing.Method.Alg() != c.Algorithm {
+		return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE is not a %s private key", c.Algorithm)
+	}
+
+	previous, err := parseKeyList(c.PreviousPublicKeyFiles, "JWT_PREVIOUS_PUBLIC_KEY_FILES")
+	if err != nil {
+		return nil, err
+	}
+	others := make([]*jwtkeys.Key, 0, len(previous))
+	for _, entry := range previous {
+		key, keyErr := loadPEMKey(entry[0], entry[1])
+		if keyErr != nil {
+			return nil, keyErr
+		}
+		others = append(others, key)
+	}
+	return jwtkeys.NewManager(signing, others...)
+}
+
+// loadPEMKey 读取PEM密钥文件
+func loadPEMKey(kid, path string) (*jwtkeys.Key, error) {
+	data, err := os.ReadFile(path) // #nosec G304 - path comes from deployment configuration
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key file %s: %w", path, err)
+	}
+	return jwtkeys.ParsePEMKey(kid, data)
+}
+
+// parseKeyList 解析 kid:value,kid:value 格式的密钥列表
+func parseKeyList(value, name string) ([][2]string, error) {
+	var entries [][2]string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kid, keyValue, found := strings.Cut(item, ":")
+		if !found || kid == "" || keyValue == "" {
+			return nil, fmt.Errorf("%s entries must be kid:value", name)
+		}
+		entries = append(entries, [2]string{kid, keyValue})
+	}
+	return entries, nil
+}
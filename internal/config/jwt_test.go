@@ -0,0 +1,113 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewJWTConfig(t *testing.T) {
+	os.Setenv("JWT_SECRET", "current-secret")
+	os.Setenv("JWT_PREVIOUS_SECRETS", "2024:old-secret")
+	defer os.Unsetenv("JWT_SECRET")
+	defer os.Unsetenv("JWT_PREVIOUS_SECRETS")
+
+	config := NewJWTConfig()
+	if config.Algorithm != JWTAlgorithmHS256 || config.SigningKeyID != "default" {
+		t.Errorf("unexpected defaults %+v", config)
+	}
+
+	manager, err := config.NewKeyManager(true)
+	if err != nil {
+		t.Fatalf("NewKeyManager: %v", err)
+	}
+	if manager.SigningKey().ID != "default" || !manager.SigningKey().IsSymmetric() {
+		t.Errorf("unexpected signing key %+v", manager.SigningKey())
+	}
+}
+
+func TestJWTConfig_DefaultSecret(t *testing.T) {
+	for _, secret := range []string{"", DefaultJWTSecret} {
+		config := &JWTConfig{Algorithm: JWTAlgorithmHS256, SigningKeyID: "default", Secret: secret}
+
+		// release模式拒绝默认密钥
+		if _, err := config.NewKeyManager(true); !errors.Is(err, ErrDefaultJWTSecret) {
+			t.Errorf("secret %q: expected ErrDefaultJWTSecret, got %v", secret, err)
+		}
+		// 开发模式回退到默认密钥
+		if _, err := config.NewKeyManager(false); err != nil {
+			t.Errorf("secret %q: unexpected error %v", secret, err)
+		}
+	}
+}
+
+func TestJWTConfig_InvalidEntries(t *testing.T) {
+	cases := []*JWTConfig{
+		{Algorithm: "HS512", SigningKeyID: "default", Secret: "secret"},
+		{Algorithm: JWTAlgorithmHS256, SigningKeyID: "default", Secret: "secret", PreviousSecrets: "no-kid"},
+		{Algorithm: JWTAlgorithmHS256, SigningKeyID: "default", Secret: "secret", PreviousSecrets: "default:dup"},
+		{Algorithm: JWTAlgorithmRS256, SigningKeyID: "default"},
+		{Algorithm: JWTAlgorithmRS256, SigningKeyID: "default", PrivateKeyFile: "/nonexistent/key.pem"},
+	}
+	for _, config := range cases {
+		if _, err := config.NewKeyManager(false); err == nil {
+			t.Errorf("expected error for %+v", config)
+		}
+	}
+}
+
+func TestJWTConfig_EdDSAKeyFiles(t *testing.T) {
+	dir := t.TempDir()
+	privatePath := writeEd25519Key(t, dir, "current.pem", true)
+	previousPath := writeEd25519Key(t, dir, "previous.pem", false)
+
+	config := &JWTConfig{
+		Algorithm:              JWTAlgorithmEdDSA,
+		SigningKeyID:           "2025",
+		PrivateKeyFile:         privatePath,
+		PreviousPublicKeyFiles: "2024:" + previousPath,
+	}
+	manager, err := config.NewKeyManager(true)
+	if err != nil {
+		t.Fatalf("NewKeyManager: %v", err)
+	}
+	if keys := manager.JWKS().Keys; len(keys) != 2 || keys[0].KeyID != "2025" || keys[1].KeyID != "2024" {
+		t.Errorf("unexpected JWKS %+v", keys)
+	}
+
+	// 私钥类型与算法不一致
+	config.Algorithm = JWTAlgorithmRS256
+	if _, err := config.NewKeyManager(true); err == nil {
+		t.Error("expected algorithm mismatch error")
+	}
+}
+
+func writeEd25519Key(t *testing.T, dir, name string, private bool) string {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	block := &pem.Block{Type: "PUBLIC KEY"}
+	if private {
+		block.Type = "PRIVATE KEY"
+		block.Bytes, err = x509.MarshalPKCS8PrivateKey(privateKey)
+	} else {
+		block.Bytes, err = x509.MarshalPKIXPublicKey(publicKey)
+	}
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// MQTTConfig represents MQTT device communication configuration
+type MQTTConfig struct {
+	Broker           string
+	Username         string
+	Password         string
+	ClientID         string
+	TopicPrefix      string
+	HeartbeatTimeout time.Duration
+}
+
+// NewMQTTConfig creates MQTT configuration from environment variables
+func NewMQTTConfig() *MQTTConfig {
+	timeoutSeconds := 90
+	if value := os.Getenv("MQTT_HEARTBEAT_TIMEOUT_SECONDS"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+			timeoutSeconds = seconds
+		}
+	}
+
+	return &MQTTConfig{
+		Broker:           os.Getenv("MQTT_BROKER"),
+		Username:         os.Getenv("MQTT_USERNAME"),
+		Password:         os.Getenv("MQTT_PASSWORD"),
+		ClientID:         getEnv("MQTT_CLIENT_ID", "drink_master_server"),
+		TopicPrefix:      getEnv("MQTT_TOPIC_PREFIX", "vm"),
+		HeartbeatTimeout: time.Duration(timeoutSeconds) * time.Second,
+	}
+}
+
+// Enabled reports whether an MQTT broker has been configured
+func (c *MQTTConfig) Enabled() bool {
+	return c.Broker != ""
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNewMQTTConfig(t *testing.T) {
+	os.Setenv("MQTT_BROKER", "tcp://broker:1883")
+	os.Setenv("MQTT_CLIENT_ID", "server-1")
+	os.Setenv("MQTT_TOPIC_PREFIX", "devices")
+	os.Setenv("MQTT_HEARTBEAT_TIMEOUT_SECONDS", "30")
+	defer func() {
+		os.Unsetenv("MQTT_BROKER")
+		os.Unsetenv("MQTT_CLIENT_ID")
+		os.Unsetenv("MQTT_TOPIC_PREFIX")
+		os.Unsetenv("MQTT_HEARTBEAT_TIMEOUT_SECONDS")
+	}()
+
+	config := NewMQTTConfig()
+
+	if !config.Enabled() {
+		t.Error("expected MQTT to be enabled")
+	}
+	if config.ClientID != "server-1" {
+		t.Errorf("expected ClientID 'server-1', got '%s'", config.ClientID)
+	}
+	if config.TopicPrefix != "devices" {
+		t.Errorf("expected TopicPrefix 'devices', got '%s'", config.TopicPrefix)
+	}
+	if config.HeartbeatTimeout != 30*time.Second {
+		t.Errorf("expected HeartbeatTimeout 30s, got %v", config.HeartbeatTimeout)
+	}
+}
+
+func TestNewMQTTConfig_DefaultValues(t *testing.T) {
+	os.Unsetenv("MQTT_BROKER")
+	os.Setenv("MQTT_HEARTBEAT_TIMEOUT_SECONDS", "invalid")
+	defer os.Unsetenv("MQTT_HEARTBEAT_TIMEOUT_SECONDS")
+
+	config := NewMQTTConfig()
+
+	if config.Enabled() {
+		t.Error("expected MQTT to be disabled without broker")
+	}
+	if config.TopicPrefix != "vm" {
+		t.Errorf("expected default TopicPrefix 'vm', got '%s'", config.TopicPrefix)
+	}
+	if config.HeartbeatTimeout != 90*time.Second {
+		t.Errorf("expected default HeartbeatTimeout 90s, got %v", config.HeartbeatTimeout)
+	}
+}
@@ -0,0 +1,21 @@
+package config
+
+import "os"
+
+// PaymentConfig represents payment account storage configuration
+type PaymentConfig struct {
+	// SecretKey base64编码的32字节密钥，用于加密存储机主收款密钥
+	SecretKey string
+}
+
+// NewPaymentConfig creates payment configuration from environment variables
+func NewPaymentConfig() *PaymentConfig {
+	return &PaymentConfig{
+		SecretKey: os.Getenv("PAYMENT_SECRET_KEY"),
+	}
+}
+
+// Enabled reports whether the payment secret key has been configured
+func (c *PaymentConfig) Enabled() bool {
+	return c.SecretKey != ""
+}
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestNewPaymentConfig(t *testing.T) {
+	os.Setenv("PAYMENT_SECRET_KEY", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	defer os.Unsetenv("PAYMENT_SECRET_KEY")
+
+	config := NewPaymentConfig()
+	if !config.Enabled() {
+		t.Error("expected payment secret key to be configured")
+	}
+	if config.SecretKey != "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=" {
+		t.Errorf("unexpected SecretKey '%s'", config.SecretKey)
+	}
+
+	os.Unsetenv("PAYMENT_SECRET_KEY")
+	if NewPaymentConfig().Enabled() {
+		t.Error("expected payment secret key to be missing")
+	}
+}
@@ -6,12 +6,20 @@ import "os"
 type WeChatConfig struct {
 	AppID     string
 	AppSecret string
+	// SessionSecretKey base64编码的32字节密钥，用于加密存储会员的微信 session_key
+	SessionSecretKey string
 }
 
 // NewWeChatConfig creates WeChat configuration from environment variables
 func NewWeChatConfig() *WeChatConfig {
 	return &WeChatConfig{
-		AppID:     os.Getenv("WECHAT_APP_ID"),
-		AppSecret: os.Getenv("WECHAT_APP_SECRET"),
+		AppID:            os.Getenv("WECHAT_APP_ID"),
+		AppSecret:        os.Getenv("WECHAT_APP_SECRET"),
+		SessionSecretKey: os.Getenv("WECHAT_SESSION_SECRET_KEY"),
 	}
 }
+
+// SessionStorageEnabled reports whether the session key encryption key has been configured
+func (c *WeChatConfig) SessionStorageEnabled() bool {
+	return c.SessionSecretKey != ""
+}
@@ -49,3 +49,17 @@ func TestNewWeChatConfig_DefaultValues(t *testing.T) {
 		t.Errorf("expected empty AppSecret, got '%s'", config.AppSecret)
 	}
 }
+
+func TestNewWeChatConfig_SessionSecretKey(t *testing.T) {
+	os.Setenv("WECHAT_SESSION_SECRET_KEY", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	defer os.Unsetenv("WECHAT_SESSION_SECRET_KEY")
+
+	if !NewWeChatConfig().SessionStorageEnabled() {
+		t.Error("expected session secret key to be configured")
+	}
+
+	os.Unsetenv("WECHAT_SESSION_SECRET_KEY")
+	if NewWeChatConfig().SessionStorageEnabled() {
+		t.Error("expected session secret key to be missing")
+	}
+}
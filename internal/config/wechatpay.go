@@ -0,0 +1,35 @@
+package config
+
+import "os"
+
+// WeChatPayConfig represents WeChat Pay API v3 direct merchant configuration
+// 收款商户按机主配置，此处商户号与APIv3密钥仅用于启动时预加载平台证书
+type WeChatPayConfig struct {
+	BaseURL         string
+	AppID           string
+	MchID           string
+	APIv3Key        string
+	SerialNo        string
+	PrivateKeyPath  string
+	NotifyURL       string
+	RefundNotifyURL string
+}
+
+// NewWeChatPayConfig creates WeChat Pay configuration from environment variables
+func NewWeChatPayConfig() *WeChatPayConfig {
+	return &WeChatPayConfig{
+		BaseURL:         os.Getenv("WECHAT_PAY_BASE_URL"),
+		AppID:           os.Getenv("WECHAT_APP_ID"),
+		MchID:           os.Getenv("WECHAT_PAY_MERCHANT_ID"),
+		APIv3Key:        os.Getenv("WECHAT_PAY_API_KEY"),
+		SerialNo:        os.Getenv("WECHAT_PAY_SERIAL_NO"),
+		PrivateKeyPath:  os.Getenv("WECHAT_PAY_PRIVATE_KEY_PATH"),
+		NotifyURL:       os.Getenv("WECHAT_PAY_NOTIFY_URL"),
+		RefundNotifyURL: os.Getenv("WECHAT_PAY_REFUND_NOTIFY_URL"),
+	}
+}
+
+// Enabled reports whether the merchant API certificate has been configured
+func (c *WeChatPayConfig) Enabled() bool {
+	return c.SerialNo != "" && c.PrivateKeyPath != ""
+}
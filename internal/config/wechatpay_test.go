@@ -0,0 +1,35 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestNewWeChatPayConfig(t *testing.T) {
+	os.Setenv("WECHAT_PAY_MERCHANT_ID", "1900000001")
+	os.Setenv("WECHAT_PAY_SERIAL_NO", "SERIAL001")
+	os.Setenv("WECHAT_PAY_PRIVATE_KEY_PATH", "/etc/wechatpay/apiclient_key.pem")
+	defer func() {
+		os.Unsetenv("WECHAT_PAY_MERCHANT_ID")
+		os.Unsetenv("WECHAT_PAY_SERIAL_NO")
+		os.Unsetenv("WECHAT_PAY_PRIVATE_KEY_PATH")
+	}()
+
+	config := NewWeChatPayConfig()
+
+	if !config.Enabled() {
+		t.Error("expected WeChat Pay to be enabled")
+	}
+	if config.MchID != "1900000001" {
+		t.Errorf("expected MchID '1900000001', got '%s'", config.MchID)
+	}
+}
+
+func TestNewWeChatPayConfig_Disabled(t *testing.T) {
+	os.Unsetenv("WECHAT_PAY_SERIAL_NO")
+	os.Unsetenv("WECHAT_PAY_PRIVATE_KEY_PATH")
+
+	if NewWeChatPayConfig().Enabled() {
+		t.Error("expected WeChat Pay to be disabled without merchant certificate")
+	}
+}
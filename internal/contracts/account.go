@@ -13,6 +13,8 @@ type CheckUserInfoResponse struct {
 	Nickname       string `json:"nickname" example:"用户昵称"`
 	IsMachineOwner bool   `json:"isMachineOwner" example:"false"`
 	Token          string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	ExpiresIn      int64  `json:"expiresIn,omitempty" example:"1800"` // 访问令牌有效秒数
+	RefreshToken   string `json:"refreshToken,omitempty" example:"q2Xv9C1mS0b..."`
 }
 
 // WeChatLoginRequest 微信登录请求
@@ -30,6 +32,8 @@ type WeChatLoginResponse struct {
 	Nickname       string `json:"nickname" example:"用户昵称"`
 	IsMachineOwner bool   `json:"isMachineOwner" example:"false"`
 	Token          string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	ExpiresIn      int64  `json:"expiresIn,omitempty" example:"1800"` // 访问令牌有效秒数
+	RefreshToken   string `json:"refreshToken,omitempty" example:"q2Xv9C1mS0b..."`
 }
 
 // GetUserInfoResponse 获取用户信息响应
@@ -39,3 +43,41 @@ type GetUserInfoResponse struct {
 	Nickname       string `json:"nickname" example:"用户昵称"`
 	IsMachineOwner bool   `json:"isMachineOwner" example:"false"`
 }
+
+// AuthTokenResponse 登录令牌
+type AuthTokenResponse struct {
+	Token            string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	ExpiresIn        int64  `json:"expiresIn" example:"1800"` // 访问令牌有效秒数
+	RefreshToken     string `json:"refreshToken" example:"q2Xv9C1mS0b..."`
+	RefreshExpiresIn int64  `json:"refreshExpiresIn" example:"2592000"` // 刷新令牌有效秒数
+}
+
+// RefreshTokenRequest 刷新令牌请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required" example:"q2Xv9C1mS0b..."`
+}
+
+// LogoutRequest 退出登录请求
+type LogoutRequest struct {
+	AllSessions bool `json:"allSessions" example:"false"` // 同时退出该会员的所有登录会话
+}
+
+// WeChatEncryptedDataRequest 小程序加密数据解密请求（wx.getPhoneNumber / wx.getUserInfo 返回的 encryptedData 与 iv）
+type WeChatEncryptedDataRequest struct {
+	EncryptedData string `json:"encryptedData" binding:"required" example:"CiyLU1Aw2KjvrjMdj8YKliAjtP4gsMZM..."`
+	Iv            string `json:"iv" binding:"required" example:"r7BXXKkLb8qrSNn05n0qiA=="`
+}
+
+// WeChatPhoneNumberResponse 解密后的微信手机号
+type WeChatPhoneNumberResponse struct {
+	PhoneNumber     string `json:"phoneNumber" example:"+86 13800138000"` // 带区号的手机号
+	PurePhoneNumber string `json:"purePhoneNumber" example:"13800138000"`
+	CountryCode     string `json:"countryCode" example:"86"`
+}
+
+// WeChatUserInfoResponse 解密后的微信用户信息
+type WeChatUserInfoResponse struct {
+	Nickname  string `json:"nickname" example:"用户昵称"`
+	AvatarUrl string `json:"avatarUrl" example:"https://example.com/avatar.jpg"`
+	UnionId   string `json:"unionId,omitempty" example:"oUpF8uMuAJO_M2pxb1Q9zNjWeS6o"`
+}
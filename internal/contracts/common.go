@@ -179,5 +179,7 @@ const (
 	ErrorCodeDatabaseError     = "DATABASE_ERROR"
 	ErrorCodeInvalidToken      = "INVALID_TOKEN"
 	ErrorCodeTokenExpired      = "TOKEN_EXPIRED"
+	ErrorCodeTokenRevoked      = "TOKEN_REVOKED"
 	ErrorCodeRateLimitExceeded = "RATE_LIMIT_EXCEEDED"
+	ErrorCodeIdempotencyReused = "IDEMPOTENCY_KEY_REUSED"
 )
@@ -0,0 +1,34 @@
+package contracts
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 订单领域事件类型
+const (
+	EventOrderPaid         = "OrderPaid"         // 订单已支付
+	EventOrderInvalidated  = "OrderInvalidated"  // 订单已作废
+	EventOrderRefunding    = "OrderRefunding"    // 订单发起退款
+	EventOrderRefunded     = "OrderRefunded"     // 订单退款成功
+	EventOrderRefundFailed = "OrderRefundFailed" // 订单退款失败
+	EventOrderMaking       = "OrderMaking"       // 订单开始制作
+	EventOrderMade         = "OrderMade"         // 订单制作完成
+	EventOrderMakeFailed   = "OrderMakeFailed"   // 订单制作失败
+)
+
+// OrderEvent 订单领域事件
+// 投递语义为至少一次，订阅者需按事件ID幂等处理
+type OrderEvent struct {
+	ID         string          `json:"id"`
+	Seq        int64           `json:"seq,omitempty"` // 发件箱写入序号，订单事件流以此作为事件ID
+	Type       string          `json:"type"`
+	OrderID    string          `json:"orderId"`
+	OrderNo    string          `json:"orderNo,omitempty"`
+	MemberID   string          `json:"memberId,omitempty"`
+	MachineID  string          `json:"machineId,omitempty"`
+	Amount     decimal.Decimal `json:"amount"`             // 支付事件为支付金额，退款事件为本次退款金额
+	RefundNo   string          `json:"refundNo,omitempty"` // 退款事件的退款单号
+	OccurredAt time.Time       `json:"occurredAt"`
+}
@@ -99,6 +99,39 @@ type DeviceStatusCheckResult struct {
 	LastSeen string `json:"lastSeen,omitempty"`
 }
 
+// DeviceRegisterCommand 设备寄存器写入指令（通过MQTT下发）
+type DeviceRegisterCommand struct {
+	DeviceID  string         `json:"deviceId"`
+	Params    map[string]int `json:"params"`
+	Timestamp int64          `json:"timestamp"`
+}
+
+// DeviceMakeCommand 饮品制作指令（通过MQTT下发）
+type DeviceMakeCommand struct {
+	OrderNo   string `json:"orderNo"`
+	ProductID string `json:"productId"`
+	HasCup    bool   `json:"hasCup"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// 设备制作事件类型
+const (
+	DeviceMakeEventAck      = "ack"      // 设备已接收制作指令
+	DeviceMakeEventProgress = "progress" // 制作进度
+	DeviceMakeEventDone     = "done"     // 制作完成
+	DeviceMakeEventFail     = "fail"     // 制作失败
+)
+
+// DeviceMakeEvent 设备上报的制作事件
+type DeviceMakeEvent struct {
+	DeviceID  string `json:"deviceId"`
+	OrderNo   string `json:"orderNo"`
+	Event     string `json:"event"`
+	Progress  int    `json:"progress,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+}
+
 // BusinessStatusEnum 营业状态枚举
 const (
 	BusinessStatusOpen    = "Open"
@@ -31,3 +31,31 @@ type MachineOwnerSalesResponse struct {
 	Meta    *Meta         `json:"meta,omitempty"`
 	Error   *APIError     `json:"error,omitempty"`
 }
+
+// OwnerPaymentAccountResponse 机主收款账户，不返回收款密钥
+type OwnerPaymentAccountResponse struct {
+	ChannelCode          string `json:"channelCode"`          // 支付渠道
+	ReceivingAccount     string `json:"receivingAccount"`     // 收款账户（商户号）
+	ReceivingOrderPrefix string `json:"receivingOrderPrefix"` // 订单前缀
+	HasReceivingKey      bool   `json:"hasReceivingKey"`      // 是否已设置收款密钥
+	MerchantSerialNo     string `json:"merchantSerialNo"`     // 商户API证书序列号
+	HasMerchantKey       bool   `json:"hasMerchantKey"`       // 是否已设置商户API证书私钥
+}
+
+// UpdatePaymentAccountRequest 设置机主收款账户请求，收款密钥与商户私钥留空时保留原值
+// 商户API证书序列号与私钥用于以机主自己的商户号调用微信支付API v3
+type UpdatePaymentAccountRequest struct {
+	ChannelCode          string `json:"channelCode" binding:"required"`
+	ReceivingAccount     string `json:"receivingAccount" binding:"required,max=16"`
+	ReceivingKey         string `json:"receivingKey" binding:"max=64"`
+	ReceivingOrderPrefix string `json:"receivingOrderPrefix" binding:"max=5"`
+	MerchantSerialNo     string `json:"merchantSerialNo" binding:"max=64"`
+	MerchantPrivateKey   string `json:"merchantPrivateKey" binding:"max=4096"`
+}
+
+// TestPaymentAccountResponse 收款账户连通性测试结果
+type TestPaymentAccountResponse struct {
+	ChannelCode string `json:"channelCode"`
+	Success     bool   `json:"success"`
+	Message     string `json:"message"`
+}
@@ -18,6 +18,7 @@ type GetMemberOrderPagingResponse struct {
 	ID                string          `json:"id" example:"order-123"`
 	OrderNo           string          `json:"orderNo" example:"ORD202508120001"`
 	ProductName       string          `json:"productName" example:"拿铁咖啡"`
+	MachineName       string          `json:"machineName" example:"办公楼1层咖啡机"`
 	PayAmount         decimal.Decimal `json:"payAmount" example:"15.80"`
 	CreatedAt         time.Time       `json:"createdAt" example:"2025-08-12T10:30:00Z"`
 	PaymentStatus     string          `json:"paymentStatus" example:"Paid"`
@@ -41,43 +42,95 @@ type CreateOrderResponse struct {
 }
 
 // RefundOrderRequest 退款订单请求
+// RefundAmount 为空或为0时退还订单剩余可退金额，小于剩余金额时为部分退款
 type RefundOrderRequest struct {
-	OrderID        string `json:"orderId" binding:"required" validate:"required" example:"order-123"`
-	Reason         string `json:"reason" example:"设备故障无法出货"`
-	IsMachineOwner bool   `json:"isMachineOwner"`
+	OrderID        string          `json:"orderId" binding:"required" validate:"required" example:"order-123"`
+	RefundAmount   decimal.Decimal `json:"refundAmount" example:"5.00"`
+	Reason         string          `json:"reason" example:"设备故障无法出货"`
+	IsMachineOwner bool            `json:"isMachineOwner"`
 }
 
 // RefundOrderResponse 退款订单响应
+// Status 为本次退款状态，Refunded 表示渠道已退款，Refunding 表示等待渠道退款结果
 type RefundOrderResponse struct {
 	OrderID      string          `json:"orderId" example:"order-123"`
+	RefundNo     string          `json:"refundNo" example:"RFORD2025081210300001"`
 	RefundAmount decimal.Decimal `json:"refundAmount" example:"15.80"`
+	Status       string          `json:"status" example:"Refunded"`
 	Message      string          `json:"message" example:"退款成功"`
 }
 
 // GetOrderByIdResponse 根据ID获取订单详情响应
 type GetOrderByIdResponse struct {
+	ID                string           `json:"id" example:"order-123"`
+	OrderNo           string           `json:"orderNo" example:"ORD202508120001"`
+	MachineID         string           `json:"machineId" example:"machine-001"`
+	MachineName       string           `json:"machineName" example:"办公楼1层咖啡机"`
+	ProductID         string           `json:"productId" example:"product-001"`
+	ProductName       string           `json:"productName" example:"拿铁咖啡"`
+	ProductPrice      *decimal.Decimal `json:"productPrice,omitempty" example:"15.80"` // 下单时的商品单价
+	PayAmount         decimal.Decimal  `json:"payAmount" example:"15.80"`
+	PaymentStatus     string           `json:"paymentStatus" example:"Paid"`
+	PaymentStatusDesc string           `json:"paymentStatusDesc" example:"已支付"`
+	MakeStatus        string           `json:"makeStatus" example:"Made"`
+	MakeStatusDesc    string           `json:"makeStatusDesc" example:"制作完成"`
+	CreatedAt         time.Time        `json:"createdAt" example:"2025-08-12T10:30:00Z"`
+	PaymentTime       *time.Time       `json:"paymentTime,omitempty" example:"2025-08-12T10:30:30Z"`
+	HasCup            bool             `json:"hasCup" example:"true"`
+	RefundAmount      decimal.Decimal  `json:"refundAmount" example:"0"`
+	RefundReason      *string          `json:"refundReason,omitempty"`
+	ChannelCode       string           `json:"channelCode,omitempty" example:"fuiou_pay_merchant"`
+}
+
+// OrderPagingResponse 订单分页响应
+type OrderPagingResponse struct {
+	Orders []GetMemberOrderPagingResponse `json:"orders"`
+	Meta   PaginationMeta                 `json:"meta"`
+}
+
+// GetOwnerOrderPagingRequest 机主订单分页请求，筛选条件均为可选
+type GetOwnerOrderPagingRequest struct {
+	MachineOwnerID string `json:"-"`
+	PageIndex      int    `json:"pageIndex" example:"1"`
+	PageSize       int    `json:"pageSize" example:"10"`
+	MachineID      string `json:"machineId,omitempty" example:"machine-001"`
+	OrderNo        string `json:"orderNo,omitempty" example:"ORD202508120001"`
+	PaymentStatus  string `json:"paymentStatus,omitempty" example:"Paid"` // WaitPay/Paid/Refunding/Refunded/Cancelled
+	MakeStatus     string `json:"makeStatus,omitempty" example:"Made"`    // WaitMake/Making/Made/Failed
+	StartDate      string `json:"startDate,omitempty" example:"2025-08-01"`
+	EndDate        string `json:"endDate,omitempty" example:"2025-08-31"` // 包含当天
+}
+
+// OwnerOrderResponse 机主订单列表项
+type OwnerOrderResponse struct {
 	ID                string          `json:"id" example:"order-123"`
 	OrderNo           string          `json:"orderNo" example:"ORD202508120001"`
 	MachineID         string          `json:"machineId" example:"machine-001"`
 	MachineName       string          `json:"machineName" example:"办公楼1层咖啡机"`
-	ProductID         string          `json:"productId" example:"product-001"`
 	ProductName       string          `json:"productName" example:"拿铁咖啡"`
 	PayAmount         decimal.Decimal `json:"payAmount" example:"15.80"`
+	RefundAmount      decimal.Decimal `json:"refundAmount" example:"0"`
 	PaymentStatus     string          `json:"paymentStatus" example:"Paid"`
 	PaymentStatusDesc string          `json:"paymentStatusDesc" example:"已支付"`
 	MakeStatus        string          `json:"makeStatus" example:"Made"`
 	MakeStatusDesc    string          `json:"makeStatusDesc" example:"制作完成"`
 	CreatedAt         time.Time       `json:"createdAt" example:"2025-08-12T10:30:00Z"`
 	PaymentTime       *time.Time      `json:"paymentTime,omitempty" example:"2025-08-12T10:30:30Z"`
-	HasCup            bool            `json:"hasCup" example:"true"`
-	RefundAmount      decimal.Decimal `json:"refundAmount" example:"0"`
-	RefundReason      *string         `json:"refundReason,omitempty"`
 }
 
-// OrderPagingResponse 订单分页响应
-type OrderPagingResponse struct {
-	Orders []GetMemberOrderPagingResponse `json:"orders"`
-	Meta   PaginationMeta                 `json:"meta"`
+// OwnerOrderSummary 筛选结果合计
+type OwnerOrderSummary struct {
+	OrderCount   int64           `json:"orderCount" example:"120"`
+	PaidAmount   decimal.Decimal `json:"paidAmount" example:"1896.00"` // 已支付订单金额（含退款中和已退款订单）
+	RefundAmount decimal.Decimal `json:"refundAmount" example:"31.60"` // 已退款金额
+	NetAmount    decimal.Decimal `json:"netAmount" example:"1864.40"`  // 实收金额
+}
+
+// OwnerOrderPagingResponse 机主订单分页响应
+type OwnerOrderPagingResponse struct {
+	Orders  []OwnerOrderResponse `json:"orders"`
+	Meta    PaginationMeta       `json:"meta"`
+	Summary OwnerOrderSummary    `json:"summary"`
 }
 
 // 订单状态常量
@@ -85,6 +138,7 @@ const (
 	PaymentStatusWaitPay   = "WaitPay"   // 等待支付
 	PaymentStatusPaid      = "Paid"      // 已支付
 	PaymentStatusRefunded  = "Refunded"  // 已退款
+	PaymentStatusRefunding = "Refunding" // 退款中
 	PaymentStatusCancelled = "Cancelled" // 已取消
 
 	MakeStatusWaitMake = "WaitMake" // 等待制作
@@ -103,7 +157,10 @@ const (
 	ErrorCodeInsufficientInventory = "INSUFFICIENT_INVENTORY"
 	ErrorCodeOrderCreateFailed     = "ORDER_CREATE_FAILED"
 	ErrorCodeRefundFailed          = "REFUND_FAILED"
+	ErrorCodeInvalidRefundAmount   = "INVALID_REFUND_AMOUNT"
+	ErrorCodeRefundInProgress      = "REFUND_IN_PROGRESS"
 	ErrorCodeInvalidOrderStatus    = "INVALID_ORDER_STATUS"
 	ErrorCodeMachineNotAvailable   = "MACHINE_NOT_AVAILABLE"
 	ErrorCodeProductNotAvailable   = "PRODUCT_NOT_AVAILABLE"
+	ErrorCodePriceMismatch         = "PRICE_MISMATCH"
 )
@@ -11,6 +11,8 @@ type WeChatPayRequest struct {
 	Ext1        string `json:"ext1" validate:"required"`        // 收款账户
 	Ext2        string `json:"ext2" validate:"required"`        // 收款密钥
 	Ext3        string `json:"ext3" validate:"required"`        // 订单前缀
+	Ext4        string `json:"ext4,omitempty"`                  // 商户证书序列号
+	Ext5        string `json:"ext5,omitempty"`                  // 商户API私钥
 	NotifyUrl   string `json:"notifyUrl" validate:"required"`   // 回调地址
 	ChannelCode string `json:"channelCode" validate:"required"` // 渠道代码
 	OrderNo     string `json:"orderNo" validate:"required"`     // 订单号
@@ -37,6 +39,8 @@ type TranQueryRequest struct {
 	Ext1          string `json:"ext1" validate:"required"`          // 收款账户
 	Ext2          string `json:"ext2" validate:"required"`          // 收款密钥
 	Ext3          string `json:"ext3" validate:"required"`          // 订单前缀
+	Ext4          string `json:"ext4,omitempty"`                    // 商户证书序列号
+	Ext5          string `json:"ext5,omitempty"`                    // 商户API私钥
 	ChannelCode   string `json:"channelCode" validate:"required"`   // 渠道代码
 	OrderNo       string `json:"orderNo" validate:"required"`       // 订单号
 	ModeOfPayment string `json:"modeOfPayment" validate:"required"` // 支付方式
@@ -52,6 +56,49 @@ type TranQueryResponse struct {
 	ErrorCode     string    `json:"errorCode,omitempty"`     // 错误码
 }
 
+// PaymentRefundRequest 渠道退款请求
+type PaymentRefundRequest struct {
+	Ext1        string `json:"ext1" validate:"required"`        // 收款账户
+	Ext2        string `json:"ext2" validate:"required"`        // 收款密钥
+	Ext3        string `json:"ext3" validate:"required"`        // 订单前缀
+	Ext4        string `json:"ext4,omitempty"`                  // 商户证书序列号
+	Ext5        string `json:"ext5,omitempty"`                  // 商户API私钥
+	ChannelCode string `json:"channelCode" validate:"required"` // 渠道代码
+	OrderNo     string `json:"orderNo" validate:"required"`     // 原订单号
+	RefundNo    string `json:"refundNo" validate:"required"`    // 退款单号（渠道侧幂等键）
+	TotalAmt    int32  `json:"totalAmt" validate:"gt=0"`        // 原订单金额(分)
+	RefundAmt   int32  `json:"refundAmt" validate:"gt=0"`       // 退款金额(分)
+	Reason      string `json:"reason"`                          // 退款原因
+}
+
+// PaymentRefundResponse 渠道退款响应
+// Pending 表示渠道已受理退款但结果需等待退款回调
+type PaymentRefundResponse struct {
+	IsSuccess       bool   `json:"isSuccess"`
+	Pending         bool   `json:"pending"`
+	ChannelRefundNo string `json:"channelRefundNo,omitempty"` // 渠道退款单号
+	Message         string `json:"message,omitempty"`         // 响应消息
+	ErrorCode       string `json:"errorCode,omitempty"`       // 错误码
+}
+
+// PaymentCloseRequest 渠道关单请求
+type PaymentCloseRequest struct {
+	Ext1        string `json:"ext1" validate:"required"`        // 收款账户
+	Ext2        string `json:"ext2" validate:"required"`        // 收款密钥
+	Ext3        string `json:"ext3" validate:"required"`        // 订单前缀
+	Ext4        string `json:"ext4,omitempty"`                  // 商户证书序列号
+	Ext5        string `json:"ext5,omitempty"`                  // 商户API私钥
+	ChannelCode string `json:"channelCode" validate:"required"` // 渠道代码
+	OrderNo     string `json:"orderNo" validate:"required"`     // 订单号
+}
+
+// PaymentCloseResponse 渠道关单响应
+type PaymentCloseResponse struct {
+	IsSuccess bool   `json:"isSuccess"`
+	Message   string `json:"message,omitempty"`   // 响应消息
+	ErrorCode string `json:"errorCode,omitempty"` // 错误码
+}
+
 // PayOrderRequest 支付订单请求（内部服务调用）
 type PayOrderRequest struct {
 	ID             string    `json:"id" validate:"required"`             // 订单ID
@@ -61,7 +108,8 @@ type PayOrderRequest struct {
 
 // InvalidOrderRequest 作废订单请求（内部服务调用）
 type InvalidOrderRequest struct {
-	ID string `json:"id" validate:"required"` // 订单ID
+	ID      string `json:"id" validate:"required"` // 订单ID
+	Unbound bool   `json:"-"`                      // 仅作废未绑定渠道（未发起过预下单）的订单
 }
 
 // PaymentAccount 支付账户信息
@@ -69,6 +117,9 @@ type PaymentAccount struct {
 	ReceivingAccount     string `json:"receivingAccount" validate:"required"`     // 收款账户
 	ReceivingKey         string `json:"receivingKey" validate:"required"`         // 收款密钥
 	ReceivingOrderPrefix string `json:"receivingOrderPrefix" validate:"required"` // 订单前缀
+	ChannelCode          string `json:"channelCode"`                              // 支付渠道
+	MerchantSerialNo     string `json:"-"`                                        // 商户API证书序列号
+	MerchantPrivateKey   string `json:"-"`                                        // 商户API证书私钥（PEM）
 }
 
 // GetPaymentRequest 获取支付信息请求
@@ -110,6 +161,7 @@ type PaymentCallbackResponse struct {
 }
 
 // PaymentCallbackResultRequest 支付结果回调请求（基于VendingMachine.MobileAPI）
+// Sign 为除自身外各字段按名称排序拼接后使用机主收款密钥计算的签名，paymentTime 按RFC3339参与签名
 type PaymentCallbackResultRequest struct {
 	ChannelCode    string    `json:"channelCode" validate:"required"`    // 渠道编码
 	TransAmt       int       `json:"transAmt" validate:"required,gt=0"`  // 订单金额(分)
@@ -120,12 +172,28 @@ type PaymentCallbackResultRequest struct {
 	ChannelOrderNo string    `json:"channelOrderNo" validate:"required"` // 渠道订单号
 	PaymentTime    time.Time `json:"paymentTime" validate:"required"`    // 支付时间
 	CallbackType   string    `json:"callbackType" validate:"required"`   // 回调类型
+	Sign           string    `json:"sign"`                               // 签名
+}
+
+// RefundCallbackRequest 退款结果回调请求
+// Sign 计算方式与支付结果回调相同，refundTime 按RFC3339参与签名
+type RefundCallbackRequest struct {
+	ChannelCode     string    `json:"channelCode" validate:"required"`    // 渠道编码
+	OrderNo         string    `json:"orderNo" validate:"required"`        // 原订单号
+	RefundNo        string    `json:"refundNo" validate:"required"`       // 退款单号
+	ChannelRefundNo string    `json:"channelRefundNo"`                    // 渠道退款单号
+	RefundAmt       int       `json:"refundAmt" validate:"required,gt=0"` // 退款金额(分)
+	RefundStatus    string    `json:"refundStatus" validate:"required"`   // 退款结果
+	RefundTime      time.Time `json:"refundTime"`                         // 退款完成时间
+	Sign            string    `json:"sign"`                               // 签名
 }
 
 // 支付相关常量
 const (
 	// 支付渠道
 	ChannelCodeFuiouMerchant = "fuiou_pay_merchant"
+	ChannelCodeWeChatPayV3   = "wechat_pay_v3"
+	ChannelCodeMock          = "mock"
 
 	// 支付方式
 	ModeOfPaymentWeChat = "WeChatPay"
@@ -138,6 +206,10 @@ const (
 	PaymentStatusTimeout   = "Timeout"   // 支付超时
 	PaymentStatusException = "Exception" // 异常
 
+	// 退款结果（退款回调）
+	RefundResultSuccess = "Success" // 退款成功
+	RefundResultFailure = "Failure" // 退款失败或关闭
+
 	// 免支付标识
 	FreePaymentChannelOrderNo = "FREE_OF_PAYMENT"
 )
@@ -151,4 +223,5 @@ const (
 	ErrorCodeInvalidPaymentAmount    = "INVALID_PAYMENT_AMOUNT"
 	ErrorCodePaymentAccountNotFound  = "PAYMENT_ACCOUNT_NOT_FOUND"
 	ErrorCodePaymentCallbackInvalid  = "PAYMENT_CALLBACK_INVALID"
+	ErrorCodePaymentOpenIDMissing    = "PAYMENT_OPENID_MISSING" // 会员未绑定微信OpenId，无法JSAPI下单
 )
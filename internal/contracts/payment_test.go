@@ -212,6 +212,7 @@ func TestPaymentErrorCodes(t *testing.T) {
 		ErrorCodeInvalidPaymentAmount,
 		ErrorCodePaymentAccountNotFound,
 		ErrorCodePaymentCallbackInvalid,
+		ErrorCodePaymentOpenIDMissing,
 	}
 
 	for _, code := range errorCodes {
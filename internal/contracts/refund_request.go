@@ -0,0 +1,61 @@
+package contracts
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 退款申请审核状态
+const (
+	RefundRequestStatusPending  = "Pending"  // 待审核
+	RefundRequestStatusApproved = "Approved" // 已同意
+	RefundRequestStatusRejected = "Rejected" // 已拒绝
+	RefundRequestStatusAll      = "All"      // 查询时不限状态
+)
+
+// SubmitRefundRequest 顾客提交退款申请请求
+type SubmitRefundRequest struct {
+	MemberID string `json:"-"`
+	OrderID  string `json:"orderId" binding:"required" example:"order-123"`
+	Reason   string `json:"reason" binding:"required,max=512" example:"饮品没有出杯"`
+	PhotoURL string `json:"photoUrl,omitempty" binding:"omitempty,url,max=512" example:"https://example.com/photo.jpg"`
+}
+
+// ReviewRefundRequest 机主审核退款申请请求
+type ReviewRefundRequest struct {
+	MachineOwnerID string `json:"-"`
+	ID             string `json:"id" binding:"required" example:"request-123"`
+	Approve        bool   `json:"approve" example:"true"`
+	Remark         string `json:"remark,omitempty" binding:"max=512" example:"已核实，同意退款"`
+}
+
+// GetRefundRequestPagingRequest 机主退款申请分页请求
+type GetRefundRequestPagingRequest struct {
+	MachineOwnerID string `json:"-"`
+	PageIndex      int    `json:"pageIndex" example:"1"`
+	PageSize       int    `json:"pageSize" example:"10"`
+	Status         string `json:"status,omitempty" example:"Pending"` // Pending/Approved/Rejected/All，默认Pending
+}
+
+// RefundRequestResponse 退款申请
+type RefundRequestResponse struct {
+	ID           string          `json:"id" example:"request-123"`
+	OrderID      string          `json:"orderId" example:"order-123"`
+	MachineID    string          `json:"machineId" example:"machine-001"`
+	Amount       decimal.Decimal `json:"amount" example:"15.80"`
+	Reason       string          `json:"reason" example:"饮品没有出杯"`
+	PhotoURL     *string         `json:"photoUrl,omitempty" example:"https://example.com/photo.jpg"`
+	Status       string          `json:"status" example:"Pending"`
+	StatusDesc   string          `json:"statusDesc" example:"待审核"`
+	ReviewRemark *string         `json:"reviewRemark,omitempty" example:"已核实，同意退款"`
+	ReviewedAt   *time.Time      `json:"reviewedAt,omitempty" example:"2025-08-12T11:00:00Z"`
+	RefundNo     *string         `json:"refundNo,omitempty" example:"RFORD20250812000101"`
+	CreatedAt    time.Time       `json:"createdAt" example:"2025-08-12T10:40:00Z"`
+}
+
+// RefundRequestPagingResponse 机主退款申请分页响应
+type RefundRequestPagingResponse struct {
+	Requests []RefundRequestResponse `json:"requests"`
+	Meta     PaginationMeta          `json:"meta"`
+}
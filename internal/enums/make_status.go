@@ -39,3 +39,21 @@ func (ms MakeStatus) String() string {
 func (ms MakeStatus) IsValid() bool {
 	return ms >= MakeStatusWaitMake && ms <= MakeStatusMakeFail
 }
+
+// IsFinal reports whether the make status is terminal (made or failed)
+func (ms MakeStatus) IsFinal() bool {
+	return ms == MakeStatusMade || ms == MakeStatusMakeFail
+}
+
+// CanTransitionTo checks whether the make status may move to next
+// 待制作 -> 制作中/制作完成/制作失败，制作中 -> 制作完成/制作失败，终态不可再变更
+func (ms MakeStatus) CanTransitionTo(next MakeStatus) bool {
+	switch ms {
+	case MakeStatusWaitMake:
+		return next == MakeStatusMaking || next == MakeStatusMade || next == MakeStatusMakeFail
+	case MakeStatusMaking:
+		return next == MakeStatusMade || next == MakeStatusMakeFail
+	default:
+		return false
+	}
+}
@@ -93,3 +93,41 @@ func TestMakeStatus_IsValid(t *testing.T) {
 		})
 	}
 }
+
+func TestMakeStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     MakeStatus
+		to       MakeStatus
+		expected bool
+	}{
+		{"WaitMake to Making", MakeStatusWaitMake, MakeStatusMaking, true},
+		{"WaitMake to Made", MakeStatusWaitMake, MakeStatusMade, true},
+		{"WaitMake to MakeFail", MakeStatusWaitMake, MakeStatusMakeFail, true},
+		{"Making to Made", MakeStatusMaking, MakeStatusMade, true},
+		{"Making to MakeFail", MakeStatusMaking, MakeStatusMakeFail, true},
+		{"Making to WaitMake", MakeStatusMaking, MakeStatusWaitMake, false},
+		{"Making to Making", MakeStatusMaking, MakeStatusMaking, false},
+		{"Made to MakeFail", MakeStatusMade, MakeStatusMakeFail, false},
+		{"MakeFail to Made", MakeStatusMakeFail, MakeStatusMade, false},
+		{"Invalid from", MakeStatus(999), MakeStatusMaking, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.from.CanTransitionTo(tt.to)
+			if result != tt.expected {
+				t.Errorf("Expected %d.CanTransitionTo(%d) to be %t, but got %t", tt.from, tt.to, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMakeStatus_IsFinal(t *testing.T) {
+	if MakeStatusWaitMake.IsFinal() || MakeStatusMaking.IsFinal() {
+		t.Error("Expected WaitMake and Making to be non-final")
+	}
+	if !MakeStatusMade.IsFinal() || !MakeStatusMakeFail.IsFinal() {
+		t.Error("Expected Made and MakeFail to be final")
+	}
+}
@@ -0,0 +1,124 @@
+package enums
+
+import "strconv"
+
+// MemberRole represents the authorization role of a member
+type MemberRole int
+
+const (
+	// MemberRoleConsumer represents an ordinary customer
+	MemberRoleConsumer MemberRole = 1 // 顾客
+	// MemberRoleOwner represents a machine owner managing their own machines
+	MemberRoleOwner MemberRole = 2 // 机主
+	// MemberRoleMaintainer represents a maintainer refilling silos for a machine owner
+	MemberRoleMaintainer MemberRole = 3 // 运维
+	// MemberRoleAdmin represents a platform administrator
+	MemberRoleAdmin MemberRole = 4 // 管理员
+)
+
+// Permission represents an operation granted to a role and embedded in the JWT
+type Permission string
+
+const (
+	// PermissionOrderPlace allows placing and viewing one's own orders and refund requests
+	PermissionOrderPlace Permission = "order:place"
+	// PermissionOrderManage allows viewing, refunding and reviewing orders of owned machines
+	PermissionOrderManage Permission = "order:manage"
+	// PermissionMachineManage allows listing owned machines and opening or closing business
+	PermissionMachineManage Permission = "machine:manage"
+	// PermissionSiloMaintain allows refilling silos and changing their products and sale status
+	PermissionSiloMaintain Permission = "silo:maintain"
+	// PermissionOwnerFinance allows viewing sales and managing the payment account
+	PermissionOwnerFinance Permission = "owner:finance"
+	// PermissionPlatformAdmin allows platform administration
+	PermissionPlatformAdmin Permission = "platform:admin"
+)
+
+// rolePermissions 各角色拥有的权限，管理员拥有全部权限
+var rolePermissions = map[MemberRole][]Permission{
+	MemberRoleConsumer: {PermissionOrderPlace},
+	MemberRoleOwner: {
+		PermissionOrderPlace, PermissionOrderManage, PermissionMachineManage,
+		PermissionSiloMaintain, PermissionOwnerFinance,
+	},
+	MemberRoleMaintainer: {PermissionOrderPlace, PermissionSiloMaintain},
+	MemberRoleAdmin: {
+		PermissionOrderPlace, PermissionOrderManage, PermissionMachineManage,
+		PermissionSiloMaintain, PermissionOwnerFinance, PermissionPlatformAdmin,
+	},
+}
+
+// roleCodes 角色在JWT及接口中的编码
+var roleCodes = map[MemberRole]string{
+	MemberRoleConsumer:   "Consumer",
+	MemberRoleOwner:      "Owner",
+	MemberRoleMaintainer: "Maintainer",
+	MemberRoleAdmin:      "Admin",
+}
+
+// GetMemberRoleDesc returns the description of the member role
+func GetMemberRoleDesc(role MemberRole) string {
+	switch role {
+	case MemberRoleConsumer:
+		return "顾客"
+	case MemberRoleOwner:
+		return "机主"
+	case MemberRoleMaintainer:
+		return "运维"
+	case MemberRoleAdmin:
+		return "管理员"
+	default:
+		return "未知角色"
+	}
+}
+
+// String returns the string representation of the member role
+func (r MemberRole) String() string {
+	return GetMemberRoleDesc(r)
+}
+
+// IsValid checks if the member role is valid
+func (r MemberRole) IsValid() bool {
+	return r >= MemberRoleConsumer && r <= MemberRoleAdmin
+}
+
+// Code returns the code of the member role used in JWT claims and API responses
+func (r MemberRole) Code() string {
+	if code, ok := roleCodes[r]; ok {
+		return code
+	}
+	return roleCodes[MemberRoleConsumer]
+}
+
+// Permissions returns the permissions granted to the member role
+func (r MemberRole) Permissions() []Permission {
+	permissions := rolePermissions[r]
+	if permissions == nil {
+		permissions = rolePermissions[MemberRoleConsumer]
+	}
+	return append([]Permission(nil), permissions...)
+}
+
+// HasPermission reports whether the member role grants permission
+func (r MemberRole) HasPermission(permission Permission) bool {
+	for _, p := range r.Permissions() {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMemberRole parses a role code from JWT claims
+// 兼容旧版Token中的数字角色（"1"/"2"）及 "Member"，无法识别的角色按顾客处理
+func ParseMemberRole(code string) MemberRole {
+	for role, roleCode := range roleCodes {
+		if roleCode == code {
+			return role
+		}
+	}
+	if value, err := strconv.Atoi(code); err == nil && MemberRole(value).IsValid() {
+		return MemberRole(value)
+	}
+	return MemberRoleConsumer
+}
@@ -0,0 +1,69 @@
+package enums
+
+import "testing"
+
+func TestGetMemberRoleDesc(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     MemberRole
+		expected string
+	}{
+		{"Consumer role", MemberRoleConsumer, "顾客"},
+		{"Owner role", MemberRoleOwner, "机主"},
+		{"Maintainer role", MemberRoleMaintainer, "运维"},
+		{"Admin role", MemberRoleAdmin, "管理员"},
+		{"Unknown role", MemberRole(999), "未知角色"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.role.String()
+			if result != tt.expected {
+				t.Errorf("Expected %d.String() to be '%s', but got '%s'", tt.role, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseMemberRole(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected MemberRole
+	}{
+		{"Consumer", MemberRoleConsumer},
+		{"Owner", MemberRoleOwner},
+		{"Maintainer", MemberRoleMaintainer},
+		{"Admin", MemberRoleAdmin},
+		{"2", MemberRoleOwner},
+		{"1", MemberRoleConsumer},
+		{"Member", MemberRoleConsumer},
+		{"", MemberRoleConsumer},
+		{"99", MemberRoleConsumer},
+	}
+
+	for _, tt := range tests {
+		if role := ParseMemberRole(tt.code); role != tt.expected {
+			t.Errorf("Expected ParseMemberRole(%q) to be %d, got %d", tt.code, tt.expected, role)
+		}
+		if role := ParseMemberRole(tt.expected.Code()); role != tt.expected {
+			t.Errorf("Expected role %d to round-trip through its code", tt.expected)
+		}
+	}
+}
+
+func TestMemberRole_HasPermission(t *testing.T) {
+	if MemberRoleConsumer.HasPermission(PermissionOrderManage) {
+		t.Error("Expected consumers not to manage orders")
+	}
+	if !MemberRoleMaintainer.HasPermission(PermissionSiloMaintain) || MemberRoleMaintainer.HasPermission(PermissionOwnerFinance) {
+		t.Error("Expected maintainers to maintain silos only")
+	}
+	if MemberRoleOwner.HasPermission(PermissionPlatformAdmin) {
+		t.Error("Expected owners not to administer the platform")
+	}
+	for _, permission := range MemberRoleOwner.Permissions() {
+		if !MemberRoleAdmin.HasPermission(permission) {
+			t.Errorf("Expected admins to have owner permission %s", permission)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package enums
+
+// OutboxStatus represents the delivery status of an outbox event
+type OutboxStatus int
+
+const (
+	// OutboxStatusPending represents an event waiting to be delivered to subscribers
+	OutboxStatusPending OutboxStatus = 0 // 待投递
+	// OutboxStatusDelivered represents an event delivered to all subscribers
+	OutboxStatusDelivered OutboxStatus = 1 // 已投递
+	// OutboxStatusFailed represents an event that gave up after retries and needs manual handling
+	OutboxStatusFailed OutboxStatus = 2 // 投递失败
+)
+
+// GetOutboxStatusDesc returns the description of the outbox status
+func GetOutboxStatusDesc(status OutboxStatus) string {
+	switch status {
+	case OutboxStatusPending:
+		return "待投递"
+	case OutboxStatusDelivered:
+		return "已投递"
+	case OutboxStatusFailed:
+		return "投递失败"
+	default:
+		return "未知状态"
+	}
+}
+
+// String returns the string representation of the outbox status
+func (os OutboxStatus) String() string {
+	return GetOutboxStatusDesc(os)
+}
+
+// IsValid checks if the outbox status is valid
+func (os OutboxStatus) IsValid() bool {
+	return os >= OutboxStatusPending && os <= OutboxStatusFailed
+}
@@ -0,0 +1,34 @@
+package enums
+
+import "testing"
+
+func TestGetOutboxStatusDesc(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   OutboxStatus
+		expected string
+	}{
+		{"Pending status", OutboxStatusPending, "待投递"},
+		{"Delivered status", OutboxStatusDelivered, "已投递"},
+		{"Failed status", OutboxStatusFailed, "投递失败"},
+		{"Unknown status", OutboxStatus(999), "未知状态"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.status.String()
+			if result != tt.expected {
+				t.Errorf("Expected %d.String() to be '%s', but got '%s'", tt.status, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestOutboxStatus_IsValid(t *testing.T) {
+	if !OutboxStatusPending.IsValid() || !OutboxStatusFailed.IsValid() {
+		t.Error("Expected defined outbox statuses to be valid")
+	}
+	if OutboxStatus(-1).IsValid() || OutboxStatus(3).IsValid() {
+		t.Error("Expected out of range outbox statuses to be invalid")
+	}
+}
@@ -12,6 +12,8 @@ const (
 	PaymentStatusInvalid PaymentStatus = 2 // 已失效
 	// PaymentStatusRefunded represents an order that has been refunded
 	PaymentStatusRefunded PaymentStatus = 3 // 已退款
+	// PaymentStatusRefunding represents an order with a refund waiting for the payment channel
+	PaymentStatusRefunding PaymentStatus = 4 // 退款中
 )
 
 // GetPaymentStatusDesc returns the description of the payment status
@@ -25,6 +27,8 @@ func GetPaymentStatusDesc(status PaymentStatus) string {
 		return "已失效"
 	case PaymentStatusRefunded:
 		return "已退款"
+	case PaymentStatusRefunding:
+		return "退款中"
 	default:
 		return "未知状态"
 	}
@@ -37,5 +41,5 @@ func (ps PaymentStatus) String() string {
 
 // IsValid checks if the payment status is valid
 func (ps PaymentStatus) IsValid() bool {
-	return ps >= PaymentStatusWaitPay && ps <= PaymentStatusRefunded
+	return ps >= PaymentStatusWaitPay && ps <= PaymentStatusRefunding
 }
@@ -12,6 +12,7 @@ func TestPaymentStatus_Constants(t *testing.T) {
 		{"PaymentStatusPaid", PaymentStatusPaid, 1},
 		{"PaymentStatusInvalid", PaymentStatusInvalid, 2},
 		{"PaymentStatusRefunded", PaymentStatusRefunded, 3},
+		{"PaymentStatusRefunding", PaymentStatusRefunding, 4},
 	}
 
 	for _, tt := range tests {
@@ -33,6 +34,7 @@ func TestGetPaymentStatusDesc(t *testing.T) {
 		{"Paid status", PaymentStatusPaid, "已支付"},
 		{"Invalid status", PaymentStatusInvalid, "已失效"},
 		{"Refunded status", PaymentStatusRefunded, "已退款"},
+		{"Refunding status", PaymentStatusRefunding, "退款中"},
 		{"Unknown status", PaymentStatus(999), "未知状态"},
 	}
 
@@ -80,7 +82,8 @@ func TestPaymentStatus_IsValid(t *testing.T) {
 		{"Valid Invalid status", PaymentStatusInvalid, true},
 		{"Valid Refunded status", PaymentStatusRefunded, true},
 		{"Invalid status -1", PaymentStatus(-1), false},
-		{"Invalid status 4", PaymentStatus(4), false},
+		{"Valid Refunding status", PaymentStatusRefunding, true},
+		{"Invalid status 5", PaymentStatus(5), false},
 		{"Invalid large status", PaymentStatus(999), false},
 	}
 
@@ -0,0 +1,37 @@
+package enums
+
+// RefundRequestStatus represents the review status of a customer refund request
+type RefundRequestStatus int
+
+const (
+	// RefundRequestStatusPending represents a request waiting for the machine owner to review
+	RefundRequestStatusPending RefundRequestStatus = 0 // 待审核
+	// RefundRequestStatusApproved represents a request approved by the machine owner and refunded
+	RefundRequestStatusApproved RefundRequestStatus = 1 // 已同意
+	// RefundRequestStatusRejected represents a request rejected by the machine owner
+	RefundRequestStatusRejected RefundRequestStatus = 2 // 已拒绝
+)
+
+// GetRefundRequestStatusDesc returns the description of the refund request status
+func GetRefundRequestStatusDesc(status RefundRequestStatus) string {
+	switch status {
+	case RefundRequestStatusPending:
+		return "待审核"
+	case RefundRequestStatusApproved:
+		return "已同意"
+	case RefundRequestStatusRejected:
+		return "已拒绝"
+	default:
+		return "未知状态"
+	}
+}
+
+// String returns the string representation of the refund request status
+func (rs RefundRequestStatus) String() string {
+	return GetRefundRequestStatusDesc(rs)
+}
+
+// IsValid checks if the refund request status is valid
+func (rs RefundRequestStatus) IsValid() bool {
+	return rs >= RefundRequestStatusPending && rs <= RefundRequestStatusRejected
+}
@@ -0,0 +1,34 @@
+package enums
+
+import "testing"
+
+func TestGetRefundRequestStatusDesc(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   RefundRequestStatus
+		expected string
+	}{
+		{"Pending status", RefundRequestStatusPending, "待审核"},
+		{"Approved status", RefundRequestStatusApproved, "已同意"},
+		{"Rejected status", RefundRequestStatusRejected, "已拒绝"},
+		{"Unknown status", RefundRequestStatus(999), "未知状态"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.status.String()
+			if result != tt.expected {
+				t.Errorf("Expected %d.String() to be '%s', but got '%s'", tt.status, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRefundRequestStatus_IsValid(t *testing.T) {
+	if !RefundRequestStatusPending.IsValid() || !RefundRequestStatusRejected.IsValid() {
+		t.Error("Expected defined refund request statuses to be valid")
+	}
+	if RefundRequestStatus(-1).IsValid() || RefundRequestStatus(3).IsValid() {
+		t.Error("Expected undefined refund request statuses to be invalid")
+	}
+}
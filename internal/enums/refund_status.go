@@ -0,0 +1,45 @@
+package enums
+
+// RefundStatus represents the processing status of a refund record
+type RefundStatus int
+
+const (
+	// RefundStatusPending represents a refund waiting to be sent to the payment channel
+	RefundStatusPending RefundStatus = 0 // 待退款
+	// RefundStatusProcessing represents a refund currently being sent to the payment channel
+	RefundStatusProcessing RefundStatus = 1 // 退款中
+	// RefundStatusSuccess represents a refund accepted by the payment channel
+	RefundStatusSuccess RefundStatus = 2 // 退款成功
+	// RefundStatusFailed represents a refund that gave up after retries and needs manual handling
+	RefundStatusFailed RefundStatus = 3 // 退款失败
+	// RefundStatusAccepted represents a refund accepted by the payment channel and waiting for its result
+	RefundStatusAccepted RefundStatus = 4 // 待渠道确认
+)
+
+// GetRefundStatusDesc returns the description of the refund status
+func GetRefundStatusDesc(status RefundStatus) string {
+	switch status {
+	case RefundStatusPending:
+		return "待退款"
+	case RefundStatusProcessing:
+		return "退款中"
+	case RefundStatusSuccess:
+		return "退款成功"
+	case RefundStatusFailed:
+		return "退款失败"
+	case RefundStatusAccepted:
+		return "待渠道确认"
+	default:
+		return "未知状态"
+	}
+}
+
+// String returns the string representation of the refund status
+func (rs RefundStatus) String() string {
+	return GetRefundStatusDesc(rs)
+}
+
+// IsValid checks if the refund status is valid
+func (rs RefundStatus) IsValid() bool {
+	return rs >= RefundStatusPending && rs <= RefundStatusAccepted
+}
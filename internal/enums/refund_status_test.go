@@ -0,0 +1,36 @@
+package enums
+
+import "testing"
+
+func TestGetRefundStatusDesc(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   RefundStatus
+		expected string
+	}{
+		{"Pending status", RefundStatusPending, "待退款"},
+		{"Processing status", RefundStatusProcessing, "退款中"},
+		{"Success status", RefundStatusSuccess, "退款成功"},
+		{"Failed status", RefundStatusFailed, "退款失败"},
+		{"Accepted status", RefundStatusAccepted, "待渠道确认"},
+		{"Unknown status", RefundStatus(999), "未知状态"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.status.String()
+			if result != tt.expected {
+				t.Errorf("Expected %d.String() to be '%s', but got '%s'", tt.status, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRefundStatus_IsValid(t *testing.T) {
+	if !RefundStatusPending.IsValid() || !RefundStatusAccepted.IsValid() {
+		t.Error("Expected defined refund statuses to be valid")
+	}
+	if RefundStatus(-1).IsValid() || RefundStatus(5).IsValid() {
+		t.Error("Expected out of range refund statuses to be invalid")
+	}
+}
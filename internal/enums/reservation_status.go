@@ -0,0 +1,37 @@
+package enums
+
+// ReservationStatus represents the status of a stock reservation held by an order
+type ReservationStatus int
+
+const (
+	// ReservationStatusReserved represents stock held for an order that has not been made yet
+	ReservationStatusReserved ReservationStatus = 0 // 已预占
+	// ReservationStatusCommitted represents reserved stock deducted after the drink was made
+	ReservationStatusCommitted ReservationStatus = 1 // 已扣减
+	// ReservationStatusReleased represents reserved stock returned to the silo
+	ReservationStatusReleased ReservationStatus = 2 // 已释放
+)
+
+// GetReservationStatusDesc returns the description of the reservation status
+func GetReservationStatusDesc(status ReservationStatus) string {
+	switch status {
+	case ReservationStatusReserved:
+		return "已预占"
+	case ReservationStatusCommitted:
+		return "已扣减"
+	case ReservationStatusReleased:
+		return "已释放"
+	default:
+		return "未知状态"
+	}
+}
+
+// String returns the string representation of the reservation status
+func (rs ReservationStatus) String() string {
+	return GetReservationStatusDesc(rs)
+}
+
+// IsValid checks if the reservation status is valid
+func (rs ReservationStatus) IsValid() bool {
+	return rs >= ReservationStatusReserved && rs <= ReservationStatusReleased
+}
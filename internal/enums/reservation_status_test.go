@@ -0,0 +1,34 @@
+package enums
+
+import "testing"
+
+func TestGetReservationStatusDesc(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   ReservationStatus
+		expected string
+	}{
+		{"Reserved status", ReservationStatusReserved, "已预占"},
+		{"Committed status", ReservationStatusCommitted, "已扣减"},
+		{"Released status", ReservationStatusReleased, "已释放"},
+		{"Unknown status", ReservationStatus(999), "未知状态"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.status.String()
+			if result != tt.expected {
+				t.Errorf("Expected %d.String() to be '%s', but got '%s'", tt.status, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestReservationStatus_IsValid(t *testing.T) {
+	if !ReservationStatusReserved.IsValid() || !ReservationStatusReleased.IsValid() {
+		t.Error("Expected defined reservation statuses to be valid")
+	}
+	if ReservationStatus(-1).IsValid() || ReservationStatus(3).IsValid() {
+		t.Error("Expected out of range reservation statuses to be invalid")
+	}
+}
@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 
 	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/middleware"
+	"github.com/ddteam/drink-master/internal/models"
 	"github.com/ddteam/drink-master/internal/services"
 	"github.com/ddteam/drink-master/pkg/wechat"
 )
@@ -22,20 +26,27 @@ func getStringValue(s *string) string {
 // AccountHandler 账户处理器 (对应MobileAPI AccountController)
 type AccountHandler struct {
 	*BaseHandler
-	memberService *services.MemberService
-	jwtService    *services.JWTService
-	cacheManager  *services.CacheManager
-	wechatClient  *wechat.Client
+	memberService  *services.MemberService
+	tokenService   services.AuthTokenServiceInterface
+	sessionService services.WeChatSessionServiceInterface
+	wechatClient   *wechat.Client
 }
 
 // NewAccountHandler 创建账户处理器
 func NewAccountHandler(db *gorm.DB, wechatClient *wechat.Client) *AccountHandler {
 	return &AccountHandler{
-		BaseHandler:   NewBaseHandler(db),
-		memberService: services.NewMemberServiceCompat(db),
-		jwtService:    services.NewJWTService(),
-		cacheManager:  services.NewCacheManager(),
-		wechatClient:  wechatClient,
+		BaseHandler:    NewBaseHandler(db),
+		memberService:  services.NewMemberServiceCompat(db),
+		tokenService:   services.NewAuthTokenService(db, services.NewJWTService()),
+		sessionService: services.NewWeChatSessionService(db, wechatClient),
+		wechatClient:   wechatClient,
+	}
+}
+
+// saveWeChatSession 保存本次登录的微信会话，失败不影响登录，仅影响之后的加密数据解密
+func (h *AccountHandler) saveWeChatSession(member *models.Member, session *wechat.SessionResponse) {
+	if err := h.sessionService.SaveSession(member, session); err != nil {
+		logrus.WithError(err).WithField("member_id", member.ID).Warn("保存微信会话失败")
 	}
 }
 
@@ -82,16 +93,16 @@ func (h *AccountHandler) CheckUserInfo(c *gin.Context) {
 		return
 	}
 
-	// 生成token
-	token, err := h.jwtService.GenerateToken(member)
+	// 每次 code2session 都会刷新 session_key，保存最新的会话
+	h.saveWeChatSession(member, session)
+
+	// 开启登录会话，签发访问令牌与刷新令牌
+	tokens, err := h.tokenService.IssueTokens(member)
 	if err != nil {
 		h.InternalErrorResponse(c, err)
 		return
 	}
 
-	// 缓存登录状态
-	h.cacheManager.SetLoginStatus(member.ID, token)
-
 	// 返回用户信息
 	response := map[string]interface{}{
 		"success": true,
@@ -99,8 +110,10 @@ func (h *AccountHandler) CheckUserInfo(c *gin.Context) {
 			Id:             member.ID,
 			AvatarUrl:      getStringValue(member.Avatar),
 			Nickname:       getStringValue(member.Nickname),
-			IsMachineOwner: member.Role == 2, // 2 for Owner role
-			Token:          token,
+			IsMachineOwner: member.IsMachineOwner(),
+			Token:          tokens.Token,
+			ExpiresIn:      tokens.ExpiresIn,
+			RefreshToken:   tokens.RefreshToken,
 		},
 	}
 	c.JSON(http.StatusOK, response)
@@ -137,16 +150,16 @@ func (h *AccountHandler) WeChatLogin(c *gin.Context) {
 		return
 	}
 
-	// 生成token
-	token, err := h.jwtService.GenerateToken(member)
+	// 每次 code2session 都会刷新 session_key，保存最新的会话
+	h.saveWeChatSession(member, session)
+
+	// 开启登录会话，签发访问令牌与刷新令牌
+	tokens, err := h.tokenService.IssueTokens(member)
 	if err != nil {
 		h.InternalErrorResponse(c, err)
 		return
 	}
 
-	// 缓存登录状态
-	h.cacheManager.SetLoginStatus(member.ID, token)
-
 	// 返回用户信息
 	response := map[string]interface{}{
 		"success": true,
@@ -154,13 +167,179 @@ func (h *AccountHandler) WeChatLogin(c *gin.Context) {
 			Id:             member.ID,
 			AvatarUrl:      getStringValue(member.Avatar),
 			Nickname:       getStringValue(member.Nickname),
-			IsMachineOwner: member.Role == 2, // 2 for Owner role
-			Token:          token,
+			IsMachineOwner: member.IsMachineOwner(),
+			Token:          tokens.Token,
+			ExpiresIn:      tokens.ExpiresIn,
+			RefreshToken:   tokens.RefreshToken,
 		},
 	}
 	c.JSON(http.StatusOK, response)
 }
 
+// RefreshToken 刷新令牌
+// @Summary 刷新令牌
+// @Description 使用刷新令牌换取新的访问令牌，刷新令牌每次使用后轮换，旧令牌作废
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param request body contracts.RefreshTokenRequest true "刷新令牌请求"
+// @Success 200 {object} contracts.APIResponse{data=contracts.AuthTokenResponse}
+// @Failure 401 {object} contracts.APIResponse
+// @Router /Account/RefreshToken [post]
+func (h *AccountHandler) RefreshToken(c *gin.Context) {
+	var req contracts.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ValidationErrorResponse(c, err)
+		return
+	}
+
+	tokens, err := h.tokenService.Refresh(req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidRefreshToken),
+			errors.Is(err, services.ErrRefreshTokenReused),
+			errors.Is(err, services.ErrMemberRoleChanged):
+			h.ErrorResponse(c, http.StatusUnauthorized, contracts.ErrorCodeTokenRevoked, err.Error())
+		default:
+			h.InternalErrorResponse(c, err)
+		}
+		return
+	}
+
+	h.SuccessResponse(c, tokens)
+}
+
+// Logout 退出登录
+// @Summary 退出登录
+// @Description 吊销当前登录会话，allSessions 为true时退出所有设备
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body contracts.LogoutRequest false "退出登录请求"
+// @Success 200 {object} contracts.APIResponse
+// @Failure 401 {object} contracts.APIResponse
+// @Router /Account/Logout [post]
+func (h *AccountHandler) Logout(c *gin.Context) {
+	memberID, exists := h.GetMemberID(c)
+	if !exists {
+		h.UnauthorizedResponse(c, "无效的用户信息")
+		return
+	}
+
+	var req contracts.LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.ValidationErrorResponse(c, err)
+			return
+		}
+	}
+
+	sessionID, _ := middleware.GetCurrentSessionID(c)
+	if err := h.tokenService.Logout(memberID, sessionID, req.AllSessions); err != nil {
+		h.InternalErrorResponse(c, err)
+		return
+	}
+
+	h.SuccessResponse(c, nil)
+}
+
+// DecryptPhoneNumber 解密微信手机号
+// @Summary 解密微信手机号
+// @Description 使用登录时保存的 session_key 解密 wx.getPhoneNumber 返回的加密数据，会话失效时需重新登录
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body contracts.WeChatEncryptedDataRequest true "加密数据"
+// @Success 200 {object} contracts.APIResponse{data=contracts.WeChatPhoneNumberResponse}
+// @Failure 400 {object} contracts.APIResponse
+// @Router /Account/DecryptPhoneNumber [post]
+func (h *AccountHandler) DecryptPhoneNumber(c *gin.Context) {
+	memberID, req, ok := h.bindEncryptedData(c)
+	if !ok {
+		return
+	}
+
+	phone, err := h.sessionService.DecryptPhoneNumber(memberID, req)
+	if err != nil {
+		h.weChatDataErrorResponse(c, err)
+		return
+	}
+	h.SuccessResponse(c, phone)
+}
+
+// DecryptUserInfo 解密微信用户信息
+// @Summary 解密微信用户信息
+// @Description 使用登录时保存的 session_key 解密 wx.getUserInfo 返回的加密数据，含 UnionID 时关联到当前会员
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body contracts.WeChatEncryptedDataRequest true "加密数据"
+// @Success 200 {object} contracts.APIResponse{data=contracts.WeChatUserInfoResponse}
+// @Failure 400 {object} contracts.APIResponse
+// @Router /Account/DecryptUserInfo [post]
+func (h *AccountHandler) DecryptUserInfo(c *gin.Context) {
+	memberID, req, ok := h.bindEncryptedData(c)
+	if !ok {
+		return
+	}
+
+	info, err := h.sessionService.DecryptUserInfo(memberID, req)
+	if err != nil {
+		h.weChatDataErrorResponse(c, err)
+		return
+	}
+	h.SuccessResponse(c, info)
+}
+
+// bindEncryptedData 获取当前会员并绑定加密数据请求
+func (h *AccountHandler) bindEncryptedData(c *gin.Context) (string, contracts.WeChatEncryptedDataRequest, bool) {
+	var req contracts.WeChatEncryptedDataRequest
+	memberID, exists := h.GetMemberID(c)
+	if !exists {
+		h.UnauthorizedResponse(c, "无效的用户信息")
+		return "", req, false
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ValidationErrorResponse(c, err)
+		return "", req, false
+	}
+	return memberID, req, true
+}
+
+// weChatDataErrorResponse 将解密错误映射为HTTP响应
+func (h *AccountHandler) weChatDataErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrWeChatSessionExpired):
+		// 错误中附带的会员信息不返回给客户端
+		err = services.ErrWeChatSessionExpired
+		h.ErrorResponse(c, http.StatusBadRequest, contracts.ErrorCodeValidation, err.Error())
+	case errors.Is(err, services.ErrWeChatDataMismatch):
+		h.ErrorResponse(c, http.StatusBadRequest, contracts.ErrorCodeValidation, err.Error())
+	default:
+		h.InternalErrorResponse(c, err)
+	}
+}
+
+// JWKS 发布JWT验证公钥
+// @Summary JWT验证公钥
+// @Description 以JWKS格式发布RS256/EdDSA验证公钥（含轮换前仍在验证的公钥），HS256 模式下密钥集为空
+// @Tags Account
+// @Produce json
+// @Success 200 {object} jwtkeys.JSONWebKeySet
+// @Router /.well-known/jwks.json [get]
+func (h *AccountHandler) JWKS(c *gin.Context) {
+	manager, err := middleware.JWTKeyManager()
+	if err != nil {
+		h.InternalErrorResponse(c, err)
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, manager.JWKS())
+}
+
 // CheckLogin 检查登录状态
 // GET /api/Account/CheckLogin (需要Authorization Bearer token)
 func (h *AccountHandler) CheckLogin(c *gin.Context) {
@@ -191,7 +370,7 @@ func (h *AccountHandler) GetUserInfo(c *gin.Context) {
 			Id:             member.ID,
 			AvatarUrl:      getStringValue(member.Avatar),
 			Nickname:       getStringValue(member.Nickname),
-			IsMachineOwner: member.Role == 2, // 2 for Owner role
+			IsMachineOwner: member.IsMachineOwner(),
 		},
 	}
 	c.JSON(http.StatusOK, response)
@@ -37,6 +37,12 @@ func setupAccountTestRouter() (*gin.Engine, *AccountHandler) {
 	router.POST("/api/Account/WeChatLogin", accountHandler.WeChatLogin)
 	router.GET("/api/Account/CheckLogin", accountHandler.CheckLogin)
 	router.GET("/api/Account/GetUserInfo", accountHandler.GetUserInfo)
+	router.POST("/api/Account/RefreshToken", accountHandler.RefreshToken)
+	router.POST("/api/Account/Logout", accountHandler.Logout)
+	router.POST("/api/Account/DecryptPhoneNumber", accountHandler.DecryptPhoneNumber)
+	withMember := func(c *gin.Context) { c.Set("member_id", "test_member_123") }
+	router.POST("/api/Account/Member/DecryptPhoneNumber", withMember, accountHandler.DecryptPhoneNumber)
+	router.POST("/api/Account/Member/DecryptUserInfo", withMember, accountHandler.DecryptUserInfo)
 
 	return router, accountHandler
 }
@@ -259,3 +265,64 @@ func TestAccountHandler_GetUserInfo(t *testing.T) {
 		t.Logf("GetUserInfo returned error status %d with Accept header", w3.Code)
 	}
 }
+
+func TestAccountHandler_RefreshToken(t *testing.T) {
+	router, _ := setupAccountTestRouter()
+
+	// 缺少刷新令牌
+	req, _ := http.NewRequest("POST", "/api/Account/RefreshToken", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for missing refresh token, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	// 未知的刷新令牌
+	req2, _ := http.NewRequest("POST", "/api/Account/RefreshToken", bytes.NewBufferString(`{"refreshToken": "unknown"}`))
+	req2.Header.Set("Content-Type", "application/json")
+	w2 := httptest.NewRecorder()
+	router.ServeHTTP(w2, req2)
+	if w2.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d for unknown refresh token, got %d", http.StatusUnauthorized, w2.Code)
+	}
+}
+
+func TestAccountHandler_Logout(t *testing.T) {
+	router, _ := setupAccountTestRouter()
+
+	// 未经 JWTAuth 设置会员信息
+	req, _ := http.NewRequest("POST", "/api/Account/Logout", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d without member, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestAccountHandler_DecryptEncryptedData(t *testing.T) {
+	router, _ := setupAccountTestRouter()
+	body := `{"encryptedData": "CiyLU1Aw2KjvrjMdj8YKliAjtP4gsMZM", "iv": "r7BXXKkLb8qrSNn05n0qiA=="}`
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		expected int
+	}{
+		{"未经 JWTAuth 设置会员信息", "/api/Account/DecryptPhoneNumber", body, http.StatusUnauthorized},
+		{"缺少加密数据", "/api/Account/Member/DecryptPhoneNumber", `{}`, http.StatusBadRequest},
+		{"未保存微信会话", "/api/Account/Member/DecryptPhoneNumber", body, http.StatusBadRequest},
+		{"未保存微信会话", "/api/Account/Member/DecryptUserInfo", body, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", tt.path, bytes.NewBufferString(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.expected {
+			t.Errorf("%s %s: expected status %d, got %d", tt.name, tt.path, tt.expected, w.Code)
+		}
+	}
+}
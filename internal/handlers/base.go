@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
@@ -8,7 +9,10 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
 	"github.com/ddteam/drink-master/internal/middleware"
+	"github.com/ddteam/drink-master/internal/repositories"
+	"github.com/ddteam/drink-master/internal/services"
 )
 
 // BaseHandler 基础控制器结构 (对应MobileAPI BaseController)
@@ -38,11 +42,68 @@ func (h *BaseHandler) IsMachineOwner(c *gin.Context) bool {
 	return middleware.IsMachineOwner(c)
 }
 
+// HasPermission 检查当前用户Token中是否包含指定权限
+func (h *BaseHandler) HasPermission(c *gin.Context, permission enums.Permission) bool {
+	return middleware.HasPermission(c, permission)
+}
+
+// RequireMachineOwnerID 获取机主账户类接口（我的售货机、机主订单、销售统计、收款账户、退款申请审核）操作的机主ID
+// 这类接口只操作Token中绑定的机主的数据：机主及绑定了机主的管理员使用所绑定的机主，
+// 未绑定机主的管理员没有机主账户，返回403；获取失败时已写入响应
+func (h *BaseHandler) RequireMachineOwnerID(c *gin.Context, action string) (string, bool) {
+	if !h.IsMachineOwner(c) {
+		if role, _ := middleware.GetCurrentMemberRole(c); role == enums.MemberRoleAdmin {
+			h.ForbiddenResponse(c, "管理员账号未绑定机主，无法"+action)
+		} else {
+			h.ForbiddenResponse(c, "您不是机主，无法"+action)
+		}
+		return "", false
+	}
+
+	machineOwnerID, exists := h.GetMachineOwnerID(c)
+	if !exists || machineOwnerID == "" {
+		h.UnauthorizedResponse(c, "无效的机主信息")
+		return "", false
+	}
+	return machineOwnerID, true
+}
+
 // GetCurrentRole 获取当前用户角色
 func (h *BaseHandler) GetCurrentRole(c *gin.Context) (string, bool) {
 	return middleware.GetCurrentRole(c)
 }
 
+// GetAccessScope 从JWT token获取当前用户的授权范围，用于售货机资源归属校验
+func (h *BaseHandler) GetAccessScope(c *gin.Context) (services.AccessScope, bool) {
+	memberID, exists := h.GetMemberID(c)
+	if !exists {
+		return services.AccessScope{}, false
+	}
+	role, exists := middleware.GetCurrentMemberRole(c)
+	if !exists {
+		return services.AccessScope{}, false
+	}
+	machineOwnerID, _ := h.GetMachineOwnerID(c)
+
+	return services.AccessScope{
+		MemberID:       memberID,
+		MachineOwnerID: machineOwnerID,
+		Role:           role,
+	}, true
+}
+
+// machineAccessErrorResponse 售货机资源归属校验失败的响应
+func (h *BaseHandler) machineAccessErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrMachineNotFound), errors.Is(err, services.ErrMaterialSiloNotFound):
+		h.NotFoundResponse(c, err.Error())
+	case errors.Is(err, services.ErrMachineAccessDenied):
+		h.ForbiddenResponse(c, err.Error())
+	default:
+		h.InternalErrorResponse(c, err)
+	}
+}
+
 // SuccessResponse 返回成功响应
 func (h *BaseHandler) SuccessResponse(c *gin.Context, data interface{}) {
 	c.JSON(http.StatusOK, contracts.APIResponse{
@@ -100,8 +161,17 @@ func (h *BaseHandler) ForbiddenResponse(c *gin.Context, message string) {
 	h.ErrorResponse(c, http.StatusForbidden, contracts.ErrorCodeForbidden, message)
 }
 
-// InternalErrorResponse 返回500响应
+// ConflictResponse 返回409响应
+func (h *BaseHandler) ConflictResponse(c *gin.Context, message string) {
+	h.ErrorResponse(c, http.StatusConflict, contracts.ErrorCodeConflict, message)
+}
+
+// InternalErrorResponse 返回500响应，数据已被并发修改时返回409，客户端可刷新后重试
 func (h *BaseHandler) InternalErrorResponse(c *gin.Context, err error) {
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		h.ConflictResponse(c, "数据已被修改，请刷新后重试")
+		return
+	}
 	h.ErrorResponse(c, http.StatusInternalServerError, contracts.ErrorCodeInternalServer, "内部服务器错误")
 }
 
@@ -4,11 +4,15 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/repositories"
 )
 
 func setupBaseTestContext() (*gin.Context, *httptest.ResponseRecorder) {
@@ -105,6 +109,44 @@ func TestBaseHandler_IsMachineOwner(t *testing.T) {
 	}
 }
 
+func TestBaseHandler_RequireMachineOwnerID(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	handler := NewBaseHandler(db)
+
+	tests := []struct {
+		name           string
+		role           string
+		machineOwnerID string
+		expected       int
+		message        string
+	}{
+		{"机主", "Owner", "owner-1", http.StatusOK, ""},
+		{"绑定机主的管理员", "Admin", "owner-1", http.StatusOK, ""},
+		{"未绑定机主的管理员", "Admin", "", http.StatusForbidden, "管理员账号未绑定机主，无法查看订单"},
+		{"运维人员", "Maintainer", "owner-1", http.StatusForbidden, "您不是机主，无法查看订单"},
+		{"缺少机主信息", "Owner", "", http.StatusUnauthorized, "无效的机主信息"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := setupBaseTestContext()
+			c.Set("role", tt.role)
+			c.Set("machine_owner_id", tt.machineOwnerID)
+
+			machineOwnerID, ok := handler.RequireMachineOwnerID(c, "查看订单")
+			if tt.expected == http.StatusOK {
+				if !ok || machineOwnerID != tt.machineOwnerID {
+					t.Errorf("Expected machine owner %q, got %q (ok=%v)", tt.machineOwnerID, machineOwnerID, ok)
+				}
+				return
+			}
+			if ok || w.Code != tt.expected || !strings.Contains(w.Body.String(), tt.message) {
+				t.Errorf("Expected %d %q, got %d %s", tt.expected, tt.message, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
 func TestBaseHandler_GetCurrentRole(t *testing.T) {
 	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	handler := NewBaseHandler(db)
@@ -180,6 +222,23 @@ func TestBaseHandler_InternalErrorResponse(t *testing.T) {
 	}
 }
 
+func TestBaseHandler_InternalErrorResponse_VersionConflict(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	handler := NewBaseHandler(db)
+	c, w := setupBaseTestContext()
+
+	err := fmt.Errorf("failed to update member: %w",
+		&repositories.VersionConflictError{Table: "members", ID: "member-1", Version: 3})
+	handler.InternalErrorResponse(c, err)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), contracts.ErrorCodeConflict) {
+		t.Errorf("Expected error code %s, got %s", contracts.ErrorCodeConflict, w.Body.String())
+	}
+}
+
 func TestBaseHandler_PagingResponse(t *testing.T) {
 	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	handler := NewBaseHandler(db)
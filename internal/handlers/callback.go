@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -8,6 +10,7 @@ import (
 
 	"github.com/ddteam/drink-master/internal/contracts"
 	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
 	"github.com/ddteam/drink-master/internal/services"
 )
 
@@ -15,6 +18,7 @@ import (
 type CallbackHandler struct {
 	orderService   services.OrderService
 	paymentService services.PaymentServiceInterface
+	refundService  services.RefundServiceInterface
 	logger         *logrus.Logger
 }
 
@@ -22,11 +26,13 @@ type CallbackHandler struct {
 func NewCallbackHandler(
 	orderService services.OrderService,
 	paymentService services.PaymentServiceInterface,
+	refundService services.RefundServiceInterface,
 	logger *logrus.Logger,
 ) *CallbackHandler {
 	return &CallbackHandler{
 		orderService:   orderService,
 		paymentService: paymentService,
+		refundService:  refundService,
 		logger:         logger,
 	}
 }
@@ -40,6 +46,7 @@ func NewCallbackHandler(
 // @Param request body contracts.PaymentCallbackResultRequest true "支付回调请求"
 // @Success 200 {string} string "ok"
 // @Failure 400 {string} string "参数错误"
+// @Failure 500 {string} string "处理失败"
 // @Router /Callback/PaymentResult [post]
 func (h *CallbackHandler) PaymentResult(c *gin.Context) {
 	var request contracts.PaymentCallbackResultRequest
@@ -51,25 +58,169 @@ func (h *CallbackHandler) PaymentResult(c *gin.Context) {
 
 	h.logger.WithField("request", request).Info("支付结果回调")
 
+	status, message := h.applyPaymentResult(c, request, func(order *models.Order) error {
+		return h.verifyCallback(order, request)
+	})
+	c.String(status, message)
+}
+
+// PaymentNotify 支付渠道原生异步通知
+// @Summary 支付渠道异步通知接口
+// @Description 接收支付渠道（如微信支付API v3）推送的签名通知，验签解密后按支付结果处理
+// @Tags Callback
+// @Accept json
+// @Produce json
+// @Param channelCode path string true "支付渠道编码"
+// @Param merchantId path string false "商户号，下单时追加到通知地址"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /Callback/PaymentNotify/{channelCode}/{merchantId} [post]
+func (h *CallbackHandler) PaymentNotify(c *gin.Context) {
+	channelCode := c.Param("channelCode")
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		h.logger.WithError(err).Error("读取支付通知失败")
+		c.JSON(http.StatusBadRequest, gin.H{"code": "FAIL", "message": "读取通知失败"})
+		return
+	}
+
+	request, err := h.paymentService.ParseNotify(channelCode, c.Param("merchantId"), c.Request.Header, body)
+	if err != nil {
+		h.auditRejected(c, contracts.PaymentCallbackResultRequest{ChannelCode: channelCode}, err)
+		c.JSON(http.StatusBadRequest, gin.H{"code": "FAIL", "message": "验签失败"})
+		return
+	}
+
+	h.logger.WithField("request", request).Info("支付结果通知")
+
+	status, message := h.applyPaymentResult(c, *request, func(order *models.Order) error {
+		// 通知已由渠道验签，只需确认订单确实绑定在该渠道上
+		if order.ChannelCode == nil || *order.ChannelCode != channelCode {
+			return errors.New("order is not bound to notifying channel")
+		}
+		return nil
+	})
+	if status != http.StatusOK {
+		c.JSON(status, gin.H{"code": "FAIL", "message": message})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": "SUCCESS", "message": message})
+}
+
+// RefundResult 退款结果回调
+// @Summary 退款结果回调接口
+// @Description 第三方支付平台回调退款结果的接口
+// @Tags Callback
+// @Accept json
+// @Produce plain
+// @Param request body contracts.RefundCallbackRequest true "退款回调请求"
+// @Success 200 {string} string "ok"
+// @Failure 400 {string} string "参数错误"
+// @Router /Callback/RefundResult [post]
+func (h *CallbackHandler) RefundResult(c *gin.Context) {
+	var request contracts.RefundCallbackRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("退款回调参数解析失败")
+		c.String(http.StatusBadRequest, "参数错误")
+		return
+	}
+
+	h.logger.WithField("request", request).Info("退款结果回调")
+
+	status, message := h.refundResultStatus(c, request, h.refundService.HandleRefundCallback(request))
+	c.String(status, message)
+}
+
+// RefundNotify 支付渠道原生退款结果通知
+// @Summary 支付渠道退款通知接口
+// @Description 接收支付渠道（如微信支付API v3）推送的签名退款通知，验签解密后按退款结果处理
+// @Tags Callback
+// @Accept json
+// @Produce json
+// @Param channelCode path string true "支付渠道编码"
+// @Param merchantId path string false "商户号，申请退款时追加到通知地址"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /Callback/RefundNotify/{channelCode}/{merchantId} [post]
+func (h *CallbackHandler) RefundNotify(c *gin.Context) {
+	channelCode := c.Param("channelCode")
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		h.logger.WithError(err).Error("读取退款通知失败")
+		c.JSON(http.StatusBadRequest, gin.H{"code": "FAIL", "message": "读取通知失败"})
+		return
+	}
+
+	request := contracts.RefundCallbackRequest{ChannelCode: channelCode}
+	status, message := h.refundResultStatus(c, request,
+		h.refundService.HandleRefundNotify(channelCode, c.Param("merchantId"), c.Request.Header, body))
+	if status != http.StatusOK {
+		c.JSON(status, gin.H{"code": "FAIL", "message": message})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": "SUCCESS", "message": message})
+}
+
+// refundResultStatus 将退款结果处理结果转换为响应状态码与消息
+// 处理异常时返回500，由渠道重新投递回调，重复回调不会重复处理
+func (h *CallbackHandler) refundResultStatus(
+	c *gin.Context, request contracts.RefundCallbackRequest, err error,
+) (int, string) {
+	switch {
+	case err == nil:
+		return http.StatusOK, "ok"
+	case errors.Is(err, services.ErrRefundRecordNotFound):
+		h.logger.WithField("refund_no", request.RefundNo).Warn("退款记录不存在")
+		return http.StatusOK, "退款记录不存在"
+	case errors.Is(err, services.ErrRefundCallbackRejected):
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"audit":        "refund_callback_rejected",
+			"client_ip":    c.ClientIP(),
+			"path":         c.Request.URL.Path,
+			"channel_code": request.ChannelCode,
+			"order_no":     request.OrderNo,
+			"refund_no":    request.RefundNo,
+			"refund_amt":   request.RefundAmt,
+		}).Warn("拒绝退款回调")
+		return http.StatusBadRequest, "回调校验失败"
+	default:
+		h.logger.WithError(err).WithField("refund_no", request.RefundNo).Error("处理退款回调异常")
+		return http.StatusInternalServerError, "处理失败"
+	}
+}
+
+// applyPaymentResult 校验并处理支付结果，返回响应状态码与消息
+func (h *CallbackHandler) applyPaymentResult(
+	c *gin.Context, request contracts.PaymentCallbackResultRequest, verify func(order *models.Order) error,
+) (int, string) {
 	// 根据订单号查找订单
 	order, err := h.orderService.GetByOrderNo(request.OrderNo)
 	if err != nil {
 		h.logger.WithError(err).Error("查询订单失败")
-		c.String(http.StatusOK, "查询订单失败")
-		return
+		return http.StatusInternalServerError, "查询订单失败"
 	}
 
 	if order == nil {
 		h.logger.Warn("订单不存在")
-		c.String(http.StatusOK, "订单不存在")
-		return
+		return http.StatusOK, "订单不存在"
+	}
+
+	// 由订单所属支付渠道校验回调，校验失败的回调不做处理
+	if verifyErr := verify(order); verifyErr != nil {
+		h.auditRejected(c, request, verifyErr)
+		return http.StatusBadRequest, "回调校验失败"
 	}
 
-	// 如果不是未支付状态，则不处理
-	if order.PaymentStatus != int(enums.PaymentStatusWaitPay) {
+	// 核对回调金额与订单应付金额
+	if amountErr := services.ReconcileCallbackAmount(order, request.TransAmt); amountErr != nil {
+		h.auditRejected(c, request, amountErr)
+		return http.StatusBadRequest, "回调金额不符"
+	}
+
+	// 已支付或已退款的订单不再处理；已作废的订单收到支付时由 PayOrder 补记并自动退款
+	if order.PaymentStatus != int(enums.PaymentStatusWaitPay) && order.PaymentStatus != int(enums.PaymentStatusInvalid) {
 		h.logger.Info("订单已处理")
-		c.String(http.StatusOK, "ok")
-		return
+		return http.StatusOK, "ok"
 	}
 
 	// 处理支付成功
@@ -79,13 +230,45 @@ func (h *CallbackHandler) PaymentResult(c *gin.Context) {
 		PaidAt:         request.PaymentTime,
 	}
 
-	err = h.paymentService.PayOrder(payOrderReq)
-	if err != nil {
+	// 入账失败时返回500，由渠道重新投递回调；已支付的订单在上方状态检查中直接应答
+	if err := h.paymentService.PayOrder(payOrderReq); err != nil {
 		h.logger.WithError(err).WithField("request", request).Error("处理支付回调异常")
-		// 这里即使失败也返回ok，避免第三方重复回调
-		c.String(http.StatusOK, "ok")
-		return
+		return http.StatusInternalServerError, "处理失败"
+	}
+
+	return http.StatusOK, "ok"
+}
+
+// verifyCallback 按订单绑定的渠道校验支付回调
+func (h *CallbackHandler) verifyCallback(order *models.Order, request contracts.PaymentCallbackResultRequest) error {
+	if order.MachineId == nil {
+		return errors.New("order has no machine")
 	}
+	account, err := h.paymentService.GetPaymentAccount(*order.MachineId)
+	if err != nil {
+		return err
+	}
+
+	orderChannel := ""
+	if order.ChannelCode != nil {
+		orderChannel = *order.ChannelCode
+	}
+	channelCode, err := services.ResolveChannelCode(orderChannel, account)
+	if err != nil {
+		return err
+	}
+	return h.paymentService.VerifyCallback(channelCode, *account, request)
+}
 
-	c.String(http.StatusOK, "ok")
+// auditRejected 记录被拒绝的支付回调，供安全审计追查伪造或篡改的请求
+func (h *CallbackHandler) auditRejected(c *gin.Context, request contracts.PaymentCallbackResultRequest, reason error) {
+	h.logger.WithError(reason).WithFields(logrus.Fields{
+		"audit":            "payment_callback_rejected",
+		"client_ip":        c.ClientIP(),
+		"path":             c.Request.URL.Path,
+		"channel_code":     request.ChannelCode,
+		"order_no":         request.OrderNo,
+		"channel_order_no": request.ChannelOrderNo,
+		"trans_amt":        request.TransAmt,
+	}).Warn("拒绝支付回调")
 }
@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+	"github.com/ddteam/drink-master/internal/services"
+)
+
+const (
+	testReceivingKey     = "test_receiving_key"
+	testPaymentSecretKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+)
+
+func setupCallbackTestRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+	os.Unsetenv("MOCK_MODE")
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.AutoMigrate(db))
+
+	// 机主收款账户经服务加密保存
+	t.Setenv("PAYMENT_SECRET_KEY", testPaymentSecretKey)
+	require.NoError(t, db.Create(&models.MachineOwner{ID: "owner123"}).Error)
+	require.NoError(t, services.NewPaymentAccountService(db).Update("owner123", contracts.UpdatePaymentAccountRequest{
+		ChannelCode:      contracts.ChannelCodeFuiouMerchant,
+		ReceivingAccount: "0002900F0001",
+		ReceivingKey:     testReceivingKey,
+	}))
+	require.NoError(t, db.Create(&models.Machine{
+		ID: "machine123", MachineNo: stringPtr("VM001"), MachineOwnerId: stringPtr("owner123"),
+	}).Error)
+	require.NoError(t, db.Create(&models.Order{
+		ID:            "order123",
+		OrderNo:       stringPtr("ORD20240813001"),
+		MachineId:     stringPtr("machine123"),
+		PayAmount:     10.50,
+		PaymentStatus: int(enums.PaymentStatusWaitPay),
+		ChannelCode:   stringPtr(contracts.ChannelCodeFuiouMerchant),
+	}).Error)
+
+	paymentService := services.NewPaymentService(db)
+	refundService := services.NewRefundService(db, paymentService)
+	orderService := services.NewOrderService(
+		repositories.NewOrderRepository(db),
+		repositories.NewMachineRepository(db),
+		repositories.NewMemberRepository(db),
+		repositories.NewProductRepository(db),
+		services.NewDeviceService(),
+		refundService,
+		nil,
+	)
+	handler := NewCallbackHandler(orderService, paymentService, refundService, logrus.New())
+
+	router := gin.New()
+	router.POST("/api/Callback/PaymentResult", handler.PaymentResult)
+	router.POST("/api/Callback/RefundResult", handler.RefundResult)
+	router.POST("/api/Callback/RefundNotify/:channelCode", handler.RefundNotify)
+	return router, db
+}
+
+func postPaymentResult(router *gin.Engine, request contracts.PaymentCallbackResultRequest) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(request)
+	req, _ := http.NewRequest(http.MethodPost, "/api/Callback/PaymentResult", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCallbackHandler_PaymentResult_DispatchByOrderChannel(t *testing.T) {
+	router, db := setupCallbackTestRouter(t)
+
+	w := postPaymentResult(router, signedPaymentResult(1050))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok", w.Body.String())
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", "order123").Error)
+	assert.Equal(t, int(enums.PaymentStatusPaid), order.PaymentStatus)
+	assert.Equal(t, "4200000001", *order.ChannelOrderNo)
+}
+
+func TestCallbackHandler_PaymentResult_PayOrderFailed(t *testing.T) {
+	router, db := setupCallbackTestRouter(t)
+
+	// 订单入账失败时不应答成功，渠道重新投递后完成入账
+	require.NoError(t, db.Migrator().DropTable(&models.OutboxEvent{}))
+	w := postPaymentResult(router, signedPaymentResult(1050))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", "order123").Error)
+	assert.Equal(t, int(enums.PaymentStatusWaitPay), order.PaymentStatus)
+
+	require.NoError(t, db.AutoMigrate(&models.OutboxEvent{}))
+	w = postPaymentResult(router, signedPaymentResult(1050))
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, db.First(&order, "Id = ?", "order123").Error)
+	assert.Equal(t, int(enums.PaymentStatusPaid), order.PaymentStatus)
+}
+
+func TestCallbackHandler_PaymentResult_InvalidatedOrderRefunds(t *testing.T) {
+	router, db := setupCallbackTestRouter(t)
+	require.NoError(t, db.Model(&models.Order{}).Where("Id = ?", "order123").
+		Update("PaymentStatus", int(enums.PaymentStatusInvalid)).Error)
+
+	// 已作废订单收到支付时补记支付并发起自动退款，不再直接应答忽略
+	w := postPaymentResult(router, signedPaymentResult(1050))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", "order123").Error)
+	assert.Equal(t, int(enums.PaymentStatusRefunding), order.PaymentStatus)
+	assert.Equal(t, "4200000001", *order.ChannelOrderNo)
+
+	var records []models.RefundRecord
+	require.NoError(t, db.Find(&records, "OrderId = ?", "order123").Error)
+	require.Len(t, records, 1)
+	assert.Equal(t, int(enums.RefundStatusPending), records[0].Status)
+
+	// 重复投递直接应答
+	w = postPaymentResult(router, signedPaymentResult(1050))
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, db.Find(&records, "OrderId = ?", "order123").Error)
+	assert.Len(t, records, 1)
+}
+
+// signedPaymentResult 构造使用机主收款密钥签名的支付结果回调
+func signedPaymentResult(transAmt int) contracts.PaymentCallbackResultRequest {
+	request := contracts.PaymentCallbackResultRequest{
+		ChannelCode:    contracts.ChannelCodeFuiouMerchant,
+		TransAmt:       transAmt,
+		OrderNo:        "ORD20240813001",
+		ChannelOrderNo: "4200000001",
+		PaymentTime:    time.Now(),
+	}
+	request.Sign = services.SignCallback(request, testReceivingKey)
+	return request
+}
+
+func TestCallbackHandler_PaymentResult_Rejected(t *testing.T) {
+	forged := signedPaymentResult(1050)
+	forged.Sign = services.SignCallback(forged, "forged_key")
+	unsigned := signedPaymentResult(1050)
+	unsigned.Sign = ""
+
+	tests := []struct {
+		name    string
+		request contracts.PaymentCallbackResultRequest
+	}{
+		{name: "伪造签名", request: forged},
+		{name: "缺少签名", request: unsigned},
+		{name: "金额不符", request: signedPaymentResult(1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, db := setupCallbackTestRouter(t)
+
+			w := postPaymentResult(router, tt.request)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var order models.Order
+			require.NoError(t, db.First(&order, "Id = ?", "order123").Error)
+			assert.Equal(t, int(enums.PaymentStatusWaitPay), order.PaymentStatus)
+			assert.Nil(t, order.ChannelOrderNo)
+		})
+	}
+}
+
+func TestCallbackHandler_PaymentResult_ChannelMismatch(t *testing.T) {
+	router, db := setupCallbackTestRouter(t)
+
+	w := postPaymentResult(router, contracts.PaymentCallbackResultRequest{
+		ChannelCode:    contracts.ChannelCodeMock,
+		TransAmt:       1050,
+		OrderNo:        "ORD20240813001",
+		ChannelOrderNo: "forged",
+		PaymentTime:    time.Now(),
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", "order123").Error)
+	assert.Equal(t, int(enums.PaymentStatusWaitPay), order.PaymentStatus)
+}
+
+func TestCallbackHandler_PaymentResult_MockChannelOutsideMockMode(t *testing.T) {
+	t.Setenv("MOCK_MODE", "")
+	router, db := setupCallbackTestRouter(t)
+	require.NoError(t, db.Model(&models.Order{}).Where("Id = ?", "order123").
+		Update("ChannelCode", contracts.ChannelCodeMock).Error)
+
+	// 模拟渠道不校验签名，非 MOCK_MODE 下绑定模拟渠道的订单也不接受回调
+	w := postPaymentResult(router, contracts.PaymentCallbackResultRequest{
+		ChannelCode:    contracts.ChannelCodeMock,
+		TransAmt:       1050,
+		OrderNo:        "ORD20240813001",
+		ChannelOrderNo: "forged",
+		PaymentTime:    time.Now(),
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", "order123").Error)
+	assert.Equal(t, int(enums.PaymentStatusWaitPay), order.PaymentStatus)
+}
+
+func TestCallbackHandler_PaymentNotify_Rejected(t *testing.T) {
+	router, db := setupCallbackTestRouter(t)
+	handler := NewCallbackHandler(nil, services.NewPaymentService(db), nil, logrus.New())
+	router.POST("/api/Callback/PaymentNotify/:channelCode", handler.PaymentNotify)
+
+	// 未验签通过的通知不处理订单
+	req, _ := http.NewRequest(http.MethodPost, "/api/Callback/PaymentNotify/"+contracts.ChannelCodeWeChatPayV3,
+		bytes.NewReader([]byte(`{"id":"EV-001","event_type":"TRANSACTION.SUCCESS"}`)))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "FAIL")
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", "order123").Error)
+	assert.Equal(t, int(enums.PaymentStatusWaitPay), order.PaymentStatus)
+}
+
+// setupRefundingOrder 将测试订单置为已支付并有一笔等待渠道确认的全额退款
+func setupRefundingOrder(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	require.NoError(t, db.Model(&models.Order{}).Where("Id = ?", "order123").
+		Update("PaymentStatus", int(enums.PaymentStatusRefunding)).Error)
+	require.NoError(t, db.Create(&models.RefundRecord{
+		ID:       "refund123",
+		OrderId:  "order123",
+		RefundNo: "RFORD2024081300101",
+		Amount:   10.50,
+		Status:   int(enums.RefundStatusAccepted),
+	}).Error)
+}
+
+// signedRefundResult 构造使用机主收款密钥签名的退款结果回调
+func signedRefundResult(status string, refundAmt int) contracts.RefundCallbackRequest {
+	request := contracts.RefundCallbackRequest{
+		ChannelCode:     contracts.ChannelCodeFuiouMerchant,
+		OrderNo:         "ORD20240813001",
+		RefundNo:        "RFORD2024081300101",
+		ChannelRefundNo: "5030000001",
+		RefundAmt:       refundAmt,
+		RefundStatus:    status,
+		RefundTime:      time.Now(),
+	}
+	request.Sign = services.SignRefundCallback(request, testReceivingKey)
+	return request
+}
+
+func postRefundResult(router *gin.Engine, request contracts.RefundCallbackRequest) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(request)
+	req, _ := http.NewRequest(http.MethodPost, "/api/Callback/RefundResult", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCallbackHandler_RefundResult(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       string
+		refundStatus enums.RefundStatus
+		orderStatus  enums.PaymentStatus
+		refundAmount float64
+	}{
+		{"退款成功", contracts.RefundResultSuccess, enums.RefundStatusSuccess, enums.PaymentStatusRefunded, 10.50},
+		{"退款失败", contracts.RefundResultFailure, enums.RefundStatusFailed, enums.PaymentStatusPaid, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, db := setupCallbackTestRouter(t)
+			setupRefundingOrder(t, db)
+
+			request := signedRefundResult(tt.status, 1050)
+			w := postRefundResult(router, request)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "ok", w.Body.String())
+
+			// 重复回调不会重复处理
+			w = postRefundResult(router, request)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var record models.RefundRecord
+			require.NoError(t, db.First(&record, "Id = ?", "refund123").Error)
+			assert.Equal(t, int(tt.refundStatus), record.Status)
+
+			var order models.Order
+			require.NoError(t, db.First(&order, "Id = ?", "order123").Error)
+			assert.Equal(t, int(tt.orderStatus), order.PaymentStatus)
+			assert.Equal(t, tt.refundAmount, order.RefundAmount)
+		})
+	}
+}
+
+func TestCallbackHandler_RefundResult_Rejected(t *testing.T) {
+	forged := signedRefundResult(contracts.RefundResultSuccess, 1050)
+	forged.Sign = services.SignRefundCallback(forged, "forged_key")
+	otherOrder := signedRefundResult(contracts.RefundResultSuccess, 1050)
+	otherOrder.OrderNo = "ORD20240813002"
+	otherOrder.Sign = services.SignRefundCallback(otherOrder, testReceivingKey)
+
+	tests := []struct {
+		name    string
+		request contracts.RefundCallbackRequest
+	}{
+		{name: "伪造签名", request: forged},
+		{name: "金额不符", request: signedRefundResult(contracts.RefundResultSuccess, 1)},
+		{name: "订单不符", request: otherOrder},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, db := setupCallbackTestRouter(t)
+			setupRefundingOrder(t, db)
+
+			w := postRefundResult(router, tt.request)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var record models.RefundRecord
+			require.NoError(t, db.First(&record, "Id = ?", "refund123").Error)
+			assert.Equal(t, int(enums.RefundStatusAccepted), record.Status)
+
+			var order models.Order
+			require.NoError(t, db.First(&order, "Id = ?", "order123").Error)
+			assert.Equal(t, int(enums.PaymentStatusRefunding), order.PaymentStatus)
+		})
+	}
+}
+
+func TestCallbackHandler_RefundNotify_Rejected(t *testing.T) {
+	router, db := setupCallbackTestRouter(t)
+	setupRefundingOrder(t, db)
+
+	// 未验签通过的退款通知不处理退款
+	req, _ := http.NewRequest(http.MethodPost, "/api/Callback/RefundNotify/"+contracts.ChannelCodeWeChatPayV3,
+		bytes.NewReader([]byte(`{"id":"EV-002","event_type":"REFUND.SUCCESS"}`)))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "FAIL")
+
+	var record models.RefundRecord
+	require.NoError(t, db.First(&record, "Id = ?", "refund123").Error)
+	assert.Equal(t, int(enums.RefundStatusAccepted), record.Status)
+}
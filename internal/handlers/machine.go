@@ -14,6 +14,7 @@ import (
 type MachineHandler struct {
 	*BaseHandler
 	machineService services.MachineServiceInterface
+	accessGuard    services.MachineAccessGuardInterface
 }
 
 // NewMachineHandler 创建售货机处理器
@@ -21,6 +22,7 @@ func NewMachineHandler(db *gorm.DB) *MachineHandler {
 	return &MachineHandler{
 		BaseHandler:    NewBaseHandler(db),
 		machineService: services.NewMachineService(db),
+		accessGuard:    services.NewMachineAccessGuard(db),
 	}
 }
 
@@ -132,16 +134,9 @@ func (h *MachineHandler) GetProductList(c *gin.Context) {
 // @Security BearerAuth
 // @Router /Machine/GetPaging [post]
 func (h *MachineHandler) GetPaging(c *gin.Context) {
-	// 检查机主权限
-	if !h.IsMachineOwner(c) {
-		h.ForbiddenResponse(c, "machine owner permission required")
-		return
-	}
-
 	// 获取机主ID
-	machineOwnerID, exists := h.GetMachineOwnerID(c)
-	if !exists {
-		h.UnauthorizedResponse(c, "machine owner id not found")
+	machineOwnerID, ok := h.RequireMachineOwnerID(c, "查看售货机列表")
+	if !ok {
 		return
 	}
 
@@ -175,16 +170,9 @@ func (h *MachineHandler) GetPaging(c *gin.Context) {
 // @Security BearerAuth
 // @Router /Machine/GetList [get]
 func (h *MachineHandler) GetList(c *gin.Context) {
-	// 检查机主权限
-	if !h.IsMachineOwner(c) {
-		h.ForbiddenResponse(c, "machine owner permission required")
-		return
-	}
-
 	// 获取机主ID
-	machineOwnerID, exists := h.GetMachineOwnerID(c)
-	if !exists {
-		h.UnauthorizedResponse(c, "machine owner id not found")
+	machineOwnerID, ok := h.RequireMachineOwnerID(c, "查看售货机列表")
+	if !ok {
 		return
 	}
 
@@ -212,24 +200,27 @@ func (h *MachineHandler) GetList(c *gin.Context) {
 // @Security BearerAuth
 // @Router /Machine/OpenOrClose [get]
 func (h *MachineHandler) OpenOrCloseBusiness(c *gin.Context) {
-	// 检查机主权限
-	if !h.IsMachineOwner(c) {
-		h.ForbiddenResponse(c, "machine owner permission required")
+	var req contracts.OpenOrCloseBusinessRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.ValidationErrorResponse(c, err)
 		return
 	}
 
-	// 获取机主ID
-	machineOwnerID, exists := h.GetMachineOwnerID(c)
+	scope, exists := h.GetAccessScope(c)
 	if !exists {
-		h.UnauthorizedResponse(c, "machine owner id not found")
+		h.UnauthorizedResponse(c, "member id not found")
 		return
 	}
-
-	var req contracts.OpenOrCloseBusinessRequest
-	if err := c.ShouldBindQuery(&req); err != nil {
-		h.ValidationErrorResponse(c, err)
+	// 按售货机归属授权，管理员可操作所有售货机
+	machine, err := h.accessGuard.AuthorizeMachine(scope, req.ID)
+	if err != nil {
+		h.machineAccessErrorResponse(c, err)
 		return
 	}
+	machineOwnerID := ""
+	if machine.MachineOwnerId != nil {
+		machineOwnerID = *machine.MachineOwnerId
+	}
 
 	result, err := h.machineService.OpenOrCloseBusiness(req.ID, machineOwnerID)
 	if err != nil {
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
@@ -14,14 +15,16 @@ import (
 // MachineOwnerHandler 机主控制器 (对应VendingMachine.MobileAPI MachineOwnerController)
 type MachineOwnerHandler struct {
 	*BaseHandler
-	machineOwnerService *services.MachineOwnerService
+	machineOwnerService   *services.MachineOwnerService
+	paymentAccountService services.PaymentAccountServiceInterface
 }
 
 // NewMachineOwnerHandler 创建机主控制器
 func NewMachineOwnerHandler(db *gorm.DB) *MachineOwnerHandler {
 	return &MachineOwnerHandler{
-		BaseHandler:         NewBaseHandler(db),
-		machineOwnerService: services.NewMachineOwnerService(db),
+		BaseHandler:           NewBaseHandler(db),
+		machineOwnerService:   services.NewMachineOwnerService(db),
+		paymentAccountService: services.NewPaymentAccountService(db),
 	}
 }
 
@@ -40,16 +43,9 @@ func NewMachineOwnerHandler(db *gorm.DB) *MachineOwnerHandler {
 // @Security Bearer
 // 对应原方法: Task<List<ColumnModel>> GetSalesAsync([FromQuery] DateTime? dateTime)
 func (h *MachineOwnerHandler) GetSales(c *gin.Context) {
-	// 验证是否为机主
-	if !h.IsMachineOwner(c) {
-		h.ForbiddenResponse(c, "您不是机主，无法查看机器列表")
-		return
-	}
-
 	// 获取机主ID
-	machineOwnerID, exists := h.GetMachineOwnerID(c)
-	if !exists || machineOwnerID == "" {
-		h.UnauthorizedResponse(c, "无效的机主信息")
+	machineOwnerID, ok := h.RequireMachineOwnerID(c, "查看销售数据")
+	if !ok {
 		return
 	}
 
@@ -105,16 +101,9 @@ func (h *MachineOwnerHandler) GetSales(c *gin.Context) {
 // @Router /MachineOwner/GetSalesStats [get]
 // @Security Bearer
 func (h *MachineOwnerHandler) GetSalesStats(c *gin.Context) {
-	// 验证是否为机主
-	if !h.IsMachineOwner(c) {
-		h.ForbiddenResponse(c, "您不是机主，无法查看统计数据")
-		return
-	}
-
 	// 获取机主ID
-	machineOwnerID, exists := h.GetMachineOwnerID(c)
-	if !exists || machineOwnerID == "" {
-		h.UnauthorizedResponse(c, "无效的机主信息")
+	machineOwnerID, ok := h.RequireMachineOwnerID(c, "查看统计数据")
+	if !ok {
 		return
 	}
 
@@ -160,3 +149,108 @@ func (h *MachineOwnerHandler) GetSalesStats(c *gin.Context) {
 
 	h.SuccessResponse(c, stats)
 }
+
+// GetPaymentAccount 获取收款账户
+// @Summary 获取机主收款账户
+// @Description 获取机主的支付渠道与商户号，收款密钥不返回
+// @Tags MachineOwner
+// @Produce json
+// @Success 200 {object} contracts.APIResponse{data=contracts.OwnerPaymentAccountResponse}
+// @Failure 401 {object} contracts.APIResponse
+// @Failure 403 {object} contracts.APIResponse
+// @Failure 404 {object} contracts.APIResponse
+// @Router /MachineOwner/GetPaymentAccount [get]
+// @Security Bearer
+func (h *MachineOwnerHandler) GetPaymentAccount(c *gin.Context) {
+	machineOwnerID, ok := h.currentMachineOwner(c)
+	if !ok {
+		return
+	}
+
+	account, err := h.paymentAccountService.Get(machineOwnerID)
+	if err != nil {
+		h.paymentAccountErrorResponse(c, err)
+		return
+	}
+	h.SuccessResponse(c, account)
+}
+
+// UpdatePaymentAccount 设置收款账户
+// @Summary 设置机主收款账户
+// @Description 设置机主的支付渠道、商户号与收款密钥，收款密钥加密存储，留空时保留原密钥
+// @Tags MachineOwner
+// @Accept json
+// @Produce json
+// @Param request body contracts.UpdatePaymentAccountRequest true "收款账户"
+// @Success 200 {object} contracts.APIResponse
+// @Failure 400 {object} contracts.APIResponse
+// @Failure 401 {object} contracts.APIResponse
+// @Failure 403 {object} contracts.APIResponse
+// @Router /MachineOwner/UpdatePaymentAccount [post]
+// @Security Bearer
+func (h *MachineOwnerHandler) UpdatePaymentAccount(c *gin.Context) {
+	machineOwnerID, ok := h.currentMachineOwner(c)
+	if !ok {
+		return
+	}
+
+	var req contracts.UpdatePaymentAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ValidationErrorResponse(c, err)
+		return
+	}
+
+	if err := h.paymentAccountService.Update(machineOwnerID, req); err != nil {
+		h.paymentAccountErrorResponse(c, err)
+		return
+	}
+	h.SuccessResponse(c, nil)
+}
+
+// TestPaymentAccount 测试收款账户
+// @Summary 测试机主收款账户
+// @Description 使用已保存的收款账户向支付渠道发起校验请求，确认商户号与密钥可用
+// @Tags MachineOwner
+// @Produce json
+// @Success 200 {object} contracts.APIResponse{data=contracts.TestPaymentAccountResponse}
+// @Failure 400 {object} contracts.APIResponse
+// @Failure 401 {object} contracts.APIResponse
+// @Failure 403 {object} contracts.APIResponse
+// @Router /MachineOwner/TestPaymentAccount [post]
+// @Security Bearer
+func (h *MachineOwnerHandler) TestPaymentAccount(c *gin.Context) {
+	machineOwnerID, ok := h.currentMachineOwner(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.paymentAccountService.Test(machineOwnerID)
+	if err != nil {
+		h.paymentAccountErrorResponse(c, err)
+		return
+	}
+	h.SuccessResponse(c, result)
+}
+
+// currentMachineOwner 校验当前用户为机主并返回机主ID，校验失败时已写入响应
+func (h *MachineOwnerHandler) currentMachineOwner(c *gin.Context) (string, bool) {
+	return h.RequireMachineOwnerID(c, "管理收款账户")
+}
+
+// paymentAccountErrorResponse 收款账户服务错误转换为响应
+func (h *MachineOwnerHandler) paymentAccountErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrMachineOwnerNotFound):
+		h.NotFoundResponse(c, "机主不存在")
+	case errors.Is(err, services.ErrPaymentAccountNotFound):
+		h.ErrorResponse(c, http.StatusBadRequest, contracts.ErrorCodePaymentAccountNotFound, "尚未配置收款账户")
+	case errors.Is(err, services.ErrReceivingKeyRequired):
+		h.ErrorResponse(c, http.StatusBadRequest, contracts.ErrorCodeValidation, "请填写收款密钥")
+	case errors.Is(err, services.ErrInvalidMerchantCredentials):
+		h.ErrorResponse(c, http.StatusBadRequest, contracts.ErrorCodeValidation, "商户证书序列号或私钥无效")
+	case errors.Is(err, services.ErrUnsupportedPaymentChannel):
+		h.ErrorResponse(c, http.StatusBadRequest, contracts.ErrorCodeValidation, "不支持的支付渠道")
+	default:
+		h.InternalErrorResponse(c, err)
+	}
+}
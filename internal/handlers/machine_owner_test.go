@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/pkg/secret"
+)
+
+func setupMachineOwnerTestRouter(t *testing.T, role string) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("PAYMENT_SECRET_KEY", testPaymentSecretKey)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.AutoMigrate(db))
+	require.NoError(t, db.Create(&models.MachineOwner{ID: "owner123"}).Error)
+
+	handler := NewMachineOwnerHandler(db)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("role", role)
+		c.Set("machine_owner_id", "owner123")
+	})
+	router.GET("/api/MachineOwner/GetPaymentAccount", handler.GetPaymentAccount)
+	router.POST("/api/MachineOwner/UpdatePaymentAccount", handler.UpdatePaymentAccount)
+	router.POST("/api/MachineOwner/TestPaymentAccount", handler.TestPaymentAccount)
+	return router, db
+}
+
+func serveMachineOwner(router *gin.Engine, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	req, _ := http.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMachineOwnerHandler_PaymentAccount(t *testing.T) {
+	os.Setenv("MOCK_MODE", "true")
+	defer os.Unsetenv("MOCK_MODE")
+	router, db := setupMachineOwnerTestRouter(t, "Owner")
+
+	// 未配置收款账户时无法测试
+	w := serveMachineOwner(router, http.MethodPost, "/api/MachineOwner/TestPaymentAccount", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), contracts.ErrorCodePaymentAccountNotFound)
+
+	w = serveMachineOwner(router, http.MethodPost, "/api/MachineOwner/UpdatePaymentAccount",
+		contracts.UpdatePaymentAccountRequest{
+			ChannelCode:          contracts.ChannelCodeFuiouMerchant,
+			ReceivingAccount:     "0002900F0001",
+			ReceivingKey:         testReceivingKey,
+			ReceivingOrderPrefix: "1066",
+		})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// 收款密钥加密存储
+	var owner models.MachineOwner
+	require.NoError(t, db.First(&owner, "Id = ?", "owner123").Error)
+	assert.True(t, secret.IsEncrypted(*owner.ReceivingKey))
+
+	// 查询结果不包含收款密钥
+	w = serveMachineOwner(router, http.MethodGet, "/api/MachineOwner/GetPaymentAccount", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"hasReceivingKey":true`)
+	assert.NotContains(t, w.Body.String(), testReceivingKey)
+	assert.NotContains(t, w.Body.String(), *owner.ReceivingKey)
+
+	w = serveMachineOwner(router, http.MethodPost, "/api/MachineOwner/TestPaymentAccount", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"success":true`)
+}
+
+func TestMachineOwnerHandler_UpdatePaymentAccount_Invalid(t *testing.T) {
+	router, _ := setupMachineOwnerTestRouter(t, "Owner")
+
+	tests := []struct {
+		name string
+		req  contracts.UpdatePaymentAccountRequest
+	}{
+		{name: "缺少商户号", req: contracts.UpdatePaymentAccountRequest{ChannelCode: contracts.ChannelCodeFuiouMerchant}},
+		{name: "首次设置缺少密钥", req: contracts.UpdatePaymentAccountRequest{
+			ChannelCode: contracts.ChannelCodeFuiouMerchant, ReceivingAccount: "0002900F0001",
+		}},
+		{name: "不支持的渠道", req: contracts.UpdatePaymentAccountRequest{
+			ChannelCode: "unknown", ReceivingAccount: "0002900F0001", ReceivingKey: testReceivingKey,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveMachineOwner(router, http.MethodPost, "/api/MachineOwner/UpdatePaymentAccount", tt.req)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestMachineOwnerHandler_PaymentAccount_NotOwner(t *testing.T) {
+	router, _ := setupMachineOwnerTestRouter(t, "Member")
+
+	w := serveMachineOwner(router, http.MethodGet, "/api/MachineOwner/GetPaymentAccount", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
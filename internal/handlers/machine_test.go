@@ -258,3 +258,46 @@ func TestMachineHandler_OpenOrCloseBusiness(t *testing.T) {
 		t.Errorf("Expected status Forbidden, Unauthorized, or BadRequest with extra params, got %d", w4.Code)
 	}
 }
+
+func TestMachineHandler_OpenOrCloseBusiness_Admin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := models.AutoMigrate(db); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	ownerID := "owner-1"
+	if err := db.Create(&models.Machine{ID: "machine-1", MachineOwnerId: &ownerID}).Error; err != nil {
+		t.Fatalf("Failed to create machine: %v", err)
+	}
+
+	handler := NewMachineHandler(db)
+	request := func(role, machineOwnerID string) int {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("member_id", "member-1")
+			c.Set("machine_owner_id", machineOwnerID)
+			c.Set("role", role)
+			c.Next()
+		})
+		router.GET("/api/Machine/OpenOrClose", handler.OpenOrCloseBusiness)
+
+		req, _ := http.NewRequest("GET", "/api/Machine/OpenOrClose?id=machine-1", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 管理员未绑定机主也可操作任意售货机，其他机主不能操作
+	if code := request("Admin", ""); code != http.StatusOK {
+		t.Errorf("Expected admin to get status OK, got %d", code)
+	}
+	if code := request("Owner", "owner-2"); code != http.StatusForbidden {
+		t.Errorf("Expected other owner to get status Forbidden, got %d", code)
+	}
+	if code := request("Owner", ownerID); code != http.StatusOK {
+		t.Errorf("Expected owner to get status OK, got %d", code)
+	}
+}
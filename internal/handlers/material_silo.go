@@ -14,6 +14,7 @@ import (
 type MaterialSiloHandler struct {
 	*BaseHandler
 	materialSiloService services.MaterialSiloServiceInterface
+	accessGuard         services.MachineAccessGuardInterface
 }
 
 // NewMaterialSiloHandler 创建物料槽处理器
@@ -21,9 +22,38 @@ func NewMaterialSiloHandler(db *gorm.DB) *MaterialSiloHandler {
 	return &MaterialSiloHandler{
 		BaseHandler:         NewBaseHandler(db),
 		materialSiloService: services.NewMaterialSiloService(db),
+		accessGuard:         services.NewMachineAccessGuard(db),
 	}
 }
 
+// authorizeMachine 校验当前用户可操作售货机，失败时已写入响应
+func (h *MaterialSiloHandler) authorizeMachine(c *gin.Context, machineID string) bool {
+	scope, exists := h.GetAccessScope(c)
+	if !exists {
+		h.UnauthorizedResponse(c, "无效的用户信息")
+		return false
+	}
+	if _, err := h.accessGuard.AuthorizeMachine(scope, machineID); err != nil {
+		h.machineAccessErrorResponse(c, err)
+		return false
+	}
+	return true
+}
+
+// authorizeSilo 校验当前用户可操作料仓所在的售货机，失败时已写入响应
+func (h *MaterialSiloHandler) authorizeSilo(c *gin.Context, siloID string) bool {
+	scope, exists := h.GetAccessScope(c)
+	if !exists {
+		h.UnauthorizedResponse(c, "无效的用户信息")
+		return false
+	}
+	if _, err := h.accessGuard.AuthorizeSilo(scope, siloID); err != nil {
+		h.machineAccessErrorResponse(c, err)
+		return false
+	}
+	return true
+}
+
 // GetPaging 获取物料槽分页列表
 // POST /api/MaterialSilo/GetPaging
 func (h *MaterialSiloHandler) GetPaging(c *gin.Context) {
@@ -33,6 +63,10 @@ func (h *MaterialSiloHandler) GetPaging(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeMachine(c, req.MachineID) {
+		return
+	}
+
 	result, err := h.materialSiloService.GetPaging(req)
 	if err != nil {
 		if err.Error() == "机器不存在" {
@@ -55,6 +89,10 @@ func (h *MaterialSiloHandler) UpdateStock(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeSilo(c, req.ID) {
+		return
+	}
+
 	result, err := h.materialSiloService.UpdateStock(req)
 	if err != nil {
 		h.InternalErrorResponse(c, err)
@@ -78,6 +116,10 @@ func (h *MaterialSiloHandler) UpdateProduct(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeSilo(c, req.ID) {
+		return
+	}
+
 	result, err := h.materialSiloService.UpdateProduct(req)
 	if err != nil {
 		h.InternalErrorResponse(c, err)
@@ -109,6 +151,10 @@ func (h *MaterialSiloHandler) ToggleSaleStatus(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeSilo(c, req.ID) {
+		return
+	}
+
 	result, err := h.materialSiloService.ToggleSaleStatus(req)
 	if err != nil {
 		h.InternalErrorResponse(c, err)
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+func setupMaterialSiloTestRouter(t *testing.T, role, machineOwnerID string) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.AutoMigrate(db))
+	ownerID := "owner-1"
+	machineID := "machine-1"
+	require.NoError(t, db.Create(&models.Machine{ID: machineID, MachineOwnerId: &ownerID}).Error)
+	require.NoError(t, db.Create(&models.MaterialSilo{ID: "silo-1", MachineId: &machineID, Total: 100, Stock: 10}).Error)
+
+	handler := NewMaterialSiloHandler(db)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("member_id", "member-1")
+		c.Set("machine_owner_id", machineOwnerID)
+		c.Set("role", role)
+		c.Next()
+	})
+	router.POST("/api/MaterialSilo/GetPaging", handler.GetPaging)
+	router.POST("/api/MaterialSilo/UpdateStock", handler.UpdateStock)
+	return router, db
+}
+
+func TestMaterialSiloHandler_UpdateStockOwnership(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           string
+		machineOwnerID string
+		siloID         string
+		expected       int
+		expectedStock  int
+	}{
+		{"owner", "Owner", "owner-1", "silo-1", http.StatusOK, 50},
+		{"maintainer of owner", "Maintainer", "owner-1", "silo-1", http.StatusOK, 50},
+		{"other owner", "Owner", "owner-2", "silo-1", http.StatusForbidden, 10},
+		{"consumer", "Consumer", "", "silo-1", http.StatusForbidden, 10},
+		{"missing silo", "Owner", "owner-1", "silo-x", http.StatusNotFound, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, db := setupMaterialSiloTestRouter(t, tt.role, tt.machineOwnerID)
+
+			body := `{"id": "` + tt.siloID + `", "stock": 50}`
+			req, _ := http.NewRequest("POST", "/api/MaterialSilo/UpdateStock", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expected, w.Code, w.Body.String())
+
+			var silo models.MaterialSilo
+			require.NoError(t, db.First(&silo, "Id = ?", "silo-1").Error)
+			assert.Equal(t, tt.expectedStock, silo.Stock)
+		})
+	}
+}
+
+func TestMaterialSiloHandler_GetPagingOwnership(t *testing.T) {
+	router, _ := setupMaterialSiloTestRouter(t, "Owner", "owner-2")
+
+	req, _ := http.NewRequest("POST", "/api/MaterialSilo/GetPaging", bytes.NewBufferString(
+		`{"machineId": "machine-1", "pageIndex": 1, "pageSize": 10}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
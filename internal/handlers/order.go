@@ -8,6 +8,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
 	"github.com/ddteam/drink-master/internal/services"
 )
 
@@ -74,6 +75,61 @@ func (h *OrderHandler) GetPaging(c *gin.Context) {
 	})
 }
 
+// 机主订单分页每页最大条数
+const ownerOrderMaxPageSize = 100
+
+// GetOwnerPaging 机主分页获取名下售货机的订单
+// @Summary 机主订单列表
+// @Description 机主按日期范围、售货机、支付状态、制作状态和订单号筛选名下售货机的订单，并返回筛选结果合计
+// @Tags Order
+// @Accept json
+// @Produce json
+// @Param request body contracts.GetOwnerOrderPagingRequest true "分页请求"
+// @Success 200 {object} contracts.APIResponse{data=contracts.OwnerOrderPagingResponse}
+// @Failure 400 {object} contracts.APIResponse
+// @Failure 401 {object} contracts.APIResponse
+// @Failure 403 {object} contracts.APIResponse
+// @Security BearerAuth
+// @Router /Order/GetOwnerPaging [post]
+func (h *OrderHandler) GetOwnerPaging(c *gin.Context) {
+	machineOwnerID, ok := h.RequireMachineOwnerID(c, "查看订单")
+	if !ok {
+		return
+	}
+
+	var request contracts.GetOwnerOrderPagingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.ValidationErrorResponse(c, err)
+		return
+	}
+	request.MachineOwnerID = machineOwnerID
+
+	if request.PageIndex <= 0 {
+		request.PageIndex = 1
+	}
+	if request.PageSize <= 0 {
+		request.PageSize = 10
+	}
+	if request.PageSize > ownerOrderMaxPageSize {
+		request.PageSize = ownerOrderMaxPageSize
+	}
+
+	response, err := h.orderService.GetOwnerOrderPaging(request)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidOrderFilter):
+			h.ValidationErrorResponse(c, err)
+		case errors.Is(err, services.ErrMachineNotOwned):
+			h.ForbiddenResponse(c, err.Error())
+		default:
+			h.InternalErrorResponse(c, err)
+		}
+		return
+	}
+
+	h.SuccessResponse(c, response)
+}
+
 // Get 获取订单详情
 // @Summary 获取订单详细信息
 // @Description 根据订单ID获取订单的详细信息
@@ -150,6 +206,16 @@ func (h *OrderHandler) Create(c *gin.Context) {
 			h.ValidationErrorResponse(c, err)
 			return
 		}
+		if code := createOrderErrorCode(err); code != "" {
+			c.JSON(http.StatusBadRequest, contracts.APIResponse{
+				Success: false,
+				Error: &contracts.APIError{
+					Code:    code,
+					Message: err.Error(),
+				},
+			})
+			return
+		}
 		if err.Error() == "机器不在线，下单失败" {
 			c.JSON(http.StatusBadRequest, contracts.APIResponse{
 				Success: false,
@@ -184,9 +250,8 @@ func (h *OrderHandler) Create(c *gin.Context) {
 // @Security BearerAuth
 // @Router /Order/Refund [post]
 func (h *OrderHandler) Refund(c *gin.Context) {
-	// 检查是否为机主
-	isMachineOwner := h.IsMachineOwner(c)
-	if !isMachineOwner {
+	// 检查订单管理权限
+	if !h.HasPermission(c, enums.PermissionOrderManage) {
 		h.ForbiddenResponse(c, "您不是机主，无法退款")
 		return
 	}
@@ -202,23 +267,52 @@ func (h *OrderHandler) Refund(c *gin.Context) {
 
 	response, err := h.orderService.Refund(request)
 	if err != nil {
-		if err.Error() == "订单不存在" {
-			h.NotFoundResponse(c, "订单不存在")
-			return
-		}
-		if err.Error() == "订单状态不允许退款" || err.Error() == "订单已经退款" {
-			c.JSON(http.StatusBadRequest, contracts.APIResponse{
-				Success: false,
-				Error: &contracts.APIError{
-					Code:    contracts.ErrorCodeInvalidOrderStatus,
-					Message: err.Error(),
-				},
-			})
-			return
-		}
+		h.refundErrorResponse(c, err)
+		return
+	}
+
+	h.SuccessResponseWithMessage(c, response, response.Message)
+}
+
+// createOrderErrorCode 下单时商品报价及库存相关错误的错误码，其他错误返回空
+func createOrderErrorCode(err error) string {
+	switch {
+	case errors.Is(err, services.ErrInsufficientStock):
+		return contracts.ErrorCodeInsufficientInventory
+	case errors.Is(err, services.ErrProductNotAvailable):
+		return contracts.ErrorCodeProductNotAvailable
+	case errors.Is(err, services.ErrOrderAmountMismatch):
+		return contracts.ErrorCodePriceMismatch
+	}
+	return ""
+}
+
+// refundErrorResponse 退款申请错误响应
+func (h *BaseHandler) refundErrorResponse(c *gin.Context, err error) {
+	code, message := "", err.Error()
+	switch {
+	case errors.Is(err, services.ErrRefundOrderNotFound):
+		h.NotFoundResponse(c, err.Error())
+		return
+	case errors.Is(err, services.ErrRefundNotAllowed), errors.Is(err, services.ErrOrderAlreadyRefunded):
+		code = contracts.ErrorCodeInvalidOrderStatus
+	case errors.Is(err, services.ErrRefundInProgress):
+		code = contracts.ErrorCodeRefundInProgress
+	case errors.Is(err, services.ErrInvalidRefundAmount), errors.Is(err, services.ErrRefundAmountExceeded):
+		code = contracts.ErrorCodeInvalidRefundAmount
+	case errors.Is(err, services.ErrRefundFailed):
+		// 渠道错误详情只记录在退款记录中
+		code, message = contracts.ErrorCodeRefundFailed, services.ErrRefundFailed.Error()
+	default:
 		h.InternalErrorResponse(c, err)
 		return
 	}
 
-	h.SuccessResponseWithMessage(c, response, "退款成功")
+	c.JSON(http.StatusBadRequest, contracts.APIResponse{
+		Success: false,
+		Error: &contracts.APIError{
+			Code:    code,
+			Message: message,
+		},
+	})
 }
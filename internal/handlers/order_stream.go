@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/services"
+)
+
+// 订单状态推送参数
+const (
+	orderStreamHeartbeatInterval = 15 * time.Second
+	orderStreamMaxDuration       = 10 * time.Minute // 连接到期后客户端携带 Last-Event-ID 重连
+	orderStreamRetryMillis       = 3000
+)
+
+// OrderStreamHandler 订单状态推送处理器
+type OrderStreamHandler struct {
+	*BaseHandler
+	eventService      services.OrderEventServiceInterface
+	heartbeatInterval time.Duration
+	maxDuration       time.Duration
+}
+
+// NewOrderStreamHandler 创建订单状态推送处理器
+func NewOrderStreamHandler(db *gorm.DB, eventService services.OrderEventServiceInterface) *OrderStreamHandler {
+	return &OrderStreamHandler{
+		BaseHandler:       NewBaseHandler(db),
+		eventService:      eventService,
+		heartbeatInterval: orderStreamHeartbeatInterval,
+		maxDuration:       orderStreamMaxDuration,
+	}
+}
+
+// Events 订单状态推送
+// @Summary 订阅订单状态变更
+// @Description 以 Server-Sent Events 推送订单的支付与制作状态变更事件，事件名为事件类型，数据为 contracts.OrderEvent。
+// @Description 首次连接推送订单全部历史事件；事件ID为递增的事件序号，携带 Last-Event-ID 头（或 lastEventId 参数）重连时从该事件之后继续推送。
+// @Tags Order
+// @Produce text/event-stream
+// @Param id query string true "订单ID"
+// @Param lastEventId query string false "最后收到的事件ID"
+// @Success 200 {string} string "事件流"
+// @Failure 400 {object} contracts.APIResponse
+// @Failure 401 {object} contracts.APIResponse
+// @Failure 404 {object} contracts.APIResponse
+// @Security BearerAuth
+// @Router /Order/Events [get]
+func (h *OrderStreamHandler) Events(c *gin.Context) {
+	memberID, exists := h.GetMemberID(c)
+	if !exists {
+		h.UnauthorizedResponse(c, "无效的用户信息")
+		return
+	}
+
+	orderID := c.Query("id")
+	if orderID == "" {
+		h.ValidationErrorResponse(c, errors.New("订单ID不能为空"))
+		return
+	}
+
+	if err := h.eventService.CheckOrderAccess(memberID, orderID); err != nil {
+		if errors.Is(err, services.ErrOrderNotFound) {
+			h.NotFoundResponse(c, "订单不存在")
+			return
+		}
+		h.InternalErrorResponse(c, err)
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventId")
+	}
+	// 无法识别的事件ID从第一条事件开始推送，订阅者按事件ID幂等处理
+	lastSeq, _ := strconv.ParseInt(lastEventID, 10, 64)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭反向代理缓冲
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", orderStreamRetryMillis)
+
+	// 先订阅再查询，避免遗漏查询与订阅之间写入的事件
+	notify, unwatch := h.eventService.Watch(orderID)
+	defer unwatch()
+
+	lastSeq = h.pushEvents(c, orderID, lastSeq)
+
+	heartbeat := time.NewTicker(h.heartbeatInterval)
+	defer heartbeat.Stop()
+	deadline := time.NewTimer(h.maxDuration)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-deadline.C:
+			return
+		case <-notify:
+			lastSeq = h.pushEvents(c, orderID, lastSeq)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			// 轮询失败时可能漏掉通知，心跳时补查一次
+			lastSeq = h.pushEvents(c, orderID, lastSeq)
+		}
+	}
+}
+
+// pushEvents 推送序号在lastSeq之后的订单事件，返回最后推送的事件序号
+func (h *OrderStreamHandler) pushEvents(c *gin.Context, orderID string, lastSeq int64) int64 {
+	events, err := h.eventService.GetOrderEvents(orderID, lastSeq)
+	if err != nil {
+		logrus.WithError(err).WithField("order_id", orderID).Warn("订单事件查询失败，稍后重试")
+		c.Writer.Flush()
+		return lastSeq
+	}
+
+	for _, event := range events {
+		// OrderEvent 只包含可序列化的字段，序列化不会失败
+		data, _ := json.Marshal(event)
+		fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, data)
+		lastSeq = event.Seq
+	}
+	c.Writer.Flush()
+	return lastSeq
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/services"
+)
+
+// setupOrderStreamTest 创建订单事件流路由，startPoller 为 false 时只能靠心跳补查推送新事件
+func setupOrderStreamTest(t *testing.T, startPoller bool) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Order{}, &models.OutboxEvent{}))
+	memberID := "member-1"
+	require.NoError(t, db.Create(&models.Order{ID: "order-1", MemberId: &memberID, CreatedOn: time.Now()}).Error)
+
+	eventService := services.NewOrderEventService(db)
+	handler := NewOrderStreamHandler(db, eventService)
+	if startPoller {
+		eventService.Start(10 * time.Millisecond)
+		t.Cleanup(eventService.Stop)
+		handler.heartbeatInterval = time.Hour
+	} else {
+		handler.heartbeatInterval = 20 * time.Millisecond
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("member_id", c.GetHeader("X-Member"))
+		c.Next()
+	})
+	router.GET("/api/Order/Events", handler.Events)
+	return router, db
+}
+
+func createOrderStreamEvent(t *testing.T, db *gorm.DB, id, eventType string, at time.Time) {
+	t.Helper()
+	payload, err := json.Marshal(contracts.OrderEvent{ID: id, Type: eventType, OrderID: "order-1", OccurredAt: at})
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&models.OutboxEvent{
+		ID:            id,
+		EventType:     eventType,
+		AggregateId:   "order-1",
+		Payload:       string(payload),
+		NextAttemptOn: at,
+		CreatedOn:     at,
+	}).Error)
+}
+
+// streamOrderEvents 打开订单事件流，during 执行完毕并等待 wait 后断开连接，返回收到的内容
+func streamOrderEvents(router *gin.Engine, member, lastEventID string, wait time.Duration,
+	during func()) *httptest.ResponseRecorder {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/api/Order/Events?id=order-1", nil)
+	req.Header.Set("X-Member", member)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		router.ServeHTTP(w, req)
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	if during != nil {
+		during()
+	}
+	time.Sleep(wait)
+	cancel()
+	<-done
+	return w
+}
+
+func TestOrderStreamHandler_Events_PushesHistoryAndNewEvents(t *testing.T) {
+	router, db := setupOrderStreamTest(t, true)
+	now := time.Now()
+	createOrderStreamEvent(t, db, "event-1", contracts.EventOrderPaid, now)
+
+	w := streamOrderEvents(router, "member-1", "", 50*time.Millisecond, func() {
+		createOrderStreamEvent(t, db, "event-2", contracts.EventOrderMaking, now.Add(time.Second))
+	})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	assert.Contains(t, body, "retry: 3000\n\n")
+	assert.Contains(t, body, "id: 1\nevent: OrderPaid\ndata: {")
+	assert.Contains(t, body, "id: 2\nevent: OrderMaking\ndata: {")
+	assert.Less(t, strings.Index(body, "event-1"), strings.Index(body, "event-2"))
+	assert.Equal(t, 1, strings.Count(body, "id: 1\n"))
+}
+
+func TestOrderStreamHandler_Events_HeartbeatPushesMissedEvents(t *testing.T) {
+	router, db := setupOrderStreamTest(t, false)
+	now := time.Now()
+	createOrderStreamEvent(t, db, "event-1", contracts.EventOrderPaid, now)
+
+	w := streamOrderEvents(router, "member-1", "", 50*time.Millisecond, func() {
+		createOrderStreamEvent(t, db, "event-2", contracts.EventOrderMaking, now.Add(time.Second))
+	})
+
+	body := w.Body.String()
+	assert.Contains(t, body, ": heartbeat\n\n")
+	assert.Contains(t, body, "id: 2\nevent: OrderMaking")
+	assert.Equal(t, 1, strings.Count(body, "id: 1\n"))
+}
+
+func TestOrderStreamHandler_Events_ResumesFromLastEventID(t *testing.T) {
+	router, db := setupOrderStreamTest(t, true)
+	now := time.Now()
+	createOrderStreamEvent(t, db, "event-1", contracts.EventOrderPaid, now)
+	createOrderStreamEvent(t, db, "event-2", contracts.EventOrderMaking, now.Add(time.Second))
+
+	w := streamOrderEvents(router, "member-1", "1", 0, nil)
+
+	body := w.Body.String()
+	assert.NotContains(t, body, "id: 1\n")
+	assert.Contains(t, body, "id: 2\nevent: OrderMaking")
+}
+
+func TestOrderStreamHandler_Events_OtherMembersOrder(t *testing.T) {
+	router, _ := setupOrderStreamTest(t, true)
+
+	req, _ := http.NewRequest("GET", "/api/Order/Events?id=order-1", nil)
+	req.Header.Set("X-Member", "member-2")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
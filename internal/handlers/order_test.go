@@ -3,6 +3,7 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -12,11 +13,14 @@ import (
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
 	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
 	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/services"
 )
 
 // Mock OrderService for testing
@@ -32,6 +36,14 @@ func (m *mockOrderService) GetMemberOrderPaging(request contracts.GetMemberOrder
 	return args.Get(0).(*contracts.OrderPagingResponse), args.Error(1)
 }
 
+func (m *mockOrderService) GetOwnerOrderPaging(request contracts.GetOwnerOrderPagingRequest) (*contracts.OwnerOrderPagingResponse, error) {
+	args := m.Called(request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*contracts.OwnerOrderPagingResponse), args.Error(1)
+}
+
 func (m *mockOrderService) GetByID(id string) (*contracts.GetOrderByIdResponse, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
@@ -263,6 +275,44 @@ func TestOrderHandler_Create_WithAuth(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestOrderHandler_Create_PriceErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+
+	tests := []struct {
+		name      string
+		err       error
+		errorCode string
+	}{
+		{"商品未定价", services.ErrProductNotAvailable, contracts.ErrorCodeProductNotAvailable},
+		{"商品售罄", services.ErrInsufficientStock, contracts.ErrorCodeInsufficientInventory},
+		{"金额不一致", fmt.Errorf("%w: 应付15.80元，提交0.01元", services.ErrOrderAmountMismatch),
+			contracts.ErrorCodePriceMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockOrderService{}
+			handler := NewOrderHandler(db, mockService)
+			mockService.On("Create", mock.AnythingOfType("contracts.CreateOrderRequest")).Return(nil, tt.err)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			requestBody := `{"machineId": "machine123", "productId": "product456", "hasCup": true, "payAmount": "0.01"}`
+			c.Request, _ = http.NewRequest("POST", "/api/Order/Create", bytes.NewBuffer([]byte(requestBody)))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("member_id", "test_member_789")
+
+			handler.Create(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var response contracts.APIResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.errorCode, response.Error.Code)
+		})
+	}
+}
+
 func TestOrderHandler_Refund(t *testing.T) {
 	router, _ := setupOrderTestRouter()
 
@@ -317,3 +367,120 @@ func TestOrderHandler_Refund_WithAuth(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	mockService.AssertExpectations(t)
 }
+
+func TestOrderHandler_Refund_Admin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockService := &mockOrderService{}
+	handler := NewOrderHandler(db, mockService)
+	mockService.On("Refund", mock.AnythingOfType("contracts.RefundOrderRequest")).
+		Return(&contracts.RefundOrderResponse{OrderID: "order123"}, nil)
+
+	// 管理员拥有订单管理权限，未绑定机主也可退款
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/api/Order/Refund", bytes.NewBufferString(`{"orderId": "order123"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("member_id", "admin-1")
+	c.Set("role", "Admin")
+	c.Set("permissions", []string{string(enums.PermissionOrderManage)})
+
+	handler.Refund(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestOrderHandler_Refund_Errors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+		errorCode  string
+	}{
+		{"订单不存在", services.ErrRefundOrderNotFound, http.StatusNotFound, ""},
+		{"已全额退款", services.ErrOrderAlreadyRefunded, http.StatusBadRequest, contracts.ErrorCodeInvalidOrderStatus},
+		{"退款处理中", services.ErrRefundInProgress, http.StatusBadRequest, contracts.ErrorCodeRefundInProgress},
+		{"金额超限", services.ErrRefundAmountExceeded, http.StatusBadRequest, contracts.ErrorCodeInvalidRefundAmount},
+		{"渠道退款失败", fmt.Errorf("%w: channel refund rejected", services.ErrRefundFailed),
+			http.StatusBadRequest, contracts.ErrorCodeRefundFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockOrderService{}
+			handler := NewOrderHandler(db, mockService)
+			mockService.On("Refund", mock.AnythingOfType("contracts.RefundOrderRequest")).Return(nil, tt.err)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			requestBody := `{"orderId": "order123", "refundAmount": "5.00"}`
+			c.Request, _ = http.NewRequest("POST", "/api/Order/Refund", bytes.NewBuffer([]byte(requestBody)))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("member_id", "test_member_101")
+			c.Set("role", "Owner")
+
+			handler.Refund(c)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			if tt.errorCode != "" {
+				var response contracts.APIResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tt.errorCode, response.Error.Code)
+				assert.NotContains(t, response.Error.Message, "channel")
+			}
+		})
+	}
+}
+
+func TestOrderHandler_GetOwnerPaging(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockService := &mockOrderService{}
+	handler := NewOrderHandler(db, mockService)
+
+	mockService.On("GetOwnerOrderPaging", contracts.GetOwnerOrderPagingRequest{
+		MachineOwnerID: "owner-1", PageIndex: 1, PageSize: 100, MachineID: "machine-1",
+	}).Return(&contracts.OwnerOrderPagingResponse{}, nil)
+	mockService.On("GetOwnerOrderPaging", contracts.GetOwnerOrderPagingRequest{
+		MachineOwnerID: "owner-1", PageIndex: 1, PageSize: 10, MachineID: "machine-2",
+	}).Return(nil, services.ErrMachineNotOwned)
+	mockService.On("GetOwnerOrderPaging", contracts.GetOwnerOrderPagingRequest{
+		MachineOwnerID: "owner-1", PageIndex: 1, PageSize: 10, StartDate: "2025/08/01",
+	}).Return(nil, fmt.Errorf("%w: 日期格式应为YYYY-MM-DD", services.ErrInvalidOrderFilter))
+
+	tests := []struct {
+		name     string
+		role     string
+		body     string
+		expected int
+	}{
+		{"非机主", "Member", `{}`, http.StatusForbidden},
+		{"未绑定机主的管理员", "Admin", `{}`, http.StatusForbidden},
+		{"每页条数超过上限", "Owner", `{"pageSize": 500, "machineId": "machine-1"}`, http.StatusOK},
+		{"其他机主的售货机", "Owner", `{"machineId": "machine-2"}`, http.StatusForbidden},
+		{"日期格式错误", "Owner", `{"startDate": "2025/08/01"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest("POST", "/api/Order/GetOwnerPaging", bytes.NewBufferString(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("member_id", "member-1")
+			if tt.role != "Admin" {
+				c.Set("machine_owner_id", "owner-1")
+			}
+			c.Set("role", tt.role)
+
+			handler.GetOwnerPaging(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+	mockService.AssertExpectations(t)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
@@ -22,20 +23,25 @@ type PaymentHandler struct {
 	paymentService services.PaymentServiceInterface
 	orderService   services.OrderService
 	machineService services.MachineServiceInterface
+	memberRepo     *repositories.MemberRepository
 }
 
 // NewPaymentHandler 创建支付处理器
-func NewPaymentHandler(db *gorm.DB) *PaymentHandler {
+func NewPaymentHandler(db *gorm.DB, paymentService services.PaymentServiceInterface) *PaymentHandler {
 	return &PaymentHandler{
 		BaseHandler:    NewBaseHandler(db),
-		paymentService: services.NewPaymentService(db),
+		paymentService: paymentService,
 		orderService: services.NewOrderService(
 			repositories.NewOrderRepository(db),
 			repositories.NewMachineRepository(db),
 			repositories.NewMemberRepository(db),
+			repositories.NewProductRepository(db),
 			services.NewDeviceService(),
+			nil,
+			nil,
 		),
 		machineService: services.NewMachineService(db),
+		memberRepo:     repositories.NewMemberRepository(db),
 	}
 }
 
@@ -85,7 +91,7 @@ func (h *PaymentHandler) Get(c *gin.Context) {
 	// 获取机器收款账户
 	account, err := h.paymentService.GetPaymentAccount(order.MachineID)
 	if err != nil {
-		h.InternalErrorResponse(c, err)
+		h.paymentAccountErrorResponse(c, err)
 		return
 	}
 
@@ -95,19 +101,25 @@ func (h *PaymentHandler) Get(c *gin.Context) {
 		notifyUrl = "http://vm-mobile-app/api/Callback/PaymentResult"
 	}
 
-	// 获取用户OpenId（这里需要从JWT token或其他方式获取）
-	openId := h.getMemberOpenId(c)
-	if openId == "" {
-		h.UnauthorizedResponse(c, "无效的用户信息")
+	// JSAPI下单需要付款会员的微信OpenId
+	openId, ok := h.getMemberOpenId(c)
+	if !ok {
 		return
 	}
 
+	channelCode, err := services.ResolveChannelCode(order.ChannelCode, account)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, contracts.ErrorCodePaymentFailed, "不支持的支付渠道")
+		return
+	}
 	wechatPayReq := contracts.WeChatPayRequest{
 		Ext1:        account.ReceivingAccount,
 		Ext2:        account.ReceivingKey,
 		Ext3:        account.ReceivingOrderPrefix,
+		Ext4:        account.MerchantSerialNo,
+		Ext5:        account.MerchantPrivateKey,
 		NotifyUrl:   notifyUrl,
-		ChannelCode: contracts.ChannelCodeFuiouMerchant,
+		ChannelCode: channelCode,
 		OrderNo:     order.OrderNo,
 		OpenId:      openId,
 		Attach:      "",
@@ -115,6 +127,17 @@ func (h *PaymentHandler) Get(c *gin.Context) {
 		TransAmt:    safeInt64ToInt32(order.PayAmount.Mul(decimal.NewFromInt(100)).IntPart()), // 元转分
 	}
 
+	// 预下单前固定订单渠道，后续查询、回调、关单与退款均走该渠道
+	// 绑定后超时任务不再直接作废订单，而是先向渠道查询并关单
+	if bindErr := h.paymentService.BindOrderChannel(order.ID, channelCode); bindErr != nil {
+		if errors.Is(bindErr, services.ErrOrderNotWaitPay) {
+			h.ErrorResponse(c, http.StatusBadRequest, contracts.ErrorCodeInvalidOrderStatus, "订单已失效")
+			return
+		}
+		h.InternalErrorResponse(c, bindErr)
+		return
+	}
+
 	// 调用微信支付
 	authInfo, err := h.paymentService.WeChatPay(wechatPayReq)
 	if err != nil || !authInfo.IsSuccess {
@@ -186,6 +209,8 @@ func (h *PaymentHandler) getOrderStatusMessage(status string) string {
 	switch status {
 	case contracts.PaymentStatusRefunded:
 		return "已退款"
+	case contracts.PaymentStatusRefunding:
+		return "退款中"
 	case contracts.PaymentStatusCancelled:
 		return "已取消"
 	default:
@@ -207,6 +232,15 @@ func (h *PaymentHandler) handleFreePaymentOrder(c *gin.Context, orderID string)
 	h.SuccessResponse(c, contracts.QueryPaymentResponse{Message: "支付成功"})
 }
 
+// paymentAccountErrorResponse 收款账户获取失败，机主未配置收款账户时返回明确的错误码
+func (h *PaymentHandler) paymentAccountErrorResponse(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrPaymentAccountNotFound) {
+		h.ErrorResponse(c, http.StatusBadRequest, contracts.ErrorCodePaymentAccountNotFound, "机主未配置收款账户")
+		return
+	}
+	h.InternalErrorResponse(c, err)
+}
+
 // queryThirdPartyPaymentStatus 查询第三方支付状态
 func (h *PaymentHandler) queryThirdPartyPaymentStatus(
 	c *gin.Context, order *contracts.GetOrderByIdResponse, orderID string,
@@ -214,7 +248,13 @@ func (h *PaymentHandler) queryThirdPartyPaymentStatus(
 	// 获取机器支付账户
 	account, err := h.paymentService.GetPaymentAccount(order.MachineID)
 	if err != nil {
-		h.InternalErrorResponse(c, err)
+		h.paymentAccountErrorResponse(c, err)
+		return
+	}
+
+	channelCode, err := services.ResolveChannelCode(order.ChannelCode, account)
+	if err != nil {
+		h.SuccessResponse(c, contracts.QueryPaymentResponse{Message: "查询失败"})
 		return
 	}
 
@@ -223,7 +263,9 @@ func (h *PaymentHandler) queryThirdPartyPaymentStatus(
 		Ext1:          account.ReceivingAccount,
 		Ext2:          account.ReceivingKey,
 		Ext3:          account.ReceivingOrderPrefix,
-		ChannelCode:   contracts.ChannelCodeFuiouMerchant,
+		Ext4:          account.MerchantSerialNo,
+		Ext5:          account.MerchantPrivateKey,
+		ChannelCode:   channelCode,
 		OrderNo:       order.OrderNo,
 		ModeOfPayment: contracts.ModeOfPaymentWeChat,
 	}
@@ -273,29 +315,34 @@ func (h *PaymentHandler) processSuccessfulPayment(
 
 // processFailedPayment 处理失败支付
 func (h *PaymentHandler) processFailedPayment(c *gin.Context, payInfo *contracts.TranQueryResponse, orderID string) {
+	// 订单已被并发支付或作废时不做处理
 	invalidReq := contracts.InvalidOrderRequest{ID: orderID}
-	if invalidErr := h.paymentService.InvalidOrder(invalidReq); invalidErr != nil {
+	invalidErr := h.paymentService.InvalidOrder(invalidReq)
+	if invalidErr != nil && !errors.Is(invalidErr, services.ErrOrderNotWaitPay) {
 		h.InternalErrorResponse(c, invalidErr)
 		return
 	}
 	h.SuccessResponse(c, contracts.QueryPaymentResponse{Message: payInfo.PaymentStatus})
 }
 
-// getMemberOpenId 获取会员微信OpenId
-func (h *PaymentHandler) getMemberOpenId(c *gin.Context) string {
-	// TODO: 从JWT token或session中获取用户的OpenId
-	// 这里需要根据实际的认证机制来实现
-	// 临时返回mock值用于测试
-	return "mock_open_id_" + h.getMemberIDString(c)
-}
-
-// getMemberIDString 获取会员ID字符串
-func (h *PaymentHandler) getMemberIDString(c *gin.Context) string {
+// getMemberOpenId 获取当前会员的微信OpenId，失败时已写入错误响应
+func (h *PaymentHandler) getMemberOpenId(c *gin.Context) (string, bool) {
 	memberID, exists := h.GetMemberID(c)
-	if !exists {
-		return ""
+	if !exists || memberID == "" {
+		h.UnauthorizedResponse(c, "无效的用户信息")
+		return "", false
+	}
+
+	member, err := h.memberRepo.GetByID(memberID)
+	if err != nil {
+		h.UnauthorizedResponse(c, "无效的用户信息")
+		return "", false
+	}
+	if member.WeChatOpenId == nil || *member.WeChatOpenId == "" {
+		h.ErrorResponse(c, http.StatusBadRequest, contracts.ErrorCodePaymentOpenIDMissing, "会员未绑定微信，请重新登录后支付")
+		return "", false
 	}
-	return memberID
+	return *member.WeChatOpenId, true
 }
 
 // getHasCupText 获取是否需要杯子的文本描述
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -10,6 +11,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/services"
 )
 
 func setupPaymentTestRouter() (*gin.Engine, *PaymentHandler) {
@@ -57,7 +59,7 @@ func setupPaymentTestRouter() (*gin.Engine, *PaymentHandler) {
 	db.Create(order)
 
 	router := gin.New()
-	paymentHandler := NewPaymentHandler(db)
+	paymentHandler := NewPaymentHandler(db, services.NewPaymentService(db))
 
 	router.GET("/api/Payment/Get", paymentHandler.Get)
 	router.GET("/api/Payment/Query", paymentHandler.Query)
@@ -67,7 +69,7 @@ func setupPaymentTestRouter() (*gin.Engine, *PaymentHandler) {
 
 func TestNewPaymentHandler(t *testing.T) {
 	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	handler := NewPaymentHandler(db)
+	handler := NewPaymentHandler(db, services.NewPaymentService(db))
 
 	if handler == nil {
 		t.Error("Expected handler to be created")
@@ -89,6 +91,10 @@ func TestPaymentHandler_Get(t *testing.T) {
 }
 
 func TestPaymentHandler_Get_WithAuth(t *testing.T) {
+	// 使用模拟支付渠道
+	os.Setenv("MOCK_MODE", "true")
+	defer os.Unsetenv("MOCK_MODE")
+
 	gin.SetMode(gin.TestMode)
 	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	// 自动迁移表结构
@@ -127,7 +133,7 @@ func TestPaymentHandler_Get_WithAuth(t *testing.T) {
 	}
 	db.Create(order)
 
-	handler := NewPaymentHandler(db)
+	handler := NewPaymentHandler(db, services.NewPaymentService(db))
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -160,7 +166,7 @@ func TestPaymentHandler_Query(t *testing.T) {
 func TestPaymentHandler_Query_WithAuth(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	handler := NewPaymentHandler(db)
+	handler := NewPaymentHandler(db, services.NewPaymentService(db))
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -181,7 +187,7 @@ func TestPaymentHandler_Query_WithAuth(t *testing.T) {
 func TestPaymentHandler_Get_InvalidRequest(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	handler := NewPaymentHandler(db)
+	handler := NewPaymentHandler(db, services.NewPaymentService(db))
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -201,7 +207,7 @@ func TestPaymentHandler_Get_InvalidRequest(t *testing.T) {
 func TestPaymentHandler_Query_InvalidRequest(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	handler := NewPaymentHandler(db)
+	handler := NewPaymentHandler(db, services.NewPaymentService(db))
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -222,7 +228,7 @@ func TestPaymentHandler_Constructor(t *testing.T) {
 	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 
 	// Test PaymentHandler constructor
-	paymentHandler := NewPaymentHandler(db)
+	paymentHandler := NewPaymentHandler(db, services.NewPaymentService(db))
 	if paymentHandler == nil {
 		t.Error("PaymentHandler should not be nil")
 		return
@@ -239,7 +245,7 @@ func TestPaymentHandler_Constructor(t *testing.T) {
 func TestPaymentHandler_GetMemberOpenId_Error(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	handler := NewPaymentHandler(db)
+	handler := NewPaymentHandler(db, services.NewPaymentService(db))
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -255,3 +261,32 @@ func TestPaymentHandler_GetMemberOpenId_Error(t *testing.T) {
 		t.Errorf("Expected error status, got %d", w.Code)
 	}
 }
+
+func TestPaymentHandler_Get_MemberWithoutOpenId(t *testing.T) {
+	os.Setenv("MOCK_MODE", "true")
+	defer os.Unsetenv("MOCK_MODE")
+
+	_, handler := setupPaymentTestRouter()
+	handler.memberRepo.Create(&models.Member{ID: "member_without_openid"})
+
+	tests := []struct {
+		memberID string
+		expected int
+	}{
+		{"member_without_openid", http.StatusBadRequest}, // 未绑定微信OpenId
+		{"unknown_member", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/api/Payment/Get?orderId=order123", nil)
+		c.Set("member_id", tt.memberID)
+
+		handler.Get(c)
+
+		if w.Code != tt.expected {
+			t.Errorf("member %s: expected status %d, got %d", tt.memberID, tt.expected, w.Code)
+		}
+	}
+}
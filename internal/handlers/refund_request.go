@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/services"
+)
+
+// 退款申请分页每页最大条数
+const refundRequestMaxPageSize = 100
+
+// RefundRequestHandler 顾客退款申请处理器
+type RefundRequestHandler struct {
+	*BaseHandler
+	requestService services.RefundRequestServiceInterface
+}
+
+// NewRefundRequestHandler 创建顾客退款申请处理器
+func NewRefundRequestHandler(db *gorm.DB, requestService services.RefundRequestServiceInterface) *RefundRequestHandler {
+	return &RefundRequestHandler{
+		BaseHandler:    NewBaseHandler(db),
+		requestService: requestService,
+	}
+}
+
+// Submit 顾客提交退款申请
+// @Summary 提交退款申请
+// @Description 顾客对自己的已支付订单提交退款申请，由售货机机主审核，申请金额为订单剩余可退金额
+// @Tags RefundRequest
+// @Accept json
+// @Produce json
+// @Param request body contracts.SubmitRefundRequest true "退款申请"
+// @Success 200 {object} contracts.APIResponse{data=contracts.RefundRequestResponse}
+// @Failure 400 {object} contracts.APIResponse
+// @Failure 401 {object} contracts.APIResponse
+// @Failure 404 {object} contracts.APIResponse
+// @Failure 409 {object} contracts.APIResponse
+// @Security BearerAuth
+// @Router /RefundRequest/Submit [post]
+func (h *RefundRequestHandler) Submit(c *gin.Context) {
+	memberID, exists := h.GetMemberID(c)
+	if !exists {
+		h.UnauthorizedResponse(c, "无效的用户信息")
+		return
+	}
+
+	var request contracts.SubmitRefundRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.ValidationErrorResponse(c, err)
+		return
+	}
+	request.MemberID = memberID
+
+	response, err := h.requestService.Submit(request)
+	if err != nil {
+		h.refundRequestErrorResponse(c, err)
+		return
+	}
+	h.SuccessResponseWithMessage(c, response, "退款申请已提交，等待机主审核")
+}
+
+// GetByOrder 顾客查看订单的退款申请
+// @Summary 获取订单退款申请
+// @Description 顾客查看自己订单的退款申请及审核结果，最新的在前
+// @Tags RefundRequest
+// @Produce json
+// @Param orderId query string true "订单ID"
+// @Success 200 {object} contracts.APIResponse{data=[]contracts.RefundRequestResponse}
+// @Failure 400 {object} contracts.APIResponse
+// @Failure 401 {object} contracts.APIResponse
+// @Failure 404 {object} contracts.APIResponse
+// @Security BearerAuth
+// @Router /RefundRequest/GetByOrder [get]
+func (h *RefundRequestHandler) GetByOrder(c *gin.Context) {
+	memberID, exists := h.GetMemberID(c)
+	if !exists {
+		h.UnauthorizedResponse(c, "无效的用户信息")
+		return
+	}
+
+	orderID := c.Query("orderId")
+	if orderID == "" {
+		h.ValidationErrorResponse(c, errors.New("订单ID不能为空"))
+		return
+	}
+
+	response, err := h.requestService.GetByOrder(memberID, orderID)
+	if err != nil {
+		h.refundRequestErrorResponse(c, err)
+		return
+	}
+	h.SuccessResponse(c, response)
+}
+
+// GetPaging 机主退款申请队列
+// @Summary 机主退款申请列表
+// @Description 机主分页查看名下售货机的退款申请，默认只返回待审核的申请并按提交顺序排列
+// @Tags RefundRequest
+// @Accept json
+// @Produce json
+// @Param request body contracts.GetRefundRequestPagingRequest true "分页请求"
+// @Success 200 {object} contracts.APIResponse{data=contracts.RefundRequestPagingResponse}
+// @Failure 400 {object} contracts.APIResponse
+// @Failure 401 {object} contracts.APIResponse
+// @Failure 403 {object} contracts.APIResponse
+// @Security BearerAuth
+// @Router /RefundRequest/GetPaging [post]
+func (h *RefundRequestHandler) GetPaging(c *gin.Context) {
+	machineOwnerID, ok := h.machineOwnerID(c)
+	if !ok {
+		return
+	}
+
+	var request contracts.GetRefundRequestPagingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.ValidationErrorResponse(c, err)
+		return
+	}
+	request.MachineOwnerID = machineOwnerID
+
+	if request.PageIndex <= 0 {
+		request.PageIndex = 1
+	}
+	if request.PageSize <= 0 {
+		request.PageSize = 10
+	}
+	if request.PageSize > refundRequestMaxPageSize {
+		request.PageSize = refundRequestMaxPageSize
+	}
+
+	response, err := h.requestService.GetOwnerPaging(request)
+	if err != nil {
+		h.refundRequestErrorResponse(c, err)
+		return
+	}
+	h.SuccessResponse(c, response)
+}
+
+// Review 机主审核退款申请
+// @Summary 审核退款申请
+// @Description 机主同意或拒绝名下售货机的退款申请，同意时按订单剩余可退金额经支付渠道原路退回；退款未能发起时申请退回待审核
+// @Tags RefundRequest
+// @Accept json
+// @Produce json
+// @Param request body contracts.ReviewRefundRequest true "审核请求"
+// @Success 200 {object} contracts.APIResponse{data=contracts.RefundRequestResponse}
+// @Failure 400 {object} contracts.APIResponse
+// @Failure 401 {object} contracts.APIResponse
+// @Failure 403 {object} contracts.APIResponse
+// @Failure 404 {object} contracts.APIResponse
+// @Failure 409 {object} contracts.APIResponse
+// @Security BearerAuth
+// @Router /RefundRequest/Review [post]
+func (h *RefundRequestHandler) Review(c *gin.Context) {
+	machineOwnerID, ok := h.machineOwnerID(c)
+	if !ok {
+		return
+	}
+
+	var request contracts.ReviewRefundRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.ValidationErrorResponse(c, err)
+		return
+	}
+	request.MachineOwnerID = machineOwnerID
+
+	response, err := h.requestService.Review(request)
+	if err != nil {
+		h.refundRequestErrorResponse(c, err)
+		return
+	}
+	h.SuccessResponse(c, response)
+}
+
+// machineOwnerID 获取当前机主ID，不是机主时返回错误响应
+func (h *RefundRequestHandler) machineOwnerID(c *gin.Context) (string, bool) {
+	return h.RequireMachineOwnerID(c, "处理退款申请")
+}
+
+// refundRequestErrorResponse 退款申请错误响应，退款相关错误与机主退款一致
+func (h *RefundRequestHandler) refundRequestErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRefundRequestNotFound):
+		h.NotFoundResponse(c, err.Error())
+	case errors.Is(err, services.ErrRefundRequestPending), errors.Is(err, services.ErrRefundRequestReviewed),
+		errors.Is(err, services.ErrRefundRequestStale):
+		h.ConflictResponse(c, err.Error())
+	case errors.Is(err, services.ErrInvalidOrderFilter):
+		h.ValidationErrorResponse(c, err)
+	default:
+		h.refundErrorResponse(c, err)
+	}
+}
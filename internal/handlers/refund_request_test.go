@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/services"
+)
+
+type mockRefundRequestService struct {
+	mock.Mock
+}
+
+func (m *mockRefundRequestService) Submit(request contracts.SubmitRefundRequest) (*contracts.RefundRequestResponse, error) {
+	args := m.Called(request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*contracts.RefundRequestResponse), args.Error(1)
+}
+
+func (m *mockRefundRequestService) GetByOrder(memberID, orderID string) ([]contracts.RefundRequestResponse, error) {
+	args := m.Called(memberID, orderID)
+	return args.Get(0).([]contracts.RefundRequestResponse), args.Error(1)
+}
+
+func (m *mockRefundRequestService) GetOwnerPaging(request contracts.GetRefundRequestPagingRequest) (*contracts.RefundRequestPagingResponse, error) {
+	args := m.Called(request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*contracts.RefundRequestPagingResponse), args.Error(1)
+}
+
+func (m *mockRefundRequestService) Review(request contracts.ReviewRefundRequest) (*contracts.RefundRequestResponse, error) {
+	args := m.Called(request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*contracts.RefundRequestResponse), args.Error(1)
+}
+
+func serveRefundRequest(handler gin.HandlerFunc, role, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/api/RefundRequest", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("member_id", "member-1")
+	c.Set("machine_owner_id", "owner-1")
+	c.Set("role", role)
+	handler(c)
+	return w
+}
+
+func TestRefundRequestHandler_Submit(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockService := &mockRefundRequestService{}
+	handler := NewRefundRequestHandler(db, mockService)
+
+	mockService.On("Submit", contracts.SubmitRefundRequest{MemberID: "member-1", OrderID: "order-1", Reason: "没有出杯"}).
+		Return(&contracts.RefundRequestResponse{ID: "request-1"}, nil).Once()
+	w := serveRefundRequest(handler.Submit, "Member", `{"orderId": "order-1", "reason": "没有出杯"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	mockService.On("Submit", contracts.SubmitRefundRequest{MemberID: "member-1", OrderID: "order-1", Reason: "没有出杯"}).
+		Return(nil, services.ErrRefundRequestPending).Once()
+	w = serveRefundRequest(handler.Submit, "Member", `{"orderId": "order-1", "reason": "没有出杯"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// 缺少原因或图片地址无效
+	w = serveRefundRequest(handler.Submit, "Member", `{"orderId": "order-1"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serveRefundRequest(handler.Submit, "Member", `{"orderId": "order-1", "reason": "x", "photoUrl": "not a url"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestRefundRequestHandler_Review(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockService := &mockRefundRequestService{}
+	handler := NewRefundRequestHandler(db, mockService)
+
+	w := serveRefundRequest(handler.Review, "Member", `{"id": "request-1", "approve": true}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	mockService.On("Review", contracts.ReviewRefundRequest{MachineOwnerID: "owner-1", ID: "request-1", Approve: true}).
+		Return(nil, services.ErrRefundRequestReviewed).Once()
+	w = serveRefundRequest(handler.Review, "Owner", `{"id": "request-1", "approve": true}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	mockService.On("Review", contracts.ReviewRefundRequest{MachineOwnerID: "owner-1", ID: "request-2", Approve: true}).
+		Return(nil, services.ErrRefundFailed).Once()
+	w = serveRefundRequest(handler.Review, "Owner", `{"id": "request-2", "approve": true}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), contracts.ErrorCodeRefundFailed)
+	mockService.AssertExpectations(t)
+}
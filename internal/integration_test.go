@@ -40,11 +40,14 @@ func SetupIntegrationTest(t *testing.T) *IntegrationTestSuite {
 	}
 
 	// 设置路由
-	router := routes.SetupRoutes(db)
+	app, err := routes.NewApp(db)
+	if err != nil {
+		t.Fatalf("Failed to setup routes: %v", err)
+	}
 
 	return &IntegrationTestSuite{
 		db:     db,
-		router: router,
+		router: app.Router,
 	}
 }
 
@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
@@ -10,16 +9,32 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 
 	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
 )
 
 // JWTClaims extends jwt.RegisteredClaims with custom fields
 type JWTClaims struct {
-	MemberID       string `json:"member_id"`
-	MachineOwnerID string `json:"machine_owner_id,omitempty"`
-	Role           string `json:"role"`
+	MemberID       string   `json:"member_id"`
+	MachineOwnerID string   `json:"machine_owner_id,omitempty"`
+	Role           string   `json:"role"`
+	Permissions    []string `json:"permissions,omitempty"`
+	SessionID      string   `json:"sid,omitempty"` // 登录会话，刷新令牌轮换时保持不变
 	jwt.RegisteredClaims
 }
 
+// TokenRevocationChecker 检查访问令牌所属的登录会话是否已被吊销
+type TokenRevocationChecker interface {
+	IsRevoked(claims *JWTClaims) (bool, error)
+}
+
+// revocationChecker 进程级吊销检查，启动时通过 SetTokenRevocationChecker 设置
+var revocationChecker TokenRevocationChecker
+
+// SetTokenRevocationChecker 设置 JWTAuth 使用的吊销检查，传入nil时不检查吊销
+func SetTokenRevocationChecker(checker TokenRevocationChecker) {
+	revocationChecker = checker
+}
+
 // JWTAuth JWT认证中间件
 func JWTAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -89,27 +104,53 @@ func JWTAuth() gin.HandlerFunc {
 			return
 		}
 
-		// 将用户信息添加到上下文
+		if rejectRevokedToken(c, claims) {
+			return
+		}
+
+		// 将用户信息添加到上下文，角色统一为角色编码（兼容旧版Token的数字角色）
+		role := enums.ParseMemberRole(claims.Role)
+		permissions := claims.Permissions
+		if len(permissions) == 0 {
+			permissions = PermissionCodes(role)
+		}
 		c.Set("member_id", claims.MemberID)
 		c.Set("machine_owner_id", claims.MachineOwnerID)
-		c.Set("role", claims.Role)
+		c.Set("role", role.Code())
+		c.Set("permissions", permissions)
+		c.Set("session_id", claims.SessionID)
 
 		c.Next()
 	}
 }
 
-// validateJWT 验证JWT token
-func validateJWT(tokenString string) (*JWTClaims, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		// #nosec G101 - This is a fallback for development only
-		jwtSecret = "default_jwt_secret_change_this_in_production"
+// rejectRevokedToken 令牌所属会话已吊销时返回401，已写入响应时返回true
+// 不含会话的旧版Token无法吊销，在过期前仍然有效
+func rejectRevokedToken(c *gin.Context, claims *JWTClaims) bool {
+	if revocationChecker == nil || claims.SessionID == "" {
+		return false
 	}
 
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(jwtSecret), nil
-	})
+	revoked, err := revocationChecker.IsRevoked(claims)
+	if err != nil {
+		abortWithError(c, http.StatusInternalServerError, contracts.ErrorCodeInternalServer, "登录状态校验失败")
+		return true
+	}
+	if revoked {
+		abortWithError(c, http.StatusUnauthorized, contracts.ErrorCodeTokenRevoked, "登录已失效，请重新登录")
+		return true
+	}
+	return false
+}
+
+// validateJWT 验证JWT token，按kid选择密钥并校验签名算法
+func validateJWT(tokenString string) (*JWTClaims, error) {
+	manager, err := JWTKeyManager()
+	if err != nil {
+		return nil, err
+	}
 
+	token, err := manager.Parse(tokenString, &JWTClaims{})
 	if err != nil {
 		return nil, err
 	}
@@ -152,6 +193,16 @@ func GetCurrentMachineOwnerID(c *gin.Context) (string, bool) {
 	return machineOwnerID.(string), true
 }
 
+// GetCurrentSessionID 获取当前登录会话ID的辅助函数
+func GetCurrentSessionID(c *gin.Context) (string, bool) {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		return "", false
+	}
+	id, _ := sessionID.(string)
+	return id, id != ""
+}
+
 // GetCurrentRole 获取当前用户角色的辅助函数
 func GetCurrentRole(c *gin.Context) (string, bool) {
 	role, exists := c.Get("role")
@@ -161,8 +212,50 @@ func GetCurrentRole(c *gin.Context) (string, bool) {
 	return role.(string), true
 }
 
-// IsMachineOwner 检查当前用户是否为机主
-func IsMachineOwner(c *gin.Context) bool {
+// GetCurrentMemberRole 获取当前用户的授权角色
+func GetCurrentMemberRole(c *gin.Context) (enums.MemberRole, bool) {
 	role, exists := GetCurrentRole(c)
-	return exists && role == "Owner"
+	if !exists || role == "" {
+		return 0, false
+	}
+	return enums.ParseMemberRole(role), true
+}
+
+// IsMachineOwner 检查当前用户是否为机主，同时是机主的管理员视为机主
+func IsMachineOwner(c *gin.Context) bool {
+	role, exists := GetCurrentMemberRole(c)
+	if !exists {
+		return false
+	}
+	if role == enums.MemberRoleAdmin {
+		machineOwnerID, _ := GetCurrentMachineOwnerID(c)
+		return machineOwnerID != ""
+	}
+	return role == enums.MemberRoleOwner
+}
+
+// HasPermission 检查当前用户Token中是否包含指定权限，未设置权限列表时按角色权限判断
+func HasPermission(c *gin.Context, permission enums.Permission) bool {
+	value, exists := c.Get("permissions")
+	if !exists {
+		role, ok := GetCurrentMemberRole(c)
+		return ok && role.HasPermission(permission)
+	}
+	permissions, _ := value.([]string)
+	for _, p := range permissions {
+		if p == string(permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionCodes 角色权限列表，写入JWT的 permissions 声明
+func PermissionCodes(role enums.MemberRole) []string {
+	permissions := role.Permissions()
+	codes := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		codes = append(codes, string(permission))
+	}
+	return codes
 }
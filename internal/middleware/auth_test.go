@@ -9,6 +9,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/pkg/jwtkeys"
 )
 
 func setupTestAuth() *gin.Engine {
@@ -224,3 +227,160 @@ func TestIsMachineOwner(t *testing.T) {
 		t.Error("Expected IsMachineOwner to return true for Owner role")
 	}
 }
+
+func TestJWTAuth_NormalizesRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(JWTAuth())
+	router.GET("/protected", func(c *gin.Context) {
+		role, _ := GetCurrentRole(c)
+		c.JSON(200, gin.H{
+			"role":      role,
+			"isOwner":   IsMachineOwner(c),
+			"canManage": HasPermission(c, enums.PermissionOrderManage),
+		})
+	})
+
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		secret = "default_jwt_secret_change_this_in_production"
+	}
+	// 旧版Token中的数字角色且不含权限声明
+	claims := &JWTClaims{
+		MemberID:       "test_member_123",
+		MachineOwnerID: "test_owner_456",
+		Role:           "2",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("Failed to generate test JWT: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	expected := `{"canManage":true,"isOwner":true,"role":"Owner"}`
+	if w.Code != http.StatusOK || w.Body.String() != expected {
+		t.Errorf("Expected %s, got %d %s", expected, w.Code, w.Body.String())
+	}
+}
+
+func TestIsMachineOwner_Admin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	c.Set("role", "Admin")
+	if IsMachineOwner(c) {
+		t.Error("Expected IsMachineOwner to return false for Admin without machine owner")
+	}
+
+	c.Set("machine_owner_id", "owner-1")
+	if !IsMachineOwner(c) {
+		t.Error("Expected IsMachineOwner to return true for Admin with machine owner")
+	}
+
+	c.Set("role", "Maintainer")
+	if IsMachineOwner(c) {
+		t.Error("Expected IsMachineOwner to return false for Maintainer role")
+	}
+}
+
+type stubRevocationChecker struct {
+	revoked map[string]bool
+}
+
+func (s *stubRevocationChecker) IsRevoked(claims *JWTClaims) (bool, error) {
+	return s.revoked[claims.SessionID], nil
+}
+
+func TestJWTAuth_RejectsRevokedSession(t *testing.T) {
+	SetTokenRevocationChecker(&stubRevocationChecker{revoked: map[string]bool{"session-revoked": true}})
+	defer SetTokenRevocationChecker(nil)
+
+	router := setupTestAuth()
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		secret = "default_jwt_secret_change_this_in_production"
+	}
+
+	tests := []struct {
+		sessionID string
+		expected  int
+	}{
+		{"session-revoked", http.StatusUnauthorized},
+		{"session-active", http.StatusOK},
+		{"", http.StatusOK}, // 旧版Token不含会话
+	}
+
+	for _, tt := range tests {
+		claims := &JWTClaims{
+			MemberID:  "test_member_123",
+			Role:      "Consumer",
+			SessionID: tt.sessionID,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatalf("Failed to generate test JWT: %v", err)
+		}
+
+		req, _ := http.NewRequest("GET", "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.expected {
+			t.Errorf("Expected session %q to get status %d, got %d", tt.sessionID, tt.expected, w.Code)
+		}
+	}
+}
+
+func TestJWTAuth_KeyRotation(t *testing.T) {
+	oldKey, _ := jwtkeys.NewHMACKey("2024", []byte("old-secret"))
+	newKey, _ := jwtkeys.NewHMACKey("2025", []byte("new-secret"))
+	oldManager, _ := jwtkeys.NewManager(oldKey)
+	rotated, _ := jwtkeys.NewManager(newKey, oldKey)
+	retired, _ := jwtkeys.NewManager(newKey)
+	defer SetJWTKeyManager(nil)
+
+	claims := &JWTClaims{
+		MemberID: "test_member_123",
+		Role:     "Consumer",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	oldToken, err := oldManager.Sign(claims)
+	if err != nil {
+		t.Fatalf("Failed to sign test JWT: %v", err)
+	}
+
+	tests := []struct {
+		manager  *jwtkeys.Manager
+		expected int
+	}{
+		{rotated, http.StatusOK},           // 轮换期内旧kid仍可验证
+		{retired, http.StatusUnauthorized}, // 旧密钥下线后失效
+	}
+
+	router := setupTestAuth()
+	for _, tt := range tests {
+		SetJWTKeyManager(tt.manager)
+
+		req, _ := http.NewRequest("GET", "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+oldToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.expected {
+			t.Errorf("Expected status %d, got %d", tt.expected, w.Code)
+		}
+	}
+}
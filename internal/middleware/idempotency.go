@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+)
+
+// 幂等请求头与记录保留策略
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	idempotencyKeyMaxLength  = 128
+	idempotencyRecordTTL     = 24 * time.Hour
+	idempotencyProcessingTTL = 5 * time.Minute // 超过该时间未续期的首次请求视为处理进程已退出，需长于支付渠道调用超时
+	idempotencyRenewInterval = time.Minute     // 首次请求处理期间续期幂等键的间隔
+)
+
+// idempotencyResponseWriter 记录处理结果的响应体，用于重复请求时重放
+type idempotencyResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyResponseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency 幂等请求中间件，需在JWTAuth之后使用
+// 携带 Idempotency-Key 头的请求按 会员+接口+键 记录请求指纹与响应：
+// 相同请求重复提交时重放首次响应，同一个键用于不同请求体时返回422，首次请求仍在处理时返回409。
+// 首次请求处理期间定期续期幂等键，处理完成前重复请求始终返回409；5xx响应不记录，客户端可以用同一个键重试。
+// 过期记录由数据清理任务删除。
+func Idempotency(repo repositories.IdempotencyRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > idempotencyKeyMaxLength {
+			abortWithError(c, http.StatusBadRequest, contracts.ErrorCodeValidation, "Idempotency-Key长度不能超过128")
+			return
+		}
+
+		memberID, _ := GetCurrentMemberID(c)
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortWithError(c, http.StatusBadRequest, contracts.ErrorCodeValidation, "读取请求体失败")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now()
+		record := &models.IdempotencyRecord{
+			MemberId:       memberID,
+			Scope:          c.FullPath(),
+			IdempotencyKey: key,
+			Fingerprint:    requestFingerprint(c.Request.Method, c.FullPath(), body),
+			ExpiresOn:      now.Add(idempotencyRecordTTL),
+			CreatedOn:      now,
+		}
+		started, err := repo.Begin(record, now.Add(-idempotencyProcessingTTL))
+		if err != nil {
+			logrus.WithError(err).Error("幂等请求记录保存失败")
+			abortWithError(c, http.StatusInternalServerError, contracts.ErrorCodeInternalServer, "服务器内部错误")
+			return
+		}
+		if !started {
+			replayIdempotentResponse(c, repo, record)
+			return
+		}
+
+		processIdempotentRequest(c, repo, record)
+	}
+}
+
+// processIdempotentRequest 处理首次请求并记录响应
+func processIdempotentRequest(c *gin.Context, repo repositories.IdempotencyRepository,
+	record *models.IdempotencyRecord) {
+	writer := &idempotencyResponseWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+
+	stopRenew := renewIdempotencyKey(repo, record, idempotencyRenewInterval)
+	completed := false
+	defer func() {
+		stopRenew()
+		if completed {
+			return
+		}
+		// 处理失败或panic时释放幂等键，客户端可以重试
+		if err := repo.Delete(record); err != nil {
+			logrus.WithError(err).Warn("幂等请求记录删除失败")
+		}
+	}()
+
+	c.Next()
+
+	if writer.Status() >= http.StatusInternalServerError {
+		return
+	}
+	record.StatusCode = writer.Status()
+	contentType := writer.Header().Get("Content-Type")
+	responseBody := writer.body.String()
+	record.ContentType = &contentType
+	record.ResponseBody = &responseBody
+	if err := repo.Complete(record); err != nil {
+		logrus.WithError(err).Error("幂等请求响应保存失败")
+		return
+	}
+	completed = true
+}
+
+// renewIdempotencyKey 在首次请求处理期间定期续期幂等键，返回停止续期的函数
+func renewIdempotencyKey(repo repositories.IdempotencyRepository, record *models.IdempotencyRecord,
+	interval time.Duration) func() {
+	key := *record
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				held, err := repo.Renew(&key, now)
+				if err != nil {
+					logrus.WithError(err).Warn("幂等请求记录续期失败")
+					continue
+				}
+				if !held {
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// replayIdempotentResponse 处理幂等键已被占用的重复请求
+func replayIdempotentResponse(c *gin.Context, repo repositories.IdempotencyRepository,
+	record *models.IdempotencyRecord) {
+	existing, err := repo.Get(record.MemberId, record.Scope, record.IdempotencyKey)
+	if err != nil {
+		logrus.WithError(err).Error("幂等请求记录查询失败")
+		abortWithError(c, http.StatusInternalServerError, contracts.ErrorCodeInternalServer, "服务器内部错误")
+		return
+	}
+	if existing == nil {
+		// 首次请求恰好处理失败并释放了幂等键
+		abortWithError(c, http.StatusConflict, contracts.ErrorCodeConflict, "请求正在处理，请稍后重试")
+		return
+	}
+	if existing.Fingerprint != record.Fingerprint {
+		abortWithError(c, http.StatusUnprocessableEntity, contracts.ErrorCodeIdempotencyReused,
+			"Idempotency-Key已用于其他请求")
+		return
+	}
+	if !existing.IsCompleted() {
+		abortWithError(c, http.StatusConflict, contracts.ErrorCodeConflict, "请求正在处理，请稍后重试")
+		return
+	}
+
+	contentType := "application/json; charset=utf-8"
+	if existing.ContentType != nil && *existing.ContentType != "" {
+		contentType = *existing.ContentType
+	}
+	var body []byte
+	if existing.ResponseBody != nil {
+		body = []byte(*existing.ResponseBody)
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Data(existing.StatusCode, contentType, body)
+	c.Abort()
+}
+
+// requestFingerprint 计算请求指纹，JSON请求体按规范化后的内容计算，字段顺序与空白不影响结果
+func requestFingerprint(method, path string, body []byte) string {
+	canonical := body
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err == nil {
+		if encoded, marshalErr := json.Marshal(value); marshalErr == nil {
+			canonical = encoded
+		}
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(canonical)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// abortWithError 返回错误响应并终止请求
+func abortWithError(c *gin.Context, status int, code, message string) {
+	c.JSON(status, contracts.APIResponse{
+		Success: false,
+		Error: &contracts.APIError{
+			Code:      code,
+			Message:   message,
+			Timestamp: time.Now(),
+			Path:      c.Request.URL.Path,
+			Method:    c.Request.Method,
+			RequestID: getRequestID(c),
+		},
+	})
+	c.Abort()
+}
@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+)
+
+func setupTestIdempotency(t *testing.T, handler gin.HandlerFunc) *gin.Engine {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.IdempotencyRecord{}))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("member_id", c.GetHeader("X-Member"))
+		c.Next()
+	})
+	router.POST("/api/Order/Create", Idempotency(repositories.NewIdempotencyRepository(db)), handler)
+	return router
+}
+
+func sendIdempotentRequest(router *gin.Engine, member, key, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/api/Order/Create", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Member", member)
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var response contracts.APIResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.NotNil(t, response.Error)
+	return response.Error.Code
+}
+
+func TestIdempotency_ReplaysResponse(t *testing.T) {
+	calls := 0
+	router := setupTestIdempotency(t, func(c *gin.Context) {
+		calls++
+		var req map[string]interface{}
+		_ = c.ShouldBindJSON(&req)
+		c.JSON(http.StatusOK, gin.H{"call": calls, "machineId": req["machineId"]})
+	})
+
+	first := sendIdempotentRequest(router, "member-1", "key-1", `{"machineId":"m1","productId":"p1"}`)
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+
+	// 字段顺序与空白不同的相同请求重放首次响应
+	second := sendIdempotentRequest(router, "member-1", "key-1", `{ "productId": "p1", "machineId": "m1" }`)
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, first.Header().Get("Content-Type"), second.Header().Get("Content-Type"))
+	assert.Equal(t, 1, calls)
+
+	// 幂等键按会员隔离
+	other := sendIdempotentRequest(router, "member-2", "key-1", `{"machineId":"m1","productId":"p1"}`)
+	assert.Equal(t, http.StatusOK, other.Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_KeyReusedWithDifferentBody(t *testing.T) {
+	calls := 0
+	router := setupTestIdempotency(t, func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	sendIdempotentRequest(router, "member-1", "key-1", `{"machineId":"m1"}`)
+	w := sendIdempotentRequest(router, "member-1", "key-1", `{"machineId":"m2"}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, contracts.ErrorCodeIdempotencyReused, errorCode(t, w))
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotency_ServerErrorReleasesKey(t *testing.T) {
+	calls := 0
+	router := setupTestIdempotency(t, func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	first := sendIdempotentRequest(router, "member-1", "key-1", `{}`)
+	assert.Equal(t, http.StatusInternalServerError, first.Code)
+
+	second := sendIdempotentRequest(router, "member-1", "key-1", `{}`)
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Empty(t, second.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_RequestInProgress(t *testing.T) {
+	var router *gin.Engine
+	var nested *httptest.ResponseRecorder
+	router = setupTestIdempotency(t, func(c *gin.Context) {
+		if nested == nil {
+			// 首次请求处理期间到达的重复请求
+			nested = sendIdempotentRequest(router, "member-1", "key-1", `{}`)
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	w := sendIdempotentRequest(router, "member-1", "key-1", `{}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, nested)
+	assert.Equal(t, http.StatusConflict, nested.Code)
+	assert.Equal(t, contracts.ErrorCodeConflict, errorCode(t, nested))
+}
+
+func TestIdempotency_WithoutKey(t *testing.T) {
+	calls := 0
+	router := setupTestIdempotency(t, func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	sendIdempotentRequest(router, "member-1", "", `{}`)
+	sendIdempotentRequest(router, "member-1", "", `{}`)
+	assert.Equal(t, 2, calls)
+
+	w := sendIdempotentRequest(router, "member-1", strings.Repeat("k", 129), `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, contracts.ErrorCodeValidation, errorCode(t, w))
+}
+
+func TestRenewIdempotencyKey(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.IdempotencyRecord{}))
+	repo := repositories.NewIdempotencyRepository(db)
+
+	startedOn := time.Now().Add(-time.Hour)
+	record := &models.IdempotencyRecord{
+		MemberId: "member-1", Scope: "/api/Order/Refund", IdempotencyKey: "key-1",
+		Fingerprint: "fp-1", ExpiresOn: startedOn.Add(idempotencyRecordTTL), CreatedOn: startedOn,
+	}
+	started, err := repo.Begin(record, startedOn)
+	require.NoError(t, err)
+	require.True(t, started)
+
+	// 首次请求处理期间持续续期，处理时间超过处理超时也不会被重复请求占用
+	stop := renewIdempotencyKey(repo, record, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		saved, getErr := repo.Get("member-1", "/api/Order/Refund", "key-1")
+		return getErr == nil && saved.UpdatedOn != nil && saved.UpdatedOn.After(startedOn)
+	}, time.Second, 10*time.Millisecond)
+	stop()
+
+	now := time.Now()
+	retry := *record
+	retry.CreatedOn = now
+	started, err = repo.Begin(&retry, now.Add(-idempotencyProcessingTTL))
+	require.NoError(t, err)
+	assert.False(t, started)
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ddteam/drink-master/internal/config"
+	"github.com/ddteam/drink-master/pkg/jwtkeys"
+)
+
+// 进程级JWT密钥管理器，签发（JWTService）与验证（JWTAuth）共用
+var (
+	keyManagerMu sync.RWMutex
+	keyManager   *jwtkeys.Manager
+)
+
+// SetJWTKeyManager 设置进程级JWT密钥管理器，服务启动时根据配置创建后设置
+func SetJWTKeyManager(manager *jwtkeys.Manager) {
+	keyManagerMu.Lock()
+	defer keyManagerMu.Unlock()
+	keyManager = manager
+}
+
+// JWTKeyManager 获取进程级JWT密钥管理器，未设置时按环境变量创建
+func JWTKeyManager() (*jwtkeys.Manager, error) {
+	keyManagerMu.RLock()
+	manager := keyManager
+	keyManagerMu.RUnlock()
+	if manager != nil {
+		return manager, nil
+	}
+
+	keyManagerMu.Lock()
+	defer keyManagerMu.Unlock()
+	if keyManager == nil {
+		created, err := config.NewJWTConfig().NewKeyManager(gin.Mode() == gin.ReleaseMode)
+		if err != nil {
+			return nil, err
+		}
+		keyManager = created
+	}
+	return keyManager, nil
+}
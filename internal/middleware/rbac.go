@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
+)
+
+// RequireRole 角色授权中间件，需在 JWTAuth 之后使用
+// 当前用户角色不在允许列表中时返回403，管理员可访问所有受角色保护的接口
+func RequireRole(roles ...enums.MemberRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, exists := GetCurrentMemberRole(c)
+		if !exists {
+			abortWithError(c, http.StatusUnauthorized, contracts.ErrorCodeUnauthorized, "无效的用户信息")
+			return
+		}
+
+		if role == enums.MemberRoleAdmin {
+			c.Next()
+			return
+		}
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		abortWithError(c, http.StatusForbidden, contracts.ErrorCodeForbidden, "没有访问该接口的权限")
+	}
+}
+
+// RequirePermission 权限授权中间件，需在 JWTAuth 之后使用
+// 当前用户Token中不含指定权限时返回403，管理员拥有全部权限
+func RequirePermission(permission enums.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, exists := GetCurrentMemberRole(c); !exists {
+			abortWithError(c, http.StatusUnauthorized, contracts.ErrorCodeUnauthorized, "无效的用户信息")
+			return
+		}
+		if !HasPermission(c, permission) {
+			abortWithError(c, http.StatusForbidden, contracts.ErrorCodeForbidden, "没有访问该接口的权限")
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// IdempotencyRecord 幂等请求记录
+// 同一会员在同一接口以相同 Idempotency-Key 重复提交时，按记录重放首次请求的响应
+type IdempotencyRecord struct {
+	MemberId       string     `json:"memberId" gorm:"primaryKey;type:varchar(36);column:MemberId"`
+	Scope          string     `json:"scope" gorm:"primaryKey;type:varchar(128);column:Scope"`
+	IdempotencyKey string     `json:"idempotencyKey" gorm:"primaryKey;type:varchar(128);column:IdempotencyKey"`
+	Fingerprint    string     `json:"fingerprint" gorm:"type:varchar(64);column:Fingerprint"`
+	StatusCode     int        `json:"statusCode" gorm:"type:int;column:StatusCode"` // 0 表示首次请求处理中
+	ContentType    *string    `json:"contentType" gorm:"type:varchar(128);column:ContentType"`
+	ResponseBody   *string    `json:"responseBody" gorm:"type:text;column:ResponseBody"`
+	ExpiresOn      time.Time  `json:"expiresOn" gorm:"index;column:ExpiresOn"`
+	CreatedOn      time.Time  `json:"createdOn" gorm:"column:CreatedOn"`
+	UpdatedOn      *time.Time `json:"updatedOn" gorm:"column:UpdatedOn"`
+}
+
+// TableName returns the table name for IdempotencyRecord
+func (IdempotencyRecord) TableName() string {
+	return "idempotency_records"
+}
+
+// IsCompleted 首次请求是否已处理完成并记录了响应
+func (r *IdempotencyRecord) IsCompleted() bool {
+	return r.StatusCode != 0
+}
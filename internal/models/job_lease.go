@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// JobLease 后台任务租约
+// 多实例部署时同一任务同一时刻只由持有未过期租约的实例执行
+type JobLease struct {
+	Name      string     `json:"name" gorm:"primaryKey;type:varchar(64);column:Name"`
+	Holder    string     `json:"holder" gorm:"type:varchar(128);column:Holder"`
+	ExpiresOn time.Time  `json:"expiresOn" gorm:"column:ExpiresOn"`
+	CreatedOn time.Time  `json:"createdOn" gorm:"column:CreatedOn"`
+	UpdatedOn *time.Time `json:"updatedOn" gorm:"column:UpdatedOn"`
+}
+
+// TableName returns the table name for JobLease
+func (JobLease) TableName() string {
+	return "job_leases"
+}
@@ -10,9 +10,12 @@ type MachineOwner struct {
 	Name                 *string    `json:"name" gorm:"type:varchar(32);column:Name"`
 	Mobile               *string    `json:"mobile" gorm:"type:varchar(11);column:Mobile"`
 	Email                *string    `json:"email" gorm:"type:varchar(64);column:Email"`
-	ReceivingAccount     *string    `json:"receivingAccount" gorm:"type:varchar(16);column:ReceivingAccount"`
-	ReceivingKey         *string    `json:"receivingKey" gorm:"type:varchar(32);column:ReceivingKey"`
+	ReceivingAccount     *string    `json:"receivingAccount" gorm:"type:varchar(16);index:idx_owner_merchant;column:ReceivingAccount"`
+	ReceivingKey         *string    `json:"-" gorm:"type:varchar(256);column:ReceivingKey"` // 加密存储
 	ReceivingOrderPrefix *string    `json:"receivingOrderPrefix" gorm:"type:varchar(5);column:ReceivingOrderPrefix"`
+	ChannelCode          *string    `json:"channelCode" gorm:"type:varchar(32);index:idx_owner_merchant;column:ChannelCode"`
+	MerchantSerialNo     *string    `json:"merchantSerialNo" gorm:"type:varchar(64);column:MerchantSerialNo"`
+	MerchantPrivateKey   *string    `json:"-" gorm:"type:text;column:MerchantPrivateKey"` // 加密存储
 	Version              int64      `json:"version" gorm:"column:Version"`
 	CreatedOn            time.Time  `json:"createdOn" gorm:"column:CreatedOn"`
 	UpdatedOn            *time.Time `json:"updatedOn" gorm:"column:UpdatedOn"`
@@ -14,6 +14,7 @@ type MaterialSilo struct {
 	IsSale     BitBool    `json:"isSale" gorm:"column:IsSale"`
 	Total      int        `json:"total" gorm:"type:int;column:Total"`
 	Stock      int        `json:"stock" gorm:"type:int;column:Stock"`
+	Reserved   int        `json:"reserved" gorm:"type:int;default:0;column:Reserved"`
 	SingleFeed int        `json:"singleFeed" gorm:"type:int;column:SingleFeed"`
 	Version    int64      `json:"version" gorm:"column:Version"`
 	CreatedOn  time.Time  `json:"createdOn" gorm:"column:CreatedOn"`
@@ -51,10 +52,15 @@ func (ms *MaterialSilo) GetStockPercentage() float64 {
 	return (float64(ms.Stock) / float64(ms.Total)) * 100
 }
 
-// CanSale checks if the silo can be sold (has product, has stock, and sale is enabled)
+// AvailableStock returns the stock not yet reserved by unfinished orders
+func (ms *MaterialSilo) AvailableStock() int {
+	return ms.Stock - ms.Reserved
+}
+
+// CanSale checks if the silo can be sold (has product, has unreserved stock, and sale is enabled)
 func (ms *MaterialSilo) CanSale() bool {
 	return ms.ProductId != nil &&
-		ms.Stock > 0 &&
+		ms.AvailableStock() > 0 &&
 		ms.IsSale.Bool()
 }
 
@@ -134,6 +134,7 @@ func TestMaterialSilo_CanSale(t *testing.T) {
 		name      string
 		productId *string
 		stock     int
+		reserved  int
 		isSale    BitBool
 		expected  bool
 	}{
@@ -158,6 +159,14 @@ func TestMaterialSilo_CanSale(t *testing.T) {
 			isSale:    BitBool(1),
 			expected:  false,
 		},
+		{
+			name:      "cannot sale - all stock reserved",
+			productId: &productID,
+			stock:     2,
+			reserved:  2,
+			isSale:    BitBool(1),
+			expected:  false,
+		},
 		{
 			name:      "cannot sale - sale disabled",
 			productId: &productID,
@@ -179,6 +188,7 @@ func TestMaterialSilo_CanSale(t *testing.T) {
 			silo := &MaterialSilo{
 				ProductId: tt.productId,
 				Stock:     tt.stock,
+				Reserved:  tt.reserved,
 				IsSale:    tt.isSale,
 			}
 			assert.Equal(t, tt.expected, silo.CanSale())
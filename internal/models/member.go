@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"github.com/ddteam/drink-master/internal/enums"
 )
 
 // Member represents the member/user entity - matches production DB structure
@@ -10,6 +12,7 @@ type Member struct {
 	Nickname       *string    `json:"nickname" gorm:"type:varchar(32);column:Nickname"`
 	Avatar         *string    `json:"avatar" gorm:"type:varchar(255);column:Avatar"`
 	WeChatOpenId   *string    `json:"weChatOpenId" gorm:"type:varchar(36);column:WeChatOpenId"`
+	UnionId        *string    `json:"unionId" gorm:"type:varchar(64);index;column:UnionId"` // 微信开放平台UnionID，关联小程序与公众号
 	Role           int        `json:"role" gorm:"type:int;column:Role"`
 	MachineOwnerId *string    `json:"machineOwnerId" gorm:"type:varchar(36);column:MachineOwnerId"`
 	IsAdmin        BitBool    `json:"isAdmin" gorm:"column:IsAdmin"`
@@ -26,3 +29,27 @@ type Member struct {
 func (Member) TableName() string {
 	return "members"
 }
+
+// EffectiveRole 会员的授权角色，IsAdmin 的会员为管理员，未知角色按顾客处理
+func (m *Member) EffectiveRole() enums.MemberRole {
+	if m.IsAdmin.Bool() {
+		return enums.MemberRoleAdmin
+	}
+	role := enums.MemberRole(m.Role)
+	if !role.IsValid() {
+		return enums.MemberRoleConsumer
+	}
+	return role
+}
+
+// IsMachineOwner 会员是否为机主，同时是机主的管理员视为机主
+func (m *Member) IsMachineOwner() bool {
+	switch m.EffectiveRole() {
+	case enums.MemberRoleOwner:
+		return true
+	case enums.MemberRoleAdmin:
+		return m.MachineOwnerId != nil && *m.MachineOwnerId != ""
+	default:
+		return false
+	}
+}
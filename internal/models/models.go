@@ -38,5 +38,14 @@ func AllModels() []interface{} {
 		&Order{},
 		&FranchiseIntention{},
 		&MaterialSilo{},
+		&RefundRecord{},
+		&StockReservation{},
+		&JobLease{},
+		&OutboxEvent{},
+		&IdempotencyRecord{},
+		&RefundRequest{},
+		&RefreshToken{},
+		&TokenRevocation{},
+		&WeChatSession{},
 	}
 }
@@ -7,6 +7,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/enums"
 )
 
 func TestAutoMigrate(t *testing.T) {
@@ -51,6 +53,19 @@ func TestMemberModel(t *testing.T) {
 	assert.Equal(t, "测试用户", *foundMember.Nickname)
 }
 
+func TestMember_EffectiveRole(t *testing.T) {
+	ownerID := "owner-1"
+	assert.Equal(t, enums.MemberRoleConsumer, (&Member{Role: 1}).EffectiveRole())
+	assert.Equal(t, enums.MemberRoleOwner, (&Member{Role: 2}).EffectiveRole())
+	assert.Equal(t, enums.MemberRoleConsumer, (&Member{Role: 0}).EffectiveRole())
+	assert.Equal(t, enums.MemberRoleAdmin, (&Member{Role: 1, IsAdmin: BitBool(1)}).EffectiveRole())
+
+	assert.True(t, (&Member{Role: 2, MachineOwnerId: &ownerID}).IsMachineOwner())
+	assert.False(t, (&Member{Role: 3, MachineOwnerId: &ownerID}).IsMachineOwner())
+	assert.False(t, (&Member{Role: 2, IsAdmin: BitBool(1)}).IsMachineOwner())
+	assert.True(t, (&Member{Role: 2, IsAdmin: BitBool(1), MachineOwnerId: &ownerID}).IsMachineOwner())
+}
+
 func TestMachineModel(t *testing.T) {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	assert.NoError(t, err)
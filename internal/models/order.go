@@ -13,16 +13,20 @@ type Order struct {
 	MachineId      *string    `json:"machineId" gorm:"type:varchar(36);column:MachineId"`
 	ProductId      *string    `json:"productId" gorm:"type:varchar(36);column:ProductId"`
 	HasCup         BitBool    `json:"hasCup" gorm:"column:HasCup"`
-	OrderNo        *string    `json:"orderNo" gorm:"type:varchar(32);column:OrderNo"`
+	OrderNo        *string    `json:"orderNo" gorm:"type:varchar(32);uniqueIndex;column:OrderNo"`
 	TotalAmount    float64    `json:"totalAmount" gorm:"type:decimal(10,2);column:TotalAmount"`
 	PayAmount      float64    `json:"payAmount" gorm:"type:decimal(10,2);column:PayAmount"`
 	PaymentStatus  int        `json:"paymentStatus" gorm:"type:int;column:PaymentStatus"`
 	PaymentTime    *time.Time `json:"paymentTime" gorm:"column:PaymentTime"`
 	ChannelOrderNo *string    `json:"channelOrderNo" gorm:"type:varchar(32);column:ChannelOrderNo"`
+	ChannelCode    *string    `json:"channelCode" gorm:"type:varchar(32);column:ChannelCode"`
 	MakeStatus     int        `json:"makeStatus" gorm:"type:int;column:MakeStatus"`
 	RefundTime     *time.Time `json:"refundTime" gorm:"column:RefundTime"`
 	RefundAmount   float64    `json:"refundAmount" gorm:"type:decimal(10,2);column:RefundAmount"`
 	RefundReason   *string    `json:"refundReason" gorm:"type:varchar(512);column:RefundReason"`
+	ProductName    *string    `json:"productName" gorm:"type:varchar(64);column:ProductName"`     // 下单时的商品名称快照
+	ProductPrice   *float64   `json:"productPrice" gorm:"type:decimal(10,2);column:ProductPrice"` // 下单时的商品单价快照
+	MachineName    *string    `json:"machineName" gorm:"type:varchar(64);column:MachineName"`     // 下单时的售货机名称快照
 	Version        int64      `json:"version" gorm:"column:Version"`
 	CreatedOn      time.Time  `json:"createdOn" gorm:"column:CreatedOn"`
 	UpdatedOn      *time.Time `json:"updatedOn" gorm:"column:UpdatedOn"`
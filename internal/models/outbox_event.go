@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/ddteam/drink-master/internal/enums"
+)
+
+// OutboxEvent 领域事件发件箱
+// 事件与产生它的状态变更在同一事务中写入，由投递任务异步投递给订阅者，投递失败按退避策略重试
+// 超过保留期的事件由数据清理任务删除；自增序号即写入顺序，投递与订单事件流按序号排序
+type OutboxEvent struct {
+	Seq           int64      `json:"seq" gorm:"primaryKey;autoIncrement;column:Seq"`
+	ID            string     `json:"id" gorm:"type:varchar(36);uniqueIndex;column:Id"`
+	EventType     string     `json:"eventType" gorm:"type:varchar(64);column:EventType"`
+	AggregateId   string     `json:"aggregateId" gorm:"type:varchar(36);index;column:AggregateId"`
+	Payload       string     `json:"payload" gorm:"type:text;column:Payload"`
+	Status        int        `json:"status" gorm:"type:int;index:idx_outbox_due,priority:1;column:Status"`
+	Attempts      int        `json:"attempts" gorm:"type:int;column:Attempts"`
+	LastError     *string    `json:"lastError" gorm:"type:varchar(512);column:LastError"`
+	NextAttemptOn time.Time  `json:"nextAttemptOn" gorm:"index:idx_outbox_due,priority:2;column:NextAttemptOn"`
+	DeliveredOn   *time.Time `json:"deliveredOn" gorm:"column:DeliveredOn"`
+	CreatedOn     time.Time  `json:"createdOn" gorm:"index:idx_outbox_created;column:CreatedOn"`
+	UpdatedOn     *time.Time `json:"updatedOn" gorm:"column:UpdatedOn"`
+}
+
+// TableName returns the table name for OutboxEvent
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// GetStatusDesc 获取投递状态描述
+func (e *OutboxEvent) GetStatusDesc() string {
+	return enums.GetOutboxStatusDesc(enums.OutboxStatus(e.Status))
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// RefreshToken 刷新令牌
+// 每次刷新时轮换为新的令牌，同一登录会话（SessionId）内的令牌构成轮换链，只存储令牌的SHA-256摘要
+type RefreshToken struct {
+	ID           string     `json:"id" gorm:"primaryKey;type:varchar(36);column:Id"`
+	MemberId     string     `json:"memberId" gorm:"type:varchar(36);index;column:MemberId"`
+	SessionId    string     `json:"sessionId" gorm:"type:varchar(36);index;column:SessionId"`
+	TokenHash    string     `json:"-" gorm:"type:varchar(64);uniqueIndex;column:TokenHash"`
+	Role         string     `json:"role" gorm:"type:varchar(16);column:Role"` // 签发时的角色编码，角色变更后刷新失败
+	ExpiresOn    time.Time  `json:"expiresOn" gorm:"index;column:ExpiresOn"`  // 过期后由数据清理任务删除
+	RevokedOn    *time.Time `json:"revokedOn" gorm:"column:RevokedOn"`
+	ReplacedById *string    `json:"replacedById" gorm:"type:varchar(36);column:ReplacedById"` // 轮换后的新令牌
+	CreatedOn    time.Time  `json:"createdOn" gorm:"column:CreatedOn"`
+}
+
+// TableName returns the table name for RefreshToken
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// IsActive 令牌未吊销、未轮换且未过期
+func (t *RefreshToken) IsActive(now time.Time) bool {
+	return t.RevokedOn == nil && now.Before(t.ExpiresOn)
+}
+
+// TokenRevocation 登录会话吊销列表
+// 会话吊销后，该会话签发的访问令牌在过期前也不再被接受
+type TokenRevocation struct {
+	SessionId string    `json:"sessionId" gorm:"primaryKey;type:varchar(36);column:SessionId"`
+	MemberId  string    `json:"memberId" gorm:"type:varchar(36);index;column:MemberId"`
+	Reason    string    `json:"reason" gorm:"type:varchar(32);column:Reason"`
+	ExpiresOn time.Time `json:"expiresOn" gorm:"index;column:ExpiresOn"` // 会话令牌均已过期后可清理
+	CreatedOn time.Time `json:"createdOn" gorm:"column:CreatedOn"`
+}
+
+// TableName returns the table name for TokenRevocation
+func (TokenRevocation) TableName() string {
+	return "token_revocations"
+}
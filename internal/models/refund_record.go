@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/ddteam/drink-master/internal/enums"
+)
+
+// RefundRecord 退款记录
+// RefundNo 作为渠道侧退款单号唯一，重复发起同一退款不会产生多笔退款
+type RefundRecord struct {
+	ID              string     `json:"id" gorm:"primaryKey;type:varchar(36);column:Id"`
+	OrderId         string     `json:"orderId" gorm:"type:varchar(36);index;column:OrderId"`
+	RefundNo        string     `json:"refundNo" gorm:"type:varchar(64);uniqueIndex;column:RefundNo"`
+	Amount          float64    `json:"amount" gorm:"type:decimal(10,2);column:Amount"`
+	Reason          *string    `json:"reason" gorm:"type:varchar(512);column:Reason"`
+	Status          int        `json:"status" gorm:"type:int;column:Status"`
+	Attempts        int        `json:"attempts" gorm:"type:int;column:Attempts"`
+	LastError       *string    `json:"lastError" gorm:"type:varchar(512);column:LastError"`
+	ChannelRefundNo *string    `json:"channelRefundNo" gorm:"type:varchar(64);column:ChannelRefundNo"`
+	NextRetryOn     *time.Time `json:"nextRetryOn" gorm:"column:NextRetryOn"`
+	CompletedOn     *time.Time `json:"completedOn" gorm:"column:CompletedOn"`
+	Version         int64      `json:"version" gorm:"column:Version"`
+	CreatedOn       time.Time  `json:"createdOn" gorm:"column:CreatedOn"`
+	UpdatedOn       *time.Time `json:"updatedOn" gorm:"column:UpdatedOn"`
+}
+
+// TableName returns the table name for RefundRecord
+func (RefundRecord) TableName() string {
+	return "refund_records"
+}
+
+// GetStatusDesc 获取退款状态描述
+func (r *RefundRecord) GetStatusDesc() string {
+	return enums.GetRefundStatusDesc(enums.RefundStatus(r.Status))
+}
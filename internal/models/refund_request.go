@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/ddteam/drink-master/internal/enums"
+)
+
+// RefundRequest 顾客退款申请
+// 顾客对已支付订单提交申请，由售货机机主审核，同意后按订单剩余可退金额发起退款
+type RefundRequest struct {
+	ID             string     `json:"id" gorm:"primaryKey;type:varchar(36);column:Id"`
+	OrderId        string     `json:"orderId" gorm:"type:varchar(36);index;column:OrderId"`
+	MemberId       string     `json:"memberId" gorm:"type:varchar(36);index;column:MemberId"`
+	MachineId      string     `json:"machineId" gorm:"type:varchar(36);column:MachineId"`
+	MachineOwnerId string     `json:"machineOwnerId" gorm:"type:varchar(36);index:idx_refund_req;column:MachineOwnerId"`
+	Amount         float64    `json:"amount" gorm:"type:decimal(10,2);column:Amount"`
+	Reason         string     `json:"reason" gorm:"type:varchar(512);column:Reason"`
+	PhotoUrl       *string    `json:"photoUrl" gorm:"type:varchar(512);column:PhotoUrl"`
+	Status         int        `json:"status" gorm:"type:int;index:idx_refund_req;column:Status"`
+	ReviewerId     *string    `json:"reviewerId" gorm:"type:varchar(36);column:ReviewerId"`
+	ReviewRemark   *string    `json:"reviewRemark" gorm:"type:varchar(512);column:ReviewRemark"`
+	ReviewedOn     *time.Time `json:"reviewedOn" gorm:"column:ReviewedOn"`
+	RefundNo       *string    `json:"refundNo" gorm:"type:varchar(64);column:RefundNo"`
+	LastError      *string    `json:"lastError" gorm:"type:varchar(512);column:LastError"` // 同意后退款失败的原因
+	Version        int64      `json:"version" gorm:"column:Version"`
+	CreatedOn      time.Time  `json:"createdOn" gorm:"column:CreatedOn"`
+	UpdatedOn      *time.Time `json:"updatedOn" gorm:"column:UpdatedOn"`
+}
+
+// TableName returns the table name for RefundRequest
+func (RefundRequest) TableName() string {
+	return "refund_requests"
+}
+
+// GetStatusDesc 获取审核状态描述
+func (r *RefundRequest) GetStatusDesc() string {
+	return enums.GetRefundRequestStatusDesc(enums.RefundRequestStatus(r.Status))
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/ddteam/drink-master/internal/enums"
+)
+
+// StockReservation 订单预占的料仓库存
+// 每个订单最多一条预占记录，制作完成时扣减库存，支付失败或制作失败时释放
+type StockReservation struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:varchar(36);column:Id"`
+	OrderId   string     `json:"orderId" gorm:"type:varchar(36);uniqueIndex;column:OrderId"`
+	SiloId    string     `json:"siloId" gorm:"type:varchar(36);index;column:SiloId"`
+	Quantity  int        `json:"quantity" gorm:"type:int;column:Quantity"`
+	Status    int        `json:"status" gorm:"type:int;column:Status"`
+	Version   int64      `json:"version" gorm:"column:Version"`
+	CreatedOn time.Time  `json:"createdOn" gorm:"column:CreatedOn"`
+	UpdatedOn *time.Time `json:"updatedOn" gorm:"column:UpdatedOn"`
+}
+
+// TableName returns the table name for StockReservation
+func (StockReservation) TableName() string {
+	return "stock_reservations"
+}
+
+// GetStatusDesc 获取预占状态描述
+func (r *StockReservation) GetStatusDesc() string {
+	return enums.GetReservationStatusDesc(enums.ReservationStatus(r.Status))
+}
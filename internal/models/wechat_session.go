@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// WeChatSession 会员最近一次微信登录的会话密钥
+// session_key 用于解密小程序 encryptedData，每次登录后覆盖，加密存储
+type WeChatSession struct {
+	MemberId   string    `json:"memberId" gorm:"primaryKey;type:varchar(36);column:MemberId"`
+	OpenId     string    `json:"openId" gorm:"type:varchar(36);column:OpenId"`
+	SessionKey string    `json:"-" gorm:"type:varchar(255);column:SessionKey"`
+	UpdatedOn  time.Time `json:"updatedOn" gorm:"column:UpdatedOn"`
+}
+
+// TableName returns the table name for WeChatSession
+func (WeChatSession) TableName() string {
+	return "wechat_sessions"
+}
@@ -64,9 +64,9 @@ func (r *FranchiseIntentionRepository) CheckExistingByMember(memberID string) (b
 	return count > 0, nil
 }
 
-// Update 更新加盟意向
+// Update 按版本号更新加盟意向，读取后已被其他请求修改时返回 ErrVersionConflict
 func (r *FranchiseIntentionRepository) Update(intention *models.FranchiseIntention) error {
-	err := r.db.Save(intention).Error
+	err := updateVersioned(r.db, intention, intention.ID, &intention.Version)
 	if err != nil {
 		return fmt.Errorf("failed to update franchise intention: %w", err)
 	}
@@ -84,7 +84,10 @@ func (r *FranchiseIntentionRepository) UpdateStatus(id string, isHandled bool) e
 
 	err := r.db.Model(&models.FranchiseIntention{}).
 		Where("id = ?", id).
-		Update("IsHandled", bitBoolValue).Error
+		Updates(map[string]interface{}{
+			"IsHandled": bitBoolValue,
+			"Version":   versionIncrement(),
+		}).Error
 	if err != nil {
 		return fmt.Errorf("failed to update franchise intention status: %w", err)
 	}
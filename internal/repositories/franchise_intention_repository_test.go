@@ -115,6 +115,12 @@ func TestFranchiseIntentionRepository_UpdateDirectly(t *testing.T) {
 	err = db.First(&result, "id = ?", intention.ID).Error
 	assert.NoError(t, err)
 	assert.True(t, result.GetHandledStatus())
+	assert.Equal(t, int64(2), result.Version)
+
+	// 状态更新递增版本号，持有旧版本的整条更新被拒绝
+	assert.NoError(t, repo.UpdateStatus(intention.ID, false))
+	intention.IsHandled = models.BitBool(1)
+	assert.ErrorIs(t, repo.Update(intention), ErrVersionConflict)
 }
 
 func TestFranchiseIntentionRepository_GetPendingDirectly(t *testing.T) {
@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+// IdempotencyRepository 幂等请求记录仓库接口
+type IdempotencyRepository interface {
+	Begin(record *models.IdempotencyRecord, staleBefore time.Time) (bool, error)
+	Get(memberID, scope, key string) (*models.IdempotencyRecord, error)
+	Renew(record *models.IdempotencyRecord, now time.Time) (bool, error)
+	Complete(record *models.IdempotencyRecord) error
+	Delete(record *models.IdempotencyRecord) error
+	DeleteExpired(now time.Time, limit int) (int64, error)
+}
+
+// idempotencyRepository 幂等请求记录仓库实现
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyRepository 创建幂等请求记录仓库
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// Begin 占用幂等键开始处理首次请求，键已被占用时返回false
+// 已过期的记录，以及处理中但最后续期时间早于staleBefore（处理进程异常退出）的记录可被重新占用
+func (r *idempotencyRepository) Begin(record *models.IdempotencyRecord, staleBefore time.Time) (bool, error) {
+	if record.CreatedOn.IsZero() {
+		record.CreatedOn = time.Now()
+	}
+	renewedOn := record.CreatedOn
+	record.UpdatedOn = &renewedOn
+	record.StatusCode = 0
+	record.ContentType = nil
+	record.ResponseBody = nil
+
+	err := r.db.Create(record).Error
+	if err == nil {
+		return true, nil
+	}
+	if !isDuplicateKeyError(err) {
+		return false, err
+	}
+
+	result := r.db.Model(&models.IdempotencyRecord{}).
+		Where("MemberId = ? AND Scope = ? AND IdempotencyKey = ?",
+			record.MemberId, record.Scope, record.IdempotencyKey).
+		Where("ExpiresOn < ? OR (StatusCode = 0 AND COALESCE(UpdatedOn, CreatedOn) < ?)",
+			record.CreatedOn, staleBefore).
+		Updates(map[string]interface{}{
+			"Fingerprint":  record.Fingerprint,
+			"StatusCode":   0,
+			"ContentType":  nil,
+			"ResponseBody": nil,
+			"ExpiresOn":    record.ExpiresOn,
+			"CreatedOn":    record.CreatedOn,
+			"UpdatedOn":    record.CreatedOn,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Get 获取幂等请求记录，不存在时返回nil
+func (r *idempotencyRepository) Get(memberID, scope, key string) (*models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+	err := r.db.Where("MemberId = ? AND Scope = ? AND IdempotencyKey = ?", memberID, scope, key).
+		First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Renew 续期处理中的幂等键，首次请求处理期间定期调用，避免处理较慢时被视为已退出而被重新占用
+// 键已完成、已释放或已被其他请求重新占用时返回false
+func (r *idempotencyRepository) Renew(record *models.IdempotencyRecord, now time.Time) (bool, error) {
+	result := r.db.Model(&models.IdempotencyRecord{}).
+		Where("MemberId = ? AND Scope = ? AND IdempotencyKey = ? AND StatusCode = 0 AND Fingerprint = ?",
+			record.MemberId, record.Scope, record.IdempotencyKey, record.Fingerprint).
+		Update("UpdatedOn", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Complete 记录首次请求的响应
+func (r *idempotencyRepository) Complete(record *models.IdempotencyRecord) error {
+	now := time.Now()
+	record.UpdatedOn = &now
+	return r.db.Model(&models.IdempotencyRecord{}).
+		Where("MemberId = ? AND Scope = ? AND IdempotencyKey = ? AND StatusCode = 0",
+			record.MemberId, record.Scope, record.IdempotencyKey).
+		Updates(map[string]interface{}{
+			"StatusCode":   record.StatusCode,
+			"ContentType":  record.ContentType,
+			"ResponseBody": record.ResponseBody,
+			"UpdatedOn":    now,
+		}).Error
+}
+
+// Delete 删除处理中的幂等请求记录，使客户端可以用同一个键重试
+func (r *idempotencyRepository) Delete(record *models.IdempotencyRecord) error {
+	return r.db.
+		Where("MemberId = ? AND Scope = ? AND IdempotencyKey = ? AND StatusCode = 0",
+			record.MemberId, record.Scope, record.IdempotencyKey).
+		Delete(&models.IdempotencyRecord{}).Error
+}
+
+// DeleteExpired 按过期时间顺序删除now之前已过期的幂等请求记录，返回删除数量
+// 每次删除约limit条（过期时间相同的记录一并删除），避免一次删除过多记录长时间锁表
+func (r *idempotencyRepository) DeleteExpired(now time.Time, limit int) (int64, error) {
+	var expiresOn []time.Time
+	err := r.db.Model(&models.IdempotencyRecord{}).
+		Where("ExpiresOn < ?", now).
+		Order("ExpiresOn ASC").
+		Limit(limit).
+		Pluck("ExpiresOn", &expiresOn).Error
+	if err != nil || len(expiresOn) == 0 {
+		return 0, err
+	}
+
+	result := r.db.Where("ExpiresOn < ? AND ExpiresOn <= ?", now, expiresOn[len(expiresOn)-1]).
+		Delete(&models.IdempotencyRecord{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+func setupIdempotencyRepository(t *testing.T) IdempotencyRepository {
+	t.Helper()
+
+	return NewIdempotencyRepository(setupTestDB(t))
+}
+
+func newIdempotencyRecord(fingerprint string, now time.Time) *models.IdempotencyRecord {
+	return &models.IdempotencyRecord{
+		MemberId:       "member-1",
+		Scope:          "/api/Order/Create",
+		IdempotencyKey: "key-1",
+		Fingerprint:    fingerprint,
+		ExpiresOn:      now.Add(24 * time.Hour),
+		CreatedOn:      now,
+	}
+}
+
+func TestIdempotencyRepository_BeginAndComplete(t *testing.T) {
+	repo := setupIdempotencyRepository(t)
+	now := time.Now()
+
+	record := newIdempotencyRecord("fp-1", now)
+	started, err := repo.Begin(record, now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.True(t, started)
+
+	// 处理中的键不能被再次占用
+	started, err = repo.Begin(newIdempotencyRecord("fp-1", now), now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.False(t, started)
+
+	contentType := "application/json"
+	body := `{"success":true}`
+	record.StatusCode = 200
+	record.ContentType = &contentType
+	record.ResponseBody = &body
+	require.NoError(t, repo.Complete(record))
+
+	saved, err := repo.Get("member-1", "/api/Order/Create", "key-1")
+	require.NoError(t, err)
+	require.NotNil(t, saved)
+	assert.True(t, saved.IsCompleted())
+	assert.Equal(t, 200, saved.StatusCode)
+	assert.Equal(t, body, *saved.ResponseBody)
+
+	// 已完成的记录不会被删除
+	require.NoError(t, repo.Delete(record))
+	saved, err = repo.Get("member-1", "/api/Order/Create", "key-1")
+	require.NoError(t, err)
+	assert.NotNil(t, saved)
+
+	missing, err := repo.Get("member-2", "/api/Order/Create", "key-1")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestIdempotencyRepository_BeginTakesOverStaleRecords(t *testing.T) {
+	repo := setupIdempotencyRepository(t)
+	now := time.Now()
+
+	// 处理进程退出后遗留的处理中记录
+	started, err := repo.Begin(newIdempotencyRecord("fp-1", now.Add(-2*time.Minute)), now.Add(-3*time.Minute))
+	require.NoError(t, err)
+	require.True(t, started)
+
+	started, err = repo.Begin(newIdempotencyRecord("fp-2", now), now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.True(t, started)
+
+	saved, err := repo.Get("member-1", "/api/Order/Create", "key-1")
+	require.NoError(t, err)
+	assert.Equal(t, "fp-2", saved.Fingerprint)
+
+	// 已过期的完成记录同样可以重新占用
+	saved.StatusCode = 201
+	require.NoError(t, repo.Complete(saved))
+	later := now.Add(25 * time.Hour)
+	started, err = repo.Begin(newIdempotencyRecord("fp-3", later), later.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.True(t, started)
+
+	saved, err = repo.Get("member-1", "/api/Order/Create", "key-1")
+	require.NoError(t, err)
+	assert.Equal(t, "fp-3", saved.Fingerprint)
+	assert.False(t, saved.IsCompleted())
+	assert.Nil(t, saved.ResponseBody)
+}
+
+func TestIdempotencyRepository_RenewKeepsKeyHeld(t *testing.T) {
+	repo := setupIdempotencyRepository(t)
+	now := time.Now()
+
+	record := newIdempotencyRecord("fp-1", now.Add(-10*time.Minute))
+	started, err := repo.Begin(record, now.Add(-15*time.Minute))
+	require.NoError(t, err)
+	require.True(t, started)
+
+	// 处理较慢的首次请求续期后不会被视为已退出
+	held, err := repo.Renew(record, now)
+	require.NoError(t, err)
+	assert.True(t, held)
+	started, err = repo.Begin(newIdempotencyRecord("fp-1", now), now.Add(-5*time.Minute))
+	require.NoError(t, err)
+	assert.False(t, started)
+
+	// 已完成的键不再续期
+	record.StatusCode = 200
+	require.NoError(t, repo.Complete(record))
+	held, err = repo.Renew(record, now)
+	require.NoError(t, err)
+	assert.False(t, held)
+}
+
+func TestIdempotencyRepository_DeleteExpired(t *testing.T) {
+	repo := setupIdempotencyRepository(t)
+	now := time.Now()
+
+	for i, expiresOn := range []time.Time{now.Add(-2 * time.Hour), now.Add(-time.Hour), now.Add(time.Hour)} {
+		record := newIdempotencyRecord("fp", now)
+		record.IdempotencyKey = fmt.Sprintf("key-%d", i)
+		record.ExpiresOn = expiresOn
+		started, err := repo.Begin(record, now)
+		require.NoError(t, err)
+		require.True(t, started)
+	}
+
+	deleted, err := repo.DeleteExpired(now, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	missing, err := repo.Get("member-1", "/api/Order/Create", "key-0")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	deleted, err = repo.DeleteExpired(now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	retained, err := repo.Get("member-1", "/api/Order/Create", "key-2")
+	require.NoError(t, err)
+	assert.NotNil(t, retained)
+}
@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+// JobLeaseRepository 后台任务租约仓库接口
+type JobLeaseRepository interface {
+	Acquire(name, holder string, now time.Time, ttl time.Duration) (bool, error)
+	Release(name, holder string) error
+}
+
+// jobLeaseRepository 后台任务租约仓库实现
+type jobLeaseRepository struct {
+	db *gorm.DB
+}
+
+// NewJobLeaseRepository 创建后台任务租约仓库
+func NewJobLeaseRepository(db *gorm.DB) JobLeaseRepository {
+	return &jobLeaseRepository{db: db}
+}
+
+// Acquire 获取或续期任务租约，租约由其他实例持有且未过期时返回false
+func (r *jobLeaseRepository) Acquire(name, holder string, now time.Time, ttl time.Duration) (bool, error) {
+	result := r.db.Model(&models.JobLease{}).
+		Where("Name = ? AND (Holder = ? OR ExpiresOn < ?)", name, holder, now).
+		Updates(map[string]interface{}{
+			"Holder":    holder,
+			"ExpiresOn": now.Add(ttl),
+			"UpdatedOn": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	// 租约不存在时创建，并发创建失败说明已被其他实例获取
+	err := r.db.Create(&models.JobLease{
+		Name:      name,
+		Holder:    holder,
+		ExpiresOn: now.Add(ttl),
+		CreatedOn: now,
+	}).Error
+	if isDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Release 释放本实例持有的任务租约，将到期时间置为当前时间使其他实例可立即接管
+func (r *jobLeaseRepository) Release(name, holder string) error {
+	now := time.Now()
+	return r.db.Model(&models.JobLease{}).
+		Where("Name = ? AND Holder = ?", name, holder).
+		Updates(map[string]interface{}{
+			"ExpiresOn": now,
+			"UpdatedOn": now,
+		}).Error
+}
@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+func TestJobLeaseRepository_Acquire(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewJobLeaseRepository(db)
+
+	now := time.Now()
+	acquired, err := repo.Acquire("order_expiry", "replica-a", now, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// 租约未过期时其他实例无法获取，持有者可以续期
+	acquired, err = repo.Acquire("order_expiry", "replica-b", now.Add(30*time.Second), time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	acquired, err = repo.Acquire("order_expiry", "replica-a", now.Add(30*time.Second), time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// 租约过期后可被其他实例接管
+	acquired, err = repo.Acquire("order_expiry", "replica-b", now.Add(2*time.Minute), time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// 释放后其他实例立即可以获取
+	require.NoError(t, repo.Release("order_expiry", "replica-b"))
+	var released models.JobLease
+	require.NoError(t, db.First(&released, "Name = ?", "order_expiry").Error)
+	assert.False(t, released.ExpiresOn.IsZero())
+	acquired, err = repo.Acquire("order_expiry", "replica-a", now.Add(2*time.Minute), time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// 不同任务的租约互不影响
+	acquired, err = repo.Acquire("other_job", "replica-b", now, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
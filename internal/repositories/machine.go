@@ -14,6 +14,7 @@ import (
 // MachineRepository 售货机仓储接口
 type MachineRepositoryInterface interface {
 	GetByID(id string) (*models.Machine, error)
+	GetByIDs(ids []string) ([]*models.Machine, error)
 	GetByDeviceID(deviceID string) (*models.Machine, error)
 	GetList(machineOwnerID string) ([]*models.Machine, error)
 	GetPaging(machineOwnerID string, keyword string, page, pageSize int) ([]*models.Machine, int64, error)
@@ -67,6 +68,19 @@ func (r *MachineRepository) GetByDeviceID(deviceID string) (*models.Machine, err
 	return &machine, nil
 }
 
+// GetByIDs 批量获取售货机，不存在的ID忽略
+func (r *MachineRepository) GetByIDs(ids []string) ([]*models.Machine, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var machines []*models.Machine
+	if err := r.db.Where("Id IN ?", ids).Find(&machines).Error; err != nil {
+		return nil, fmt.Errorf("failed to get machines by ids: %w", err)
+	}
+	return machines, nil
+}
+
 // GetList 获取售货机列表（根据机主ID）
 func (r *MachineRepository) GetList(machineOwnerID string) ([]*models.Machine, error) {
 	var machines []*models.Machine
@@ -121,7 +135,10 @@ func (r *MachineRepository) GetPaging(
 func (r *MachineRepository) UpdateBusinessStatus(id string, status enums.BusinessStatus) error {
 	result := r.db.Model(&models.Machine{}).
 		Where("id = ?", id).
-		Update("BusinessStatus", status)
+		Updates(map[string]interface{}{
+			"BusinessStatus": status,
+			"Version":        versionIncrement(),
+		})
 
 	if result.Error != nil {
 		return fmt.Errorf("failed to update business status: %w", result.Error)
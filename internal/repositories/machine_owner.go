@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+// MachineOwnerRepositoryInterface 机主仓储接口
+type MachineOwnerRepositoryInterface interface {
+	GetByID(id string) (*models.MachineOwner, error)
+	GetByMerchant(channelCode, receivingAccount string) (*models.MachineOwner, error)
+	UpdatePaymentAccount(owner *models.MachineOwner) error
+}
+
+// MachineOwnerRepository 机主仓储实现
+type MachineOwnerRepository struct {
+	db *gorm.DB
+}
+
+// NewMachineOwnerRepository 创建机主仓储
+func NewMachineOwnerRepository(db *gorm.DB) MachineOwnerRepositoryInterface {
+	return &MachineOwnerRepository{
+		db: db,
+	}
+}
+
+// GetByID 根据ID获取机主，不存在时返回nil
+func (r *MachineOwnerRepository) GetByID(id string) (*models.MachineOwner, error) {
+	var owner models.MachineOwner
+	err := r.db.Where("Id = ?", id).First(&owner).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get machine owner by id: %w", err)
+	}
+
+	return &owner, nil
+}
+
+// GetByMerchant 根据支付渠道与商户号获取机主，不存在时返回nil
+// 多个机主共用同一商户号时收款参数相同，取最近更新的一条
+func (r *MachineOwnerRepository) GetByMerchant(channelCode, receivingAccount string) (*models.MachineOwner, error) {
+	var owner models.MachineOwner
+	err := r.db.Where("ChannelCode = ? AND ReceivingAccount = ?", channelCode, receivingAccount).
+		Order("UpdatedOn DESC").
+		First(&owner).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get machine owner by merchant: %w", err)
+	}
+
+	return &owner, nil
+}
+
+// UpdatePaymentAccount 更新机主收款账户与支付渠道，ReceivingKey 与 MerchantPrivateKey 应为加密后的值
+func (r *MachineOwnerRepository) UpdatePaymentAccount(owner *models.MachineOwner) error {
+	now := time.Now()
+	result := r.db.Model(&models.MachineOwner{}).
+		Where("Id = ?", owner.ID).
+		Updates(map[string]interface{}{
+			"ReceivingAccount":     owner.ReceivingAccount,
+			"ReceivingKey":         owner.ReceivingKey,
+			"ReceivingOrderPrefix": owner.ReceivingOrderPrefix,
+			"ChannelCode":          owner.ChannelCode,
+			"MerchantSerialNo":     owner.MerchantSerialNo,
+			"MerchantPrivateKey":   owner.MerchantPrivateKey,
+			"Version":              gorm.Expr("Version + 1"),
+			"UpdatedOn":            now,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update machine owner payment account: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	owner.UpdatedOn = &now
+	return nil
+}
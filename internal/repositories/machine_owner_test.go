@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+func TestMachineOwnerRepository_GetByID(t *testing.T) {
+	db := setupMachineTestDB(t)
+	repo := NewMachineOwnerRepository(db)
+
+	owner := &models.MachineOwner{
+		ID:          "owner-123",
+		Name:        stringPtr("Test Owner"),
+		ChannelCode: stringPtr("fuiou_pay_merchant"),
+	}
+	require.NoError(t, db.Create(owner).Error)
+
+	found, err := repo.GetByID("owner-123")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, "Test Owner", *found.Name)
+	assert.Equal(t, "fuiou_pay_merchant", *found.ChannelCode)
+
+	missing, err := repo.GetByID("owner-404")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestMachineOwnerRepository_UpdatePaymentAccount(t *testing.T) {
+	db := setupMachineTestDB(t)
+	repo := NewMachineOwnerRepository(db)
+
+	require.NoError(t, db.Create(&models.MachineOwner{ID: "owner-123", Name: stringPtr("Test Owner")}).Error)
+
+	err := repo.UpdatePaymentAccount(&models.MachineOwner{
+		ID:                   "owner-123",
+		ReceivingAccount:     stringPtr("0002900F0001"),
+		ReceivingKey:         stringPtr("enc:v1:ciphertext"),
+		ReceivingOrderPrefix: stringPtr("1066"),
+		ChannelCode:          stringPtr("fuiou_pay_merchant"),
+	})
+	require.NoError(t, err)
+
+	found, err := repo.GetByID("owner-123")
+	require.NoError(t, err)
+	assert.Equal(t, "0002900F0001", *found.ReceivingAccount)
+	assert.Equal(t, "enc:v1:ciphertext", *found.ReceivingKey)
+	assert.Equal(t, "1066", *found.ReceivingOrderPrefix)
+	assert.Equal(t, "fuiou_pay_merchant", *found.ChannelCode)
+	assert.Equal(t, "Test Owner", *found.Name)
+	assert.Equal(t, int64(1), found.Version)
+	assert.NotNil(t, found.UpdatedOn)
+
+	err = repo.UpdatePaymentAccount(&models.MachineOwner{ID: "owner-404"})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestMachineOwnerRepository_GetByMerchant(t *testing.T) {
+	db := setupMachineTestDB(t)
+	repo := NewMachineOwnerRepository(db)
+
+	require.NoError(t, db.Create(&models.MachineOwner{
+		ID:               "owner-123",
+		ChannelCode:      stringPtr("wechat_pay_v3"),
+		ReceivingAccount: stringPtr("1900000109"),
+		MerchantSerialNo: stringPtr("SERIAL-123"),
+	}).Error)
+
+	found, err := repo.GetByMerchant("wechat_pay_v3", "1900000109")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, "owner-123", found.ID)
+	assert.Equal(t, "SERIAL-123", *found.MerchantSerialNo)
+
+	// 商户号相同但渠道不同时不匹配
+	missing, err := repo.GetByMerchant("fuiou_pay_merchant", "1900000109")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
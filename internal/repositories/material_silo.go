@@ -40,10 +40,8 @@ func NewMaterialSiloRepository(db *gorm.DB) MaterialSiloRepositoryInterface {
 // GetByID 根据ID获取物料槽
 func (r *MaterialSiloRepository) GetByID(id string) (*models.MaterialSilo, error) {
 	var silo models.MaterialSilo
-	err := r.db.Where("id = ?", id).
-		Preload("Machine").
-		Preload("Product").
-		First(&silo).Error
+	// 模型未声明 Machine/Product 关联，不能预加载
+	err := r.db.Where("id = ?", id).First(&silo).Error
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -108,9 +106,9 @@ func (r *MaterialSiloRepository) Create(silo *models.MaterialSilo) error {
 	return nil
 }
 
-// Update 更新物料槽
+// Update 按版本号更新物料槽，读取后已被其他请求修改时返回 ErrVersionConflict
 func (r *MaterialSiloRepository) Update(silo *models.MaterialSilo) error {
-	err := r.db.Save(silo).Error
+	err := updateVersioned(r.db, silo, silo.ID, &silo.Version)
 	if err != nil {
 		return fmt.Errorf("failed to update material silo: %w", err)
 	}
@@ -121,7 +119,10 @@ func (r *MaterialSiloRepository) Update(silo *models.MaterialSilo) error {
 func (r *MaterialSiloRepository) UpdateStock(id string, stock int) error {
 	err := r.db.Model(&models.MaterialSilo{}).
 		Where("id = ?", id).
-		Update("Stock", stock).Error
+		Updates(map[string]interface{}{
+			"Stock":   stock,
+			"Version": versionIncrement(),
+		}).Error
 
 	if err != nil {
 		return fmt.Errorf("failed to update material silo stock: %w", err)
@@ -134,7 +135,10 @@ func (r *MaterialSiloRepository) UpdateStock(id string, stock int) error {
 func (r *MaterialSiloRepository) UpdateProduct(id string, productID string) error {
 	err := r.db.Model(&models.MaterialSilo{}).
 		Where("id = ?", id).
-		Update("ProductId", productID).Error
+		Updates(map[string]interface{}{
+			"ProductId": productID,
+			"Version":   versionIncrement(),
+		}).Error
 
 	if err != nil {
 		return fmt.Errorf("failed to update material silo product: %w", err)
@@ -154,7 +158,10 @@ func (r *MaterialSiloRepository) UpdateSaleStatus(id string, status enums.SaleSt
 
 	err := r.db.Model(&models.MaterialSilo{}).
 		Where("id = ?", id).
-		Update("IsSale", isSale).Error
+		Updates(map[string]interface{}{
+			"IsSale":  isSale,
+			"Version": versionIncrement(),
+		}).Error
 
 	if err != nil {
 		return fmt.Errorf("failed to update material silo sale status: %w", err)
@@ -44,9 +44,9 @@ func (r *MemberRepository) GetByWeChatOpenID(openID string) (*models.Member, err
 	return &member, nil
 }
 
-// Update 更新会员信息
+// Update 按版本号更新会员信息，读取后已被其他请求修改时返回 ErrVersionConflict
 func (r *MemberRepository) Update(member *models.Member) error {
-	err := r.db.Save(member).Error
+	err := updateVersioned(r.db, member, member.ID, &member.Version)
 	if err != nil {
 		return fmt.Errorf("failed to update member: %w", err)
 	}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -136,6 +137,39 @@ func TestMemberRepository_Update(t *testing.T) {
 	}
 }
 
+func TestMemberRepository_Update_VersionConflict(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewMemberRepository(db)
+	createTestMember(t, db)
+
+	first, err := repo.GetByID("test-member-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	second, err := repo.GetByID("test-member-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	first.Nickname = stringPtr("第一次修改")
+	if err := repo.Update(first); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	second.Nickname = stringPtr("第二次修改")
+	if err := repo.Update(second); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected version conflict, got %v", err)
+	}
+
+	updated, err := repo.GetByID("test-member-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if *updated.Nickname != "第一次修改" || updated.Version != 1 {
+		t.Errorf("expected first update with version 1, got '%s' version %d", *updated.Nickname, updated.Version)
+	}
+}
+
 func TestMemberRepository_Create(t *testing.T) {
 	db := setupTestDB(t)
 	repo := NewMemberRepository(db)
@@ -1,20 +1,54 @@
 package repositories
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"github.com/ddteam/drink-master/internal/enums"
 	"github.com/ddteam/drink-master/internal/models"
 )
 
+// ErrDuplicateOrderNo 订单号已存在
+var ErrDuplicateOrderNo = errors.New("order no already exists")
+
 // OrderRepository 订单仓库接口
 type OrderRepository interface {
 	Create(order *models.Order) error
 	GetByID(id string) (*models.Order, error)
 	GetByMemberPaging(memberID string, pageIndex, pageSize int) ([]models.Order, int64, error)
-	Update(order *models.Order) error
+	GetByOwnerPaging(filter OwnerOrderFilter, pageIndex, pageSize int) ([]models.Order, error)
+	GetOwnerTotals(filter OwnerOrderFilter) (*OrderTotals, error)
+	Update(order *models.Order, events ...*models.OutboxEvent) error
 	Delete(id string) error
 	GetByOrderNo(orderNo string) (*models.Order, error)
+	UpdateMakeStatus(id string, from, to enums.MakeStatus, events ...*models.OutboxEvent) (bool, error)
+	GetMakeTimedOut(paidBefore time.Time, limit int) ([]models.Order, error)
+	GetPayTimedOut(createdBefore time.Time, after *models.Order, limit int) ([]models.Order, error)
+	BindChannel(id string, channelCode string) (bool, error)
+	Invalidate(id string, unboundOnly bool, events ...*models.OutboxEvent) (bool, error)
+}
+
+// OwnerOrderFilter 机主订单查询条件，只查询机主名下售货机的订单
+type OwnerOrderFilter struct {
+	MachineOwnerID string
+	MachineID      string
+	OrderNo        string
+	PaymentStatus  *enums.PaymentStatus
+	MakeStatus     *enums.MakeStatus
+	CreatedFrom    *time.Time // 包含
+	CreatedTo      *time.Time // 不包含
+}
+
+// OrderTotals 订单合计
+type OrderTotals struct {
+	Count        int64   // 订单数
+	PaidAmount   float64 // 已支付订单的支付金额合计（含退款中和已退款订单）
+	RefundAmount float64 // 已退款金额合计
 }
 
 // orderRepository 订单仓库实现
@@ -29,12 +63,19 @@ func NewOrderRepository(db *gorm.DB) OrderRepository {
 	}
 }
 
-// Create 创建订单
+// Create 创建订单，订单号已存在时返回 ErrDuplicateOrderNo
 func (r *orderRepository) Create(order *models.Order) error {
 	if order.ID == "" {
 		order.ID = uuid.New().String()
 	}
-	return r.db.Create(order).Error
+	if order.CreatedOn.IsZero() {
+		order.CreatedOn = time.Now()
+	}
+	err := r.db.Create(order).Error
+	if isDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %v", ErrDuplicateOrderNo, err)
+	}
+	return err
 }
 
 // GetByID 根据ID获取订单
@@ -73,9 +114,70 @@ func (r *orderRepository) GetByMemberPaging(memberID string, pageIndex, pageSize
 	return orders, total, err
 }
 
-// Update 更新订单
-func (r *orderRepository) Update(order *models.Order) error {
-	return r.db.Save(order).Error
+// GetByOwnerPaging 按条件分页获取机主名下售货机的订单
+func (r *orderRepository) GetByOwnerPaging(filter OwnerOrderFilter, pageIndex, pageSize int) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.ownerOrderQuery(filter).
+		Order("CreatedOn DESC").
+		Offset((pageIndex - 1) * pageSize).
+		Limit(pageSize).
+		Find(&orders).Error
+	return orders, err
+}
+
+// GetOwnerTotals 按条件统计机主名下售货机的订单数及金额
+func (r *orderRepository) GetOwnerTotals(filter OwnerOrderFilter) (*OrderTotals, error) {
+	var totals OrderTotals
+	err := r.ownerOrderQuery(filter).
+		Select(`COUNT(*) AS count,
+			COALESCE(SUM(CASE WHEN PaymentStatus IN ? THEN PayAmount ELSE 0 END), 0) AS paid_amount,
+			COALESCE(SUM(RefundAmount), 0) AS refund_amount`,
+			[]int{int(enums.PaymentStatusPaid), int(enums.PaymentStatusRefunding), int(enums.PaymentStatusRefunded)}).
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return &totals, nil
+}
+
+// ownerOrderQuery 机主订单查询条件
+func (r *orderRepository) ownerOrderQuery(filter OwnerOrderFilter) *gorm.DB {
+	ownedMachines := r.db.Model(&models.Machine{}).Select("Id").Where("MachineOwnerId = ?", filter.MachineOwnerID)
+	query := r.db.Model(&models.Order{}).Where("MachineId IN (?)", ownedMachines)
+
+	if filter.MachineID != "" {
+		query = query.Where("MachineId = ?", filter.MachineID)
+	}
+	if filter.OrderNo != "" {
+		query = query.Where("OrderNo = ?", filter.OrderNo)
+	}
+	if filter.PaymentStatus != nil {
+		query = query.Where("PaymentStatus = ?", int(*filter.PaymentStatus))
+	}
+	if filter.MakeStatus != nil {
+		query = query.Where("MakeStatus = ?", int(*filter.MakeStatus))
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("CreatedOn >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("CreatedOn < ?", *filter.CreatedTo)
+	}
+	return query
+}
+
+// Update 按版本号更新订单，同一事务中写入订单变更产生的领域事件
+// 订单在读取后已被其他请求修改时返回 ErrVersionConflict，避免迟到的回调覆盖退款等状态
+func (r *orderRepository) Update(order *models.Order, events ...*models.OutboxEvent) error {
+	if len(events) == 0 {
+		return updateVersioned(r.db, order, order.ID, &order.Version)
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := updateVersioned(tx, order, order.ID, &order.Version); err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, events)
+	})
 }
 
 // Delete 软删除订单
@@ -92,3 +194,119 @@ func (r *orderRepository) GetByOrderNo(orderNo string) (*models.Order, error) {
 	}
 	return &order, nil
 }
+
+// UpdateMakeStatus 条件更新制作状态，仅当当前状态为from时更新为to
+// 返回是否实际更新，用于防止并发事件重复或逆向推进状态；仅实际更新时写入领域事件
+func (r *orderRepository) UpdateMakeStatus(
+	id string, from, to enums.MakeStatus, events ...*models.OutboxEvent,
+) (bool, error) {
+	updated := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("Id = ? AND MakeStatus = ?", id, int(from)).
+			Updates(map[string]interface{}{
+				"MakeStatus": int(to),
+				"Version":    versionIncrement(),
+				"UpdatedOn":  time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		updated = true
+		return createOutboxEvents(tx, events)
+	})
+	if err != nil {
+		return false, err
+	}
+	return updated, nil
+}
+
+// GetMakeTimedOut 获取支付时间早于paidBefore且仍未制作完成的已支付订单
+func (r *orderRepository) GetMakeTimedOut(paidBefore time.Time, limit int) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.db.Where("PaymentStatus = ? AND MakeStatus IN ? AND PaymentTime < ?",
+		int(enums.PaymentStatusPaid),
+		[]int{int(enums.MakeStatusWaitMake), int(enums.MakeStatusMaking)},
+		paidBefore).
+		Order("PaymentTime ASC").
+		Limit(limit).
+		Find(&orders).Error
+	return orders, err
+}
+
+// GetPayTimedOut 获取下单时间早于指定时间仍未支付的订单，按下单时间与订单ID排序
+// after 不为空时只获取排在该订单之后的订单，用于跳过上一轮已扫描的订单
+func (r *orderRepository) GetPayTimedOut(createdBefore time.Time, after *models.Order, limit int) ([]models.Order, error) {
+	query := r.db.Where("PaymentStatus = ? AND CreatedOn < ?", int(enums.PaymentStatusWaitPay), createdBefore)
+	if after != nil {
+		query = query.Where("(CreatedOn > ? OR (CreatedOn = ? AND Id > ?))", after.CreatedOn, after.CreatedOn, after.ID)
+	}
+
+	var orders []models.Order
+	err := query.Order("CreatedOn ASC, Id ASC").
+		Limit(limit).
+		Find(&orders).Error
+	return orders, err
+}
+
+// BindChannel 绑定订单支付渠道，仅当订单仍待支付且尚未绑定渠道时生效
+// 订单一经预下单即固定在该渠道上查询、回调与退款，机主后续切换渠道不影响在途订单
+func (r *orderRepository) BindChannel(id string, channelCode string) (bool, error) {
+	result := r.db.Model(&models.Order{}).
+		Where("Id = ? AND PaymentStatus = ? AND (ChannelCode IS NULL OR ChannelCode = '')",
+			id, int(enums.PaymentStatusWaitPay)).
+		Updates(map[string]interface{}{
+			"ChannelCode": channelCode,
+			"Version":     versionIncrement(),
+			"UpdatedOn":   time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Invalidate 条件作废订单，仅当订单仍为待支付时更新为已作废
+// unboundOnly 为true时仅作废尚未绑定渠道（未发起过预下单）的订单；仅实际更新时写入领域事件
+func (r *orderRepository) Invalidate(id string, unboundOnly bool, events ...*models.OutboxEvent) (bool, error) {
+	updated := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&models.Order{}).
+			Where("Id = ? AND PaymentStatus = ?", id, int(enums.PaymentStatusWaitPay))
+		if unboundOnly {
+			query = query.Where("(ChannelCode IS NULL OR ChannelCode = '')")
+		}
+		result := query.Updates(map[string]interface{}{
+			"PaymentStatus": int(enums.PaymentStatusInvalid),
+			"Version":       versionIncrement(),
+			"UpdatedOn":     time.Now(),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		updated = true
+		return createOutboxEvents(tx, events)
+	})
+	if err != nil {
+		return false, err
+	}
+	return updated, nil
+}
+
+// isDuplicateKeyError 判断是否为唯一索引冲突（MySQL 1062 / SQLite UNIQUE constraint）
+func isDuplicateKeyError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	message := err.Error()
+	return strings.Contains(message, "Duplicate entry") || strings.Contains(message, "UNIQUE constraint failed")
+}
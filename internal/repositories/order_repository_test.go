@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -25,7 +26,7 @@ func (suite *OrderRepositoryTestSuite) SetupTest() {
 	suite.Require().NoError(err)
 
 	// 自动迁移
-	err = db.AutoMigrate(&models.Order{}, &models.Member{}, &models.Machine{}, &models.Product{})
+	err = db.AutoMigrate(&models.Order{}, &models.Member{}, &models.Machine{}, &models.Product{}, &models.OutboxEvent{})
 	suite.Require().NoError(err)
 
 	suite.db = db
@@ -203,6 +204,46 @@ func (suite *OrderRepositoryTestSuite) TestUpdate() {
 	assert.NotNil(suite.T(), updatedOrder.PaymentTime)
 }
 
+func (suite *OrderRepositoryTestSuite) TestUpdate_VersionConflict() {
+	order := &models.Order{
+		ID:            "test-order-6",
+		OrderNo:       stringPtr("ORD202508120006"),
+		PayAmount:     15.80,
+		PaymentStatus: int(enums.PaymentStatusPaid),
+	}
+	suite.Require().NoError(suite.db.Create(order).Error)
+
+	stale, err := suite.repo.GetByID(order.ID)
+	suite.Require().NoError(err)
+
+	// 退款等条件更新会递增版本号
+	refunding := suite.db.Model(&models.Order{}).Where("Id = ?", order.ID).Updates(map[string]interface{}{
+		"PaymentStatus": int(enums.PaymentStatusRefunded),
+		"Version":       versionIncrement(),
+	})
+	suite.Require().NoError(refunding.Error)
+
+	// 持有旧版本的迟到更新不能覆盖退款状态
+	stale.PaymentStatus = int(enums.PaymentStatusPaid)
+	err = suite.repo.Update(stale)
+	suite.True(errors.Is(err, ErrVersionConflict))
+	var conflict *VersionConflictError
+	suite.Require().True(errors.As(err, &conflict))
+	suite.Equal("orders", conflict.Table)
+	suite.Equal(order.ID, conflict.ID)
+	suite.Equal(int64(0), stale.Version)
+
+	current, err := suite.repo.GetByID(order.ID)
+	suite.Require().NoError(err)
+	suite.Equal(int(enums.PaymentStatusRefunded), current.PaymentStatus)
+	suite.Equal(int64(1), current.Version)
+
+	// 重新读取后可以更新，版本号递增
+	current.RefundAmount = 15.80
+	suite.Require().NoError(suite.repo.Update(current))
+	suite.Equal(int64(2), current.Version)
+}
+
 func (suite *OrderRepositoryTestSuite) TestDelete() {
 	// 创建测试订单
 	order := &models.Order{
@@ -260,6 +301,16 @@ func (suite *OrderRepositoryTestSuite) TestGetByOrderNo() {
 	assert.Equal(suite.T(), "ORD202508120007", *result.OrderNo)
 }
 
+func (suite *OrderRepositoryTestSuite) TestCreate_DuplicateOrderNo() {
+	first := &models.Order{ID: "test-order-8", OrderNo: stringPtr("ORD202508120008")}
+	assert.NoError(suite.T(), suite.repo.Create(first))
+
+	// 订单号唯一索引冲突
+	second := &models.Order{ID: "test-order-9", OrderNo: stringPtr("ORD202508120008")}
+	err := suite.repo.Create(second)
+	assert.ErrorIs(suite.T(), err, ErrDuplicateOrderNo)
+}
+
 func (suite *OrderRepositoryTestSuite) TestGetByOrderNo_NotFound() {
 	result, err := suite.repo.GetByOrderNo("NON-EXISTENT-ORDER")
 	assert.Error(suite.T(), err)
@@ -267,6 +318,142 @@ func (suite *OrderRepositoryTestSuite) TestGetByOrderNo_NotFound() {
 	assert.Equal(suite.T(), gorm.ErrRecordNotFound, err)
 }
 
+func (suite *OrderRepositoryTestSuite) TestUpdateMakeStatus() {
+	order := &models.Order{
+		ID:            "test-order-8",
+		OrderNo:       stringPtr("ORD202508120008"),
+		PaymentStatus: int(enums.PaymentStatusPaid),
+		MakeStatus:    int(enums.MakeStatusWaitMake),
+	}
+	suite.db.Create(order)
+
+	updated, err := suite.repo.UpdateMakeStatus(order.ID, enums.MakeStatusWaitMake, enums.MakeStatusMaking)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), updated)
+
+	// 当前状态已不是待制作，条件更新不生效
+	updated, err = suite.repo.UpdateMakeStatus(order.ID, enums.MakeStatusWaitMake, enums.MakeStatusMakeFail)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), updated)
+
+	var result models.Order
+	suite.db.First(&result, "id = ?", order.ID)
+	assert.Equal(suite.T(), int(enums.MakeStatusMaking), result.MakeStatus)
+	assert.NotNil(suite.T(), result.UpdatedOn)
+}
+
+func (suite *OrderRepositoryTestSuite) TestGetMakeTimedOut() {
+	old := time.Now().Add(-10 * time.Minute)
+	recent := time.Now()
+	orders := []*models.Order{
+		{ID: "timeout-1", PaymentStatus: int(enums.PaymentStatusPaid), MakeStatus: int(enums.MakeStatusWaitMake), PaymentTime: &old},
+		{ID: "timeout-2", PaymentStatus: int(enums.PaymentStatusPaid), MakeStatus: int(enums.MakeStatusMaking), PaymentTime: &old},
+		{ID: "made-1", PaymentStatus: int(enums.PaymentStatusPaid), MakeStatus: int(enums.MakeStatusMade), PaymentTime: &old},
+		{ID: "recent-1", PaymentStatus: int(enums.PaymentStatusPaid), MakeStatus: int(enums.MakeStatusWaitMake), PaymentTime: &recent},
+		{ID: "unpaid-1", PaymentStatus: int(enums.PaymentStatusWaitPay), MakeStatus: int(enums.MakeStatusWaitMake)},
+	}
+	for _, order := range orders {
+		suite.db.Create(order)
+	}
+
+	result, err := suite.repo.GetMakeTimedOut(time.Now().Add(-5*time.Minute), 10)
+	assert.NoError(suite.T(), err)
+	ids := make([]string, 0, len(result))
+	for _, order := range result {
+		ids = append(ids, order.ID)
+	}
+	assert.ElementsMatch(suite.T(), []string{"timeout-1", "timeout-2"}, ids)
+}
+
+func (suite *OrderRepositoryTestSuite) TestGetPayTimedOut() {
+	old := time.Now().Add(-30 * time.Minute)
+	orders := []*models.Order{
+		{ID: "expired-1", PaymentStatus: int(enums.PaymentStatusWaitPay), CreatedOn: old},
+		{ID: "paid-1", PaymentStatus: int(enums.PaymentStatusPaid), CreatedOn: old},
+		{ID: "invalid-1", PaymentStatus: int(enums.PaymentStatusInvalid), CreatedOn: old},
+	}
+	for _, order := range orders {
+		suite.db.Create(order)
+	}
+	// 未指定下单时间的订单按创建时间记录
+	assert.NoError(suite.T(), suite.repo.Create(&models.Order{ID: "recent-1", PaymentStatus: int(enums.PaymentStatusWaitPay)}))
+
+	result, err := suite.repo.GetPayTimedOut(time.Now().Add(-15*time.Minute), nil, 10)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), result, 1)
+	assert.Equal(suite.T(), "expired-1", result[0].ID)
+
+	// 从指定订单之后继续获取，同一下单时间按订单ID排序
+	suite.db.Create(&models.Order{ID: "expired-0", PaymentStatus: int(enums.PaymentStatusWaitPay), CreatedOn: old})
+	suite.db.Create(&models.Order{ID: "expired-2", PaymentStatus: int(enums.PaymentStatusWaitPay), CreatedOn: old.Add(time.Minute)})
+	result, err = suite.repo.GetPayTimedOut(time.Now().Add(-15*time.Minute), nil, 10)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"expired-0", "expired-1", "expired-2"}, orderIDs(result))
+
+	result, err = suite.repo.GetPayTimedOut(time.Now().Add(-15*time.Minute), &result[0], 10)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"expired-1", "expired-2"}, orderIDs(result))
+}
+
+func (suite *OrderRepositoryTestSuite) TestBindChannel() {
+	order := &models.Order{ID: "bind-1", PaymentStatus: int(enums.PaymentStatusWaitPay)}
+	suite.db.Create(order)
+
+	bound, err := suite.repo.BindChannel("bind-1", "fuiou_pay_merchant")
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), bound)
+
+	// 已绑定的订单不再切换渠道
+	bound, err = suite.repo.BindChannel("bind-1", "mock")
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), bound)
+
+	saved, err := suite.repo.GetByID("bind-1")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "fuiou_pay_merchant", *saved.ChannelCode)
+}
+
+func (suite *OrderRepositoryTestSuite) TestBindChannel_Invalidated() {
+	suite.db.Create(&models.Order{ID: "bind-2", PaymentStatus: int(enums.PaymentStatusInvalid)})
+
+	// 已作废的订单不再发起预下单
+	bound, err := suite.repo.BindChannel("bind-2", "fuiou_pay_merchant")
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), bound)
+}
+
+func (suite *OrderRepositoryTestSuite) TestInvalidate() {
+	suite.db.Create(&models.Order{ID: "invalid-1", PaymentStatus: int(enums.PaymentStatusWaitPay)})
+	suite.db.Create(&models.Order{
+		ID:            "invalid-2",
+		PaymentStatus: int(enums.PaymentStatusWaitPay),
+		ChannelCode:   stringPtr("fuiou_pay_merchant"),
+	})
+	suite.db.Create(&models.Order{ID: "invalid-3", PaymentStatus: int(enums.PaymentStatusPaid)})
+
+	// 已发起预下单的订单不按未绑定订单作废
+	invalidated, err := suite.repo.Invalidate("invalid-2", true)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), invalidated)
+
+	invalidated, err = suite.repo.Invalidate("invalid-1", true)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), invalidated)
+
+	invalidated, err = suite.repo.Invalidate("invalid-2", false)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), invalidated)
+
+	// 已支付的订单不被作废
+	invalidated, err = suite.repo.Invalidate("invalid-3", false)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), invalidated)
+
+	saved, err := suite.repo.GetByID("invalid-2")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int(enums.PaymentStatusInvalid), saved.PaymentStatus)
+}
+
 func TestOrderRepositoryTestSuite(t *testing.T) {
 	suite.Run(t, new(OrderRepositoryTestSuite))
 }
@@ -280,3 +467,11 @@ func TestNewOrderRepository(t *testing.T) {
 	assert.NotNil(t, repo)
 	assert.IsType(t, &orderRepository{}, repo)
 }
+
+func orderIDs(orders []models.Order) []string {
+	ids := make([]string, 0, len(orders))
+	for _, order := range orders {
+		ids = append(ids, order.ID)
+	}
+	return ids
+}
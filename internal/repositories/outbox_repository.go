@@ -0,0 +1,146 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+// OutboxRepository 领域事件发件箱仓库接口
+type OutboxRepository interface {
+	GetDue(now time.Time, limit int) ([]models.OutboxEvent, error)
+	ListByAggregate(aggregateID string, afterSeq int64, limit int) ([]models.OutboxEvent, error)
+	ListAfter(afterSeq int64, limit int) ([]models.OutboxEvent, error)
+	LatestSeq() (int64, error)
+	MarkDelivered(id string, deliveredAt time.Time) error
+	MarkRetry(id string, lastError string, nextAttemptOn time.Time) error
+	MarkFailed(id string, lastError string, failedAt time.Time) error
+	DeleteCreatedBefore(before time.Time, limit int) (int64, error)
+}
+
+// outboxRepository 领域事件发件箱仓库实现
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository 创建领域事件发件箱仓库
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// GetDue 获取已到投递时间的待投递事件，按写入顺序返回
+func (r *outboxRepository) GetDue(now time.Time, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := r.db.Where("Status = ? AND NextAttemptOn <= ?", int(enums.OutboxStatusPending), now).
+		Order("Seq ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// ListByAggregate 按写入顺序获取聚合序号在afterSeq之后的事件，afterSeq为0时从第一条事件开始
+// 同一订单的事件在锁定订单行的事务中写入，序号顺序与提交顺序一致，按序号续传不会遗漏
+func (r *outboxRepository) ListByAggregate(aggregateID string, afterSeq int64, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := r.db.Where("AggregateId = ? AND Seq > ?", aggregateID, afterSeq).
+		Order("Seq ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// ListAfter 按写入顺序获取序号在afterSeq之后的事件
+func (r *outboxRepository) ListAfter(afterSeq int64, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := r.db.Where("Seq > ?", afterSeq).
+		Order("Seq ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// LatestSeq 获取最后写入事件的序号，没有事件时返回0
+func (r *outboxRepository) LatestSeq() (int64, error) {
+	var seq int64
+	err := r.db.Model(&models.OutboxEvent{}).Select("COALESCE(MAX(Seq), 0)").Scan(&seq).Error
+	return seq, err
+}
+
+// MarkDelivered 标记事件已投递
+func (r *outboxRepository) MarkDelivered(id string, deliveredAt time.Time) error {
+	return r.db.Model(&models.OutboxEvent{}).
+		Where("Id = ? AND Status = ?", id, int(enums.OutboxStatusPending)).
+		Updates(map[string]interface{}{
+			"Status":      int(enums.OutboxStatusDelivered),
+			"Attempts":    gorm.Expr("Attempts + 1"),
+			"LastError":   nil,
+			"DeliveredOn": deliveredAt,
+			"UpdatedOn":   deliveredAt,
+		}).Error
+}
+
+// MarkRetry 记录投递失败并安排下次投递
+func (r *outboxRepository) MarkRetry(id string, lastError string, nextAttemptOn time.Time) error {
+	return r.db.Model(&models.OutboxEvent{}).
+		Where("Id = ? AND Status = ?", id, int(enums.OutboxStatusPending)).
+		Updates(map[string]interface{}{
+			"Attempts":      gorm.Expr("Attempts + 1"),
+			"LastError":     lastError,
+			"NextAttemptOn": nextAttemptOn,
+			"UpdatedOn":     time.Now(),
+		}).Error
+}
+
+// MarkFailed 标记事件投递失败，不再自动重试
+func (r *outboxRepository) MarkFailed(id string, lastError string, failedAt time.Time) error {
+	return r.db.Model(&models.OutboxEvent{}).
+		Where("Id = ? AND Status = ?", id, int(enums.OutboxStatusPending)).
+		Updates(map[string]interface{}{
+			"Status":    int(enums.OutboxStatusFailed),
+			"Attempts":  gorm.Expr("Attempts + 1"),
+			"LastError": lastError,
+			"UpdatedOn": failedAt,
+		}).Error
+}
+
+// DeleteCreatedBefore 按写入顺序删除before之前写入且已投递或投递失败的事件，单次最多删除limit条，返回删除数量
+// 仍待投递的事件不删除，避免订阅者漏处理
+func (r *outboxRepository) DeleteCreatedBefore(before time.Time, limit int) (int64, error) {
+	var ids []string
+	err := r.db.Model(&models.OutboxEvent{}).
+		Where("CreatedOn < ? AND Status IN ?", before,
+			[]int{int(enums.OutboxStatusDelivered), int(enums.OutboxStatusFailed)}).
+		Order("CreatedOn ASC, Id ASC").
+		Limit(limit).
+		Pluck("Id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	result := r.db.Where("Id IN ?", ids).Delete(&models.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// createOutboxEvents 在调用方事务中写入领域事件，保证事件与状态变更同时提交或回滚
+func createOutboxEvents(tx *gorm.DB, events []*models.OutboxEvent) error {
+	for _, event := range events {
+		if event.ID == "" {
+			event.ID = uuid.New().String()
+		}
+		if event.CreatedOn.IsZero() {
+			event.CreatedOn = time.Now()
+		}
+		if event.NextAttemptOn.IsZero() {
+			event.NextAttemptOn = event.CreatedOn
+		}
+		event.Status = int(enums.OutboxStatusPending)
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+func setupOutboxRepository(t *testing.T) (*gorm.DB, OutboxRepository) {
+	t.Helper()
+
+	db := setupTestDB(t)
+	require.NoError(t, db.Create(&models.Order{
+		ID:            "order-1",
+		PaymentStatus: int(enums.PaymentStatusWaitPay),
+		MakeStatus:    int(enums.MakeStatusWaitMake),
+		CreatedOn:     time.Now(),
+	}).Error)
+
+	return db, NewOutboxRepository(db)
+}
+
+func getOutboxEvent(t *testing.T, db *gorm.DB, id string) models.OutboxEvent {
+	t.Helper()
+	var event models.OutboxEvent
+	require.NoError(t, db.Where("Id = ?", id).First(&event).Error)
+	return event
+}
+
+func TestOutboxRepository_WrittenWithOrderUpdate(t *testing.T) {
+	db, _ := setupOutboxRepository(t)
+	orderRepo := NewOrderRepository(db)
+
+	order, err := orderRepo.GetByID("order-1")
+	require.NoError(t, err)
+	order.PaymentStatus = int(enums.PaymentStatusPaid)
+	require.NoError(t, orderRepo.Update(order, &models.OutboxEvent{ID: "event-1", EventType: "OrderPaid"}))
+
+	event := getOutboxEvent(t, db, "event-1")
+	assert.Equal(t, int(enums.OutboxStatusPending), event.Status)
+	assert.False(t, event.NextAttemptOn.IsZero())
+
+	// 事件写入失败时订单变更一并回滚
+	order.PaymentStatus = int(enums.PaymentStatusRefunded)
+	err = orderRepo.Update(order, &models.OutboxEvent{ID: "event-1", EventType: "OrderRefunded"})
+	require.Error(t, err)
+	reloaded, err := orderRepo.GetByID("order-1")
+	require.NoError(t, err)
+	assert.Equal(t, int(enums.PaymentStatusPaid), reloaded.PaymentStatus)
+}
+
+func TestOutboxRepository_WrittenOnlyWhenMakeStatusUpdated(t *testing.T) {
+	db, _ := setupOutboxRepository(t)
+	orderRepo := NewOrderRepository(db)
+
+	updated, err := orderRepo.UpdateMakeStatus("order-1", enums.MakeStatusWaitMake, enums.MakeStatusMaking,
+		&models.OutboxEvent{ID: "event-1", EventType: "OrderMaking"})
+	require.NoError(t, err)
+	assert.True(t, updated)
+
+	// 状态已被推进，条件更新未生效时不写入事件
+	updated, err = orderRepo.UpdateMakeStatus("order-1", enums.MakeStatusWaitMake, enums.MakeStatusMaking,
+		&models.OutboxEvent{ID: "event-2", EventType: "OrderMaking"})
+	require.NoError(t, err)
+	assert.False(t, updated)
+
+	var count int64
+	require.NoError(t, db.Model(&models.OutboxEvent{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestOutboxRepository_GetDue(t *testing.T) {
+	db, repo := setupOutboxRepository(t)
+	now := time.Now()
+
+	require.NoError(t, createOutboxEvents(db, []*models.OutboxEvent{
+		{ID: "second", CreatedOn: now.Add(-time.Minute)},
+		{ID: "first", CreatedOn: now.Add(-2 * time.Minute)},
+		{ID: "later", CreatedOn: now.Add(-3 * time.Minute), NextAttemptOn: now.Add(time.Minute)},
+		{ID: "delivered", CreatedOn: now.Add(-4 * time.Minute)},
+	}))
+	require.NoError(t, repo.MarkDelivered("delivered", now))
+
+	// 按写入顺序投递，与事件记录的发生时间无关
+	events, err := repo.GetDue(now, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "second", events[0].ID)
+	assert.Equal(t, "first", events[1].ID)
+	assert.Less(t, events[0].Seq, events[1].Seq)
+}
+
+func TestOutboxRepository_MarkRetryAndFailed(t *testing.T) {
+	db, repo := setupOutboxRepository(t)
+	now := time.Now()
+	require.NoError(t, createOutboxEvents(db, []*models.OutboxEvent{{ID: "event-1", CreatedOn: now}}))
+
+	next := now.Add(time.Minute)
+	require.NoError(t, repo.MarkRetry("event-1", "subscriber down", next))
+	event := getOutboxEvent(t, db, "event-1")
+	assert.Equal(t, int(enums.OutboxStatusPending), event.Status)
+	assert.Equal(t, 1, event.Attempts)
+	require.NotNil(t, event.LastError)
+	assert.Equal(t, "subscriber down", *event.LastError)
+	assert.WithinDuration(t, next, event.NextAttemptOn, time.Second)
+
+	require.NoError(t, repo.MarkFailed("event-1", "still down", now))
+	event = getOutboxEvent(t, db, "event-1")
+	assert.Equal(t, int(enums.OutboxStatusFailed), event.Status)
+	assert.Equal(t, 2, event.Attempts)
+
+	// 已失败的事件不会再被标记为已投递
+	require.NoError(t, repo.MarkDelivered("event-1", now))
+	assert.Equal(t, int(enums.OutboxStatusFailed), getOutboxEvent(t, db, "event-1").Status)
+}
+
+func TestOutboxRepository_ListByAggregate(t *testing.T) {
+	db, repo := setupOutboxRepository(t)
+	now := time.Now()
+
+	require.NoError(t, createOutboxEvents(db, []*models.OutboxEvent{
+		{ID: "paid", AggregateId: "order-1", CreatedOn: now.Add(-2 * time.Minute)},
+		{ID: "making", AggregateId: "order-1", CreatedOn: now.Add(-time.Minute)},
+		{ID: "other", AggregateId: "order-2", CreatedOn: now.Add(-time.Minute)},
+		// 发生时间早于已写入事件的退款事件仍排在其后
+		{ID: "refunding", AggregateId: "order-1", CreatedOn: now.Add(-3 * time.Minute)},
+	}))
+
+	events, err := repo.ListByAggregate("order-1", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, "paid", events[0].ID)
+	assert.Equal(t, "refunding", events[2].ID)
+
+	events, err = repo.ListByAggregate("order-1", events[0].Seq, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "making", events[0].ID)
+	assert.Equal(t, "refunding", events[1].ID)
+
+	events, err = repo.ListByAggregate("order-1", events[1].Seq, 10)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestOutboxRepository_ListAfter(t *testing.T) {
+	db, repo := setupOutboxRepository(t)
+
+	latest, err := repo.LatestSeq()
+	require.NoError(t, err)
+	assert.Zero(t, latest)
+
+	require.NoError(t, createOutboxEvents(db, []*models.OutboxEvent{
+		{ID: "paid", AggregateId: "order-1"},
+		{ID: "other", AggregateId: "order-2"},
+	}))
+	latest, err = repo.LatestSeq()
+	require.NoError(t, err)
+
+	events, err := repo.ListAfter(0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "paid", events[0].ID)
+	assert.Equal(t, latest, events[1].Seq)
+
+	events, err = repo.ListAfter(events[0].Seq, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "other", events[0].ID)
+}
+
+func TestOutboxRepository_DeleteCreatedBefore(t *testing.T) {
+	db, repo := setupOutboxRepository(t)
+	now := time.Now()
+
+	require.NoError(t, createOutboxEvents(db, []*models.OutboxEvent{
+		{ID: "oldest", AggregateId: "order-1", CreatedOn: now.Add(-72 * time.Hour)},
+		{ID: "old", AggregateId: "order-1", CreatedOn: now.Add(-48 * time.Hour)},
+		{ID: "pending", AggregateId: "order-1", CreatedOn: now.Add(-48 * time.Hour)},
+		{ID: "recent", AggregateId: "order-1", CreatedOn: now},
+	}))
+	require.NoError(t, repo.MarkDelivered("oldest", now))
+	require.NoError(t, repo.MarkFailed("old", "handler failed", now))
+
+	// 单次最多删除limit条，优先删除最早写入的事件
+	deleted, err := repo.DeleteCreatedBefore(now.Add(-24*time.Hour), 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	events, err := repo.ListByAggregate("order-1", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, "old", events[0].ID)
+
+	deleted, err = repo.DeleteCreatedBefore(now.Add(-24*time.Hour), 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	// 仍待投递的事件超过保留期也不删除
+	deleted, err = repo.DeleteCreatedBefore(now.Add(-24*time.Hour), 10)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+	events, err = repo.ListByAggregate("order-1", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "pending", events[0].ID)
+	assert.Equal(t, "recent", events[1].ID)
+}
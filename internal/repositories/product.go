@@ -12,7 +12,9 @@ import (
 // ProductRepositoryInterface 商品仓储接口
 type ProductRepositoryInterface interface {
 	GetByID(id string) (*models.Product, error)
+	GetByIDs(ids []string) ([]*models.Product, error)
 	GetMachineProducts(machineID string) ([]*models.MachineProductPrice, error)
+	GetMachineProductPrice(machineID, productID string) (*models.MachineProductPrice, error)
 }
 
 // ProductRepository 商品仓储实现
@@ -42,6 +44,19 @@ func (r *ProductRepository) GetByID(id string) (*models.Product, error) {
 	return &product, nil
 }
 
+// GetByIDs 批量获取产品，不存在的ID忽略
+func (r *ProductRepository) GetByIDs(ids []string) ([]*models.Product, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var products []*models.Product
+	if err := r.db.Where("Id IN ?", ids).Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("failed to get products by ids: %w", err)
+	}
+	return products, nil
+}
+
 // GetMachineProducts 获取售货机商品列表
 func (r *ProductRepository) GetMachineProducts(machineID string) ([]*models.MachineProductPrice, error) {
 	var machineProducts []*models.MachineProductPrice
@@ -57,3 +72,18 @@ func (r *ProductRepository) GetMachineProducts(machineID string) ([]*models.Mach
 
 	return machineProducts, nil
 }
+
+// GetMachineProductPrice 获取售货机中指定商品的价格，未配置时返回nil
+func (r *ProductRepository) GetMachineProductPrice(machineID, productID string) (*models.MachineProductPrice, error) {
+	var price models.MachineProductPrice
+	err := r.db.Where("MachineId = ? AND ProductId = ?", machineID, productID).First(&price).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get machine product price: %w", err)
+	}
+
+	return &price, nil
+}
@@ -93,3 +93,44 @@ func TestProductRepository_GetMachineProducts_EmptyResult(t *testing.T) {
 	assert.Len(t, result, 0)
 	assert.NotNil(t, result) // 应该返回空数组而不是nil
 }
+
+func TestProductRepository_GetMachineProductPrice(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewProductRepository(db)
+
+	require.NoError(t, db.Create(&models.MachineProductPrice{
+		ID:              "mp-1",
+		MachineId:       "machine-123",
+		ProductId:       "product-1",
+		Price:           5.5,
+		PriceWithoutCup: 5.0,
+		CreatedOn:       time.Now(),
+	}).Error)
+
+	price, err := repo.GetMachineProductPrice("machine-123", "product-1")
+	require.NoError(t, err)
+	require.NotNil(t, price)
+	assert.Equal(t, 5.5, price.Price)
+	assert.Equal(t, 5.0, price.PriceWithoutCup)
+
+	// 其他售货机未配置该商品价格
+	price, err = repo.GetMachineProductPrice("machine-456", "product-1")
+	require.NoError(t, err)
+	assert.Nil(t, price)
+}
+
+func TestProductRepository_GetByIDs(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewProductRepository(db)
+
+	require.NoError(t, db.Create(&models.Product{ID: "product-1", Name: "Coffee", CreatedOn: time.Now()}).Error)
+	require.NoError(t, db.Create(&models.Product{ID: "product-2", Name: "Tea", CreatedOn: time.Now()}).Error)
+
+	products, err := repo.GetByIDs([]string{"product-1", "product-2", "missing"})
+	require.NoError(t, err)
+	assert.Len(t, products, 2)
+
+	products, err = repo.GetByIDs(nil)
+	require.NoError(t, err)
+	assert.Empty(t, products)
+}
@@ -0,0 +1,325 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+// RefundRecordRepository 退款记录仓库接口
+type RefundRecordRepository interface {
+	Create(record *models.RefundRecord) error
+	CreateForOrder(record *models.RefundRecord, events ...*models.OutboxEvent) (bool, error)
+	CreateNumberedForOrder(
+		record *models.RefundRecord, prepare func(seq int) ([]*models.OutboxEvent, error),
+	) (bool, error)
+	CreateForInvalidatedOrder(
+		record *models.RefundRecord, channelOrderNo string, paidAt time.Time, events ...*models.OutboxEvent,
+	) (bool, error)
+	GetByID(id string) (*models.RefundRecord, error)
+	GetByRefundNo(refundNo string) (*models.RefundRecord, error)
+	GetByOrderID(orderID string) ([]models.RefundRecord, error)
+	Update(record *models.RefundRecord) error
+	Claim(id string, now, staleBefore time.Time) (bool, error)
+	GetRetryable(now, staleBefore time.Time, limit int) ([]models.RefundRecord, error)
+	MarkAccepted(record *models.RefundRecord, acceptedAt time.Time) error
+	MarkSucceeded(record *models.RefundRecord, refundedAt time.Time, events ...*models.OutboxEvent) error
+	MarkFailed(record *models.RefundRecord, failedAt time.Time, events ...*models.OutboxEvent) error
+}
+
+// refundRecordRepository 退款记录仓库实现
+type refundRecordRepository struct {
+	db *gorm.DB
+}
+
+// NewRefundRecordRepository 创建退款记录仓库
+func NewRefundRecordRepository(db *gorm.DB) RefundRecordRepository {
+	return &refundRecordRepository{db: db}
+}
+
+// Create 创建退款记录
+func (r *refundRecordRepository) Create(record *models.RefundRecord) error {
+	if record.ID == "" {
+		record.ID = uuid.New().String()
+	}
+	if record.CreatedOn.IsZero() {
+		record.CreatedOn = time.Now()
+	}
+	return r.db.Create(record).Error
+}
+
+// CreateForOrder 在同一事务中将已支付订单置为退款中、创建退款记录并写入领域事件
+// 订单不是已支付状态（已有退款在处理或已全额退款）时不创建记录并返回false
+func (r *refundRecordRepository) CreateForOrder(
+	record *models.RefundRecord, events ...*models.OutboxEvent,
+) (bool, error) {
+	return r.createForOrder(record, func(*gorm.DB) ([]*models.OutboxEvent, error) {
+		return events, nil
+	})
+}
+
+// CreateNumberedForOrder 与 CreateForOrder 相同，退款序号在订单置为退款中（锁定订单行）后按订单已有退款记录数生成
+// prepare 根据序号（从1开始）设置退款单号并返回领域事件，并发发起的退款不会得到相同序号
+func (r *refundRecordRepository) CreateNumberedForOrder(
+	record *models.RefundRecord, prepare func(seq int) ([]*models.OutboxEvent, error),
+) (bool, error) {
+	return r.createForOrder(record, func(tx *gorm.DB) ([]*models.OutboxEvent, error) {
+		var count int64
+		if err := tx.Model(&models.RefundRecord{}).Where("OrderId = ?", record.OrderId).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		return prepare(int(count) + 1)
+	})
+}
+
+// createForOrder 将已支付订单置为退款中后，由 prepare 在同一事务中准备领域事件，再创建退款记录并写入事件
+func (r *refundRecordRepository) createForOrder(
+	record *models.RefundRecord, prepare func(tx *gorm.DB) ([]*models.OutboxEvent, error),
+) (bool, error) {
+	if record.ID == "" {
+		record.ID = uuid.New().String()
+	}
+	if record.CreatedOn.IsZero() {
+		record.CreatedOn = time.Now()
+	}
+
+	created := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("Id = ? AND PaymentStatus = ?", record.OrderId, int(enums.PaymentStatusPaid)).
+			Updates(map[string]interface{}{
+				"PaymentStatus": int(enums.PaymentStatusRefunding),
+				"Version":       versionIncrement(),
+				"UpdatedOn":     record.CreatedOn,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		events, err := prepare(tx)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		created = true
+		return createOutboxEvents(tx, events)
+	})
+	if err != nil {
+		return false, err
+	}
+	return created, nil
+}
+
+// CreateForInvalidatedOrder 已作废订单收到支付时，在同一事务中补记支付、将订单置为退款中并创建退款记录
+// 订单已不是作废状态（如重复通知已处理）时不创建记录并返回false
+func (r *refundRecordRepository) CreateForInvalidatedOrder(
+	record *models.RefundRecord, channelOrderNo string, paidAt time.Time, events ...*models.OutboxEvent,
+) (bool, error) {
+	if record.ID == "" {
+		record.ID = uuid.New().String()
+	}
+	if record.CreatedOn.IsZero() {
+		record.CreatedOn = time.Now()
+	}
+
+	created := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("Id = ? AND PaymentStatus = ?", record.OrderId, int(enums.PaymentStatusInvalid)).
+			Updates(map[string]interface{}{
+				"PaymentStatus":  int(enums.PaymentStatusRefunding),
+				"ChannelOrderNo": channelOrderNo,
+				"PaymentTime":    paidAt,
+				"Version":        versionIncrement(),
+				"UpdatedOn":      record.CreatedOn,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		created = true
+		return createOutboxEvents(tx, events)
+	})
+	return created, err
+}
+
+// GetByID 根据ID获取退款记录
+func (r *refundRecordRepository) GetByID(id string) (*models.RefundRecord, error) {
+	var record models.RefundRecord
+	err := r.db.Where("Id = ?", id).First(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// GetByRefundNo 根据退款单号获取退款记录
+func (r *refundRecordRepository) GetByRefundNo(refundNo string) (*models.RefundRecord, error) {
+	var record models.RefundRecord
+	err := r.db.Where("RefundNo = ?", refundNo).First(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// GetByOrderID 获取订单的全部退款记录
+func (r *refundRecordRepository) GetByOrderID(orderID string) ([]models.RefundRecord, error) {
+	var records []models.RefundRecord
+	err := r.db.Where("OrderId = ?", orderID).Order("CreatedOn ASC").Find(&records).Error
+	return records, err
+}
+
+// Update 按版本号更新退款记录，读取后已被其他请求修改（如退款回调已记录结果）时返回 ErrVersionConflict
+func (r *refundRecordRepository) Update(record *models.RefundRecord) error {
+	now := time.Now()
+	record.UpdatedOn = &now
+	return updateVersioned(r.db, record, record.ID, &record.Version)
+}
+
+// Claim 领取退款记录进行处理，并累加尝试次数
+// 仅待退款且已到重试时间，或处理中但超过staleBefore未更新（处理进程异常退出）的记录可被领取
+func (r *refundRecordRepository) Claim(id string, now, staleBefore time.Time) (bool, error) {
+	result := r.db.Model(&models.RefundRecord{}).
+		Where("Id = ?", id).
+		Where(r.retryableCondition(now, staleBefore)).
+		Updates(map[string]interface{}{
+			"Status":    int(enums.RefundStatusProcessing),
+			"Attempts":  gorm.Expr("Attempts + 1"),
+			"Version":   versionIncrement(),
+			"UpdatedOn": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetRetryable 获取可处理的退款记录
+func (r *refundRecordRepository) GetRetryable(now, staleBefore time.Time, limit int) ([]models.RefundRecord, error) {
+	var records []models.RefundRecord
+	err := r.db.Where(r.retryableCondition(now, staleBefore)).
+		Order("CreatedOn ASC").
+		Limit(limit).
+		Find(&records).Error
+	return records, err
+}
+
+// MarkAccepted 标记退款已被渠道受理，等待渠道退款结果回调
+func (r *refundRecordRepository) MarkAccepted(record *models.RefundRecord, acceptedAt time.Time) error {
+	return r.db.Model(&models.RefundRecord{}).
+		Where("Id = ? AND Status = ?", record.ID, int(enums.RefundStatusProcessing)).
+		Updates(map[string]interface{}{
+			"Status":          int(enums.RefundStatusAccepted),
+			"ChannelRefundNo": record.ChannelRefundNo,
+			"LastError":       nil,
+			"NextRetryOn":     nil,
+			"Version":         versionIncrement(),
+			"UpdatedOn":       acceptedAt,
+		}).Error
+}
+
+// MarkSucceeded 在同一事务中标记退款成功、回写订单退款信息并写入领域事件
+// 订单退款金额累加本次退款金额，全额退款后订单状态置为已退款，部分退款后恢复为已支付
+func (r *refundRecordRepository) MarkSucceeded(
+	record *models.RefundRecord, refundedAt time.Time, events ...*models.OutboxEvent,
+) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RefundRecord{}).
+			Where("Id = ? AND Status <> ?", record.ID, int(enums.RefundStatusSuccess)).
+			Updates(map[string]interface{}{
+				"Status":          int(enums.RefundStatusSuccess),
+				"ChannelRefundNo": record.ChannelRefundNo,
+				"LastError":       nil,
+				"NextRetryOn":     nil,
+				"CompletedOn":     refundedAt,
+				"Version":         versionIncrement(),
+				"UpdatedOn":       refundedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// 已记录过成功，避免重复累加订单退款金额
+			return nil
+		}
+
+		var order models.Order
+		if err := tx.Where("Id = ?", record.OrderId).First(&order).Error; err != nil {
+			return err
+		}
+
+		refunded := decimal.NewFromFloat(order.RefundAmount).Add(decimal.NewFromFloat(record.Amount)).Round(2)
+		order.RefundAmount = refunded.InexactFloat64()
+		order.RefundTime = &refundedAt
+		order.RefundReason = record.Reason
+		order.UpdatedOn = &refundedAt
+		if refunded.GreaterThanOrEqual(decimal.NewFromFloat(order.PayAmount)) {
+			order.PaymentStatus = int(enums.PaymentStatusRefunded)
+		} else if order.PaymentStatus == int(enums.PaymentStatusRefunding) {
+			order.PaymentStatus = int(enums.PaymentStatusPaid)
+		}
+		if err := updateVersioned(tx, &order, order.ID, &order.Version); err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, events)
+	})
+}
+
+// MarkFailed 在同一事务中标记退款失败并将退款中的订单恢复为已支付，失败记录待人工处理
+// 已标记过成功或失败的记录不重复处理，也不写入领域事件
+func (r *refundRecordRepository) MarkFailed(
+	record *models.RefundRecord, failedAt time.Time, events ...*models.OutboxEvent,
+) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RefundRecord{}).
+			Where("Id = ? AND Status NOT IN ?", record.ID,
+				[]int{int(enums.RefundStatusSuccess), int(enums.RefundStatusFailed)}).
+			Updates(map[string]interface{}{
+				"Status":      int(enums.RefundStatusFailed),
+				"LastError":   record.LastError,
+				"NextRetryOn": nil,
+				"Version":     versionIncrement(),
+				"UpdatedOn":   failedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		err := tx.Model(&models.Order{}).
+			Where("Id = ? AND PaymentStatus = ?", record.OrderId, int(enums.PaymentStatusRefunding)).
+			Updates(map[string]interface{}{
+				"PaymentStatus": int(enums.PaymentStatusPaid),
+				"Version":       versionIncrement(),
+				"UpdatedOn":     failedAt,
+			}).Error
+		if err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, events)
+	})
+}
+
+// retryableCondition 可领取退款记录的查询条件
+func (r *refundRecordRepository) retryableCondition(now, staleBefore time.Time) *gorm.DB {
+	return r.db.Where("Status = ? AND (NextRetryOn IS NULL OR NextRetryOn <= ?)",
+		int(enums.RefundStatusPending), now).
+		Or("Status = ? AND UpdatedOn < ?", int(enums.RefundStatusProcessing), staleBefore)
+}
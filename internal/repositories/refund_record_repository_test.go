@@ -0,0 +1,258 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+func setupRefundRecordRepository(t *testing.T) (*gorm.DB, RefundRecordRepository) {
+	t.Helper()
+
+	db := setupTestDB(t)
+	require.NoError(t, db.Create(&models.Order{
+		ID:            "order-1",
+		OrderNo:       stringPtr("ORD001"),
+		PayAmount:     10.00,
+		PaymentStatus: int(enums.PaymentStatusPaid),
+	}).Error)
+
+	return db, NewRefundRecordRepository(db)
+}
+
+func TestRefundRecordRepository_UniqueRefundNo(t *testing.T) {
+	_, repo := setupRefundRecordRepository(t)
+
+	require.NoError(t, repo.Create(&models.RefundRecord{OrderId: "order-1", RefundNo: "RF001", Amount: 10}))
+	assert.Error(t, repo.Create(&models.RefundRecord{OrderId: "order-1", RefundNo: "RF001", Amount: 10}))
+
+	record, err := repo.GetByRefundNo("RF001")
+	require.NoError(t, err)
+	assert.Equal(t, "order-1", record.OrderId)
+}
+
+func TestRefundRecordRepository_Claim(t *testing.T) {
+	_, repo := setupRefundRecordRepository(t)
+	now := time.Now()
+
+	record := &models.RefundRecord{OrderId: "order-1", RefundNo: "RF001", Amount: 10}
+	require.NoError(t, repo.Create(record))
+
+	claimed, err := repo.Claim(record.ID, now, now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	// 处理中的记录不能被重复领取
+	claimed, err = repo.Claim(record.ID, now, now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	// 处理超时后可被重新领取
+	claimed, err = repo.Claim(record.ID, now.Add(time.Hour), now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	saved, err := repo.GetByID(record.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, saved.Attempts)
+	assert.Equal(t, int(enums.RefundStatusProcessing), saved.Status)
+}
+
+func TestRefundRecordRepository_GetRetryable(t *testing.T) {
+	_, repo := setupRefundRecordRepository(t)
+	now := time.Now()
+	later := now.Add(time.Hour)
+
+	require.NoError(t, repo.Create(&models.RefundRecord{OrderId: "order-1", RefundNo: "RF-due", Amount: 1}))
+	require.NoError(t, repo.Create(&models.RefundRecord{
+		OrderId: "order-1", RefundNo: "RF-later", Amount: 1, NextRetryOn: &later,
+	}))
+	require.NoError(t, repo.Create(&models.RefundRecord{
+		OrderId: "order-1", RefundNo: "RF-done", Amount: 1, Status: int(enums.RefundStatusSuccess),
+	}))
+
+	records, err := repo.GetRetryable(now, now.Add(-time.Minute), 10)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "RF-due", records[0].RefundNo)
+}
+
+func TestRefundRecordRepository_MarkSucceeded(t *testing.T) {
+	db, repo := setupRefundRecordRepository(t)
+	reason := "制作失败自动退款"
+
+	partial := &models.RefundRecord{OrderId: "order-1", RefundNo: "RF001", Amount: 4.50, Reason: &reason}
+	rest := &models.RefundRecord{OrderId: "order-1", RefundNo: "RF002", Amount: 5.50, Reason: &reason}
+	require.NoError(t, repo.Create(partial))
+	require.NoError(t, repo.Create(rest))
+
+	require.NoError(t, repo.MarkSucceeded(partial, time.Now()))
+	// 重复标记不会重复累加退款金额
+	require.NoError(t, repo.MarkSucceeded(partial, time.Now()))
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", "order-1").Error)
+	assert.Equal(t, 4.50, order.RefundAmount)
+	assert.Equal(t, int(enums.PaymentStatusPaid), order.PaymentStatus)
+
+	require.NoError(t, repo.MarkSucceeded(rest, time.Now()))
+	require.NoError(t, db.First(&order, "Id = ?", "order-1").Error)
+	assert.Equal(t, 10.00, order.RefundAmount)
+	assert.Equal(t, int(enums.PaymentStatusRefunded), order.PaymentStatus)
+	assert.Equal(t, reason, *order.RefundReason)
+}
+
+func TestRefundRecordRepository_CreateForOrder(t *testing.T) {
+	db, repo := setupRefundRecordRepository(t)
+
+	created, err := repo.CreateForOrder(&models.RefundRecord{OrderId: "order-1", RefundNo: "RF001", Amount: 4})
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", "order-1").Error)
+	assert.Equal(t, int(enums.PaymentStatusRefunding), order.PaymentStatus)
+
+	// 已有退款在处理时不能再发起退款
+	created, err = repo.CreateForOrder(&models.RefundRecord{OrderId: "order-1", RefundNo: "RF002", Amount: 4})
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	records, err := repo.GetByOrderID("order-1")
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+}
+
+func TestRefundRecordRepository_CreateNumberedForOrder(t *testing.T) {
+	db, repo := setupRefundRecordRepository(t)
+	prepare := func(record *models.RefundRecord) func(seq int) ([]*models.OutboxEvent, error) {
+		return func(seq int) ([]*models.OutboxEvent, error) {
+			record.RefundNo = fmt.Sprintf("RFORD001%02d", seq)
+			return nil, nil
+		}
+	}
+
+	first := &models.RefundRecord{OrderId: "order-1", Amount: 4}
+	created, err := repo.CreateNumberedForOrder(first, prepare(first))
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "RFORD00101", first.RefundNo)
+
+	// 退款处理中时不生成序号
+	pending := &models.RefundRecord{OrderId: "order-1", Amount: 4}
+	created, err = repo.CreateNumberedForOrder(pending, prepare(pending))
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Empty(t, pending.RefundNo)
+
+	// 上一笔退款完成后按已有退款记录数生成下一个序号
+	require.NoError(t, repo.MarkSucceeded(first, time.Now()))
+	second := &models.RefundRecord{OrderId: "order-1", Amount: 4}
+	created, err = repo.CreateNumberedForOrder(second, prepare(second))
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "RFORD00102", second.RefundNo)
+
+	// prepare 失败时整个事务回滚，订单保持已支付
+	require.NoError(t, repo.MarkSucceeded(second, time.Now()))
+	rejected := &models.RefundRecord{OrderId: "order-1", Amount: 1}
+	_, err = repo.CreateNumberedForOrder(rejected, func(int) ([]*models.OutboxEvent, error) {
+		return nil, errors.New("refund limit reached")
+	})
+	assert.Error(t, err)
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", "order-1").Error)
+	assert.Equal(t, int(enums.PaymentStatusPaid), order.PaymentStatus)
+	records, err := repo.GetByOrderID("order-1")
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+}
+
+func TestRefundRecordRepository_MarkAcceptedAndFailed(t *testing.T) {
+	db, repo := setupRefundRecordRepository(t)
+	now := time.Now()
+
+	record := &models.RefundRecord{OrderId: "order-1", RefundNo: "RF001", Amount: 4}
+	created, err := repo.CreateForOrder(record)
+	require.NoError(t, err)
+	require.True(t, created)
+
+	claimed, err := repo.Claim(record.ID, now, now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	channelRefundNo := "CH001"
+	record.ChannelRefundNo = &channelRefundNo
+	require.NoError(t, repo.MarkAccepted(record, now))
+
+	saved, err := repo.GetByID(record.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int(enums.RefundStatusAccepted), saved.Status)
+	assert.Equal(t, "CH001", *saved.ChannelRefundNo)
+
+	// 已受理的记录不会被重试领取
+	claimed, err = repo.Claim(record.ID, now.Add(time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	lastError := "退款关闭"
+	record.LastError = &lastError
+	require.NoError(t, repo.MarkFailed(record, now))
+
+	saved, err = repo.GetByID(record.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int(enums.RefundStatusFailed), saved.Status)
+	assert.Equal(t, lastError, *saved.LastError)
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", "order-1").Error)
+	assert.Equal(t, int(enums.PaymentStatusPaid), order.PaymentStatus, "退款失败后订单恢复为已支付")
+	assert.Zero(t, order.RefundAmount)
+}
+
+func TestRefundRecordRepository_MarkSucceeded_PartialRestoresPaid(t *testing.T) {
+	db, repo := setupRefundRecordRepository(t)
+
+	record := &models.RefundRecord{OrderId: "order-1", RefundNo: "RF001", Amount: 4}
+	created, err := repo.CreateForOrder(record)
+	require.NoError(t, err)
+	require.True(t, created)
+
+	require.NoError(t, repo.MarkSucceeded(record, time.Now()))
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", "order-1").Error)
+	assert.Equal(t, int(enums.PaymentStatusPaid), order.PaymentStatus)
+	assert.Equal(t, 4.0, order.RefundAmount)
+}
+
+func TestRefundRecordRepository_Update_VersionConflict(t *testing.T) {
+	_, repo := setupRefundRecordRepository(t)
+	now := time.Now()
+
+	record := &models.RefundRecord{OrderId: "order-1", RefundNo: "RF001", Amount: 4}
+	require.NoError(t, repo.Create(record))
+	stale, err := repo.GetByID(record.ID)
+	require.NoError(t, err)
+
+	// 读取后退款结果已被记录，持有旧版本的更新不能覆盖
+	require.NoError(t, repo.MarkSucceeded(record, now))
+	stale.Status = int(enums.RefundStatusPending)
+	assert.ErrorIs(t, repo.Update(stale), ErrVersionConflict)
+
+	saved, err := repo.GetByID(record.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int(enums.RefundStatusSuccess), saved.Status)
+
+	saved.Reason = stringPtr("人工备注")
+	require.NoError(t, repo.Update(saved))
+}
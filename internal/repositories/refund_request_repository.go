@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+// RefundRequestRepository 顾客退款申请仓库接口
+type RefundRequestRepository interface {
+	Create(request *models.RefundRequest) error
+	CreatePending(request *models.RefundRequest) (bool, error)
+	GetByID(id string) (*models.RefundRequest, error)
+	GetByOrderID(orderID string) ([]models.RefundRequest, error)
+	GetByOwnerPaging(
+		machineOwnerID string, status *enums.RefundRequestStatus, pageIndex, pageSize int,
+	) ([]models.RefundRequest, int64, error)
+	UpdateStatus(id string, from, to enums.RefundRequestStatus, updates map[string]interface{}) (bool, error)
+}
+
+// refundRequestRepository 顾客退款申请仓库实现
+type refundRequestRepository struct {
+	db *gorm.DB
+}
+
+// NewRefundRequestRepository 创建顾客退款申请仓库
+func NewRefundRequestRepository(db *gorm.DB) RefundRequestRepository {
+	return &refundRequestRepository{db: db}
+}
+
+// Create 创建退款申请
+func (r *refundRequestRepository) Create(request *models.RefundRequest) error {
+	if request.ID == "" {
+		request.ID = uuid.New().String()
+	}
+	if request.CreatedOn.IsZero() {
+		request.CreatedOn = time.Now()
+	}
+	return r.db.Create(request).Error
+}
+
+// CreatePending 在订单没有待审核申请时创建退款申请，已有待审核申请时返回false
+// 事务内锁定订单行后再检查，避免同一订单并发提交产生多个待审核申请
+func (r *refundRequestRepository) CreatePending(request *models.RefundRequest) (bool, error) {
+	if request.ID == "" {
+		request.ID = uuid.New().String()
+	}
+	if request.CreatedOn.IsZero() {
+		request.CreatedOn = time.Now()
+	}
+
+	created := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("Id").
+			Where("Id = ?", request.OrderId).
+			First(&order).Error
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		err = tx.Model(&models.RefundRequest{}).
+			Where("OrderId = ? AND Status = ?", request.OrderId, int(enums.RefundRequestStatusPending)).
+			Count(&count).Error
+		if err != nil || count > 0 {
+			return err
+		}
+
+		if err := tx.Create(request).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+// GetByID 获取退款申请，不存在时返回nil
+func (r *refundRequestRepository) GetByID(id string) (*models.RefundRequest, error) {
+	var request models.RefundRequest
+	err := r.db.Where("Id = ?", id).First(&request).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// GetByOrderID 获取订单的全部退款申请，最新的在前
+func (r *refundRequestRepository) GetByOrderID(orderID string) ([]models.RefundRequest, error) {
+	var requests []models.RefundRequest
+	err := r.db.Where("OrderId = ?", orderID).Order("CreatedOn DESC").Find(&requests).Error
+	return requests, err
+}
+
+// GetByOwnerPaging 分页获取机主待处理的退款申请，status为nil时不限状态；待审核队列按提交顺序返回
+func (r *refundRequestRepository) GetByOwnerPaging(
+	machineOwnerID string, status *enums.RefundRequestStatus, pageIndex, pageSize int,
+) ([]models.RefundRequest, int64, error) {
+	query := r.db.Model(&models.RefundRequest{}).Where("MachineOwnerId = ?", machineOwnerID)
+	order := "CreatedOn DESC"
+	if status != nil {
+		query = query.Where("Status = ?", int(*status))
+		if *status == enums.RefundRequestStatusPending {
+			order = "CreatedOn ASC"
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var requests []models.RefundRequest
+	err := query.Order(order).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&requests).Error
+	return requests, total, err
+}
+
+// UpdateStatus 仅当退款申请处于from状态时更新为to状态，并更新附加字段
+// 返回是否更新成功，用于避免同一申请被重复审核
+func (r *refundRequestRepository) UpdateStatus(
+	id string, from, to enums.RefundRequestStatus, updates map[string]interface{},
+) (bool, error) {
+	values := map[string]interface{}{
+		"Status":    int(to),
+		"Version":   versionIncrement(),
+		"UpdatedOn": time.Now(),
+	}
+	for column, value := range updates {
+		values[column] = value
+	}
+
+	result := r.db.Model(&models.RefundRequest{}).
+		Where("Id = ? AND Status = ?", id, int(from)).
+		Updates(values)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
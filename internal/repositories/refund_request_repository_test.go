@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+func setupRefundRequestRepository(t *testing.T) RefundRequestRepository {
+	t.Helper()
+
+	return NewRefundRequestRepository(setupTestDB(t))
+}
+
+func TestRefundRequestRepository_OwnerQueue(t *testing.T) {
+	repo := setupRefundRequestRepository(t)
+	now := time.Now()
+
+	require.NoError(t, repo.Create(&models.RefundRequest{ID: "second", OrderId: "order-2",
+		MachineOwnerId: "owner-1", CreatedOn: now}))
+	require.NoError(t, repo.Create(&models.RefundRequest{ID: "first", OrderId: "order-1",
+		MachineOwnerId: "owner-1", CreatedOn: now.Add(-time.Minute)}))
+	require.NoError(t, repo.Create(&models.RefundRequest{ID: "other", OrderId: "order-3",
+		MachineOwnerId: "owner-2", CreatedOn: now}))
+
+	pending := enums.RefundRequestStatusPending
+	requests, total, err := repo.GetByOwnerPaging("owner-1", &pending, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, requests, 2)
+	assert.Equal(t, "first", requests[0].ID)
+
+	// 已审核的申请不能再次审核
+	updated, err := repo.UpdateStatus("first", enums.RefundRequestStatusPending, enums.RefundRequestStatusRejected,
+		map[string]interface{}{"ReviewRemark": "饮品已正常出杯"})
+	require.NoError(t, err)
+	assert.True(t, updated)
+	updated, err = repo.UpdateStatus("first", enums.RefundRequestStatusPending, enums.RefundRequestStatusApproved, nil)
+	require.NoError(t, err)
+	assert.False(t, updated)
+
+	request, err := repo.GetByID("first")
+	require.NoError(t, err)
+	assert.Equal(t, int(enums.RefundRequestStatusRejected), request.Status)
+	assert.Equal(t, "饮品已正常出杯", *request.ReviewRemark)
+	assert.Equal(t, int64(1), request.Version)
+
+	missing, err := repo.GetByID("missing")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestRefundRequestRepository_CreatePending(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRefundRequestRepository(db)
+	require.NoError(t, db.Create(&models.Order{ID: "order-1", CreatedOn: time.Now()}).Error)
+
+	created, err := repo.CreatePending(&models.RefundRequest{OrderId: "order-1", MachineOwnerId: "owner-1"})
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	// 同一订单已有待审核申请时不再创建
+	duplicate := &models.RefundRequest{OrderId: "order-1", MachineOwnerId: "owner-1"}
+	created, err = repo.CreatePending(duplicate)
+	require.NoError(t, err)
+	assert.False(t, created)
+	requests, err := repo.GetByOrderID("order-1")
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+
+	// 待审核申请被驳回后可以重新申请
+	_, err = repo.UpdateStatus(requests[0].ID, enums.RefundRequestStatusPending, enums.RefundRequestStatusRejected, nil)
+	require.NoError(t, err)
+	created, err = repo.CreatePending(duplicate)
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	_, err = repo.CreatePending(&models.RefundRequest{OrderId: "missing", MachineOwnerId: "owner-1"})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+// StockReservationRepository 库存预占仓库接口
+type StockReservationRepository interface {
+	Reserve(reservation *models.StockReservation) (bool, error)
+	Commit(orderID string, at time.Time) (bool, error)
+	Release(orderID string, at time.Time) (bool, error)
+}
+
+// stockReservationRepository 库存预占仓库实现
+type stockReservationRepository struct {
+	db *gorm.DB
+}
+
+// NewStockReservationRepository 创建库存预占仓库
+func NewStockReservationRepository(db *gorm.DB) StockReservationRepository {
+	return &stockReservationRepository{db: db}
+}
+
+// Reserve 在同一事务中占用料仓可售库存并创建预占记录
+// 料仓已停售或可用库存不足时不创建记录并返回false
+func (r *stockReservationRepository) Reserve(reservation *models.StockReservation) (bool, error) {
+	if reservation.ID == "" {
+		reservation.ID = uuid.New().String()
+	}
+	if reservation.CreatedOn.IsZero() {
+		reservation.CreatedOn = time.Now()
+	}
+	reservation.Status = int(enums.ReservationStatusReserved)
+
+	reserved := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.MaterialSilo{}).
+			Where("Id = ? AND IsSale = ? AND ProductId IS NOT NULL AND Stock - Reserved >= ?",
+				reservation.SiloId, models.BitBool(1), reservation.Quantity).
+			Updates(map[string]interface{}{
+				"Reserved":  gorm.Expr("Reserved + ?", reservation.Quantity),
+				"Version":   versionIncrement(),
+				"UpdatedOn": reservation.CreatedOn,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := tx.Create(reservation).Error; err != nil {
+			return err
+		}
+		reserved = true
+		return nil
+	})
+	return reserved, err
+}
+
+// Commit 制作完成后扣减预占的库存，库存耗尽时料仓自动停售
+// 返回料仓是否因本次扣减停售；订单没有待扣减的预占时不做处理
+func (r *stockReservationRepository) Commit(orderID string, at time.Time) (bool, error) {
+	soldOut := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		reservation, err := r.transit(tx, orderID, enums.ReservationStatusCommitted, at)
+		if err != nil || reservation == nil {
+			return err
+		}
+
+		err = tx.Model(&models.MaterialSilo{}).
+			Where("Id = ?", reservation.SiloId).
+			Updates(map[string]interface{}{
+				"Stock":     decrementExpr("Stock", reservation.Quantity),
+				"Reserved":  decrementExpr("Reserved", reservation.Quantity),
+				"Version":   versionIncrement(),
+				"UpdatedOn": at,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.MaterialSilo{}).
+			Where("Id = ? AND Stock <= 0 AND IsSale = ?", reservation.SiloId, models.BitBool(1)).
+			Updates(map[string]interface{}{
+				"IsSale":  models.BitBool(0),
+				"Version": versionIncrement(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		soldOut = result.RowsAffected > 0
+		return nil
+	})
+	return soldOut, err
+}
+
+// Release 将预占的库存归还料仓，订单没有待扣减的预占时返回false
+func (r *stockReservationRepository) Release(orderID string, at time.Time) (bool, error) {
+	released := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		reservation, err := r.transit(tx, orderID, enums.ReservationStatusReleased, at)
+		if err != nil || reservation == nil {
+			return err
+		}
+
+		err = tx.Model(&models.MaterialSilo{}).
+			Where("Id = ?", reservation.SiloId).
+			Updates(map[string]interface{}{
+				"Reserved":  decrementExpr("Reserved", reservation.Quantity),
+				"Version":   versionIncrement(),
+				"UpdatedOn": at,
+			}).Error
+		if err != nil {
+			return err
+		}
+		released = true
+		return nil
+	})
+	return released, err
+}
+
+// transit 将订单待扣减的预占记录置为目标状态，没有待扣减的预占或已被并发处理时返回nil
+func (r *stockReservationRepository) transit(
+	tx *gorm.DB, orderID string, to enums.ReservationStatus, at time.Time,
+) (*models.StockReservation, error) {
+	var reservation models.StockReservation
+	err := tx.Where("OrderId = ? AND Status = ?", orderID, int(enums.ReservationStatusReserved)).
+		First(&reservation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := tx.Model(&models.StockReservation{}).
+		Where("Id = ? AND Status = ?", reservation.ID, int(enums.ReservationStatusReserved)).
+		Updates(map[string]interface{}{
+			"Status":    int(to),
+			"UpdatedOn": at,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &reservation, nil
+}
+
+// decrementExpr 扣减数量列，结果不小于0
+func decrementExpr(column string, quantity int) clause.Expr {
+	return gorm.Expr(fmt.Sprintf("CASE WHEN %s > ? THEN %s - ? ELSE 0 END", column, column), quantity, quantity)
+}
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+func setupStockReservationRepository(t *testing.T, stock int) (*gorm.DB, StockReservationRepository) {
+	t.Helper()
+
+	db := setupTestDB(t)
+	require.NoError(t, db.Create(&models.MaterialSilo{
+		ID:        "silo-1",
+		MachineId: stringPtr("machine-1"),
+		ProductId: stringPtr("product-1"),
+		IsSale:    models.BitBool(1),
+		Total:     10,
+		Stock:     stock,
+		CreatedOn: time.Now(),
+	}).Error)
+
+	return db, NewStockReservationRepository(db)
+}
+
+func getSilo(t *testing.T, db *gorm.DB) models.MaterialSilo {
+	t.Helper()
+	var silo models.MaterialSilo
+	require.NoError(t, db.Where("Id = ?", "silo-1").First(&silo).Error)
+	return silo
+}
+
+func TestStockReservationRepository_Reserve(t *testing.T) {
+	db, repo := setupStockReservationRepository(t, 1)
+
+	reserved, err := repo.Reserve(&models.StockReservation{OrderId: "order-1", SiloId: "silo-1", Quantity: 1})
+	require.NoError(t, err)
+	assert.True(t, reserved)
+
+	// 库存已全部被预占，后续订单不能再占用
+	reserved, err = repo.Reserve(&models.StockReservation{OrderId: "order-2", SiloId: "silo-1", Quantity: 1})
+	require.NoError(t, err)
+	assert.False(t, reserved)
+
+	silo := getSilo(t, db)
+	assert.Equal(t, 1, silo.Stock)
+	assert.Equal(t, 1, silo.Reserved)
+	assert.Equal(t, int64(1), silo.Version)
+
+	var count int64
+	require.NoError(t, db.Model(&models.StockReservation{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestStockReservationRepository_Reserve_SaleOff(t *testing.T) {
+	db, repo := setupStockReservationRepository(t, 5)
+	require.NoError(t, db.Model(&models.MaterialSilo{}).Where("Id = ?", "silo-1").
+		Update("IsSale", models.BitBool(0)).Error)
+
+	reserved, err := repo.Reserve(&models.StockReservation{OrderId: "order-1", SiloId: "silo-1", Quantity: 1})
+	require.NoError(t, err)
+	assert.False(t, reserved)
+}
+
+func TestStockReservationRepository_Commit(t *testing.T) {
+	db, repo := setupStockReservationRepository(t, 2)
+	for _, orderID := range []string{"order-1", "order-2"} {
+		reserved, err := repo.Reserve(&models.StockReservation{OrderId: orderID, SiloId: "silo-1", Quantity: 1})
+		require.NoError(t, err)
+		require.True(t, reserved)
+	}
+
+	soldOut, err := repo.Commit("order-1", time.Now())
+	require.NoError(t, err)
+	assert.False(t, soldOut)
+	silo := getSilo(t, db)
+	assert.Equal(t, 1, silo.Stock)
+	assert.Equal(t, 1, silo.Reserved)
+
+	// 重复扣减不影响库存
+	soldOut, err = repo.Commit("order-1", time.Now())
+	require.NoError(t, err)
+	assert.False(t, soldOut)
+	assert.Equal(t, 1, getSilo(t, db).Stock)
+
+	// 库存耗尽后自动停售
+	soldOut, err = repo.Commit("order-2", time.Now())
+	require.NoError(t, err)
+	assert.True(t, soldOut)
+	silo = getSilo(t, db)
+	assert.Equal(t, 0, silo.Stock)
+	assert.Equal(t, 0, silo.Reserved)
+	assert.False(t, silo.IsSale.Bool())
+
+	var reservation models.StockReservation
+	require.NoError(t, db.Where("OrderId = ?", "order-2").First(&reservation).Error)
+	assert.Equal(t, int(enums.ReservationStatusCommitted), reservation.Status)
+}
+
+func TestStockReservationRepository_Release(t *testing.T) {
+	db, repo := setupStockReservationRepository(t, 1)
+	reserved, err := repo.Reserve(&models.StockReservation{OrderId: "order-1", SiloId: "silo-1", Quantity: 1})
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	released, err := repo.Release("order-1", time.Now())
+	require.NoError(t, err)
+	assert.True(t, released)
+	silo := getSilo(t, db)
+	assert.Equal(t, 1, silo.Stock)
+	assert.Equal(t, 0, silo.Reserved)
+	assert.True(t, silo.IsSale.Bool())
+
+	// 已释放的预占不能再扣减，也不会重复归还
+	soldOut, err := repo.Commit("order-1", time.Now())
+	require.NoError(t, err)
+	assert.False(t, soldOut)
+	released, err = repo.Release("order-1", time.Now())
+	require.NoError(t, err)
+	assert.False(t, released)
+	assert.Equal(t, 1, getSilo(t, db).Stock)
+
+	// 没有预占的订单
+	released, err = repo.Release("order-unknown", time.Now())
+	require.NoError(t, err)
+	assert.False(t, released)
+}
+
+func TestMaterialSiloRepository_Update_VersionConflict(t *testing.T) {
+	db, repo := setupStockReservationRepository(t, 5)
+	siloRepo := NewMaterialSiloRepository(db)
+	stale := getSilo(t, db)
+
+	// 预占库存后料仓版本号递增，基于旧数据的整条更新不能覆盖预占数量
+	reserved, err := repo.Reserve(&models.StockReservation{OrderId: "order-1", SiloId: "silo-1", Quantity: 1})
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	stale.Stock = 10
+	require.ErrorIs(t, siloRepo.Update(&stale), ErrVersionConflict)
+	silo := getSilo(t, db)
+	assert.Equal(t, 5, silo.Stock)
+	assert.Equal(t, 1, silo.Reserved)
+
+	silo.Stock = 10
+	require.NoError(t, siloRepo.Update(&silo))
+	assert.Equal(t, 10, getSilo(t, db).Stock)
+}
@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+// TokenRepository 刷新令牌与会话吊销仓库接口
+type TokenRepository interface {
+	CreateRefreshToken(token *models.RefreshToken) error
+	GetRefreshTokenByHash(tokenHash string) (*models.RefreshToken, error)
+	RotateRefreshToken(current *models.RefreshToken, next *models.RefreshToken) (bool, error)
+	RevokeSession(memberID, sessionID, reason string, expiresOn time.Time) error
+	GetActiveSessionIDs(memberID string, now time.Time) ([]string, error)
+	IsSessionRevoked(sessionID string) (bool, error)
+	DeleteExpiredRevocations(now time.Time, limit int) (int64, error)
+	DeleteExpiredRefreshTokens(now time.Time, limit int) (int64, error)
+}
+
+// tokenRepository 刷新令牌与会话吊销仓库实现
+type tokenRepository struct {
+	db *gorm.DB
+}
+
+// NewTokenRepository 创建刷新令牌与会话吊销仓库
+func NewTokenRepository(db *gorm.DB) TokenRepository {
+	return &tokenRepository{db: db}
+}
+
+// CreateRefreshToken 保存新签发的刷新令牌
+func (r *tokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	if token.CreatedOn.IsZero() {
+		token.CreatedOn = time.Now()
+	}
+	return r.db.Create(token).Error
+}
+
+// GetRefreshTokenByHash 按令牌摘要获取刷新令牌，不存在时返回nil
+func (r *tokenRepository) GetRefreshTokenByHash(tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.Where("TokenHash = ?", tokenHash).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RotateRefreshToken 将当前刷新令牌轮换为next，当前令牌已被轮换或吊销时返回false
+// 并发使用同一令牌刷新时只有一个请求成功
+func (r *tokenRepository) RotateRefreshToken(current *models.RefreshToken, next *models.RefreshToken) (bool, error) {
+	rotated := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.RefreshToken{}).
+			Where("Id = ? AND RevokedOn IS NULL", current.ID).
+			Updates(map[string]interface{}{
+				"RevokedOn":    now,
+				"ReplacedById": next.ID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if next.CreatedOn.IsZero() {
+			next.CreatedOn = now
+		}
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+		rotated = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return rotated, nil
+}
+
+// RevokeSession 吊销登录会话：作废会话内未吊销的刷新令牌，并将会话加入吊销列表
+// 会话已在吊销列表中时保持原记录
+func (r *tokenRepository) RevokeSession(memberID, sessionID, reason string, expiresOn time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Model(&models.RefreshToken{}).
+			Where("SessionId = ? AND RevokedOn IS NULL", sessionID).
+			Update("RevokedOn", now).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Create(&models.TokenRevocation{
+			SessionId: sessionID,
+			MemberId:  memberID,
+			Reason:    reason,
+			ExpiresOn: expiresOn,
+			CreatedOn: now,
+		}).Error
+		if err != nil && !isDuplicateKeyError(err) {
+			return err
+		}
+		return nil
+	})
+}
+
+// GetActiveSessionIDs 获取会员仍持有有效刷新令牌的登录会话
+func (r *tokenRepository) GetActiveSessionIDs(memberID string, now time.Time) ([]string, error) {
+	var sessionIDs []string
+	err := r.db.Model(&models.RefreshToken{}).
+		Where("MemberId = ? AND RevokedOn IS NULL AND ExpiresOn > ?", memberID, now).
+		Distinct().
+		Pluck("SessionId", &sessionIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessionIDs, nil
+}
+
+// IsSessionRevoked 登录会话是否在吊销列表中
+func (r *tokenRepository) IsSessionRevoked(sessionID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.TokenRevocation{}).
+		Where("SessionId = ?", sessionID).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DeleteExpiredRevocations 删除now之前已过期的会话吊销记录，单次最多删除limit条，返回删除数量
+// 吊销记录过期时会话签发的访问令牌均已过期，不再需要校验
+func (r *tokenRepository) DeleteExpiredRevocations(now time.Time, limit int) (int64, error) {
+	var sessionIDs []string
+	err := r.db.Model(&models.TokenRevocation{}).
+		Where("ExpiresOn < ?", now).
+		Order("ExpiresOn ASC").
+		Limit(limit).
+		Pluck("SessionId", &sessionIDs).Error
+	if err != nil || len(sessionIDs) == 0 {
+		return 0, err
+	}
+
+	result := r.db.Where("SessionId IN ?", sessionIDs).Delete(&models.TokenRevocation{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteExpiredRefreshTokens 删除now之前已过期的刷新令牌，单次最多删除limit条，返回删除数量
+func (r *tokenRepository) DeleteExpiredRefreshTokens(now time.Time, limit int) (int64, error) {
+	var ids []string
+	err := r.db.Model(&models.RefreshToken{}).
+		Where("ExpiresOn < ?", now).
+		Order("ExpiresOn ASC").
+		Limit(limit).
+		Pluck("Id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	result := r.db.Where("Id IN ?", ids).Delete(&models.RefreshToken{})
+	return result.RowsAffected, result.Error
+}
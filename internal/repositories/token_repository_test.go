@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+func setupTokenRepository(t *testing.T) TokenRepository {
+	t.Helper()
+
+	return NewTokenRepository(setupTestDB(t))
+}
+
+func newRefreshTokenRecord(id, sessionID string, expiresOn time.Time) *models.RefreshToken {
+	return &models.RefreshToken{
+		ID:        id,
+		MemberId:  "member-1",
+		SessionId: sessionID,
+		TokenHash: "hash-" + id,
+		Role:      "Consumer",
+		ExpiresOn: expiresOn,
+	}
+}
+
+func TestTokenRepository_RotateRefreshToken(t *testing.T) {
+	repo := setupTokenRepository(t)
+	expiresOn := time.Now().Add(time.Hour)
+
+	current := newRefreshTokenRecord("token-1", "session-1", expiresOn)
+	require.NoError(t, repo.CreateRefreshToken(current))
+
+	rotated, err := repo.RotateRefreshToken(current, newRefreshTokenRecord("token-2", "session-1", expiresOn))
+	require.NoError(t, err)
+	assert.True(t, rotated)
+
+	// 已轮换的令牌不能再次轮换
+	rotated, err = repo.RotateRefreshToken(current, newRefreshTokenRecord("token-3", "session-1", expiresOn))
+	require.NoError(t, err)
+	assert.False(t, rotated)
+
+	old, err := repo.GetRefreshTokenByHash("hash-token-1")
+	require.NoError(t, err)
+	require.NotNil(t, old)
+	assert.NotNil(t, old.RevokedOn)
+	require.NotNil(t, old.ReplacedById)
+	assert.Equal(t, "token-2", *old.ReplacedById)
+
+	next, err := repo.GetRefreshTokenByHash("hash-token-2")
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.True(t, next.IsActive(time.Now()))
+
+	missing, err := repo.GetRefreshTokenByHash("hash-token-3")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestTokenRepository_RevokeSession(t *testing.T) {
+	repo := setupTokenRepository(t)
+	now := time.Now()
+
+	require.NoError(t, repo.CreateRefreshToken(newRefreshTokenRecord("token-1", "session-1", now.Add(time.Hour))))
+	require.NoError(t, repo.CreateRefreshToken(newRefreshTokenRecord("token-2", "session-2", now.Add(time.Hour))))
+	require.NoError(t, repo.CreateRefreshToken(newRefreshTokenRecord("token-3", "session-3", now.Add(-time.Hour))))
+
+	sessionIDs, err := repo.GetActiveSessionIDs("member-1", now)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"session-1", "session-2"}, sessionIDs)
+
+	require.NoError(t, repo.RevokeSession("member-1", "session-1", "Logout", now.Add(time.Hour)))
+	// 重复吊销保持原记录
+	require.NoError(t, repo.RevokeSession("member-1", "session-1", "SignOutAll", now.Add(time.Hour)))
+
+	revoked, err := repo.IsSessionRevoked("session-1")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = repo.IsSessionRevoked("session-2")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	sessionIDs, err = repo.GetActiveSessionIDs("member-1", now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"session-2"}, sessionIDs)
+}
+
+func TestTokenRepository_DeleteExpiredRevocations(t *testing.T) {
+	repo := setupTokenRepository(t)
+	now := time.Now()
+
+	require.NoError(t, repo.RevokeSession("member-1", "session-1", "Logout", now.Add(-2*time.Hour)))
+	require.NoError(t, repo.RevokeSession("member-1", "session-2", "Logout", now.Add(-time.Hour)))
+	require.NoError(t, repo.RevokeSession("member-1", "session-3", "Logout", now.Add(time.Hour)))
+
+	deleted, err := repo.DeleteExpiredRevocations(now, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	deleted, err = repo.DeleteExpiredRevocations(now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	revoked, err := repo.IsSessionRevoked("session-2")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	revoked, err = repo.IsSessionRevoked("session-3")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestTokenRepository_DeleteExpiredRefreshTokens(t *testing.T) {
+	repo := setupTokenRepository(t)
+	now := time.Now()
+
+	require.NoError(t, repo.CreateRefreshToken(newRefreshTokenRecord("token-1", "session-1", now.Add(-time.Hour))))
+	require.NoError(t, repo.CreateRefreshToken(newRefreshTokenRecord("token-2", "session-2", now.Add(time.Hour))))
+
+	deleted, err := repo.DeleteExpiredRefreshTokens(now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	token, err := repo.GetRefreshTokenByHash("hash-token-1")
+	require.NoError(t, err)
+	assert.Nil(t, token)
+
+	token, err = repo.GetRefreshTokenByHash("hash-token-2")
+	require.NoError(t, err)
+	require.NotNil(t, token)
+	assert.Equal(t, "token-2", token.ID)
+}
@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrVersionConflict 记录在读取后已被其他请求修改，调用方应重新读取后再更新
+var ErrVersionConflict = errors.New("record modified concurrently")
+
+// VersionConflictError 乐观锁版本冲突，errors.Is(err, ErrVersionConflict) 可匹配
+type VersionConflictError struct {
+	Table   string
+	ID      string
+	Version int64
+}
+
+// Error 实现 error 接口
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%s %s: %v (version %d)", e.Table, e.ID, ErrVersionConflict, e.Version)
+}
+
+// Is 匹配 ErrVersionConflict
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// versionIncrement 条件更新时递增版本号，使持有旧版本的整条记录更新失败
+func versionIncrement() clause.Expr {
+	return gorm.Expr("Version + 1")
+}
+
+// updateVersioned 仅当数据库中版本号与读取时一致时更新整条记录，并递增版本号
+// 记录已被修改或已不存在时返回 *VersionConflictError，更新失败时 version 保持原值
+func updateVersioned(db *gorm.DB, model interface{}, id string, version *int64) error {
+	expected := *version
+	*version = expected + 1
+
+	result := db.Model(model).Where("Version = ?", expected).Select("*").Updates(model)
+	if result.Error != nil {
+		*version = expected
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		*version = expected
+		return &VersionConflictError{Table: tableName(db, model), ID: id, Version: expected}
+	}
+	return nil
+}
+
+// tableName 获取模型对应的表名
+func tableName(db *gorm.DB, model interface{}) string {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return fmt.Sprintf("%T", model)
+	}
+	return stmt.Schema.Table
+}
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+// WeChatSessionRepository 微信会话密钥仓库接口
+type WeChatSessionRepository interface {
+	Save(session *models.WeChatSession) error
+	GetByMemberID(memberID string) (*models.WeChatSession, error)
+}
+
+// weChatSessionRepository 微信会话密钥仓库实现
+type weChatSessionRepository struct {
+	db *gorm.DB
+}
+
+// NewWeChatSessionRepository 创建微信会话密钥仓库
+func NewWeChatSessionRepository(db *gorm.DB) WeChatSessionRepository {
+	return &weChatSessionRepository{db: db}
+}
+
+// Save 保存会员的微信会话密钥，已存在时覆盖
+func (r *weChatSessionRepository) Save(session *models.WeChatSession) error {
+	if session.UpdatedOn.IsZero() {
+		session.UpdatedOn = time.Now()
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "MemberId"}},
+		DoUpdates: clause.AssignmentColumns([]string{"OpenId", "SessionKey", "UpdatedOn"}),
+	}).Create(session).Error
+}
+
+// GetByMemberID 获取会员的微信会话密钥，不存在时返回nil
+func (r *weChatSessionRepository) GetByMemberID(memberID string) (*models.WeChatSession, error) {
+	var session models.WeChatSession
+	err := r.db.Where("MemberId = ?", memberID).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
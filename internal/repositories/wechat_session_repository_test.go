@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+func TestWeChatSessionRepository_Save(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewWeChatSessionRepository(db)
+
+	missing, err := repo.GetByMemberID("member-1")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	require.NoError(t, repo.Save(&models.WeChatSession{MemberId: "member-1", OpenId: "openid-1", SessionKey: "key-1"}))
+
+	// 重新登录后覆盖旧的会话密钥
+	require.NoError(t, repo.Save(&models.WeChatSession{MemberId: "member-1", OpenId: "openid-1", SessionKey: "key-2"}))
+
+	session, err := repo.GetByMemberID("member-1")
+	require.NoError(t, err)
+	require.NotNil(t, session)
+	assert.Equal(t, "key-2", session.SessionKey)
+
+	var count int64
+	require.NoError(t, db.Model(&models.WeChatSession{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
@@ -1,6 +1,9 @@
 package routes
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -9,6 +12,8 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 
 	"github.com/ddteam/drink-master/internal/config"
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
 	"github.com/ddteam/drink-master/internal/handlers"
 	"github.com/ddteam/drink-master/internal/middleware"
 	"github.com/ddteam/drink-master/internal/repositories"
@@ -16,9 +21,105 @@ import (
 	"github.com/ddteam/drink-master/pkg/wechat"
 )
 
-// SetupRoutes 设置所有路由 (基于MobileAPI Controllers)
-func SetupRoutes(db *gorm.DB) *gin.Engine {
+// 后台任务扫描间隔
+const (
+	makeTimeoutScanInterval = 30 * time.Second // 制作超时扫描
+	refundRetryScanInterval = time.Minute      // 退款重试扫描
+	orderExpiryScanInterval = time.Minute      // 超时未支付订单扫描
+	outboxDispatchInterval  = time.Second      // 领域事件投递，支付成功后尽快下发制作指令
+	orderEventPollInterval  = time.Second      // 订单状态推送读取新事件，所有推送连接共用
+	dataCleanupInterval     = time.Hour        // 过期数据清理
+)
+
+// backgroundService 定期执行的后台任务
+type backgroundService interface {
+	Start(interval time.Duration)
+	Stop()
+}
+
+// backgroundJob 后台任务及其执行间隔
+type backgroundJob struct {
+	service  backgroundService
+	interval time.Duration
+}
+
+// App 路由与后台任务
+// 创建时只注册路由，后台任务（领域事件投递、订单状态推送、退款重试、超时关单、数据清理、制作超时扫描）随服务启动与停止
+type App struct {
+	Router *gin.Engine
+	jobs   []backgroundJob
+}
+
+// Start 启动后台任务
+func (a *App) Start() {
+	for _, job := range a.jobs {
+		job.service.Start(job.interval)
+	}
+}
+
+// Stop 按启动的相反顺序停止后台任务
+func (a *App) Stop() {
+	for i := len(a.jobs) - 1; i >= 0; i-- {
+		a.jobs[i].service.Stop()
+	}
+}
+
+// NewApp 设置所有路由 (基于MobileAPI Controllers) 并创建后台任务
+// 配置了MQTT broker但无法连接时返回错误，不以默认设备服务启动
+func NewApp(db *gorm.DB) (*App, error) {
 	router := gin.Default()
+	app := &App{Router: router}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.InfoLevel)
+
+	// 初始化设备服务（配置MQTT时接入真实设备状态），需在创建其他服务前注册
+	deviceConnected, err := setupDeviceService(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// 进程内唯一的制作服务，支付成功后由订单已支付事件的订阅者下发制作指令
+	makeService := services.NewMakeService(db, services.NewDeviceService())
+	paymentService := services.NewPaymentService(db)
+
+	// 自动退款重试
+	refundService := services.NewRefundService(db, paymentService)
+	app.jobs = append(app.jobs, backgroundJob{refundService, refundRetryScanInterval})
+
+	// 超时未支付订单关单，多实例部署时由持有任务租约的实例执行
+	orderExpiryService := services.NewOrderExpiryService(db, paymentService)
+	app.jobs = append(app.jobs, backgroundJob{orderExpiryService, orderExpiryScanInterval})
+
+	// 过期数据清理，超过保留期的领域事件与已过期的幂等请求记录、刷新令牌、会话吊销记录按批次删除
+	dataCleanupService := services.NewDataCleanupService(db)
+	dataCleanupService.Register("outbox_events", services.OutboxCleanup(db))
+	dataCleanupService.Register("idempotency_records", services.IdempotencyCleanup(db))
+	dataCleanupService.Register("refresh_tokens", services.RefreshTokenCleanup(db))
+	dataCleanupService.Register("token_revocations", services.TokenRevocationCleanup(db))
+	app.jobs = append(app.jobs, backgroundJob{dataCleanupService, dataCleanupInterval})
+
+	// 领域事件投递：支付成功后下发制作指令，按制作结果或订单作废结算预占库存，制作失败时自动退款
+	// 收不到设备制作结果时支付成功即扣减预占库存；事件先于其他后台任务启动、最后停止
+	dispatcher := services.NewOutboxDispatcher(db)
+	dispatcher.Subscribe(contracts.EventOrderPaid, services.MakeDispatchSubscriber(makeService))
+	stockSubscriber := services.StockReservationSubscriber(services.NewStockService(db), !makeService.TracksMakeResult())
+	for _, eventType := range []string{
+		contracts.EventOrderPaid, contracts.EventOrderMade, contracts.EventOrderMakeFailed, contracts.EventOrderInvalidated,
+	} {
+		dispatcher.Subscribe(eventType, stockSubscriber)
+	}
+	dispatcher.Subscribe(contracts.EventOrderMakeFailed, services.MakeFailRefundSubscriber(refundService))
+	app.jobs = append([]backgroundJob{{dispatcher, outboxDispatchInterval}}, app.jobs...)
+
+	// 订单状态推送，所有连接共用一个轮询任务读取新写入的事件
+	orderEventService := services.NewOrderEventService(db)
+	app.jobs = append(app.jobs, backgroundJob{orderEventService, orderEventPollInterval})
+
+	// 接入设备后订阅设备制作事件并扫描制作超时订单
+	if deviceConnected {
+		app.jobs = append(app.jobs, backgroundJob{makeService, makeTimeoutScanInterval})
+	}
 
 	// 中间件设置
 	router.Use(middleware.CORSMiddleware())
@@ -38,6 +139,18 @@ func SetupRoutes(db *gorm.DB) *gin.Engine {
 	wechatConfig := config.NewWeChatConfig()
 	wechatClient := wechat.NewClient(wechatConfig.AppID, wechatConfig.AppSecret)
 
+	// JWTAuth 拒绝已吊销会话（退出登录、刷新令牌重复使用、角色变更）签发的访问令牌
+	middleware.SetTokenRevocationChecker(services.NewAuthTokenService(db, services.NewJWTService()))
+
+	// 角色与权限授权，需在 JWTAuth 之后使用；管理员可访问所有受角色保护的接口并拥有全部权限
+	requireOwner := middleware.RequireRole(enums.MemberRoleOwner)
+	requireMaintainer := middleware.RequireRole(enums.MemberRoleOwner, enums.MemberRoleMaintainer)
+	requireOrderPlace := middleware.RequirePermission(enums.PermissionOrderPlace)
+	requireOrderManage := middleware.RequirePermission(enums.PermissionOrderManage)
+	requireMachineManage := middleware.RequirePermission(enums.PermissionMachineManage)
+	requireSiloMaintain := middleware.RequirePermission(enums.PermissionSiloMaintain)
+	requireOwnerFinance := middleware.RequirePermission(enums.PermissionOwnerFinance)
+
 	// 基于AccountController的路由
 	accountHandler := handlers.NewAccountHandler(db, wechatClient)
 	account := router.Group("/api/Account")
@@ -45,12 +158,19 @@ func SetupRoutes(db *gorm.DB) *gin.Engine {
 		// 公开接口
 		account.GET("/CheckUserInfo", accountHandler.CheckUserInfo)
 		account.POST("/weChatLogin", accountHandler.WeChatLogin)
+		account.POST("/RefreshToken", accountHandler.RefreshToken)
 
 		// 需要认证的接口
 		account.GET("/CheckLogin", middleware.JWTAuth(), accountHandler.CheckLogin)
 		account.GET("/GetUserInfo", middleware.JWTAuth(), accountHandler.GetUserInfo)
+		account.POST("/Logout", middleware.JWTAuth(), accountHandler.Logout)
+		account.POST("/DecryptPhoneNumber", middleware.JWTAuth(), accountHandler.DecryptPhoneNumber)
+		account.POST("/DecryptUserInfo", middleware.JWTAuth(), accountHandler.DecryptUserInfo)
 	}
 
+	// 公开JWT验证公钥，供其他服务离线验证token
+	router.GET("/.well-known/jwks.json", accountHandler.JWKS)
+
 	// 基于MemberController的路由
 	memberHandler := handlers.NewMemberHandler(db)
 	member := router.Group("/api/Member")
@@ -70,10 +190,11 @@ func SetupRoutes(db *gorm.DB) *gin.Engine {
 		machine.GET("/CheckDeviceExist", machineHandler.CheckDeviceExist)
 		machine.GET("/GetProductList", machineHandler.GetProductList)
 
-		// 需要认证的接口
-		machine.POST("/GetPaging", middleware.JWTAuth(), machineHandler.GetPaging)
-		machine.GET("/GetList", middleware.JWTAuth(), machineHandler.GetList)
-		machine.GET("/OpenOrClose", middleware.JWTAuth(), machineHandler.OpenOrCloseBusiness)
+		// 机主接口
+		ownerMachine := machine.Group("", middleware.JWTAuth(), requireOwner, requireMachineManage)
+		ownerMachine.POST("/GetPaging", machineHandler.GetPaging)
+		ownerMachine.GET("/GetList", machineHandler.GetList)
+		ownerMachine.GET("/OpenOrClose", machineHandler.OpenOrCloseBusiness)
 	}
 
 	// 基于OrderController的路由
@@ -81,22 +202,48 @@ func SetupRoutes(db *gorm.DB) *gin.Engine {
 	orderRepo := repositories.NewOrderRepository(db)
 	machineRepo := repositories.NewMachineRepository(db)
 	memberRepo := repositories.NewMemberRepository(db)
+	productRepo := repositories.NewProductRepository(db)
 	deviceSvc := services.NewDeviceService()
-	orderService := services.NewOrderService(orderRepo, machineRepo, memberRepo, deviceSvc)
+	stockService := services.NewStockService(db)
+	orderService := services.NewOrderService(
+		orderRepo, machineRepo, memberRepo, productRepo, deviceSvc, refundService, stockService,
+	)
 	orderHandler := handlers.NewOrderHandler(db, orderService)
+	orderStreamHandler := handlers.NewOrderStreamHandler(db, orderEventService)
+	// 下单与退款支持 Idempotency-Key，弱网重试时重放首次响应
+	idempotency := middleware.Idempotency(repositories.NewIdempotencyRepository(db))
 	order := router.Group("/api/Order")
-	order.Use(middleware.JWTAuth()) // 所有Order接口都需要认证
+	order.Use(middleware.JWTAuth(), requireOrderPlace) // 所有Order接口都需要认证
 	{
 		order.POST("/GetPaging", orderHandler.GetPaging)
 		order.GET("/Get", orderHandler.Get)
-		order.POST("/Create", orderHandler.Create)
-		order.POST("/Refund", orderHandler.Refund)
+		order.GET("/Events", orderStreamHandler.Events)
+		order.POST("/Create", idempotency, orderHandler.Create)
+
+		// 机主接口
+		ownerOrder := order.Group("", requireOwner, requireOrderManage)
+		ownerOrder.POST("/GetOwnerPaging", orderHandler.GetOwnerPaging)
+		ownerOrder.POST("/Refund", idempotency, orderHandler.Refund)
+	}
+
+	// 顾客退款申请，机主审核同意后发起退款
+	refundRequestHandler := handlers.NewRefundRequestHandler(db, services.NewRefundRequestService(db, refundService))
+	refundRequest := router.Group("/api/RefundRequest")
+	refundRequest.Use(middleware.JWTAuth(), requireOrderPlace)
+	{
+		refundRequest.POST("/Submit", idempotency, refundRequestHandler.Submit)
+		refundRequest.GET("/GetByOrder", refundRequestHandler.GetByOrder)
+
+		// 机主审核
+		ownerRefundRequest := refundRequest.Group("", requireOwner, requireOrderManage)
+		ownerRefundRequest.POST("/GetPaging", refundRequestHandler.GetPaging)
+		ownerRefundRequest.POST("/Review", idempotency, refundRequestHandler.Review)
 	}
 
 	// 基于PaymentController的路由
-	paymentHandler := handlers.NewPaymentHandler(db)
+	paymentHandler := handlers.NewPaymentHandler(db, paymentService)
 	payment := router.Group("/api/Payment")
-	payment.Use(middleware.JWTAuth()) // 所有Payment接口都需要认证
+	payment.Use(middleware.JWTAuth(), requireOrderPlace) // 所有Payment接口都需要认证
 	{
 		payment.GET("/Get", paymentHandler.Get)
 		payment.GET("/Query", paymentHandler.Query)
@@ -113,7 +260,7 @@ func SetupRoutes(db *gorm.DB) *gin.Engine {
 	// 基于MaterialSiloController的路由 (物料槽管理)
 	materialSiloHandler := handlers.NewMaterialSiloHandler(db)
 	materialSilo := router.Group("/api/MaterialSilo")
-	materialSilo.Use(middleware.JWTAuth()) // 物料槽管理需要认证
+	materialSilo.Use(middleware.JWTAuth(), requireMaintainer, requireSiloMaintain) // 物料槽管理限机主与运维人员
 	{
 		materialSilo.POST("/GetPaging", materialSiloHandler.GetPaging)
 		materialSilo.POST("/UpdateStock", materialSiloHandler.UpdateStock)
@@ -124,19 +271,42 @@ func SetupRoutes(db *gorm.DB) *gin.Engine {
 	// 基于MachineOwnerController的路由 (机主管理功能)
 	machineOwnerHandler := handlers.NewMachineOwnerHandler(db)
 	machineOwner := router.Group("/api/MachineOwner")
-	machineOwner.Use(middleware.JWTAuth()) // 所有机主接口都需要认证
+	machineOwner.Use(middleware.JWTAuth(), requireOwner, requireOwnerFinance) // 所有机主接口都需要机主认证
 	{
 		machineOwner.GET("/GetSales", machineOwnerHandler.GetSales)
 		machineOwner.GET("/GetSalesStats", machineOwnerHandler.GetSalesStats)
+		machineOwner.GET("/GetPaymentAccount", machineOwnerHandler.GetPaymentAccount)
+		machineOwner.POST("/UpdatePaymentAccount", machineOwnerHandler.UpdatePaymentAccount)
+		machineOwner.POST("/TestPaymentAccount", machineOwnerHandler.TestPaymentAccount)
 	}
 
 	// 基于CallbackController的路由 (无需认证)
-	// 初始化回调服务所需的依赖
-	paymentService := services.NewPaymentService(db)
-	logger := logrus.New()
-	logger.SetLevel(logrus.InfoLevel)
-	callbackHandler := handlers.NewCallbackHandler(orderService, paymentService, logger)
+	callbackHandler := handlers.NewCallbackHandler(orderService, paymentService, refundService, logger)
 	router.POST("/api/Callback/PaymentResult", callbackHandler.PaymentResult)
+	router.POST("/api/Callback/PaymentNotify/:channelCode", callbackHandler.PaymentNotify)
+	router.POST("/api/Callback/PaymentNotify/:channelCode/:merchantId", callbackHandler.PaymentNotify)
+	router.POST("/api/Callback/RefundResult", callbackHandler.RefundResult)
+	router.POST("/api/Callback/RefundNotify/:channelCode", callbackHandler.RefundNotify)
+	router.POST("/api/Callback/RefundNotify/:channelCode/:merchantId", callbackHandler.RefundNotify)
+
+	return app, nil
+}
+
+// setupDeviceService 配置了MQTT broker时连接设备网络，并注册为进程级设备服务
+// 返回是否已接入真实设备；配置了broker但连接失败时返回错误
+// 默认设备服务总是报告设备在线，回退到默认服务会让制作指令静默丢失而订单照常收款
+func setupDeviceService(logger *logrus.Logger) (bool, error) {
+	mqttConfig := config.NewMQTTConfig()
+	if !mqttConfig.Enabled() {
+		return false, nil
+	}
+
+	deviceService, err := services.NewMQTTDeviceService(mqttConfig)
+	if err != nil {
+		return false, fmt.Errorf("mqtt device service: %w", err)
+	}
 
-	return router
+	services.SetDefaultDeviceService(deviceService)
+	logger.WithField("broker", mqttConfig.Broker).Info("MQTT设备服务已连接")
+	return true, nil
 }
@@ -18,11 +18,15 @@ func setupTestDB() *gorm.DB {
 	return db
 }
 
-func TestSetupRoutes(t *testing.T) {
+func TestNewApp(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db := setupTestDB()
 
-	router := SetupRoutes(db)
+	app, err := NewApp(db)
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	router := app.Router
 
 	if router == nil {
 		t.Error("Expected router to be created")
@@ -38,11 +42,15 @@ func TestSetupRoutes(t *testing.T) {
 	}
 }
 
-func TestSetupRoutes_DatabaseHealth(t *testing.T) {
+func TestNewApp_DatabaseHealth(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db := setupTestDB()
 
-	router := SetupRoutes(db)
+	app, err := NewApp(db)
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	router := app.Router
 
 	// 测试数据库健康检查路由
 	req, _ := http.NewRequest("GET", "/api/health/db", nil)
@@ -53,3 +61,30 @@ func TestSetupRoutes_DatabaseHealth(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
 	}
 }
+
+func TestNewApp_MQTTUnreachable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("MQTT_BROKER", "tcp://127.0.0.1:1")
+
+	// 配置了MQTT但broker不可达时拒绝启动，不回退到总是报告在线的默认设备服务
+	if _, err := NewApp(setupTestDB()); err == nil {
+		t.Error("Expected error when MQTT broker is unreachable")
+	}
+}
+
+func TestApp_StartStop(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	app, err := NewApp(setupTestDB())
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	if len(app.jobs) == 0 {
+		t.Fatal("Expected background jobs to be registered")
+	}
+
+	// 后台任务由调用方启动与停止，停止后可安全重复调用
+	app.Start()
+	app.Stop()
+	app.Stop()
+}
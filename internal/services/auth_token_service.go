@@ -0,0 +1,254 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/middleware"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+)
+
+// 刷新令牌相关错误
+var (
+	// ErrInvalidRefreshToken 刷新令牌不存在、已吊销或已过期
+	ErrInvalidRefreshToken = errors.New("刷新令牌无效或已过期")
+	// ErrRefreshTokenReused 已轮换的刷新令牌被再次使用，疑似泄露，所属会话已吊销
+	ErrRefreshTokenReused = errors.New("刷新令牌已被使用，请重新登录")
+	// ErrMemberRoleChanged 会员角色已变更，所有会话已吊销
+	ErrMemberRoleChanged = errors.New("会员角色已变更，请重新登录")
+)
+
+// 会话吊销原因
+const (
+	TokenRevokeReasonLogout      = "Logout"
+	TokenRevokeReasonSignOutAll  = "SignOutAll"
+	TokenRevokeReasonReuse       = "RefreshReuse"
+	TokenRevokeReasonRoleChanged = "RoleChanged"
+)
+
+// defaultRefreshTokenDays 刷新令牌默认有效天数，每次刷新后重新计算
+const defaultRefreshTokenDays = 30
+
+// AuthTokenServiceInterface 登录令牌服务接口
+// 登录时签发短期访问令牌与刷新令牌，刷新令牌每次使用后轮换，同时实现 JWTAuth 的吊销检查
+type AuthTokenServiceInterface interface {
+	IssueTokens(member *models.Member) (*contracts.AuthTokenResponse, error)
+	Refresh(refreshToken string) (*contracts.AuthTokenResponse, error)
+	Logout(memberID, sessionID string, allSessions bool) error
+	RevokeMemberSessions(memberID, reason string) error
+	IsRevoked(claims *middleware.JWTClaims) (bool, error)
+}
+
+// authTokenService 登录令牌服务实现
+type authTokenService struct {
+	tokenRepo     repositories.TokenRepository
+	memberRepo    *repositories.MemberRepository
+	jwtService    *JWTService
+	refreshExpiry time.Duration
+	logger        *logrus.Logger
+}
+
+// NewAuthTokenService 创建登录令牌服务
+func NewAuthTokenService(db *gorm.DB, jwtService *JWTService) AuthTokenServiceInterface {
+	refreshDays := defaultRefreshTokenDays
+	if value := os.Getenv("JWT_REFRESH_TOKEN_DAYS"); value != "" {
+		if days, err := strconv.Atoi(value); err == nil && days > 0 {
+			refreshDays = days
+		}
+	}
+
+	return &authTokenService{
+		tokenRepo:     repositories.NewTokenRepository(db),
+		memberRepo:    repositories.NewMemberRepository(db),
+		jwtService:    jwtService,
+		refreshExpiry: time.Duration(refreshDays) * 24 * time.Hour,
+		logger:        logrus.New(),
+	}
+}
+
+// IssueTokens 登录时开启新会话，签发访问令牌与刷新令牌
+func (s *authTokenService) IssueTokens(member *models.Member) (*contracts.AuthTokenResponse, error) {
+	refreshToken, record, err := s.newRefreshToken(member, uuid.New().String())
+	if err != nil {
+		return nil, err
+	}
+	if createErr := s.tokenRepo.CreateRefreshToken(record); createErr != nil {
+		return nil, fmt.Errorf("failed to save refresh token: %w", createErr)
+	}
+	return s.tokenResponse(member, record.SessionId, refreshToken)
+}
+
+// Refresh 使用刷新令牌换取新的访问令牌，并轮换刷新令牌
+func (s *authTokenService) Refresh(refreshToken string) (*contracts.AuthTokenResponse, error) {
+	current, err := s.tokenRepo.GetRefreshTokenByHash(hashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	if current == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if !current.IsActive(time.Now()) {
+		return nil, s.rejectInactiveToken(current)
+	}
+
+	member, err := s.memberRepo.GetByID(current.MemberId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member: %w", err)
+	}
+	if member.EffectiveRole().Code() != current.Role {
+		if revokeErr := s.RevokeMemberSessions(member.ID, TokenRevokeReasonRoleChanged); revokeErr != nil {
+			return nil, revokeErr
+		}
+		return nil, ErrMemberRoleChanged
+	}
+
+	nextToken, next, err := s.newRefreshToken(member, current.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := s.tokenRepo.RotateRefreshToken(current, next)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if !rotated {
+		// 并发刷新时已被其他请求轮换
+		return nil, ErrInvalidRefreshToken
+	}
+	return s.tokenResponse(member, current.SessionId, nextToken)
+}
+
+// Logout 吊销当前会话，allSessions 为true时吊销会员的所有会话
+func (s *authTokenService) Logout(memberID, sessionID string, allSessions bool) error {
+	if allSessions {
+		if err := s.RevokeMemberSessions(memberID, TokenRevokeReasonSignOutAll); err != nil {
+			return err
+		}
+	}
+	if sessionID == "" {
+		return nil
+	}
+	return s.revokeSession(memberID, sessionID, TokenRevokeReasonLogout)
+}
+
+// rejectInactiveToken 拒绝已失效的刷新令牌，已轮换的令牌被再次使用时吊销整个会话
+func (s *authTokenService) rejectInactiveToken(token *models.RefreshToken) error {
+	if token.ReplacedById == nil {
+		return ErrInvalidRefreshToken
+	}
+
+	if err := s.revokeSession(token.MemberId, token.SessionId, TokenRevokeReasonReuse); err != nil {
+		return err
+	}
+	s.logger.WithFields(logrus.Fields{
+		"member_id":  token.MemberId,
+		"session_id": token.SessionId,
+	}).Warn("检测到刷新令牌重复使用，已吊销会话")
+	return ErrRefreshTokenReused
+}
+
+// RevokeMemberSessions 吊销会员的所有会话，会员角色变更后强制重新登录
+func (s *authTokenService) RevokeMemberSessions(memberID, reason string) error {
+	sessionIDs, err := s.tokenRepo.GetActiveSessionIDs(memberID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to get member sessions: %w", err)
+	}
+	for _, sessionID := range sessionIDs {
+		if revokeErr := s.revokeSession(memberID, sessionID, reason); revokeErr != nil {
+			return revokeErr
+		}
+	}
+	return nil
+}
+
+// IsRevoked 访问令牌所属会话是否已吊销
+// 会员角色或所属机主与令牌签发时不一致时吊销该会员的所有会话，旧角色的访问令牌立即失效
+func (s *authTokenService) IsRevoked(claims *middleware.JWTClaims) (bool, error) {
+	if claims.SessionID == "" {
+		return false, nil
+	}
+	revoked, err := s.tokenRepo.IsSessionRevoked(claims.SessionID)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	member, err := s.memberRepo.GetByID(claims.MemberID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get member: %w", err)
+	}
+	if member.EffectiveRole().Code() == enums.ParseMemberRole(claims.Role).Code() &&
+		ptrToString(member.MachineOwnerId) == claims.MachineOwnerID {
+		return false, nil
+	}
+	if err := s.RevokeMemberSessions(member.ID, TokenRevokeReasonRoleChanged); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// revokeSession 吊销会话，吊销记录保留到会话内访问令牌全部过期
+func (s *authTokenService) revokeSession(memberID, sessionID, reason string) error {
+	expiresOn := time.Now().Add(s.jwtService.Expiry())
+	if err := s.tokenRepo.RevokeSession(memberID, sessionID, reason, expiresOn); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// newRefreshToken 生成随机刷新令牌，返回令牌明文与待保存的记录
+func (s *authTokenService) newRefreshToken(
+	member *models.Member, sessionID string,
+) (string, *models.RefreshToken, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	now := time.Now()
+	return token, &models.RefreshToken{
+		ID:        uuid.New().String(),
+		MemberId:  member.ID,
+		SessionId: sessionID,
+		TokenHash: hashRefreshToken(token),
+		Role:      member.EffectiveRole().Code(),
+		ExpiresOn: now.Add(s.refreshExpiry),
+		CreatedOn: now,
+	}, nil
+}
+
+// tokenResponse 签发访问令牌并组装响应
+func (s *authTokenService) tokenResponse(
+	member *models.Member, sessionID, refreshToken string,
+) (*contracts.AuthTokenResponse, error) {
+	accessToken, err := s.jwtService.GenerateToken(member, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+	return &contracts.AuthTokenResponse{
+		Token:            accessToken,
+		ExpiresIn:        int64(s.jwtService.Expiry().Seconds()),
+		RefreshToken:     refreshToken,
+		RefreshExpiresIn: int64(s.refreshExpiry.Seconds()),
+	}, nil
+}
+
+// hashRefreshToken 刷新令牌摘要，数据库中只保存摘要
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/middleware"
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+// setupAuthTokenService 创建使用内存数据库的登录令牌服务，并写入一名顾客
+func setupAuthTokenService(t *testing.T) (AuthTokenServiceInterface, *JWTService, *gorm.DB, *models.Member) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Member{}, &models.RefreshToken{}, &models.TokenRevocation{}))
+	member := &models.Member{ID: "member-1", Role: int(enums.MemberRoleConsumer)}
+	require.NoError(t, db.Create(member).Error)
+
+	jwtService := NewJWTService()
+	return NewAuthTokenService(db, jwtService), jwtService, db, member
+}
+
+func sessionClaims(t *testing.T, jwtService *JWTService, token string) *middleware.JWTClaims {
+	t.Helper()
+	claims, err := jwtService.ValidateToken(token)
+	require.NoError(t, err)
+	require.NotEmpty(t, claims.SessionID)
+	return claims
+}
+
+func TestAuthTokenService_RefreshRotatesToken(t *testing.T) {
+	service, jwtService, _, member := setupAuthTokenService(t)
+
+	issued, err := service.IssueTokens(member)
+	require.NoError(t, err)
+	assert.NotEmpty(t, issued.RefreshToken)
+	assert.Equal(t, int64(1800), issued.ExpiresIn)
+	claims := sessionClaims(t, jwtService, issued.Token)
+	assert.Equal(t, "Consumer", claims.Role)
+
+	refreshed, err := service.Refresh(issued.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, issued.RefreshToken, refreshed.RefreshToken)
+	assert.Equal(t, claims.SessionID, sessionClaims(t, jwtService, refreshed.Token).SessionID)
+
+	revoked, err := service.IsRevoked(claims)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	// 旧令牌再次使用视为泄露，整个会话被吊销
+	_, err = service.Refresh(issued.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	_, err = service.Refresh(refreshed.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	revoked, err = service.IsRevoked(claims)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	_, err = service.Refresh("unknown-token")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestAuthTokenService_Logout(t *testing.T) {
+	service, jwtService, _, member := setupAuthTokenService(t)
+
+	first, err := service.IssueTokens(member)
+	require.NoError(t, err)
+	second, err := service.IssueTokens(member)
+	require.NoError(t, err)
+	firstClaims := sessionClaims(t, jwtService, first.Token)
+	secondClaims := sessionClaims(t, jwtService, second.Token)
+
+	require.NoError(t, service.Logout(member.ID, firstClaims.SessionID, false))
+	revoked, err := service.IsRevoked(firstClaims)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = service.IsRevoked(secondClaims)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+	_, err = service.Refresh(first.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	require.NoError(t, service.Logout(member.ID, secondClaims.SessionID, true))
+	revoked, err = service.IsRevoked(secondClaims)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestAuthTokenService_RoleChangeSignsOutAllSessions(t *testing.T) {
+	service, jwtService, db, member := setupAuthTokenService(t)
+
+	first, err := service.IssueTokens(member)
+	require.NoError(t, err)
+	second, err := service.IssueTokens(member)
+	require.NoError(t, err)
+
+	require.NoError(t, db.Model(&models.Member{}).Where("Id = ?", member.ID).
+		Update("Role", int(enums.MemberRoleOwner)).Error)
+
+	_, err = service.Refresh(first.RefreshToken)
+	assert.ErrorIs(t, err, ErrMemberRoleChanged)
+
+	for _, token := range []string{first.Token, second.Token} {
+		revoked, revokeErr := service.IsRevoked(sessionClaims(t, jwtService, token))
+		require.NoError(t, revokeErr)
+		assert.True(t, revoked)
+	}
+	_, err = service.Refresh(second.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestAuthTokenService_RoleChangeRevokesAccessTokens(t *testing.T) {
+	service, jwtService, db, member := setupAuthTokenService(t)
+
+	issued, err := service.IssueTokens(member)
+	require.NoError(t, err)
+	claims := sessionClaims(t, jwtService, issued.Token)
+
+	// 角色变更后旧角色的访问令牌立即失效，无需等待下次刷新
+	require.NoError(t, db.Model(&models.Member{}).Where("Id = ?", member.ID).
+		Update("Role", int(enums.MemberRoleOwner)).Error)
+	revoked, err := service.IsRevoked(claims)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	_, err = service.Refresh(issued.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// 重新登录后按新角色签发，所属机主变更同样吊销
+	var owner models.Member
+	require.NoError(t, db.First(&owner, "Id = ?", member.ID).Error)
+	issued, err = service.IssueTokens(&owner)
+	require.NoError(t, err)
+	claims = sessionClaims(t, jwtService, issued.Token)
+	revoked, err = service.IsRevoked(claims)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	require.NoError(t, db.Model(&models.Member{}).Where("Id = ?", member.ID).
+		Update("MachineOwnerId", "owner-2").Error)
+	revoked, err = service.IsRevoked(claims)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/repositories"
+)
+
+// 过期数据清理批次大小、默认保留期与清理任务租约
+const (
+	dataCleanupBatchSize     = 1000
+	defaultOutboxRetention   = 7 * 24 * time.Hour
+	dataCleanupLeaseName     = "data_cleanup"
+	dataCleanupLeaseTTL      = 10 * time.Minute
+	dataCleanupMaxBatchesRun = 100 // 单轮每个清理项最多执行的批次数，剩余数据下一轮继续清理
+)
+
+// CleanupFunc 删除now之前已过期的数据，单次最多删除limit条，返回删除数量
+type CleanupFunc func(now time.Time, limit int) (int64, error)
+
+// DataCleanupServiceInterface 过期数据清理服务接口
+type DataCleanupServiceInterface interface {
+	Register(name string, cleanup CleanupFunc)
+	RunOnce() (int64, error)
+	Start(interval time.Duration)
+	Stop()
+}
+
+// dataCleanupService 过期数据清理服务实现
+// 按批次删除各清理项的过期数据，避免长事务和锁表；单个清理项失败不影响其他清理项
+// 多实例部署时通过任务租约保证同一时刻只有一个实例清理
+type dataCleanupService struct {
+	leaseRepo repositories.JobLeaseRepository
+	holder    string
+	now       func() time.Time
+	logger    *logrus.Logger
+	mu        sync.RWMutex
+	names     []string
+	cleanups  map[string]CleanupFunc
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+// NewDataCleanupService 创建过期数据清理服务
+func NewDataCleanupService(db *gorm.DB) DataCleanupServiceInterface {
+	return &dataCleanupService{
+		leaseRepo: repositories.NewJobLeaseRepository(db),
+		holder:    leaseHolderID(),
+		now:       time.Now,
+		logger:    logrus.StandardLogger(),
+		cleanups:  make(map[string]CleanupFunc),
+		stop:      make(chan struct{}),
+	}
+}
+
+// Register 注册清理项，同名清理项后注册的覆盖先注册的
+func (s *dataCleanupService) Register(name string, cleanup CleanupFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.cleanups[name]; !ok {
+		s.names = append(s.names, name)
+	}
+	s.cleanups[name] = cleanup
+}
+
+// RunOnce 执行一轮清理，返回删除的数据总量
+// 租约由其他实例持有时不做处理
+func (s *dataCleanupService) RunOnce() (int64, error) {
+	acquired, err := s.leaseRepo.Acquire(dataCleanupLeaseName, s.holder, s.now(), dataCleanupLeaseTTL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire data cleanup lease: %w", err)
+	}
+	if !acquired {
+		return 0, nil
+	}
+
+	s.mu.RLock()
+	names := append([]string(nil), s.names...)
+	cleanups := make(map[string]CleanupFunc, len(s.cleanups))
+	for name, cleanup := range s.cleanups {
+		cleanups[name] = cleanup
+	}
+	s.mu.RUnlock()
+
+	var total int64
+	for _, name := range names {
+		deleted, err := s.runCleanup(cleanups[name])
+		total += deleted
+		if err != nil {
+			s.logger.WithError(err).WithField("cleanup", name).Warn("过期数据清理失败")
+			continue
+		}
+		if deleted > 0 {
+			s.logger.WithFields(logrus.Fields{"cleanup": name, "count": deleted}).Info("已清理过期数据")
+		}
+	}
+	return total, nil
+}
+
+// runCleanup 按批次执行单个清理项，直到不足一批或达到单轮批次上限
+func (s *dataCleanupService) runCleanup(cleanup CleanupFunc) (int64, error) {
+	now := s.now()
+	var total int64
+	for i := 0; i < dataCleanupMaxBatchesRun; i++ {
+		deleted, err := cleanup(now, dataCleanupBatchSize)
+		total += deleted
+		if err != nil {
+			return total, err
+		}
+		if deleted < dataCleanupBatchSize {
+			break
+		}
+	}
+	return total, nil
+}
+
+// Start 定期清理过期数据
+func (s *dataCleanupService) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if _, err := s.RunOnce(); err != nil {
+					s.logger.WithError(err).Error("过期数据清理失败")
+				}
+			}
+		}
+	}()
+}
+
+// Stop 停止清理并释放任务租约
+func (s *dataCleanupService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		if err := s.leaseRepo.Release(dataCleanupLeaseName, s.holder); err != nil {
+			s.logger.WithError(err).Warn("释放数据清理任务租约失败")
+		}
+	})
+}
+
+// OutboxCleanup 删除超过保留期且已投递或投递失败的领域事件
+// 保留期由 OUTBOX_RETENTION_DAYS 配置，默认7天；订单事件流只回放保留期内的事件
+func OutboxCleanup(db *gorm.DB) CleanupFunc {
+	retention := defaultOutboxRetention
+	if days, err := strconv.Atoi(getEnvOrDefault("OUTBOX_RETENTION_DAYS", "")); err == nil && days > 0 {
+		retention = time.Duration(days) * 24 * time.Hour
+	}
+
+	outboxRepo := repositories.NewOutboxRepository(db)
+	return func(now time.Time, limit int) (int64, error) {
+		return outboxRepo.DeleteCreatedBefore(now.Add(-retention), limit)
+	}
+}
+
+// IdempotencyCleanup 删除已过期的幂等请求记录
+func IdempotencyCleanup(db *gorm.DB) CleanupFunc {
+	return repositories.NewIdempotencyRepository(db).DeleteExpired
+}
+
+// TokenRevocationCleanup 删除已过期的会话吊销记录
+func TokenRevocationCleanup(db *gorm.DB) CleanupFunc {
+	return repositories.NewTokenRepository(db).DeleteExpiredRevocations
+}
+
+// RefreshTokenCleanup 删除已过期的刷新令牌
+func RefreshTokenCleanup(db *gorm.DB) CleanupFunc {
+	return repositories.NewTokenRepository(db).DeleteExpiredRefreshTokens
+}
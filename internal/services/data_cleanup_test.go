@@ -0,0 +1,102 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+)
+
+func setupDataCleanupService(t *testing.T, holder string) (*dataCleanupService, *gorm.DB) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.JobLease{}, &models.OutboxEvent{}))
+
+	service := &dataCleanupService{
+		leaseRepo: repositories.NewJobLeaseRepository(db),
+		holder:    holder,
+		now:       time.Now,
+		logger:    logrus.New(),
+		cleanups:  make(map[string]CleanupFunc),
+		stop:      make(chan struct{}),
+	}
+	return service, db
+}
+
+func TestDataCleanupService_RunOnce(t *testing.T) {
+	service, _ := setupDataCleanupService(t, "replica-a")
+
+	// 满批次时继续清理，不足一批时结束
+	var batches []int64
+	remaining := int64(dataCleanupBatchSize + 10)
+	service.Register("records", func(now time.Time, limit int) (int64, error) {
+		deleted := remaining
+		if deleted > int64(limit) {
+			deleted = int64(limit)
+		}
+		remaining -= deleted
+		batches = append(batches, deleted)
+		return deleted, nil
+	})
+	// 清理失败不影响其他清理项
+	service.Register("broken", func(now time.Time, limit int) (int64, error) {
+		return 0, errors.New("db down")
+	})
+	var cleaned bool
+	service.Register("sessions", func(now time.Time, limit int) (int64, error) {
+		cleaned = true
+		return 2, nil
+	})
+
+	total, err := service.RunOnce()
+	require.NoError(t, err)
+	assert.Equal(t, int64(dataCleanupBatchSize+12), total)
+	assert.Equal(t, []int64{dataCleanupBatchSize, 10}, batches)
+	assert.True(t, cleaned)
+}
+
+func TestDataCleanupService_LeaseHeldByOtherReplica(t *testing.T) {
+	service, db := setupDataCleanupService(t, "replica-a")
+	other := &dataCleanupService{leaseRepo: repositories.NewJobLeaseRepository(db), holder: "replica-b", now: time.Now}
+	acquired, err := other.leaseRepo.Acquire(dataCleanupLeaseName, other.holder, time.Now(), dataCleanupLeaseTTL)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	service.Register("records", func(now time.Time, limit int) (int64, error) {
+		t.Fatal("cleanup should not run without the lease")
+		return 0, nil
+	})
+	total, err := service.RunOnce()
+	require.NoError(t, err)
+	assert.Zero(t, total)
+}
+
+func TestOutboxCleanup(t *testing.T) {
+	t.Setenv("OUTBOX_RETENTION_DAYS", "3")
+	_, db := setupDataCleanupService(t, "replica-a")
+	now := time.Now()
+	require.NoError(t, db.Create(&[]models.OutboxEvent{
+		{ID: "expired", AggregateId: "order-1", Status: int(enums.OutboxStatusDelivered), CreatedOn: now.Add(-4 * 24 * time.Hour)},
+		{ID: "undelivered", AggregateId: "order-1", CreatedOn: now.Add(-4 * 24 * time.Hour)},
+		{ID: "retained", AggregateId: "order-1", Status: int(enums.OutboxStatusDelivered), CreatedOn: now.Add(-2 * 24 * time.Hour)},
+	}).Error)
+
+	deleted, err := OutboxCleanup(db)(now, dataCleanupBatchSize)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	var ids []string
+	require.NoError(t, db.Model(&models.OutboxEvent{}).Order("Id").Pluck("Id", &ids).Error)
+	assert.Equal(t, []string{"retained", "undelivered"}, ids)
+}
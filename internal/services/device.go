@@ -1,6 +1,7 @@
 package services
 
 import (
+	"sync"
 	"time"
 
 	"github.com/ddteam/drink-master/internal/contracts"
@@ -11,43 +12,73 @@ type DeviceServiceInterface interface {
 	CheckDeviceOnline(deviceID string) (bool, error)
 	UpdateRegister(deviceID string, params map[string]int) error
 	GetDeviceStatus(deviceID string) (*contracts.DeviceStatusCheckResult, error)
+	DispatchMake(deviceID string, command contracts.DeviceMakeCommand) error
+	SubscribeMakeEvents(handler DeviceMakeEventHandler)
 }
 
-// DeviceService 设备服务实现
-type DeviceService struct {
-	// TODO: 添加MQTT客户端或其他设备通信接口
+// DeviceMakeEventHandler 设备制作事件处理函数
+type DeviceMakeEventHandler func(event contracts.DeviceMakeEvent)
+
+// DeviceService 设备服务默认实现（未接入设备通信时使用）
+type DeviceService struct{}
+
+var (
+	defaultDeviceMu      sync.RWMutex
+	defaultDeviceService DeviceServiceInterface
+)
+
+// SetDefaultDeviceService 注册进程级设备服务（如MQTT设备服务），传入nil恢复默认实现
+func SetDefaultDeviceService(service DeviceServiceInterface) {
+	defaultDeviceMu.Lock()
+	defer defaultDeviceMu.Unlock()
+	defaultDeviceService = service
 }
 
 // NewDeviceService 创建设备服务
+// 已注册进程级设备服务时返回该实例，保证各服务共享同一设备连接与在线状态表
 func NewDeviceService() DeviceServiceInterface {
+	defaultDeviceMu.RLock()
+	defer defaultDeviceMu.RUnlock()
+	if defaultDeviceService != nil {
+		return defaultDeviceService
+	}
 	return &DeviceService{}
 }
 
+// reportsMakeEvents 设备服务是否上报制作事件，默认实现未接入设备通信，订单收不到制作结果
+func reportsMakeEvents(service DeviceServiceInterface) bool {
+	_, isDefault := service.(*DeviceService)
+	return service != nil && !isDefault
+}
+
 // CheckDeviceOnline 检查设备在线状态
 func (s *DeviceService) CheckDeviceOnline(deviceID string) (bool, error) {
-	// TODO: 实现设备在线状态检查逻辑
-	// 这里应该通过MQTT或其他通信方式检查设备状态
-
-	// 临时实现：模拟设备状态检查
+	// 未接入设备通信时无法获知真实状态，视所有设备为在线
+	// 真实在线状态由 MQTTDeviceService 提供
 	if deviceID == "" {
 		return false, nil
 	}
 
-	// 假设所有设备都在线（实际应该连接MQTT broker检查）
 	return true, nil
 }
 
 // UpdateRegister 更新设备寄存器
 func (s *DeviceService) UpdateRegister(deviceID string, params map[string]int) error {
-	// TODO: 实现设备寄存器更新逻辑
-	// 这里应该通过MQTT发送控制指令到设备
-
-	// 临时实现：记录日志
-	// log.Printf("UpdateRegister for device %s with params: %v", deviceID, params)
+	// 未接入设备通信时不下发指令，由 MQTTDeviceService 负责真实下发
+	return nil
+}
 
+// DispatchMake 下发饮品制作指令
+func (s *DeviceService) DispatchMake(deviceID string, command contracts.DeviceMakeCommand) error {
+	// 未接入设备通信时不下发指令，由 MQTTDeviceService 负责真实下发
 	return nil
 }
 
+// SubscribeMakeEvents 订阅设备制作事件
+func (s *DeviceService) SubscribeMakeEvents(handler DeviceMakeEventHandler) {
+	// 未接入设备通信时不会产生设备事件
+}
+
 // GetDeviceStatus 获取设备状态详情
 func (s *DeviceService) GetDeviceStatus(deviceID string) (*contracts.DeviceStatusCheckResult, error) {
 	online, err := s.CheckDeviceOnline(deviceID)
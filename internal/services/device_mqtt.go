@@ -0,0 +1,294 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ddteam/drink-master/internal/config"
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/pkg/mqtt"
+)
+
+// 设备主题后缀: {prefix}/{deviceID}/{suffix}
+const (
+	deviceTopicHeartbeat = "heartbeat" // 设备 -> 服务端 心跳
+	deviceTopicStatus    = "status"    // 设备 -> 服务端 上下线状态（含遗嘱消息）
+	deviceTopicRegister  = "register"  // 服务端 -> 设备 寄存器写入
+	deviceTopicMake      = "make"      // 服务端 -> 设备 饮品制作指令
+	deviceTopicEvent     = "event"     // 设备 -> 服务端 制作事件
+)
+
+// 设备状态消息内容
+const (
+	deviceStatusOnline  = "online"
+	deviceStatusOffline = "offline"
+)
+
+// mqttTransport MQTT客户端抽象，便于替换实现
+type mqttTransport interface {
+	Publish(topic string, payload []byte, retain bool) error
+	Subscribe(filter string, handler mqtt.MessageHandler) error
+	Close() error
+}
+
+// devicePresence 设备在线状态记录
+type devicePresence struct {
+	online   bool
+	lastSeen time.Time
+}
+
+// MQTTDeviceService 基于MQTT的设备服务实现
+// 订阅设备心跳与遗嘱主题，在内存中维护设备在线表
+type MQTTDeviceService struct {
+	client           mqttTransport
+	topicPrefix      string
+	heartbeatTimeout time.Duration
+	now              func() time.Time
+
+	mu       sync.RWMutex
+	presence map[string]*devicePresence
+	handlers []DeviceMakeEventHandler
+}
+
+// NewMQTTDeviceService 连接MQTT broker并创建设备服务
+func NewMQTTDeviceService(cfg *config.MQTTConfig) (*MQTTDeviceService, error) {
+	if cfg == nil || !cfg.Enabled() {
+		return nil, errors.New("mqtt broker is not configured")
+	}
+
+	client, err := mqtt.Dial(mqtt.Options{
+		Broker:   cfg.Broker,
+		ClientID: cfg.ClientID,
+		Username: cfg.Username,
+		Password: cfg.Password,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect mqtt broker: %w", err)
+	}
+
+	service, err := newMQTTDeviceService(client, cfg.TopicPrefix, cfg.HeartbeatTimeout)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return service, nil
+}
+
+// newMQTTDeviceService 基于已建立的连接创建设备服务并订阅设备主题
+func newMQTTDeviceService(
+	client mqttTransport, topicPrefix string, heartbeatTimeout time.Duration,
+) (*MQTTDeviceService, error) {
+	s := &MQTTDeviceService{
+		client:           client,
+		topicPrefix:      strings.TrimSuffix(topicPrefix, "/"),
+		heartbeatTimeout: heartbeatTimeout,
+		now:              time.Now,
+		presence:         make(map[string]*devicePresence),
+	}
+
+	if err := client.Subscribe(s.topicFilter(deviceTopicHeartbeat), s.handleHeartbeat); err != nil {
+		return nil, fmt.Errorf("failed to subscribe heartbeat topic: %w", err)
+	}
+	if err := client.Subscribe(s.topicFilter(deviceTopicStatus), s.handleStatus); err != nil {
+		return nil, fmt.Errorf("failed to subscribe status topic: %w", err)
+	}
+	if err := client.Subscribe(s.topicFilter(deviceTopicEvent), s.handleMakeEvent); err != nil {
+		return nil, fmt.Errorf("failed to subscribe event topic: %w", err)
+	}
+
+	return s, nil
+}
+
+// CheckDeviceOnline 检查设备在线状态
+// 设备需已上报在线且最近一次心跳未超时
+func (s *MQTTDeviceService) CheckDeviceOnline(deviceID string) (bool, error) {
+	if deviceID == "" {
+		return false, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, exists := s.presence[deviceID]
+	if !exists || !p.online {
+		return false, nil
+	}
+	return s.now().Sub(p.lastSeen) <= s.heartbeatTimeout, nil
+}
+
+// UpdateRegister 更新设备寄存器（发布到设备register主题）
+func (s *MQTTDeviceService) UpdateRegister(deviceID string, params map[string]int) error {
+	if deviceID == "" {
+		return errors.New("device id is required")
+	}
+
+	payload, err := json.Marshal(contracts.DeviceRegisterCommand{
+		DeviceID:  deviceID,
+		Params:    params,
+		Timestamp: s.now().Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode register command: %w", err)
+	}
+
+	if err := s.client.Publish(s.deviceTopic(deviceID, deviceTopicRegister), payload, false); err != nil {
+		return fmt.Errorf("failed to publish register command: %w", err)
+	}
+	return nil
+}
+
+// DispatchMake 下发饮品制作指令（发布到设备make主题）
+func (s *MQTTDeviceService) DispatchMake(deviceID string, command contracts.DeviceMakeCommand) error {
+	if deviceID == "" {
+		return errors.New("device id is required")
+	}
+	if command.Timestamp == 0 {
+		command.Timestamp = s.now().Unix()
+	}
+
+	payload, err := json.Marshal(command)
+	if err != nil {
+		return fmt.Errorf("failed to encode make command: %w", err)
+	}
+
+	if err := s.client.Publish(s.deviceTopic(deviceID, deviceTopicMake), payload, false); err != nil {
+		return fmt.Errorf("failed to publish make command: %w", err)
+	}
+	return nil
+}
+
+// SubscribeMakeEvents 订阅设备制作事件
+func (s *MQTTDeviceService) SubscribeMakeEvents(handler DeviceMakeEventHandler) {
+	if handler == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, handler)
+}
+
+// GetDeviceStatus 获取设备状态详情
+func (s *MQTTDeviceService) GetDeviceStatus(deviceID string) (*contracts.DeviceStatusCheckResult, error) {
+	online, err := s.CheckDeviceOnline(deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &contracts.DeviceStatusCheckResult{
+		DeviceID: deviceID,
+		Online:   online,
+	}
+
+	s.mu.RLock()
+	if p, exists := s.presence[deviceID]; exists && !p.lastSeen.IsZero() {
+		result.LastSeen = p.lastSeen.Format(time.RFC3339)
+	}
+	s.mu.RUnlock()
+
+	return result, nil
+}
+
+// Close 断开MQTT连接
+func (s *MQTTDeviceService) Close() error {
+	return s.client.Close()
+}
+
+// handleHeartbeat 处理设备心跳，任何心跳都视为设备在线
+func (s *MQTTDeviceService) handleHeartbeat(msg mqtt.Message) {
+	deviceID := s.deviceIDFromTopic(msg.Topic)
+	if deviceID == "" {
+		return
+	}
+	s.markPresence(deviceID, true)
+}
+
+// handleStatus 处理设备上下线消息，payload 为 online/offline 或 {"status":"offline"}
+func (s *MQTTDeviceService) handleStatus(msg mqtt.Message) {
+	deviceID := s.deviceIDFromTopic(msg.Topic)
+	if deviceID == "" {
+		return
+	}
+
+	status := strings.TrimSpace(string(msg.Payload))
+	if strings.HasPrefix(status, "{") {
+		var body struct {
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal(msg.Payload, &body); err != nil {
+			return
+		}
+		status = body.Status
+	}
+
+	switch strings.ToLower(status) {
+	case deviceStatusOnline:
+		s.markPresence(deviceID, true)
+	case deviceStatusOffline:
+		s.markPresence(deviceID, false)
+	}
+}
+
+// handleMakeEvent 处理设备制作事件，设备ID以主题为准
+func (s *MQTTDeviceService) handleMakeEvent(msg mqtt.Message) {
+	deviceID := s.deviceIDFromTopic(msg.Topic)
+	if deviceID == "" {
+		return
+	}
+
+	var event contracts.DeviceMakeEvent
+	if err := json.Unmarshal(msg.Payload, &event); err != nil || event.OrderNo == "" {
+		return
+	}
+	event.DeviceID = deviceID
+
+	// 能上报事件的设备必然在线
+	s.markPresence(deviceID, true)
+
+	s.mu.RLock()
+	handlers := append([]DeviceMakeEventHandler(nil), s.handlers...)
+	s.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+func (s *MQTTDeviceService) markPresence(deviceID string, online bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, exists := s.presence[deviceID]
+	if !exists {
+		p = &devicePresence{}
+		s.presence[deviceID] = p
+	}
+	p.online = online
+	if online {
+		p.lastSeen = s.now()
+	}
+}
+
+func (s *MQTTDeviceService) topicFilter(suffix string) string {
+	return fmt.Sprintf("%s/+/%s", s.topicPrefix, suffix)
+}
+
+func (s *MQTTDeviceService) deviceTopic(deviceID, suffix string) string {
+	return fmt.Sprintf("%s/%s/%s", s.topicPrefix, deviceID, suffix)
+}
+
+// deviceIDFromTopic 从 {prefix}/{deviceID}/{suffix} 中解析设备ID
+func (s *MQTTDeviceService) deviceIDFromTopic(topic string) string {
+	rest := strings.TrimPrefix(topic, s.topicPrefix+"/")
+	if rest == topic {
+		return ""
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) != 2 {
+		return ""
+	}
+	return parts[0]
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ddteam/drink-master/internal/config"
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/pkg/mqtt"
+)
+
+// setupMQTTDeviceService 启动内嵌broker并创建设备服务与模拟设备客户端
+func setupMQTTDeviceService(t *testing.T) (*MQTTDeviceService, *mqtt.Client) {
+	t.Helper()
+
+	broker := mqtt.NewBroker()
+	require.NoError(t, broker.Listen("127.0.0.1:0"))
+	t.Cleanup(func() { broker.Close() })
+
+	service, err := NewMQTTDeviceService(&config.MQTTConfig{
+		Broker:           "tcp://" + broker.Addr(),
+		ClientID:         "server",
+		TopicPrefix:      "vm",
+		HeartbeatTimeout: time.Minute,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { service.Close() })
+
+	device, err := mqtt.Dial(mqtt.Options{Broker: "tcp://" + broker.Addr(), ClientID: "device-M001", ReconnectDelay: -1})
+	require.NoError(t, err)
+	t.Cleanup(func() { device.Close() })
+
+	return service, device
+}
+
+func waitForOnline(t *testing.T, service *MQTTDeviceService, deviceID string, expected bool) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		online, err := service.CheckDeviceOnline(deviceID)
+		return err == nil && online == expected
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestMQTTDeviceService_HeartbeatMarksOnline(t *testing.T) {
+	service, device := setupMQTTDeviceService(t)
+
+	online, err := service.CheckDeviceOnline("M001")
+	require.NoError(t, err)
+	assert.False(t, online, "未上报心跳的设备应为离线")
+
+	require.NoError(t, device.Publish("vm/M001/heartbeat", []byte(`{"ts":1}`), false))
+	waitForOnline(t, service, "M001", true)
+
+	status, err := service.GetDeviceStatus("M001")
+	require.NoError(t, err)
+	assert.True(t, status.Online)
+	assert.NotEmpty(t, status.LastSeen)
+}
+
+func TestMQTTDeviceService_StatusOffline(t *testing.T) {
+	service, device := setupMQTTDeviceService(t)
+
+	require.NoError(t, device.Publish("vm/M001/status", []byte("online"), true))
+	waitForOnline(t, service, "M001", true)
+
+	require.NoError(t, device.Publish("vm/M001/status", []byte(`{"status":"offline"}`), true))
+	waitForOnline(t, service, "M001", false)
+
+	status, err := service.GetDeviceStatus("M001")
+	require.NoError(t, err)
+	assert.False(t, status.Online)
+	assert.NotEmpty(t, status.LastSeen, "离线设备仍保留最后在线时间")
+}
+
+func TestMQTTDeviceService_HeartbeatTimeout(t *testing.T) {
+	service, device := setupMQTTDeviceService(t)
+
+	require.NoError(t, device.Publish("vm/M001/heartbeat", nil, false))
+	waitForOnline(t, service, "M001", true)
+
+	service.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+
+	online, err := service.CheckDeviceOnline("M001")
+	require.NoError(t, err)
+	assert.False(t, online, "心跳超时后设备应为离线")
+}
+
+func TestMQTTDeviceService_UpdateRegister(t *testing.T) {
+	service, device := setupMQTTDeviceService(t)
+
+	received := make(chan mqtt.Message, 1)
+	require.NoError(t, device.Subscribe("vm/M001/register", func(msg mqtt.Message) { received <- msg }))
+
+	require.NoError(t, service.UpdateRegister("M001", map[string]int{"temperature": 25}))
+
+	select {
+	case msg := <-received:
+		var command contracts.DeviceRegisterCommand
+		require.NoError(t, json.Unmarshal(msg.Payload, &command))
+		assert.Equal(t, "M001", command.DeviceID)
+		assert.Equal(t, 25, command.Params["temperature"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("设备未收到寄存器写入指令")
+	}
+
+	assert.Error(t, service.UpdateRegister("", nil))
+}
+
+func TestMQTTDeviceService_DeviceIDFromTopic(t *testing.T) {
+	service := &MQTTDeviceService{topicPrefix: "vm"}
+
+	assert.Equal(t, "M001", service.deviceIDFromTopic("vm/M001/heartbeat"))
+	assert.Equal(t, "", service.deviceIDFromTopic("other/M001/heartbeat"))
+	assert.Equal(t, "", service.deviceIDFromTopic("vm/M001/a/b"))
+}
+
+func TestNewMQTTDeviceService_NotConfigured(t *testing.T) {
+	_, err := NewMQTTDeviceService(&config.MQTTConfig{})
+	assert.Error(t, err)
+}
+
+func TestMachineService_GetMachineByID_MQTTPresence(t *testing.T) {
+	deviceService, device := setupMQTTDeviceService(t)
+	service, mockRepo, _, _ := createMachineService()
+	service.deviceService = deviceService
+
+	machine := &models.Machine{
+		ID:             "machine-123",
+		MachineNo:      stringPtr("M001"),
+		BusinessStatus: enums.BusinessStatusOpen,
+	}
+	mockRepo.On("GetByID", "machine-123").Return(machine, nil)
+
+	result, err := service.GetMachineByID("machine-123")
+	require.NoError(t, err)
+	assert.Equal(t, contracts.BusinessStatusOffline, result.BusinessStatus)
+
+	require.NoError(t, device.Publish("vm/M001/heartbeat", nil, false))
+	waitForOnline(t, deviceService, "M001", true)
+
+	result, err = service.GetMachineByID("machine-123")
+	require.NoError(t, err)
+	assert.Equal(t, contracts.BusinessStatusOpen, result.BusinessStatus)
+}
+
+func TestNewDeviceService_UsesDefault(t *testing.T) {
+	custom := &MQTTDeviceService{presence: make(map[string]*devicePresence)}
+	SetDefaultDeviceService(custom)
+	defer SetDefaultDeviceService(nil)
+
+	assert.Same(t, custom, NewDeviceService())
+}
+
+func TestMQTTDeviceService_DispatchMake(t *testing.T) {
+	service, device := setupMQTTDeviceService(t)
+
+	received := make(chan mqtt.Message, 1)
+	require.NoError(t, device.Subscribe("vm/M001/make", func(msg mqtt.Message) { received <- msg }))
+
+	require.NoError(t, service.DispatchMake("M001", contracts.DeviceMakeCommand{OrderNo: "ORD001", HasCup: true}))
+
+	select {
+	case msg := <-received:
+		var command contracts.DeviceMakeCommand
+		require.NoError(t, json.Unmarshal(msg.Payload, &command))
+		assert.Equal(t, "ORD001", command.OrderNo)
+		assert.True(t, command.HasCup)
+		assert.NotZero(t, command.Timestamp)
+	case <-time.After(2 * time.Second):
+		t.Fatal("设备未收到制作指令")
+	}
+
+	assert.Error(t, service.DispatchMake("", contracts.DeviceMakeCommand{}))
+}
+
+func TestMQTTDeviceService_MakeEvents(t *testing.T) {
+	service, device := setupMQTTDeviceService(t)
+
+	events := make(chan contracts.DeviceMakeEvent, 1)
+	service.SubscribeMakeEvents(func(event contracts.DeviceMakeEvent) { events <- event })
+
+	// 设备ID以主题为准，忽略payload中的设备ID
+	payload := []byte(`{"deviceId":"M999","orderNo":"ORD001","event":"progress","progress":50}`)
+	require.NoError(t, device.Publish("vm/M001/event", payload, false))
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "M001", event.DeviceID)
+		assert.Equal(t, "ORD001", event.OrderNo)
+		assert.Equal(t, contracts.DeviceMakeEventProgress, event.Event)
+		assert.Equal(t, 50, event.Progress)
+	case <-time.After(2 * time.Second):
+		t.Fatal("未收到设备制作事件")
+	}
+
+	waitForOnline(t, service, "M001", true)
+}
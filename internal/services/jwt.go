@@ -1,73 +1,84 @@
 package services
 
 import (
-	"fmt"
 	"os"
 	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 
 	"github.com/ddteam/drink-master/internal/middleware"
 	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/pkg/jwtkeys"
 )
 
 // JWTService handles JWT token operations
 type JWTService struct {
-	secret []byte
+	keys   *jwtkeys.Manager
+	err    error
 	expiry time.Duration
 }
 
-// NewJWTService creates a new JWT service
+// NewJWTService creates a new JWT service signing with the process-wide key manager
 func NewJWTService() *JWTService {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		// #nosec G101 - This is a fallback for development only
-		secret = "default_jwt_secret_change_this_in_production"
-	}
+	keys, err := middleware.JWTKeyManager()
 
-	// Get expiry hours from environment, default to 24 hours
-	expiryHours := 24
-	if expiryStr := os.Getenv("JWT_EXPIRES_HOURS"); expiryStr != "" {
-		if hours, err := strconv.Atoi(expiryStr); err == nil {
-			expiryHours = hours
+	// Access tokens are short-lived and renewed with refresh tokens, default to 30 minutes
+	expiryMinutes := 30
+	if expiryStr := os.Getenv("JWT_ACCESS_TOKEN_MINUTES"); expiryStr != "" {
+		if minutes, convErr := strconv.Atoi(expiryStr); convErr == nil && minutes > 0 {
+			expiryMinutes = minutes
 		}
 	}
 
 	return &JWTService{
-		secret: []byte(secret),
-		expiry: time.Duration(expiryHours) * time.Hour,
+		keys:   keys,
+		err:    err,
+		expiry: time.Duration(expiryMinutes) * time.Minute,
 	}
 }
 
-// GenerateToken generates a JWT token for a member
-func (j *JWTService) GenerateToken(member *models.Member) (string, error) {
+// Expiry returns the lifetime of issued access tokens
+func (j *JWTService) Expiry() time.Duration {
+	return j.expiry
+}
+
+// GenerateToken generates an access token for a member within a login session
+func (j *JWTService) GenerateToken(member *models.Member, sessionID string) (string, error) {
 	now := time.Now()
+	role := member.EffectiveRole()
 	claims := &middleware.JWTClaims{
-		MemberID: member.ID,
-		Role:     fmt.Sprintf("%d", member.Role), // Convert int to string
+		MemberID:    member.ID,
+		Role:        role.Code(),
+		Permissions: middleware.PermissionCodes(role),
+		SessionID:   sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(now.Add(j.expiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
-	// Set machine owner ID if the member is an owner
+	// Set machine owner ID if the member is an owner or maintainer
 	if member.MachineOwnerId != nil {
 		claims.MachineOwnerID = *member.MachineOwnerId
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secret)
+	if j.err != nil {
+		return "", j.err
+	}
+	return j.keys.Sign(claims)
 }
 
 // ValidateToken validates a JWT token and returns claims
 func (j *JWTService) ValidateToken(tokenString string) (*middleware.JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &middleware.JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return j.secret, nil
-	})
+	if j.err != nil {
+		return nil, j.err
+	}
 
+	token, err := j.keys.Parse(tokenString, &middleware.JWTClaims{})
 	if err != nil {
 		return nil, err
 	}
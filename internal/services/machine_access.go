@@ -0,0 +1,102 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+)
+
+// 售货机资源访问校验错误
+var (
+	// ErrMachineNotFound 售货机不存在
+	ErrMachineNotFound = errors.New("售货机不存在")
+	// ErrMaterialSiloNotFound 料仓不存在
+	ErrMaterialSiloNotFound = errors.New("物料槽不存在")
+	// ErrMachineAccessDenied 当前用户无权操作该售货机
+	ErrMachineAccessDenied = errors.New("无权操作该售货机")
+)
+
+// AccessScope 当前用户的授权范围，取自JWT
+type AccessScope struct {
+	MemberID       string
+	MachineOwnerID string
+	Role           enums.MemberRole
+}
+
+// MachineAccessGuardInterface 售货机资源归属校验接口
+// 料仓按 料仓 -> 售货机 -> 机主 解析归属，售货机及料仓的变更接口统一经此校验
+type MachineAccessGuardInterface interface {
+	AuthorizeMachine(scope AccessScope, machineID string) (*models.Machine, error)
+	AuthorizeSilo(scope AccessScope, siloID string) (*models.MaterialSilo, error)
+}
+
+// MachineAccessGuard 售货机资源归属校验实现
+type MachineAccessGuard struct {
+	machineRepo      repositories.MachineRepositoryInterface
+	materialSiloRepo repositories.MaterialSiloRepositoryInterface
+}
+
+// NewMachineAccessGuard 创建售货机资源归属校验
+func NewMachineAccessGuard(db *gorm.DB) MachineAccessGuardInterface {
+	return &MachineAccessGuard{
+		machineRepo:      repositories.NewMachineRepository(db),
+		materialSiloRepo: repositories.NewMaterialSiloRepository(db),
+	}
+}
+
+// AuthorizeMachine 校验当前用户可操作售货机，返回该售货机
+func (g *MachineAccessGuard) AuthorizeMachine(scope AccessScope, machineID string) (*models.Machine, error) {
+	machine, err := g.machineRepo.GetByID(machineID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get machine: %w", err)
+	}
+	if machine == nil {
+		return nil, ErrMachineNotFound
+	}
+
+	if !canAccessMachine(scope, machine) {
+		return nil, ErrMachineAccessDenied
+	}
+	return machine, nil
+}
+
+// AuthorizeSilo 校验当前用户可操作料仓所在的售货机，返回该料仓
+func (g *MachineAccessGuard) AuthorizeSilo(scope AccessScope, siloID string) (*models.MaterialSilo, error) {
+	silo, err := g.materialSiloRepo.GetByID(siloID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get material silo: %w", err)
+	}
+	if silo == nil {
+		return nil, ErrMaterialSiloNotFound
+	}
+	// 未绑定售货机的料仓只允许管理员操作
+	if silo.MachineId == nil {
+		if scope.Role == enums.MemberRoleAdmin {
+			return silo, nil
+		}
+		return nil, ErrMachineAccessDenied
+	}
+
+	if _, authErr := g.AuthorizeMachine(scope, *silo.MachineId); authErr != nil {
+		return nil, authErr
+	}
+	return silo, nil
+}
+
+// canAccessMachine 管理员可操作所有售货机，机主及运维人员只能操作所属机主名下的售货机
+func canAccessMachine(scope AccessScope, machine *models.Machine) bool {
+	switch scope.Role {
+	case enums.MemberRoleAdmin:
+		return true
+	case enums.MemberRoleOwner, enums.MemberRoleMaintainer:
+		return scope.MachineOwnerID != "" &&
+			machine.MachineOwnerId != nil && *machine.MachineOwnerId == scope.MachineOwnerID
+	default:
+		return false
+	}
+}
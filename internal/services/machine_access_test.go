@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+// setupMachineAccessGuard 写入 owner-1 名下的售货机及料仓，以及未绑定售货机的料仓
+func setupMachineAccessGuard(t *testing.T) MachineAccessGuardInterface {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Machine{}, &models.MaterialSilo{}))
+	require.NoError(t, db.Create(&models.Machine{ID: "machine-1", MachineOwnerId: stringPtr("owner-1")}).Error)
+	require.NoError(t, db.Create(&models.MaterialSilo{ID: "silo-1", MachineId: stringPtr("machine-1")}).Error)
+	require.NoError(t, db.Create(&models.MaterialSilo{ID: "silo-orphan"}).Error)
+
+	return NewMachineAccessGuard(db)
+}
+
+func TestMachineAccessGuard_AuthorizeSilo(t *testing.T) {
+	guard := setupMachineAccessGuard(t)
+
+	tests := []struct {
+		name     string
+		scope    AccessScope
+		siloID   string
+		expected error
+	}{
+		{"owner", AccessScope{MachineOwnerID: "owner-1", Role: enums.MemberRoleOwner}, "silo-1", nil},
+		{"maintainer", AccessScope{MachineOwnerID: "owner-1", Role: enums.MemberRoleMaintainer}, "silo-1", nil},
+		{"admin", AccessScope{Role: enums.MemberRoleAdmin}, "silo-1", nil},
+		{"other owner", AccessScope{MachineOwnerID: "owner-2", Role: enums.MemberRoleOwner}, "silo-1", ErrMachineAccessDenied},
+		{"other maintainer", AccessScope{MachineOwnerID: "owner-2", Role: enums.MemberRoleMaintainer}, "silo-1",
+			ErrMachineAccessDenied},
+		{"consumer", AccessScope{MachineOwnerID: "owner-1", Role: enums.MemberRoleConsumer}, "silo-1", ErrMachineAccessDenied},
+		{"owner without machine owner", AccessScope{Role: enums.MemberRoleOwner}, "silo-1", ErrMachineAccessDenied},
+		{"unbound silo", AccessScope{MachineOwnerID: "owner-1", Role: enums.MemberRoleOwner}, "silo-orphan",
+			ErrMachineAccessDenied},
+		{"unbound silo admin", AccessScope{Role: enums.MemberRoleAdmin}, "silo-orphan", nil},
+		{"missing silo", AccessScope{MachineOwnerID: "owner-1", Role: enums.MemberRoleOwner}, "silo-x", ErrMaterialSiloNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			silo, err := guard.AuthorizeSilo(tt.scope, tt.siloID)
+			assert.ErrorIs(t, err, tt.expected)
+			if tt.expected == nil {
+				require.NotNil(t, silo)
+				assert.Equal(t, tt.siloID, silo.ID)
+			}
+		})
+	}
+}
+
+func TestMachineAccessGuard_AuthorizeMachine(t *testing.T) {
+	guard := setupMachineAccessGuard(t)
+	owner := AccessScope{MachineOwnerID: "owner-1", Role: enums.MemberRoleOwner}
+
+	machine, err := guard.AuthorizeMachine(owner, "machine-1")
+	require.NoError(t, err)
+	assert.Equal(t, "machine-1", machine.ID)
+
+	_, err = guard.AuthorizeMachine(AccessScope{MachineOwnerID: "owner-2", Role: enums.MemberRoleOwner}, "machine-1")
+	assert.ErrorIs(t, err, ErrMachineAccessDenied)
+
+	_, err = guard.AuthorizeMachine(owner, "machine-x")
+	assert.ErrorIs(t, err, ErrMachineNotFound)
+}
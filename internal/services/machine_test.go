@@ -33,6 +33,11 @@ func (m *MockMachineRepository) GetByID(id string) (*models.Machine, error) {
 	return args.Get(0).(*models.Machine), args.Error(1)
 }
 
+func (m *MockMachineRepository) GetByIDs(ids []string) ([]*models.Machine, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]*models.Machine), args.Error(1)
+}
+
 func (m *MockMachineRepository) GetByDeviceID(deviceID string) (*models.Machine, error) {
 	args := m.Called(deviceID)
 	if args.Get(0) == nil {
@@ -73,11 +78,24 @@ func (m *MockProductRepository) GetByID(id string) (*models.Product, error) {
 	return args.Get(0).(*models.Product), args.Error(1)
 }
 
+func (m *MockProductRepository) GetByIDs(ids []string) ([]*models.Product, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]*models.Product), args.Error(1)
+}
+
 func (m *MockProductRepository) GetMachineProducts(machineID string) ([]*models.MachineProductPrice, error) {
 	args := m.Called(machineID)
 	return args.Get(0).([]*models.MachineProductPrice), args.Error(1)
 }
 
+func (m *MockProductRepository) GetMachineProductPrice(machineID, productID string) (*models.MachineProductPrice, error) {
+	args := m.Called(machineID, productID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MachineProductPrice), args.Error(1)
+}
+
 type MockDeviceService struct {
 	mock.Mock
 }
@@ -100,6 +118,15 @@ func (m *MockDeviceService) GetDeviceStatus(deviceID string) (*contracts.DeviceS
 	return args.Get(0).(*contracts.DeviceStatusCheckResult), args.Error(1)
 }
 
+func (m *MockDeviceService) DispatchMake(deviceID string, command contracts.DeviceMakeCommand) error {
+	args := m.Called(deviceID, command)
+	return args.Error(0)
+}
+
+func (m *MockDeviceService) SubscribeMakeEvents(handler DeviceMakeEventHandler) {
+	m.Called(handler)
+}
+
 func createMachineService() (*MachineService, *MockMachineRepository, *MockProductRepository, *MockDeviceService) {
 	mockMachineRepo := new(MockMachineRepository)
 	mockProductRepo := new(MockProductRepository)
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+)
+
+// ErrInvalidMakeTransition 制作状态不允许按该方向变更
+var ErrInvalidMakeTransition = errors.New("invalid make status transition")
+
+// ErrMakeStatusConflict 制作状态已被并发修改
+var ErrMakeStatusConflict = errors.New("make status changed concurrently")
+
+// ErrOrderNotWaitMake 订单未支付或已不在待制作状态，无需下发制作指令
+var ErrOrderNotWaitMake = errors.New("order is not waiting to be made")
+
+// 默认制作超时时间与每轮超时扫描的订单数量
+const (
+	defaultMakeTimeout   = 5 * time.Minute
+	makeTimeoutBatchSize = 100
+)
+
+// makeStatusEvents 制作状态变更对应的订单领域事件
+var makeStatusEvents = map[enums.MakeStatus]string{
+	enums.MakeStatusMaking:   contracts.EventOrderMaking,
+	enums.MakeStatusMade:     contracts.EventOrderMade,
+	enums.MakeStatusMakeFail: contracts.EventOrderMakeFailed,
+}
+
+// MakeServiceInterface 饮品制作服务接口
+type MakeServiceInterface interface {
+	Dispatch(orderID string) error
+	HandleMakeEvent(event contracts.DeviceMakeEvent) error
+	FailTimedOutOrders() (int, error)
+	TracksMakeResult() bool
+	Start(interval time.Duration)
+	Stop()
+}
+
+// makeService 饮品制作服务实现
+// 负责向设备下发制作指令，并根据设备事件与超时推进订单制作状态
+type makeService struct {
+	orderRepo   repositories.OrderRepository
+	machineRepo repositories.MachineRepositoryInterface
+	deviceSvc   DeviceServiceInterface
+	timeout     time.Duration
+	now         func() time.Time
+	logger      *logrus.Logger
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+// NewMakeService 创建饮品制作服务
+// 制作超时时间由 MAKE_TIMEOUT_SECONDS 配置，默认5分钟（从支付时间起算）
+func NewMakeService(db *gorm.DB, deviceSvc DeviceServiceInterface) MakeServiceInterface {
+	timeout := defaultMakeTimeout
+	if seconds, err := strconv.Atoi(getEnvOrDefault("MAKE_TIMEOUT_SECONDS", "")); err == nil && seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	return &makeService{
+		orderRepo:   repositories.NewOrderRepository(db),
+		machineRepo: repositories.NewMachineRepository(db),
+		deviceSvc:   deviceSvc,
+		timeout:     timeout,
+		now:         time.Now,
+		logger:      logrus.StandardLogger(),
+		stop:        make(chan struct{}),
+	}
+}
+
+// Dispatch 向订单所属机器下发制作指令
+// 下发失败时订单保持待制作，由超时扫描标记为制作失败
+func (s *makeService) Dispatch(orderID string) error {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+	if order == nil {
+		return errors.New("order not found")
+	}
+
+	if order.PaymentStatus != int(enums.PaymentStatusPaid) ||
+		enums.MakeStatus(order.MakeStatus) != enums.MakeStatusWaitMake {
+		return ErrOrderNotWaitMake
+	}
+
+	deviceID, err := s.orderDeviceID(order)
+	if err != nil {
+		return err
+	}
+
+	online, err := s.deviceSvc.CheckDeviceOnline(deviceID)
+	if err != nil {
+		return fmt.Errorf("failed to check device status: %w", err)
+	}
+	if !online {
+		return errors.New("device is offline")
+	}
+
+	command := contracts.DeviceMakeCommand{
+		OrderNo:   ptrToString(order.OrderNo),
+		ProductID: ptrToString(order.ProductId),
+		HasCup:    order.HasCup.Bool(),
+		Timestamp: s.now().Unix(),
+	}
+	if err := s.deviceSvc.DispatchMake(deviceID, command); err != nil {
+		return fmt.Errorf("failed to dispatch make command: %w", err)
+	}
+
+	return nil
+}
+
+// HandleMakeEvent 处理设备上报的制作事件
+func (s *makeService) HandleMakeEvent(event contracts.DeviceMakeEvent) error {
+	var target enums.MakeStatus
+	switch event.Event {
+	case contracts.DeviceMakeEventAck, contracts.DeviceMakeEventProgress:
+		target = enums.MakeStatusMaking
+	case contracts.DeviceMakeEventDone:
+		target = enums.MakeStatusMade
+	case contracts.DeviceMakeEventFail:
+		target = enums.MakeStatusMakeFail
+	default:
+		return fmt.Errorf("unknown make event: %s", event.Event)
+	}
+
+	order, err := s.orderRepo.GetByOrderNo(event.OrderNo)
+	if err != nil {
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+	if order == nil {
+		return errors.New("order not found")
+	}
+
+	// 只接受订单所属机器上报的事件
+	deviceID, err := s.orderDeviceID(order)
+	if err != nil {
+		return err
+	}
+	if deviceID != event.DeviceID {
+		return errors.New("device does not match order machine")
+	}
+
+	return s.transition(order, target)
+}
+
+// FailTimedOutOrders 将超时未完成制作的已支付订单标记为制作失败，返回处理的订单数
+func (s *makeService) FailTimedOutOrders() (int, error) {
+	orders, err := s.orderRepo.GetMakeTimedOut(s.now().Add(-s.timeout), makeTimeoutBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get timed out orders: %w", err)
+	}
+
+	failed := 0
+	for i := range orders {
+		if err := s.transition(&orders[i], enums.MakeStatusMakeFail); err != nil {
+			s.logger.WithError(err).WithField("order_id", orders[i].ID).Warn("制作超时订单状态更新失败")
+			continue
+		}
+		failed++
+	}
+	return failed, nil
+}
+
+// TracksMakeResult 是否能收到设备上报的制作结果，未接入设备通信时订单停留在待制作
+func (s *makeService) TracksMakeResult() bool {
+	return reportsMakeEvents(s.deviceSvc)
+}
+
+// Start 订阅设备制作事件并定期扫描制作超时订单
+func (s *makeService) Start(interval time.Duration) {
+	s.deviceSvc.SubscribeMakeEvents(func(event contracts.DeviceMakeEvent) {
+		if err := s.HandleMakeEvent(event); err != nil {
+			s.logger.WithError(err).WithFields(logrus.Fields{
+				"device_id": event.DeviceID,
+				"order_no":  event.OrderNo,
+				"event":     event.Event,
+			}).Warn("设备制作事件处理失败")
+		}
+	})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if count, err := s.FailTimedOutOrders(); err != nil {
+					s.logger.WithError(err).Error("制作超时扫描失败")
+				} else if count > 0 {
+					s.logger.WithField("count", count).Info("已将制作超时订单标记为制作失败")
+				}
+			}
+		}
+	}()
+}
+
+// Stop 停止超时扫描
+func (s *makeService) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// transition 按状态机推进制作状态，重复事件视为成功
+func (s *makeService) transition(order *models.Order, to enums.MakeStatus) error {
+	from := enums.MakeStatus(order.MakeStatus)
+	if from == to {
+		return nil
+	}
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidMakeTransition, from, to)
+	}
+
+	var events []*models.OutboxEvent
+	if eventType, ok := makeStatusEvents[to]; ok {
+		events = append(events, newOrderEvent(eventType, order, s.now()))
+	}
+	updated, err := s.orderRepo.UpdateMakeStatus(order.ID, from, to, events...)
+	if err != nil {
+		return fmt.Errorf("failed to update make status: %w", err)
+	}
+	if !updated {
+		return ErrMakeStatusConflict
+	}
+
+	order.MakeStatus = int(to)
+	return nil
+}
+
+// orderDeviceID 获取订单所属机器的设备编号
+func (s *makeService) orderDeviceID(order *models.Order) (string, error) {
+	if order.MachineId == nil || *order.MachineId == "" {
+		return "", errors.New("order has no machine")
+	}
+
+	machine, err := s.machineRepo.GetByID(*order.MachineId)
+	if err != nil {
+		return "", fmt.Errorf("failed to get machine: %w", err)
+	}
+	if machine == nil {
+		return "", errors.New("machine not found")
+	}
+	if machine.MachineNo == nil || *machine.MachineNo == "" {
+		return "", errors.New("machine has no device number")
+	}
+	return *machine.MachineNo, nil
+}
+
+// MakeDispatchSubscriber 订单支付成功后下发制作指令的领域事件订阅者
+// 下发失败时事件稍后重新投递，订单已开始制作或已被制作超时扫描标记为失败后不再下发
+func MakeDispatchSubscriber(makeSvc MakeServiceInterface) OrderEventHandler {
+	return func(event contracts.OrderEvent) error {
+		if event.Type != contracts.EventOrderPaid {
+			return nil
+		}
+		if err := makeSvc.Dispatch(event.OrderID); err != nil && !errors.Is(err, ErrOrderNotWaitMake) {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+)
+
+// setupMakeService 创建使用内存数据库与模拟设备服务的制作服务
+func setupMakeService(t *testing.T) (*makeService, *gorm.DB, *MockDeviceService) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Order{}, &models.Machine{}, &models.OutboxEvent{}))
+
+	require.NoError(t, db.Create(&models.Machine{
+		ID:             "machine-1",
+		MachineNo:      stringPtr("M001"),
+		BusinessStatus: enums.BusinessStatusOpen,
+	}).Error)
+
+	deviceSvc := new(MockDeviceService)
+	service := &makeService{
+		orderRepo:   repositories.NewOrderRepository(db),
+		machineRepo: repositories.NewMachineRepository(db),
+		deviceSvc:   deviceSvc,
+		timeout:     5 * time.Minute,
+		now:         time.Now,
+		logger:      logrus.New(),
+		stop:        make(chan struct{}),
+	}
+	return service, db, deviceSvc
+}
+
+func createMakeOrder(t *testing.T, db *gorm.DB, id string, paymentStatus enums.PaymentStatus, paidAt *time.Time) {
+	t.Helper()
+	require.NoError(t, db.Create(&models.Order{
+		ID:            id,
+		MachineId:     stringPtr("machine-1"),
+		ProductId:     stringPtr("product-1"),
+		OrderNo:       stringPtr("NO-" + id),
+		HasCup:        models.BitBool(1),
+		PaymentStatus: int(paymentStatus),
+		PaymentTime:   paidAt,
+		MakeStatus:    int(enums.MakeStatusWaitMake),
+	}).Error)
+}
+
+func getMakeStatus(t *testing.T, db *gorm.DB, id string) enums.MakeStatus {
+	t.Helper()
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", id).Error)
+	return enums.MakeStatus(order.MakeStatus)
+}
+
+func TestMakeService_Dispatch(t *testing.T) {
+	service, db, deviceSvc := setupMakeService(t)
+	now := time.Now()
+	createMakeOrder(t, db, "order-1", enums.PaymentStatusPaid, &now)
+
+	deviceSvc.On("CheckDeviceOnline", "M001").Return(true, nil)
+	deviceSvc.On("DispatchMake", "M001", mock.MatchedBy(func(cmd contracts.DeviceMakeCommand) bool {
+		return cmd.OrderNo == "NO-order-1" && cmd.ProductID == "product-1" && cmd.HasCup
+	})).Return(nil)
+
+	require.NoError(t, service.Dispatch("order-1"))
+	deviceSvc.AssertExpectations(t)
+	assert.Equal(t, enums.MakeStatusWaitMake, getMakeStatus(t, db, "order-1"))
+}
+
+func TestMakeService_Dispatch_Rejected(t *testing.T) {
+	service, db, deviceSvc := setupMakeService(t)
+	now := time.Now()
+	createMakeOrder(t, db, "unpaid", enums.PaymentStatusWaitPay, nil)
+	createMakeOrder(t, db, "paid", enums.PaymentStatusPaid, &now)
+
+	err := service.Dispatch("unpaid")
+	assert.ErrorIs(t, err, ErrOrderNotWaitMake)
+
+	deviceSvc.On("CheckDeviceOnline", "M001").Return(false, nil)
+	err = service.Dispatch("paid")
+	assert.EqualError(t, err, "device is offline")
+
+	deviceSvc.AssertNotCalled(t, "DispatchMake", mock.Anything, mock.Anything)
+}
+
+func TestMakeService_HandleMakeEvent(t *testing.T) {
+	service, db, _ := setupMakeService(t)
+	now := time.Now()
+	createMakeOrder(t, db, "order-1", enums.PaymentStatusPaid, &now)
+
+	event := func(name string) contracts.DeviceMakeEvent {
+		return contracts.DeviceMakeEvent{DeviceID: "M001", OrderNo: "NO-order-1", Event: name}
+	}
+
+	require.NoError(t, service.HandleMakeEvent(event(contracts.DeviceMakeEventAck)))
+	assert.Equal(t, enums.MakeStatusMaking, getMakeStatus(t, db, "order-1"))
+
+	// 重复的进度事件不会再次触发状态变更
+	require.NoError(t, service.HandleMakeEvent(event(contracts.DeviceMakeEventProgress)))
+
+	require.NoError(t, service.HandleMakeEvent(event(contracts.DeviceMakeEventDone)))
+	assert.Equal(t, enums.MakeStatusMade, getMakeStatus(t, db, "order-1"))
+
+	// 终态不可再变更
+	err := service.HandleMakeEvent(event(contracts.DeviceMakeEventFail))
+	assert.True(t, errors.Is(err, ErrInvalidMakeTransition))
+	assert.Equal(t, enums.MakeStatusMade, getMakeStatus(t, db, "order-1"))
+
+	assert.ElementsMatch(t, []string{contracts.EventOrderMaking, contracts.EventOrderMade}, outboxEventTypes(t, db))
+
+	assert.Error(t, service.HandleMakeEvent(event("unknown")))
+}
+
+func TestMakeService_HandleMakeEvent_DeviceMismatch(t *testing.T) {
+	service, db, _ := setupMakeService(t)
+	now := time.Now()
+	createMakeOrder(t, db, "order-1", enums.PaymentStatusPaid, &now)
+
+	err := service.HandleMakeEvent(contracts.DeviceMakeEvent{
+		DeviceID: "M999",
+		OrderNo:  "NO-order-1",
+		Event:    contracts.DeviceMakeEventDone,
+	})
+	assert.EqualError(t, err, "device does not match order machine")
+	assert.Equal(t, enums.MakeStatusWaitMake, getMakeStatus(t, db, "order-1"))
+}
+
+func TestMakeService_FailTimedOutOrders(t *testing.T) {
+	service, db, _ := setupMakeService(t)
+	old := time.Now().Add(-10 * time.Minute)
+	recent := time.Now()
+	createMakeOrder(t, db, "stale", enums.PaymentStatusPaid, &old)
+	createMakeOrder(t, db, "fresh", enums.PaymentStatusPaid, &recent)
+
+	count, err := service.FailTimedOutOrders()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []string{contracts.EventOrderMakeFailed}, outboxEventTypes(t, db))
+	assert.Equal(t, enums.MakeStatusMakeFail, getMakeStatus(t, db, "stale"))
+	assert.Equal(t, enums.MakeStatusWaitMake, getMakeStatus(t, db, "fresh"))
+}
+
+func TestMakeDispatchSubscriber(t *testing.T) {
+	service, db, deviceSvc := setupMakeService(t)
+	now := time.Now()
+	createMakeOrder(t, db, "order-1", enums.PaymentStatusPaid, &now)
+	createMakeOrder(t, db, "made", enums.PaymentStatusPaid, &now)
+	require.NoError(t, db.Model(&models.Order{}).Where("Id = ?", "made").
+		Update("MakeStatus", int(enums.MakeStatusMade)).Error)
+	subscriber := MakeDispatchSubscriber(service)
+	paid := func(orderID string) contracts.OrderEvent {
+		return contracts.OrderEvent{Type: contracts.EventOrderPaid, OrderID: orderID}
+	}
+
+	// 设备离线时下发失败，事件稍后重新投递
+	deviceSvc.On("CheckDeviceOnline", "M001").Return(false, nil).Once()
+	assert.EqualError(t, subscriber(paid("order-1")), "device is offline")
+
+	deviceSvc.On("CheckDeviceOnline", "M001").Return(true, nil)
+	deviceSvc.On("DispatchMake", "M001", mock.AnythingOfType("contracts.DeviceMakeCommand")).Return(nil).Once()
+	require.NoError(t, subscriber(paid("order-1")))
+
+	// 已不在待制作状态的订单不再下发
+	require.NoError(t, subscriber(paid("made")))
+	require.NoError(t, subscriber(contracts.OrderEvent{Type: contracts.EventOrderMade, OrderID: "order-1"}))
+	deviceSvc.AssertNumberOfCalls(t, "DispatchMake", 1)
+}
+
+func TestMakeService_TracksMakeResult(t *testing.T) {
+	service, _, _ := setupMakeService(t)
+	assert.True(t, service.TracksMakeResult())
+
+	service.deviceSvc = &DeviceService{}
+	assert.False(t, service.TracksMakeResult())
+}
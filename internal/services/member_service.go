@@ -10,6 +10,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
 	"github.com/ddteam/drink-master/internal/models"
 	"github.com/ddteam/drink-master/internal/repositories"
 )
@@ -232,7 +233,7 @@ func (s *MemberService) GetMemberInfo(memberID string) (*contracts.GetMemberInfo
 		Nickname:            nickname,
 		Avatar:              avatar,
 		WeChatOpenID:        wechatOpenId,
-		Role:                fmt.Sprintf("%d", member.Role), // Convert int to string
+		Role:                member.EffectiveRole().Code(),
 		IsAdmin:             member.IsAdmin.Bool(),
 		CreatedAt:           member.CreatedOn,
 		UpdatedAt:           member.CreatedOn, // Use CreatedOn since UpdatedOn might be nil
@@ -331,7 +332,7 @@ func (s *MemberService) FindOrCreateByOpenID(openID, nickname, avatarUrl string)
 			Nickname:     &nickname,
 			Avatar:       &avatarUrl,
 			WeChatOpenId: &openID,
-			Role:         1, // Default role as int (1 for Member)
+			Role:         int(enums.MemberRoleConsumer),
 			Version:      0,
 			CreatedOn:    time.Now(),
 		}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+)
+
+// ErrOrderNotFound 订单不存在或不属于当前会员
+var ErrOrderNotFound = errors.New("订单不存在")
+
+// 每次拉取的订单事件数量上限
+const orderEventBatchSize = 100
+
+// OrderEventServiceInterface 订单事件查询服务接口
+// 订单状态推送从发件箱读取支付与制作状态变更事件，多实例部署时任一实例都能推送
+type OrderEventServiceInterface interface {
+	CheckOrderAccess(memberID, orderID string) error
+	GetOrderEvents(orderID string, afterSeq int64) ([]contracts.OrderEvent, error)
+	Watch(orderID string) (<-chan struct{}, func())
+	Start(interval time.Duration)
+	Stop()
+}
+
+// orderEventService 订单事件查询服务实现
+// 所有推送连接共用一个轮询任务：按序号读取发件箱新写入的事件，通知订阅了对应订单的连接再查询该订单的事件
+// 没有连接订阅时不查询数据库
+type orderEventService struct {
+	orderRepo  repositories.OrderRepository
+	outboxRepo repositories.OutboxRepository
+	logger     *logrus.Logger
+
+	mu       sync.Mutex
+	watchers map[string]map[chan struct{}]struct{}
+	tracking bool // 是否已确定读取位置
+	lastSeq  int64
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewOrderEventService 创建订单事件查询服务
+func NewOrderEventService(db *gorm.DB) OrderEventServiceInterface {
+	return &orderEventService{
+		orderRepo:  repositories.NewOrderRepository(db),
+		outboxRepo: repositories.NewOutboxRepository(db),
+		logger:     logrus.StandardLogger(),
+		watchers:   make(map[string]map[chan struct{}]struct{}),
+		stop:       make(chan struct{}),
+	}
+}
+
+// CheckOrderAccess 检查订单属于该会员
+func (s *orderEventService) CheckOrderAccess(memberID, orderID string) error {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOrderNotFound
+		}
+		return fmt.Errorf("获取订单失败: %w", err)
+	}
+	if ptrToString(order.MemberId) != memberID {
+		return ErrOrderNotFound
+	}
+	return nil
+}
+
+// GetOrderEvents 按写入顺序获取订单序号在afterSeq之后的事件，afterSeq为0时从第一条事件开始
+func (s *orderEventService) GetOrderEvents(orderID string, afterSeq int64) ([]contracts.OrderEvent, error) {
+	records, err := s.outboxRepo.ListByAggregate(orderID, afterSeq, orderEventBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("获取订单事件失败: %w", err)
+	}
+
+	events := make([]contracts.OrderEvent, 0, len(records))
+	for _, record := range records {
+		var event contracts.OrderEvent
+		if unmarshalErr := json.Unmarshal([]byte(record.Payload), &event); unmarshalErr != nil {
+			// 无法解析的事件由投递任务标记为失败，推送时跳过
+			s.logger.WithError(unmarshalErr).WithField("event_id", record.ID).Warn("订单事件解析失败")
+			continue
+		}
+		event.Seq = record.Seq
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// Watch 订阅订单的新事件通知，返回通知通道与取消订阅函数
+// 通知只表示订单可能有新事件，收到后调用 GetOrderEvents 获取；未及时处理的通知会合并
+func (s *orderEventService) Watch(orderID string) (<-chan struct{}, func()) {
+	notify := make(chan struct{}, 1)
+
+	s.mu.Lock()
+	if s.watchers[orderID] == nil {
+		s.watchers[orderID] = make(map[chan struct{}]struct{})
+	}
+	s.watchers[orderID][notify] = struct{}{}
+	s.mu.Unlock()
+
+	var once sync.Once
+	return notify, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			delete(s.watchers[orderID], notify)
+			if len(s.watchers[orderID]) == 0 {
+				delete(s.watchers, orderID)
+			}
+		})
+	}
+}
+
+// Start 定期读取发件箱新写入的事件并通知订阅的连接
+func (s *orderEventService) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if err := s.poll(); err != nil {
+					s.logger.WithError(err).Warn("订单事件轮询失败")
+				}
+			}
+		}
+	}()
+}
+
+// Stop 停止轮询
+func (s *orderEventService) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// poll 读取上次轮询之后写入的事件并通知订阅了对应订单的连接
+// 没有订阅时不读取，下次有订阅时从最新事件之后开始，并通知所有订阅的连接补查一次
+func (s *orderEventService) poll() error {
+	s.mu.Lock()
+	if len(s.watchers) == 0 {
+		s.tracking = false
+		s.mu.Unlock()
+		return nil
+	}
+	tracking, lastSeq := s.tracking, s.lastSeq
+	s.mu.Unlock()
+
+	if !tracking {
+		latest, err := s.outboxRepo.LatestSeq()
+		if err != nil {
+			return fmt.Errorf("获取最新事件序号失败: %w", err)
+		}
+		s.startTracking(latest)
+		return nil
+	}
+
+	for {
+		records, err := s.outboxRepo.ListAfter(lastSeq, orderEventBatchSize)
+		if err != nil {
+			return fmt.Errorf("获取新写入的事件失败: %w", err)
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		lastSeq = records[len(records)-1].Seq
+		s.notify(records, lastSeq)
+		if len(records) < orderEventBatchSize {
+			return nil
+		}
+	}
+}
+
+// notify 通知订阅了事件所属订单的连接，并记录读取位置
+func (s *orderEventService) notify(records []models.OutboxEvent, lastSeq int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSeq = lastSeq
+	for _, record := range records {
+		for notify := range s.watchers[record.AggregateId] {
+			wake(notify)
+		}
+	}
+}
+
+// startTracking 从latestSeq之后开始读取，连接查询历史事件与开始读取之间写入的事件由补查推送
+func (s *orderEventService) startTracking(latestSeq int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tracking = true
+	s.lastSeq = latestSeq
+	for _, watchers := range s.watchers {
+		for notify := range watchers {
+			wake(notify)
+		}
+	}
+}
+
+// wake 发送通知，连接尚未处理上一次通知时合并
+func wake(notify chan struct{}) {
+	select {
+	case notify <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+)
+
+// 默认未支付订单超时时间、每轮扫描的订单数量与扫描任务租约
+const (
+	defaultPayTimeout    = 15 * time.Minute
+	payTimeoutBatchSize  = 100
+	orderExpiryLeaseName = "order_expiry"
+	orderExpiryLeaseTTL  = 5 * time.Minute
+)
+
+// OrderExpiryServiceInterface 未支付订单超时处理服务接口
+type OrderExpiryServiceInterface interface {
+	ExpireUnpaidOrders() (int, error)
+	Start(interval time.Duration)
+	Stop()
+}
+
+// orderExpiryService 未支付订单超时处理服务实现
+// 超时订单先向渠道查询支付结果，已支付的补记支付，未支付的关闭渠道交易后作废订单并释放预占库存
+// 多实例部署时通过任务租约保证同一时刻只有一个实例扫描
+type orderExpiryService struct {
+	orderRepo  repositories.OrderRepository
+	leaseRepo  repositories.JobLeaseRepository
+	paymentSvc PaymentServiceInterface
+	holder     string
+	timeout    time.Duration
+	batchSize  int
+	cursor     *models.Order
+	now        func() time.Time
+	logger     *logrus.Logger
+	stop       chan struct{}
+	stopOnce   sync.Once
+}
+
+// NewOrderExpiryService 创建未支付订单超时处理服务
+// 超时时间由 ORDER_PAY_TIMEOUT_SECONDS 配置，默认15分钟（从下单时间起算）
+func NewOrderExpiryService(db *gorm.DB, paymentSvc PaymentServiceInterface) OrderExpiryServiceInterface {
+	timeout := defaultPayTimeout
+	if seconds, err := strconv.Atoi(getEnvOrDefault("ORDER_PAY_TIMEOUT_SECONDS", "")); err == nil && seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	return &orderExpiryService{
+		orderRepo:  repositories.NewOrderRepository(db),
+		leaseRepo:  repositories.NewJobLeaseRepository(db),
+		paymentSvc: paymentSvc,
+		holder:     leaseHolderID(),
+		timeout:    timeout,
+		batchSize:  payTimeoutBatchSize,
+		now:        time.Now,
+		logger:     logrus.StandardLogger(),
+		stop:       make(chan struct{}),
+	}
+}
+
+// ExpireUnpaidOrders 处理超时未支付订单，返回已处理（补记支付或作废）的订单数量
+// 租约由其他实例持有时不做处理；单个订单处理失败时保留待支付，
+// 下一轮从其后的订单继续扫描，扫描到末尾后再从头重试，避免处理失败的订单挤占新超时的订单
+func (s *orderExpiryService) ExpireUnpaidOrders() (int, error) {
+	now := s.now()
+	acquired, err := s.holdLease()
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire order expiry lease: %w", err)
+	}
+	if !acquired {
+		return 0, nil
+	}
+
+	orders, err := s.orderRepo.GetPayTimedOut(now.Add(-s.timeout), s.cursor, s.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pay timed out orders: %w", err)
+	}
+
+	handled := 0
+	for i := range orders {
+		// 渠道调用可能较慢，逐单续期租约，租约被接管后停止本轮处理
+		if i > 0 {
+			if held, holdErr := s.holdLease(); holdErr != nil || !held {
+				return handled, nil
+			}
+		}
+		s.cursor = &orders[i]
+		err := s.expire(&orders[i])
+		if errors.Is(err, ErrOrderNotWaitPay) {
+			// 扫描后订单已支付或已发起预下单，下一轮按最新状态处理
+			continue
+		}
+		if err != nil {
+			s.logger.WithError(err).WithField("order_id", orders[i].ID).Warn("超时未支付订单处理失败")
+			continue
+		}
+		handled++
+	}
+	if len(orders) < s.batchSize {
+		// 已扫描到末尾，下一轮从头重试处理失败的订单
+		s.cursor = nil
+	}
+	return handled, nil
+}
+
+// Start 定期处理超时未支付订单
+func (s *orderExpiryService) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if count, err := s.ExpireUnpaidOrders(); err != nil {
+					s.logger.WithError(err).Error("超时未支付订单扫描失败")
+				} else if count > 0 {
+					s.logger.WithField("count", count).Info("已处理超时未支付订单")
+				}
+			}
+		}
+	}()
+}
+
+// Stop 停止扫描并释放任务租约
+func (s *orderExpiryService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		if err := s.leaseRepo.Release(orderExpiryLeaseName, s.holder); err != nil {
+			s.logger.WithError(err).Warn("释放订单超时任务租约失败")
+		}
+	})
+}
+
+// holdLease 获取或续期扫描任务租约
+func (s *orderExpiryService) holdLease() (bool, error) {
+	return s.leaseRepo.Acquire(orderExpiryLeaseName, s.holder, s.now(), orderExpiryLeaseTTL)
+}
+
+// expire 处理单个超时订单
+// 未绑定渠道的订单未发起过支付，仅在仍未绑定时作废，避免作废正在预下单的订单
+// 渠道关单成功后才作废订单，避免作废后仍被支付
+func (s *orderExpiryService) expire(order *models.Order) error {
+	if order.ChannelCode == nil || *order.ChannelCode == "" {
+		return s.paymentSvc.InvalidOrder(contracts.InvalidOrderRequest{ID: order.ID, Unbound: true})
+	}
+	if order.MachineId == nil {
+		return errors.New("order has no machine")
+	}
+
+	account, err := s.paymentSvc.GetPaymentAccount(*order.MachineId)
+	if err != nil {
+		return fmt.Errorf("failed to get payment account: %w", err)
+	}
+	channelCode, err := ResolveChannelCode(*order.ChannelCode, account)
+	if err != nil {
+		return err
+	}
+	orderNo := ptrToString(order.OrderNo)
+
+	result, err := s.paymentSvc.TranQuery(contracts.TranQueryRequest{
+		Ext1:          account.ReceivingAccount,
+		Ext2:          account.ReceivingKey,
+		Ext3:          account.ReceivingOrderPrefix,
+		Ext4:          account.MerchantSerialNo,
+		Ext5:          account.MerchantPrivateKey,
+		ChannelCode:   channelCode,
+		OrderNo:       orderNo,
+		ModeOfPayment: contracts.ModeOfPaymentWeChat,
+	})
+	if err == nil && result.IsSuccess && result.PaymentStatus == contracts.PaymentStatusSuccess {
+		return s.paymentSvc.PayOrder(contracts.PayOrderRequest{
+			ID:             order.ID,
+			ChannelOrderNo: result.TransactionId,
+			PaidAt:         result.PaymentTime,
+		})
+	}
+
+	// 查询失败或未支付时都关闭渠道交易，已支付的订单关单失败，下一轮查询时补记支付
+	closed, err := s.paymentSvc.Close(contracts.PaymentCloseRequest{
+		Ext1:        account.ReceivingAccount,
+		Ext2:        account.ReceivingKey,
+		Ext3:        account.ReceivingOrderPrefix,
+		Ext4:        account.MerchantSerialNo,
+		Ext5:        account.MerchantPrivateKey,
+		ChannelCode: channelCode,
+		OrderNo:     orderNo,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to close channel order: %w", err)
+	}
+	if !closed.IsSuccess {
+		return fmt.Errorf("channel close rejected: %s", closed.Message)
+	}
+	return s.paymentSvc.InvalidOrder(contracts.InvalidOrderRequest{ID: order.ID})
+}
+
+// leaseHolderID 生成任务租约持有者标识，主机名便于排查，随机后缀区分同一主机上的多个进程
+func leaseHolderID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "unknown"
+	}
+	return hostname + "-" + uuid.New().String()[:8]
+}
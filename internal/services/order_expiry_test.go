@@ -0,0 +1,165 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+)
+
+func setupOrderExpiryService(t *testing.T, holder string) (*orderExpiryService, *gorm.DB, *MockPaymentService) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Order{}, &models.JobLease{}, &models.OutboxEvent{}))
+
+	paymentSvc := &MockPaymentService{}
+	service := &orderExpiryService{
+		orderRepo:  repositories.NewOrderRepository(db),
+		leaseRepo:  repositories.NewJobLeaseRepository(db),
+		paymentSvc: paymentSvc,
+		holder:     holder,
+		timeout:    15 * time.Minute,
+		batchSize:  payTimeoutBatchSize,
+		now:        time.Now,
+		logger:     logrus.New(),
+		stop:       make(chan struct{}),
+	}
+	return service, db, paymentSvc
+}
+
+func createUnpaidOrder(t *testing.T, db *gorm.DB, id string, channelCode *string, createdOn time.Time) {
+	t.Helper()
+	require.NoError(t, db.Create(&models.Order{
+		ID:            id,
+		MachineId:     stringPtr("machine-1"),
+		OrderNo:       stringPtr("ORD" + id),
+		PayAmount:     15.80,
+		PaymentStatus: int(enums.PaymentStatusWaitPay),
+		ChannelCode:   channelCode,
+		CreatedOn:     createdOn,
+	}).Error)
+}
+
+func TestOrderExpiryService_ExpireUnpaidOrders(t *testing.T) {
+	service, db, paymentSvc := setupOrderExpiryService(t, "replica-a")
+	expired := time.Now().Add(-30 * time.Minute)
+	channel := stringPtr(contracts.ChannelCodeFuiouMerchant)
+
+	createUnpaidOrder(t, db, "no-channel", nil, expired)
+	createUnpaidOrder(t, db, "paid", channel, expired)
+	createUnpaidOrder(t, db, "unpaid", channel, expired)
+	createUnpaidOrder(t, db, "close-rejected", channel, expired)
+	createUnpaidOrder(t, db, "recent", channel, time.Now())
+
+	paymentSvc.On("GetPaymentAccount", "machine-1").Return(&contracts.PaymentAccount{
+		ReceivingAccount: "merchant-1",
+		ReceivingKey:     "key-1",
+		ChannelCode:      contracts.ChannelCodeFuiouMerchant,
+	}, nil)
+	queryOrder := func(orderNo string) interface{} {
+		return mock.MatchedBy(func(req contracts.TranQueryRequest) bool { return req.OrderNo == orderNo })
+	}
+	closeOrder := func(orderNo string) interface{} {
+		return mock.MatchedBy(func(req contracts.PaymentCloseRequest) bool { return req.OrderNo == orderNo })
+	}
+
+	// 渠道已支付的订单补记支付
+	paidAt := time.Now().Add(-20 * time.Minute)
+	paymentSvc.On("TranQuery", queryOrder("ORDpaid")).Return(&contracts.TranQueryResponse{
+		IsSuccess: true, PaymentStatus: contracts.PaymentStatusSuccess, TransactionId: "TX001", PaymentTime: paidAt,
+	}, nil)
+	paymentSvc.On("PayOrder", contracts.PayOrderRequest{ID: "paid", ChannelOrderNo: "TX001", PaidAt: paidAt}).Return(nil)
+
+	// 未支付的订单关单后作废
+	paymentSvc.On("TranQuery", queryOrder("ORDunpaid")).Return(&contracts.TranQueryResponse{
+		IsSuccess: true, PaymentStatus: contracts.PaymentStatusPaying,
+	}, nil)
+	paymentSvc.On("Close", closeOrder("ORDunpaid")).Return(&contracts.PaymentCloseResponse{IsSuccess: true}, nil)
+	paymentSvc.On("InvalidOrder", contracts.InvalidOrderRequest{ID: "unpaid"}).Return(nil)
+
+	// 关单失败时保留订单等待下一轮
+	paymentSvc.On("TranQuery", queryOrder("ORDclose-rejected")).Return(&contracts.TranQueryResponse{
+		IsSuccess: false, Message: "system busy",
+	}, nil)
+	paymentSvc.On("Close", closeOrder("ORDclose-rejected")).Return(&contracts.PaymentCloseResponse{
+		IsSuccess: false, Message: "order paid",
+	}, nil)
+
+	// 未发起支付的订单直接作废
+	paymentSvc.On("InvalidOrder", contracts.InvalidOrderRequest{ID: "no-channel", Unbound: true}).Return(nil)
+
+	count, err := service.ExpireUnpaidOrders()
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	paymentSvc.AssertExpectations(t)
+	paymentSvc.AssertNotCalled(t, "InvalidOrder", contracts.InvalidOrderRequest{ID: "close-rejected"})
+	paymentSvc.AssertNotCalled(t, "TranQuery", queryOrder("ORDrecent"))
+}
+
+func TestOrderExpiryService_LeaseHeldByOtherReplica(t *testing.T) {
+	service, db, paymentSvc := setupOrderExpiryService(t, "replica-a")
+	createUnpaidOrder(t, db, "no-channel", nil, time.Now().Add(-30*time.Minute))
+
+	acquired, err := repositories.NewJobLeaseRepository(db).
+		Acquire(orderExpiryLeaseName, "replica-b", time.Now(), orderExpiryLeaseTTL)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	count, err := service.ExpireUnpaidOrders()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	paymentSvc.AssertNotCalled(t, "InvalidOrder", mock.Anything)
+
+	// 持有者释放租约后本实例接管
+	require.NoError(t, repositories.NewJobLeaseRepository(db).Release(orderExpiryLeaseName, "replica-b"))
+	paymentSvc.On("InvalidOrder", contracts.InvalidOrderRequest{ID: "no-channel", Unbound: true}).Return(nil)
+	count, err = service.ExpireUnpaidOrders()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestOrderExpiryService_FailingOrdersDoNotStarveNewerOrders(t *testing.T) {
+	service, db, paymentSvc := setupOrderExpiryService(t, "replica-a")
+	service.batchSize = 2
+	expired := time.Now().Add(-time.Hour)
+
+	// 超过一批的订单每次作废都失败，排在较新的超时订单之前
+	for i, id := range []string{"failing-1", "failing-2", "failing-3"} {
+		createUnpaidOrder(t, db, id, nil, expired.Add(time.Duration(i)*time.Minute))
+		paymentSvc.On("InvalidOrder", contracts.InvalidOrderRequest{ID: id, Unbound: true}).Return(assert.AnError)
+	}
+	createUnpaidOrder(t, db, "newer", nil, expired.Add(10*time.Minute))
+	paymentSvc.On("InvalidOrder", contracts.InvalidOrderRequest{ID: "newer", Unbound: true}).Return(nil).Once()
+
+	count, err := service.ExpireUnpaidOrders()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// 下一轮从上一批之后继续扫描，较新的订单得以作废
+	count, err = service.ExpireUnpaidOrders()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	paymentSvc.AssertCalled(t, "InvalidOrder", contracts.InvalidOrderRequest{ID: "newer", Unbound: true})
+
+	// 扫描到末尾后从头重试处理失败的订单
+	count, err = service.ExpireUnpaidOrders()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	count, err = service.ExpireUnpaidOrders()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	paymentSvc.AssertNumberOfCalls(t, "InvalidOrder", 6)
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// 订单号格式：ORD + yyMMddHHmmss + 6位随机数，共21位
+// 退款单号格式：RF + 订单号 + 2位退款序号，共25位；自动退款不带序号
+// 支付渠道会在订单号与退款单号前拼接同一个收款订单前缀，前缀按最长的退款单号预留，不超过7位
+const (
+	orderNoPrefix       = "ORD"
+	orderNoTimeLayout   = "060102150405"
+	orderNoRandomDigits = 6
+	orderNoLength       = 21
+	orderNoMaxAttempts  = 3 // 订单号冲突时最多生成次数
+
+	refundNoPrefix    = "RF"
+	refundNoSeqDigits = 2
+	refundNoMaxSeq    = 99 // 同一订单最多退款次数
+	maxRefundNoLength = len(refundNoPrefix) + orderNoLength + refundNoSeqDigits
+
+	merchantOrderNoMaxLength = 32                                           // 渠道商户订单号最大长度
+	orderPrefixMaxLength     = merchantOrderNoMaxLength - maxRefundNoLength // 收款订单前缀最大长度
+)
+
+// OrderNoGenerator 订单号生成器
+// 随机部分使用加密随机数，多实例同一秒下单冲突概率极低，冲突由订单号唯一索引兜底并重新生成
+type OrderNoGenerator struct {
+	now    func() time.Time
+	random func() uint32
+}
+
+// NewOrderNoGenerator 创建订单号生成器
+func NewOrderNoGenerator() *OrderNoGenerator {
+	return &OrderNoGenerator{now: time.Now, random: cryptoUint32}
+}
+
+// Generate 生成订单号
+func (g *OrderNoGenerator) Generate() string {
+	return fmt.Sprintf("%s%s%06d", orderNoPrefix, g.now().Format(orderNoTimeLayout), g.random()%1000000)
+}
+
+// autoRefundNo 自动退款单号，同一订单只有一笔自动退款
+func autoRefundNo(orderNo string) string {
+	return refundNoPrefix + orderNo
+}
+
+// numberedRefundNo 机主发起退款的退款单号，seq 为订单内退款序号
+func numberedRefundNo(orderNo string, seq int) string {
+	return fmt.Sprintf("%s%s%0*d", refundNoPrefix, orderNo, refundNoSeqDigits, seq)
+}
+
+// machineOrderPrefix 机主未设置订单前缀时按机器编号生成前缀
+// 机器编号过长时以编号摘要代替，保证拼接后的商户订单号与退款单号不超过32位
+func machineOrderPrefix(machineNo string) string {
+	prefix := fmt.Sprintf("VM_%s_", machineNo)
+	if len(prefix) <= orderPrefixMaxLength {
+		return prefix
+	}
+	sum := sha256.Sum256([]byte(machineNo))
+	return "VM_" + hex.EncodeToString(sum[:])[:orderPrefixMaxLength-4] + "_"
+}
+
+// cryptoUint32 返回加密随机数，随机源不可用时退化为纳秒时间
+func cryptoUint32() uint32 {
+	var buf [4]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return uint32(time.Now().UnixNano()) // #nosec G115 - truncation is intended
+	}
+	return binary.BigEndian.Uint32(buf[:])
+}
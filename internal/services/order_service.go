@@ -1,11 +1,13 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 
 	"github.com/ddteam/drink-master/internal/contracts"
@@ -17,32 +19,75 @@ import (
 // OrderService 订单服务接口
 type OrderService interface {
 	GetMemberOrderPaging(request contracts.GetMemberOrderPagingRequest) (*contracts.OrderPagingResponse, error)
+	GetOwnerOrderPaging(request contracts.GetOwnerOrderPagingRequest) (*contracts.OwnerOrderPagingResponse, error)
 	GetByID(id string) (*contracts.GetOrderByIdResponse, error)
 	GetByOrderNo(orderNo string) (*models.Order, error)
 	Create(request contracts.CreateOrderRequest) (*contracts.CreateOrderResponse, error)
 	Refund(request contracts.RefundOrderRequest) (*contracts.RefundOrderResponse, error)
 }
 
+var (
+	// ErrProductNotAvailable 商品不存在或未定价
+	ErrProductNotAvailable = errors.New("商品不存在或未定价")
+	// ErrOrderAmountMismatch 客户端提交的金额与服务端报价不一致
+	ErrOrderAmountMismatch = errors.New("订单金额与商品价格不一致")
+	// ErrInvalidOrderFilter 订单查询条件无效
+	ErrInvalidOrderFilter = errors.New("订单查询条件无效")
+	// ErrMachineNotOwned 售货机不属于当前机主
+	ErrMachineNotOwned = errors.New("售货机不属于当前机主")
+)
+
+// 机主订单查询的日期格式
+const orderFilterDateLayout = "2006-01-02"
+
+// 接口支付状态对应的订单支付状态
+var orderPaymentStatusFilters = map[string]enums.PaymentStatus{
+	contracts.PaymentStatusWaitPay:   enums.PaymentStatusWaitPay,
+	contracts.PaymentStatusPaid:      enums.PaymentStatusPaid,
+	contracts.PaymentStatusRefunding: enums.PaymentStatusRefunding,
+	contracts.PaymentStatusRefunded:  enums.PaymentStatusRefunded,
+	contracts.PaymentStatusCancelled: enums.PaymentStatusInvalid,
+}
+
+// 接口制作状态对应的订单制作状态
+var orderMakeStatusFilters = map[string]enums.MakeStatus{
+	contracts.MakeStatusWaitMake: enums.MakeStatusWaitMake,
+	contracts.MakeStatusMaking:   enums.MakeStatusMaking,
+	contracts.MakeStatusMade:     enums.MakeStatusMade,
+	contracts.MakeStatusFailed:   enums.MakeStatusMakeFail,
+}
+
 // orderService 订单服务实现
 type orderService struct {
 	orderRepo   repositories.OrderRepository
 	machineRepo repositories.MachineRepositoryInterface
 	memberRepo  *repositories.MemberRepository
+	productRepo repositories.ProductRepositoryInterface
 	deviceSvc   DeviceServiceInterface
+	refundSvc   RefundServiceInterface
+	stockSvc    StockServiceInterface
+	orderNos    *OrderNoGenerator
 }
 
-// NewOrderService 创建订单服务
+// NewOrderService 创建订单服务，refundSvc 为空时不支持退款，stockSvc 为空时不预占库存
 func NewOrderService(
 	orderRepo repositories.OrderRepository,
 	machineRepo repositories.MachineRepositoryInterface,
 	memberRepo *repositories.MemberRepository,
+	productRepo repositories.ProductRepositoryInterface,
 	deviceSvc DeviceServiceInterface,
+	refundSvc RefundServiceInterface,
+	stockSvc StockServiceInterface,
 ) OrderService {
 	return &orderService{
 		orderRepo:   orderRepo,
 		machineRepo: machineRepo,
 		memberRepo:  memberRepo,
+		productRepo: productRepo,
 		deviceSvc:   deviceSvc,
+		refundSvc:   refundSvc,
+		stockSvc:    stockSvc,
+		orderNos:    NewOrderNoGenerator(),
 	}
 }
 
@@ -65,25 +110,14 @@ func (s *orderService) GetMemberOrderPaging(
 		return nil, fmt.Errorf("获取订单列表失败: %w", err)
 	}
 
+	names, err := s.loadCatalogNames(orders)
+	if err != nil {
+		return nil, err
+	}
+
 	// 转换为响应格式
 	orderResponses := make([]contracts.GetMemberOrderPagingResponse, len(orders))
 	for i, order := range orders {
-		productName := "Unknown Product" // Since Product association is disabled
-
-		paymentStatus := ""
-		switch enums.PaymentStatus(order.PaymentStatus) {
-		case enums.PaymentStatusWaitPay:
-			paymentStatus = contracts.PaymentStatusWaitPay
-		case enums.PaymentStatusPaid:
-			paymentStatus = contracts.PaymentStatusPaid
-		case enums.PaymentStatusRefunded:
-			paymentStatus = contracts.PaymentStatusRefunded
-		case enums.PaymentStatusInvalid:
-			paymentStatus = contracts.PaymentStatusCancelled
-		default:
-			paymentStatus = contracts.PaymentStatusWaitPay
-		}
-
 		orderNo := ""
 		if order.OrderNo != nil {
 			orderNo = *order.OrderNo
@@ -92,38 +126,165 @@ func (s *orderService) GetMemberOrderPaging(
 		orderResponses[i] = contracts.GetMemberOrderPagingResponse{
 			ID:                order.ID,
 			OrderNo:           orderNo,
-			ProductName:       productName,
+			ProductName:       names.productName(&order),
+			MachineName:       names.machineName(&order),
 			PayAmount:         decimal.NewFromFloat(order.PayAmount),
 			CreatedAt:         order.CreatedOn,
-			PaymentStatus:     paymentStatus,
+			PaymentStatus:     orderPaymentStatus(order.PaymentStatus),
 			PaymentStatusDesc: order.GetPaymentStatusDesc(),
 		}
 	}
 
-	// 计算分页信息
-	totalPages := int(total) / request.PageSize
-	if int(total)%request.PageSize > 0 {
+	return &contracts.OrderPagingResponse{
+		Orders: orderResponses,
+		Meta:   newPaginationMeta(total, len(orderResponses), request.PageIndex, request.PageSize),
+	}, nil
+}
+
+// GetOwnerOrderPaging 分页查询机主名下售货机的订单，并返回筛选结果合计
+func (s *orderService) GetOwnerOrderPaging(
+	request contracts.GetOwnerOrderPagingRequest,
+) (*contracts.OwnerOrderPagingResponse, error) {
+	filter, err := s.ownerOrderFilter(request)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := s.orderRepo.GetOwnerTotals(filter)
+	if err != nil {
+		return nil, fmt.Errorf("统计订单失败: %w", err)
+	}
+	orders, err := s.orderRepo.GetByOwnerPaging(filter, request.PageIndex, request.PageSize)
+	if err != nil {
+		return nil, fmt.Errorf("获取订单列表失败: %w", err)
+	}
+	names, err := s.loadCatalogNames(orders)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]contracts.OwnerOrderResponse, len(orders))
+	for i, order := range orders {
+		items[i] = contracts.OwnerOrderResponse{
+			ID:                order.ID,
+			OrderNo:           ptrToString(order.OrderNo),
+			MachineID:         ptrToString(order.MachineId),
+			MachineName:       names.machineName(&order),
+			ProductName:       names.productName(&order),
+			PayAmount:         decimal.NewFromFloat(order.PayAmount),
+			RefundAmount:      decimal.NewFromFloat(order.RefundAmount),
+			PaymentStatus:     orderPaymentStatus(order.PaymentStatus),
+			PaymentStatusDesc: order.GetPaymentStatusDesc(),
+			MakeStatus:        orderMakeStatus(order.MakeStatus),
+			MakeStatusDesc:    order.GetMakeStatusDesc(),
+			CreatedAt:         order.CreatedOn,
+			PaymentTime:       order.PaymentTime,
+		}
+	}
+
+	paidAmount := decimal.NewFromFloat(totals.PaidAmount).Round(2)
+	refundAmount := decimal.NewFromFloat(totals.RefundAmount).Round(2)
+	return &contracts.OwnerOrderPagingResponse{
+		Orders: items,
+		Meta:   newPaginationMeta(totals.Count, len(items), request.PageIndex, request.PageSize),
+		Summary: contracts.OwnerOrderSummary{
+			OrderCount:   totals.Count,
+			PaidAmount:   paidAmount,
+			RefundAmount: refundAmount,
+			NetAmount:    paidAmount.Sub(refundAmount),
+		},
+	}, nil
+}
+
+// ownerOrderFilter 校验机主订单查询条件，指定的售货机必须属于该机主
+func (s *orderService) ownerOrderFilter(
+	request contracts.GetOwnerOrderPagingRequest,
+) (repositories.OwnerOrderFilter, error) {
+	filter := repositories.OwnerOrderFilter{
+		MachineOwnerID: request.MachineOwnerID,
+		MachineID:      request.MachineID,
+		OrderNo:        request.OrderNo,
+	}
+
+	err := applyOrderStatusFilters(&filter, request.PaymentStatus, request.MakeStatus)
+	if err != nil {
+		return filter, err
+	}
+	if filter.CreatedFrom, err = parseOrderFilterDate(request.StartDate, 0); err != nil {
+		return filter, err
+	}
+	// 结束日期包含当天
+	if filter.CreatedTo, err = parseOrderFilterDate(request.EndDate, 1); err != nil {
+		return filter, err
+	}
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		return filter, fmt.Errorf("%w: 开始日期不能晚于结束日期", ErrInvalidOrderFilter)
+	}
+
+	if request.MachineID != "" {
+		machine, getErr := s.machineRepo.GetByID(request.MachineID)
+		if getErr != nil {
+			return filter, fmt.Errorf("查询机器信息失败: %w", getErr)
+		}
+		if machine == nil || ptrToString(machine.MachineOwnerId) != request.MachineOwnerID {
+			return filter, ErrMachineNotOwned
+		}
+	}
+	return filter, nil
+}
+
+// applyOrderStatusFilters 将接口支付状态与制作状态转换为订单状态查询条件
+func applyOrderStatusFilters(filter *repositories.OwnerOrderFilter, paymentStatus, makeStatus string) error {
+	if paymentStatus != "" {
+		status, ok := orderPaymentStatusFilters[paymentStatus]
+		if !ok {
+			return fmt.Errorf("%w: 不支持的支付状态 %s", ErrInvalidOrderFilter, paymentStatus)
+		}
+		filter.PaymentStatus = &status
+	}
+	if makeStatus != "" {
+		status, ok := orderMakeStatusFilters[makeStatus]
+		if !ok {
+			return fmt.Errorf("%w: 不支持的制作状态 %s", ErrInvalidOrderFilter, makeStatus)
+		}
+		filter.MakeStatus = &status
+	}
+	return nil
+}
+
+// parseOrderFilterDate 解析查询日期并偏移指定天数，日期为空时返回nil
+func parseOrderFilterDate(value string, offsetDays int) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.ParseInLocation(orderFilterDateLayout, value, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 日期格式应为YYYY-MM-DD", ErrInvalidOrderFilter)
+	}
+	date = date.AddDate(0, 0, offsetDays)
+	return &date, nil
+}
+
+// newPaginationMeta 生成分页信息
+func newPaginationMeta(total int64, count, pageIndex, pageSize int) contracts.PaginationMeta {
+	totalPages := int(total) / pageSize
+	if int(total)%pageSize > 0 {
 		totalPages++
 	}
 
-	meta := contracts.PaginationMeta{
+	return contracts.PaginationMeta{
 		Total:       total,
-		Count:       len(orderResponses),
-		PerPage:     request.PageSize,
-		CurrentPage: request.PageIndex,
+		Count:       count,
+		PerPage:     pageSize,
+		CurrentPage: pageIndex,
 		TotalPages:  totalPages,
-		HasNext:     request.PageIndex < totalPages,
-		HasPrev:     request.PageIndex > 1,
+		HasNext:     pageIndex < totalPages,
+		HasPrev:     pageIndex > 1,
 		Meta: &contracts.Meta{
 			Timestamp: time.Now(),
 			Version:   "v1.0.0",
 		},
 	}
-
-	return &contracts.OrderPagingResponse{
-		Orders: orderResponses,
-		Meta:   meta,
-	}, nil
 }
 
 // GetByID 根据ID获取订单详情
@@ -136,34 +297,6 @@ func (s *orderService) GetByID(id string) (*contracts.GetOrderByIdResponse, erro
 		return nil, fmt.Errorf("获取订单详情失败: %w", err)
 	}
 
-	paymentStatus := ""
-	switch enums.PaymentStatus(order.PaymentStatus) {
-	case enums.PaymentStatusWaitPay:
-		paymentStatus = contracts.PaymentStatusWaitPay
-	case enums.PaymentStatusPaid:
-		paymentStatus = contracts.PaymentStatusPaid
-	case enums.PaymentStatusRefunded:
-		paymentStatus = contracts.PaymentStatusRefunded
-	case enums.PaymentStatusInvalid:
-		paymentStatus = contracts.PaymentStatusCancelled
-	default:
-		paymentStatus = contracts.PaymentStatusWaitPay
-	}
-
-	makeStatus := ""
-	switch enums.MakeStatus(order.MakeStatus) {
-	case enums.MakeStatusWaitMake:
-		makeStatus = contracts.MakeStatusWaitMake
-	case enums.MakeStatusMaking:
-		makeStatus = contracts.MakeStatusMaking
-	case enums.MakeStatusMade:
-		makeStatus = contracts.MakeStatusMade
-	case enums.MakeStatusMakeFail:
-		makeStatus = contracts.MakeStatusFailed
-	default:
-		makeStatus = contracts.MakeStatusWaitMake
-	}
-
 	// 构建响应
 	orderNo := ""
 	machineId := ""
@@ -185,20 +318,28 @@ func (s *orderService) GetByID(id string) (*contracts.GetOrderByIdResponse, erro
 		MachineID:         machineId,
 		ProductID:         productId,
 		PayAmount:         decimal.NewFromFloat(order.PayAmount),
-		PaymentStatus:     paymentStatus,
+		PaymentStatus:     orderPaymentStatus(order.PaymentStatus),
 		PaymentStatusDesc: order.GetPaymentStatusDesc(),
-		MakeStatus:        makeStatus,
+		MakeStatus:        orderMakeStatus(order.MakeStatus),
 		MakeStatusDesc:    order.GetMakeStatusDesc(),
 		CreatedAt:         order.CreatedOn,
 		PaymentTime:       order.PaymentTime,
 		HasCup:            order.HasCup.Bool(),
 		RefundAmount:      decimal.NewFromFloat(order.RefundAmount),
 		RefundReason:      order.RefundReason,
+		ChannelCode:       ptrToString(order.ChannelCode),
 	}
 
-	// Machine and Product associations are disabled, set default names
-	response.MachineName = "Unknown Machine"
-	response.ProductName = "Unknown Product"
+	names, err := s.loadCatalogNames([]models.Order{*order})
+	if err != nil {
+		return nil, err
+	}
+	response.ProductName = names.productName(order)
+	response.MachineName = names.machineName(order)
+	if order.ProductPrice != nil {
+		productPrice := decimal.NewFromFloat(*order.ProductPrice)
+		response.ProductPrice = &productPrice
+	}
 
 	return response, nil
 }
@@ -222,6 +363,24 @@ func (s *orderService) Create(request contracts.CreateOrderRequest) (*contracts.
 		}
 		return nil, fmt.Errorf("查询机器信息失败: %w", err)
 	}
+	if machine == nil {
+		return nil, fmt.Errorf("机器不存在")
+	}
+
+	// 服务端报价，客户端金额仅用于核对
+	amount, err := s.quoteAmount(request.MachineID, request.ProductID, request.HasCup)
+	if err != nil {
+		return nil, err
+	}
+	if !request.PayAmount.Equal(amount) {
+		return nil, fmt.Errorf("%w: 应付%s元，提交%s元",
+			ErrOrderAmountMismatch, amount.StringFixed(2), request.PayAmount.StringFixed(2))
+	}
+
+	product, err := s.productRepo.GetByID(request.ProductID)
+	if err != nil {
+		return nil, fmt.Errorf("查询商品信息失败: %w", err)
+	}
 
 	// 检查设备是否在线 - Use MachineNo as device identifier
 	deviceId := machine.MachineNo
@@ -238,32 +397,34 @@ func (s *orderService) Create(request contracts.CreateOrderRequest) (*contracts.
 		return nil, fmt.Errorf("机器不在线，下单失败")
 	}
 
-	// 生成订单号
-	orderNo := s.generateOrderNo()
-
 	// 创建订单
 	order := &models.Order{
 		ID:        uuid.New().String(),
 		MemberId:  &request.MemberID,
 		MachineId: &request.MachineID,
 		ProductId: &request.ProductID,
-		OrderNo:   &orderNo,
 		HasCup: models.BitBool(func() int8 {
 			if request.HasCup {
 				return 1
 			}
 			return 0
 		}()),
-		TotalAmount:   request.PayAmount.InexactFloat64(),
-		PayAmount:     request.PayAmount.InexactFloat64(),
+		TotalAmount:   amount.InexactFloat64(),
+		PayAmount:     amount.InexactFloat64(),
 		PaymentStatus: int(enums.PaymentStatusWaitPay),
 		MakeStatus:    int(enums.MakeStatusWaitMake),
 		RefundAmount:  0,
 	}
+	// 快照商品与售货机信息，商品目录修改后历史订单保持下单时的内容
+	unitPrice := amount.InexactFloat64()
+	order.ProductPrice = &unitPrice
+	order.MachineName = machine.Name
+	if product != nil {
+		order.ProductName = &product.Name
+	}
 
-	err = s.orderRepo.Create(order)
-	if err != nil {
-		return nil, fmt.Errorf("创建订单失败: %w", err)
+	if err = s.createReservingStock(order); err != nil {
+		return nil, err
 	}
 
 	responseOrderNo := ""
@@ -278,48 +439,159 @@ func (s *orderService) Create(request contracts.CreateOrderRequest) (*contracts.
 	}, nil
 }
 
-// Refund 退款订单
+// Refund 退款订单，通过订单支付渠道原路退回
+// 渠道受理后需等待退款结果的退款返回退款中，订单在渠道确认前保持退款中状态
 func (s *orderService) Refund(request contracts.RefundOrderRequest) (*contracts.RefundOrderResponse, error) {
-	// 获取订单信息
-	order, err := s.orderRepo.GetByID(request.OrderID)
+	// 只有机主可以退款
+	if !request.IsMachineOwner {
+		return nil, fmt.Errorf("您不是机主，无法退款")
+	}
+	if s.refundSvc == nil {
+		return nil, fmt.Errorf("退款服务未配置")
+	}
+
+	record, err := s.refundSvc.Refund(request.OrderID, request.RefundAmount, request.Reason)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("订单不存在")
+		return nil, err
+	}
+
+	response := &contracts.RefundOrderResponse{
+		OrderID:      record.OrderId,
+		RefundNo:     record.RefundNo,
+		RefundAmount: decimal.NewFromFloat(record.Amount),
+		Status:       contracts.PaymentStatusRefunding,
+		Message:      "退款申请已提交，等待渠道处理",
+	}
+	if enums.RefundStatus(record.Status) == enums.RefundStatusSuccess {
+		response.Status = contracts.PaymentStatusRefunded
+		response.Message = "退款成功"
+	}
+	return response, nil
+}
+
+// quoteAmount 计算商品应付金额
+// 优先使用售货机商品价格，未配置时使用商品默认价格；不要杯时取无杯价格
+func (s *orderService) quoteAmount(machineID, productID string, hasCup bool) (decimal.Decimal, error) {
+	machinePrice, err := s.productRepo.GetMachineProductPrice(machineID, productID)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("查询商品价格失败: %w", err)
+	}
+
+	price, priceWithoutCup := 0.0, 0.0
+	if machinePrice != nil {
+		price, priceWithoutCup = machinePrice.Price, machinePrice.PriceWithoutCup
+	} else {
+		product, getErr := s.productRepo.GetByID(productID)
+		if getErr != nil {
+			return decimal.Zero, fmt.Errorf("查询商品信息失败: %w", getErr)
+		}
+		if product == nil {
+			return decimal.Zero, ErrProductNotAvailable
 		}
-		return nil, fmt.Errorf("获取订单信息失败: %w", err)
+		price, priceWithoutCup = product.Price, product.PriceWithoutCup
 	}
 
-	// 检查订单状态
-	if order.PaymentStatus != int(enums.PaymentStatusPaid) {
-		return nil, fmt.Errorf("订单状态不允许退款")
+	if !hasCup {
+		price = priceWithoutCup
+	}
+	amount := decimal.NewFromFloat(price).Round(2)
+	if !amount.IsPositive() {
+		return decimal.Zero, ErrProductNotAvailable
 	}
+	return amount, nil
+}
 
-	if order.PaymentStatus == int(enums.PaymentStatusRefunded) {
-		return nil, fmt.Errorf("订单已经退款")
+// orderPaymentStatus 订单支付状态对应的接口状态码
+func orderPaymentStatus(status int) string {
+	switch enums.PaymentStatus(status) {
+	case enums.PaymentStatusPaid:
+		return contracts.PaymentStatusPaid
+	case enums.PaymentStatusRefunded:
+		return contracts.PaymentStatusRefunded
+	case enums.PaymentStatusRefunding:
+		return contracts.PaymentStatusRefunding
+	case enums.PaymentStatusInvalid:
+		return contracts.PaymentStatusCancelled
+	default:
+		return contracts.PaymentStatusWaitPay
 	}
+}
 
-	// 只有机主可以退款
-	if !request.IsMachineOwner {
-		return nil, fmt.Errorf("您不是机主，无法退款")
+// orderMakeStatus 订单制作状态对应的接口状态码
+func orderMakeStatus(status int) string {
+	switch enums.MakeStatus(status) {
+	case enums.MakeStatusMaking:
+		return contracts.MakeStatusMaking
+	case enums.MakeStatusMade:
+		return contracts.MakeStatusMade
+	case enums.MakeStatusMakeFail:
+		return contracts.MakeStatusFailed
+	default:
+		return contracts.MakeStatusWaitMake
 	}
+}
 
-	// 更新订单状态
-	now := time.Now()
-	order.PaymentStatus = int(enums.PaymentStatusRefunded)
-	order.RefundTime = &now
-	order.RefundAmount = order.PayAmount
-	order.RefundReason = &request.Reason
+// orderCatalogNames 订单的商品与售货机名称，优先使用下单时的快照
+type orderCatalogNames struct {
+	products map[string]string
+	machines map[string]string
+}
 
-	err = s.orderRepo.Update(order)
-	if err != nil {
-		return nil, fmt.Errorf("更新订单状态失败: %w", err)
+// loadCatalogNames 批量查询没有名称快照的历史订单对应的当前商品与售货机名称
+func (s *orderService) loadCatalogNames(orders []models.Order) (*orderCatalogNames, error) {
+	names := &orderCatalogNames{products: map[string]string{}, machines: map[string]string{}}
+
+	var productIDs, machineIDs []string
+	for _, order := range orders {
+		if id := ptrToString(order.ProductId); order.ProductName == nil && id != "" {
+			if _, ok := names.products[id]; !ok {
+				names.products[id] = ""
+				productIDs = append(productIDs, id)
+			}
+		}
+		if id := ptrToString(order.MachineId); order.MachineName == nil && id != "" {
+			if _, ok := names.machines[id]; !ok {
+				names.machines[id] = ""
+				machineIDs = append(machineIDs, id)
+			}
+		}
 	}
 
-	return &contracts.RefundOrderResponse{
-		OrderID:      order.ID,
-		RefundAmount: decimal.NewFromFloat(order.RefundAmount),
-		Message:      "退款成功",
-	}, nil
+	if len(productIDs) > 0 {
+		products, err := s.productRepo.GetByIDs(productIDs)
+		if err != nil {
+			return nil, fmt.Errorf("获取商品信息失败: %w", err)
+		}
+		for _, product := range products {
+			names.products[product.ID] = product.Name
+		}
+	}
+	if len(machineIDs) > 0 {
+		machines, err := s.machineRepo.GetByIDs(machineIDs)
+		if err != nil {
+			return nil, fmt.Errorf("获取售货机信息失败: %w", err)
+		}
+		for _, machine := range machines {
+			names.machines[machine.ID] = ptrToString(machine.Name)
+		}
+	}
+	return names, nil
+}
+
+// productName 订单商品名称
+func (n *orderCatalogNames) productName(order *models.Order) string {
+	if order.ProductName != nil {
+		return *order.ProductName
+	}
+	return n.products[ptrToString(order.ProductId)]
+}
+
+// machineName 订单售货机名称
+func (n *orderCatalogNames) machineName(order *models.Order) string {
+	if order.MachineName != nil {
+		return *order.MachineName
+	}
+	return n.machines[ptrToString(order.MachineId)]
 }
 
 // GetByOrderNo 根据订单号获取订单
@@ -334,7 +606,42 @@ func (s *orderService) GetByOrderNo(orderNo string) (*models.Order, error) {
 	return order, nil
 }
 
+// createReservingStock 预占料仓库存后创建订单，订单创建失败时释放预占
+func (s *orderService) createReservingStock(order *models.Order) error {
+	if s.stockSvc != nil {
+		if err := s.stockSvc.Reserve(order.ID, *order.MachineId, *order.ProductId); err != nil {
+			return err
+		}
+	}
+
+	if err := s.createWithOrderNo(order); err != nil {
+		if s.stockSvc != nil {
+			if releaseErr := s.stockSvc.Release(order.ID); releaseErr != nil {
+				logrus.WithError(releaseErr).WithField("order_id", order.ID).Error("订单创建失败后释放库存失败")
+			}
+		}
+		return fmt.Errorf("创建订单失败: %w", err)
+	}
+	return nil
+}
+
+// createWithOrderNo 生成订单号并创建订单，订单号冲突时重新生成
+func (s *orderService) createWithOrderNo(order *models.Order) error {
+	var err error
+	for attempt := 0; attempt < orderNoMaxAttempts; attempt++ {
+		orderNo := s.generateOrderNo()
+		order.OrderNo = &orderNo
+		if err = s.orderRepo.Create(order); !errors.Is(err, repositories.ErrDuplicateOrderNo) {
+			return err
+		}
+	}
+	return err
+}
+
 // generateOrderNo 生成订单号
 func (s *orderService) generateOrderNo() string {
-	return fmt.Sprintf("ORD%s", time.Now().Format("20060102150405"))
+	if s.orderNos == nil {
+		return NewOrderNoGenerator().Generate()
+	}
+	return s.orderNos.Generate()
 }
@@ -1,19 +1,25 @@
 package services
 
 import (
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
 	"github.com/ddteam/drink-master/internal/contracts"
 	"github.com/ddteam/drink-master/internal/enums"
 	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
 )
 
 func TestNewOrderService(t *testing.T) {
-	service := NewOrderService(nil, nil, nil, nil)
+	service := NewOrderService(nil, nil, nil, nil, nil, nil, nil)
 	assert.NotNil(t, service)
 }
 
@@ -23,7 +29,7 @@ func TestOrderService_GenerateOrderNo(t *testing.T) {
 
 	assert.NotEmpty(t, orderNo)
 	assert.Contains(t, orderNo, "ORD")
-	assert.Len(t, orderNo, 17) // ORD + 14位时间戳
+	assert.Len(t, orderNo, 21) // ORD + 12位时间戳 + 6位随机数
 }
 
 // 测试常量值覆盖率
@@ -42,7 +48,7 @@ func TestOrderConstants(t *testing.T) {
 // 测试创建服务的各种情况
 func TestOrderService_ServiceCreation(t *testing.T) {
 	// 测试nil参数创建
-	service1 := NewOrderService(nil, nil, nil, nil)
+	service1 := NewOrderService(nil, nil, nil, nil, nil, nil, nil)
 	assert.NotNil(t, service1)
 
 	// 转换为具体类型以测试私有方法
@@ -93,21 +99,21 @@ func TestOrderService_GenerateOrderNoFormat(t *testing.T) {
 
 	// 验证格式一致性
 	for _, orderNo := range orderNos {
-		assert.True(t, len(orderNo) == 17, "Order number should be 17 characters long")
+		assert.True(t, len(orderNo) == 21, "Order number should be 21 characters long")
 		assert.True(t, orderNo[:3] == "ORD", "Order number should start with ORD")
 
-		// 验证后面是数字
-		dateTimePart := orderNo[3:]
-		assert.Len(t, dateTimePart, 14, "DateTime part should be 14 characters")
+		// 验证时间部分符合 yyMMddHHmmss
+		_, err := time.ParseInLocation(orderNoTimeLayout, orderNo[3:15], time.Local)
+		assert.NoError(t, err, "DateTime part should be yyMMddHHmmss")
 
-		// 验证是否符合时间格式 (YYYYMMDDHHMMSS)
-		assert.Regexp(t, `^\d{14}$`, dateTimePart, "DateTime part should be 14 digits")
+		// 验证随机部分为6位数字
+		assert.Regexp(t, `^\d{6}$`, orderNo[15:], "Random part should be 6 digits")
 	}
 }
 
 // 测试基础结构体方法调用
 func TestOrderService_BasicMethodsExist(t *testing.T) {
-	service := NewOrderService(nil, nil, nil, nil)
+	service := NewOrderService(nil, nil, nil, nil, nil, nil, nil)
 
 	// 检查方法是否存在，这里只验证接口方法存在
 	assert.NotNil(t, service)
@@ -133,7 +139,7 @@ func TestOrderService_CreationWithDifferentParams(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			service := NewOrderService(nil, nil, nil, nil)
+			service := NewOrderService(nil, nil, nil, nil, nil, nil, nil)
 			assert.NotNil(t, service)
 		})
 	}
@@ -254,6 +260,21 @@ func (m *mockOrderRepository) GetByID(id string) (*models.Order, error) {
 	return args.Get(0).(*models.Order), args.Error(1)
 }
 
+func (m *mockOrderRepository) GetByOwnerPaging(
+	filter repositories.OwnerOrderFilter, pageIndex, pageSize int,
+) ([]models.Order, error) {
+	args := m.Called(filter, pageIndex, pageSize)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (m *mockOrderRepository) GetOwnerTotals(filter repositories.OwnerOrderFilter) (*repositories.OrderTotals, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.OrderTotals), args.Error(1)
+}
+
 func (m *mockOrderRepository) GetByMemberPaging(memberID string, pageIndex, pageSize int) ([]models.Order, int64, error) {
 	args := m.Called(memberID, pageIndex, pageSize)
 	return args.Get(0).([]models.Order), args.Get(1).(int64), args.Error(2)
@@ -264,7 +285,7 @@ func (m *mockOrderRepository) Create(order *models.Order) error {
 	return args.Error(0)
 }
 
-func (m *mockOrderRepository) Update(order *models.Order) error {
+func (m *mockOrderRepository) Update(order *models.Order, _ ...*models.OutboxEvent) error {
 	args := m.Called(order)
 	return args.Error(0)
 }
@@ -274,6 +295,71 @@ func (m *mockOrderRepository) Delete(id string) error {
 	return args.Error(0)
 }
 
+func (m *mockOrderRepository) UpdateMakeStatus(id string, from, to enums.MakeStatus, _ ...*models.OutboxEvent) (bool, error) {
+	args := m.Called(id, from, to)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockOrderRepository) GetMakeTimedOut(paidBefore time.Time, limit int) ([]models.Order, error) {
+	args := m.Called(paidBefore, limit)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (m *mockOrderRepository) GetPayTimedOut(createdBefore time.Time, after *models.Order, limit int) ([]models.Order, error) {
+	args := m.Called(createdBefore, after, limit)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (m *mockOrderRepository) BindChannel(id string, channelCode string) (bool, error) {
+	args := m.Called(id, channelCode)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockOrderRepository) Invalidate(id string, unboundOnly bool, events ...*models.OutboxEvent) (bool, error) {
+	args := m.Called(id, unboundOnly)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestOrderNoGenerator_Generate(t *testing.T) {
+	generator := &OrderNoGenerator{
+		now:    func() time.Time { return time.Date(2025, 8, 12, 10, 30, 5, 0, time.Local) },
+		random: func() uint32 { return 4012345678 },
+	}
+	assert.Equal(t, "ORD250812103005345678", generator.Generate())
+	assert.Len(t, generator.Generate(), orderNoLength)
+
+	// 同一秒内的订单号由随机部分区分
+	orderNos := make(map[string]bool)
+	generator.random = cryptoUint32
+	for i := 0; i < 100; i++ {
+		orderNos[generator.Generate()] = true
+	}
+	assert.Greater(t, len(orderNos), 90)
+}
+
+func TestOrderService_CreateWithOrderNo_RetryOnConflict(t *testing.T) {
+	mockRepo := &mockOrderRepository{}
+	service := &orderService{orderRepo: mockRepo, orderNos: NewOrderNoGenerator()}
+
+	var attempted []string
+	mockRepo.On("Create", mock.AnythingOfType("*models.Order")).
+		Run(func(args mock.Arguments) { attempted = append(attempted, *args.Get(0).(*models.Order).OrderNo) }).
+		Return(repositories.ErrDuplicateOrderNo).Once()
+	mockRepo.On("Create", mock.AnythingOfType("*models.Order")).
+		Run(func(args mock.Arguments) { attempted = append(attempted, *args.Get(0).(*models.Order).OrderNo) }).
+		Return(nil).Once()
+
+	order := &models.Order{ID: "order-1"}
+	require.NoError(t, service.createWithOrderNo(order))
+	require.Len(t, attempted, 2)
+	assert.Equal(t, attempted[1], *order.OrderNo)
+
+	// 持续冲突时放弃
+	mockRepo.On("Create", mock.AnythingOfType("*models.Order")).Return(repositories.ErrDuplicateOrderNo)
+	assert.ErrorIs(t, service.createWithOrderNo(&models.Order{ID: "order-2"}), repositories.ErrDuplicateOrderNo)
+	mockRepo.AssertNumberOfCalls(t, "Create", 2+orderNoMaxAttempts)
+}
+
 // Test GetByOrderNo method
 func TestOrderService_GetByOrderNo(t *testing.T) {
 	mockRepo := &mockOrderRepository{}
@@ -330,3 +416,318 @@ func TestOrderService_GetByOrderNo_DatabaseError(t *testing.T) {
 
 	mockRepo.AssertExpectations(t)
 }
+
+func TestOrderService_QuoteAmount(t *testing.T) {
+	tests := []struct {
+		name         string
+		machinePrice *models.MachineProductPrice
+		product      *models.Product
+		hasCup       bool
+		expected     string
+		expectedErr  error
+	}{
+		{"售货机价格含杯", &models.MachineProductPrice{Price: 15.8, PriceWithoutCup: 14.8}, nil, true, "15.8", nil},
+		{"售货机价格无杯", &models.MachineProductPrice{Price: 15.8, PriceWithoutCup: 14.8}, nil, false, "14.8", nil},
+		{"回退商品价格含杯", nil, &models.Product{Price: 12, PriceWithoutCup: 11}, true, "12", nil},
+		{"回退商品价格无杯", nil, &models.Product{Price: 12, PriceWithoutCup: 11}, false, "11", nil},
+		{"商品不存在", nil, nil, true, "", ErrProductNotAvailable},
+		{"未配置无杯价格", &models.MachineProductPrice{Price: 15.8}, nil, false, "", ErrProductNotAvailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			productRepo := &MockProductRepository{}
+			productRepo.On("GetMachineProductPrice", "machine-1", "product-1").Return(tt.machinePrice, nil)
+			productRepo.On("GetByID", "product-1").Return(tt.product, nil)
+			service := &orderService{productRepo: productRepo}
+
+			amount, err := service.quoteAmount("machine-1", "product-1", tt.hasCup)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, decimal.RequireFromString(tt.expected).Equal(amount), "got %s", amount)
+		})
+	}
+}
+
+// setupOrderCreateDB 创建下单所需的会员、在线机器及售货机商品价格
+func setupOrderCreateDB(t *testing.T) (*gorm.DB, *MockDeviceService) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Member{}, &models.Machine{}, &models.Product{},
+		&models.MachineProductPrice{}, &models.Order{}, &models.MaterialSilo{}, &models.StockReservation{}))
+	require.NoError(t, db.Create(&models.Member{ID: "member-1"}).Error)
+	require.NoError(t, db.Create(&models.Machine{ID: "machine-1", MachineNo: stringPtr("DEV001")}).Error)
+	require.NoError(t, db.Create(&models.MachineProductPrice{
+		ID: "mp-1", MachineId: "machine-1", ProductId: "product-1", Price: 15.8, PriceWithoutCup: 14.8,
+	}).Error)
+
+	deviceSvc := &MockDeviceService{}
+	deviceSvc.On("CheckDeviceOnline", "DEV001").Return(true, nil)
+	return db, deviceSvc
+}
+
+func TestOrderService_Create_ServerSidePrice(t *testing.T) {
+	db, deviceSvc := setupOrderCreateDB(t)
+	service := NewOrderService(repositories.NewOrderRepository(db), repositories.NewMachineRepository(db),
+		repositories.NewMemberRepository(db), repositories.NewProductRepository(db), deviceSvc, nil, nil)
+
+	request := contracts.CreateOrderRequest{
+		MemberID:  "member-1",
+		MachineID: "machine-1",
+		ProductID: "product-1",
+		HasCup:    false,
+		PayAmount: decimal.RequireFromString("0.01"),
+	}
+
+	// 篡改金额被拒绝，不创建订单
+	_, err := service.Create(request)
+	assert.ErrorIs(t, err, ErrOrderAmountMismatch)
+	var count int64
+	require.NoError(t, db.Model(&models.Order{}).Count(&count).Error)
+	assert.Zero(t, count)
+
+	request.PayAmount = decimal.RequireFromString("14.80")
+	response, err := service.Create(request)
+	require.NoError(t, err)
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", response.OrderID).Error)
+	assert.Equal(t, 14.8, order.PayAmount)
+	assert.Equal(t, 14.8, order.TotalAmount)
+	assert.False(t, order.HasCup.Bool())
+}
+
+func TestOrderService_Create_ReservesStock(t *testing.T) {
+	db, deviceSvc := setupOrderCreateDB(t)
+	require.NoError(t, db.Create(&models.MaterialSilo{
+		ID:        "silo-1",
+		MachineId: stringPtr("machine-1"),
+		ProductId: stringPtr("product-1"),
+		IsSale:    models.BitBool(1),
+		Total:     10,
+		Stock:     1,
+	}).Error)
+	service := NewOrderService(repositories.NewOrderRepository(db), repositories.NewMachineRepository(db),
+		repositories.NewMemberRepository(db), repositories.NewProductRepository(db), deviceSvc, nil,
+		NewStockService(db))
+
+	request := contracts.CreateOrderRequest{
+		MemberID:  "member-1",
+		MachineID: "machine-1",
+		ProductID: "product-1",
+		HasCup:    true,
+		PayAmount: decimal.RequireFromString("15.80"),
+	}
+
+	response, err := service.Create(request)
+	require.NoError(t, err)
+
+	var reservation models.StockReservation
+	require.NoError(t, db.First(&reservation, "OrderId = ?", response.OrderID).Error)
+	assert.Equal(t, "silo-1", reservation.SiloId)
+
+	// 唯一的库存已被预占，后续下单提示售罄且不创建订单
+	_, err = service.Create(request)
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+	var count int64
+	require.NoError(t, db.Model(&models.Order{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestOrderService_Create_SnapshotsCatalog(t *testing.T) {
+	db, deviceSvc := setupOrderCreateDB(t)
+	require.NoError(t, db.Create(&models.Product{ID: "product-1", Name: "拿铁咖啡", Price: 18}).Error)
+	require.NoError(t, db.Model(&models.Machine{}).Where("Id = ?", "machine-1").
+		Update("Name", "办公楼1层咖啡机").Error)
+	service := NewOrderService(repositories.NewOrderRepository(db), repositories.NewMachineRepository(db),
+		repositories.NewMemberRepository(db), repositories.NewProductRepository(db), deviceSvc, nil, nil)
+
+	response, err := service.Create(contracts.CreateOrderRequest{
+		MemberID:  "member-1",
+		MachineID: "machine-1",
+		ProductID: "product-1",
+		HasCup:    true,
+		PayAmount: decimal.RequireFromString("15.80"),
+	})
+	require.NoError(t, err)
+
+	// 商品目录修改后订单仍展示下单时的名称与单价
+	require.NoError(t, db.Model(&models.Product{}).Where("Id = ?", "product-1").Update("Name", "新品拿铁").Error)
+	require.NoError(t, db.Model(&models.MachineProductPrice{}).Where("Id = ?", "mp-1").Update("Price", 20).Error)
+
+	detail, err := service.GetByID(response.OrderID)
+	require.NoError(t, err)
+	assert.Equal(t, "拿铁咖啡", detail.ProductName)
+	assert.Equal(t, "办公楼1层咖啡机", detail.MachineName)
+	require.NotNil(t, detail.ProductPrice)
+	assert.Equal(t, "15.8", detail.ProductPrice.String())
+}
+
+func TestOrderService_GetMemberOrderPaging_BatchLoadsNames(t *testing.T) {
+	db, _ := setupOrderCreateDB(t)
+	require.NoError(t, db.Create(&models.Product{ID: "product-1", Name: "拿铁咖啡"}).Error)
+	require.NoError(t, db.Create(&models.Product{ID: "product-2", Name: "美式咖啡"}).Error)
+	require.NoError(t, db.Model(&models.Machine{}).Where("Id = ?", "machine-1").
+		Update("Name", "办公楼1层咖啡机").Error)
+
+	now := time.Now()
+	orders := []models.Order{
+		// 快照上线前的历史订单按当前商品目录展示
+		{ID: "order-1", MemberId: stringPtr("member-1"), MachineId: stringPtr("machine-1"),
+			ProductId: stringPtr("product-1"), CreatedOn: now.Add(-3 * time.Minute)},
+		{ID: "order-2", MemberId: stringPtr("member-1"), MachineId: stringPtr("machine-1"),
+			ProductId: stringPtr("product-2"), CreatedOn: now.Add(-2 * time.Minute)},
+		{ID: "order-3", MemberId: stringPtr("member-1"), MachineId: stringPtr("machine-1"),
+			ProductId: stringPtr("product-1"), CreatedOn: now.Add(-time.Minute)},
+		{ID: "order-4", MemberId: stringPtr("member-1"), MachineId: stringPtr("machine-1"),
+			ProductId: stringPtr("product-1"), ProductName: stringPtr("旧版拿铁"),
+			MachineName: stringPtr("旧机器名"), CreatedOn: now},
+	}
+	for i := range orders {
+		require.NoError(t, db.Create(&orders[i]).Error)
+	}
+
+	catalogQueries := 0
+	require.NoError(t, db.Callback().Query().After("gorm:query").Register("test:count_catalog",
+		func(tx *gorm.DB) {
+			if tx.Statement.Table == "products" || tx.Statement.Table == "machines" {
+				catalogQueries++
+			}
+		}))
+
+	service := NewOrderService(repositories.NewOrderRepository(db), repositories.NewMachineRepository(db),
+		repositories.NewMemberRepository(db), repositories.NewProductRepository(db), nil, nil, nil)
+	response, err := service.GetMemberOrderPaging(contracts.GetMemberOrderPagingRequest{
+		MemberID: "member-1", PageIndex: 1, PageSize: 10,
+	})
+	require.NoError(t, err)
+
+	names := map[string][2]string{}
+	for _, order := range response.Orders {
+		names[order.ID] = [2]string{order.ProductName, order.MachineName}
+	}
+	assert.Equal(t, [2]string{"拿铁咖啡", "办公楼1层咖啡机"}, names["order-1"])
+	assert.Equal(t, [2]string{"美式咖啡", "办公楼1层咖啡机"}, names["order-2"])
+	assert.Equal(t, [2]string{"拿铁咖啡", "办公楼1层咖啡机"}, names["order-3"])
+	assert.Equal(t, [2]string{"旧版拿铁", "旧机器名"}, names["order-4"])
+	// 商品与售货机各批量查询一次
+	assert.Equal(t, 2, catalogQueries)
+}
+
+func TestOrderService_GetOwnerOrderPaging(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Machine{}, &models.Product{}, &models.Order{}))
+	require.NoError(t, db.Create(&models.Machine{ID: "machine-1", MachineOwnerId: stringPtr("owner-1"),
+		Name: stringPtr("一号机")}).Error)
+	require.NoError(t, db.Create(&models.Machine{ID: "machine-2", MachineOwnerId: stringPtr("owner-1"),
+		Name: stringPtr("二号机")}).Error)
+	require.NoError(t, db.Create(&models.Machine{ID: "machine-3", MachineOwnerId: stringPtr("owner-2")}).Error)
+
+	day := time.Date(2025, 8, 12, 10, 0, 0, 0, time.Local)
+	orders := []models.Order{
+		{ID: "paid", OrderNo: stringPtr("ORD1"), MachineId: stringPtr("machine-1"), PayAmount: 15.8,
+			PaymentStatus: int(enums.PaymentStatusPaid), MakeStatus: int(enums.MakeStatusMade), CreatedOn: day},
+		{ID: "refunded", OrderNo: stringPtr("ORD2"), MachineId: stringPtr("machine-2"), PayAmount: 12,
+			RefundAmount: 12, PaymentStatus: int(enums.PaymentStatusRefunded),
+			MakeStatus: int(enums.MakeStatusMakeFail), CreatedOn: day.Add(time.Hour)},
+		{ID: "unpaid", OrderNo: stringPtr("ORD3"), MachineId: stringPtr("machine-1"), PayAmount: 9,
+			PaymentStatus: int(enums.PaymentStatusWaitPay), CreatedOn: day.AddDate(0, 0, 1)},
+		{ID: "other-owner", OrderNo: stringPtr("ORD4"), MachineId: stringPtr("machine-3"), PayAmount: 20,
+			PaymentStatus: int(enums.PaymentStatusPaid), CreatedOn: day},
+	}
+	for i := range orders {
+		require.NoError(t, db.Create(&orders[i]).Error)
+	}
+
+	service := NewOrderService(repositories.NewOrderRepository(db), repositories.NewMachineRepository(db),
+		nil, repositories.NewProductRepository(db), nil, nil, nil)
+	request := contracts.GetOwnerOrderPagingRequest{MachineOwnerID: "owner-1", PageIndex: 1, PageSize: 10}
+
+	// 只返回机主名下售货机的订单
+	response, err := service.GetOwnerOrderPaging(request)
+	require.NoError(t, err)
+	require.Len(t, response.Orders, 3)
+	assert.Equal(t, "unpaid", response.Orders[0].ID)
+	assert.Equal(t, int64(3), response.Meta.Total)
+	assert.Equal(t, int64(3), response.Summary.OrderCount)
+	assert.Equal(t, "27.8", response.Summary.PaidAmount.String())
+	assert.Equal(t, "12", response.Summary.RefundAmount.String())
+	assert.Equal(t, "15.8", response.Summary.NetAmount.String())
+
+	// 日期范围包含结束日期当天
+	dated := request
+	dated.StartDate, dated.EndDate = "2025-08-12", "2025-08-12"
+	response, err = service.GetOwnerOrderPaging(dated)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), response.Summary.OrderCount)
+
+	// 状态与售货机筛选
+	filtered := request
+	filtered.MachineID, filtered.PaymentStatus, filtered.MakeStatus = "machine-2", "Refunded", "Failed"
+	response, err = service.GetOwnerOrderPaging(filtered)
+	require.NoError(t, err)
+	require.Len(t, response.Orders, 1)
+	assert.Equal(t, "refunded", response.Orders[0].ID)
+	assert.Equal(t, "二号机", response.Orders[0].MachineName)
+
+	byOrderNo := request
+	byOrderNo.OrderNo = "ORD4"
+	response, err = service.GetOwnerOrderPaging(byOrderNo)
+	require.NoError(t, err)
+	assert.Empty(t, response.Orders)
+
+	// 其他机主的售货机
+	notOwned := request
+	notOwned.MachineID = "machine-3"
+	_, err = service.GetOwnerOrderPaging(notOwned)
+	assert.ErrorIs(t, err, ErrMachineNotOwned)
+
+	invalid := request
+	invalid.PaymentStatus = "Unknown"
+	_, err = service.GetOwnerOrderPaging(invalid)
+	assert.ErrorIs(t, err, ErrInvalidOrderFilter)
+
+	invalid = request
+	invalid.StartDate, invalid.EndDate = "2025-08-13", "2025-08-12"
+	_, err = service.GetOwnerOrderPaging(invalid)
+	assert.ErrorIs(t, err, ErrInvalidOrderFilter)
+}
+
+func TestMachineOrderPrefix(t *testing.T) {
+	assert.Equal(t, "VM_001_", machineOrderPrefix("001"))
+
+	// 机器编号最长32位，拼接订单号后不得超过渠道商户订单号长度
+	longNo := strings.Repeat("M", 32)
+	prefix := machineOrderPrefix(longNo)
+	assert.Len(t, prefix, orderPrefixMaxLength)
+	assert.Regexp(t, `^VM_[0-9a-f]{3}_$`, prefix)
+	assert.Equal(t, prefix, machineOrderPrefix(longNo))
+	assert.NotEqual(t, prefix, machineOrderPrefix(strings.Repeat("N", 32)))
+
+	orderNo := NewOrderNoGenerator().Generate()
+	for _, machineNo := range []string{"", "VM001", "VM0000001", "VM00000001", longNo} {
+		assert.LessOrEqual(t, len(machineOrderPrefix(machineNo)+orderNo), merchantOrderNoMaxLength, machineNo)
+	}
+}
+
+func TestRefundNo_FitsMerchantOrderNo(t *testing.T) {
+	orderNo := NewOrderNoGenerator().Generate()
+	assert.Equal(t, "RF"+orderNo, autoRefundNo(orderNo))
+	assert.Equal(t, "RF"+orderNo+"01", numberedRefundNo(orderNo, 1))
+	assert.Len(t, numberedRefundNo(orderNo, refundNoMaxSeq), maxRefundNoLength)
+
+	// 渠道在退款单号前拼接与订单号相同的收款订单前缀，机主设置的前缀最长5位
+	prefixes := []string{"ABCDE", machineOrderPrefix("VM001"), machineOrderPrefix(strings.Repeat("M", 32))}
+	refundNos := []string{autoRefundNo(orderNo), numberedRefundNo(orderNo, 1), numberedRefundNo(orderNo, refundNoMaxSeq)}
+	for _, prefix := range prefixes {
+		for _, refundNo := range refundNos {
+			assert.LessOrEqual(t, len(prefix+refundNo), merchantOrderNoMaxLength, prefix+refundNo)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+)
+
+// 领域事件投递策略与投递任务租约
+const (
+	outboxMaxAttempts       = 10               // 最大投递次数，超过后标记投递失败待人工处理
+	outboxRetryBaseDelay    = 10 * time.Second // 首次重试间隔，之后按指数递增
+	outboxRetryMaxDelay     = 30 * time.Minute // 最大重试间隔
+	outboxDispatchBatchSize = 100
+	outboxDispatchLeaseName = "outbox_dispatch"
+	outboxDispatchLeaseTTL  = 5 * time.Minute
+	outboxLastErrorMaxRunes = 512
+)
+
+// OrderEventHandler 订单领域事件订阅者，返回错误时事件稍后重新投递
+type OrderEventHandler func(event contracts.OrderEvent) error
+
+// OutboxDispatcherInterface 领域事件投递服务接口
+type OutboxDispatcherInterface interface {
+	Subscribe(eventType string, handler OrderEventHandler)
+	DispatchPending() (int, error)
+	Start(interval time.Duration)
+	Stop()
+}
+
+// outboxDispatcher 领域事件投递服务实现
+// 按写入顺序将发件箱中的事件投递给进程内订阅者，任一订阅者失败时整条事件按退避策略重新投递（至少一次）
+// 多实例部署时通过任务租约保证同一时刻只有一个实例投递
+type outboxDispatcher struct {
+	outboxRepo  repositories.OutboxRepository
+	leaseRepo   repositories.JobLeaseRepository
+	holder      string
+	now         func() time.Time
+	logger      *logrus.Logger
+	mu          sync.RWMutex
+	handlers    map[string][]OrderEventHandler
+	stop        chan struct{}
+	stopOnce    sync.Once
+	maxAttempts int
+}
+
+// NewOutboxDispatcher 创建领域事件投递服务
+func NewOutboxDispatcher(db *gorm.DB) OutboxDispatcherInterface {
+	return &outboxDispatcher{
+		outboxRepo:  repositories.NewOutboxRepository(db),
+		leaseRepo:   repositories.NewJobLeaseRepository(db),
+		holder:      leaseHolderID(),
+		now:         time.Now,
+		logger:      logrus.StandardLogger(),
+		handlers:    make(map[string][]OrderEventHandler),
+		stop:        make(chan struct{}),
+		maxAttempts: outboxMaxAttempts,
+	}
+}
+
+// Subscribe 订阅指定类型的领域事件
+func (d *outboxDispatcher) Subscribe(eventType string, handler OrderEventHandler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[eventType] = append(d.handlers[eventType], handler)
+}
+
+// DispatchPending 投递已到投递时间的事件，返回投递成功的事件数量
+// 租约由其他实例持有时不做处理；没有订阅者的事件直接标记为已投递
+func (d *outboxDispatcher) DispatchPending() (int, error) {
+	acquired, err := d.holdLease()
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire outbox dispatch lease: %w", err)
+	}
+	if !acquired {
+		return 0, nil
+	}
+
+	events, err := d.outboxRepo.GetDue(d.now(), outboxDispatchBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending outbox events: %w", err)
+	}
+
+	delivered := 0
+	for i := range events {
+		if i > 0 {
+			if held, holdErr := d.holdLease(); holdErr != nil || !held {
+				break
+			}
+		}
+		ok, err := d.dispatch(&events[i])
+		if err != nil {
+			d.logger.WithError(err).WithField("event_id", events[i].ID).Error("领域事件投递状态保存失败")
+			continue
+		}
+		if ok {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// Start 定期投递领域事件
+func (d *outboxDispatcher) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+				if _, err := d.DispatchPending(); err != nil {
+					d.logger.WithError(err).Error("领域事件投递失败")
+				}
+			}
+		}
+	}()
+}
+
+// Stop 停止投递并释放任务租约
+func (d *outboxDispatcher) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stop)
+		if err := d.leaseRepo.Release(outboxDispatchLeaseName, d.holder); err != nil {
+			d.logger.WithError(err).Warn("释放领域事件投递任务租约失败")
+		}
+	})
+}
+
+// holdLease 获取或续期投递任务租约
+func (d *outboxDispatcher) holdLease() (bool, error) {
+	return d.leaseRepo.Acquire(outboxDispatchLeaseName, d.holder, d.now(), outboxDispatchLeaseTTL)
+}
+
+// dispatch 投递单个事件并记录投递结果，返回是否投递成功
+func (d *outboxDispatcher) dispatch(event *models.OutboxEvent) (bool, error) {
+	var payload contracts.OrderEvent
+	if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+		// 无法解析的事件重试也不会成功
+		return false, d.outboxRepo.MarkFailed(event.ID, truncateRunes(err.Error(), outboxLastErrorMaxRunes), d.now())
+	}
+
+	deliverErr := d.deliver(payload)
+	if deliverErr == nil {
+		return true, d.outboxRepo.MarkDelivered(event.ID, d.now())
+	}
+
+	message := truncateRunes(deliverErr.Error(), outboxLastErrorMaxRunes)
+	logger := d.logger.WithError(deliverErr).WithFields(logrus.Fields{
+		"event_id":   event.ID,
+		"event_type": event.EventType,
+		"order_id":   event.AggregateId,
+	})
+	if event.Attempts+1 >= d.maxAttempts {
+		logger.Error("领域事件投递失败次数超过上限，待人工处理")
+		return false, d.outboxRepo.MarkFailed(event.ID, message, d.now())
+	}
+	logger.Warn("领域事件投递失败，稍后重试")
+	return false, d.outboxRepo.MarkRetry(event.ID, message, d.now().Add(outboxRetryDelay(event.Attempts+1)))
+}
+
+// deliver 将事件依次交给该类型的全部订阅者
+func (d *outboxDispatcher) deliver(event contracts.OrderEvent) error {
+	d.mu.RLock()
+	handlers := append([]OrderEventHandler(nil), d.handlers[event.Type]...)
+	d.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := callEventHandler(handler, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// callEventHandler 调用订阅者，订阅者panic时按投递失败处理
+func callEventHandler(handler OrderEventHandler, event contracts.OrderEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("event handler panic: %v", r)
+		}
+	}()
+	return handler(event)
+}
+
+// outboxRetryDelay 按投递次数计算重试间隔
+func outboxRetryDelay(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := outboxRetryBaseDelay << uint(attempts-1) // #nosec G115 - attempts is bounded by outboxMaxAttempts
+	if delay <= 0 || delay > outboxRetryMaxDelay {
+		return outboxRetryMaxDelay
+	}
+	return delay
+}
+
+// newOrderEvent 根据订单当前状态生成待写入发件箱的领域事件
+func newOrderEvent(eventType string, order *models.Order, at time.Time) *models.OutboxEvent {
+	return newOutboxEvent(contracts.OrderEvent{
+		Type:       eventType,
+		OrderID:    order.ID,
+		OrderNo:    ptrToString(order.OrderNo),
+		MemberID:   ptrToString(order.MemberId),
+		MachineID:  ptrToString(order.MachineId),
+		Amount:     decimal.NewFromFloat(order.PayAmount),
+		OccurredAt: at,
+	})
+}
+
+// newRefundEvent 根据退款记录生成待写入发件箱的领域事件
+func newRefundEvent(eventType string, record *models.RefundRecord, at time.Time) *models.OutboxEvent {
+	return newOutboxEvent(contracts.OrderEvent{
+		Type:       eventType,
+		OrderID:    record.OrderId,
+		Amount:     decimal.NewFromFloat(record.Amount),
+		RefundNo:   record.RefundNo,
+		OccurredAt: at,
+	})
+}
+
+// newOutboxEvent 生成事件ID并序列化事件内容
+func newOutboxEvent(event contracts.OrderEvent) *models.OutboxEvent {
+	event.ID = uuid.New().String()
+	// OrderEvent 只包含可序列化的字段，序列化不会失败
+	payload, _ := json.Marshal(event)
+	return &models.OutboxEvent{
+		ID:          event.ID,
+		EventType:   event.Type,
+		AggregateId: event.OrderID,
+		Payload:     string(payload),
+		CreatedOn:   event.OccurredAt,
+	}
+}
+
+// truncateRunes 截断过长的文本以适配字段长度
+func truncateRunes(s string, limit int) string {
+	if runes := []rune(s); len(runes) > limit {
+		return string(runes[:limit])
+	}
+	return s
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+)
+
+func setupOutboxDispatcher(t *testing.T, holder string) (*outboxDispatcher, *gorm.DB) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Order{}, &models.OutboxEvent{}, &models.JobLease{}))
+
+	dispatcher := &outboxDispatcher{
+		outboxRepo:  repositories.NewOutboxRepository(db),
+		leaseRepo:   repositories.NewJobLeaseRepository(db),
+		holder:      holder,
+		now:         time.Now,
+		logger:      logrus.New(),
+		handlers:    make(map[string][]OrderEventHandler),
+		stop:        make(chan struct{}),
+		maxAttempts: 3,
+	}
+	return dispatcher, db
+}
+
+// createPaidOrder 通过支付服务支付订单，订单状态与OrderPaid事件在同一事务中写入
+func createPaidOrder(t *testing.T, db *gorm.DB, id string) {
+	t.Helper()
+	require.NoError(t, db.Create(&models.Order{
+		ID:            id,
+		OrderNo:       stringPtr("ORD" + id),
+		MemberId:      stringPtr("member-1"),
+		MachineId:     stringPtr("machine-1"),
+		PayAmount:     15.80,
+		PaymentStatus: int(enums.PaymentStatusWaitPay),
+		CreatedOn:     time.Now(),
+	}).Error)
+
+	paymentSvc := &paymentService{orderRepo: repositories.NewOrderRepository(db)}
+	require.NoError(t, paymentSvc.PayOrder(contracts.PayOrderRequest{ID: id, ChannelOrderNo: "TX" + id, PaidAt: time.Now()}))
+}
+
+func getOutboxEvents(t *testing.T, db *gorm.DB) []models.OutboxEvent {
+	t.Helper()
+	var events []models.OutboxEvent
+	require.NoError(t, db.Order("CreatedOn ASC").Find(&events).Error)
+	return events
+}
+
+func outboxEventTypes(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	var types []string
+	for _, event := range getOutboxEvents(t, db) {
+		types = append(types, event.EventType)
+	}
+	return types
+}
+
+func TestOutboxDispatcher_DeliversPaymentEvents(t *testing.T) {
+	dispatcher, db := setupOutboxDispatcher(t, "replica-a")
+	createPaidOrder(t, db, "order-1")
+
+	var received []contracts.OrderEvent
+	dispatcher.Subscribe(contracts.EventOrderPaid, func(event contracts.OrderEvent) error {
+		received = append(received, event)
+		return nil
+	})
+
+	count, err := dispatcher.DispatchPending()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	require.Len(t, received, 1)
+	assert.Equal(t, contracts.EventOrderPaid, received[0].Type)
+	assert.Equal(t, "order-1", received[0].OrderID)
+	assert.Equal(t, "ORDorder-1", received[0].OrderNo)
+	assert.Equal(t, "member-1", received[0].MemberID)
+	assert.Equal(t, "15.8", received[0].Amount.String())
+
+	events := getOutboxEvents(t, db)
+	require.Len(t, events, 1)
+	assert.Equal(t, received[0].ID, events[0].ID)
+	assert.Equal(t, int(enums.OutboxStatusDelivered), events[0].Status)
+	assert.NotNil(t, events[0].DeliveredOn)
+
+	// 已投递的事件不会重复投递
+	count, err = dispatcher.DispatchPending()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	assert.Len(t, received, 1)
+}
+
+func TestOutboxDispatcher_RetriesFailedDelivery(t *testing.T) {
+	dispatcher, db := setupOutboxDispatcher(t, "replica-a")
+	createPaidOrder(t, db, "order-1")
+
+	now := time.Now()
+	dispatcher.now = func() time.Time { return now }
+
+	var delivered int
+	failures := 1
+	dispatcher.Subscribe(contracts.EventOrderPaid, func(event contracts.OrderEvent) error {
+		delivered++
+		return nil
+	})
+	dispatcher.Subscribe(contracts.EventOrderPaid, func(event contracts.OrderEvent) error {
+		if failures > 0 {
+			failures--
+			return errors.New("subscriber unavailable")
+		}
+		return nil
+	})
+
+	count, err := dispatcher.DispatchPending()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	events := getOutboxEvents(t, db)
+	assert.Equal(t, int(enums.OutboxStatusPending), events[0].Status)
+	assert.Equal(t, 1, events[0].Attempts)
+	require.NotNil(t, events[0].LastError)
+	assert.Contains(t, *events[0].LastError, "subscriber unavailable")
+	assert.WithinDuration(t, now.Add(outboxRetryBaseDelay), events[0].NextAttemptOn, time.Second)
+
+	// 未到重试时间不投递
+	count, err = dispatcher.DispatchPending()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// 到达重试时间后重新投递给全部订阅者（至少一次）
+	now = now.Add(outboxRetryBaseDelay)
+	count, err = dispatcher.DispatchPending()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, 2, delivered)
+	assert.Equal(t, int(enums.OutboxStatusDelivered), getOutboxEvents(t, db)[0].Status)
+}
+
+func TestOutboxDispatcher_GiveUpAfterMaxAttempts(t *testing.T) {
+	dispatcher, db := setupOutboxDispatcher(t, "replica-a")
+	createPaidOrder(t, db, "order-1")
+
+	now := time.Now()
+	dispatcher.now = func() time.Time { return now }
+	dispatcher.Subscribe(contracts.EventOrderPaid, func(event contracts.OrderEvent) error {
+		panic("handler bug")
+	})
+
+	for i := 0; i < dispatcher.maxAttempts; i++ {
+		_, err := dispatcher.DispatchPending()
+		require.NoError(t, err)
+		now = now.Add(outboxRetryMaxDelay)
+	}
+
+	events := getOutboxEvents(t, db)
+	assert.Equal(t, int(enums.OutboxStatusFailed), events[0].Status)
+	assert.Equal(t, dispatcher.maxAttempts, events[0].Attempts)
+	require.NotNil(t, events[0].LastError)
+	assert.Contains(t, *events[0].LastError, "handler bug")
+}
+
+func TestOutboxDispatcher_NoSubscribers(t *testing.T) {
+	dispatcher, db := setupOutboxDispatcher(t, "replica-a")
+	createPaidOrder(t, db, "order-1")
+
+	count, err := dispatcher.DispatchPending()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, int(enums.OutboxStatusDelivered), getOutboxEvents(t, db)[0].Status)
+}
+
+func TestOutboxDispatcher_LeaseHeldByOtherReplica(t *testing.T) {
+	dispatcher, db := setupOutboxDispatcher(t, "replica-a")
+	createPaidOrder(t, db, "order-1")
+
+	acquired, err := repositories.NewJobLeaseRepository(db).
+		Acquire(outboxDispatchLeaseName, "replica-b", time.Now(), outboxDispatchLeaseTTL)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	count, err := dispatcher.DispatchPending()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	assert.Equal(t, int(enums.OutboxStatusPending), getOutboxEvents(t, db)[0].Status)
+}
+
+func TestPaymentService_InvalidOrder_WritesEvent(t *testing.T) {
+	_, db := setupOutboxDispatcher(t, "replica-a")
+	require.NoError(t, db.Create(&models.Order{
+		ID:            "order-1",
+		PaymentStatus: int(enums.PaymentStatusWaitPay),
+		CreatedOn:     time.Now(),
+	}).Error)
+
+	paymentSvc := &paymentService{orderRepo: repositories.NewOrderRepository(db)}
+	require.NoError(t, paymentSvc.InvalidOrder(contracts.InvalidOrderRequest{ID: "order-1"}))
+
+	events := getOutboxEvents(t, db)
+	require.Len(t, events, 1)
+	assert.Equal(t, contracts.EventOrderInvalidated, events[0].EventType)
+	assert.Equal(t, "order-1", events[0].AggregateId)
+
+	// 已作废的订单不重复写入事件
+	err := paymentSvc.InvalidOrder(contracts.InvalidOrderRequest{ID: "order-1"})
+	assert.ErrorIs(t, err, ErrOrderNotWaitPay)
+	assert.Len(t, getOutboxEvents(t, db), 1)
+}
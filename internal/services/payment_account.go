@@ -0,0 +1,240 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/config"
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+	"github.com/ddteam/drink-master/pkg/secret"
+	"github.com/ddteam/drink-master/pkg/wechatpay"
+)
+
+var (
+	// ErrPaymentAccountNotFound 机主未配置收款账户
+	ErrPaymentAccountNotFound = errors.New("payment account not found")
+	// ErrMachineOwnerNotFound 机主不存在
+	ErrMachineOwnerNotFound = errors.New("machine owner not found")
+	// ErrReceivingKeyRequired 首次设置收款账户时必须提供收款密钥
+	ErrReceivingKeyRequired = errors.New("receiving key is required")
+	// ErrInvalidMerchantCredentials 商户API证书序列号与私钥不完整或私钥无法解析
+	ErrInvalidMerchantCredentials = errors.New("invalid merchant credentials")
+	// errPaymentSecretNotConfigured 未配置收款密钥加密密钥
+	errPaymentSecretNotConfigured = errors.New("payment secret key is not configured")
+)
+
+// paymentSecretBox 根据环境配置创建收款密钥加解密器
+func paymentSecretBox() (*secret.Box, error) {
+	cfg := config.NewPaymentConfig()
+	if !cfg.Enabled() {
+		return nil, errPaymentSecretNotConfigured
+	}
+	return secret.NewFromBase64(cfg.SecretKey)
+}
+
+// encryptReceivingKey 加密收款密钥用于存储
+func encryptReceivingKey(key string) (string, error) {
+	box, err := paymentSecretBox()
+	if err != nil {
+		return "", err
+	}
+	return box.Encrypt(key)
+}
+
+// decryptReceivingKey 解密存储的收款密钥
+// 历史数据为明文时原样返回，机主重新保存收款账户后转为加密存储
+func decryptReceivingKey(stored string) (string, error) {
+	if !secret.IsEncrypted(stored) {
+		return stored, nil
+	}
+	box, err := paymentSecretBox()
+	if err != nil {
+		return "", err
+	}
+	return box.Decrypt(stored)
+}
+
+// ownerPaymentAccount 根据机主收款配置构建支付账户，未配置商户号或密钥时返回 ErrPaymentAccountNotFound
+func ownerPaymentAccount(owner *models.MachineOwner) (*contracts.PaymentAccount, error) {
+	if owner == nil {
+		return nil, ErrPaymentAccountNotFound
+	}
+	receivingAccount := ptrToString(owner.ReceivingAccount)
+	storedKey := ptrToString(owner.ReceivingKey)
+	if receivingAccount == "" || storedKey == "" {
+		return nil, fmt.Errorf("%w: owner %s", ErrPaymentAccountNotFound, owner.ID)
+	}
+
+	receivingKey, err := decryptReceivingKey(storedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt receiving key of owner %s: %w", owner.ID, err)
+	}
+
+	merchantKey := ""
+	if stored := ptrToString(owner.MerchantPrivateKey); stored != "" {
+		if merchantKey, err = decryptReceivingKey(stored); err != nil {
+			return nil, fmt.Errorf("failed to decrypt merchant private key of owner %s: %w", owner.ID, err)
+		}
+	}
+
+	return &contracts.PaymentAccount{
+		ReceivingAccount:     receivingAccount,
+		ReceivingKey:         receivingKey,
+		ReceivingOrderPrefix: ptrToString(owner.ReceivingOrderPrefix),
+		ChannelCode:          ptrToString(owner.ChannelCode),
+		MerchantSerialNo:     ptrToString(owner.MerchantSerialNo),
+		MerchantPrivateKey:   merchantKey,
+	}, nil
+}
+
+// PaymentAccountServiceInterface 机主收款账户服务接口
+type PaymentAccountServiceInterface interface {
+	Get(ownerID string) (*contracts.OwnerPaymentAccountResponse, error)
+	Update(ownerID string, req contracts.UpdatePaymentAccountRequest) error
+	Test(ownerID string) (*contracts.TestPaymentAccountResponse, error)
+}
+
+// paymentAccountService 机主收款账户服务实现
+type paymentAccountService struct {
+	ownerRepo repositories.MachineOwnerRepositoryInterface
+	channels  *PaymentChannelRegistry
+}
+
+// NewPaymentAccountService 创建机主收款账户服务
+func NewPaymentAccountService(db *gorm.DB) PaymentAccountServiceInterface {
+	return &paymentAccountService{
+		ownerRepo: repositories.NewMachineOwnerRepository(db),
+		channels:  defaultPaymentChannelRegistry(),
+	}
+}
+
+// getOwner 获取机主，不存在时返回 ErrMachineOwnerNotFound
+func (s *paymentAccountService) getOwner(ownerID string) (*models.MachineOwner, error) {
+	owner, err := s.ownerRepo.GetByID(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if owner == nil {
+		return nil, ErrMachineOwnerNotFound
+	}
+	return owner, nil
+}
+
+// Get 获取机主收款账户
+func (s *paymentAccountService) Get(ownerID string) (*contracts.OwnerPaymentAccountResponse, error) {
+	owner, err := s.getOwner(ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &contracts.OwnerPaymentAccountResponse{
+		ChannelCode:          ptrToString(owner.ChannelCode),
+		ReceivingAccount:     ptrToString(owner.ReceivingAccount),
+		ReceivingOrderPrefix: ptrToString(owner.ReceivingOrderPrefix),
+		HasReceivingKey:      ptrToString(owner.ReceivingKey) != "",
+		MerchantSerialNo:     ptrToString(owner.MerchantSerialNo),
+		HasMerchantKey:       ptrToString(owner.MerchantPrivateKey) != "",
+	}, nil
+}
+
+// Update 设置机主收款账户，收款密钥与商户私钥加密后存储
+func (s *paymentAccountService) Update(ownerID string, req contracts.UpdatePaymentAccountRequest) error {
+	// 模拟渠道不校验回调，不允许机主选用
+	if req.ChannelCode == contracts.ChannelCodeMock {
+		return fmt.Errorf("%w: %s", ErrUnsupportedPaymentChannel, req.ChannelCode)
+	}
+	if _, err := s.channels.Get(req.ChannelCode); err != nil {
+		return err
+	}
+
+	owner, err := s.getOwner(ownerID)
+	if err != nil {
+		return err
+	}
+
+	storedKey := ptrToString(owner.ReceivingKey)
+	if req.ReceivingKey != "" {
+		if storedKey, err = encryptReceivingKey(req.ReceivingKey); err != nil {
+			return fmt.Errorf("failed to encrypt receiving key: %w", err)
+		}
+	} else if storedKey == "" {
+		return ErrReceivingKeyRequired
+	}
+
+	storedMerchantKey, err := mergeMerchantPrivateKey(ptrToString(owner.MerchantPrivateKey), req)
+	if err != nil {
+		return err
+	}
+
+	owner.ChannelCode = &req.ChannelCode
+	owner.ReceivingAccount = &req.ReceivingAccount
+	owner.ReceivingKey = &storedKey
+	owner.ReceivingOrderPrefix = &req.ReceivingOrderPrefix
+	owner.MerchantSerialNo = &req.MerchantSerialNo
+	owner.MerchantPrivateKey = &storedMerchantKey
+	return s.ownerRepo.UpdatePaymentAccount(owner)
+}
+
+// mergeMerchantPrivateKey 返回待存储的加密商户私钥，请求未提供私钥时保留原值
+// 证书序列号与私钥须同时配置或同时为空
+func mergeMerchantPrivateKey(stored string, req contracts.UpdatePaymentAccountRequest) (string, error) {
+	if req.MerchantPrivateKey != "" {
+		if _, err := wechatpay.ParsePrivateKey([]byte(req.MerchantPrivateKey)); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidMerchantCredentials, err)
+		}
+		encrypted, err := encryptReceivingKey(req.MerchantPrivateKey)
+		if err != nil {
+			return "", fmt.Errorf("failed to encrypt merchant private key: %w", err)
+		}
+		stored = encrypted
+	}
+	if req.MerchantSerialNo == "" {
+		// 清除证书序列号即不再使用机主自己的商户证书
+		stored = ""
+	} else if stored == "" {
+		return "", fmt.Errorf("%w: private key is required with serial no", ErrInvalidMerchantCredentials)
+	}
+	return stored, nil
+}
+
+// Test 使用已保存的收款账户向支付渠道发起校验请求
+func (s *paymentAccountService) Test(ownerID string) (*contracts.TestPaymentAccountResponse, error) {
+	owner, err := s.getOwner(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	account, err := ownerPaymentAccount(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	channelCode, err := ResolveChannelCode("", account)
+	if err != nil {
+		return nil, err
+	}
+	result := &contracts.TestPaymentAccountResponse{ChannelCode: channelCode}
+
+	var channel PaymentChannel = mockPaymentChannel{}
+	if !mockModeEnabled() {
+		if channel, err = s.channels.Get(channelCode); err != nil {
+			return nil, err
+		}
+	}
+	verifier, ok := channel.(PaymentAccountVerifier)
+	if !ok {
+		result.Message = "该支付渠道不支持校验收款账户"
+		return result, nil
+	}
+
+	if verifyErr := verifier.VerifyAccount(*account); verifyErr != nil {
+		result.Message = verifyErr.Error()
+		return result, nil
+	}
+	result.Success = true
+	result.Message = "收款账户校验通过"
+	return result, nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/pkg/secret"
+)
+
+const (
+	testReceivingKey     = "test_receiving_key"
+	testPaymentSecretKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+)
+
+// testOwnedMachine 归属测试机主 owner-001 的机器
+func testOwnedMachine() *models.Machine {
+	return &models.Machine{ID: "machine-001", MachineNo: stringPtr("VM001"), MachineOwnerId: stringPtr("owner-001")}
+}
+
+// newTestOwnerRepo 返回已配置加密收款密钥的测试机主 owner-001
+func newTestOwnerRepo(t *testing.T) *MockMachineOwnerRepository {
+	t.Setenv("PAYMENT_SECRET_KEY", testPaymentSecretKey)
+	encrypted, err := encryptReceivingKey(testReceivingKey)
+	require.NoError(t, err)
+
+	repo := &MockMachineOwnerRepository{}
+	repo.On("GetByID", "owner-001").Return(&models.MachineOwner{
+		ID:               "owner-001",
+		ReceivingAccount: stringPtr("merchant-001"),
+		ReceivingKey:     &encrypted,
+	}, nil)
+	return repo
+}
+
+func TestReceivingKeyEncryption(t *testing.T) {
+	t.Setenv("PAYMENT_SECRET_KEY", testPaymentSecretKey)
+
+	encrypted, err := encryptReceivingKey(testReceivingKey)
+	require.NoError(t, err)
+	assert.True(t, secret.IsEncrypted(encrypted))
+	assert.NotContains(t, encrypted, testReceivingKey)
+
+	decrypted, err := decryptReceivingKey(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, testReceivingKey, decrypted)
+
+	// 未配置加密密钥时无法加解密
+	t.Setenv("PAYMENT_SECRET_KEY", "")
+	_, err = encryptReceivingKey(testReceivingKey)
+	assert.ErrorIs(t, err, errPaymentSecretNotConfigured)
+	_, err = decryptReceivingKey(encrypted)
+	assert.ErrorIs(t, err, errPaymentSecretNotConfigured)
+}
+
+func TestPaymentAccountService_Get(t *testing.T) {
+	service := &paymentAccountService{ownerRepo: newTestOwnerRepo(t)}
+
+	account, err := service.Get("owner-001")
+	require.NoError(t, err)
+	assert.Equal(t, "merchant-001", account.ReceivingAccount)
+	assert.True(t, account.HasReceivingKey)
+
+	missing := &MockMachineOwnerRepository{}
+	missing.On("GetByID", "owner-404").Return(nil, nil)
+	service.ownerRepo = missing
+	_, err = service.Get("owner-404")
+	assert.ErrorIs(t, err, ErrMachineOwnerNotFound)
+}
+
+func TestPaymentAccountService_Update(t *testing.T) {
+	t.Setenv("PAYMENT_SECRET_KEY", testPaymentSecretKey)
+	mockOwnerRepo := &MockMachineOwnerRepository{}
+	service := &paymentAccountService{
+		ownerRepo: mockOwnerRepo,
+		channels:  NewPaymentChannelRegistry(newFuiouChannel(nil)),
+	}
+
+	mockOwnerRepo.On("GetByID", "owner-001").Return(&models.MachineOwner{ID: "owner-001"}, nil)
+	var saved *models.MachineOwner
+	mockOwnerRepo.On("UpdatePaymentAccount", mock.AnythingOfType("*models.MachineOwner")).
+		Run(func(args mock.Arguments) { saved = args.Get(0).(*models.MachineOwner) }).
+		Return(nil)
+
+	req := contracts.UpdatePaymentAccountRequest{
+		ChannelCode:          contracts.ChannelCodeFuiouMerchant,
+		ReceivingAccount:     "0002900F0001",
+		ReceivingOrderPrefix: "1066",
+	}
+
+	// 首次设置必须提供收款密钥
+	assert.ErrorIs(t, service.Update("owner-001", req), ErrReceivingKeyRequired)
+
+	req.ReceivingKey = testReceivingKey
+	require.NoError(t, service.Update("owner-001", req))
+	require.NotNil(t, saved)
+	assert.Equal(t, "0002900F0001", *saved.ReceivingAccount)
+	assert.Equal(t, contracts.ChannelCodeFuiouMerchant, *saved.ChannelCode)
+	assert.True(t, secret.IsEncrypted(*saved.ReceivingKey))
+
+	account, err := ownerPaymentAccount(saved)
+	require.NoError(t, err)
+	assert.Equal(t, testReceivingKey, account.ReceivingKey)
+
+	// 未注册的渠道与模拟渠道
+	req.ChannelCode = "unknown"
+	assert.ErrorIs(t, service.Update("owner-001", req), ErrUnsupportedPaymentChannel)
+	req.ChannelCode = contracts.ChannelCodeMock
+	assert.ErrorIs(t, service.Update("owner-001", req), ErrUnsupportedPaymentChannel)
+}
+
+func TestPaymentAccountService_Test(t *testing.T) {
+	os.Unsetenv("MOCK_MODE")
+	service := &paymentAccountService{
+		ownerRepo: newTestOwnerRepo(t),
+		channels:  NewPaymentChannelRegistry(newFuiouChannel(nil), mockPaymentChannel{}),
+	}
+
+	// 富友未配置机构参数时校验失败
+	result, err := service.Test("owner-001")
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Equal(t, contracts.ChannelCodeFuiouMerchant, result.ChannelCode)
+	assert.NotEmpty(t, result.Message)
+
+	os.Setenv("MOCK_MODE", "true")
+	defer os.Unsetenv("MOCK_MODE")
+	result, err = service.Test("owner-001")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+
+	unconfigured := &MockMachineOwnerRepository{}
+	unconfigured.On("GetByID", "owner-002").Return(&models.MachineOwner{ID: "owner-002"}, nil)
+	service.ownerRepo = unconfigured
+	_, err = service.Test("owner-002")
+	assert.ErrorIs(t, err, ErrPaymentAccountNotFound)
+}
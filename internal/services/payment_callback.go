@@ -0,0 +1,88 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/pkg/fuiou"
+)
+
+var (
+	// ErrInvalidCallbackSignature 回调签名无效
+	ErrInvalidCallbackSignature = errors.New("invalid callback signature")
+	// ErrCallbackAmountMismatch 回调金额与订单应付金额不一致
+	ErrCallbackAmountMismatch = errors.New("callback amount does not match order amount")
+)
+
+// CallbackSignParams 支付结果回调参与签名的字段
+func CallbackSignParams(req contracts.PaymentCallbackResultRequest) map[string]string {
+	return map[string]string{
+		"channelCode":    req.ChannelCode,
+		"transAmt":       strconv.Itoa(req.TransAmt),
+		"returnAmt":      strconv.Itoa(req.ReturnAmt),
+		"orderNo":        req.OrderNo,
+		"orderInfo":      req.OrderInfo,
+		"modeOfPayment":  strconv.Itoa(req.ModeOfPayment),
+		"channelOrderNo": req.ChannelOrderNo,
+		"paymentTime":    req.PaymentTime.Format(time.RFC3339),
+		"callbackType":   req.CallbackType,
+	}
+}
+
+// RefundCallbackSignParams 退款结果回调参与签名的字段
+func RefundCallbackSignParams(req contracts.RefundCallbackRequest) map[string]string {
+	return map[string]string{
+		"channelCode":     req.ChannelCode,
+		"orderNo":         req.OrderNo,
+		"refundNo":        req.RefundNo,
+		"channelRefundNo": req.ChannelRefundNo,
+		"refundAmt":       strconv.Itoa(req.RefundAmt),
+		"refundStatus":    req.RefundStatus,
+		"refundTime":      req.RefundTime.Format(time.RFC3339),
+	}
+}
+
+// SignCallback 使用机主收款密钥计算支付结果回调签名
+func SignCallback(req contracts.PaymentCallbackResultRequest, key string) string {
+	return fuiou.Sign(CallbackSignParams(req), key)
+}
+
+// SignRefundCallback 使用机主收款密钥计算退款结果回调签名
+func SignRefundCallback(req contracts.RefundCallbackRequest, key string) string {
+	return fuiou.Sign(RefundCallbackSignParams(req), key)
+}
+
+// verifyCallbackSign 校验支付结果回调签名，未配置收款密钥时一律拒绝
+func verifyCallbackSign(req contracts.PaymentCallbackResultRequest, key string) error {
+	return verifySignedParams(CallbackSignParams(req), req.Sign, key)
+}
+
+// verifyRefundCallbackSign 校验退款结果回调签名
+func verifyRefundCallbackSign(req contracts.RefundCallbackRequest, key string) error {
+	return verifySignedParams(RefundCallbackSignParams(req), req.Sign, key)
+}
+
+// verifySignedParams 使用收款密钥校验回调字段签名
+func verifySignedParams(params map[string]string, sign, key string) error {
+	if key == "" {
+		return fmt.Errorf("%w: receiving key is not configured", ErrInvalidCallbackSignature)
+	}
+	params["sign"] = sign
+	if !fuiou.Verify(params, key) {
+		return ErrInvalidCallbackSignature
+	}
+	return nil
+}
+
+// ReconcileCallbackAmount 核对回调金额(分)与订单应付金额
+func ReconcileCallbackAmount(order *models.Order, transAmt int) error {
+	expected := int(yuanToFen(order.PayAmount))
+	if transAmt != expected {
+		return fmt.Errorf("%w: callback %d, order %d", ErrCallbackAmountMismatch, transAmt, expected)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/ddteam/drink-master/internal/config"
+	"github.com/ddteam/drink-master/internal/contracts"
+)
+
+// ErrUnsupportedPaymentChannel 支付渠道未注册
+var ErrUnsupportedPaymentChannel = errors.New("unsupported payment channel")
+
+// PaymentChannel 支付渠道，每个实现对接一个第三方支付通道
+// 请求中的Ext1/Ext2/Ext3为机主收款账户参数，由各渠道自行解释
+type PaymentChannel interface {
+	// Code 渠道编码，与订单及机主配置的ChannelCode对应
+	Code() string
+	// Prepay 小程序预下单，返回wx.requestPayment所需参数
+	Prepay(req contracts.WeChatPayRequest) (*contracts.WeChatPayResponse, error)
+	// Query 查询订单支付状态
+	Query(req contracts.TranQueryRequest) (*contracts.TranQueryResponse, error)
+	// Refund 发起退款，同一退款单号重复提交不会重复退款
+	Refund(req contracts.PaymentRefundRequest) (*contracts.PaymentRefundResponse, error)
+	// Close 关闭未支付订单
+	Close(req contracts.PaymentCloseRequest) (*contracts.PaymentCloseResponse, error)
+	// VerifyCallback 校验支付结果回调是否来自本渠道
+	VerifyCallback(account contracts.PaymentAccount, req contracts.PaymentCallbackResultRequest) error
+	// VerifyRefundCallback 校验退款结果回调是否来自本渠道
+	VerifyRefundCallback(account contracts.PaymentAccount, req contracts.RefundCallbackRequest) error
+}
+
+// PaymentNotifyParser 支持原生异步通知的支付渠道，验签解密后转换为统一的支付结果回调
+// account 为通知地址所指商户的收款账户，为空时由渠道自行匹配已加载的商户
+type PaymentNotifyParser interface {
+	ParseNotify(
+		account *contracts.PaymentAccount, header http.Header, body []byte,
+	) (*contracts.PaymentCallbackResultRequest, error)
+}
+
+// RefundNotifyParser 支持原生退款结果通知的支付渠道，验签解密后转换为统一的退款结果回调
+type RefundNotifyParser interface {
+	ParseRefundNotify(
+		account *contracts.PaymentAccount, header http.Header, body []byte,
+	) (*contracts.RefundCallbackRequest, error)
+}
+
+// PaymentAccountVerifier 支持校验机主收款账户参数的支付渠道
+type PaymentAccountVerifier interface {
+	VerifyAccount(account contracts.PaymentAccount) error
+}
+
+// PaymentChannelRegistry 按渠道编码索引的支付渠道注册表
+type PaymentChannelRegistry struct {
+	mu       sync.RWMutex
+	channels map[string]PaymentChannel
+}
+
+// NewPaymentChannelRegistry 创建支付渠道注册表
+func NewPaymentChannelRegistry(channels ...PaymentChannel) *PaymentChannelRegistry {
+	registry := &PaymentChannelRegistry{channels: make(map[string]PaymentChannel)}
+	for _, channel := range channels {
+		registry.Register(channel)
+	}
+	return registry
+}
+
+// Register 注册支付渠道，相同编码的渠道会被替换
+func (r *PaymentChannelRegistry) Register(channel PaymentChannel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.channels[channel.Code()] = channel
+}
+
+// Get 根据渠道编码获取支付渠道
+func (r *PaymentChannelRegistry) Get(code string) (PaymentChannel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	channel, ok := r.channels[code]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedPaymentChannel, code)
+	}
+	return channel, nil
+}
+
+// Codes 返回已注册的渠道编码
+func (r *PaymentChannelRegistry) Codes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	codes := make([]string, 0, len(r.channels))
+	for code := range r.channels {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+var (
+	defaultChannelsOnce sync.Once
+	defaultChannels     *PaymentChannelRegistry
+)
+
+// mockModeEnabled 是否启用模拟支付（MOCK_MODE=true），仅用于开发联调
+func mockModeEnabled() bool {
+	return os.Getenv("MOCK_MODE") == "true"
+}
+
+// defaultPaymentChannelRegistry 返回进程级支付渠道注册表，首次使用时根据环境配置创建
+// 各服务实例共享渠道客户端，使预下单时加载的商户在异步通知时同样可用
+// 模拟渠道不校验回调，仅在 MOCK_MODE 下注册
+func defaultPaymentChannelRegistry() *PaymentChannelRegistry {
+	defaultChannelsOnce.Do(func() {
+		defaultChannels = NewPaymentChannelRegistry(
+			newFuiouChannel(newFuiouClient()),
+			newWeChatPayChannel(config.NewWeChatPayConfig()),
+		)
+		if mockModeEnabled() {
+			defaultChannels.Register(mockPaymentChannel{})
+		}
+	})
+	return defaultChannels
+}
+
+// ResolveChannelCode 确定订单使用的支付渠道
+// 订单已绑定渠道时沿用订单渠道，否则使用收款账户配置的渠道，均未配置时默认富友
+// 非 MOCK_MODE 下模拟渠道及未注册的渠道返回 ErrUnsupportedPaymentChannel
+func ResolveChannelCode(orderChannel string, account *contracts.PaymentAccount) (string, error) {
+	code := contracts.ChannelCodeFuiouMerchant
+	switch {
+	case orderChannel != "":
+		code = orderChannel
+	case account != nil && account.ChannelCode != "":
+		code = account.ChannelCode
+	}
+
+	if mockModeEnabled() {
+		return code, nil
+	}
+	if code == contracts.ChannelCodeMock {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedPaymentChannel, code)
+	}
+	if _, err := defaultPaymentChannelRegistry().Get(code); err != nil {
+		return "", err
+	}
+	return code, nil
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+// MockMachineOwnerRepository 机主仓储Mock
+type MockMachineOwnerRepository struct {
+	mock.Mock
+}
+
+func (m *MockMachineOwnerRepository) GetByID(id string) (*models.MachineOwner, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MachineOwner), args.Error(1)
+}
+
+func (m *MockMachineOwnerRepository) GetByMerchant(channelCode, receivingAccount string) (*models.MachineOwner, error) {
+	args := m.Called(channelCode, receivingAccount)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MachineOwner), args.Error(1)
+}
+
+func (m *MockMachineOwnerRepository) UpdatePaymentAccount(owner *models.MachineOwner) error {
+	args := m.Called(owner)
+	return args.Error(0)
+}
+
+func TestPaymentChannelRegistry(t *testing.T) {
+	registry := NewPaymentChannelRegistry(newFuiouChannel(nil), mockPaymentChannel{})
+	assert.Equal(t, []string{contracts.ChannelCodeFuiouMerchant, contracts.ChannelCodeMock}, registry.Codes())
+
+	channel, err := registry.Get(contracts.ChannelCodeMock)
+	require.NoError(t, err)
+	assert.Equal(t, contracts.ChannelCodeMock, channel.Code())
+
+	_, err = registry.Get("unknown")
+	assert.ErrorIs(t, err, ErrUnsupportedPaymentChannel)
+}
+
+func TestResolveChannelCode(t *testing.T) {
+	t.Setenv("MOCK_MODE", "")
+	wechatAccount := &contracts.PaymentAccount{ChannelCode: contracts.ChannelCodeWeChatPayV3}
+
+	resolve := func(orderChannel string, account *contracts.PaymentAccount) string {
+		code, err := ResolveChannelCode(orderChannel, account)
+		require.NoError(t, err)
+		return code
+	}
+	assert.Equal(t, contracts.ChannelCodeFuiouMerchant, resolve(contracts.ChannelCodeFuiouMerchant, wechatAccount))
+	assert.Equal(t, contracts.ChannelCodeWeChatPayV3, resolve("", wechatAccount))
+	assert.Equal(t, contracts.ChannelCodeFuiouMerchant, resolve("", &contracts.PaymentAccount{}))
+	assert.Equal(t, contracts.ChannelCodeFuiouMerchant, resolve("", nil))
+
+	// 非 MOCK_MODE 下拒绝模拟渠道及未注册的渠道，避免伪造回调被模拟渠道放行
+	mockAccount := &contracts.PaymentAccount{ChannelCode: contracts.ChannelCodeMock}
+	_, err := ResolveChannelCode("", mockAccount)
+	assert.ErrorIs(t, err, ErrUnsupportedPaymentChannel)
+	_, err = ResolveChannelCode(contracts.ChannelCodeMock, nil)
+	assert.ErrorIs(t, err, ErrUnsupportedPaymentChannel)
+	_, err = ResolveChannelCode("unknown", nil)
+	assert.ErrorIs(t, err, ErrUnsupportedPaymentChannel)
+
+	t.Setenv("MOCK_MODE", "true")
+	assert.Equal(t, contracts.ChannelCodeMock, resolve("", mockAccount))
+}
+
+func TestPaymentService_DispatchByChannel(t *testing.T) {
+	os.Unsetenv("MOCK_MODE")
+	service := &paymentService{channels: NewPaymentChannelRegistry(newFuiouChannel(nil), mockPaymentChannel{})}
+
+	resp, err := service.TranQuery(contracts.TranQueryRequest{ChannelCode: contracts.ChannelCodeMock, OrderNo: "ORD001"})
+	require.NoError(t, err)
+	assert.Equal(t, "mock_transaction_id_ORD001", resp.TransactionId)
+
+	closeResp, err := service.Close(contracts.PaymentCloseRequest{ChannelCode: contracts.ChannelCodeMock, OrderNo: "ORD001"})
+	require.NoError(t, err)
+	assert.True(t, closeResp.IsSuccess)
+
+	_, err = service.Close(contracts.PaymentCloseRequest{ChannelCode: contracts.ChannelCodeFuiouMerchant})
+	assert.ErrorIs(t, err, errFuiouNotConfigured)
+
+	err = service.VerifyCallback(contracts.ChannelCodeFuiouMerchant, contracts.PaymentAccount{},
+		contracts.PaymentCallbackResultRequest{ChannelCode: contracts.ChannelCodeMock})
+	assert.Error(t, err)
+
+	_, err = service.Refund(contracts.PaymentRefundRequest{ChannelCode: "unknown"})
+	assert.ErrorIs(t, err, ErrUnsupportedPaymentChannel)
+}
+
+func TestPaymentService_GetPaymentAccount_OwnerChannel(t *testing.T) {
+	t.Setenv("PAYMENT_SECRET_KEY", testPaymentSecretKey)
+	mockMachineRepo := &MockMachineRepository{}
+	mockOwnerRepo := &MockMachineOwnerRepository{}
+	service := &paymentService{machineRepo: mockMachineRepo, ownerRepo: mockOwnerRepo}
+
+	mockMachineRepo.On("GetByID", "machine-001").Return(testOwnedMachine(), nil)
+	mockOwnerRepo.On("GetByID", "owner-001").Return(&models.MachineOwner{
+		ID:                   "owner-001",
+		ReceivingAccount:     stringPtr("merchant-001"),
+		ReceivingKey:         stringPtr("legacy_plain_key"),
+		ReceivingOrderPrefix: stringPtr("1066"),
+		ChannelCode:          stringPtr(contracts.ChannelCodeMock),
+	}, nil)
+
+	account, err := service.GetPaymentAccount("machine-001")
+	require.NoError(t, err)
+	assert.Equal(t, contracts.ChannelCodeMock, account.ChannelCode)
+	// 历史明文密钥仍可使用
+	assert.Equal(t, "legacy_plain_key", account.ReceivingKey)
+	assert.Equal(t, "1066", account.ReceivingOrderPrefix)
+
+	mockMachineRepo.AssertExpectations(t)
+	mockOwnerRepo.AssertExpectations(t)
+}
+
+func TestPaymentService_MerchantAccount(t *testing.T) {
+	t.Setenv("PAYMENT_SECRET_KEY", testPaymentSecretKey)
+	mockOwnerRepo := &MockMachineOwnerRepository{}
+	service := &paymentService{ownerRepo: mockOwnerRepo}
+
+	mockOwnerRepo.On("GetByMerchant", contracts.ChannelCodeWeChatPayV3, "1900000002").Return(&models.MachineOwner{
+		ID:                   "owner-001",
+		ReceivingAccount:     stringPtr("1900000002"),
+		ReceivingKey:         stringPtr("legacy_plain_key"),
+		ReceivingOrderPrefix: stringPtr("1066"),
+		ChannelCode:          stringPtr(contracts.ChannelCodeWeChatPayV3),
+		MerchantSerialNo:     stringPtr("SERIAL002"),
+	}, nil)
+	mockOwnerRepo.On("GetByMerchant", contracts.ChannelCodeWeChatPayV3, "1900000001").Return(nil, nil)
+
+	account, err := service.merchantAccount(contracts.ChannelCodeWeChatPayV3, "1900000002")
+	require.NoError(t, err)
+	require.NotNil(t, account)
+	assert.Equal(t, "1900000002", account.ReceivingAccount)
+	assert.Equal(t, "SERIAL002", account.MerchantSerialNo)
+
+	// 部署商户未被机主使用时由渠道匹配已加载的商户
+	account, err = service.merchantAccount(contracts.ChannelCodeWeChatPayV3, "1900000001")
+	require.NoError(t, err)
+	assert.Nil(t, account)
+
+	// 旧通知地址不带商户号
+	account, err = service.merchantAccount(contracts.ChannelCodeWeChatPayV3, "")
+	require.NoError(t, err)
+	assert.Nil(t, account)
+
+	mockOwnerRepo.AssertExpectations(t)
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ddteam/drink-master/internal/config"
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/pkg/fuiou"
+)
+
+// errFuiouNotConfigured 未配置富友机构参数
+var errFuiouNotConfigured = errors.New("fuiou payment channel is not configured")
+
+// newFuiouClient 根据环境配置创建富友客户端，未配置时返回nil
+func newFuiouClient() *fuiou.Client {
+	cfg := config.NewFuiouConfig()
+	if !cfg.Enabled() {
+		return nil
+	}
+	return fuiou.NewClient(fuiou.Config{
+		BaseURL:  cfg.BaseURL,
+		InsCd:    cfg.InsCd,
+		TermID:   cfg.TermID,
+		SubAppID: cfg.SubAppID,
+	})
+}
+
+// fuiouChannel 富友聚合支付渠道
+type fuiouChannel struct {
+	client *fuiou.Client
+}
+
+// newFuiouChannel 创建富友支付渠道，client为nil时各接口返回未配置错误
+func newFuiouChannel(client *fuiou.Client) *fuiouChannel {
+	return &fuiouChannel{client: client}
+}
+
+// Code 渠道编码
+func (c *fuiouChannel) Code() string {
+	return contracts.ChannelCodeFuiouMerchant
+}
+
+// fuiouAccount Ext1/Ext2/Ext3 分别对应富友商户号、签名密钥与订单前缀
+func fuiouAccount(ext1, ext2, ext3 string) fuiou.Account {
+	return fuiou.Account{MerchantCode: ext1, Key: ext2, OrderPrefix: ext3}
+}
+
+// Prepay 通过富友发起小程序预下单
+func (c *fuiouChannel) Prepay(req contracts.WeChatPayRequest) (*contracts.WeChatPayResponse, error) {
+	if c.client == nil {
+		return nil, errFuiouNotConfigured
+	}
+
+	resp, err := c.client.PreCreate(fuiouAccount(req.Ext1, req.Ext2, req.Ext3), fuiou.PreCreateRequest{
+		OrderNo:     req.OrderNo,
+		Amount:      int64(req.TransAmt),
+		Description: req.OrderInfo,
+		OpenID:      req.OpenId,
+		NotifyURL:   req.NotifyUrl,
+		Attach:      req.Attach,
+	})
+	if err != nil {
+		var apiErr *fuiou.APIError
+		if errors.As(err, &apiErr) {
+			return &contracts.WeChatPayResponse{IsSuccess: false, Message: apiErr.Message}, nil
+		}
+		return nil, fmt.Errorf("failed to create fuiou pre-order: %w", err)
+	}
+
+	return &contracts.WeChatPayResponse{
+		IsSuccess: true,
+		AppId:     resp.AppID,
+		TimeStamp: resp.TimeStamp,
+		NonceStr:  resp.NonceStr,
+		Package:   resp.Package,
+		SignType:  resp.SignType,
+		PaySign:   resp.PaySign,
+		Message:   "支付信息获取成功",
+	}, nil
+}
+
+// Query 通过富友查询订单支付状态
+func (c *fuiouChannel) Query(req contracts.TranQueryRequest) (*contracts.TranQueryResponse, error) {
+	if c.client == nil {
+		return nil, errFuiouNotConfigured
+	}
+
+	resp, err := c.client.Query(fuiouAccount(req.Ext1, req.Ext2, req.Ext3), req.OrderNo)
+	if err != nil {
+		var apiErr *fuiou.APIError
+		if errors.As(err, &apiErr) {
+			return &contracts.TranQueryResponse{
+				IsSuccess: false,
+				Message:   apiErr.Message,
+				ErrorCode: apiErr.Code,
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to query fuiou order: %w", err)
+	}
+
+	paymentTime := resp.FinishedAt
+	if paymentTime.IsZero() {
+		paymentTime = time.Now()
+	}
+
+	return &contracts.TranQueryResponse{
+		IsSuccess:     true,
+		PaymentStatus: fuiouPaymentStatus(resp.TransStat),
+		TransactionId: resp.TransactionID,
+		PaymentTime:   paymentTime,
+		Message:       "查询成功",
+	}, nil
+}
+
+// Refund 通过富友发起退款
+func (c *fuiouChannel) Refund(req contracts.PaymentRefundRequest) (*contracts.PaymentRefundResponse, error) {
+	if c.client == nil {
+		return nil, errFuiouNotConfigured
+	}
+
+	resp, err := c.client.Refund(fuiouAccount(req.Ext1, req.Ext2, req.Ext3), fuiou.RefundRequest{
+		OrderNo:      req.OrderNo,
+		RefundNo:     req.RefundNo,
+		TotalAmount:  int64(req.TotalAmt),
+		RefundAmount: int64(req.RefundAmt),
+	})
+	if err != nil {
+		var apiErr *fuiou.APIError
+		if errors.As(err, &apiErr) {
+			return &contracts.PaymentRefundResponse{
+				IsSuccess: false,
+				Message:   apiErr.Message,
+				ErrorCode: apiErr.Code,
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to refund fuiou order: %w", err)
+	}
+
+	return &contracts.PaymentRefundResponse{
+		IsSuccess:       true,
+		ChannelRefundNo: resp.RefundID,
+		Message:         "退款成功",
+	}, nil
+}
+
+// Close 通过富友关闭未支付订单
+func (c *fuiouChannel) Close(req contracts.PaymentCloseRequest) (*contracts.PaymentCloseResponse, error) {
+	if c.client == nil {
+		return nil, errFuiouNotConfigured
+	}
+
+	if err := c.client.Close(fuiouAccount(req.Ext1, req.Ext2, req.Ext3), req.OrderNo); err != nil {
+		var apiErr *fuiou.APIError
+		if errors.As(err, &apiErr) {
+			return &contracts.PaymentCloseResponse{
+				IsSuccess: false,
+				Message:   apiErr.Message,
+				ErrorCode: apiErr.Code,
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to close fuiou order: %w", err)
+	}
+
+	return &contracts.PaymentCloseResponse{IsSuccess: true, Message: "关单成功"}, nil
+}
+
+// VerifyCallback 校验回调渠道与订单渠道一致，并使用机主收款密钥验签
+func (c *fuiouChannel) VerifyCallback(
+	account contracts.PaymentAccount, req contracts.PaymentCallbackResultRequest,
+) error {
+	if req.ChannelCode != c.Code() {
+		return fmt.Errorf("callback channel %q does not match order channel %q", req.ChannelCode, c.Code())
+	}
+	return verifyCallbackSign(req, account.ReceivingKey)
+}
+
+// VerifyRefundCallback 校验退款回调渠道与订单渠道一致，并使用机主收款密钥验签
+func (c *fuiouChannel) VerifyRefundCallback(
+	account contracts.PaymentAccount, req contracts.RefundCallbackRequest,
+) error {
+	if req.ChannelCode != c.Code() {
+		return fmt.Errorf("callback channel %q does not match order channel %q", req.ChannelCode, c.Code())
+	}
+	return verifyRefundCallbackSign(req, account.ReceivingKey)
+}
+
+// fuiouPaymentStatus 富友交易状态转换为统一支付状态
+func fuiouPaymentStatus(transStat string) string {
+	switch transStat {
+	case fuiou.TransStatSuccess, fuiou.TransStatRefund:
+		// 转入退款说明订单曾支付成功
+		return contracts.PaymentStatusSuccess
+	case fuiou.TransStatNotPay, fuiou.TransStatUserPaying:
+		return contracts.PaymentStatusPaying
+	case fuiou.TransStatClosed, fuiou.TransStatRevoked:
+		return contracts.PaymentStatusCancel
+	case fuiou.TransStatPayError:
+		return contracts.PaymentStatusFailure
+	default:
+		return contracts.PaymentStatusException
+	}
+}
+
+// VerifyAccount 以不存在的订单号查询，校验富友商户号与签名密钥是否匹配
+func (c *fuiouChannel) VerifyAccount(account contracts.PaymentAccount) error {
+	if c.client == nil {
+		return errFuiouNotConfigured
+	}
+	return c.client.VerifyAccount(
+		fuiouAccount(account.ReceivingAccount, account.ReceivingKey, account.ReceivingOrderPrefix),
+	)
+}
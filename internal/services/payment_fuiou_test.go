@@ -0,0 +1,179 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/pkg/fuiou"
+)
+
+// newFuiouStandIn 启动富友接口替身，按路径返回签名后的响应字段
+func newFuiouStandIn(t *testing.T, key string, responses map[string]map[string]string) *fuiou.Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fields, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if fields["result_code"] == fuiou.ResultCodeSuccess {
+			fields["sign"] = fuiou.Sign(fields, key)
+		}
+
+		var b strings.Builder
+		b.WriteString("<xml>")
+		for name, value := range fields {
+			fmt.Fprintf(&b, "<%s>%s</%s>", name, value, name)
+		}
+		b.WriteString("</xml>")
+		_, _ = w.Write([]byte(b.String()))
+	}))
+	t.Cleanup(server.Close)
+
+	return fuiou.NewClient(fuiou.Config{BaseURL: server.URL, InsCd: "08A9999999", TermID: "88888888"})
+}
+
+func TestPaymentService_WeChatPay_Fuiou(t *testing.T) {
+	client := newFuiouStandIn(t, "test_key", map[string]map[string]string{
+		"/wxPreCreate": {
+			"result_code":   fuiou.ResultCodeSuccess,
+			"sdk_appid":     "wx_app",
+			"sdk_timestamp": "1700000000",
+			"sdk_noncestr":  "nonce",
+			"sdk_package":   "prepay_id=wx001",
+			"sdk_signtype":  "RSA",
+			"sdk_paysign":   "paysign",
+		},
+	})
+	service := &paymentService{channels: NewPaymentChannelRegistry(newFuiouChannel(client))}
+
+	resp, err := service.WeChatPay(contracts.WeChatPayRequest{
+		Ext1:        "merchant",
+		Ext2:        "test_key",
+		Ext3:        "1066",
+		ChannelCode: contracts.ChannelCodeFuiouMerchant,
+		OrderNo:     "ORD001",
+		OpenId:      "openid",
+		OrderInfo:   "拿铁咖啡",
+		TransAmt:    1580,
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.IsSuccess)
+	assert.Equal(t, "wx_app", resp.AppId)
+	assert.Equal(t, "prepay_id=wx001", resp.Package)
+	assert.Equal(t, "paysign", resp.PaySign)
+}
+
+func TestPaymentService_TranQuery_Fuiou(t *testing.T) {
+	client := newFuiouStandIn(t, "test_key", map[string]map[string]string{
+		"/commonQuery": {
+			"result_code":         fuiou.ResultCodeSuccess,
+			"trans_stat":          fuiou.TransStatSuccess,
+			"transaction_id":      "4200000001",
+			"reserved_txn_fin_ts": "20250812153000",
+		},
+	})
+	service := &paymentService{channels: NewPaymentChannelRegistry(newFuiouChannel(client))}
+
+	resp, err := service.TranQuery(contracts.TranQueryRequest{
+		Ext1:        "merchant",
+		Ext2:        "test_key",
+		Ext3:        "1066",
+		ChannelCode: contracts.ChannelCodeFuiouMerchant,
+		OrderNo:     "ORD001",
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.IsSuccess)
+	assert.Equal(t, contracts.PaymentStatusSuccess, resp.PaymentStatus)
+	assert.Equal(t, "4200000001", resp.TransactionId)
+	assert.Equal(t, 2025, resp.PaymentTime.Year())
+}
+
+func TestPaymentService_TranQuery_FuiouRejected(t *testing.T) {
+	client := newFuiouStandIn(t, "test_key", map[string]map[string]string{
+		"/commonQuery": {"result_code": "030010", "result_msg": "order not found"},
+	})
+	service := &paymentService{channels: NewPaymentChannelRegistry(newFuiouChannel(client))}
+
+	resp, err := service.TranQuery(contracts.TranQueryRequest{
+		Ext1: "merchant", Ext2: "test_key", Ext3: "1066",
+		ChannelCode: contracts.ChannelCodeFuiouMerchant, OrderNo: "ORD404",
+	})
+	require.NoError(t, err)
+	assert.False(t, resp.IsSuccess)
+	assert.Equal(t, "030010", resp.ErrorCode)
+}
+
+func TestPaymentService_Fuiou_NotConfigured(t *testing.T) {
+	service := &paymentService{channels: NewPaymentChannelRegistry(newFuiouChannel(nil))}
+
+	_, err := service.WeChatPay(contracts.WeChatPayRequest{ChannelCode: contracts.ChannelCodeFuiouMerchant})
+	assert.ErrorIs(t, err, errFuiouNotConfigured)
+
+	_, err = service.TranQuery(contracts.TranQueryRequest{ChannelCode: "unknown"})
+	assert.EqualError(t, err, "unsupported payment channel: unknown")
+}
+
+func TestFuiouChannel_Close(t *testing.T) {
+	client := newFuiouStandIn(t, "test_key", map[string]map[string]string{
+		"/closeorder": {"result_code": fuiou.ResultCodeSuccess},
+	})
+	channel := newFuiouChannel(client)
+
+	resp, err := channel.Close(contracts.PaymentCloseRequest{
+		Ext1: "merchant", Ext2: "test_key", Ext3: "1066",
+		ChannelCode: contracts.ChannelCodeFuiouMerchant, OrderNo: "ORD001",
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.IsSuccess)
+}
+
+func TestFuiouChannel_VerifyCallback(t *testing.T) {
+	channel := newFuiouChannel(nil)
+	account := contracts.PaymentAccount{ReceivingAccount: "merchant", ReceivingKey: "test_key"}
+
+	req := contracts.PaymentCallbackResultRequest{
+		ChannelCode:    contracts.ChannelCodeFuiouMerchant,
+		TransAmt:       1580,
+		OrderNo:        "ORD001",
+		ChannelOrderNo: "4200000001",
+		PaymentTime:    time.Date(2025, 8, 12, 15, 30, 0, 0, time.UTC),
+	}
+	req.Sign = SignCallback(req, "test_key")
+	assert.NoError(t, channel.VerifyCallback(account, req))
+
+	// 篡改金额后验签失败
+	tampered := req
+	tampered.TransAmt = 1
+	assert.ErrorIs(t, channel.VerifyCallback(account, tampered), ErrInvalidCallbackSignature)
+
+	// 其他密钥签名或未签名均拒绝
+	forged := req
+	forged.Sign = SignCallback(req, "other_key")
+	assert.ErrorIs(t, channel.VerifyCallback(account, forged), ErrInvalidCallbackSignature)
+	forged.Sign = ""
+	assert.ErrorIs(t, channel.VerifyCallback(account, forged), ErrInvalidCallbackSignature)
+	assert.ErrorIs(t, channel.VerifyCallback(contracts.PaymentAccount{}, req), ErrInvalidCallbackSignature)
+
+	mismatched := req
+	mismatched.ChannelCode = contracts.ChannelCodeMock
+	assert.Error(t, channel.VerifyCallback(account, mismatched))
+}
+
+func TestFuiouPaymentStatus(t *testing.T) {
+	assert.Equal(t, contracts.PaymentStatusSuccess, fuiouPaymentStatus(fuiou.TransStatSuccess))
+	assert.Equal(t, contracts.PaymentStatusSuccess, fuiouPaymentStatus(fuiou.TransStatRefund))
+	assert.Equal(t, contracts.PaymentStatusPaying, fuiouPaymentStatus(fuiou.TransStatUserPaying))
+	assert.Equal(t, contracts.PaymentStatusCancel, fuiouPaymentStatus(fuiou.TransStatClosed))
+	assert.Equal(t, contracts.PaymentStatusFailure, fuiouPaymentStatus(fuiou.TransStatPayError))
+	assert.Equal(t, contracts.PaymentStatusException, fuiouPaymentStatus("UNKNOWN"))
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+)
+
+// mockPaymentChannel 模拟支付渠道，用于开发联调及演示机器，所有请求均直接成功
+type mockPaymentChannel struct{}
+
+// Code 渠道编码
+func (mockPaymentChannel) Code() string {
+	return contracts.ChannelCodeMock
+}
+
+// Prepay 返回模拟的支付参数
+func (mockPaymentChannel) Prepay(req contracts.WeChatPayRequest) (*contracts.WeChatPayResponse, error) {
+	return &contracts.WeChatPayResponse{
+		IsSuccess: true,
+		AppId:     "mock_app_id",
+		TimeStamp: fmt.Sprintf("%d", time.Now().Unix()),
+		NonceStr:  "mock_nonce_str",
+		Package:   "prepay_id=mock_prepay_id",
+		SignType:  "RSA",
+		PaySign:   "mock_pay_sign",
+		Message:   "支付信息获取成功",
+	}, nil
+}
+
+// Query 返回模拟的支付成功结果
+func (mockPaymentChannel) Query(req contracts.TranQueryRequest) (*contracts.TranQueryResponse, error) {
+	return &contracts.TranQueryResponse{
+		IsSuccess:     true,
+		PaymentStatus: contracts.PaymentStatusSuccess,
+		TransactionId: "mock_transaction_id_" + req.OrderNo,
+		PaymentTime:   time.Now(),
+		Message:       "查询成功",
+	}, nil
+}
+
+// Refund 返回模拟的退款成功结果
+func (mockPaymentChannel) Refund(req contracts.PaymentRefundRequest) (*contracts.PaymentRefundResponse, error) {
+	return &contracts.PaymentRefundResponse{
+		IsSuccess:       true,
+		ChannelRefundNo: "mock_refund_id_" + req.RefundNo,
+		Message:         "退款成功",
+	}, nil
+}
+
+// Close 返回模拟的关单成功结果
+func (mockPaymentChannel) Close(req contracts.PaymentCloseRequest) (*contracts.PaymentCloseResponse, error) {
+	return &contracts.PaymentCloseResponse{IsSuccess: true, Message: "关单成功"}, nil
+}
+
+// VerifyCallback 模拟渠道不校验回调
+func (mockPaymentChannel) VerifyCallback(
+	account contracts.PaymentAccount, req contracts.PaymentCallbackResultRequest,
+) error {
+	return nil
+}
+
+// VerifyRefundCallback 模拟渠道不校验退款回调
+func (mockPaymentChannel) VerifyRefundCallback(
+	account contracts.PaymentAccount, req contracts.RefundCallbackRequest,
+) error {
+	return nil
+}
+
+// VerifyAccount 模拟渠道不校验收款账户
+func (mockPaymentChannel) VerifyAccount(account contracts.PaymentAccount) error {
+	return nil
+}
+
+// mockPaymentAccount 模拟收款账户
+func mockPaymentAccount() *contracts.PaymentAccount {
+	return &contracts.PaymentAccount{
+		ReceivingAccount: "mock_merchant",
+		ReceivingKey:     "mock_key",
+		ChannelCode:      contracts.ChannelCodeMock,
+	}
+}
@@ -7,27 +7,47 @@ import (
 	"os"
 	"time"
 
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 
 	"github.com/ddteam/drink-master/internal/contracts"
 	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
 	"github.com/ddteam/drink-master/internal/repositories"
 )
 
+// ErrOrderNotWaitPay 订单已不是待支付状态（已支付、已作废或已退款）
+var ErrOrderNotWaitPay = errors.New("order is not in wait pay status")
+
 // PaymentServiceInterface 支付服务接口
 type PaymentServiceInterface interface {
 	WeChatPay(req contracts.WeChatPayRequest) (*contracts.WeChatPayResponse, error)
 	TranQuery(req contracts.TranQueryRequest) (*contracts.TranQueryResponse, error)
+	Refund(req contracts.PaymentRefundRequest) (*contracts.PaymentRefundResponse, error)
+	Close(req contracts.PaymentCloseRequest) (*contracts.PaymentCloseResponse, error)
+	VerifyCallback(
+		channelCode string, account contracts.PaymentAccount, req contracts.PaymentCallbackResultRequest,
+	) error
+	ParseNotify(
+		channelCode, merchantID string, header http.Header, body []byte,
+	) (*contracts.PaymentCallbackResultRequest, error)
+	VerifyRefundCallback(channelCode string, account contracts.PaymentAccount, req contracts.RefundCallbackRequest) error
+	ParseRefundNotify(
+		channelCode, merchantID string, header http.Header, body []byte,
+	) (*contracts.RefundCallbackRequest, error)
 	GetPaymentAccount(machineID string) (*contracts.PaymentAccount, error)
+	BindOrderChannel(orderID string, channelCode string) error
 	PayOrder(req contracts.PayOrderRequest) error
 	InvalidOrder(req contracts.InvalidOrderRequest) error
-	ProcessPaymentCallback(req contracts.PaymentCallbackRequest) (*contracts.PaymentCallbackResponse, error)
 }
 
 // paymentService 支付服务实现
 type paymentService struct {
 	orderRepo   repositories.OrderRepository
 	machineRepo repositories.MachineRepositoryInterface
+	ownerRepo   repositories.MachineOwnerRepositoryInterface
+	refundRepo  repositories.RefundRecordRepository
+	channels    *PaymentChannelRegistry
 	httpClient  *http.Client
 }
 
@@ -36,39 +56,140 @@ func NewPaymentService(db *gorm.DB) PaymentServiceInterface {
 	return &paymentService{
 		orderRepo:   repositories.NewOrderRepository(db),
 		machineRepo: repositories.NewMachineRepository(db),
+		ownerRepo:   repositories.NewMachineOwnerRepository(db),
+		refundRepo:  repositories.NewRefundRecordRepository(db),
+		channels:    defaultPaymentChannelRegistry(),
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 	}
 }
 
+// channel 获取请求对应的支付渠道，MOCK_MODE 下统一使用模拟渠道
+func (s *paymentService) channel(code string) (PaymentChannel, error) {
+	if mockModeEnabled() {
+		return mockPaymentChannel{}, nil
+	}
+	if s.channels == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedPaymentChannel, code)
+	}
+	return s.channels.Get(code)
+}
+
 // WeChatPay 发起微信支付
 func (s *paymentService) WeChatPay(req contracts.WeChatPayRequest) (*contracts.WeChatPayResponse, error) {
-	// 在实际生产环境中，这里应该调用真实的微信支付接口
-	// 目前使用mock实现，返回模拟的支付信息
-
-	if os.Getenv("MOCK_MODE") == "true" {
-		return s.mockWeChatPay(req)
+	channel, err := s.channel(req.ChannelCode)
+	if err != nil {
+		return nil, err
 	}
-
-	// TODO: 实现真实的微信支付调用
-	return s.mockWeChatPay(req)
+	return channel.Prepay(req)
 }
 
 // TranQuery 查询支付状态
 func (s *paymentService) TranQuery(req contracts.TranQueryRequest) (*contracts.TranQueryResponse, error) {
-	// 在实际生产环境中，这里应该调用真实的支付查询接口
-	// 目前使用mock实现
+	channel, err := s.channel(req.ChannelCode)
+	if err != nil {
+		return nil, err
+	}
+	return channel.Query(req)
+}
 
-	if os.Getenv("MOCK_MODE") == "true" {
-		return s.mockTranQuery(req)
+// Refund 发起渠道退款
+func (s *paymentService) Refund(req contracts.PaymentRefundRequest) (*contracts.PaymentRefundResponse, error) {
+	channel, err := s.channel(req.ChannelCode)
+	if err != nil {
+		return nil, err
 	}
+	return channel.Refund(req)
+}
 
-	// TODO: 实现真实的支付查询调用
-	return s.mockTranQuery(req)
+// Close 关闭渠道订单
+func (s *paymentService) Close(req contracts.PaymentCloseRequest) (*contracts.PaymentCloseResponse, error) {
+	channel, err := s.channel(req.ChannelCode)
+	if err != nil {
+		return nil, err
+	}
+	return channel.Close(req)
+}
+
+// VerifyCallback 由订单所属渠道校验支付结果回调
+func (s *paymentService) VerifyCallback(
+	channelCode string, account contracts.PaymentAccount, req contracts.PaymentCallbackResultRequest,
+) error {
+	channel, err := s.channel(channelCode)
+	if err != nil {
+		return err
+	}
+	return channel.VerifyCallback(account, req)
 }
 
-// GetPaymentAccount 获取机器支付账户信息
+// ParseNotify 由渠道验签解密原生异步通知，merchantID 为通知地址中的商户号
+func (s *paymentService) ParseNotify(
+	channelCode, merchantID string, header http.Header, body []byte,
+) (*contracts.PaymentCallbackResultRequest, error) {
+	channel, err := s.channel(channelCode)
+	if err != nil {
+		return nil, err
+	}
+	parser, ok := channel.(PaymentNotifyParser)
+	if !ok {
+		return nil, fmt.Errorf("payment channel %s does not support notifications", channelCode)
+	}
+	account, err := s.merchantAccount(channelCode, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	return parser.ParseNotify(account, header, body)
+}
+
+// merchantAccount 根据通知地址中的商户号获取机主收款账户
+// 未携带商户号或没有机主使用该商户号（如部署配置的默认商户）时返回nil，由渠道匹配已加载的商户
+func (s *paymentService) merchantAccount(channelCode, merchantID string) (*contracts.PaymentAccount, error) {
+	if merchantID == "" || s.ownerRepo == nil {
+		return nil, nil
+	}
+	owner, err := s.ownerRepo.GetByMerchant(channelCode, merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get machine owner: %w", err)
+	}
+	if owner == nil {
+		return nil, nil
+	}
+	return ownerPaymentAccount(owner)
+}
+
+// VerifyRefundCallback 由订单所属渠道校验退款结果回调
+func (s *paymentService) VerifyRefundCallback(
+	channelCode string, account contracts.PaymentAccount, req contracts.RefundCallbackRequest,
+) error {
+	channel, err := s.channel(channelCode)
+	if err != nil {
+		return err
+	}
+	return channel.VerifyRefundCallback(account, req)
+}
+
+// ParseRefundNotify 由渠道验签解密原生退款结果通知，merchantID 为通知地址中的商户号
+func (s *paymentService) ParseRefundNotify(
+	channelCode, merchantID string, header http.Header, body []byte,
+) (*contracts.RefundCallbackRequest, error) {
+	channel, err := s.channel(channelCode)
+	if err != nil {
+		return nil, err
+	}
+	parser, ok := channel.(RefundNotifyParser)
+	if !ok {
+		return nil, fmt.Errorf("payment channel %s does not support refund notifications", channelCode)
+	}
+	account, err := s.merchantAccount(channelCode, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	return parser.ParseRefundNotify(account, header, body)
+}
+
+// GetPaymentAccount 获取机器所属机主的收款账户，机主未配置收款账户时返回 ErrPaymentAccountNotFound
+// MOCK_MODE 下未配置收款账户的机器使用模拟账户
 func (s *paymentService) GetPaymentAccount(machineID string) (*contracts.PaymentAccount, error) {
 	machine, err := s.machineRepo.GetByID(machineID)
 	if err != nil {
@@ -79,17 +200,52 @@ func (s *paymentService) GetPaymentAccount(machineID string) (*contracts.Payment
 		return nil, errors.New("machine not found")
 	}
 
-	// 构建支付账户信息
-	// TODO: 从实际的机器配置或数据库中获取真实的支付账户信息
-	account := &contracts.PaymentAccount{
-		ReceivingAccount:     getEnvOrDefault("WECHAT_PAY_MERCHANT_ID", "test_merchant_001"),
-		ReceivingKey:         getEnvOrDefault("WECHAT_PAY_API_KEY", "test_key_123"),
-		ReceivingOrderPrefix: fmt.Sprintf("VM_%s_", *machine.MachineNo),
+	var owner *models.MachineOwner
+	if s.ownerRepo != nil && ptrToString(machine.MachineOwnerId) != "" {
+		if owner, err = s.ownerRepo.GetByID(*machine.MachineOwnerId); err != nil {
+			return nil, fmt.Errorf("failed to get machine owner: %w", err)
+		}
 	}
 
+	account, err := ownerPaymentAccount(owner)
+	if errors.Is(err, ErrPaymentAccountNotFound) && mockModeEnabled() {
+		account, err = mockPaymentAccount(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("machine %s: %w", machineID, err)
+	}
+
+	// 机主未设置订单前缀时按机器编号生成
+	if account.ReceivingOrderPrefix == "" {
+		account.ReceivingOrderPrefix = machineOrderPrefix(ptrToString(machine.MachineNo))
+	}
 	return account, nil
 }
 
+// BindOrderChannel 在预下单前将订单绑定到支付渠道，已绑定的订单保持不变
+// 订单已作废或已支付时返回 ErrOrderNotWaitPay，不再发起预下单
+func (s *paymentService) BindOrderChannel(orderID string, channelCode string) error {
+	bound, err := s.orderRepo.BindChannel(orderID, channelCode)
+	if err != nil {
+		return fmt.Errorf("failed to bind order channel: %w", err)
+	}
+	if bound {
+		return nil
+	}
+
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+	if order == nil {
+		return errors.New("order not found")
+	}
+	if order.PaymentStatus != int(enums.PaymentStatusWaitPay) {
+		return ErrOrderNotWaitPay
+	}
+	return nil
+}
+
 // PayOrder 支付订单
 func (s *paymentService) PayOrder(req contracts.PayOrderRequest) error {
 	order, err := s.orderRepo.GetByID(req.ID)
@@ -102,8 +258,13 @@ func (s *paymentService) PayOrder(req contracts.PayOrderRequest) error {
 	}
 
 	// 检查订单状态
-	if order.PaymentStatus != int(enums.PaymentStatusWaitPay) {
-		return errors.New("order is not in wait pay status")
+	switch enums.PaymentStatus(order.PaymentStatus) {
+	case enums.PaymentStatusWaitPay:
+	case enums.PaymentStatusInvalid:
+		// 作废前已发起的支付在作废后才到账，补记支付并自动全额退款
+		return s.refundInvalidatedOrder(order, req)
+	default:
+		return ErrOrderNotWaitPay
 	}
 
 	// 更新订单状态为已支付
@@ -111,7 +272,8 @@ func (s *paymentService) PayOrder(req contracts.PayOrderRequest) error {
 	order.ChannelOrderNo = &req.ChannelOrderNo
 	order.PaymentTime = &req.PaidAt
 
-	err = s.orderRepo.Update(order)
+	// 下发制作指令与库存结算由订单已支付事件的订阅者处理
+	err = s.orderRepo.Update(order, newOrderEvent(contracts.EventOrderPaid, order, time.Now()))
 	if err != nil {
 		return fmt.Errorf("failed to update order: %w", err)
 	}
@@ -130,130 +292,49 @@ func (s *paymentService) InvalidOrder(req contracts.InvalidOrderRequest) error {
 		return errors.New("order not found")
 	}
 
-	// 更新订单状态为已取消
-	order.PaymentStatus = int(enums.PaymentStatusInvalid)
-
-	err = s.orderRepo.Update(order)
+	// 仅作废仍待支付的订单，并发支付成功或已作废的订单返回 ErrOrderNotWaitPay
+	// 预占库存由订单作废事件的订阅者归还
+	invalidated, err := s.orderRepo.Invalidate(order.ID, req.Unbound,
+		newOrderEvent(contracts.EventOrderInvalidated, order, time.Now()))
 	if err != nil {
-		return fmt.Errorf("failed to update order: %w", err)
+		return fmt.Errorf("failed to invalidate order: %w", err)
 	}
-
-	return nil
-}
-
-// ProcessPaymentCallback 处理支付回调
-func (s *paymentService) ProcessPaymentCallback(
-	req contracts.PaymentCallbackRequest,
-) (*contracts.PaymentCallbackResponse, error) {
-	// 根据订单号查找订单
-	order, err := s.orderRepo.GetByOrderNo(req.OrderNo)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get order by order no: %w", err)
+	if !invalidated {
+		return ErrOrderNotWaitPay
 	}
+	order.PaymentStatus = int(enums.PaymentStatusInvalid)
 
-	if order == nil {
-		return nil, errors.New("order not found")
-	}
-
-	// 验证回调签名（实际项目中需要实现签名验证）
-	if !s.verifyCallbackSignature(req) {
-		return nil, errors.New("invalid callback signature")
-	}
-
-	// 根据支付状态处理订单
-	switch req.Status {
-	case contracts.PaymentStatusSuccess:
-		return s.handleSuccessCallback(req)
-	case contracts.PaymentStatusFailure, contracts.PaymentStatusCancel:
-		return s.handleFailureCallback(req)
-	default:
-		return &contracts.PaymentCallbackResponse{
-			Processed: true,
-			Message:   "回调已接收，状态待处理",
-		}, nil
-	}
-}
-
-// mock和辅助函数
-func (s *paymentService) mockWeChatPay(req contracts.WeChatPayRequest) (*contracts.WeChatPayResponse, error) {
-	// 模拟微信支付响应
-	return &contracts.WeChatPayResponse{
-		IsSuccess: true,
-		AppId:     "mock_app_id",
-		TimeStamp: fmt.Sprintf("%d", time.Now().Unix()),
-		NonceStr:  "mock_nonce_str",
-		Package:   "prepay_id=mock_prepay_id",
-		SignType:  "RSA",
-		PaySign:   "mock_pay_sign",
-		Message:   "支付信息获取成功",
-	}, nil
-}
-
-func (s *paymentService) mockTranQuery(req contracts.TranQueryRequest) (*contracts.TranQueryResponse, error) {
-	// 模拟支付查询响应
-	return &contracts.TranQueryResponse{
-		IsSuccess:     true,
-		PaymentStatus: contracts.PaymentStatusSuccess,
-		TransactionId: "mock_transaction_id_" + req.OrderNo,
-		PaymentTime:   time.Now(),
-		Message:       "查询成功",
-	}, nil
-}
-
-// verifyCallbackSignature 验证回调签名
-func (s *paymentService) verifyCallbackSignature(req contracts.PaymentCallbackRequest) bool {
-	// TODO: 实现真实的签名验证逻辑
-	// 这里应该根据支付提供商的签名算法进行验证
-	return req.Signature != ""
+	return nil
 }
 
-// handleSuccessCallback 处理成功支付回调
-func (s *paymentService) handleSuccessCallback(
-	req contracts.PaymentCallbackRequest,
-) (*contracts.PaymentCallbackResponse, error) {
-	payOrderReq := contracts.PayOrderRequest{
-		ID:             req.OrderNo, // 注意：这里需要根据OrderNo获取内部OrderID
-		ChannelOrderNo: req.TransactionId,
-		PaidAt:         req.PaidAt,
-	}
-
-	// 首先通过订单号获取订单ID
-	order, err := s.orderRepo.GetByOrderNo(req.OrderNo)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get order: %w", err)
+// refundInvalidatedOrder 补记已作废订单的支付并创建全额退款记录，退款由退款重试任务向渠道发起
+// 重复的支付通知不会重复创建退款
+func (s *paymentService) refundInvalidatedOrder(order *models.Order, req contracts.PayOrderRequest) error {
+	amount := refundableAmount(order)
+	if s.refundRepo == nil || ptrToString(order.OrderNo) == "" || !amount.IsPositive() {
+		return ErrOrderNotWaitPay
 	}
 
-	payOrderReq.ID = order.ID
-
-	if err := s.PayOrder(payOrderReq); err != nil {
-		return nil, fmt.Errorf("failed to pay order: %w", err)
+	reason := refundReasonInvalidOrder
+	record := &models.RefundRecord{
+		OrderId:  order.ID,
+		RefundNo: autoRefundNo(*order.OrderNo),
+		Amount:   amount.InexactFloat64(),
+		Reason:   &reason,
+		Status:   int(enums.RefundStatusPending),
 	}
-
-	return &contracts.PaymentCallbackResponse{
-		Processed: true,
-		Message:   "回调处理成功",
-	}, nil
-}
-
-// handleFailureCallback 处理失败支付回调
-func (s *paymentService) handleFailureCallback(
-	req contracts.PaymentCallbackRequest,
-) (*contracts.PaymentCallbackResponse, error) {
-	// 首先通过订单号获取订单ID
-	order, err := s.orderRepo.GetByOrderNo(req.OrderNo)
+	created, err := s.refundRepo.CreateForInvalidatedOrder(record, req.ChannelOrderNo, req.PaidAt,
+		newRefundEvent(contracts.EventOrderRefunding, record, time.Now()))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get order: %w", err)
+		return fmt.Errorf("failed to create refund record: %w", err)
 	}
-
-	invalidReq := contracts.InvalidOrderRequest{ID: order.ID}
-	if err := s.InvalidOrder(invalidReq); err != nil {
-		return nil, fmt.Errorf("failed to invalid order: %w", err)
+	if created {
+		logrus.WithFields(logrus.Fields{
+			"order_id":         order.ID,
+			"channel_order_no": req.ChannelOrderNo,
+		}).Warn("已作废订单收到支付，已发起自动退款")
 	}
-
-	return &contracts.PaymentCallbackResponse{
-		Processed: true,
-		Message:   "回调处理成功",
-	}, nil
+	return nil
 }
 
 // getEnvOrDefault 获取环境变量或默认值
@@ -5,13 +5,14 @@ import (
 	"testing"
 	"time"
 
-	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/ddteam/drink-master/internal/contracts"
 	"github.com/ddteam/drink-master/internal/enums"
 	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
 )
 
 // MockOrderRepository for testing
@@ -32,12 +33,27 @@ func (m *MockOrderRepository) GetByID(id string) (*models.Order, error) {
 	return args.Get(0).(*models.Order), args.Error(1)
 }
 
+func (m *MockOrderRepository) GetByOwnerPaging(
+	filter repositories.OwnerOrderFilter, pageIndex, pageSize int,
+) ([]models.Order, error) {
+	args := m.Called(filter, pageIndex, pageSize)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (m *MockOrderRepository) GetOwnerTotals(filter repositories.OwnerOrderFilter) (*repositories.OrderTotals, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repositories.OrderTotals), args.Error(1)
+}
+
 func (m *MockOrderRepository) GetByMemberPaging(memberID string, pageIndex, pageSize int) ([]models.Order, int64, error) {
 	args := m.Called(memberID, pageIndex, pageSize)
 	return args.Get(0).([]models.Order), args.Get(1).(int64), args.Error(2)
 }
 
-func (m *MockOrderRepository) Update(order *models.Order) error {
+func (m *MockOrderRepository) Update(order *models.Order, _ ...*models.OutboxEvent) error {
 	args := m.Called(order)
 	return args.Error(0)
 }
@@ -47,6 +63,31 @@ func (m *MockOrderRepository) Delete(id string) error {
 	return args.Error(0)
 }
 
+func (m *MockOrderRepository) UpdateMakeStatus(id string, from, to enums.MakeStatus, _ ...*models.OutboxEvent) (bool, error) {
+	args := m.Called(id, from, to)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockOrderRepository) GetMakeTimedOut(paidBefore time.Time, limit int) ([]models.Order, error) {
+	args := m.Called(paidBefore, limit)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (m *MockOrderRepository) GetPayTimedOut(createdBefore time.Time, after *models.Order, limit int) ([]models.Order, error) {
+	args := m.Called(createdBefore, after, limit)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (m *MockOrderRepository) BindChannel(id string, channelCode string) (bool, error) {
+	args := m.Called(id, channelCode)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockOrderRepository) Invalidate(id string, unboundOnly bool, events ...*models.OutboxEvent) (bool, error) {
+	args := m.Called(id, unboundOnly)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockOrderRepository) GetByOrderNo(orderNo string) (*models.Order, error) {
 	args := m.Called(orderNo)
 	if args.Get(0) == nil {
@@ -124,33 +165,52 @@ func TestPaymentService_TranQuery_Mock(t *testing.T) {
 }
 
 func TestPaymentService_GetPaymentAccount(t *testing.T) {
-	mockOrderRepo := &MockOrderRepository{}
 	mockMachineRepo := &MockMachineRepository{}
-
 	service := &paymentService{
-		orderRepo:   mockOrderRepo,
 		machineRepo: mockMachineRepo,
+		ownerRepo:   newTestOwnerRepo(t),
 	}
 
-	machine := &models.Machine{
-		ID:        "machine-001",
-		MachineNo: stringPtr("VM001"),
-		Name:      stringPtr("测试咖啡机"),
-	}
-
-	mockMachineRepo.On("GetByID", "machine-001").Return(machine, nil)
+	mockMachineRepo.On("GetByID", "machine-001").Return(testOwnedMachine(), nil)
 
 	account, err := service.GetPaymentAccount("machine-001")
 
 	assert.NoError(t, err)
-	assert.NotNil(t, account)
-	assert.NotEmpty(t, account.ReceivingAccount)
-	assert.NotEmpty(t, account.ReceivingKey)
-	assert.Equal(t, "VM_VM001_", account.ReceivingOrderPrefix)
+	require.NotNil(t, account)
+	assert.Equal(t, "merchant-001", account.ReceivingAccount)
+	assert.Equal(t, testReceivingKey, account.ReceivingKey)
+	assert.Equal(t, machineOrderPrefix("VM001"), account.ReceivingOrderPrefix)
 
 	mockMachineRepo.AssertExpectations(t)
 }
 
+func TestPaymentService_GetPaymentAccount_NotConfigured(t *testing.T) {
+	os.Unsetenv("MOCK_MODE")
+	mockMachineRepo := &MockMachineRepository{}
+	mockOwnerRepo := &MockMachineOwnerRepository{}
+	service := &paymentService{machineRepo: mockMachineRepo, ownerRepo: mockOwnerRepo}
+
+	mockMachineRepo.On("GetByID", "machine-001").Return(testOwnedMachine(), nil)
+	mockMachineRepo.On("GetByID", "machine-002").Return(&models.Machine{ID: "machine-002", MachineNo: stringPtr("VM002")}, nil)
+	mockOwnerRepo.On("GetByID", "owner-001").Return(&models.MachineOwner{ID: "owner-001"}, nil)
+
+	// 机主未配置收款账户
+	_, err := service.GetPaymentAccount("machine-001")
+	assert.ErrorIs(t, err, ErrPaymentAccountNotFound)
+
+	// 机器未绑定机主
+	_, err = service.GetPaymentAccount("machine-002")
+	assert.ErrorIs(t, err, ErrPaymentAccountNotFound)
+
+	// 模拟模式下使用模拟账户
+	os.Setenv("MOCK_MODE", "true")
+	defer os.Unsetenv("MOCK_MODE")
+	account, err := service.GetPaymentAccount("machine-002")
+	require.NoError(t, err)
+	assert.Equal(t, contracts.ChannelCodeMock, account.ChannelCode)
+	assert.Equal(t, machineOrderPrefix("VM002"), account.ReceivingOrderPrefix)
+}
+
 func TestPaymentService_PayOrder(t *testing.T) {
 	mockOrderRepo := &MockOrderRepository{}
 	mockMachineRepo := &MockMachineRepository{}
@@ -237,7 +297,7 @@ func TestPaymentService_InvalidOrder(t *testing.T) {
 	}
 
 	mockOrderRepo.On("GetByID", "order-001").Return(order, nil)
-	mockOrderRepo.On("Update", mock.AnythingOfType("*models.Order")).Return(nil)
+	mockOrderRepo.On("Invalidate", "order-001", false).Return(true, nil)
 
 	req := contracts.InvalidOrderRequest{
 		ID: "order-001",
@@ -246,95 +306,24 @@ func TestPaymentService_InvalidOrder(t *testing.T) {
 	err := service.InvalidOrder(req)
 
 	assert.NoError(t, err)
-
-	// 验证订单状态已更新为失效
-	mockOrderRepo.AssertCalled(t, "Update", mock.MatchedBy(func(o *models.Order) bool {
-		return o.PaymentStatus == int(enums.PaymentStatusInvalid)
-	}))
-
-	mockOrderRepo.AssertExpectations(t)
-}
-
-func TestPaymentService_ProcessPaymentCallback_Success(t *testing.T) {
-	mockOrderRepo := &MockOrderRepository{}
-	mockMachineRepo := &MockMachineRepository{}
-
-	service := &paymentService{
-		orderRepo:   mockOrderRepo,
-		machineRepo: mockMachineRepo,
-	}
-
-	order := &models.Order{
-		ID:            "order-001",
-		OrderNo:       stringPtr("ORD20250812001"),
-		PaymentStatus: int(enums.PaymentStatusWaitPay),
-		PayAmount:     15.80,
-	}
-
-	mockOrderRepo.On("GetByOrderNo", "ORD20250812001").Return(order, nil)
-	mockOrderRepo.On("GetByID", "order-001").Return(order, nil)
-	mockOrderRepo.On("Update", mock.AnythingOfType("*models.Order")).Return(nil)
-
-	req := contracts.PaymentCallbackRequest{
-		OrderNo:       "ORD20250812001",
-		TransactionId: "wx_123456789",
-		Amount:        decimal.NewFromFloat(15.80),
-		Status:        contracts.PaymentStatusSuccess,
-		PaidAt:        time.Now(),
-		Signature:     "valid_signature",
-	}
-
-	response, err := service.ProcessPaymentCallback(req)
-
-	assert.NoError(t, err)
-	assert.NotNil(t, response)
-	assert.True(t, response.Processed)
-	assert.Equal(t, "回调处理成功", response.Message)
+	assert.Equal(t, int(enums.PaymentStatusInvalid), order.PaymentStatus)
 
 	mockOrderRepo.AssertExpectations(t)
 }
 
-func TestPaymentService_ProcessPaymentCallback_Failed(t *testing.T) {
+func TestPaymentService_InvalidOrder_NotWaitPay(t *testing.T) {
 	mockOrderRepo := &MockOrderRepository{}
-	mockMachineRepo := &MockMachineRepository{}
+	service := &paymentService{orderRepo: mockOrderRepo}
 
-	service := &paymentService{
-		orderRepo:   mockOrderRepo,
-		machineRepo: mockMachineRepo,
-	}
-
-	order := &models.Order{
+	mockOrderRepo.On("GetByID", "order-001").Return(&models.Order{
 		ID:            "order-001",
-		OrderNo:       stringPtr("ORD20250812001"),
-		PaymentStatus: int(enums.PaymentStatusWaitPay),
-		PayAmount:     15.80,
-	}
-
-	mockOrderRepo.On("GetByOrderNo", "ORD20250812001").Return(order, nil)
-	mockOrderRepo.On("GetByID", "order-001").Return(order, nil)
-	mockOrderRepo.On("Update", mock.AnythingOfType("*models.Order")).Return(nil)
+		PaymentStatus: int(enums.PaymentStatusPaid),
+	}, nil)
+	mockOrderRepo.On("Invalidate", "order-001", true).Return(false, nil)
 
-	req := contracts.PaymentCallbackRequest{
-		OrderNo:       "ORD20250812001",
-		TransactionId: "wx_123456789",
-		Amount:        decimal.NewFromFloat(15.80),
-		Status:        contracts.PaymentStatusFailure,
-		PaidAt:        time.Now(),
-		Signature:     "valid_signature",
-	}
-
-	response, err := service.ProcessPaymentCallback(req)
-
-	assert.NoError(t, err)
-	assert.NotNil(t, response)
-	assert.True(t, response.Processed)
-
-	// 验证订单被作废
-	mockOrderRepo.AssertCalled(t, "Update", mock.MatchedBy(func(o *models.Order) bool {
-		return o.PaymentStatus == int(enums.PaymentStatusInvalid)
-	}))
-
-	mockOrderRepo.AssertExpectations(t)
+	// 并发支付成功或已发起预下单的订单不作废
+	err := service.InvalidOrder(contracts.InvalidOrderRequest{ID: "order-001", Unbound: true})
+	assert.ErrorIs(t, err, ErrOrderNotWaitPay)
 }
 
 func TestGetEnvOrDefault(t *testing.T) {
@@ -349,3 +338,28 @@ func TestGetEnvOrDefault(t *testing.T) {
 	result = getEnvOrDefault("NON_EXISTENT_VAR", "default_value")
 	assert.Equal(t, "default_value", result)
 }
+
+func TestPaymentService_BindOrderChannel(t *testing.T) {
+	mockOrderRepo := &MockOrderRepository{}
+	service := &paymentService{orderRepo: mockOrderRepo}
+
+	mockOrderRepo.On("BindChannel", "order-001", contracts.ChannelCodeFuiouMerchant).Return(true, nil)
+	require.NoError(t, service.BindOrderChannel("order-001", contracts.ChannelCodeFuiouMerchant))
+
+	// 已绑定且仍待支付的订单可再次预下单
+	mockOrderRepo.On("BindChannel", "order-002", contracts.ChannelCodeFuiouMerchant).Return(false, nil)
+	mockOrderRepo.On("GetByID", "order-002").Return(&models.Order{
+		ID:            "order-002",
+		PaymentStatus: int(enums.PaymentStatusWaitPay),
+	}, nil)
+	require.NoError(t, service.BindOrderChannel("order-002", contracts.ChannelCodeFuiouMerchant))
+
+	// 已被超时任务作废的订单不再预下单
+	mockOrderRepo.On("BindChannel", "order-003", contracts.ChannelCodeFuiouMerchant).Return(false, nil)
+	mockOrderRepo.On("GetByID", "order-003").Return(&models.Order{
+		ID:            "order-003",
+		PaymentStatus: int(enums.PaymentStatusInvalid),
+	}, nil)
+	err := service.BindOrderChannel("order-003", contracts.ChannelCodeFuiouMerchant)
+	assert.ErrorIs(t, err, ErrOrderNotWaitPay)
+}
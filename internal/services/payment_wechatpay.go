@@ -0,0 +1,471 @@
+package services
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ddteam/drink-master/internal/config"
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/pkg/wechatpay"
+)
+
+var (
+	// errWeChatPayNotConfigured 未配置微信支付商户API证书
+	errWeChatPayNotConfigured = errors.New("wechat pay channel is not configured")
+	// errWeChatPayMerchantCertificate 机主商户未配置API证书，且不是部署配置的默认商户
+	errWeChatPayMerchantCertificate = errors.New("wechat pay merchant certificate is not configured")
+)
+
+// wechatPayCallbackType 微信支付异步通知转换后的回调类型
+const wechatPayCallbackType = "WeChatPayNotify"
+
+var (
+	_ PaymentNotifyParser    = (*wechatPayChannel)(nil)
+	_ RefundNotifyParser     = (*wechatPayChannel)(nil)
+	_ PaymentAccountVerifier = (*wechatPayChannel)(nil)
+)
+
+// wechatPayChannel 微信支付API v3直连商户渠道
+// Ext1/Ext2/Ext3 分别对应商户号、APIv3密钥与订单前缀，Ext4/Ext5 为机主商户的API证书序列号与私钥
+// 机主未配置商户证书时仅部署配置的默认商户可用，使用部署配置的证书
+type wechatPayChannel struct {
+	cfg        *config.WeChatPayConfig
+	privateKey *rsa.PrivateKey
+
+	mu      sync.Mutex
+	clients map[string]*wechatPayMerchant
+}
+
+// wechatPayMerchant 按商户号缓存的客户端，平台证书随客户端缓存
+type wechatPayMerchant struct {
+	apiV3Key string
+	serialNo string
+	client   *wechatpay.Client
+}
+
+// wechatPayCredentials 调用微信支付API v3的商户凭证
+type wechatPayCredentials struct {
+	mchID         string
+	apiV3Key      string
+	serialNo      string
+	privateKeyPEM string
+}
+
+// wechatPayAccountCredentials 从机主收款账户获取商户凭证
+func wechatPayAccountCredentials(account contracts.PaymentAccount) wechatPayCredentials {
+	return wechatPayCredentials{
+		mchID:         account.ReceivingAccount,
+		apiV3Key:      account.ReceivingKey,
+		serialNo:      account.MerchantSerialNo,
+		privateKeyPEM: account.MerchantPrivateKey,
+	}
+}
+
+// newWeChatPayChannel 根据环境配置创建微信支付渠道，未配置证书时各接口返回未配置错误
+func newWeChatPayChannel(cfg *config.WeChatPayConfig) *wechatPayChannel {
+	channel := &wechatPayChannel{cfg: cfg, clients: make(map[string]*wechatPayMerchant)}
+	if !cfg.Enabled() {
+		return channel
+	}
+
+	pemData, err := os.ReadFile(cfg.PrivateKeyPath)
+	if err == nil {
+		channel.privateKey, err = wechatpay.ParsePrivateKey(pemData)
+	}
+	if err != nil {
+		logrus.WithError(err).Warn("微信支付商户私钥加载失败")
+		return channel
+	}
+
+	// 预先创建默认商户客户端，保证服务重启后首个异步通知也能验签解密
+	if cfg.MchID != "" && cfg.APIv3Key != "" {
+		if _, err := channel.client(wechatPayCredentials{mchID: cfg.MchID, apiV3Key: cfg.APIv3Key}); err != nil {
+			logrus.WithError(err).Warn("微信支付默认商户初始化失败")
+		}
+	}
+	return channel
+}
+
+// Code 渠道编码
+func (c *wechatPayChannel) Code() string {
+	return contracts.ChannelCodeWeChatPayV3
+}
+
+// client 获取商户客户端，APIv3密钥或商户证书变更时重建
+func (c *wechatPayChannel) client(creds wechatPayCredentials) (*wechatpay.Client, error) {
+	serialNo, privateKey, err := c.certificate(creds)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if merchant, ok := c.clients[creds.mchID]; ok &&
+		merchant.apiV3Key == creds.apiV3Key && merchant.serialNo == serialNo {
+		return merchant.client, nil
+	}
+
+	client, err := c.newClient(creds.mchID, creds.apiV3Key, serialNo, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	c.clients[creds.mchID] = &wechatPayMerchant{apiV3Key: creds.apiV3Key, serialNo: serialNo, client: client}
+	return client, nil
+}
+
+// certificate 返回商户API证书序列号与私钥，机主未配置时仅默认商户可使用部署配置的证书
+func (c *wechatPayChannel) certificate(creds wechatPayCredentials) (string, *rsa.PrivateKey, error) {
+	if creds.serialNo != "" && creds.privateKeyPEM != "" {
+		privateKey, err := wechatpay.ParsePrivateKey([]byte(creds.privateKeyPEM))
+		if err != nil {
+			return "", nil, fmt.Errorf("merchant %s: %w", creds.mchID, err)
+		}
+		return creds.serialNo, privateKey, nil
+	}
+	if c.privateKey == nil {
+		return "", nil, errWeChatPayNotConfigured
+	}
+	if creds.mchID != c.cfg.MchID {
+		return "", nil, fmt.Errorf("%w: merchant %s", errWeChatPayMerchantCertificate, creds.mchID)
+	}
+	return c.cfg.SerialNo, c.privateKey, nil
+}
+
+// newClient 创建商户客户端
+func (c *wechatPayChannel) newClient(
+	mchID, apiV3Key, serialNo string, privateKey *rsa.PrivateKey,
+) (*wechatpay.Client, error) {
+	return wechatpay.NewClient(wechatpay.Config{
+		BaseURL:    c.cfg.BaseURL,
+		AppID:      c.cfg.AppID,
+		MchID:      mchID,
+		SerialNo:   serialNo,
+		PrivateKey: privateKey,
+		APIv3Key:   apiV3Key,
+	})
+}
+
+// merchantNotifyURL 在通知地址后追加商户号路径，异步通知据此加载对应商户验签解密
+// 微信支付API v3的通知地址不能携带查询参数
+func merchantNotifyURL(notifyURL, mchID string) string {
+	if notifyURL == "" {
+		return ""
+	}
+	return strings.TrimRight(notifyURL, "/") + "/" + url.PathEscape(mchID)
+}
+
+// Prepay 小程序JSAPI下单，订单号放入附加数据以便异步通知关联订单
+func (c *wechatPayChannel) Prepay(req contracts.WeChatPayRequest) (*contracts.WeChatPayResponse, error) {
+	client, err := c.client(wechatPayCredentials{req.Ext1, req.Ext2, req.Ext4, req.Ext5})
+	if err != nil {
+		return nil, err
+	}
+
+	notifyURL := merchantNotifyURL(c.cfg.NotifyURL, req.Ext1)
+	if notifyURL == "" {
+		notifyURL = req.NotifyUrl
+	}
+
+	params, err := client.PrepayMiniProgram(wechatpay.PrepayRequest{
+		OutTradeNo:  req.Ext3 + req.OrderNo,
+		Description: req.OrderInfo,
+		Amount:      int64(req.TransAmt),
+		OpenID:      req.OpenId,
+		NotifyURL:   notifyURL,
+		Attach:      req.OrderNo,
+	})
+	if err != nil {
+		var apiErr *wechatpay.APIError
+		if errors.As(err, &apiErr) {
+			return &contracts.WeChatPayResponse{IsSuccess: false, Message: apiErr.Message}, nil
+		}
+		return nil, fmt.Errorf("failed to create wechat pay order: %w", err)
+	}
+
+	return &contracts.WeChatPayResponse{
+		IsSuccess: true,
+		AppId:     params.AppID,
+		TimeStamp: params.TimeStamp,
+		NonceStr:  params.NonceStr,
+		Package:   params.Package,
+		SignType:  params.SignType,
+		PaySign:   params.PaySign,
+		Message:   "支付信息获取成功",
+	}, nil
+}
+
+// Query 按商户订单号查询支付状态
+func (c *wechatPayChannel) Query(req contracts.TranQueryRequest) (*contracts.TranQueryResponse, error) {
+	client, err := c.client(wechatPayCredentials{req.Ext1, req.Ext2, req.Ext4, req.Ext5})
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := client.QueryByOutTradeNo(req.Ext3 + req.OrderNo)
+	if err != nil {
+		var apiErr *wechatpay.APIError
+		if errors.As(err, &apiErr) {
+			return &contracts.TranQueryResponse{
+				IsSuccess: false,
+				Message:   apiErr.Message,
+				ErrorCode: apiErr.Code,
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to query wechat pay order: %w", err)
+	}
+
+	paymentTime, ok := tx.PaidAt()
+	if !ok {
+		paymentTime = time.Now()
+	}
+
+	return &contracts.TranQueryResponse{
+		IsSuccess:     true,
+		PaymentStatus: wechatPayPaymentStatus(tx.TradeState),
+		TransactionId: tx.TransactionID,
+		PaymentTime:   paymentTime,
+		Message:       "查询成功",
+	}, nil
+}
+
+// Refund 申请退款，退款处理中时等待退款结果通知
+// 退款单号不加订单前缀，退款通知据此直接关联退款记录
+func (c *wechatPayChannel) Refund(req contracts.PaymentRefundRequest) (*contracts.PaymentRefundResponse, error) {
+	client, err := c.client(wechatPayCredentials{req.Ext1, req.Ext2, req.Ext4, req.Ext5})
+	if err != nil {
+		return nil, err
+	}
+
+	refund, err := client.Refund(wechatpay.RefundRequest{
+		OutTradeNo:  req.Ext3 + req.OrderNo,
+		OutRefundNo: req.RefundNo,
+		Reason:      req.Reason,
+		NotifyURL:   merchantNotifyURL(c.cfg.RefundNotifyURL, req.Ext1),
+		Total:       int64(req.TotalAmt),
+		Refund:      int64(req.RefundAmt),
+	})
+	if err != nil {
+		var apiErr *wechatpay.APIError
+		if errors.As(err, &apiErr) {
+			return &contracts.PaymentRefundResponse{
+				IsSuccess: false,
+				Message:   apiErr.Message,
+				ErrorCode: apiErr.Code,
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to refund wechat pay order: %w", err)
+	}
+
+	switch refund.Status {
+	case wechatpay.RefundStatusClosed, wechatpay.RefundStatusAbnormal:
+		return &contracts.PaymentRefundResponse{
+			IsSuccess: false,
+			Message:   "退款失败",
+			ErrorCode: refund.Status,
+		}, nil
+	}
+	if refund.Status == wechatpay.RefundStatusProcessing {
+		return &contracts.PaymentRefundResponse{
+			IsSuccess:       true,
+			Pending:         true,
+			ChannelRefundNo: refund.RefundID,
+			Message:         "退款处理中",
+		}, nil
+	}
+	return &contracts.PaymentRefundResponse{
+		IsSuccess:       true,
+		ChannelRefundNo: refund.RefundID,
+		Message:         "退款成功",
+	}, nil
+}
+
+// Close 关闭未支付订单
+func (c *wechatPayChannel) Close(req contracts.PaymentCloseRequest) (*contracts.PaymentCloseResponse, error) {
+	client, err := c.client(wechatPayCredentials{req.Ext1, req.Ext2, req.Ext4, req.Ext5})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.Close(req.Ext3 + req.OrderNo); err != nil {
+		var apiErr *wechatpay.APIError
+		if errors.As(err, &apiErr) {
+			return &contracts.PaymentCloseResponse{
+				IsSuccess: false,
+				Message:   apiErr.Message,
+				ErrorCode: apiErr.Code,
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to close wechat pay order: %w", err)
+	}
+
+	return &contracts.PaymentCloseResponse{IsSuccess: true, Message: "关单成功"}, nil
+}
+
+// VerifyCallback 微信支付结果只接受经平台证书验签的异步通知，不接受通用回调
+func (c *wechatPayChannel) VerifyCallback(
+	account contracts.PaymentAccount, req contracts.PaymentCallbackResultRequest,
+) error {
+	return errors.New("wechat pay results are only accepted from signed notifications")
+}
+
+// VerifyRefundCallback 微信支付退款结果只接受经平台证书验签的异步通知
+func (c *wechatPayChannel) VerifyRefundCallback(
+	account contracts.PaymentAccount, req contracts.RefundCallbackRequest,
+) error {
+	return errors.New("wechat pay refund results are only accepted from signed notifications")
+}
+
+// VerifyAccount 下载平台证书校验商户号、APIv3密钥与商户证书，使用独立客户端避免替换已缓存的商户
+func (c *wechatPayChannel) VerifyAccount(account contracts.PaymentAccount) error {
+	creds := wechatPayAccountCredentials(account)
+	serialNo, privateKey, err := c.certificate(creds)
+	if err != nil {
+		return err
+	}
+	client, err := c.newClient(creds.mchID, creds.apiV3Key, serialNo, privateKey)
+	if err != nil {
+		return err
+	}
+	return client.VerifyAccount()
+}
+
+// notifyClients 返回验签解密通知的商户客户端
+// 通知地址带商户号时只使用该商户的收款账户，否则依次尝试已加载的商户
+func (c *wechatPayChannel) notifyClients(account *contracts.PaymentAccount) ([]*wechatpay.Client, error) {
+	if account != nil {
+		client, err := c.client(wechatPayAccountCredentials(*account))
+		if err != nil {
+			return nil, err
+		}
+		return []*wechatpay.Client{client}, nil
+	}
+
+	clients := c.loadedClients()
+	if len(clients) == 0 {
+		return nil, errWeChatPayNotConfigured
+	}
+	return clients, nil
+}
+
+// ParseNotify 验签并解密支付结果通知
+func (c *wechatPayChannel) ParseNotify(
+	account *contracts.PaymentAccount, header http.Header, body []byte,
+) (*contracts.PaymentCallbackResultRequest, error) {
+	clients, err := c.notifyClients(account)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, client := range clients {
+		tx, err := client.ParseTransactionNotification(header, body)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return wechatPayCallbackResult(tx), nil
+	}
+	return nil, lastErr
+}
+
+// ParseRefundNotify 验签并解密退款结果通知
+func (c *wechatPayChannel) ParseRefundNotify(
+	account *contracts.PaymentAccount, header http.Header, body []byte,
+) (*contracts.RefundCallbackRequest, error) {
+	clients, err := c.notifyClients(account)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, client := range clients {
+		refund, err := client.ParseRefundNotification(header, body)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return wechatPayRefundResult(refund), nil
+	}
+	return nil, lastErr
+}
+
+// loadedClients 返回已加载的商户客户端
+func (c *wechatPayChannel) loadedClients() []*wechatpay.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clients := make([]*wechatpay.Client, 0, len(c.clients))
+	for _, merchant := range c.clients {
+		clients = append(clients, merchant.client)
+	}
+	return clients
+}
+
+// wechatPayRefundResult 将退款结果通知转换为统一的退款结果回调
+// 原订单号为带前缀的商户订单号，退款单号未加前缀
+func wechatPayRefundResult(refund *wechatpay.RefundResult) *contracts.RefundCallbackRequest {
+	status := contracts.RefundResultFailure
+	if refund.RefundStatus == wechatpay.RefundStatusSuccess {
+		status = contracts.RefundResultSuccess
+	}
+	refundTime, ok := refund.RefundedAt()
+	if !ok {
+		refundTime = time.Now()
+	}
+
+	return &contracts.RefundCallbackRequest{
+		ChannelCode:     contracts.ChannelCodeWeChatPayV3,
+		OrderNo:         refund.OutTradeNo,
+		RefundNo:        refund.OutRefundNo,
+		ChannelRefundNo: refund.RefundID,
+		RefundAmt:       int(refund.Amount.Refund),
+		RefundStatus:    status,
+		RefundTime:      refundTime,
+	}
+}
+
+// wechatPayCallbackResult 将支付成功通知转换为统一的支付结果回调
+func wechatPayCallbackResult(tx *wechatpay.Transaction) *contracts.PaymentCallbackResultRequest {
+	orderNo := tx.Attach
+	if orderNo == "" {
+		orderNo = tx.OutTradeNo
+	}
+	paymentTime, ok := tx.PaidAt()
+	if !ok {
+		paymentTime = time.Now()
+	}
+
+	return &contracts.PaymentCallbackResultRequest{
+		ChannelCode:    contracts.ChannelCodeWeChatPayV3,
+		TransAmt:       int(tx.Amount.Total),
+		OrderNo:        orderNo,
+		ChannelOrderNo: tx.TransactionID,
+		PaymentTime:    paymentTime,
+		CallbackType:   wechatPayCallbackType,
+	}
+}
+
+// wechatPayPaymentStatus 微信支付交易状态转换为统一支付状态
+func wechatPayPaymentStatus(tradeState string) string {
+	switch tradeState {
+	case wechatpay.TradeStateSuccess, wechatpay.TradeStateRefund:
+		// 转入退款说明订单曾支付成功
+		return contracts.PaymentStatusSuccess
+	case wechatpay.TradeStateNotPay, wechatpay.TradeStateUserPaying:
+		return contracts.PaymentStatusPaying
+	case wechatpay.TradeStateClosed, wechatpay.TradeStateRevoked:
+		return contracts.PaymentStatusCancel
+	case wechatpay.TradeStatePayError:
+		return contracts.PaymentStatusFailure
+	default:
+		return contracts.PaymentStatusException
+	}
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ddteam/drink-master/internal/config"
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/pkg/wechatpay"
+)
+
+const testWeChatPayAPIv3Key = "0123456789abcdef0123456789abcdef"
+
+func TestWeChatPayChannel_NotConfigured(t *testing.T) {
+	channel := newWeChatPayChannel(&config.WeChatPayConfig{})
+
+	_, err := channel.Prepay(contracts.WeChatPayRequest{Ext1: "1900000001", Ext2: testWeChatPayAPIv3Key})
+	assert.ErrorIs(t, err, errWeChatPayNotConfigured)
+
+	_, err = channel.ParseNotify(nil, nil, []byte("{}"))
+	assert.ErrorIs(t, err, errWeChatPayNotConfigured)
+
+	_, err = channel.ParseRefundNotify(nil, nil, []byte("{}"))
+	assert.ErrorIs(t, err, errWeChatPayNotConfigured)
+}
+
+func TestWeChatPayChannel_ClientPerMerchant(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	channel := newWeChatPayChannel(&config.WeChatPayConfig{MchID: "1900000001", SerialNo: "SERIAL001"})
+	channel.privateKey = key
+
+	defaultMerchant := wechatPayCredentials{mchID: "1900000001", apiV3Key: testWeChatPayAPIv3Key}
+	first, err := channel.client(defaultMerchant)
+	require.NoError(t, err)
+	again, err := channel.client(defaultMerchant)
+	require.NoError(t, err)
+	assert.Same(t, first, again)
+
+	// APIv3密钥变更后重建客户端
+	rotated, err := channel.client(wechatPayCredentials{mchID: "1900000001", apiV3Key: "abcdef0123456789abcdef0123456789"})
+	require.NoError(t, err)
+	assert.NotSame(t, first, rotated)
+
+	// 其他商户须配置自己的API证书，不能使用部署配置的证书签名
+	_, err = channel.client(wechatPayCredentials{mchID: "1900000002", apiV3Key: testWeChatPayAPIv3Key})
+	assert.ErrorIs(t, err, errWeChatPayMerchantCertificate)
+
+	ownerMerchant := wechatPayCredentials{
+		mchID:         "1900000002",
+		apiV3Key:      testWeChatPayAPIv3Key,
+		serialNo:      "SERIAL002",
+		privateKeyPEM: testMerchantPrivateKeyPEM(t),
+	}
+	client, err := channel.client(ownerMerchant)
+	require.NoError(t, err)
+	assert.NotNil(t, client)
+
+	ownerMerchant.apiV3Key = "short"
+	_, err = channel.client(ownerMerchant)
+	assert.Error(t, err)
+}
+
+func TestWeChatPayChannel_OwnerMerchantWithoutDeploymentCertificate(t *testing.T) {
+	channel := newWeChatPayChannel(&config.WeChatPayConfig{})
+	account := &contracts.PaymentAccount{
+		ReceivingAccount:   "1900000002",
+		ReceivingKey:       testWeChatPayAPIv3Key,
+		MerchantSerialNo:   "SERIAL002",
+		MerchantPrivateKey: testMerchantPrivateKeyPEM(t),
+	}
+
+	// 重启后未加载任何商户时，通知地址所指商户的客户端按收款账户创建
+	clients, err := channel.notifyClients(account)
+	require.NoError(t, err)
+	assert.Len(t, clients, 1)
+	assert.Len(t, channel.loadedClients(), 1)
+
+	_, err = channel.notifyClients(nil)
+	assert.NoError(t, err)
+}
+
+func TestMerchantNotifyURL(t *testing.T) {
+	assert.Equal(t, "https://example.com/api/Callback/PaymentNotify/wechat_pay_v3/1900000001",
+		merchantNotifyURL("https://example.com/api/Callback/PaymentNotify/wechat_pay_v3/", "1900000001"))
+	assert.Empty(t, merchantNotifyURL("", "1900000001"))
+}
+
+// testMerchantPrivateKeyPEM 生成PKCS#8格式的商户API证书私钥
+func testMerchantPrivateKeyPEM(t *testing.T) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func TestWeChatPayChannel_RejectsGenericCallback(t *testing.T) {
+	channel := newWeChatPayChannel(&config.WeChatPayConfig{})
+
+	err := channel.VerifyCallback(contracts.PaymentAccount{}, contracts.PaymentCallbackResultRequest{
+		ChannelCode: contracts.ChannelCodeWeChatPayV3, OrderNo: "ORD001",
+	})
+	assert.Error(t, err)
+
+	err = channel.VerifyRefundCallback(contracts.PaymentAccount{}, contracts.RefundCallbackRequest{
+		ChannelCode: contracts.ChannelCodeWeChatPayV3, RefundNo: "RFORD00101",
+	})
+	assert.Error(t, err)
+}
+
+func TestWeChatPayRefundResult(t *testing.T) {
+	result := wechatPayRefundResult(&wechatpay.RefundResult{
+		OutTradeNo:   "1066ORD001",
+		OutRefundNo:  "RFORD00101",
+		RefundID:     "5030000001",
+		RefundStatus: wechatpay.RefundStatusSuccess,
+		SuccessTime:  "2025-08-12T15:30:00+08:00",
+		Amount:       wechatpay.RefundAmount{Total: 1580, Refund: 500},
+	})
+	assert.Equal(t, contracts.ChannelCodeWeChatPayV3, result.ChannelCode)
+	assert.Equal(t, "1066ORD001", result.OrderNo)
+	assert.Equal(t, "RFORD00101", result.RefundNo)
+	assert.Equal(t, 500, result.RefundAmt)
+	assert.Equal(t, contracts.RefundResultSuccess, result.RefundStatus)
+	assert.Equal(t, 2025, result.RefundTime.Year())
+
+	// 退款关闭与异常均视为退款失败
+	for _, status := range []string{wechatpay.RefundStatusClosed, wechatpay.RefundStatusAbnormal} {
+		result = wechatPayRefundResult(&wechatpay.RefundResult{OutRefundNo: "RFORD00101", RefundStatus: status})
+		assert.Equal(t, contracts.RefundResultFailure, result.RefundStatus)
+	}
+}
+
+func TestWeChatPayCallbackResult(t *testing.T) {
+	result := wechatPayCallbackResult(&wechatpay.Transaction{
+		OutTradeNo:    "1066ORD001",
+		TransactionID: "4200000001",
+		Attach:        "ORD001",
+		SuccessTime:   "2025-08-12T15:30:00+08:00",
+		Amount:        wechatpay.Amount{Total: 1580},
+	})
+	assert.Equal(t, contracts.ChannelCodeWeChatPayV3, result.ChannelCode)
+	assert.Equal(t, "ORD001", result.OrderNo)
+	assert.Equal(t, 1580, result.TransAmt)
+	assert.Equal(t, "4200000001", result.ChannelOrderNo)
+	assert.Equal(t, 2025, result.PaymentTime.Year())
+
+	// 无附加数据时使用商户订单号
+	result = wechatPayCallbackResult(&wechatpay.Transaction{OutTradeNo: "ORD002"})
+	assert.Equal(t, "ORD002", result.OrderNo)
+}
+
+func TestWeChatPayPaymentStatus(t *testing.T) {
+	assert.Equal(t, contracts.PaymentStatusSuccess, wechatPayPaymentStatus(wechatpay.TradeStateSuccess))
+	assert.Equal(t, contracts.PaymentStatusSuccess, wechatPayPaymentStatus(wechatpay.TradeStateRefund))
+	assert.Equal(t, contracts.PaymentStatusPaying, wechatPayPaymentStatus(wechatpay.TradeStateNotPay))
+	assert.Equal(t, contracts.PaymentStatusCancel, wechatPayPaymentStatus(wechatpay.TradeStateClosed))
+	assert.Equal(t, contracts.PaymentStatusFailure, wechatPayPaymentStatus(wechatpay.TradeStatePayError))
+	assert.Equal(t, contracts.PaymentStatusException, wechatPayPaymentStatus("UNKNOWN"))
+}
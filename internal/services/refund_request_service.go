@@ -0,0 +1,294 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+)
+
+// 退款申请错误，错误信息直接返回给用户
+var (
+	ErrRefundRequestNotFound = errors.New("退款申请不存在")
+	ErrRefundRequestPending  = errors.New("订单已有待审核的退款申请")
+	ErrRefundRequestReviewed = errors.New("退款申请已审核")
+	ErrRefundRequestStale    = errors.New("订单可退金额已变化，请拒绝后由用户重新申请")
+)
+
+// 退款申请失败原因的最大长度
+const refundRequestLastErrorMaxRunes = 512
+
+// 接口审核状态对应的退款申请状态
+var refundRequestStatusFilters = map[string]enums.RefundRequestStatus{
+	contracts.RefundRequestStatusPending:  enums.RefundRequestStatusPending,
+	contracts.RefundRequestStatusApproved: enums.RefundRequestStatusApproved,
+	contracts.RefundRequestStatusRejected: enums.RefundRequestStatusRejected,
+}
+
+// RefundRequestServiceInterface 顾客退款申请服务接口
+type RefundRequestServiceInterface interface {
+	Submit(request contracts.SubmitRefundRequest) (*contracts.RefundRequestResponse, error)
+	GetByOrder(memberID, orderID string) ([]contracts.RefundRequestResponse, error)
+	GetOwnerPaging(request contracts.GetRefundRequestPagingRequest) (*contracts.RefundRequestPagingResponse, error)
+	Review(request contracts.ReviewRefundRequest) (*contracts.RefundRequestResponse, error)
+}
+
+// refundRequestService 顾客退款申请服务实现
+// 顾客对已支付订单提交申请，售货机机主审核；同意时先将申请置为已同意防止重复审核，再按订单剩余可退金额发起退款，
+// 退款未能发起时申请退回待审核，机主可以重新处理
+type refundRequestService struct {
+	requestRepo repositories.RefundRequestRepository
+	orderRepo   repositories.OrderRepository
+	machineRepo repositories.MachineRepositoryInterface
+	refundSvc   RefundServiceInterface
+	now         func() time.Time
+}
+
+// NewRefundRequestService 创建顾客退款申请服务
+func NewRefundRequestService(db *gorm.DB, refundSvc RefundServiceInterface) RefundRequestServiceInterface {
+	return &refundRequestService{
+		requestRepo: repositories.NewRefundRequestRepository(db),
+		orderRepo:   repositories.NewOrderRepository(db),
+		machineRepo: repositories.NewMachineRepository(db),
+		refundSvc:   refundSvc,
+		now:         time.Now,
+	}
+}
+
+// Submit 顾客对自己的已支付订单提交退款申请，申请金额为订单剩余可退金额
+func (s *refundRequestService) Submit(request contracts.SubmitRefundRequest) (*contracts.RefundRequestResponse, error) {
+	order, err := s.getMemberOrder(request.MemberID, request.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := refundRequestAmount(order, decimal.Zero)
+	if err != nil {
+		return nil, err
+	}
+
+	machine, err := s.machineRepo.GetByID(ptrToString(order.MachineId))
+	if err != nil {
+		return nil, fmt.Errorf("查询机器信息失败: %w", err)
+	}
+	if machine == nil || ptrToString(machine.MachineOwnerId) == "" {
+		return nil, ErrRefundNotAllowed
+	}
+
+	refundRequest := &models.RefundRequest{
+		OrderId:        order.ID,
+		MemberId:       request.MemberID,
+		MachineId:      machine.ID,
+		MachineOwnerId: *machine.MachineOwnerId,
+		Amount:         amount.InexactFloat64(),
+		Reason:         request.Reason,
+		Status:         int(enums.RefundRequestStatusPending),
+		CreatedOn:      s.now(),
+	}
+	if request.PhotoURL != "" {
+		refundRequest.PhotoUrl = &request.PhotoURL
+	}
+	created, err := s.requestRepo.CreatePending(refundRequest)
+	if err != nil {
+		return nil, fmt.Errorf("创建退款申请失败: %w", err)
+	}
+	if !created {
+		return nil, ErrRefundRequestPending
+	}
+	return newRefundRequestResponse(refundRequest), nil
+}
+
+// GetByOrder 获取顾客订单的退款申请记录
+func (s *refundRequestService) GetByOrder(memberID, orderID string) ([]contracts.RefundRequestResponse, error) {
+	if _, err := s.getMemberOrder(memberID, orderID); err != nil {
+		return nil, err
+	}
+	requests, err := s.requestRepo.GetByOrderID(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("查询退款申请失败: %w", err)
+	}
+
+	responses := make([]contracts.RefundRequestResponse, len(requests))
+	for i := range requests {
+		responses[i] = *newRefundRequestResponse(&requests[i])
+	}
+	return responses, nil
+}
+
+// GetOwnerPaging 分页获取机主名下售货机的退款申请，默认只返回待审核的申请
+func (s *refundRequestService) GetOwnerPaging(
+	request contracts.GetRefundRequestPagingRequest,
+) (*contracts.RefundRequestPagingResponse, error) {
+	var status *enums.RefundRequestStatus
+	switch request.Status {
+	case "":
+		pending := enums.RefundRequestStatusPending
+		status = &pending
+	case contracts.RefundRequestStatusAll:
+	default:
+		value, ok := refundRequestStatusFilters[request.Status]
+		if !ok {
+			return nil, fmt.Errorf("%w: 不支持的审核状态 %s", ErrInvalidOrderFilter, request.Status)
+		}
+		status = &value
+	}
+
+	requests, total, err := s.requestRepo.GetByOwnerPaging(
+		request.MachineOwnerID, status, request.PageIndex, request.PageSize)
+	if err != nil {
+		return nil, fmt.Errorf("查询退款申请失败: %w", err)
+	}
+
+	responses := make([]contracts.RefundRequestResponse, len(requests))
+	for i := range requests {
+		responses[i] = *newRefundRequestResponse(&requests[i])
+	}
+	return &contracts.RefundRequestPagingResponse{
+		Requests: responses,
+		Meta:     newPaginationMeta(total, len(responses), request.PageIndex, request.PageSize),
+	}, nil
+}
+
+// Review 机主审核退款申请，同意时发起退款
+func (s *refundRequestService) Review(request contracts.ReviewRefundRequest) (*contracts.RefundRequestResponse, error) {
+	refundRequest, err := s.requestRepo.GetByID(request.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询退款申请失败: %w", err)
+	}
+	if refundRequest == nil || refundRequest.MachineOwnerId != request.MachineOwnerID {
+		return nil, ErrRefundRequestNotFound
+	}
+
+	if request.Approve {
+		if err := s.checkRefundable(refundRequest); err != nil {
+			return nil, err
+		}
+	}
+
+	reviewedAt := s.now()
+	to := enums.RefundRequestStatusRejected
+	if request.Approve {
+		to = enums.RefundRequestStatusApproved
+	}
+	var remark *string
+	if request.Remark != "" {
+		remark = &request.Remark
+	}
+	review := map[string]interface{}{
+		"ReviewerId":   request.MachineOwnerID,
+		"ReviewRemark": remark,
+		"ReviewedOn":   reviewedAt,
+		"LastError":    nil,
+	}
+	updated, err := s.requestRepo.UpdateStatus(refundRequest.ID, enums.RefundRequestStatusPending, to, review)
+	if err != nil {
+		return nil, fmt.Errorf("更新退款申请失败: %w", err)
+	}
+	if !updated {
+		return nil, ErrRefundRequestReviewed
+	}
+
+	if request.Approve {
+		if refundErr := s.refund(refundRequest); refundErr != nil {
+			return nil, refundErr
+		}
+	}
+
+	reviewed, err := s.requestRepo.GetByID(refundRequest.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询退款申请失败: %w", err)
+	}
+	return newRefundRequestResponse(reviewed), nil
+}
+
+// checkRefundable 校验订单剩余可退金额仍为申请时的金额
+// 申请提交后机主已发起过退款时，按申请金额退款会与用户预期不符，需拒绝后重新申请
+func (s *refundRequestService) checkRefundable(refundRequest *models.RefundRequest) error {
+	order, err := s.orderRepo.GetByID(refundRequest.OrderId)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && order == nil) {
+		return ErrRefundOrderNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("获取订单失败: %w", err)
+	}
+	if !refundableAmount(order).Equal(decimal.NewFromFloat(refundRequest.Amount).Round(2)) {
+		return ErrRefundRequestStale
+	}
+	return nil
+}
+
+// refund 为已同意的退款申请按申请金额发起退款，未能发起时申请退回待审核并记录原因
+func (s *refundRequestService) refund(refundRequest *models.RefundRequest) error {
+	amount := decimal.NewFromFloat(refundRequest.Amount)
+	record, refundErr := s.refundSvc.Refund(refundRequest.OrderId, amount, refundRequest.Reason)
+	if refundErr == nil {
+		_, err := s.requestRepo.UpdateStatus(refundRequest.ID,
+			enums.RefundRequestStatusApproved, enums.RefundRequestStatusApproved,
+			map[string]interface{}{"RefundNo": record.RefundNo})
+		if err != nil {
+			// 退款已发起，退款单号可通过订单退款记录查询
+			logrus.WithError(err).WithField("refund_request_id", refundRequest.ID).Warn("退款申请记录退款单号失败")
+		}
+		return nil
+	}
+
+	reverted, err := s.requestRepo.UpdateStatus(refundRequest.ID,
+		enums.RefundRequestStatusApproved, enums.RefundRequestStatusPending,
+		map[string]interface{}{
+			"ReviewerId":   nil,
+			"ReviewRemark": nil,
+			"ReviewedOn":   nil,
+			"LastError":    truncateRunes(refundErr.Error(), refundRequestLastErrorMaxRunes),
+		})
+	if err != nil || !reverted {
+		logrus.WithError(err).WithField("refund_request_id", refundRequest.ID).Error("退款申请退回待审核失败")
+	}
+	return refundErr
+}
+
+// getMemberOrder 获取属于该会员的订单
+func (s *refundRequestService) getMemberOrder(memberID, orderID string) (*models.Order, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && order == nil) {
+		return nil, ErrRefundOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取订单失败: %w", err)
+	}
+	if ptrToString(order.MemberId) != memberID {
+		return nil, ErrRefundOrderNotFound
+	}
+	return order, nil
+}
+
+// newRefundRequestResponse 转换退款申请响应
+func newRefundRequestResponse(request *models.RefundRequest) *contracts.RefundRequestResponse {
+	status := contracts.RefundRequestStatusPending
+	switch enums.RefundRequestStatus(request.Status) {
+	case enums.RefundRequestStatusApproved:
+		status = contracts.RefundRequestStatusApproved
+	case enums.RefundRequestStatusRejected:
+		status = contracts.RefundRequestStatusRejected
+	}
+	return &contracts.RefundRequestResponse{
+		ID:           request.ID,
+		OrderID:      request.OrderId,
+		MachineID:    request.MachineId,
+		Amount:       decimal.NewFromFloat(request.Amount),
+		Reason:       request.Reason,
+		PhotoURL:     request.PhotoUrl,
+		Status:       status,
+		StatusDesc:   request.GetStatusDesc(),
+		ReviewRemark: request.ReviewRemark,
+		ReviewedAt:   request.ReviewedOn,
+		RefundNo:     request.RefundNo,
+		CreatedAt:    request.CreatedOn,
+	}
+}
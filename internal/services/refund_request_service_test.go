@@ -0,0 +1,181 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+)
+
+// setupRefundRequestService 创建使用内存数据库与模拟退款服务的退款申请服务，并写入机主售货机上的一笔已支付订单
+func setupRefundRequestService(t *testing.T) (*refundRequestService, *gorm.DB, *MockRefundService) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Order{}, &models.Machine{}, &models.RefundRequest{}))
+	require.NoError(t, db.Create(&models.Machine{ID: "machine-1", MachineOwnerId: stringPtr("owner-1")}).Error)
+	require.NoError(t, db.Create(&models.Order{
+		ID:            "order-1",
+		OrderNo:       stringPtr("ORD001"),
+		MemberId:      stringPtr("member-1"),
+		MachineId:     stringPtr("machine-1"),
+		PayAmount:     15.8,
+		PaymentStatus: int(enums.PaymentStatusPaid),
+		CreatedOn:     time.Now(),
+	}).Error)
+
+	refundSvc := &MockRefundService{}
+	service := &refundRequestService{
+		requestRepo: repositories.NewRefundRequestRepository(db),
+		orderRepo:   repositories.NewOrderRepository(db),
+		machineRepo: repositories.NewMachineRepository(db),
+		refundSvc:   refundSvc,
+		now:         time.Now,
+	}
+	return service, db, refundSvc
+}
+
+func submitTestRefundRequest(t *testing.T, service *refundRequestService) *contracts.RefundRequestResponse {
+	t.Helper()
+	response, err := service.Submit(contracts.SubmitRefundRequest{
+		MemberID: "member-1",
+		OrderID:  "order-1",
+		Reason:   "饮品没有出杯",
+		PhotoURL: "https://example.com/photo.jpg",
+	})
+	require.NoError(t, err)
+	return response
+}
+
+func TestRefundRequestService_Submit(t *testing.T) {
+	service, db, _ := setupRefundRequestService(t)
+
+	// 其他会员的订单视为不存在
+	_, err := service.Submit(contracts.SubmitRefundRequest{MemberID: "member-2", OrderID: "order-1", Reason: "x"})
+	assert.ErrorIs(t, err, ErrRefundOrderNotFound)
+
+	response := submitTestRefundRequest(t, service)
+	assert.Equal(t, contracts.RefundRequestStatusPending, response.Status)
+	assert.Equal(t, "15.8", response.Amount.String())
+	require.NotNil(t, response.PhotoURL)
+
+	var saved models.RefundRequest
+	require.NoError(t, db.First(&saved, "Id = ?", response.ID).Error)
+	assert.Equal(t, "owner-1", saved.MachineOwnerId)
+
+	// 同一订单只能有一个待审核申请
+	_, err = service.Submit(contracts.SubmitRefundRequest{MemberID: "member-1", OrderID: "order-1", Reason: "x"})
+	assert.ErrorIs(t, err, ErrRefundRequestPending)
+
+	// 未支付订单不能申请
+	require.NoError(t, db.Model(&models.Order{}).Where("Id = ?", "order-1").
+		Update("PaymentStatus", int(enums.PaymentStatusWaitPay)).Error)
+	require.NoError(t, db.Model(&models.RefundRequest{}).Where("Id = ?", response.ID).
+		Update("Status", int(enums.RefundRequestStatusRejected)).Error)
+	_, err = service.Submit(contracts.SubmitRefundRequest{MemberID: "member-1", OrderID: "order-1", Reason: "x"})
+	assert.ErrorIs(t, err, ErrRefundNotAllowed)
+}
+
+func TestRefundRequestService_ApproveTriggersRefund(t *testing.T) {
+	service, _, refundSvc := setupRefundRequestService(t)
+	submitted := submitTestRefundRequest(t, service)
+	refundSvc.On("Refund", "order-1", decimal.NewFromFloat(15.8), "饮品没有出杯").
+		Return(&models.RefundRecord{RefundNo: "RFORD00101"}, nil).Once()
+
+	// 其他机主不能审核
+	_, err := service.Review(contracts.ReviewRefundRequest{MachineOwnerID: "owner-2", ID: submitted.ID, Approve: true})
+	assert.ErrorIs(t, err, ErrRefundRequestNotFound)
+
+	response, err := service.Review(contracts.ReviewRefundRequest{
+		MachineOwnerID: "owner-1", ID: submitted.ID, Approve: true, Remark: "已核实",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, contracts.RefundRequestStatusApproved, response.Status)
+	require.NotNil(t, response.RefundNo)
+	assert.Equal(t, "RFORD00101", *response.RefundNo)
+	assert.Equal(t, "已核实", *response.ReviewRemark)
+	assert.NotNil(t, response.ReviewedAt)
+
+	// 已审核的申请不会重复退款
+	_, err = service.Review(contracts.ReviewRefundRequest{MachineOwnerID: "owner-1", ID: submitted.ID, Approve: true})
+	assert.ErrorIs(t, err, ErrRefundRequestReviewed)
+	refundSvc.AssertExpectations(t)
+}
+
+func TestRefundRequestService_RefundFailureReturnsToQueue(t *testing.T) {
+	service, db, refundSvc := setupRefundRequestService(t)
+	submitted := submitTestRefundRequest(t, service)
+	refundSvc.On("Refund", "order-1", decimal.NewFromFloat(15.8), "饮品没有出杯").
+		Return(&models.RefundRecord{}, fmt.Errorf("%w: channel rejected", ErrRefundFailed)).Once()
+
+	_, err := service.Review(contracts.ReviewRefundRequest{MachineOwnerID: "owner-1", ID: submitted.ID, Approve: true})
+	assert.ErrorIs(t, err, ErrRefundFailed)
+
+	var saved models.RefundRequest
+	require.NoError(t, db.First(&saved, "Id = ?", submitted.ID).Error)
+	assert.Equal(t, int(enums.RefundRequestStatusPending), saved.Status)
+	assert.Nil(t, saved.ReviewedOn)
+	require.NotNil(t, saved.LastError)
+	assert.Contains(t, *saved.LastError, "channel rejected")
+
+	// 退回待审核后机主可以拒绝
+	response, err := service.Review(contracts.ReviewRefundRequest{MachineOwnerID: "owner-1", ID: submitted.ID})
+	require.NoError(t, err)
+	assert.Equal(t, contracts.RefundRequestStatusRejected, response.Status)
+	refundSvc.AssertExpectations(t)
+}
+
+func TestRefundRequestService_ApproveRejectsChangedAmount(t *testing.T) {
+	service, db, refundSvc := setupRefundRequestService(t)
+	submitted := submitTestRefundRequest(t, service)
+
+	// 申请提交后机主已部分退款，剩余可退金额与申请金额不一致
+	require.NoError(t, db.Model(&models.Order{}).Where("Id = ?", "order-1").
+		Update("RefundAmount", 5.0).Error)
+
+	_, err := service.Review(contracts.ReviewRefundRequest{MachineOwnerID: "owner-1", ID: submitted.ID, Approve: true})
+	assert.ErrorIs(t, err, ErrRefundRequestStale)
+
+	var saved models.RefundRequest
+	require.NoError(t, db.First(&saved, "Id = ?", submitted.ID).Error)
+	assert.Equal(t, int(enums.RefundRequestStatusPending), saved.Status)
+	refundSvc.AssertNotCalled(t, "Refund")
+}
+
+func TestRefundRequestService_GetOwnerPaging(t *testing.T) {
+	service, _, _ := setupRefundRequestService(t)
+	submitted := submitTestRefundRequest(t, service)
+
+	response, err := service.GetOwnerPaging(contracts.GetRefundRequestPagingRequest{
+		MachineOwnerID: "owner-1", PageIndex: 1, PageSize: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Requests, 1)
+	assert.Equal(t, submitted.ID, response.Requests[0].ID)
+
+	response, err = service.GetOwnerPaging(contracts.GetRefundRequestPagingRequest{
+		MachineOwnerID: "owner-1", PageIndex: 1, PageSize: 10, Status: contracts.RefundRequestStatusApproved,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, response.Requests)
+
+	_, err = service.GetOwnerPaging(contracts.GetRefundRequestPagingRequest{
+		MachineOwnerID: "owner-1", PageIndex: 1, PageSize: 10, Status: "Unknown",
+	})
+	assert.ErrorIs(t, err, ErrInvalidOrderFilter)
+
+	requests, err := service.GetByOrder("member-1", "order-1")
+	require.NoError(t, err)
+	assert.Len(t, requests, 1)
+}
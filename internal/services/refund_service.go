@@ -0,0 +1,555 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+)
+
+// 自动退款重试策略
+const (
+	refundMaxAttempts    = 5                // 最大尝试次数，超过后标记失败待人工处理
+	refundRetryBaseDelay = time.Minute      // 首次重试间隔，之后按指数递增
+	refundRetryMaxDelay  = 30 * time.Minute // 最大重试间隔
+	refundProcessingTTL  = 5 * time.Minute  // 处理中记录超过该时间未更新视为处理中断，可重新领取
+	refundRetryBatchSize = 50
+)
+
+// 自动退款原因
+const (
+	refundReasonMakeFail     = "制作失败自动退款"
+	refundReasonInvalidOrder = "订单已作废自动退款"
+)
+
+// 退款申请错误，错误信息直接返回给用户
+var (
+	ErrRefundOrderNotFound  = errors.New("订单不存在")
+	ErrRefundNotAllowed     = errors.New("订单状态不允许退款")
+	ErrOrderAlreadyRefunded = errors.New("订单已经退款")
+	ErrRefundInProgress     = errors.New("订单有退款正在处理")
+	ErrInvalidRefundAmount  = errors.New("退款金额无效")
+	ErrRefundAmountExceeded = errors.New("退款金额超过可退金额")
+	ErrRefundFailed         = errors.New("退款失败")
+)
+
+var (
+	// ErrRefundRecordNotFound 退款回调对应的退款记录不存在
+	ErrRefundRecordNotFound = errors.New("refund record not found")
+	// ErrRefundCallbackRejected 退款回调未通过渠道校验或与退款记录不符
+	ErrRefundCallbackRejected = errors.New("refund callback rejected")
+)
+
+// RefundServiceInterface 退款服务接口
+type RefundServiceInterface interface {
+	AutoRefund(orderID, reason string) (*models.RefundRecord, error)
+	Refund(orderID string, amount decimal.Decimal, reason string) (*models.RefundRecord, error)
+	HandleRefundCallback(req contracts.RefundCallbackRequest) error
+	HandleRefundNotify(channelCode, merchantID string, header http.Header, body []byte) error
+	RetryPending() (int, error)
+	Start(interval time.Duration)
+	Stop()
+}
+
+// refundService 退款服务实现
+// 每笔退款先落库为退款记录并将订单置为退款中，再调用支付渠道退款接口，失败时按退避策略重试
+// 渠道受理后需等待退款结果的退款由退款回调完成
+type refundService struct {
+	orderRepo   repositories.OrderRepository
+	refundRepo  repositories.RefundRecordRepository
+	paymentSvc  PaymentServiceInterface
+	now         func() time.Time
+	logger      *logrus.Logger
+	stop        chan struct{}
+	stopOnce    sync.Once
+	maxAttempts int
+}
+
+// NewRefundService 创建退款服务
+func NewRefundService(db *gorm.DB, paymentSvc PaymentServiceInterface) RefundServiceInterface {
+	return &refundService{
+		orderRepo:   repositories.NewOrderRepository(db),
+		refundRepo:  repositories.NewRefundRecordRepository(db),
+		paymentSvc:  paymentSvc,
+		now:         time.Now,
+		logger:      logrus.StandardLogger(),
+		stop:        make(chan struct{}),
+		maxAttempts: refundMaxAttempts,
+	}
+}
+
+// MakeFailRefundSubscriber 制作失败时自动发起全额退款的领域事件订阅者
+// 退款记录创建后渠道调用失败由退款重试任务处理；订单已退款或已不可退款时不再重试
+func MakeFailRefundSubscriber(refundSvc RefundServiceInterface) OrderEventHandler {
+	return func(event contracts.OrderEvent) error {
+		if event.Type != contracts.EventOrderMakeFailed {
+			return nil
+		}
+		record, err := refundSvc.AutoRefund(event.OrderID, refundReasonMakeFail)
+		if errors.Is(err, ErrOrderAlreadyRefunded) || errors.Is(err, ErrRefundNotAllowed) {
+			return nil
+		}
+		if err != nil && record == nil {
+			return err
+		}
+		if err != nil {
+			logrus.WithError(err).WithField("order_id", event.OrderID).Warn("制作失败自动退款未完成，等待重试")
+		}
+		return nil
+	}
+}
+
+// AutoRefund 为订单发起全额退款
+// 同一订单重复调用只会产生一条退款记录，已成功的退款直接返回
+func (s *refundService) AutoRefund(orderID, reason string) (*models.RefundRecord, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	if order == nil {
+		return nil, errors.New("order not found")
+	}
+	if order.OrderNo == nil || *order.OrderNo == "" {
+		return nil, errors.New("order has no order no")
+	}
+
+	refundNo := autoRefundNo(*order.OrderNo)
+	record, err := s.refundRepo.GetByRefundNo(refundNo)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get refund record: %w", err)
+	}
+
+	if record == nil {
+		if record, err = s.createAutoRefund(order, refundNo, reason); err != nil {
+			return nil, err
+		}
+	}
+
+	switch enums.RefundStatus(record.Status) {
+	case enums.RefundStatusSuccess, enums.RefundStatusAccepted:
+		return record, nil
+	}
+	return record, s.process(record, order)
+}
+
+// createAutoRefund 为已支付订单创建退还剩余金额的退款记录
+func (s *refundService) createAutoRefund(
+	order *models.Order, refundNo, reason string,
+) (*models.RefundRecord, error) {
+	remaining, err := refundRequestAmount(order, decimal.Zero)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &models.RefundRecord{
+		OrderId:  order.ID,
+		RefundNo: refundNo,
+		Amount:   remaining.InexactFloat64(),
+		Reason:   &reason,
+		Status:   int(enums.RefundStatusPending),
+	}
+	created, err := s.refundRepo.CreateForOrder(record, newRefundEvent(contracts.EventOrderRefunding, record, s.now()))
+	if err == nil && created {
+		return record, nil
+	}
+
+	// 并发发起时以已存在的记录为准
+	existing, getErr := s.refundRepo.GetByRefundNo(refundNo)
+	if getErr == nil {
+		return existing, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refund record: %w", err)
+	}
+	return nil, errors.New("order has a refund in progress")
+}
+
+// Refund 为已支付订单发起退款，amount 为0时退还剩余可退金额
+// 同一订单同时只能有一笔退款在处理，渠道调用失败时退款保持待退款并按退避策略重试
+func (s *refundService) Refund(orderID string, amount decimal.Decimal, reason string) (*models.RefundRecord, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && order == nil) {
+		return nil, ErrRefundOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+
+	amount, err = refundRequestAmount(order, amount)
+	if err != nil {
+		return nil, err
+	}
+	if order.OrderNo == nil || *order.OrderNo == "" {
+		return nil, errors.New("order has no order no")
+	}
+
+	record := &models.RefundRecord{
+		OrderId: order.ID,
+		Amount:  amount.InexactFloat64(),
+		Reason:  &reason,
+		Status:  int(enums.RefundStatusPending),
+	}
+	// 退款序号在锁定订单后生成，并发发起的退款不会得到相同的退款单号
+	created, err := s.refundRepo.CreateNumberedForOrder(record, func(seq int) ([]*models.OutboxEvent, error) {
+		if seq > refundNoMaxSeq {
+			return nil, fmt.Errorf("%w: 退款次数已达上限", ErrRefundNotAllowed)
+		}
+		record.RefundNo = numberedRefundNo(*order.OrderNo, seq)
+		return []*models.OutboxEvent{newRefundEvent(contracts.EventOrderRefunding, record, s.now())}, nil
+	})
+	if errors.Is(err, ErrRefundNotAllowed) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refund record: %w", err)
+	}
+	if !created {
+		return nil, ErrRefundInProgress
+	}
+
+	if err := s.process(record, order); err != nil {
+		if enums.RefundStatus(record.Status) == enums.RefundStatusFailed {
+			return record, fmt.Errorf("%w: %w", ErrRefundFailed, err)
+		}
+		s.logger.WithError(err).WithField("refund_no", record.RefundNo).Warn("渠道退款未完成，等待重试")
+	}
+	return record, nil
+}
+
+// HandleRefundCallback 处理退款结果回调，由订单所属渠道使用机主收款密钥校验
+func (s *refundService) HandleRefundCallback(req contracts.RefundCallbackRequest) error {
+	return s.applyRefundResult(req, func(order *models.Order) error {
+		if order.MachineId == nil {
+			return errors.New("order has no machine")
+		}
+		account, err := s.paymentSvc.GetPaymentAccount(*order.MachineId)
+		if err != nil {
+			return err
+		}
+		channelCode, err := ResolveChannelCode(ptrToString(order.ChannelCode), account)
+		if err != nil {
+			return err
+		}
+		return s.paymentSvc.VerifyRefundCallback(channelCode, *account, req)
+	})
+}
+
+// HandleRefundNotify 处理支付渠道原生退款结果通知，merchantID 为通知地址中的商户号
+func (s *refundService) HandleRefundNotify(channelCode, merchantID string, header http.Header, body []byte) error {
+	req, err := s.paymentSvc.ParseRefundNotify(channelCode, merchantID, header, body)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRefundCallbackRejected, err)
+	}
+	return s.applyRefundResult(*req, func(order *models.Order) error {
+		// 通知已由渠道验签，只需确认订单确实绑定在该渠道上
+		if ptrToString(order.ChannelCode) != channelCode {
+			return errors.New("order is not bound to notifying channel")
+		}
+		return nil
+	})
+}
+
+// applyRefundResult 校验退款结果并更新退款记录，重复回调不会重复处理
+func (s *refundService) applyRefundResult(
+	req contracts.RefundCallbackRequest, verify func(order *models.Order) error,
+) error {
+	record, err := s.refundRepo.GetByRefundNo(req.RefundNo)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrRefundRecordNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get refund record: %w", err)
+	}
+	order, err := s.orderRepo.GetByID(record.OrderId)
+	if err != nil {
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+
+	// 渠道回传的订单号可能带有收款账户订单前缀
+	if orderNo := ptrToString(order.OrderNo); orderNo == "" || !strings.HasSuffix(req.OrderNo, orderNo) {
+		return fmt.Errorf("%w: order no %q does not match refund", ErrRefundCallbackRejected, req.OrderNo)
+	}
+	if verifyErr := verify(order); verifyErr != nil {
+		return fmt.Errorf("%w: %w", ErrRefundCallbackRejected, verifyErr)
+	}
+	if expected := int(yuanToFen(record.Amount)); req.RefundAmt != expected {
+		return fmt.Errorf("%w: %w: callback %d, refund %d",
+			ErrRefundCallbackRejected, ErrCallbackAmountMismatch, req.RefundAmt, expected)
+	}
+	return s.recordRefundResult(record, req)
+}
+
+// recordRefundResult 按退款结果标记退款记录成功或失败
+func (s *refundService) recordRefundResult(record *models.RefundRecord, req contracts.RefundCallbackRequest) error {
+	switch req.RefundStatus {
+	case contracts.RefundResultSuccess:
+		if req.ChannelRefundNo != "" {
+			record.ChannelRefundNo = &req.ChannelRefundNo
+		}
+		refundedAt := req.RefundTime
+		if refundedAt.IsZero() {
+			refundedAt = s.now()
+		}
+		refunded := newRefundEvent(contracts.EventOrderRefunded, record, refundedAt)
+		if err := s.refundRepo.MarkSucceeded(record, refundedAt, refunded); err != nil {
+			return fmt.Errorf("failed to save refund result: %w", err)
+		}
+	case contracts.RefundResultFailure:
+		if enums.RefundStatus(record.Status) == enums.RefundStatusSuccess {
+			s.logger.WithField("refund_no", record.RefundNo).Warn("已成功的退款收到失败回调，忽略")
+			return nil
+		}
+		message := "渠道退款失败"
+		record.LastError = &message
+		failedAt := s.now()
+		failed := newRefundEvent(contracts.EventOrderRefundFailed, record, failedAt)
+		if err := s.refundRepo.MarkFailed(record, failedAt, failed); err != nil {
+			return fmt.Errorf("failed to save refund failure: %w", err)
+		}
+	default:
+		return fmt.Errorf("%w: unknown refund status %q", ErrRefundCallbackRejected, req.RefundStatus)
+	}
+	return nil
+}
+
+// refundRequestAmount 校验订单状态与申请退款金额，返回本次退款金额
+func refundRequestAmount(order *models.Order, amount decimal.Decimal) (decimal.Decimal, error) {
+	switch enums.PaymentStatus(order.PaymentStatus) {
+	case enums.PaymentStatusPaid:
+	case enums.PaymentStatusRefunded:
+		return decimal.Zero, ErrOrderAlreadyRefunded
+	case enums.PaymentStatusRefunding:
+		return decimal.Zero, ErrRefundInProgress
+	default:
+		return decimal.Zero, ErrRefundNotAllowed
+	}
+
+	remaining := refundableAmount(order)
+	if !remaining.IsPositive() {
+		return decimal.Zero, ErrOrderAlreadyRefunded
+	}
+	if amount.IsZero() {
+		return remaining, nil
+	}
+	amount = amount.Round(2)
+	if !amount.IsPositive() {
+		return decimal.Zero, ErrInvalidRefundAmount
+	}
+	if amount.GreaterThan(remaining) {
+		return decimal.Zero, ErrRefundAmountExceeded
+	}
+	return amount, nil
+}
+
+// refundableAmount 订单剩余可退金额
+func refundableAmount(order *models.Order) decimal.Decimal {
+	return decimal.NewFromFloat(order.PayAmount).Sub(decimal.NewFromFloat(order.RefundAmount)).Round(2)
+}
+
+// RetryPending 重试到期的待退款记录，返回退款成功或已被渠道受理的数量
+func (s *refundService) RetryPending() (int, error) {
+	now := s.now()
+	records, err := s.refundRepo.GetRetryable(now, now.Add(-refundProcessingTTL), refundRetryBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get retryable refunds: %w", err)
+	}
+
+	succeeded := 0
+	for i := range records {
+		order, err := s.orderRepo.GetByID(records[i].OrderId)
+		if err != nil {
+			s.logger.WithError(err).WithField("refund_no", records[i].RefundNo).Warn("退款订单查询失败")
+			continue
+		}
+		if err := s.process(&records[i], order); err != nil {
+			s.logger.WithError(err).WithField("refund_no", records[i].RefundNo).Warn("退款重试失败")
+			continue
+		}
+		succeeded++
+	}
+	return succeeded, nil
+}
+
+// Start 定期重试待退款记录
+func (s *refundService) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if count, err := s.RetryPending(); err != nil {
+					s.logger.WithError(err).Error("退款重试扫描失败")
+				} else if count > 0 {
+					s.logger.WithField("count", count).Info("退款重试成功")
+				}
+			}
+		}
+	}()
+}
+
+// Stop 停止退款重试
+func (s *refundService) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// process 领取退款记录并调用渠道退款
+// 领取失败说明记录正由其他流程处理或未到重试时间
+func (s *refundService) process(record *models.RefundRecord, order *models.Order) error {
+	now := s.now()
+	claimed, err := s.refundRepo.Claim(record.ID, now, now.Add(-refundProcessingTTL))
+	if err != nil {
+		return fmt.Errorf("failed to claim refund record: %w", err)
+	}
+	if !claimed {
+		return errors.New("refund is being processed or waiting for retry")
+	}
+	record.Status = int(enums.RefundStatusProcessing)
+	record.Attempts++
+	record.Version++
+
+	resp, err := s.callChannel(record, order)
+	if err == nil && !resp.IsSuccess {
+		err = fmt.Errorf("channel refund rejected: %s", resp.Message)
+	}
+	if err != nil {
+		return s.recordFailure(record, err)
+	}
+
+	record.ChannelRefundNo = &resp.ChannelRefundNo
+	if resp.Pending {
+		// 渠道已受理，退款结果以退款回调为准
+		if err := s.refundRepo.MarkAccepted(record, s.now()); err != nil {
+			return fmt.Errorf("failed to save refund acceptance: %w", err)
+		}
+		record.Status = int(enums.RefundStatusAccepted)
+		return nil
+	}
+	refundedAt := s.now()
+	refunded := newRefundEvent(contracts.EventOrderRefunded, record, refundedAt)
+	if err := s.refundRepo.MarkSucceeded(record, refundedAt, refunded); err != nil {
+		return fmt.Errorf("failed to save refund result: %w", err)
+	}
+	record.Status = int(enums.RefundStatusSuccess)
+	return nil
+}
+
+// callChannel 调用支付渠道退款接口
+func (s *refundService) callChannel(
+	record *models.RefundRecord, order *models.Order,
+) (*contracts.PaymentRefundResponse, error) {
+	if order.MachineId == nil {
+		return nil, errors.New("order has no machine")
+	}
+	account, err := s.paymentSvc.GetPaymentAccount(*order.MachineId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment account: %w", err)
+	}
+
+	channelCode, err := ResolveChannelCode(ptrToString(order.ChannelCode), account)
+	if err != nil {
+		return nil, err
+	}
+
+	reason := ""
+	if record.Reason != nil {
+		reason = *record.Reason
+	}
+
+	return s.paymentSvc.Refund(contracts.PaymentRefundRequest{
+		Ext1:        account.ReceivingAccount,
+		Ext2:        account.ReceivingKey,
+		Ext3:        account.ReceivingOrderPrefix,
+		Ext4:        account.MerchantSerialNo,
+		Ext5:        account.MerchantPrivateKey,
+		ChannelCode: channelCode,
+		OrderNo:     ptrToString(order.OrderNo),
+		RefundNo:    record.RefundNo,
+		TotalAmt:    yuanToFen(order.PayAmount),
+		RefundAmt:   yuanToFen(record.Amount),
+		Reason:      reason,
+	})
+}
+
+// recordFailure 记录退款失败，未超过最大次数时安排重试，否则标记失败待人工处理并恢复订单为已支付
+func (s *refundService) recordFailure(record *models.RefundRecord, cause error) error {
+	message := cause.Error()
+	if runes := []rune(message); len(runes) > 512 {
+		message = string(runes[:512])
+	}
+	record.LastError = &message
+
+	if record.Attempts >= s.maxAttempts {
+		record.Status = int(enums.RefundStatusFailed)
+		record.NextRetryOn = nil
+		failedAt := s.now()
+		failed := newRefundEvent(contracts.EventOrderRefundFailed, record, failedAt)
+		if err := s.refundRepo.MarkFailed(record, failedAt, failed); err != nil {
+			return fmt.Errorf("failed to save refund failure: %w", err)
+		}
+		return cause
+	}
+
+	record.Status = int(enums.RefundStatusPending)
+	nextRetry := s.now().Add(refundRetryDelay(record.Attempts))
+	record.NextRetryOn = &nextRetry
+	err := s.refundRepo.Update(record)
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		// 调用渠道期间退款回调已记录结果，以已记录的结果为准，不再覆盖为待重试
+		return s.reloadSettled(record)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save refund failure: %w", err)
+	}
+	return cause
+}
+
+// reloadSettled 重新读取已由其他流程处理的退款记录
+func (s *refundService) reloadSettled(record *models.RefundRecord) error {
+	latest, err := s.refundRepo.GetByID(record.ID)
+	if err != nil {
+		return fmt.Errorf("failed to reload refund record: %w", err)
+	}
+	s.logger.WithField("refund_no", record.RefundNo).Info("退款记录已由回调处理，忽略本次渠道调用失败")
+	*record = *latest
+	return nil
+}
+
+// refundRetryDelay 第attempts次失败后的重试间隔
+func refundRetryDelay(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := refundRetryBaseDelay << uint(attempts-1) // #nosec G115 - attempts is bounded by refundMaxAttempts
+	if delay <= 0 || delay > refundRetryMaxDelay {
+		return refundRetryMaxDelay
+	}
+	return delay
+}
+
+// yuanToFen 元转分
+func yuanToFen(amount float64) int32 {
+	fen := decimal.NewFromFloat(amount).Mul(decimal.NewFromInt(100)).Round(0).IntPart()
+	if fen > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	if fen < 0 {
+		return 0
+	}
+	return int32(fen) // #nosec G115 - bounds checked above
+}
@@ -0,0 +1,504 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+)
+
+// MockPaymentService for testing
+type MockPaymentService struct {
+	mock.Mock
+}
+
+func (m *MockPaymentService) WeChatPay(req contracts.WeChatPayRequest) (*contracts.WeChatPayResponse, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*contracts.WeChatPayResponse), args.Error(1)
+}
+
+func (m *MockPaymentService) TranQuery(req contracts.TranQueryRequest) (*contracts.TranQueryResponse, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*contracts.TranQueryResponse), args.Error(1)
+}
+
+func (m *MockPaymentService) Refund(req contracts.PaymentRefundRequest) (*contracts.PaymentRefundResponse, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*contracts.PaymentRefundResponse), args.Error(1)
+}
+
+func (m *MockPaymentService) Close(req contracts.PaymentCloseRequest) (*contracts.PaymentCloseResponse, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*contracts.PaymentCloseResponse), args.Error(1)
+}
+
+func (m *MockPaymentService) VerifyCallback(
+	channelCode string, account contracts.PaymentAccount, req contracts.PaymentCallbackResultRequest,
+) error {
+	args := m.Called(channelCode, account, req)
+	return args.Error(0)
+}
+
+func (m *MockPaymentService) ParseNotify(
+	channelCode, merchantID string, header http.Header, body []byte,
+) (*contracts.PaymentCallbackResultRequest, error) {
+	args := m.Called(channelCode, merchantID, header, body)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*contracts.PaymentCallbackResultRequest), args.Error(1)
+}
+
+func (m *MockPaymentService) VerifyRefundCallback(
+	channelCode string, account contracts.PaymentAccount, req contracts.RefundCallbackRequest,
+) error {
+	args := m.Called(channelCode, account, req)
+	return args.Error(0)
+}
+
+func (m *MockPaymentService) ParseRefundNotify(
+	channelCode, merchantID string, header http.Header, body []byte,
+) (*contracts.RefundCallbackRequest, error) {
+	args := m.Called(channelCode, merchantID, header, body)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*contracts.RefundCallbackRequest), args.Error(1)
+}
+
+func (m *MockPaymentService) BindOrderChannel(orderID string, channelCode string) error {
+	args := m.Called(orderID, channelCode)
+	return args.Error(0)
+}
+
+func (m *MockPaymentService) GetPaymentAccount(machineID string) (*contracts.PaymentAccount, error) {
+	args := m.Called(machineID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*contracts.PaymentAccount), args.Error(1)
+}
+
+func (m *MockPaymentService) PayOrder(req contracts.PayOrderRequest) error {
+	return m.Called(req).Error(0)
+}
+
+func (m *MockPaymentService) InvalidOrder(req contracts.InvalidOrderRequest) error {
+	return m.Called(req).Error(0)
+}
+
+// MockRefundService for testing
+type MockRefundService struct {
+	mock.Mock
+}
+
+func (m *MockRefundService) AutoRefund(orderID, reason string) (*models.RefundRecord, error) {
+	args := m.Called(orderID, reason)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RefundRecord), args.Error(1)
+}
+
+func (m *MockRefundService) Refund(orderID string, amount decimal.Decimal, reason string) (*models.RefundRecord, error) {
+	args := m.Called(orderID, amount, reason)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RefundRecord), args.Error(1)
+}
+
+func (m *MockRefundService) HandleRefundCallback(req contracts.RefundCallbackRequest) error {
+	return m.Called(req).Error(0)
+}
+
+func (m *MockRefundService) HandleRefundNotify(channelCode, merchantID string, header http.Header, body []byte) error {
+	return m.Called(channelCode, merchantID, header, body).Error(0)
+}
+
+func (m *MockRefundService) RetryPending() (int, error) {
+	args := m.Called()
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRefundService) Start(interval time.Duration) {
+	m.Called(interval)
+}
+
+func (m *MockRefundService) Stop() {
+	m.Called()
+}
+
+// setupRefundService 创建使用内存数据库与模拟支付服务的退款服务，并写入一笔已支付订单
+func setupRefundService(t *testing.T) (*refundService, *gorm.DB, *MockPaymentService) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Order{}, &models.RefundRecord{}, &models.OutboxEvent{}))
+
+	paidAt := time.Now()
+	require.NoError(t, db.Create(&models.Order{
+		ID:            "order-1",
+		MachineId:     stringPtr("machine-1"),
+		OrderNo:       stringPtr("ORD001"),
+		PayAmount:     15.80,
+		TotalAmount:   15.80,
+		PaymentStatus: int(enums.PaymentStatusPaid),
+		PaymentTime:   &paidAt,
+		MakeStatus:    int(enums.MakeStatusMakeFail),
+	}).Error)
+
+	paymentSvc := new(MockPaymentService)
+	paymentSvc.On("GetPaymentAccount", "machine-1").Return(&contracts.PaymentAccount{
+		ReceivingAccount:     "merchant",
+		ReceivingKey:         "key",
+		ReceivingOrderPrefix: "VM_",
+	}, nil)
+
+	service := &refundService{
+		orderRepo:   repositories.NewOrderRepository(db),
+		refundRepo:  repositories.NewRefundRecordRepository(db),
+		paymentSvc:  paymentSvc,
+		now:         time.Now,
+		logger:      logrus.New(),
+		stop:        make(chan struct{}),
+		maxAttempts: refundMaxAttempts,
+	}
+	return service, db, paymentSvc
+}
+
+func TestRefundService_AutoRefund(t *testing.T) {
+	service, db, paymentSvc := setupRefundService(t)
+
+	paymentSvc.On("Refund", mock.MatchedBy(func(req contracts.PaymentRefundRequest) bool {
+		return req.RefundNo == "RFORD001" && req.TotalAmt == 1580 && req.RefundAmt == 1580 &&
+			req.Ext1 == "merchant" && req.Reason == refundReasonMakeFail
+	})).Return(&contracts.PaymentRefundResponse{IsSuccess: true, ChannelRefundNo: "CH001"}, nil)
+
+	record, err := service.AutoRefund("order-1", refundReasonMakeFail)
+	require.NoError(t, err)
+	assert.Equal(t, int(enums.RefundStatusSuccess), record.Status)
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", "order-1").Error)
+	assert.Equal(t, int(enums.PaymentStatusRefunded), order.PaymentStatus)
+	assert.Equal(t, 15.80, order.RefundAmount)
+	assert.NotNil(t, order.RefundTime)
+	assert.Equal(t, refundReasonMakeFail, *order.RefundReason)
+
+	// 重复触发不会再次调用渠道退款
+	record, err = service.AutoRefund("order-1", refundReasonMakeFail)
+	require.NoError(t, err)
+	assert.Equal(t, int(enums.RefundStatusSuccess), record.Status)
+	paymentSvc.AssertNumberOfCalls(t, "Refund", 1)
+
+	var count int64
+	db.Model(&models.RefundRecord{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+	assert.ElementsMatch(t, []string{contracts.EventOrderRefunding, contracts.EventOrderRefunded}, outboxEventTypes(t, db))
+}
+
+func TestPaymentService_PayOrder_InvalidatedOrderRefunds(t *testing.T) {
+	service, db, paymentSvc := setupRefundService(t)
+	require.NoError(t, db.Model(&models.Order{}).Where("Id = ?", "order-1").Updates(map[string]interface{}{
+		"PaymentStatus": int(enums.PaymentStatusInvalid),
+		"PaymentTime":   nil,
+	}).Error)
+	payment := &paymentService{orderRepo: service.orderRepo, refundRepo: service.refundRepo}
+
+	// 作废后到账的支付补记后自动退款，不下发制作
+	paidAt := time.Now()
+	req := contracts.PayOrderRequest{ID: "order-1", ChannelOrderNo: "wx_late", PaidAt: paidAt}
+	require.NoError(t, payment.PayOrder(req))
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", "order-1").Error)
+	assert.Equal(t, int(enums.PaymentStatusRefunding), order.PaymentStatus)
+	assert.Equal(t, "wx_late", *order.ChannelOrderNo)
+	assert.NotNil(t, order.PaymentTime)
+
+	record, err := service.refundRepo.GetByRefundNo("RFORD001")
+	require.NoError(t, err)
+	assert.Equal(t, int(enums.RefundStatusPending), record.Status)
+	assert.Equal(t, 15.80, record.Amount)
+	assert.Equal(t, refundReasonInvalidOrder, *record.Reason)
+
+	// 重复通知不再创建退款
+	assert.ErrorIs(t, payment.PayOrder(req), ErrOrderNotWaitPay)
+
+	// 退款重试任务向渠道发起退款
+	paymentSvc.On("Refund", mock.MatchedBy(func(req contracts.PaymentRefundRequest) bool {
+		return req.RefundNo == "RFORD001" && req.RefundAmt == 1580
+	})).Return(&contracts.PaymentRefundResponse{IsSuccess: true, ChannelRefundNo: "CH001"}, nil)
+	succeeded, err := service.RetryPending()
+	require.NoError(t, err)
+	assert.Equal(t, 1, succeeded)
+
+	require.NoError(t, db.First(&order, "Id = ?", "order-1").Error)
+	assert.Equal(t, int(enums.PaymentStatusRefunded), order.PaymentStatus)
+	assert.ElementsMatch(t, []string{contracts.EventOrderRefunding, contracts.EventOrderRefunded}, outboxEventTypes(t, db))
+}
+
+func TestRefundService_RetryAfterFailure(t *testing.T) {
+	service, db, paymentSvc := setupRefundService(t)
+
+	paymentSvc.On("Refund", mock.Anything).Return(nil, errors.New("channel unavailable")).Once()
+
+	record, err := service.AutoRefund("order-1", refundReasonMakeFail)
+	assert.Error(t, err)
+	require.NotNil(t, record)
+	assert.Equal(t, int(enums.RefundStatusPending), record.Status)
+	assert.Equal(t, 1, record.Attempts)
+	require.NotNil(t, record.NextRetryOn)
+	assert.Equal(t, "channel unavailable", *record.LastError)
+
+	// 未到重试时间不会重试
+	count, err := service.RetryPending()
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	paymentSvc.On("Refund", mock.Anything).
+		Return(&contracts.PaymentRefundResponse{IsSuccess: true, ChannelRefundNo: "CH001"}, nil).Once()
+	service.now = func() time.Time { return time.Now().Add(2 * refundRetryBaseDelay) }
+
+	count, err = service.RetryPending()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	var saved models.RefundRecord
+	require.NoError(t, db.First(&saved, "RefundNo = ?", "RFORD001").Error)
+	assert.Equal(t, int(enums.RefundStatusSuccess), saved.Status)
+	assert.Equal(t, 2, saved.Attempts)
+	assert.Equal(t, "CH001", *saved.ChannelRefundNo)
+}
+
+func TestRefundService_FailureAfterCallbackSettled(t *testing.T) {
+	service, db, paymentSvc := setupRefundService(t)
+
+	// 渠道调用超时期间退款成功回调已到达，失败结果不覆盖已记录的成功
+	paymentSvc.On("Refund", mock.Anything).Run(func(args mock.Arguments) {
+		req := args.Get(0).(contracts.PaymentRefundRequest)
+		record, err := service.refundRepo.GetByRefundNo(req.RefundNo)
+		require.NoError(t, err)
+		require.NoError(t, service.refundRepo.MarkSucceeded(record, time.Now()))
+	}).Return(nil, errors.New("read timeout"))
+
+	record, err := service.AutoRefund("order-1", refundReasonMakeFail)
+	require.NoError(t, err)
+	assert.Equal(t, int(enums.RefundStatusSuccess), record.Status)
+
+	var saved models.RefundRecord
+	require.NoError(t, db.First(&saved, "RefundNo = ?", "RFORD001").Error)
+	assert.Equal(t, int(enums.RefundStatusSuccess), saved.Status)
+	assert.Nil(t, saved.NextRetryOn)
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", "order-1").Error)
+	assert.Equal(t, int(enums.PaymentStatusRefunded), order.PaymentStatus)
+}
+
+func TestRefundService_GiveUpAfterMaxAttempts(t *testing.T) {
+	service, db, paymentSvc := setupRefundService(t)
+	service.maxAttempts = 1
+
+	paymentSvc.On("Refund", mock.Anything).
+		Return(&contracts.PaymentRefundResponse{IsSuccess: false, Message: "余额不足"}, nil)
+
+	record, err := service.AutoRefund("order-1", refundReasonMakeFail)
+	assert.Error(t, err)
+	assert.Equal(t, int(enums.RefundStatusFailed), record.Status)
+	assert.Nil(t, record.NextRetryOn)
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", "order-1").Error)
+	assert.Equal(t, int(enums.PaymentStatusPaid), order.PaymentStatus, "退款未成功时订单保持已支付")
+	assert.ElementsMatch(t, []string{contracts.EventOrderRefunding, contracts.EventOrderRefundFailed}, outboxEventTypes(t, db))
+}
+
+func TestRefundService_AutoRefund_NotPaid(t *testing.T) {
+	service, db, _ := setupRefundService(t)
+	require.NoError(t, db.Model(&models.Order{}).Where("Id = ?", "order-1").
+		Update("PaymentStatus", int(enums.PaymentStatusWaitPay)).Error)
+
+	_, err := service.AutoRefund("order-1", refundReasonMakeFail)
+	assert.ErrorIs(t, err, ErrRefundNotAllowed)
+}
+
+func TestRefundService_Refund_Partial(t *testing.T) {
+	service, db, paymentSvc := setupRefundService(t)
+
+	paymentSvc.On("Refund", mock.MatchedBy(func(req contracts.PaymentRefundRequest) bool {
+		return req.RefundNo == "RFORD00101" && req.TotalAmt == 1580 && req.RefundAmt == 500
+	})).Return(&contracts.PaymentRefundResponse{IsSuccess: true, ChannelRefundNo: "CH001"}, nil).Once()
+
+	record, err := service.Refund("order-1", decimal.RequireFromString("5.00"), "少出一杯")
+	require.NoError(t, err)
+	assert.Equal(t, int(enums.RefundStatusSuccess), record.Status)
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", "order-1").Error)
+	assert.Equal(t, int(enums.PaymentStatusPaid), order.PaymentStatus, "部分退款后订单仍为已支付")
+	assert.Equal(t, 5.00, order.RefundAmount)
+
+	// 超过剩余可退金额
+	_, err = service.Refund("order-1", decimal.RequireFromString("10.81"), "")
+	assert.ErrorIs(t, err, ErrRefundAmountExceeded)
+	_, err = service.Refund("order-1", decimal.RequireFromString("-1"), "")
+	assert.ErrorIs(t, err, ErrInvalidRefundAmount)
+
+	// 未指定金额时退还剩余金额
+	paymentSvc.On("Refund", mock.MatchedBy(func(req contracts.PaymentRefundRequest) bool {
+		return req.RefundNo == "RFORD00102" && req.RefundAmt == 1080
+	})).Return(&contracts.PaymentRefundResponse{IsSuccess: true, ChannelRefundNo: "CH002"}, nil).Once()
+
+	record, err = service.Refund("order-1", decimal.Zero, "")
+	require.NoError(t, err)
+	assert.Equal(t, 10.80, record.Amount)
+
+	require.NoError(t, db.First(&order, "Id = ?", "order-1").Error)
+	assert.Equal(t, int(enums.PaymentStatusRefunded), order.PaymentStatus)
+	assert.Equal(t, 15.80, order.RefundAmount)
+
+	_, err = service.Refund("order-1", decimal.Zero, "")
+	assert.ErrorIs(t, err, ErrOrderAlreadyRefunded)
+}
+
+func TestRefundService_Refund_PendingUntilCallback(t *testing.T) {
+	service, db, paymentSvc := setupRefundService(t)
+
+	paymentSvc.On("Refund", mock.Anything).
+		Return(&contracts.PaymentRefundResponse{IsSuccess: true, Pending: true, ChannelRefundNo: "CH001"}, nil)
+
+	record, err := service.Refund("order-1", decimal.Zero, "设备故障")
+	require.NoError(t, err)
+	assert.Equal(t, int(enums.RefundStatusAccepted), record.Status)
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", "order-1").Error)
+	assert.Equal(t, int(enums.PaymentStatusRefunding), order.PaymentStatus)
+	assert.Zero(t, order.RefundAmount)
+
+	// 渠道确认前不能再次发起退款
+	_, err = service.Refund("order-1", decimal.Zero, "")
+	assert.ErrorIs(t, err, ErrRefundInProgress)
+
+	callback := contracts.RefundCallbackRequest{
+		ChannelCode:  contracts.ChannelCodeFuiouMerchant,
+		OrderNo:      "VM_ORD001",
+		RefundNo:     record.RefundNo,
+		RefundAmt:    1580,
+		RefundStatus: contracts.RefundResultSuccess,
+	}
+	paymentSvc.On("VerifyRefundCallback", contracts.ChannelCodeFuiouMerchant, mock.Anything, callback).Return(nil)
+
+	require.NoError(t, service.HandleRefundCallback(callback))
+	require.NoError(t, service.HandleRefundCallback(callback))
+
+	require.NoError(t, db.First(&order, "Id = ?", "order-1").Error)
+	assert.Equal(t, int(enums.PaymentStatusRefunded), order.PaymentStatus)
+	assert.Equal(t, 15.80, order.RefundAmount)
+}
+
+func TestRefundService_HandleRefundCallback_Rejected(t *testing.T) {
+	service, db, paymentSvc := setupRefundService(t)
+
+	paymentSvc.On("Refund", mock.Anything).
+		Return(&contracts.PaymentRefundResponse{IsSuccess: true, Pending: true}, nil)
+	record, err := service.Refund("order-1", decimal.Zero, "")
+	require.NoError(t, err)
+
+	callback := contracts.RefundCallbackRequest{
+		OrderNo:      "ORD001",
+		RefundNo:     record.RefundNo,
+		RefundAmt:    1580,
+		RefundStatus: contracts.RefundResultSuccess,
+	}
+	paymentSvc.On("VerifyRefundCallback", mock.Anything, mock.Anything, mock.Anything).
+		Return(ErrInvalidCallbackSignature)
+
+	err = service.HandleRefundCallback(callback)
+	assert.ErrorIs(t, err, ErrRefundCallbackRejected)
+	assert.ErrorIs(t, err, ErrInvalidCallbackSignature)
+
+	callback.RefundNo = "RF-unknown"
+	assert.ErrorIs(t, service.HandleRefundCallback(callback), ErrRefundRecordNotFound)
+
+	var order models.Order
+	require.NoError(t, db.First(&order, "Id = ?", "order-1").Error)
+	assert.Equal(t, int(enums.PaymentStatusRefunding), order.PaymentStatus)
+}
+
+func TestRefundService_Refund_NotAllowed(t *testing.T) {
+	service, db, _ := setupRefundService(t)
+
+	_, err := service.Refund("missing", decimal.Zero, "")
+	assert.ErrorIs(t, err, ErrRefundOrderNotFound)
+
+	require.NoError(t, db.Model(&models.Order{}).Where("Id = ?", "order-1").
+		Update("PaymentStatus", int(enums.PaymentStatusWaitPay)).Error)
+	_, err = service.Refund("order-1", decimal.Zero, "")
+	assert.ErrorIs(t, err, ErrRefundNotAllowed)
+}
+
+func TestMakeFailRefundSubscriber(t *testing.T) {
+	refundSvc := new(MockRefundService)
+	subscriber := MakeFailRefundSubscriber(refundSvc)
+	makeFailed := func(orderID string) contracts.OrderEvent {
+		return contracts.OrderEvent{Type: contracts.EventOrderMakeFailed, OrderID: orderID}
+	}
+
+	require.NoError(t, subscriber(contracts.OrderEvent{Type: contracts.EventOrderMaking, OrderID: "order-1"}))
+
+	// 退款记录已创建时渠道失败由退款重试任务处理
+	refundSvc.On("AutoRefund", "order-1", refundReasonMakeFail).
+		Return(&models.RefundRecord{}, errors.New("channel busy")).Once()
+	require.NoError(t, subscriber(makeFailed("order-1")))
+
+	// 未能创建退款记录时事件稍后重新投递，订单已退款则不再重试
+	refundSvc.On("AutoRefund", "order-2", refundReasonMakeFail).
+		Return(nil, errors.New("db down")).Once()
+	assert.Error(t, subscriber(makeFailed("order-2")))
+	refundSvc.On("AutoRefund", "order-3", refundReasonMakeFail).
+		Return(nil, ErrOrderAlreadyRefunded).Once()
+	require.NoError(t, subscriber(makeFailed("order-3")))
+
+	refundSvc.AssertExpectations(t)
+}
+
+func TestRefundRetryDelay(t *testing.T) {
+	assert.Equal(t, time.Minute, refundRetryDelay(1))
+	assert.Equal(t, 4*time.Minute, refundRetryDelay(3))
+	assert.Equal(t, refundRetryMaxDelay, refundRetryDelay(10))
+}
+
+func TestYuanToFen(t *testing.T) {
+	assert.Equal(t, int32(1580), yuanToFen(15.80))
+	assert.Equal(t, int32(1), yuanToFen(0.01))
+	assert.Equal(t, int32(0), yuanToFen(-1))
+}
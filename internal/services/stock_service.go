@@ -0,0 +1,111 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+)
+
+// ErrInsufficientStock 售货机没有可售库存的料仓
+var ErrInsufficientStock = errors.New("商品已售罄")
+
+// 每杯饮品占用的料仓库存
+const stockPerOrder = 1
+
+// StockServiceInterface 库存服务接口
+// 下单时预占料仓库存，制作完成时扣减，支付失败或制作失败时释放
+type StockServiceInterface interface {
+	Reserve(orderID, machineID, productID string) error
+	Commit(orderID string) error
+	Release(orderID string) error
+}
+
+// stockService 库存服务实现
+type stockService struct {
+	siloRepo        repositories.MaterialSiloRepositoryInterface
+	reservationRepo repositories.StockReservationRepository
+	now             func() time.Time
+}
+
+// NewStockService 创建库存服务
+func NewStockService(db *gorm.DB) StockServiceInterface {
+	return &stockService{
+		siloRepo:        repositories.NewMaterialSiloRepository(db),
+		reservationRepo: repositories.NewStockReservationRepository(db),
+		now:             time.Now,
+	}
+}
+
+// Reserve 为订单预占售货机中该商品的一个可售料仓库存
+// 按槽位顺序尝试可售料仓，并发下单占用失败时尝试下一个料仓
+func (s *stockService) Reserve(orderID, machineID, productID string) error {
+	silos, err := s.siloRepo.GetByMachineAndProduct(machineID, productID)
+	if err != nil {
+		return fmt.Errorf("查询料仓失败: %w", err)
+	}
+
+	for _, silo := range silos {
+		if !silo.CanSale() {
+			continue
+		}
+		reserved, reserveErr := s.reservationRepo.Reserve(&models.StockReservation{
+			OrderId:   orderID,
+			SiloId:    silo.ID,
+			Quantity:  stockPerOrder,
+			CreatedOn: s.now(),
+		})
+		if reserveErr != nil {
+			return fmt.Errorf("预占库存失败: %w", reserveErr)
+		}
+		if reserved {
+			return nil
+		}
+	}
+	return ErrInsufficientStock
+}
+
+// Commit 扣减订单预占的库存，库存耗尽的料仓自动停售
+func (s *stockService) Commit(orderID string) error {
+	soldOut, err := s.reservationRepo.Commit(orderID, s.now())
+	if err != nil {
+		return fmt.Errorf("扣减库存失败: %w", err)
+	}
+	if soldOut {
+		logrus.WithField("order_id", orderID).Info("料仓库存耗尽，已自动停售")
+	}
+	return nil
+}
+
+// Release 释放订单预占的库存，重复释放或预占已扣减时不做处理
+func (s *stockService) Release(orderID string) error {
+	if _, err := s.reservationRepo.Release(orderID, s.now()); err != nil {
+		return fmt.Errorf("释放库存失败: %w", err)
+	}
+	return nil
+}
+
+// StockReservationSubscriber 按订单领域事件结算预占库存的订阅者
+// 制作完成时扣减，制作失败或订单作废时释放；commitOnPaid 为true时（收不到设备制作结果）支付成功即扣减
+// 扣减与释放只处理仍在预占中的记录，重复投递不会重复结算
+func StockReservationSubscriber(stockSvc StockServiceInterface, commitOnPaid bool) OrderEventHandler {
+	return func(event contracts.OrderEvent) error {
+		switch event.Type {
+		case contracts.EventOrderPaid:
+			if commitOnPaid {
+				return stockSvc.Commit(event.OrderID)
+			}
+		case contracts.EventOrderMade:
+			return stockSvc.Commit(event.OrderID)
+		case contracts.EventOrderMakeFailed, contracts.EventOrderInvalidated:
+			return stockSvc.Release(event.OrderID)
+		}
+		return nil
+	}
+}
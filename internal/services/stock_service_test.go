@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/models"
+)
+
+type MockStockService struct {
+	mock.Mock
+}
+
+func (m *MockStockService) Reserve(orderID, machineID, productID string) error {
+	args := m.Called(orderID, machineID, productID)
+	return args.Error(0)
+}
+
+func (m *MockStockService) Commit(orderID string) error {
+	args := m.Called(orderID)
+	return args.Error(0)
+}
+
+func (m *MockStockService) Release(orderID string) error {
+	args := m.Called(orderID)
+	return args.Error(0)
+}
+
+func setupStockService(t *testing.T) (StockServiceInterface, *gorm.DB) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.MaterialSilo{}, &models.StockReservation{}))
+
+	silos := []models.MaterialSilo{
+		// 停售料仓不参与预占
+		{ID: "silo-1", No: stringPtr("1"), IsSale: models.BitBool(0), Stock: 5},
+		{ID: "silo-2", No: stringPtr("2"), IsSale: models.BitBool(1), Stock: 1},
+		{ID: "silo-3", No: stringPtr("3"), IsSale: models.BitBool(1), Stock: 1},
+	}
+	for i := range silos {
+		silos[i].MachineId = stringPtr("machine-1")
+		silos[i].ProductId = stringPtr("product-1")
+		silos[i].Total = 10
+		silos[i].CreatedOn = time.Now()
+		require.NoError(t, db.Create(&silos[i]).Error)
+	}
+
+	return NewStockService(db), db
+}
+
+func TestStockService_Reserve(t *testing.T) {
+	stockSvc, db := setupStockService(t)
+
+	require.NoError(t, stockSvc.Reserve("order-1", "machine-1", "product-1"))
+	require.NoError(t, stockSvc.Reserve("order-2", "machine-1", "product-1"))
+	assert.ErrorIs(t, stockSvc.Reserve("order-3", "machine-1", "product-1"), ErrInsufficientStock)
+	assert.ErrorIs(t, stockSvc.Reserve("order-4", "machine-1", "product-2"), ErrInsufficientStock)
+
+	var reservations []models.StockReservation
+	require.NoError(t, db.Order("OrderId").Find(&reservations).Error)
+	require.Len(t, reservations, 2)
+	assert.Equal(t, "silo-2", reservations[0].SiloId)
+	assert.Equal(t, "silo-3", reservations[1].SiloId)
+
+	// 释放后库存可再次售出
+	require.NoError(t, stockSvc.Release("order-1"))
+	require.NoError(t, stockSvc.Reserve("order-3", "machine-1", "product-1"))
+}
+
+func TestStockService_CommitSoldOut(t *testing.T) {
+	stockSvc, db := setupStockService(t)
+	require.NoError(t, stockSvc.Reserve("order-1", "machine-1", "product-1"))
+
+	require.NoError(t, stockSvc.Commit("order-1"))
+
+	var silo models.MaterialSilo
+	require.NoError(t, db.First(&silo, "Id = ?", "silo-2").Error)
+	assert.Equal(t, 0, silo.Stock)
+	assert.Equal(t, 0, silo.Reserved)
+	assert.False(t, silo.IsSale.Bool())
+}
+
+func TestStockReservationSubscriber(t *testing.T) {
+	stockSvc := &MockStockService{}
+	event := func(eventType string) contracts.OrderEvent {
+		return contracts.OrderEvent{Type: eventType, OrderID: "order-1"}
+	}
+
+	// 接入设备时按制作结果结算
+	subscriber := StockReservationSubscriber(stockSvc, false)
+	stockSvc.On("Commit", "order-1").Return(nil)
+	require.NoError(t, subscriber(event(contracts.EventOrderPaid)))
+	require.NoError(t, subscriber(event(contracts.EventOrderMade)))
+
+	// 释放失败时事件稍后重新投递
+	stockSvc.On("Release", "order-1").Return(errors.New("db down")).Once()
+	assert.Error(t, subscriber(event(contracts.EventOrderMakeFailed)))
+	stockSvc.On("Release", "order-1").Return(nil)
+	require.NoError(t, subscriber(event(contracts.EventOrderInvalidated)))
+	require.NoError(t, subscriber(event(contracts.EventOrderMaking)))
+	stockSvc.AssertNumberOfCalls(t, "Commit", 1)
+	stockSvc.AssertNumberOfCalls(t, "Release", 2)
+
+	// 收不到制作结果时支付成功即扣减
+	require.NoError(t, StockReservationSubscriber(stockSvc, true)(event(contracts.EventOrderPaid)))
+	stockSvc.AssertNumberOfCalls(t, "Commit", 2)
+}
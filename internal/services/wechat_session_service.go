@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/config"
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/internal/repositories"
+	"github.com/ddteam/drink-master/pkg/secret"
+	"github.com/ddteam/drink-master/pkg/wechat"
+)
+
+var (
+	// ErrWeChatSessionExpired 未保存会话密钥或密钥已失效（用户已在其他地方重新登录），需重新登录
+	ErrWeChatSessionExpired = errors.New("微信会话已失效，请重新登录")
+	// ErrWeChatDataMismatch 加密数据不属于本小程序或当前会员
+	ErrWeChatDataMismatch = errors.New("加密数据与当前用户不匹配")
+	// errWeChatSessionSecretNotConfigured 未配置会话密钥加密密钥
+	errWeChatSessionSecretNotConfigured = errors.New("wechat session secret key is not configured")
+)
+
+// wechatSessionSecretBox 根据环境配置创建会话密钥加解密器
+func wechatSessionSecretBox() (*secret.Box, error) {
+	cfg := config.NewWeChatConfig()
+	if !cfg.SessionStorageEnabled() {
+		return nil, errWeChatSessionSecretNotConfigured
+	}
+	return secret.NewFromBase64(cfg.SessionSecretKey)
+}
+
+// WeChatSessionServiceInterface 微信会话服务接口
+// 登录时加密保存会员的 session_key 并记录 UnionID，之后用于解密小程序的 encryptedData
+type WeChatSessionServiceInterface interface {
+	SaveSession(member *models.Member, session *wechat.SessionResponse) error
+	DecryptPhoneNumber(
+		memberID string,
+		req contracts.WeChatEncryptedDataRequest,
+	) (*contracts.WeChatPhoneNumberResponse, error)
+	DecryptUserInfo(
+		memberID string,
+		req contracts.WeChatEncryptedDataRequest,
+	) (*contracts.WeChatUserInfoResponse, error)
+}
+
+// weChatSessionService 微信会话服务实现
+type weChatSessionService struct {
+	sessionRepo repositories.WeChatSessionRepository
+	memberRepo  *repositories.MemberRepository
+	client      *wechat.Client
+}
+
+// NewWeChatSessionService 创建微信会话服务
+func NewWeChatSessionService(db *gorm.DB, client *wechat.Client) WeChatSessionServiceInterface {
+	return &weChatSessionService{
+		sessionRepo: repositories.NewWeChatSessionRepository(db),
+		memberRepo:  repositories.NewMemberRepository(db),
+		client:      client,
+	}
+}
+
+// SaveSession 记录会员的 UnionID 并加密保存 session_key，覆盖之前登录的会话
+func (s *weChatSessionService) SaveSession(member *models.Member, session *wechat.SessionResponse) error {
+	if err := s.linkUnionID(member, session.UnionID); err != nil {
+		return err
+	}
+
+	box, err := wechatSessionSecretBox()
+	if err != nil {
+		return err
+	}
+	encrypted, err := box.Encrypt(session.SessionKey)
+	if err != nil {
+		return err
+	}
+	return s.sessionRepo.Save(&models.WeChatSession{
+		MemberId:   member.ID,
+		OpenId:     session.OpenID,
+		SessionKey: encrypted,
+	})
+}
+
+// DecryptPhoneNumber 解密 wx.getPhoneNumber 返回的手机号，手机号不落库
+func (s *weChatSessionService) DecryptPhoneNumber(
+	memberID string,
+	req contracts.WeChatEncryptedDataRequest,
+) (*contracts.WeChatPhoneNumberResponse, error) {
+	session, sessionKey, err := s.loadSession(memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	phone, err := s.client.DecryptPhoneNumber(sessionKey, req.EncryptedData, req.Iv)
+	if err != nil {
+		return nil, decryptError(session, err)
+	}
+	return &contracts.WeChatPhoneNumberResponse{
+		PhoneNumber:     phone.PhoneNumber,
+		PurePhoneNumber: phone.PurePhoneNumber,
+		CountryCode:     phone.CountryCode,
+	}, nil
+}
+
+// DecryptUserInfo 解密 wx.getUserInfo 返回的用户信息，含 UnionID 时关联到会员
+func (s *weChatSessionService) DecryptUserInfo(
+	memberID string,
+	req contracts.WeChatEncryptedDataRequest,
+) (*contracts.WeChatUserInfoResponse, error) {
+	session, sessionKey, err := s.loadSession(memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := s.client.DecryptUserInfo(sessionKey, req.EncryptedData, req.Iv)
+	if err != nil {
+		return nil, decryptError(session, err)
+	}
+	if info.OpenID != session.OpenId {
+		return nil, ErrWeChatDataMismatch
+	}
+
+	if info.UnionID != "" {
+		member, memberErr := s.memberRepo.GetByID(memberID)
+		if memberErr != nil {
+			return nil, memberErr
+		}
+		if linkErr := s.linkUnionID(member, info.UnionID); linkErr != nil {
+			return nil, linkErr
+		}
+	}
+
+	return &contracts.WeChatUserInfoResponse{
+		Nickname:  info.NickName,
+		AvatarUrl: info.AvatarURL,
+		UnionId:   info.UnionID,
+	}, nil
+}
+
+// loadSession 获取并解密会员保存的 session_key
+func (s *weChatSessionService) loadSession(memberID string) (*models.WeChatSession, string, error) {
+	session, err := s.sessionRepo.GetByMemberID(memberID)
+	if err != nil {
+		return nil, "", err
+	}
+	if session == nil {
+		return nil, "", ErrWeChatSessionExpired
+	}
+
+	box, err := wechatSessionSecretBox()
+	if err != nil {
+		return nil, "", err
+	}
+	sessionKey, err := box.Decrypt(session.SessionKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decrypt session key of member %s: %w", memberID, err)
+	}
+	return session, sessionKey, nil
+}
+
+// linkUnionID 记录会员的 UnionID，未变化时不更新
+func (s *weChatSessionService) linkUnionID(member *models.Member, unionID string) error {
+	if unionID == "" || ptrToString(member.UnionId) == unionID {
+		return nil
+	}
+	member.UnionId = &unionID
+	return s.memberRepo.Update(member)
+}
+
+// decryptError 将解密错误转换为业务错误
+func decryptError(session *models.WeChatSession, err error) error {
+	switch {
+	case errors.Is(err, wechat.ErrWatermarkMismatch):
+		return ErrWeChatDataMismatch
+	case errors.Is(err, wechat.ErrDecryptFailed), errors.Is(err, wechat.ErrInvalidSessionKey):
+		// 用户在其他地方调用 wx.login 后旧的 session_key 失效
+		return fmt.Errorf("%w: member %s", ErrWeChatSessionExpired, session.MemberId)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/ddteam/drink-master/internal/contracts"
+	"github.com/ddteam/drink-master/internal/enums"
+	"github.com/ddteam/drink-master/internal/models"
+	"github.com/ddteam/drink-master/pkg/wechat"
+)
+
+const (
+	testWeChatAppID      = "wx_test_app"
+	testWeChatSessionKey = "MDEyMzQ1Njc4OWFiY2RlZg==" // 0123456789abcdef
+	testWeChatIV         = "ZmVkY2JhOTg3NjU0MzIxMA==" // fedcba9876543210
+)
+
+// setupWeChatSessionService 创建使用内存数据库的微信会话服务，并写入一名顾客
+func setupWeChatSessionService(t *testing.T) (WeChatSessionServiceInterface, *gorm.DB, *models.Member) {
+	t.Helper()
+	t.Setenv("WECHAT_SESSION_SECRET_KEY", testPaymentSecretKey)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Member{}, &models.WeChatSession{}))
+	openID := "openid-1"
+	member := &models.Member{ID: "member-1", WeChatOpenId: &openID, Role: int(enums.MemberRoleConsumer)}
+	require.NoError(t, db.Create(member).Error)
+
+	return NewWeChatSessionService(db, wechat.NewClient(testWeChatAppID, "secret")), db, member
+}
+
+// encryptWeChatData 按微信的方式（AES-128-CBC）加密测试数据
+func encryptWeChatData(t *testing.T, plaintext string) contracts.WeChatEncryptedDataRequest {
+	t.Helper()
+	key, _ := base64.StdEncoding.DecodeString(testWeChatSessionKey)
+	iv, _ := base64.StdEncoding.DecodeString(testWeChatIV)
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	data := append([]byte(plaintext), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+	return contracts.WeChatEncryptedDataRequest{
+		EncryptedData: base64.StdEncoding.EncodeToString(data),
+		Iv:            testWeChatIV,
+	}
+}
+
+func TestWeChatSessionService_SaveSessionEncryptsKey(t *testing.T) {
+	service, db, member := setupWeChatSessionService(t)
+
+	err := service.SaveSession(member, &wechat.SessionResponse{
+		OpenID: "openid-1", SessionKey: testWeChatSessionKey, UnionID: "union-1",
+	})
+	require.NoError(t, err)
+
+	var stored models.WeChatSession
+	require.NoError(t, db.First(&stored, "MemberId = ?", member.ID).Error)
+	assert.NotContains(t, stored.SessionKey, testWeChatSessionKey)
+
+	var reloaded models.Member
+	require.NoError(t, db.First(&reloaded, "Id = ?", member.ID).Error)
+	require.NotNil(t, reloaded.UnionId)
+	assert.Equal(t, "union-1", *reloaded.UnionId)
+}
+
+func TestWeChatSessionService_SaveSessionWithoutSecretKey(t *testing.T) {
+	service, db, member := setupWeChatSessionService(t)
+	t.Setenv("WECHAT_SESSION_SECRET_KEY", "")
+
+	// 未配置加密密钥时不保存会话，但仍记录 UnionID
+	err := service.SaveSession(member, &wechat.SessionResponse{
+		OpenID: "openid-1", SessionKey: testWeChatSessionKey, UnionID: "union-1",
+	})
+	assert.ErrorIs(t, err, errWeChatSessionSecretNotConfigured)
+
+	var count int64
+	require.NoError(t, db.Model(&models.WeChatSession{}).Count(&count).Error)
+	assert.Zero(t, count)
+	assert.Equal(t, "union-1", *member.UnionId)
+}
+
+func TestWeChatSessionService_DecryptPhoneNumber(t *testing.T) {
+	service, _, member := setupWeChatSessionService(t)
+	req := encryptWeChatData(t, `{"phoneNumber":"+86 13800138000","purePhoneNumber":"13800138000",`+
+		`"countryCode":"86","watermark":{"appid":"wx_test_app","timestamp":1700000000}}`)
+
+	// 未登录保存会话时需重新登录
+	_, err := service.DecryptPhoneNumber(member.ID, req)
+	assert.ErrorIs(t, err, ErrWeChatSessionExpired)
+
+	require.NoError(t, service.SaveSession(member, &wechat.SessionResponse{
+		OpenID: "openid-1", SessionKey: testWeChatSessionKey,
+	}))
+	phone, err := service.DecryptPhoneNumber(member.ID, req)
+	require.NoError(t, err)
+	assert.Equal(t, "13800138000", phone.PurePhoneNumber)
+	assert.Equal(t, "86", phone.CountryCode)
+
+	// 其他小程序签发的数据
+	other := encryptWeChatData(t, `{"purePhoneNumber":"13800138000","watermark":{"appid":"wx_other_app"}}`)
+	_, err = service.DecryptPhoneNumber(member.ID, other)
+	assert.ErrorIs(t, err, ErrWeChatDataMismatch)
+
+	// 用户重新调用 wx.login 后旧会话密钥失效
+	require.NoError(t, service.SaveSession(member, &wechat.SessionResponse{
+		OpenID: "openid-1", SessionKey: "ZmZmZmZmZmZmZmZmZmZmZg==",
+	}))
+	_, err = service.DecryptPhoneNumber(member.ID, req)
+	assert.ErrorIs(t, err, ErrWeChatSessionExpired)
+}
+
+func TestWeChatSessionService_DecryptUserInfoLinksUnionID(t *testing.T) {
+	service, db, member := setupWeChatSessionService(t)
+	require.NoError(t, service.SaveSession(member, &wechat.SessionResponse{
+		OpenID: "openid-1", SessionKey: testWeChatSessionKey,
+	}))
+
+	req := encryptWeChatData(t, `{"openId":"openid-1","nickName":"用户","avatarUrl":"https://example.com/a.jpg",`+
+		`"unionId":"union-1","watermark":{"appid":"wx_test_app"}}`)
+	info, err := service.DecryptUserInfo(member.ID, req)
+	require.NoError(t, err)
+	assert.Equal(t, "union-1", info.UnionId)
+	assert.Equal(t, "用户", info.Nickname)
+
+	var reloaded models.Member
+	require.NoError(t, db.First(&reloaded, "Id = ?", member.ID).Error)
+	require.NotNil(t, reloaded.UnionId)
+	assert.Equal(t, "union-1", *reloaded.UnionId)
+
+	// 其他用户的加密数据
+	other := encryptWeChatData(t, `{"openId":"openid-2","unionId":"union-2","watermark":{"appid":"wx_test_app"}}`)
+	_, err = service.DecryptUserInfo(member.ID, other)
+	assert.ErrorIs(t, err, ErrWeChatDataMismatch)
+}
@@ -0,0 +1,20 @@
+-- 退款记录：制作失败自动退款及退款重试
+CREATE TABLE IF NOT EXISTS `refund_records` (
+  `Id` varchar(36) NOT NULL,
+  `OrderId` varchar(36) NOT NULL,
+  `RefundNo` varchar(64) NOT NULL,
+  `Amount` decimal(10,2) NOT NULL DEFAULT 0,
+  `Reason` varchar(512) NULL,
+  `Status` int NOT NULL DEFAULT 0,
+  `Attempts` int NOT NULL DEFAULT 0,
+  `LastError` varchar(512) NULL,
+  `ChannelRefundNo` varchar(64) NULL,
+  `NextRetryOn` datetime(3) NULL,
+  `CompletedOn` datetime(3) NULL,
+  `Version` bigint NOT NULL DEFAULT 0,
+  `CreatedOn` datetime(3) NOT NULL,
+  `UpdatedOn` datetime(3) NULL,
+  PRIMARY KEY (`Id`),
+  UNIQUE KEY `idx_refund_records_refund_no` (`RefundNo`),
+  KEY `idx_refund_records_order_id` (`OrderId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,2 @@
+ALTER TABLE `machine_owners` DROP COLUMN `ChannelCode`;
+ALTER TABLE `orders` DROP COLUMN `ChannelCode`;
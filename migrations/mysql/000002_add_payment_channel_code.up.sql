@@ -0,0 +1,3 @@
+-- 支付渠道编码：订单绑定下单渠道，机主配置收款渠道
+ALTER TABLE `orders` ADD COLUMN `ChannelCode` varchar(32) NULL;
+ALTER TABLE `machine_owners` ADD COLUMN `ChannelCode` varchar(32) NULL;
@@ -0,0 +1,4 @@
+DROP INDEX `idx_owner_merchant` ON `machine_owners`;
+ALTER TABLE `machine_owners` DROP COLUMN `MerchantPrivateKey`;
+ALTER TABLE `machine_owners` DROP COLUMN `MerchantSerialNo`;
+ALTER TABLE `machine_owners` MODIFY COLUMN `ReceivingKey` varchar(32) NULL;
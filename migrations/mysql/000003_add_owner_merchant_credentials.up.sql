@@ -0,0 +1,5 @@
+-- 机主收款账户：收款密钥加密存储后变长，微信支付商户API证书按机主配置
+ALTER TABLE `machine_owners` MODIFY COLUMN `ReceivingKey` varchar(256) NULL;
+ALTER TABLE `machine_owners` ADD COLUMN `MerchantSerialNo` varchar(64) NULL;
+ALTER TABLE `machine_owners` ADD COLUMN `MerchantPrivateKey` text NULL;
+CREATE INDEX `idx_owner_merchant` ON `machine_owners` (`ReceivingAccount`, `ChannelCode`);
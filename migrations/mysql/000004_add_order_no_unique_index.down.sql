@@ -0,0 +1 @@
+DROP INDEX `idx_orders_order_no` ON `orders`;
@@ -0,0 +1,3 @@
+-- 订单号唯一：并发下单生成重复订单号时插入失败并重新生成
+-- 执行前须确认历史订单号无重复：SELECT `OrderNo` FROM `orders` GROUP BY `OrderNo` HAVING COUNT(*) > 1;
+CREATE UNIQUE INDEX `idx_orders_order_no` ON `orders` (`OrderNo`);
@@ -0,0 +1,16 @@
+-- 库存预占：下单预占料仓库存，制作完成扣减，支付失败或制作失败释放
+ALTER TABLE `material_silos` ADD COLUMN `Reserved` int NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS `stock_reservations` (
+  `Id` varchar(36) NOT NULL,
+  `OrderId` varchar(36) NOT NULL,
+  `SiloId` varchar(36) NOT NULL,
+  `Quantity` int NOT NULL DEFAULT 0,
+  `Status` int NOT NULL DEFAULT 0,
+  `Version` bigint NOT NULL DEFAULT 0,
+  `CreatedOn` datetime(3) NOT NULL,
+  `UpdatedOn` datetime(3) NULL,
+  PRIMARY KEY (`Id`),
+  UNIQUE KEY `idx_stock_reservations_order_id` (`OrderId`),
+  KEY `idx_stock_reservations_silo_id` (`SiloId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,9 @@
+-- 后台任务租约：多实例部署时同一任务只由一个实例执行
+CREATE TABLE IF NOT EXISTS `job_leases` (
+  `Name` varchar(64) NOT NULL,
+  `Holder` varchar(128) NOT NULL,
+  `ExpiresOn` datetime(3) NOT NULL,
+  `CreatedOn` datetime(3) NOT NULL,
+  `UpdatedOn` datetime(3) NULL,
+  PRIMARY KEY (`Name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,18 @@
+-- 领域事件发件箱：事件与订单状态变更同事务写入，超过保留期后由数据清理任务删除
+CREATE TABLE IF NOT EXISTS `outbox_events` (
+  `Id` varchar(36) NOT NULL,
+  `EventType` varchar(64) NOT NULL DEFAULT '',
+  `AggregateId` varchar(36) NOT NULL DEFAULT '',
+  `Payload` text NULL,
+  `Status` int NOT NULL DEFAULT 0,
+  `Attempts` int NOT NULL DEFAULT 0,
+  `LastError` varchar(512) NULL,
+  `NextAttemptOn` datetime(3) NOT NULL,
+  `DeliveredOn` datetime(3) NULL,
+  `CreatedOn` datetime(3) NOT NULL,
+  `UpdatedOn` datetime(3) NULL,
+  PRIMARY KEY (`Id`),
+  KEY `idx_outbox_events_aggregate_id` (`AggregateId`),
+  KEY `idx_outbox_due` (`Status`, `NextAttemptOn`),
+  KEY `idx_outbox_created` (`CreatedOn`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
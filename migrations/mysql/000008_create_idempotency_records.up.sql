@@ -0,0 +1,15 @@
+-- 幂等请求记录：按 会员+接口+Idempotency-Key 重放首次响应，过期记录由数据清理任务删除
+CREATE TABLE IF NOT EXISTS `idempotency_records` (
+  `MemberId` varchar(36) NOT NULL,
+  `Scope` varchar(128) NOT NULL,
+  `IdempotencyKey` varchar(128) NOT NULL,
+  `Fingerprint` varchar(64) NOT NULL DEFAULT '',
+  `StatusCode` int NOT NULL DEFAULT 0,
+  `ContentType` varchar(128) NULL,
+  `ResponseBody` text NULL,
+  `ExpiresOn` datetime(3) NOT NULL,
+  `CreatedOn` datetime(3) NOT NULL,
+  `UpdatedOn` datetime(3) NULL,
+  PRIMARY KEY (`MemberId`, `Scope`, `IdempotencyKey`),
+  KEY `idx_idempotency_records_expires_on` (`ExpiresOn`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
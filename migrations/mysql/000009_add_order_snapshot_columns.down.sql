@@ -0,0 +1,3 @@
+ALTER TABLE `orders` DROP COLUMN `MachineName`;
+ALTER TABLE `orders` DROP COLUMN `ProductPrice`;
+ALTER TABLE `orders` DROP COLUMN `ProductName`;
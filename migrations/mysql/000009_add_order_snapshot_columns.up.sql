@@ -0,0 +1,4 @@
+-- 订单快照：下单时的商品名称、单价与售货机名称，历史订单为空时按当前商品目录展示
+ALTER TABLE `orders` ADD COLUMN `ProductName` varchar(64) NULL;
+ALTER TABLE `orders` ADD COLUMN `ProductPrice` decimal(10,2) NULL;
+ALTER TABLE `orders` ADD COLUMN `MachineName` varchar(64) NULL;
@@ -0,0 +1,24 @@
+-- 顾客退款申请：由售货机机主审核，同一订单同时只有一个待审核申请（提交时锁定订单行检查）
+CREATE TABLE IF NOT EXISTS `refund_requests` (
+  `Id` varchar(36) NOT NULL,
+  `OrderId` varchar(36) NOT NULL,
+  `MemberId` varchar(36) NOT NULL,
+  `MachineId` varchar(36) NOT NULL,
+  `MachineOwnerId` varchar(36) NOT NULL,
+  `Amount` decimal(10,2) NOT NULL DEFAULT 0,
+  `Reason` varchar(512) NOT NULL DEFAULT '',
+  `PhotoUrl` varchar(512) NULL,
+  `Status` int NOT NULL DEFAULT 0,
+  `ReviewerId` varchar(36) NULL,
+  `ReviewRemark` varchar(512) NULL,
+  `ReviewedOn` datetime(3) NULL,
+  `RefundNo` varchar(64) NULL,
+  `LastError` varchar(512) NULL,
+  `Version` bigint NOT NULL DEFAULT 0,
+  `CreatedOn` datetime(3) NOT NULL,
+  `UpdatedOn` datetime(3) NULL,
+  PRIMARY KEY (`Id`),
+  KEY `idx_refund_requests_order_id` (`OrderId`),
+  KEY `idx_refund_requests_member_id` (`MemberId`),
+  KEY `idx_refund_req` (`MachineOwnerId`, `Status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,28 @@
+-- 刷新令牌与会话吊销列表：刷新令牌每次刷新时轮换，只存储SHA-256摘要；过期的令牌与吊销记录由数据清理任务删除
+CREATE TABLE IF NOT EXISTS `refresh_tokens` (
+  `Id` varchar(36) NOT NULL,
+  `MemberId` varchar(36) NOT NULL DEFAULT '',
+  `SessionId` varchar(36) NOT NULL DEFAULT '',
+  `TokenHash` varchar(64) NOT NULL DEFAULT '',
+  `Role` varchar(16) NOT NULL DEFAULT '',
+  `ExpiresOn` datetime(3) NOT NULL,
+  `RevokedOn` datetime(3) NULL,
+  `ReplacedById` varchar(36) NULL,
+  `CreatedOn` datetime(3) NOT NULL,
+  PRIMARY KEY (`Id`),
+  KEY `idx_refresh_tokens_member_id` (`MemberId`),
+  KEY `idx_refresh_tokens_session_id` (`SessionId`),
+  UNIQUE KEY `idx_refresh_tokens_token_hash` (`TokenHash`),
+  KEY `idx_refresh_tokens_expires_on` (`ExpiresOn`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `token_revocations` (
+  `SessionId` varchar(36) NOT NULL,
+  `MemberId` varchar(36) NOT NULL DEFAULT '',
+  `Reason` varchar(32) NOT NULL DEFAULT '',
+  `ExpiresOn` datetime(3) NOT NULL,
+  `CreatedOn` datetime(3) NOT NULL,
+  PRIMARY KEY (`SessionId`),
+  KEY `idx_token_revocations_member_id` (`MemberId`),
+  KEY `idx_token_revocations_expires_on` (`ExpiresOn`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
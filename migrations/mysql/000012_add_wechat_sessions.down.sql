@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `wechat_sessions`;
+ALTER TABLE `members` DROP KEY `idx_members_union_id`;
+ALTER TABLE `members` DROP COLUMN `UnionId`;
@@ -0,0 +1,11 @@
+-- 微信登录：会员UnionID用于关联小程序与公众号账号；会话密钥表保存最近一次登录的 session_key（加密存储）
+ALTER TABLE `members` ADD COLUMN `UnionId` varchar(64) NULL;
+ALTER TABLE `members` ADD KEY `idx_members_union_id` (`UnionId`);
+
+CREATE TABLE IF NOT EXISTS `wechat_sessions` (
+  `MemberId` varchar(36) NOT NULL,
+  `OpenId` varchar(36) NOT NULL DEFAULT '',
+  `SessionKey` varchar(255) NOT NULL DEFAULT '',
+  `UpdatedOn` datetime(3) NOT NULL,
+  PRIMARY KEY (`MemberId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
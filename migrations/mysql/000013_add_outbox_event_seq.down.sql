@@ -0,0 +1,5 @@
+ALTER TABLE `outbox_events`
+  DROP PRIMARY KEY,
+  DROP KEY `idx_outbox_events_id`,
+  DROP COLUMN `Seq`,
+  ADD PRIMARY KEY (`Id`);
@@ -0,0 +1,10 @@
+-- 领域事件发件箱自增序号：投递与订单事件流按序号排序，事件流以序号作为断点续传的事件ID
+-- 已有事件按写入时间编号
+ALTER TABLE `outbox_events` ADD COLUMN `Seq` bigint NOT NULL DEFAULT 0 FIRST;
+SET @seq := 0;
+UPDATE `outbox_events` SET `Seq` = (@seq := @seq + 1) ORDER BY `CreatedOn`, `Id`;
+ALTER TABLE `outbox_events`
+  DROP PRIMARY KEY,
+  MODIFY COLUMN `Seq` bigint NOT NULL AUTO_INCREMENT,
+  ADD PRIMARY KEY (`Seq`),
+  ADD UNIQUE KEY `idx_outbox_events_id` (`Id`);
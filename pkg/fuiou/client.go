@@ -0,0 +1,333 @@
+package fuiou
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the Fuiou aggregated payment gateway
+const DefaultBaseURL = "https://fundwx.fuiou.com"
+
+// ResultCodeSuccess is the result_code returned for accepted requests
+const ResultCodeSuccess = "000000"
+
+// API paths
+const (
+	pathPreCreate = "/wxPreCreate"
+	pathQuery     = "/commonQuery"
+	pathRefund    = "/commonRefund"
+	pathClose     = "/closeorder"
+)
+
+// 交易状态（trans_stat）
+const (
+	TransStatSuccess    = "SUCCESS"    // 支付成功
+	TransStatRefund     = "REFUND"     // 转入退款
+	TransStatNotPay     = "NOTPAY"     // 未支付
+	TransStatClosed     = "CLOSED"     // 已关闭
+	TransStatRevoked    = "REVOKED"    // 已撤销
+	TransStatUserPaying = "USERPAYING" // 用户支付中
+	TransStatPayError   = "PAYERROR"   // 支付失败
+)
+
+const (
+	apiVersion    = "1.0"
+	orderTypeWx   = "WECHAT"
+	tradeTypeMini = "LETPAY" // 小程序支付
+	timeLayout    = "20060102150405"
+)
+
+// ErrInvalidSignature is returned when a response signature does not verify
+var ErrInvalidSignature = errors.New("fuiou: invalid response signature")
+
+// APIError is returned when Fuiou rejects a request
+type APIError struct {
+	Code    string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("fuiou: %s - %s", e.Code, e.Message)
+}
+
+// Config configures the institution-level parameters shared by all merchants
+type Config struct {
+	BaseURL  string        // 网关地址，默认 DefaultBaseURL
+	InsCd    string        // 机构号
+	TermID   string        // 终端号
+	SubAppID string        // 小程序AppID
+	Timeout  time.Duration // 请求超时
+}
+
+// Account holds the merchant credentials for one machine owner
+type Account struct {
+	MerchantCode string // 商户号
+	Key          string // 签名密钥
+	OrderPrefix  string // 商户订单号前缀
+}
+
+// PreCreateRequest describes a mini program pre-order
+type PreCreateRequest struct {
+	OrderNo     string // 业务订单号（不含前缀）
+	Amount      int64  // 金额(分)
+	Description string // 商品描述
+	OpenID      string // 用户在小程序下的OpenId
+	NotifyURL   string // 支付结果通知地址
+	Attach      string // 附加信息，原样回传
+	ClientIP    string // 终端IP
+}
+
+// PreCreateResponse carries the parameters for wx.requestPayment
+type PreCreateResponse struct {
+	AppID     string
+	TimeStamp string
+	NonceStr  string
+	Package   string
+	SignType  string
+	PaySign   string
+}
+
+// QueryResponse describes the state of an order
+type QueryResponse struct {
+	TransStat     string
+	TransactionID string
+	Amount        int64
+	FinishedAt    time.Time
+}
+
+// RefundRequest describes a (partial) refund
+type RefundRequest struct {
+	OrderNo      string // 原业务订单号（不含前缀）
+	RefundNo     string // 退款单号（不含前缀），重复提交同一退款单号不会重复退款
+	TotalAmount  int64  // 原订单金额(分)
+	RefundAmount int64  // 退款金额(分)
+}
+
+// RefundResponse describes an accepted refund
+type RefundResponse struct {
+	RefundID string
+}
+
+// Client calls the Fuiou aggregated payment API
+type Client struct {
+	cfg    Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewClient creates a Fuiou client
+func NewClient(cfg Config) *Client {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+
+	return &Client{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		now:    time.Now,
+	}
+}
+
+// PreCreate places a mini program pre-order and returns the payment parameters
+func (c *Client) PreCreate(account Account, req PreCreateRequest) (*PreCreateResponse, error) {
+	if req.OrderNo == "" || req.Amount <= 0 {
+		return nil, errors.New("fuiou: order no and positive amount are required")
+	}
+
+	params := c.baseParams(account)
+	params["goods_des"] = req.Description
+	params["addn_inf"] = req.Attach
+	params["mchnt_order_no"] = account.OrderPrefix + req.OrderNo
+	params["curr_type"] = "CNY"
+	params["order_amt"] = strconv.FormatInt(req.Amount, 10)
+	params["term_ip"] = req.ClientIP
+	params["txn_begin_ts"] = c.now().Format(timeLayout)
+	params["notify_url"] = req.NotifyURL
+	params["trade_type"] = tradeTypeMini
+	params["sub_openid"] = req.OpenID
+	params["sub_appid"] = c.cfg.SubAppID
+
+	resp, err := c.call(pathPreCreate, account, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PreCreateResponse{
+		AppID:     resp["sdk_appid"],
+		TimeStamp: resp["sdk_timestamp"],
+		NonceStr:  resp["sdk_noncestr"],
+		Package:   resp["sdk_package"],
+		SignType:  resp["sdk_signtype"],
+		PaySign:   resp["sdk_paysign"],
+	}, nil
+}
+
+// Query looks up an order by business order number
+func (c *Client) Query(account Account, orderNo string) (*QueryResponse, error) {
+	if orderNo == "" {
+		return nil, errors.New("fuiou: order no is required")
+	}
+
+	params := c.baseParams(account)
+	params["order_type"] = orderTypeWx
+	params["mchnt_order_no"] = account.OrderPrefix + orderNo
+
+	resp, err := c.call(pathQuery, account, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &QueryResponse{
+		TransStat:     resp["trans_stat"],
+		TransactionID: resp["transaction_id"],
+	}
+	if amount, err := strconv.ParseInt(resp["order_amt"], 10, 64); err == nil {
+		result.Amount = amount
+	}
+	if finished, err := time.ParseInLocation(timeLayout, resp["reserved_txn_fin_ts"], time.Local); err == nil {
+		result.FinishedAt = finished
+	}
+	return result, nil
+}
+
+// Refund refunds all or part of a paid order
+func (c *Client) Refund(account Account, req RefundRequest) (*RefundResponse, error) {
+	if req.OrderNo == "" || req.RefundNo == "" {
+		return nil, errors.New("fuiou: order no and refund no are required")
+	}
+	if req.RefundAmount <= 0 || req.RefundAmount > req.TotalAmount {
+		return nil, errors.New("fuiou: invalid refund amount")
+	}
+
+	params := c.baseParams(account)
+	params["order_type"] = orderTypeWx
+	params["mchnt_order_no"] = account.OrderPrefix + req.OrderNo
+	params["refund_order_no"] = account.OrderPrefix + req.RefundNo
+	params["total_amt"] = strconv.FormatInt(req.TotalAmount, 10)
+	params["refund_amt"] = strconv.FormatInt(req.RefundAmount, 10)
+
+	resp, err := c.call(pathRefund, account, params)
+	if err != nil {
+		return nil, err
+	}
+	return &RefundResponse{RefundID: resp["refund_id"]}, nil
+}
+
+// Close closes an unpaid order so it can no longer be paid
+func (c *Client) Close(account Account, orderNo string) error {
+	if orderNo == "" {
+		return errors.New("fuiou: order no is required")
+	}
+
+	params := c.baseParams(account)
+	params["order_type"] = orderTypeWx
+	params["mchnt_order_no"] = account.OrderPrefix + orderNo
+	params["sub_appid"] = c.cfg.SubAppID
+
+	_, err := c.call(pathClose, account, params)
+	return err
+}
+
+// VerifyAccount checks merchant credentials by querying an order number that does not exist.
+// The gateway signs business errors as well, so a response whose signature verifies under
+// account.Key proves the merchant code and key match
+func (c *Client) VerifyAccount(account Account) error {
+	params := c.baseParams(account)
+	params["order_type"] = orderTypeWx
+	params["mchnt_order_no"] = account.OrderPrefix + "VERIFY" + randomString()[:8]
+
+	resp, err := c.exchange(pathQuery, account, params)
+	if err != nil {
+		return err
+	}
+	if !Verify(resp, account.Key) {
+		if resp["result_code"] != ResultCodeSuccess {
+			return &APIError{Code: resp["result_code"], Message: resp["result_msg"]}
+		}
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// baseParams returns the fields common to every request
+func (c *Client) baseParams(account Account) map[string]string {
+	return map[string]string{
+		"version":    apiVersion,
+		"ins_cd":     c.cfg.InsCd,
+		"mchnt_cd":   account.MerchantCode,
+		"term_id":    c.cfg.TermID,
+		"random_str": randomString(),
+	}
+}
+
+// call signs params, posts them as req=<xml> and returns the verified response fields
+func (c *Client) call(path string, account Account, params map[string]string) (map[string]string, error) {
+	resp, err := c.exchange(path, account, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp["result_code"] != ResultCodeSuccess {
+		return nil, &APIError{Code: resp["result_code"], Message: resp["result_msg"]}
+	}
+	if !Verify(resp, account.Key) {
+		return nil, ErrInvalidSignature
+	}
+	return resp, nil
+}
+
+// exchange signs params, posts them as req=<xml> and returns the decoded response fields
+func (c *Client) exchange(path string, account Account, params map[string]string) (map[string]string, error) {
+	if account.MerchantCode == "" || account.Key == "" {
+		return nil, errors.New("fuiou: merchant code and key are required")
+	}
+
+	params[signField] = Sign(params, account.Key)
+	form := url.Values{}
+	form.Set("req", string(encodeXML(params)))
+
+	httpResp, err := c.client.Post(c.cfg.BaseURL+path, "application/x-www-form-urlencoded",
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("fuiou: request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("fuiou: failed to read response: %w", err)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fuiou: unexpected http status %d", httpResp.StatusCode)
+	}
+
+	// 响应报文可能经过URL编码
+	body = bytes.TrimSpace(body)
+	if !bytes.HasPrefix(body, []byte("<")) {
+		if unescaped, err := url.QueryUnescape(string(body)); err == nil {
+			body = []byte(unescaped)
+		}
+	}
+
+	return decodeXML(body)
+}
+
+// randomString returns a 32 character random nonce
+func randomString() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,252 @@
+package fuiou
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testKey = "test_key_123"
+
+var testAccount = Account{MerchantCode: "0002900F0001", Key: testKey, OrderPrefix: "1066"}
+
+// newTestServer starts a Fuiou stand-in that verifies request signatures and
+// answers with the signed fields returned by handle
+func newTestServer(t *testing.T, handle func(path string, req map[string]string) map[string]string) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+		}
+		req, err := decodeXML([]byte(r.PostForm.Get("req")))
+		if err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			return
+		}
+		if !Verify(req, testKey) {
+			t.Errorf("request signature did not verify")
+		}
+
+		resp := handle(r.URL.Path, req)
+		if resp["result_code"] == ResultCodeSuccess {
+			resp[signField] = Sign(resp, testKey)
+		}
+		_, _ = w.Write(encodeXML(resp))
+	}))
+	t.Cleanup(server.Close)
+
+	return NewClient(Config{BaseURL: server.URL, InsCd: "08A9999999", TermID: "88888888", SubAppID: "wx123"})
+}
+
+func TestSignAndVerify(t *testing.T) {
+	params := map[string]string{
+		"b":             "2",
+		"a":             "1",
+		"empty":         "",
+		"reserved_info": "ignored",
+	}
+
+	sign := Sign(params, "key")
+	// md5("a=1&b=2&key=key")
+	if sign != "735a0bafc42420a9b223ce31415e043a" {
+		t.Errorf("unexpected sign %s", sign)
+	}
+
+	params[signField] = sign
+	if !Verify(params, "key") {
+		t.Error("expected signature to verify")
+	}
+	params["a"] = "changed"
+	if Verify(params, "key") {
+		t.Error("expected tampered params to fail verification")
+	}
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	params := map[string]string{"goods_des": "拿铁<热>&冰", "order_amt": "1580"}
+
+	decoded, err := decodeXML(encodeXML(params))
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if decoded["goods_des"] != params["goods_des"] || decoded["order_amt"] != "1580" {
+		t.Errorf("unexpected round trip result %v", decoded)
+	}
+
+	if _, err := decodeXML([]byte("not xml")); err == nil {
+		t.Error("expected error for invalid xml")
+	}
+}
+
+func TestClient_PreCreate(t *testing.T) {
+	client := newTestServer(t, func(path string, req map[string]string) map[string]string {
+		if path != pathPreCreate {
+			t.Errorf("unexpected path %s", path)
+		}
+		if req["mchnt_order_no"] != "1066ORD001" || req["order_amt"] != "1580" {
+			t.Errorf("unexpected order fields %v", req)
+		}
+		if req["sub_openid"] != "openid" || req["sub_appid"] != "wx123" || req["trade_type"] != tradeTypeMini {
+			t.Errorf("unexpected payer fields %v", req)
+		}
+		return map[string]string{
+			"result_code":   ResultCodeSuccess,
+			"result_msg":    "SUCCESS",
+			"sdk_appid":     "wx123",
+			"sdk_timestamp": "1700000000",
+			"sdk_noncestr":  "nonce",
+			"sdk_package":   "prepay_id=wx_prepay",
+			"sdk_signtype":  "RSA",
+			"sdk_paysign":   "paysign",
+		}
+	})
+
+	resp, err := client.PreCreate(testAccount, PreCreateRequest{
+		OrderNo:     "ORD001",
+		Amount:      1580,
+		Description: "拿铁咖啡",
+		OpenID:      "openid",
+		NotifyURL:   "https://example.com/notify",
+	})
+	if err != nil {
+		t.Fatalf("PreCreate failed: %v", err)
+	}
+	if resp.Package != "prepay_id=wx_prepay" || resp.PaySign != "paysign" || resp.AppID != "wx123" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestClient_Query(t *testing.T) {
+	client := newTestServer(t, func(path string, req map[string]string) map[string]string {
+		if path != pathQuery || req["mchnt_order_no"] != "1066ORD001" {
+			t.Errorf("unexpected request %s %v", path, req)
+		}
+		return map[string]string{
+			"result_code":         ResultCodeSuccess,
+			"trans_stat":          TransStatSuccess,
+			"transaction_id":      "4200000001",
+			"order_amt":           "1580",
+			"reserved_txn_fin_ts": "20250812153000",
+		}
+	})
+
+	resp, err := client.Query(testAccount, "ORD001")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if resp.TransStat != TransStatSuccess || resp.TransactionID != "4200000001" || resp.Amount != 1580 {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if resp.FinishedAt.Format(timeLayout) != "20250812153000" {
+		t.Errorf("unexpected finish time %v", resp.FinishedAt)
+	}
+}
+
+func TestClient_Refund(t *testing.T) {
+	client := newTestServer(t, func(path string, req map[string]string) map[string]string {
+		if path != pathRefund || req["refund_order_no"] != "1066RFORD001" || req["refund_amt"] != "500" {
+			t.Errorf("unexpected request %s %v", path, req)
+		}
+		return map[string]string{"result_code": ResultCodeSuccess, "refund_id": "R001"}
+	})
+
+	resp, err := client.Refund(testAccount, RefundRequest{
+		OrderNo: "ORD001", RefundNo: "RFORD001", TotalAmount: 1580, RefundAmount: 500,
+	})
+	if err != nil {
+		t.Fatalf("Refund failed: %v", err)
+	}
+	if resp.RefundID != "R001" {
+		t.Errorf("unexpected refund id %s", resp.RefundID)
+	}
+
+	if _, err := client.Refund(testAccount, RefundRequest{
+		OrderNo: "ORD001", RefundNo: "RF", TotalAmount: 100, RefundAmount: 200,
+	}); err == nil {
+		t.Error("expected error when refund exceeds total")
+	}
+}
+
+func TestClient_Close(t *testing.T) {
+	client := newTestServer(t, func(path string, req map[string]string) map[string]string {
+		if path != pathClose || req["mchnt_order_no"] != "1066ORD001" {
+			t.Errorf("unexpected request %s %v", path, req)
+		}
+		return map[string]string{"result_code": ResultCodeSuccess}
+	})
+
+	if err := client.Close(testAccount, "ORD001"); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := client.Close(testAccount, ""); err == nil {
+		t.Error("expected error without order no")
+	}
+}
+
+func TestClient_APIError(t *testing.T) {
+	client := newTestServer(t, func(path string, req map[string]string) map[string]string {
+		return map[string]string{"result_code": "030010", "result_msg": "订单不存在"}
+	})
+
+	_, err := client.Query(testAccount, "ORD404")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+	if apiErr.Code != "030010" {
+		t.Errorf("unexpected code %s", apiErr.Code)
+	}
+}
+
+func TestClient_InvalidResponseSignature(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(encodeXML(map[string]string{
+			"result_code": ResultCodeSuccess,
+			"trans_stat":  TransStatSuccess,
+			"sign":        "forged",
+		}))
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{BaseURL: server.URL})
+	if _, err := client.Query(testAccount, "ORD001"); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func TestClient_MissingCredentials(t *testing.T) {
+	client := NewClient(Config{})
+	if _, err := client.Query(Account{}, "ORD001"); err == nil {
+		t.Error("expected error without merchant credentials")
+	}
+}
+
+func TestClient_VerifyAccount(t *testing.T) {
+	// 网关使用商户密钥对业务错误应答签名
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		req, _ := decodeXML([]byte(r.PostForm.Get("req")))
+		resp := map[string]string{"result_code": "030010", "result_msg": "订单不存在"}
+		if Verify(req, testKey) {
+			resp[signField] = Sign(resp, testKey)
+		} else {
+			resp = map[string]string{"result_code": "1009", "result_msg": "验签失败"}
+		}
+		_, _ = w.Write(encodeXML(resp))
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{BaseURL: server.URL, InsCd: "08A9999999"})
+	if err := client.VerifyAccount(testAccount); err != nil {
+		t.Errorf("expected credentials to verify, got %v", err)
+	}
+
+	wrongKey := testAccount
+	wrongKey.Key = "wrong_key"
+	var apiErr *APIError
+	if err := client.VerifyAccount(wrongKey); !errors.As(err, &apiErr) || apiErr.Code != "1009" {
+		t.Errorf("expected gateway rejection, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package fuiou
+
+import (
+	"crypto/md5" // #nosec G501 - 富友接口规定使用MD5签名
+	"crypto/subtle"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// signField is the parameter carrying the signature
+const signField = "sign"
+
+// Sign computes the request/response signature.
+// Parameters are sorted by name and joined as k=v&..., skipping empty values, the
+// sign field itself and reserved_* extension fields, then "key=<merchant key>"
+// is appended and the MD5 digest is returned in lower-case hex.
+func Sign(params map[string]string, key string) string {
+	names := make([]string, 0, len(params))
+	for name, value := range params {
+		if value == "" || name == signField || strings.HasPrefix(name, "reserved") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(params[name])
+		b.WriteByte('&')
+	}
+	b.WriteString("key=")
+	b.WriteString(key)
+
+	sum := md5.Sum([]byte(b.String())) // #nosec G401 - 富友接口规定使用MD5签名
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks the sign field of params against key
+func Verify(params map[string]string, key string) bool {
+	sign := strings.ToLower(params[signField])
+	if sign == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(sign), []byte(Sign(params, key))) == 1
+}
@@ -0,0 +1,77 @@
+package fuiou
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// encodeXML renders params as a flat <xml> document with elements in name order
+func encodeXML(params map[string]string) []byte {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?><xml>`)
+	for _, name := range names {
+		b.WriteString("<" + name + ">")
+		_ = xml.EscapeText(&b, []byte(params[name]))
+		b.WriteString("</" + name + ">")
+	}
+	b.WriteString("</xml>")
+	return b.Bytes()
+}
+
+// decodeXML parses a flat XML document into a map of element name to text
+func decodeXML(data []byte) (map[string]string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// 富友部分环境以GBK声明编码，报文字段均为ASCII或已转义，按原样读取
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	params := make(map[string]string)
+	depth := 0
+	var current string
+	var text strings.Builder
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fuiou: invalid xml response: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 {
+				current = t.Name.Local
+				text.Reset()
+			}
+		case xml.CharData:
+			if depth == 2 {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if depth == 2 {
+				params[current] = strings.TrimSpace(text.String())
+			}
+			depth--
+		}
+	}
+
+	if len(params) == 0 {
+		return nil, errors.New("fuiou: empty xml response")
+	}
+	return params, nil
+}
@@ -0,0 +1,238 @@
+// Package jwtkeys signs and verifies JWTs with a set of kid-identified keys, so keys can be rotated
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrUnknownKey is returned when a token names a kid that is not configured
+	ErrUnknownKey = errors.New("jwtkeys: unknown key id")
+	// ErrAlgorithmMismatch is returned when a token is not signed with its key's algorithm
+	ErrAlgorithmMismatch = errors.New("jwtkeys: unexpected signing algorithm")
+	// ErrNoSigningKey is returned when signing with a manager that only holds verification keys
+	ErrNoSigningKey = errors.New("jwtkeys: no signing key")
+)
+
+// Key is a signing or verification key identified by kid
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// NewHMACKey creates an HS256 key from a shared secret
+func NewHMACKey(kid string, secret []byte) (*Key, error) {
+	if kid == "" {
+		return nil, errors.New("jwtkeys: key id is required")
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("jwtkeys: secret is required")
+	}
+	return &Key{ID: kid, Method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}, nil
+}
+
+// ParsePEMKey creates an RS256 or EdDSA key from a PEM encoded private or public key
+// Private keys can sign and verify, public keys can only verify
+func ParsePEMKey(kid string, data []byte) (*Key, error) {
+	if kid == "" {
+		return nil, errors.New("jwtkeys: key id is required")
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwtkeys: no PEM block found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwtkeys: invalid RSA private key: %w", err)
+		}
+		return newKey(kid, privateKey)
+	case "PRIVATE KEY":
+		privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwtkeys: invalid private key: %w", err)
+		}
+		return newKey(kid, privateKey)
+	case "PUBLIC KEY":
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwtkeys: invalid public key: %w", err)
+		}
+		return newKey(kid, publicKey)
+	default:
+		return nil, fmt.Errorf("jwtkeys: unsupported PEM block %q", block.Type)
+	}
+}
+
+// newKey wraps a parsed RSA or Ed25519 key
+func newKey(kid string, parsed interface{}) (*Key, error) {
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodRS256, signKey: key, verifyKey: &key.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodRS256, verifyKey: key}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodEdDSA, signKey: key, verifyKey: key.Public()}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodEdDSA, verifyKey: key}, nil
+	default:
+		return nil, fmt.Errorf("jwtkeys: unsupported key type %T", parsed)
+	}
+}
+
+// CanSign reports whether the key holds private material
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// IsSymmetric reports whether the key is a shared HMAC secret
+func (k *Key) IsSymmetric() bool {
+	return k.Method == jwt.SigningMethodHS256
+}
+
+// Manager signs tokens with the current key and verifies tokens with any configured key
+type Manager struct {
+	signing *Key
+	keys    map[string]*Key
+	methods []string
+}
+
+// NewManager creates a manager signing with signing, other keys are only used for verification
+func NewManager(signing *Key, others ...*Key) (*Manager, error) {
+	if signing == nil || !signing.CanSign() {
+		return nil, ErrNoSigningKey
+	}
+
+	m := &Manager{signing: signing, keys: make(map[string]*Key)}
+	for _, key := range append([]*Key{signing}, others...) {
+		if _, exists := m.keys[key.ID]; exists {
+			return nil, fmt.Errorf("jwtkeys: duplicate key id %q", key.ID)
+		}
+		m.keys[key.ID] = key
+		if !containsString(m.methods, key.Method.Alg()) {
+			m.methods = append(m.methods, key.Method.Alg())
+		}
+	}
+	return m, nil
+}
+
+// SigningKey returns the key new tokens are signed with
+func (m *Manager) SigningKey() *Key {
+	return m.signing
+}
+
+// Sign signs claims with the current signing key and records its kid in the header
+func (m *Manager) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(m.signing.Method, claims)
+	token.Header["kid"] = m.signing.ID
+	return token.SignedString(m.signing.signKey)
+}
+
+// Parse verifies tokenString and decodes it into claims
+// The key is chosen by kid and the token must use that key's algorithm. Tokens without kid
+// (issued before keys were named) are only accepted when the signing key is an HMAC secret
+func (m *Manager) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, m.keyFunc, jwt.WithValidMethods(m.methods))
+}
+
+// keyFunc resolves the verification key of a token
+func (m *Manager) keyFunc(token *jwt.Token) (interface{}, error) {
+	var key *Key
+	switch kid := token.Header["kid"].(type) {
+	case string:
+		key = m.keys[kid]
+	case nil:
+		if m.signing.IsSymmetric() {
+			key = m.signing
+		}
+	}
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrAlgorithmMismatch
+	}
+	return key.verifyKey, nil
+}
+
+// JSONWebKey is a public key in JWK format
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JSONWebKeySet is a JWKS document
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS returns the public keys of all asymmetric keys, HMAC secrets are never published
+func (m *Manager) JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, key := range m.orderedKeys() {
+		switch publicKey := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JSONWebKey{
+				KeyType:   "RSA",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Method.Alg(),
+				N:         base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JSONWebKey{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Method.Alg(),
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(publicKey),
+			})
+		}
+	}
+	return set
+}
+
+// orderedKeys returns the signing key first, followed by the verification keys sorted by kid
+func (m *Manager) orderedKeys() []*Key {
+	others := make([]*Key, 0, len(m.keys))
+	for _, key := range m.keys {
+		if key != m.signing {
+			others = append(others, key)
+		}
+	}
+	sort.Slice(others, func(i, j int) bool { return others[i].ID < others[j].ID })
+	return append([]*Key{m.signing}, others...)
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func testClaims() *jwt.RegisteredClaims {
+	return &jwt.RegisteredClaims{
+		Subject:   "member-1",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+}
+
+func mustHMACKey(t *testing.T, kid, secret string) *Key {
+	t.Helper()
+	key, err := NewHMACKey(kid, []byte(secret))
+	if err != nil {
+		t.Fatalf("NewHMACKey: %v", err)
+	}
+	return key
+}
+
+func mustManager(t *testing.T, signing *Key, others ...*Key) *Manager {
+	t.Helper()
+	m, err := NewManager(signing, others...)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	return m
+}
+
+func TestManager_HMACRotation(t *testing.T) {
+	oldKey := mustHMACKey(t, "2024", "old-secret")
+	newKey := mustHMACKey(t, "2025", "new-secret")
+
+	oldToken, err := mustManager(t, oldKey).Sign(testClaims())
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	// 轮换后新token使用新kid签名，旧token仍可验证
+	rotated := mustManager(t, newKey, oldKey)
+	newToken, err := rotated.Sign(testClaims())
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	for _, tokenString := range []string{oldToken, newToken} {
+		claims := &jwt.RegisteredClaims{}
+		if _, err := rotated.Parse(tokenString, claims); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if claims.Subject != "member-1" {
+			t.Errorf("unexpected subject %q", claims.Subject)
+		}
+	}
+
+	// 旧密钥下线后旧token失效
+	retired := mustManager(t, newKey)
+	if _, err := retired.Parse(oldToken, &jwt.RegisteredClaims{}); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestManager_LegacyTokenWithoutKid(t *testing.T) {
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims()).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+
+	m := mustManager(t, mustHMACKey(t, "default", "secret"))
+	if _, err := m.Parse(legacy, &jwt.RegisteredClaims{}); err != nil {
+		t.Errorf("expected legacy token to be accepted, got %v", err)
+	}
+
+	// 非对称签名密钥下不接受无kid的token
+	rsaKey := mustRSAKey(t, "rsa")
+	asymmetric := mustManager(t, rsaKey, mustHMACKey(t, "default", "secret"))
+	if _, err := asymmetric.Parse(legacy, &jwt.RegisteredClaims{}); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestManager_RejectsAlgorithmMismatch(t *testing.T) {
+	rsaKey := mustRSAKey(t, "rsa")
+	hmacKey := mustHMACKey(t, "hmac", "secret")
+	m := mustManager(t, rsaKey, hmacKey)
+
+	// 以HS256签名却指向RSA密钥的token必须被拒绝
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	token.Header["kid"] = "rsa"
+	forged, err := token.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	if _, err := m.Parse(forged, &jwt.RegisteredClaims{}); !errors.Is(err, ErrAlgorithmMismatch) {
+		t.Errorf("expected ErrAlgorithmMismatch, got %v", err)
+	}
+
+	// alg=none 永远不被接受
+	none := jwt.NewWithClaims(jwt.SigningMethodNone, testClaims())
+	none.Header["kid"] = "hmac"
+	unsigned, err := none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	if _, err := m.Parse(unsigned, &jwt.RegisteredClaims{}); err == nil {
+		t.Error("expected unsigned token to be rejected")
+	}
+}
+
+func TestManager_AsymmetricKeys(t *testing.T) {
+	rsaKey := mustRSAKey(t, "rsa-2025")
+	edKey := mustEd25519Key(t, "ed-2024")
+
+	for _, signing := range []*Key{rsaKey, edKey} {
+		m := mustManager(t, signing)
+		tokenString, err := m.Sign(testClaims())
+		if err != nil {
+			t.Fatalf("Sign %s: %v", signing.Method.Alg(), err)
+		}
+		if _, err := m.Parse(tokenString, &jwt.RegisteredClaims{}); err != nil {
+			t.Errorf("Parse %s: %v", signing.Method.Alg(), err)
+		}
+	}
+
+	// 仅含公钥的密钥只能用于验证
+	publicKey := mustPublicKey(t, "ed-2024", edKey)
+	if publicKey.CanSign() {
+		t.Error("expected public key to be verify only")
+	}
+	if _, err := NewManager(publicKey); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("expected ErrNoSigningKey, got %v", err)
+	}
+
+	edToken, _ := mustManager(t, edKey).Sign(testClaims())
+	m := mustManager(t, rsaKey, publicKey, mustHMACKey(t, "hmac", "secret"))
+	if _, err := m.Parse(edToken, &jwt.RegisteredClaims{}); err != nil {
+		t.Errorf("expected rotated Ed25519 token to verify, got %v", err)
+	}
+
+	// JWKS 只发布非对称公钥，签名密钥在前
+	set := m.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(set.Keys))
+	}
+	if set.Keys[0].KeyID != "rsa-2025" || set.Keys[0].KeyType != "RSA" || set.Keys[0].Algorithm != "RS256" {
+		t.Errorf("unexpected RSA key %+v", set.Keys[0])
+	}
+	if set.Keys[0].N == "" || set.Keys[0].E != "AQAB" {
+		t.Errorf("unexpected RSA modulus/exponent %+v", set.Keys[0])
+	}
+	if set.Keys[1].KeyID != "ed-2024" || set.Keys[1].KeyType != "OKP" || set.Keys[1].Curve != "Ed25519" {
+		t.Errorf("unexpected Ed25519 key %+v", set.Keys[1])
+	}
+}
+
+func TestNewManager_DuplicateKid(t *testing.T) {
+	_, err := NewManager(mustHMACKey(t, "same", "a"), mustHMACKey(t, "same", "b"))
+	if err == nil {
+		t.Error("expected duplicate kid to be rejected")
+	}
+}
+
+func TestParsePEMKey_Invalid(t *testing.T) {
+	if _, err := ParsePEMKey("kid", []byte("not a pem")); err == nil {
+		t.Error("expected error for non PEM data")
+	}
+	block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}})
+	if _, err := ParsePEMKey("kid", block); err == nil {
+		t.Error("expected error for unsupported PEM block")
+	}
+}
+
+func mustRSAKey(t *testing.T, kid string) *Key {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	key, err := ParsePEMKey(kid, data)
+	if err != nil {
+		t.Fatalf("ParsePEMKey: %v", err)
+	}
+	return key
+}
+
+func mustEd25519Key(t *testing.T, kid string) *Key {
+	t.Helper()
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	key, err := ParsePEMKey(kid, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("ParsePEMKey: %v", err)
+	}
+	return key
+}
+
+func mustPublicKey(t *testing.T, kid string, private *Key) *Key {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(private.verifyKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+	key, err := ParsePEMKey(kid, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("ParsePEMKey: %v", err)
+	}
+	return key
+}
@@ -0,0 +1,315 @@
+package mqtt
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// Broker is a small embedded MQTT 3.1.1 broker intended for local development
+// and tests. It supports QoS 0 delivery, retained messages, wildcards and
+// last-will messages; every subscription is granted QoS 0.
+type Broker struct {
+	mu       sync.RWMutex
+	sessions map[*brokerSession]struct{}
+	retained map[string]Message
+	listener net.Listener
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// brokerSession holds the state of a connected client
+type brokerSession struct {
+	conn     net.Conn
+	clientID string
+	will     *Message
+
+	writeMu sync.Mutex
+	filters map[string]struct{}
+}
+
+// NewBroker creates an embedded broker
+func NewBroker() *Broker {
+	return &Broker{
+		sessions: make(map[*brokerSession]struct{}),
+		retained: make(map[string]Message),
+	}
+}
+
+// Listen starts serving on addr (e.g. "127.0.0.1:0") in the background
+func (b *Broker) Listen(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.listener = l
+	b.mu.Unlock()
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		_ = b.Serve(l)
+	}()
+	return nil
+}
+
+// Addr returns the listening address, or "" when the broker is not listening
+func (b *Broker) Addr() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.listener == nil {
+		return ""
+	}
+	return b.listener.Addr().String()
+}
+
+// Serve accepts connections on l until the broker is closed
+func (b *Broker) Serve(l net.Listener) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		l.Close()
+		return errors.New("mqtt: broker closed")
+	}
+	b.listener = l
+	b.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			b.mu.RLock()
+			closed := b.closed
+			b.mu.RUnlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			b.handle(conn)
+		}()
+	}
+}
+
+// Close stops the listener and disconnects all clients without publishing wills
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	if b.listener != nil {
+		b.listener.Close()
+	}
+	for s := range b.sessions {
+		s.will = nil
+		s.conn.Close()
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+	return nil
+}
+
+// Publish injects a message as if it had been published by a client
+func (b *Broker) Publish(msg Message) {
+	b.route(msg)
+}
+
+func (b *Broker) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	p, err := readPacket(reader)
+	if err != nil || p.kind != packetConnect {
+		return
+	}
+
+	connect, code, err := decodeConnect(p.body)
+	if err != nil {
+		if code != connackAccepted {
+			_ = writePacket(conn, packetConnack, 0, []byte{0, code})
+		}
+		return
+	}
+
+	s := &brokerSession{
+		conn:     conn,
+		clientID: connect.clientID,
+		will:     connect.will,
+		filters:  make(map[string]struct{}),
+	}
+	if !b.register(s) {
+		return
+	}
+	if err := s.write(packetConnack, 0, []byte{0, connackAccepted}); err != nil {
+		_ = b.unregister(s)
+		return
+	}
+
+	keepAlive := time.Duration(connect.keepAlive) * time.Second
+	graceful := b.serveSession(s, reader, keepAlive)
+
+	will := b.unregister(s)
+	if !graceful && will != nil {
+		b.route(*will)
+	}
+}
+
+// serveSession processes packets until the client disconnects; it reports
+// whether the client sent DISCONNECT
+func (b *Broker) serveSession(s *brokerSession, reader *bufio.Reader, keepAlive time.Duration) bool {
+	for {
+		if keepAlive > 0 {
+			_ = s.conn.SetReadDeadline(time.Now().Add(keepAlive * 3 / 2))
+		} else {
+			_ = s.conn.SetReadDeadline(time.Time{})
+		}
+
+		p, err := readPacket(reader)
+		if err != nil {
+			return false
+		}
+
+		switch p.kind {
+		case packetPublish:
+			pub, err := decodePublish(p.flags, p.body)
+			if err != nil {
+				return false
+			}
+			if pub.qos > 0 {
+				if err := s.write(packetPuback, 0, appendUint16(nil, pub.packetID)); err != nil {
+					return false
+				}
+			}
+			b.route(pub.message)
+		case packetSubscribe:
+			packetID, filters, err := decodeSubscribe(p.body)
+			if err != nil {
+				return false
+			}
+			b.subscribe(s, packetID, filters)
+		case packetUnsubscribe:
+			packetID, filters, err := decodeUnsubscribe(p.body)
+			if err != nil {
+				return false
+			}
+			b.mu.Lock()
+			for _, filter := range filters {
+				delete(s.filters, filter)
+			}
+			b.mu.Unlock()
+			if err := s.write(packetUnsuback, 0, appendUint16(nil, packetID)); err != nil {
+				return false
+			}
+		case packetPingreq:
+			if err := s.write(packetPingresp, 0, nil); err != nil {
+				return false
+			}
+		case packetDisconnect:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// register adds the session, taking over any existing session with the same client ID
+func (b *Broker) register(s *brokerSession) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return false
+	}
+	if s.clientID != "" {
+		for existing := range b.sessions {
+			if existing.clientID == s.clientID {
+				existing.will = nil
+				existing.conn.Close()
+				delete(b.sessions, existing)
+			}
+		}
+	}
+	b.sessions[s] = struct{}{}
+	return true
+}
+
+// unregister removes the session and returns its pending will message
+func (b *Broker) unregister(s *brokerSession) *Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.sessions, s)
+	return s.will
+}
+
+func (b *Broker) subscribe(s *brokerSession, packetID uint16, filters []string) {
+	b.mu.Lock()
+	for _, filter := range filters {
+		s.filters[filter] = struct{}{}
+	}
+	var retained []Message
+	for topic, msg := range b.retained {
+		for _, filter := range filters {
+			if Match(filter, topic) {
+				retained = append(retained, msg)
+				break
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	ack := appendUint16(nil, packetID)
+	for range filters {
+		ack = append(ack, 0)
+	}
+	if err := s.write(packetSuback, 0, ack); err != nil {
+		return
+	}
+
+	for _, msg := range retained {
+		flags, body := encodePublish(msg)
+		_ = s.write(packetPublish, flags, body)
+	}
+}
+
+// route stores retained messages and forwards msg to every matching session
+func (b *Broker) route(msg Message) {
+	b.mu.Lock()
+	if msg.Retain {
+		if len(msg.Payload) == 0 {
+			delete(b.retained, msg.Topic)
+		} else {
+			b.retained[msg.Topic] = msg
+		}
+	}
+
+	var targets []*brokerSession
+	for s := range b.sessions {
+		for filter := range s.filters {
+			if Match(filter, msg.Topic) {
+				targets = append(targets, s)
+				break
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	// 实时转发的消息不带retain标志
+	flags, body := encodePublish(Message{Topic: msg.Topic, Payload: msg.Payload})
+	for _, s := range targets {
+		_ = s.write(packetPublish, flags, body)
+	}
+}
+
+func (s *brokerSession) write(kind, flags byte, body []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_ = s.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return writePacket(s.conn, kind, flags, body)
+}
@@ -0,0 +1,414 @@
+package mqtt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotConnected is returned when an operation needs a live broker connection
+var ErrNotConnected = errors.New("mqtt: not connected")
+
+// ErrClientClosed is returned after Close has been called
+var ErrClientClosed = errors.New("mqtt: client closed")
+
+// Message represents an application message
+type Message struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// MessageHandler is called for every message matching a subscription
+type MessageHandler func(Message)
+
+// Options configures a Client
+type Options struct {
+	Broker           string // tcp://host:port 或 host:port
+	ClientID         string
+	Username         string
+	Password         string
+	KeepAlive        time.Duration
+	ConnectTimeout   time.Duration
+	ReconnectDelay   time.Duration // 断线重连间隔，小于0表示不自动重连
+	Will             *Message      // 遗嘱消息，异常断开时由broker发布
+	OnConnectionLost func(err error)
+}
+
+// Client is a minimal MQTT 3.1.1 client supporting QoS 0 publish/subscribe,
+// keep-alive and automatic reconnection with resubscription.
+type Client struct {
+	opts Options
+	addr string
+
+	writeMu sync.Mutex
+	conn    net.Conn
+
+	subMu  sync.RWMutex
+	subs   map[string]MessageHandler
+	acks   map[uint16]chan byte
+	nextID uint16
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// Dial connects to the broker described by opts
+func Dial(opts Options) (*Client, error) {
+	addr, err := brokerAddress(opts.Broker)
+	if err != nil {
+		return nil, err
+	}
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = 30 * time.Second
+	}
+	if opts.ConnectTimeout <= 0 {
+		opts.ConnectTimeout = 10 * time.Second
+	}
+	if opts.ReconnectDelay == 0 {
+		opts.ReconnectDelay = 5 * time.Second
+	}
+
+	c := &Client{
+		opts:   opts,
+		addr:   addr,
+		subs:   make(map[string]MessageHandler),
+		acks:   make(map[uint16]chan byte),
+		closed: make(chan struct{}),
+	}
+
+	conn, reader, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+
+	c.wg.Add(2)
+	go c.readLoop(conn, reader)
+	go c.keepAliveLoop()
+
+	return c, nil
+}
+
+// Publish sends a QoS 0 message
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	if topic == "" {
+		return errors.New("mqtt: topic cannot be empty")
+	}
+	flags, body := encodePublish(Message{Topic: topic, Payload: payload, Retain: retain})
+	return c.write(packetPublish, flags, body)
+}
+
+// Subscribe registers handler for filter and waits for the broker acknowledgement
+func (c *Client) Subscribe(filter string, handler MessageHandler) error {
+	if filter == "" || handler == nil {
+		return errors.New("mqtt: filter and handler are required")
+	}
+
+	c.subMu.Lock()
+	c.subs[filter] = handler
+	packetID := c.nextPacketID()
+	ack := make(chan byte, 1)
+	c.acks[packetID] = ack
+	c.subMu.Unlock()
+
+	defer func() {
+		c.subMu.Lock()
+		delete(c.acks, packetID)
+		c.subMu.Unlock()
+	}()
+
+	if err := c.write(packetSubscribe, subscribeFixedHeaderFlag, encodeSubscribe(packetID, []string{filter})); err != nil {
+		return err
+	}
+
+	select {
+	case code := <-ack:
+		if code == subackFailure {
+			return fmt.Errorf("mqtt: subscription to %q rejected", filter)
+		}
+		return nil
+	case <-time.After(c.opts.ConnectTimeout):
+		return fmt.Errorf("mqtt: subscribe %q timed out", filter)
+	case <-c.closed:
+		return ErrClientClosed
+	}
+}
+
+// Close disconnects from the broker and stops background goroutines
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		_ = c.write(packetDisconnect, 0, nil)
+
+		c.writeMu.Lock()
+		if c.conn != nil {
+			_ = c.conn.Close()
+			c.conn = nil
+		}
+		c.writeMu.Unlock()
+	})
+	c.wg.Wait()
+	return nil
+}
+
+// IsConnected reports whether the client currently holds a broker connection
+func (c *Client) IsConnected() bool {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn != nil
+}
+
+func (c *Client) write(kind, flags byte, body []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.conn == nil {
+		select {
+		case <-c.closed:
+			return ErrClientClosed
+		default:
+			return ErrNotConnected
+		}
+	}
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.opts.ConnectTimeout))
+	return writePacket(c.conn, kind, flags, body)
+}
+
+// connect dials the broker and completes the CONNECT/CONNACK handshake
+func (c *Client) connect() (net.Conn, *bufio.Reader, error) {
+	conn, err := net.DialTimeout("tcp", c.addr, c.opts.ConnectTimeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("mqtt: dial %s: %w", c.addr, err)
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(c.opts.ConnectTimeout))
+	body := encodeConnect(connectPacket{
+		clientID:     c.opts.ClientID,
+		username:     c.opts.Username,
+		password:     c.opts.Password,
+		keepAlive:    uint16(c.opts.KeepAlive / time.Second), // #nosec G115 - keep-alive seconds fit in uint16
+		cleanSession: true,
+		will:         c.opts.Will,
+	})
+	if err := writePacket(conn, packetConnect, 0, body); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("mqtt: send connect: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	p, err := readPacket(reader)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("mqtt: read connack: %w", err)
+	}
+	if p.kind != packetConnack || len(p.body) != 2 {
+		conn.Close()
+		return nil, nil, errMalformedPacket
+	}
+	if code := p.body[1]; code != connackAccepted {
+		conn.Close()
+		return nil, nil, fmt.Errorf("mqtt: connection refused, code %d", code)
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+	return conn, reader, nil
+}
+
+// readLoop handles incoming packets and reconnects when the connection drops
+func (c *Client) readLoop(conn net.Conn, reader *bufio.Reader) {
+	defer c.wg.Done()
+
+	for {
+		err := c.readPackets(conn, reader)
+
+		select {
+		case <-c.closed:
+			return
+		default:
+		}
+
+		c.writeMu.Lock()
+		if c.conn == conn {
+			c.conn = nil
+		}
+		c.writeMu.Unlock()
+		conn.Close()
+
+		if c.opts.OnConnectionLost != nil {
+			c.opts.OnConnectionLost(err)
+		}
+		if c.opts.ReconnectDelay < 0 {
+			return
+		}
+
+		conn, reader = c.reconnect()
+		if conn == nil {
+			return
+		}
+	}
+}
+
+func (c *Client) readPackets(conn net.Conn, reader *bufio.Reader) error {
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(c.opts.KeepAlive * 2))
+		p, err := readPacket(reader)
+		if err != nil {
+			return err
+		}
+
+		switch p.kind {
+		case packetPublish:
+			pub, err := decodePublish(p.flags, p.body)
+			if err != nil {
+				return err
+			}
+			if pub.qos > 0 {
+				if err := c.write(packetPuback, 0, appendUint16(nil, pub.packetID)); err != nil {
+					return err
+				}
+			}
+			c.dispatch(pub.message)
+		case packetSuback:
+			d := &decoder{buf: p.body}
+			packetID := d.uint16()
+			code := d.uint8()
+			if d.err != nil {
+				return d.err
+			}
+			c.subMu.RLock()
+			ack, ok := c.acks[packetID]
+			c.subMu.RUnlock()
+			if ok {
+				ack <- code
+			}
+		case packetPingresp, packetPuback, packetUnsuback:
+			// 无需处理
+		default:
+			return fmt.Errorf("mqtt: unexpected packet type %d", p.kind)
+		}
+	}
+}
+
+// reconnect redials until it succeeds or the client is closed, then restores subscriptions
+func (c *Client) reconnect() (net.Conn, *bufio.Reader) {
+	for {
+		select {
+		case <-c.closed:
+			return nil, nil
+		case <-time.After(c.opts.ReconnectDelay):
+		}
+
+		conn, reader, err := c.connect()
+		if err != nil {
+			continue
+		}
+
+		c.writeMu.Lock()
+		select {
+		case <-c.closed:
+			c.writeMu.Unlock()
+			conn.Close()
+			return nil, nil
+		default:
+		}
+		c.conn = conn
+		c.writeMu.Unlock()
+
+		c.subMu.Lock()
+		filters := make([]string, 0, len(c.subs))
+		for filter := range c.subs {
+			filters = append(filters, filter)
+		}
+		packetID := c.nextPacketID()
+		c.subMu.Unlock()
+
+		if len(filters) > 0 {
+			// SUBACK由readLoop接收，这里无需等待
+			if err := c.write(packetSubscribe, subscribeFixedHeaderFlag, encodeSubscribe(packetID, filters)); err != nil {
+				conn.Close()
+				continue
+			}
+		}
+		return conn, reader
+	}
+}
+
+// nextPacketID returns the next packet identifier, skipping 0 which MQTT 3.1.1 does not allow.
+// The caller must hold subMu.
+func (c *Client) nextPacketID() uint16 {
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1
+	}
+	return c.nextID
+}
+
+func (c *Client) dispatch(msg Message) {
+	c.subMu.RLock()
+	handlers := make([]MessageHandler, 0, 1)
+	for filter, handler := range c.subs {
+		if Match(filter, msg.Topic) {
+			handlers = append(handlers, handler)
+		}
+	}
+	c.subMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(msg)
+	}
+}
+
+func (c *Client) keepAliveLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.opts.KeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-ticker.C:
+			_ = c.write(packetPingreq, 0, nil)
+		}
+	}
+}
+
+// brokerAddress converts a broker URL such as tcp://host:1883 into host:port
+func brokerAddress(broker string) (string, error) {
+	if broker == "" {
+		return "", errors.New("mqtt: broker address is required")
+	}
+
+	if !strings.Contains(broker, "://") {
+		// 不带scheme的 host:port 形式
+		if _, _, err := net.SplitHostPort(broker); err != nil {
+			return "", fmt.Errorf("mqtt: invalid broker address %q", broker)
+		}
+		return broker, nil
+	}
+
+	u, err := url.Parse(broker)
+	if err != nil || u.Hostname() == "" {
+		return "", fmt.Errorf("mqtt: invalid broker address %q", broker)
+	}
+
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	default:
+		return "", fmt.Errorf("mqtt: unsupported broker scheme %q", u.Scheme)
+	}
+
+	if u.Port() == "" {
+		return net.JoinHostPort(u.Hostname(), "1883"), nil
+	}
+	return u.Host, nil
+}
@@ -0,0 +1,188 @@
+package mqtt
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func startBroker(t *testing.T) *Broker {
+	t.Helper()
+	broker := NewBroker()
+	if err := broker.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("failed to start broker: %v", err)
+	}
+	t.Cleanup(func() { broker.Close() })
+	return broker
+}
+
+func dialClient(t *testing.T, broker *Broker, clientID string, will *Message) *Client {
+	t.Helper()
+	client, err := Dial(Options{
+		Broker:         "tcp://" + broker.Addr(),
+		ClientID:       clientID,
+		KeepAlive:      5 * time.Second,
+		ConnectTimeout: 2 * time.Second,
+		ReconnectDelay: -1,
+		Will:           will,
+	})
+	if err != nil {
+		t.Fatalf("failed to connect %s: %v", clientID, err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func receive(t *testing.T, ch <-chan Message) Message {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for message")
+		return Message{}
+	}
+}
+
+func TestClient_PublishSubscribe(t *testing.T) {
+	broker := startBroker(t)
+	subscriber := dialClient(t, broker, "subscriber", nil)
+	publisher := dialClient(t, broker, "publisher", nil)
+
+	received := make(chan Message, 1)
+	if err := subscriber.Subscribe("vm/+/heartbeat", func(msg Message) { received <- msg }); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	if err := publisher.Publish("vm/M001/heartbeat", []byte("ping"), false); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	msg := receive(t, received)
+	if msg.Topic != "vm/M001/heartbeat" || string(msg.Payload) != "ping" {
+		t.Errorf("unexpected message %s %q", msg.Topic, msg.Payload)
+	}
+}
+
+func TestClient_RetainedMessage(t *testing.T) {
+	broker := startBroker(t)
+	publisher := dialClient(t, broker, "publisher", nil)
+
+	if err := publisher.Publish("vm/M001/status", []byte("online"), true); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	// 等待broker处理保留消息
+	time.Sleep(50 * time.Millisecond)
+
+	subscriber := dialClient(t, broker, "subscriber", nil)
+	received := make(chan Message, 1)
+	if err := subscriber.Subscribe("vm/#", func(msg Message) { received <- msg }); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	msg := receive(t, received)
+	if !msg.Retain || string(msg.Payload) != "online" {
+		t.Errorf("expected retained online message, got retain=%v payload=%q", msg.Retain, msg.Payload)
+	}
+}
+
+func TestClient_WillPublishedOnAbnormalDisconnect(t *testing.T) {
+	broker := startBroker(t)
+	subscriber := dialClient(t, broker, "subscriber", nil)
+
+	received := make(chan Message, 1)
+	if err := subscriber.Subscribe("vm/M001/status", func(msg Message) { received <- msg }); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	// 模拟设备直接断开TCP连接（不发送DISCONNECT）
+	conn, err := net.Dial("tcp", broker.Addr())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	body := encodeConnect(connectPacket{
+		clientID:     "device",
+		keepAlive:    30,
+		cleanSession: true,
+		will:         &Message{Topic: "vm/M001/status", Payload: []byte("offline")},
+	})
+	if err := writePacket(conn, packetConnect, 0, body); err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	conn.Close()
+
+	msg := receive(t, received)
+	if string(msg.Payload) != "offline" {
+		t.Errorf("expected will payload offline, got %q", msg.Payload)
+	}
+}
+
+func TestClient_GracefulCloseSkipsWill(t *testing.T) {
+	broker := startBroker(t)
+	subscriber := dialClient(t, broker, "subscriber", nil)
+
+	received := make(chan Message, 1)
+	if err := subscriber.Subscribe("vm/M001/status", func(msg Message) { received <- msg }); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	device := dialClient(t, broker, "device", &Message{Topic: "vm/M001/status", Payload: []byte("offline")})
+	device.Close()
+
+	select {
+	case msg := <-received:
+		t.Errorf("unexpected will message %q after graceful close", msg.Payload)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestClient_PublishAfterClose(t *testing.T) {
+	broker := startBroker(t)
+	client := dialClient(t, broker, "client", nil)
+	client.Close()
+
+	if err := client.Publish("vm/M001/register", []byte("{}"), false); err != ErrClientClosed {
+		t.Errorf("expected ErrClientClosed, got %v", err)
+	}
+	if client.IsConnected() {
+		t.Error("client should not report connected after close")
+	}
+}
+
+func TestDial_InvalidBroker(t *testing.T) {
+	tests := []string{"", "http://localhost:1883", "not a url"}
+	for _, broker := range tests {
+		if _, err := Dial(Options{Broker: broker, ConnectTimeout: 100 * time.Millisecond}); err == nil {
+			t.Errorf("expected error for broker %q", broker)
+		}
+	}
+}
+
+func TestBrokerAddress(t *testing.T) {
+	tests := map[string]string{
+		"tcp://localhost:1883": "localhost:1883",
+		"mqtt://broker":        "broker:1883",
+		"127.0.0.1:1884":       "127.0.0.1:1884",
+	}
+	for input, expected := range tests {
+		addr, err := brokerAddress(input)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", input, err)
+			continue
+		}
+		if addr != expected {
+			t.Errorf("brokerAddress(%q) = %q, want %q", input, addr, expected)
+		}
+	}
+}
+
+func TestClient_NextPacketIDSkipsZero(t *testing.T) {
+	c := &Client{nextID: 0xFFFE}
+
+	for _, expected := range []uint16{0xFFFF, 1, 2} {
+		if id := c.nextPacketID(); id != expected {
+			t.Errorf("nextPacketID() = %d, want %d", id, expected)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MQTT 3.1.1 control packet types
+const (
+	packetConnect     byte = 1
+	packetConnack     byte = 2
+	packetPublish     byte = 3
+	packetPuback      byte = 4
+	packetSubscribe   byte = 8
+	packetSuback      byte = 9
+	packetUnsubscribe byte = 10
+	packetUnsuback    byte = 11
+	packetPingreq     byte = 12
+	packetPingresp    byte = 13
+	packetDisconnect  byte = 14
+)
+
+const (
+	protocolName  = "MQTT"
+	protocolLevel = 4
+
+	// maxPacketSize limits the remaining length we are willing to buffer
+	maxPacketSize = 1 << 20
+)
+
+// connect flag bits
+const (
+	flagCleanSession byte = 0x02
+	flagWill         byte = 0x04
+	flagWillRetain   byte = 0x20
+	flagPassword     byte = 0x40
+	flagUsername     byte = 0x80
+)
+
+// CONNACK return codes
+const (
+	connackAccepted          byte = 0
+	connackBadProtocol       byte = 1
+	connackBadCredentials    byte = 4
+	subackFailure            byte = 0x80
+	publishFlagRetain        byte = 0x01
+	publishFlagQoSMask       byte = 0x06
+	subscribeFixedHeaderFlag byte = 0x02
+)
+
+var errMalformedPacket = errors.New("mqtt: malformed packet")
+
+// packet is a raw control packet with its fixed header split out
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+// readPacket reads one control packet from r
+func readPacket(r *bufio.Reader) (*packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := readRemainingLength(r)
+	if err != nil {
+		return nil, err
+	}
+	if length > maxPacketSize {
+		return nil, fmt.Errorf("mqtt: packet too large: %d bytes", length)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	return &packet{kind: header >> 4, flags: header & 0x0f, body: body}, nil
+}
+
+// writePacket encodes and writes one control packet to w
+func writePacket(w io.Writer, kind, flags byte, body []byte) error {
+	buf := make([]byte, 0, len(body)+5)
+	buf = append(buf, kind<<4|flags)
+	buf = appendRemainingLength(buf, len(body))
+	buf = append(buf, body...)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readRemainingLength(r io.ByteReader) (int, error) {
+	value := 0
+	multiplier := 1
+	for i := 0; i < 4; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		value += int(b&0x7f) * multiplier
+		if b&0x80 == 0 {
+			return value, nil
+		}
+		multiplier *= 128
+	}
+	return 0, errMalformedPacket
+}
+
+func appendRemainingLength(b []byte, length int) []byte {
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if length == 0 {
+			return b
+		}
+	}
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return binary.BigEndian.AppendUint16(b, v)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = appendUint16(b, uint16(len(s))) // #nosec G115 - topic and credential lengths are bounded by the protocol
+	return append(b, s...)
+}
+
+// decoder reads fields sequentially from a packet body and records the first error
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) uint8() byte {
+	if d.err != nil || len(d.buf) < 1 {
+		d.err = errMalformedPacket
+		return 0
+	}
+	v := d.buf[0]
+	d.buf = d.buf[1:]
+	return v
+}
+
+func (d *decoder) uint16() uint16 {
+	if d.err != nil || len(d.buf) < 2 {
+		d.err = errMalformedPacket
+		return 0
+	}
+	v := binary.BigEndian.Uint16(d.buf)
+	d.buf = d.buf[2:]
+	return v
+}
+
+func (d *decoder) bytes() []byte {
+	n := int(d.uint16())
+	if d.err != nil || len(d.buf) < n {
+		d.err = errMalformedPacket
+		return nil
+	}
+	v := d.buf[:n]
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) rest() []byte {
+	v := d.buf
+	d.buf = nil
+	return v
+}
+
+// connectPacket is the decoded form of a CONNECT packet
+type connectPacket struct {
+	clientID     string
+	username     string
+	password     string
+	keepAlive    uint16
+	cleanSession bool
+	will         *Message
+}
+
+func encodeConnect(p connectPacket) []byte {
+	var flags byte
+	if p.cleanSession {
+		flags |= flagCleanSession
+	}
+	if p.will != nil {
+		flags |= flagWill
+		if p.will.Retain {
+			flags |= flagWillRetain
+		}
+	}
+	if p.username != "" {
+		flags |= flagUsername
+	}
+	if p.password != "" {
+		flags |= flagPassword
+	}
+
+	body := appendString(nil, protocolName)
+	body = append(body, protocolLevel, flags)
+	body = appendUint16(body, p.keepAlive)
+	body = appendString(body, p.clientID)
+	if p.will != nil {
+		body = appendString(body, p.will.Topic)
+		body = appendString(body, string(p.will.Payload))
+	}
+	if p.username != "" {
+		body = appendString(body, p.username)
+	}
+	if p.password != "" {
+		body = appendString(body, p.password)
+	}
+	return body
+}
+
+func decodeConnect(body []byte) (*connectPacket, byte, error) {
+	d := &decoder{buf: body}
+	name := d.string()
+	level := d.uint8()
+	flags := d.uint8()
+	keepAlive := d.uint16()
+	if d.err != nil {
+		return nil, 0, d.err
+	}
+	if name != protocolName || level != protocolLevel {
+		return nil, connackBadProtocol, fmt.Errorf("mqtt: unsupported protocol %q level %d", name, level)
+	}
+
+	p := &connectPacket{
+		keepAlive:    keepAlive,
+		cleanSession: flags&flagCleanSession != 0,
+		clientID:     d.string(),
+	}
+	if flags&flagWill != 0 {
+		p.will = &Message{
+			Topic:   d.string(),
+			Payload: append([]byte(nil), d.bytes()...),
+			Retain:  flags&flagWillRetain != 0,
+		}
+	}
+	if flags&flagUsername != 0 {
+		p.username = d.string()
+	}
+	if flags&flagPassword != 0 {
+		p.password = d.string()
+	}
+	if d.err != nil {
+		return nil, 0, d.err
+	}
+	return p, connackAccepted, nil
+}
+
+// publishPacket is the decoded form of a PUBLISH packet
+type publishPacket struct {
+	message  Message
+	qos      byte
+	packetID uint16
+}
+
+func encodePublish(msg Message) (byte, []byte) {
+	var flags byte
+	if msg.Retain {
+		flags |= publishFlagRetain
+	}
+	body := appendString(nil, msg.Topic)
+	body = append(body, msg.Payload...)
+	return flags, body
+}
+
+func decodePublish(flags byte, body []byte) (*publishPacket, error) {
+	d := &decoder{buf: body}
+	p := &publishPacket{qos: (flags & publishFlagQoSMask) >> 1}
+	p.message.Topic = d.string()
+	p.message.Retain = flags&publishFlagRetain != 0
+	if p.qos > 0 {
+		p.packetID = d.uint16()
+	}
+	p.message.Payload = append([]byte(nil), d.rest()...)
+	if d.err != nil {
+		return nil, d.err
+	}
+	return p, nil
+}
+
+func encodeSubscribe(packetID uint16, filters []string) []byte {
+	body := appendUint16(nil, packetID)
+	for _, filter := range filters {
+		body = appendString(body, filter)
+		body = append(body, 0) // QoS 0
+	}
+	return body
+}
+
+func decodeSubscribe(body []byte) (uint16, []string, error) {
+	d := &decoder{buf: body}
+	packetID := d.uint16()
+	var filters []string
+	for d.err == nil && len(d.buf) > 0 {
+		filters = append(filters, d.string())
+		d.uint8() // requested QoS, always granted as 0
+	}
+	if d.err != nil || len(filters) == 0 {
+		return 0, nil, errMalformedPacket
+	}
+	return packetID, filters, nil
+}
+
+func decodeUnsubscribe(body []byte) (uint16, []string, error) {
+	d := &decoder{buf: body}
+	packetID := d.uint16()
+	var filters []string
+	for d.err == nil && len(d.buf) > 0 {
+		filters = append(filters, d.string())
+	}
+	if d.err != nil {
+		return 0, nil, d.err
+	}
+	return packetID, filters, nil
+}
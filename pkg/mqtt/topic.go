@@ -0,0 +1,32 @@
+package mqtt
+
+import "strings"
+
+// Match reports whether topic matches the subscription filter.
+// "+" matches exactly one level and "#" matches any remaining levels.
+func Match(filter, topic string) bool {
+	if filter == "" || topic == "" {
+		return false
+	}
+	// 以$开头的系统主题不参与首层通配符匹配
+	if strings.HasPrefix(topic, "$") && (filter[0] == '+' || filter[0] == '#') {
+		return false
+	}
+
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+
+	for i, level := range filterLevels {
+		if level == "#" {
+			return i == len(filterLevels)-1
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if level != "+" && level != topicLevels[i] {
+			return false
+		}
+	}
+
+	return len(filterLevels) == len(topicLevels)
+}
@@ -0,0 +1,29 @@
+package mqtt
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		filter string
+		topic  string
+		want   bool
+	}{
+		{"vm/M001/heartbeat", "vm/M001/heartbeat", true},
+		{"vm/+/heartbeat", "vm/M001/heartbeat", true},
+		{"vm/+/heartbeat", "vm/M001/status", false},
+		{"vm/+", "vm/M001/heartbeat", false},
+		{"vm/#", "vm/M001/heartbeat", true},
+		{"vm/#", "vm", true},
+		{"#", "vm/M001", true},
+		{"#", "$SYS/uptime", false},
+		{"+/M001", "$SYS/M001", false},
+		{"vm/M001", "vm/M001/heartbeat", false},
+		{"", "vm", false},
+	}
+
+	for _, tt := range tests {
+		if got := Match(tt.filter, tt.topic); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.filter, tt.topic, got, tt.want)
+		}
+	}
+}
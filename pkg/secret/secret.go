@@ -0,0 +1,81 @@
+// Package secret encrypts small secrets (merchant keys, tokens) for storage at rest
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// prefix marks values produced by Encrypt, so legacy plaintext can be told apart
+const prefix = "enc:v1:"
+
+// ErrDecryptFailed is returned when a value was not encrypted with this key or has been altered
+var ErrDecryptFailed = errors.New("secret: failed to decrypt value")
+
+// Box encrypts and decrypts values with AES-256-GCM
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New creates a Box from a 32 byte key
+func New(key []byte) (*Box, error) {
+	if len(key) != 32 {
+		return nil, errors.New("secret: key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("secret: invalid key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("secret: failed to create cipher: %w", err)
+	}
+	return &Box{aead: aead}, nil
+}
+
+// NewFromBase64 creates a Box from a base64 encoded 32 byte key
+func NewFromBase64(encoded string) (*Box, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("secret: invalid base64 key: %w", err)
+	}
+	return New(key)
+}
+
+// IsEncrypted reports whether value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt seals plaintext with a random nonce and returns a printable value
+func (b *Box) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("secret: failed to generate nonce: %w", err)
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt
+func (b *Box) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", fmt.Errorf("%w: missing prefix", ErrDecryptFailed)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, prefix))
+	if err != nil || len(sealed) < b.aead.NonceSize() {
+		return "", ErrDecryptFailed
+	}
+
+	nonce, ciphertext := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrDecryptFailed
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,81 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestBox_RoundTrip(t *testing.T) {
+	box, err := New(testKey(1))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	encrypted, err := box.Encrypt("merchant-key-0123456789")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !IsEncrypted(encrypted) || strings.Contains(encrypted, "merchant-key") {
+		t.Fatalf("unexpected encrypted value %q", encrypted)
+	}
+
+	// 相同明文每次加密结果不同
+	again, _ := box.Encrypt("merchant-key-0123456789")
+	if again == encrypted {
+		t.Error("expected a fresh nonce for every encryption")
+	}
+
+	plaintext, err := box.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if plaintext != "merchant-key-0123456789" {
+		t.Errorf("got %q", plaintext)
+	}
+}
+
+func TestBox_DecryptRejects(t *testing.T) {
+	box, _ := New(testKey(1))
+	other, _ := New(testKey(2))
+	encrypted, _ := box.Encrypt("merchant-key")
+
+	tampered := []byte(encrypted)
+	tampered[len(tampered)-2] ^= 1
+
+	tests := []struct {
+		name  string
+		box   *Box
+		value string
+	}{
+		{"wrong key", other, encrypted},
+		{"plaintext", box, "merchant-key"},
+		{"tampered", box, string(tampered)},
+		{"truncated", box, prefix + "AAAA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.box.Decrypt(tt.value); !errors.Is(err, ErrDecryptFailed) {
+				t.Errorf("expected ErrDecryptFailed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewFromBase64(t *testing.T) {
+	if _, err := NewFromBase64(base64.StdEncoding.EncodeToString(testKey(3))); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := NewFromBase64("not-base64!"); err == nil {
+		t.Error("expected error for invalid base64")
+	}
+	if _, err := NewFromBase64(base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Error("expected error for short key")
+	}
+}
@@ -0,0 +1,117 @@
+package wechat
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidSessionKey is returned when the session key or iv is not a valid base64 AES-128 value
+	ErrInvalidSessionKey = errors.New("wechat: invalid session key or iv")
+	// ErrDecryptFailed is returned when encryptedData cannot be decrypted with the session key
+	ErrDecryptFailed = errors.New("wechat: failed to decrypt data")
+	// ErrWatermarkMismatch is returned when the decrypted data was issued to another appid
+	ErrWatermarkMismatch = errors.New("wechat: watermark appid mismatch")
+)
+
+// Watermark identifies the mini program the encrypted data was issued to
+type Watermark struct {
+	AppID     string `json:"appid"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// PhoneNumber represents decrypted wx.getPhoneNumber data
+type PhoneNumber struct {
+	PhoneNumber     string    `json:"phoneNumber"`
+	PurePhoneNumber string    `json:"purePhoneNumber"`
+	CountryCode     string    `json:"countryCode"`
+	Watermark       Watermark `json:"watermark"`
+}
+
+// UserInfo represents decrypted wx.getUserInfo data
+type UserInfo struct {
+	OpenID    string    `json:"openId"`
+	NickName  string    `json:"nickName"`
+	Gender    int       `json:"gender"`
+	City      string    `json:"city"`
+	Province  string    `json:"province"`
+	Country   string    `json:"country"`
+	AvatarURL string    `json:"avatarUrl"`
+	UnionID   string    `json:"unionId,omitempty"`
+	Watermark Watermark `json:"watermark"`
+}
+
+// DecryptPhoneNumber decrypts wx.getPhoneNumber encryptedData with the user's session key
+func (c *Client) DecryptPhoneNumber(sessionKey, encryptedData, iv string) (*PhoneNumber, error) {
+	var phone PhoneNumber
+	if err := c.decryptData(sessionKey, encryptedData, iv, &phone, &phone.Watermark); err != nil {
+		return nil, err
+	}
+	return &phone, nil
+}
+
+// DecryptUserInfo decrypts wx.getUserInfo encryptedData with the user's session key
+func (c *Client) DecryptUserInfo(sessionKey, encryptedData, iv string) (*UserInfo, error) {
+	var info UserInfo
+	if err := c.decryptData(sessionKey, encryptedData, iv, &info, &info.Watermark); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// decryptData decrypts encryptedData into out and checks that its watermark names this client's appid
+func (c *Client) decryptData(sessionKey, encryptedData, iv string, out interface{}, watermark *Watermark) error {
+	plaintext, err := DecryptData(sessionKey, encryptedData, iv)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(plaintext, out); err != nil {
+		return fmt.Errorf("%w: %v", ErrDecryptFailed, err)
+	}
+	if watermark.AppID != c.appID {
+		return ErrWatermarkMismatch
+	}
+	return nil
+}
+
+// DecryptData decrypts a WeChat encryptedData payload (AES-128-CBC, PKCS#7 padding)
+// sessionKey, encryptedData and iv are the base64 values returned by WeChat
+func DecryptData(sessionKey, encryptedData, iv string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(sessionKey)
+	if err != nil || len(key) != 16 {
+		return nil, ErrInvalidSessionKey
+	}
+	ivBytes, err := base64.StdEncoding.DecodeString(iv)
+	if err != nil || len(ivBytes) != aes.BlockSize {
+		return nil, ErrInvalidSessionKey
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encryptedData)
+	if err != nil || len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, ErrDecryptFailed
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrInvalidSessionKey
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, ivBytes).CryptBlocks(plaintext, ciphertext)
+	return pkcs7Unpad(plaintext)
+}
+
+// pkcs7Unpad removes and validates PKCS#7 padding
+func pkcs7Unpad(data []byte) ([]byte, error) {
+	padding := int(data[len(data)-1])
+	if padding == 0 || padding > aes.BlockSize || padding > len(data) {
+		return nil, ErrDecryptFailed
+	}
+	if !bytes.Equal(data[len(data)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, ErrDecryptFailed
+	}
+	return data[:len(data)-padding], nil
+}
@@ -0,0 +1,100 @@
+package wechat
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+var (
+	testSessionKey = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef"))
+	testIV         = base64.StdEncoding.EncodeToString([]byte("fedcba9876543210"))
+)
+
+// encryptTestData 按微信的方式加密测试数据
+func encryptTestData(t *testing.T, sessionKey, iv, plaintext string) string {
+	t.Helper()
+	key, _ := base64.StdEncoding.DecodeString(sessionKey)
+	ivBytes, _ := base64.StdEncoding.DecodeString(iv)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("NewCipher: %v", err)
+	}
+
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	data := append([]byte(plaintext), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	ciphertext := make([]byte, len(data))
+	cipher.NewCBCEncrypter(block, ivBytes).CryptBlocks(ciphertext, data)
+	return base64.StdEncoding.EncodeToString(ciphertext)
+}
+
+func TestDecryptPhoneNumber(t *testing.T) {
+	client := NewClient("wx_test_app", "secret")
+	encrypted := encryptTestData(t, testSessionKey, testIV,
+		`{"phoneNumber":"+86 13800138000","purePhoneNumber":"13800138000","countryCode":"86",`+
+			`"watermark":{"appid":"wx_test_app","timestamp":1700000000}}`)
+
+	phone, err := client.DecryptPhoneNumber(testSessionKey, encrypted, testIV)
+	if err != nil {
+		t.Fatalf("DecryptPhoneNumber: %v", err)
+	}
+	if phone.PurePhoneNumber != "13800138000" || phone.CountryCode != "86" {
+		t.Errorf("unexpected phone %+v", phone)
+	}
+	if phone.Watermark.Timestamp != 1700000000 {
+		t.Errorf("unexpected watermark %+v", phone.Watermark)
+	}
+}
+
+func TestDecryptUserInfo(t *testing.T) {
+	client := NewClient("wx_test_app", "secret")
+	encrypted := encryptTestData(t, testSessionKey, testIV,
+		`{"openId":"openid_1","nickName":"用户","unionId":"union_1","watermark":{"appid":"wx_test_app"}}`)
+
+	info, err := client.DecryptUserInfo(testSessionKey, encrypted, testIV)
+	if err != nil {
+		t.Fatalf("DecryptUserInfo: %v", err)
+	}
+	if info.OpenID != "openid_1" || info.UnionID != "union_1" {
+		t.Errorf("unexpected user info %+v", info)
+	}
+}
+
+func TestDecryptData_WatermarkMismatch(t *testing.T) {
+	// 其他小程序签发的数据不被接受
+	client := NewClient("wx_test_app", "secret")
+	encrypted := encryptTestData(t, testSessionKey, testIV,
+		`{"purePhoneNumber":"13800138000","watermark":{"appid":"wx_other_app"}}`)
+
+	if _, err := client.DecryptPhoneNumber(testSessionKey, encrypted, testIV); !errors.Is(err, ErrWatermarkMismatch) {
+		t.Errorf("expected ErrWatermarkMismatch, got %v", err)
+	}
+}
+
+func TestDecryptData_Invalid(t *testing.T) {
+	encrypted := encryptTestData(t, testSessionKey, testIV, `{"watermark":{"appid":"wx_test_app"}}`)
+	otherKey := base64.StdEncoding.EncodeToString([]byte("ffffffffffffffff"))
+
+	tests := []struct {
+		name          string
+		sessionKey    string
+		encryptedData string
+		iv            string
+		expected      error
+	}{
+		{"invalid session key", "not-base64!", encrypted, testIV, ErrInvalidSessionKey},
+		{"short session key", base64.StdEncoding.EncodeToString([]byte("short")), encrypted, testIV, ErrInvalidSessionKey},
+		{"invalid iv", testSessionKey, encrypted, base64.StdEncoding.EncodeToString([]byte("iv")), ErrInvalidSessionKey},
+		{"truncated data", testSessionKey, base64.StdEncoding.EncodeToString([]byte("abc")), testIV, ErrDecryptFailed},
+		{"wrong session key", otherKey, encrypted, testIV, ErrDecryptFailed},
+	}
+
+	for _, tt := range tests {
+		if _, err := DecryptData(tt.sessionKey, tt.encryptedData, tt.iv); !errors.Is(err, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package wechatpay
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// pathCertificates downloads the platform certificates
+const pathCertificates = "/v3/certificates"
+
+// algorithmAESGCM is the only encryption algorithm used by API v3
+const algorithmAESGCM = "AEAD_AES_256_GCM"
+
+// EncryptedResource is an AES-256-GCM encrypted payload (certificates, notifications)
+type EncryptedResource struct {
+	Algorithm      string `json:"algorithm"`
+	Ciphertext     string `json:"ciphertext"`
+	AssociatedData string `json:"associated_data"`
+	Nonce          string `json:"nonce"`
+	OriginalType   string `json:"original_type,omitempty"`
+}
+
+// DecryptResource decrypts resource with the merchant APIv3 key
+func DecryptResource(apiV3Key string, resource EncryptedResource) ([]byte, error) {
+	if resource.Algorithm != algorithmAESGCM {
+		return nil, fmt.Errorf("wechatpay: unsupported algorithm %s", resource.Algorithm)
+	}
+	if len(apiV3Key) != 32 {
+		return nil, errors.New("wechatpay: APIv3 key must be 32 bytes")
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(resource.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("wechatpay: invalid ciphertext: %w", err)
+	}
+	block, err := aes.NewCipher([]byte(apiV3Key))
+	if err != nil {
+		return nil, fmt.Errorf("wechatpay: invalid APIv3 key: %w", err)
+	}
+	aead, err := cipher.NewGCMWithNonceSize(block, len(resource.Nonce))
+	if err != nil {
+		return nil, fmt.Errorf("wechatpay: invalid nonce: %w", err)
+	}
+
+	plaintext, err := aead.Open(nil, []byte(resource.Nonce), ciphertext, []byte(resource.AssociatedData))
+	if err != nil {
+		return nil, ErrDecryptFailed
+	}
+	return plaintext, nil
+}
+
+// certificateStore caches platform certificates by serial number
+type certificateStore struct {
+	mu       sync.RWMutex
+	certs    map[string]*x509.Certificate
+	loadedAt time.Time
+}
+
+// get returns the public key of a valid platform certificate
+func (s *certificateStore) get(serial string, now time.Time) (*rsa.PublicKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cert, ok := s.certs[serial]
+	if !ok || now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return nil, false
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	return key, ok
+}
+
+// replace swaps in a freshly downloaded certificate set
+func (s *certificateStore) replace(certs map[string]*x509.Certificate, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.certs = certs
+	s.loadedAt = now
+}
+
+// stale reports whether the certificate set should be refreshed
+func (s *certificateStore) stale(now time.Time, ttl time.Duration) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.certs == nil || now.Sub(s.loadedAt) >= ttl
+}
+
+// certificatesResponse is the body of GET /v3/certificates
+type certificatesResponse struct {
+	Data []struct {
+		SerialNo           string            `json:"serial_no"`
+		EncryptCertificate EncryptedResource `json:"encrypt_certificate"`
+	} `json:"data"`
+}
+
+// platformKey returns the public key for a platform certificate serial,
+// downloading the certificate set when the serial is unknown or the cache is stale
+func (c *Client) platformKey(serial string) (*rsa.PublicKey, error) {
+	now := c.now()
+	if !c.certs.stale(now, c.cfg.CertificateTTL) {
+		if key, ok := c.certs.get(serial, now); ok {
+			return key, nil
+		}
+	}
+
+	c.certMu.Lock()
+	defer c.certMu.Unlock()
+	// 等待期间其他请求可能已完成下载
+	if key, ok := c.certs.get(serial, now); ok && !c.certs.stale(now, c.cfg.CertificateTTL) {
+		return key, nil
+	}
+	if err := c.downloadCertificates(); err != nil {
+		return nil, err
+	}
+	if key, ok := c.certs.get(serial, c.now()); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownCertificate, serial)
+}
+
+// downloadCertificates fetches, decrypts and verifies the platform certificates.
+// The download response is signed with one of the certificates it carries, so the
+// set is trusted only after the response signature verifies against it.
+func (c *Client) downloadCertificates() error {
+	header, body, err := c.send(http.MethodGet, pathCertificates, nil)
+	if err != nil {
+		return err
+	}
+
+	var resp certificatesResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("wechatpay: invalid certificates response: %w", err)
+	}
+
+	certs := make(map[string]*x509.Certificate, len(resp.Data))
+	for _, item := range resp.Data {
+		plaintext, err := DecryptResource(c.cfg.APIv3Key, item.EncryptCertificate)
+		if err != nil {
+			return err
+		}
+		block, _ := pem.Decode(plaintext)
+		if block == nil {
+			return errors.New("wechatpay: invalid platform certificate pem")
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("wechatpay: failed to parse platform certificate: %w", err)
+		}
+		certs[item.SerialNo] = cert
+	}
+
+	cert, ok := certs[header.Get(headerSerial)]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownCertificate, header.Get(headerSerial))
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("wechatpay: platform certificate key is not RSA")
+	}
+	if err := verifyResponse(key, header, body); err != nil {
+		return err
+	}
+
+	c.certs.replace(certs, c.now())
+	return nil
+}
+
+// VerifyAccount checks the merchant credentials by downloading the platform certificates.
+// The download is authenticated with the merchant certificate and the certificates
+// decrypt only with the merchant's APIv3 key
+func (c *Client) VerifyAccount() error {
+	c.certMu.Lock()
+	defer c.certMu.Unlock()
+	return c.downloadCertificates()
+}
@@ -0,0 +1,203 @@
+package wechatpay
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBaseURL is the WeChat Pay API v3 gateway
+const DefaultBaseURL = "https://api.mch.weixin.qq.com"
+
+// 应答及通知签名相关请求头
+const (
+	headerTimestamp = "Wechatpay-Timestamp"
+	headerNonce     = "Wechatpay-Nonce"
+	headerSignature = "Wechatpay-Signature"
+	headerSerial    = "Wechatpay-Serial"
+)
+
+// maxClockSkew bounds the accepted difference between a signed timestamp and now
+const maxClockSkew = 5 * time.Minute
+
+var (
+	// ErrInvalidSignature is returned when a response or notification signature does not verify
+	ErrInvalidSignature = errors.New("wechatpay: invalid signature")
+	// ErrUnknownCertificate is returned when no platform certificate matches a serial number
+	ErrUnknownCertificate = errors.New("wechatpay: unknown platform certificate")
+	// ErrDecryptFailed is returned when an encrypted resource fails authentication
+	ErrDecryptFailed = errors.New("wechatpay: failed to decrypt resource")
+)
+
+// APIError is returned when WeChat Pay rejects a request
+type APIError struct {
+	StatusCode int
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("wechatpay: %d %s - %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Config configures a client for one merchant
+type Config struct {
+	BaseURL        string          // 网关地址，默认 DefaultBaseURL
+	AppID          string          // 小程序AppID
+	MchID          string          // 商户号
+	SerialNo       string          // 商户API证书序列号
+	PrivateKey     *rsa.PrivateKey // 商户API证书私钥
+	APIv3Key       string          // APIv3密钥
+	Timeout        time.Duration   // 请求超时
+	CertificateTTL time.Duration   // 平台证书缓存时间
+}
+
+// Client calls the WeChat Pay API v3 for one merchant
+type Client struct {
+	cfg    Config
+	client *http.Client
+	now    func() time.Time
+	certs  certificateStore
+	certMu sync.Mutex
+}
+
+// NewClient creates a WeChat Pay API v3 client
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.MchID == "" || cfg.SerialNo == "" || cfg.PrivateKey == nil {
+		return nil, errors.New("wechatpay: mch id, certificate serial no and private key are required")
+	}
+	if len(cfg.APIv3Key) != 32 {
+		return nil, errors.New("wechatpay: APIv3 key must be 32 bytes")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.CertificateTTL <= 0 {
+		cfg.CertificateTTL = 12 * time.Hour
+	}
+
+	return &Client{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		now:    time.Now,
+	}, nil
+}
+
+// MchID returns the merchant this client acts for
+func (c *Client) MchID() string {
+	return c.cfg.MchID
+}
+
+// do sends a signed request, verifies the response signature and decodes the body into out
+func (c *Client) do(method, path string, payload, out interface{}) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return fmt.Errorf("wechatpay: failed to encode request: %w", err)
+		}
+	}
+
+	header, respBody, err := c.send(method, path, body)
+	if err != nil {
+		return err
+	}
+	// 无应答体的成功响应（如关单返回204）不携带签名
+	if len(respBody) == 0 && header.Get(headerSignature) == "" {
+		return nil
+	}
+	if err := c.verify(header, respBody); err != nil {
+		return err
+	}
+
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("wechatpay: invalid response: %w", err)
+	}
+	return nil
+}
+
+// send signs and performs a request, returning the response headers and body of a 2xx response
+func (c *Client) send(method, path string, body []byte) (http.Header, []byte, error) {
+	authorization, err := c.authorization(method, path, body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest(method, c.cfg.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("wechatpay: failed to build request: %w", err)
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("wechatpay: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, nil, fmt.Errorf("wechatpay: failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		_ = json.Unmarshal(respBody, apiErr)
+		return nil, nil, apiErr
+	}
+	return resp.Header, respBody, nil
+}
+
+// authorization builds the Authorization header for a request
+func (c *Client) authorization(method, path string, body []byte) (string, error) {
+	nonce := nonceString()
+	timestamp := strconv.FormatInt(c.now().Unix(), 10)
+	signature, err := signSHA256WithRSA(c.cfg.PrivateKey, buildMessage(method, path, timestamp, nonce, string(body)))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`%s mchid="%s",nonce_str="%s",signature="%s",timestamp="%s",serial_no="%s"`,
+		authSchema, c.cfg.MchID, nonce, signature, timestamp, c.cfg.SerialNo), nil
+}
+
+// verify checks a signed response or notification against the platform certificates
+func (c *Client) verify(header http.Header, body []byte) error {
+	timestamp, err := strconv.ParseInt(header.Get(headerTimestamp), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if skew := c.now().Sub(time.Unix(timestamp, 0)); skew > maxClockSkew || skew < -maxClockSkew {
+		return fmt.Errorf("%w: timestamp out of range", ErrInvalidSignature)
+	}
+
+	key, err := c.platformKey(header.Get(headerSerial))
+	if err != nil {
+		return err
+	}
+	return verifyResponse(key, header, body)
+}
+
+// verifyResponse checks the signature headers of body against a platform public key
+func verifyResponse(key *rsa.PublicKey, header http.Header, body []byte) error {
+	message := buildMessage(header.Get(headerTimestamp), header.Get(headerNonce), string(body))
+	return verifySHA256WithRSA(key, message, header.Get(headerSignature))
+}
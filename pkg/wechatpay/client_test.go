@@ -0,0 +1,416 @@
+package wechatpay
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const (
+	testAPIv3Key       = "0123456789abcdef0123456789abcdef"
+	testPlatformSerial = "PLATFORM0001"
+	testMerchantSerial = "MERCHANT0001"
+)
+
+// standIn is a WeChat Pay stand-in that checks request signatures and signs its responses
+type standIn struct {
+	t           *testing.T
+	merchantKey *rsa.PrivateKey
+	platformKey *rsa.PrivateKey
+	certPEM     []byte
+	server      *httptest.Server
+	handle      func(r *http.Request, body []byte) (int, interface{})
+}
+
+func newStandIn(t *testing.T, handle func(r *http.Request, body []byte) (int, interface{})) (*standIn, *Client) {
+	t.Helper()
+
+	s := &standIn{t: t, handle: handle}
+	s.merchantKey = generateKey(t)
+	s.platformKey = generateKey(t)
+	s.certPEM = selfSignedCert(t, s.platformKey)
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.server.Close)
+
+	client, err := NewClient(Config{
+		BaseURL:    s.server.URL,
+		AppID:      "wx123",
+		MchID:      "1900000001",
+		SerialNo:   testMerchantSerial,
+		PrivateKey: s.merchantKey,
+		APIv3Key:   testAPIv3Key,
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	return s, client
+}
+
+var authPattern = regexp.MustCompile(`mchid="([^"]*)",nonce_str="([^"]*)",signature="([^"]*)",timestamp="([^"]*)"`)
+
+func (s *standIn) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	match := authPattern.FindStringSubmatch(r.Header.Get("Authorization"))
+	if match == nil || match[1] != "1900000001" {
+		s.t.Errorf("unexpected authorization %q", r.Header.Get("Authorization"))
+	} else {
+		message := buildMessage(r.Method, r.URL.RequestURI(), match[4], match[2], string(body))
+		if err := verifySHA256WithRSA(&s.merchantKey.PublicKey, message, match[3]); err != nil {
+			s.t.Errorf("request signature did not verify: %v", err)
+		}
+	}
+
+	status, resp := http.StatusOK, interface{}(nil)
+	if r.URL.Path == pathCertificates {
+		resp = map[string]interface{}{"data": []map[string]interface{}{{
+			"serial_no":           testPlatformSerial,
+			"encrypt_certificate": encryptResource(s.t, s.certPEM),
+		}}}
+	} else {
+		status, resp = s.handle(r, body)
+	}
+
+	var respBody []byte
+	if resp != nil {
+		respBody, _ = json.Marshal(resp)
+	}
+	s.sign(w.Header(), respBody)
+	w.WriteHeader(status)
+	_, _ = w.Write(respBody)
+}
+
+// sign sets the platform signature headers for body
+func (s *standIn) sign(header http.Header, body []byte) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := nonceString()
+	signature, err := signSHA256WithRSA(s.platformKey, buildMessage(timestamp, nonce, string(body)))
+	if err != nil {
+		s.t.Fatalf("failed to sign response: %v", err)
+	}
+	header.Set(headerTimestamp, timestamp)
+	header.Set(headerNonce, nonce)
+	header.Set(headerSignature, signature)
+	header.Set(headerSerial, testPlatformSerial)
+}
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key
+}
+
+func selfSignedCert(t *testing.T, key *rsa.PrivateKey) []byte {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Tenpay.com Root CA"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func encryptResource(t *testing.T, plaintext []byte) EncryptedResource {
+	t.Helper()
+	block, _ := aes.NewCipher([]byte(testAPIv3Key))
+	aead, _ := cipher.NewGCM(block)
+	nonce := "abcdef123456"
+	ciphertext := aead.Seal(nil, []byte(nonce), plaintext, []byte("certificate"))
+	return EncryptedResource{
+		Algorithm:      algorithmAESGCM,
+		Ciphertext:     base64.StdEncoding.EncodeToString(ciphertext),
+		AssociatedData: "certificate",
+		Nonce:          nonce,
+	}
+}
+
+func TestDecryptResource(t *testing.T) {
+	resource := encryptResource(t, []byte(`{"trade_state":"SUCCESS"}`))
+
+	plaintext, err := DecryptResource(testAPIv3Key, resource)
+	if err != nil {
+		t.Fatalf("DecryptResource failed: %v", err)
+	}
+	if string(plaintext) != `{"trade_state":"SUCCESS"}` {
+		t.Errorf("unexpected plaintext %s", plaintext)
+	}
+
+	resource.AssociatedData = "tampered"
+	if _, err := DecryptResource(testAPIv3Key, resource); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("expected ErrDecryptFailed, got %v", err)
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	key := generateKey(t)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	parsed, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("ParsePrivateKey failed: %v", err)
+	}
+	if !parsed.Equal(key) {
+		t.Error("parsed key does not match")
+	}
+
+	if _, err := ParsePrivateKey([]byte("not pem")); err == nil {
+		t.Error("expected error for invalid pem")
+	}
+}
+
+func TestClient_PrepayMiniProgram(t *testing.T) {
+	s, client := newStandIn(t, func(r *http.Request, body []byte) (int, interface{}) {
+		if r.URL.Path != pathJSAPIPrepay {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var req map[string]interface{}
+		_ = json.Unmarshal(body, &req)
+		if req["out_trade_no"] != "1066ORD001" || req["appid"] != "wx123" {
+			t.Errorf("unexpected request %v", req)
+		}
+		return http.StatusOK, map[string]string{"prepay_id": "wx_prepay_001"}
+	})
+
+	params, err := client.PrepayMiniProgram(PrepayRequest{
+		OutTradeNo: "1066ORD001", Description: "拿铁咖啡", Amount: 1580, OpenID: "openid",
+	})
+	if err != nil {
+		t.Fatalf("PrepayMiniProgram failed: %v", err)
+	}
+	if params.Package != "prepay_id=wx_prepay_001" || params.AppID != "wx123" || params.SignType != "RSA" {
+		t.Errorf("unexpected params %+v", params)
+	}
+
+	message := buildMessage(params.AppID, params.TimeStamp, params.NonceStr, params.Package)
+	if err := verifySHA256WithRSA(&s.merchantKey.PublicKey, message, params.PaySign); err != nil {
+		t.Errorf("pay sign did not verify: %v", err)
+	}
+}
+
+func TestClient_QueryByOutTradeNo(t *testing.T) {
+	_, client := newStandIn(t, func(r *http.Request, body []byte) (int, interface{}) {
+		if r.URL.Path != pathOutTradeNo+"1066ORD001" || r.URL.Query().Get("mchid") != "1900000001" {
+			t.Errorf("unexpected request %s", r.URL.RequestURI())
+		}
+		return http.StatusOK, map[string]interface{}{
+			"out_trade_no":   "1066ORD001",
+			"transaction_id": "4200000001",
+			"trade_state":    TradeStateSuccess,
+			"success_time":   "2025-08-12T15:30:00+08:00",
+			"amount":         map[string]interface{}{"total": 1580},
+		}
+	})
+
+	tx, err := client.QueryByOutTradeNo("1066ORD001")
+	if err != nil {
+		t.Fatalf("QueryByOutTradeNo failed: %v", err)
+	}
+	if tx.TradeState != TradeStateSuccess || tx.TransactionID != "4200000001" || tx.Amount.Total != 1580 {
+		t.Errorf("unexpected transaction %+v", tx)
+	}
+	if paidAt, ok := tx.PaidAt(); !ok || paidAt.Year() != 2025 {
+		t.Errorf("unexpected success time %s", tx.SuccessTime)
+	}
+}
+
+func TestClient_CloseAndRefund(t *testing.T) {
+	_, client := newStandIn(t, func(r *http.Request, body []byte) (int, interface{}) {
+		switch r.URL.Path {
+		case pathOutTradeNo + "1066ORD001/close":
+			return http.StatusNoContent, nil
+		case pathRefunds:
+			return http.StatusOK, map[string]string{"refund_id": "5030000001", "status": RefundStatusProcessing}
+		}
+		t.Errorf("unexpected path %s", r.URL.Path)
+		return http.StatusNotFound, nil
+	})
+
+	if err := client.Close("1066ORD001"); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	refund, err := client.Refund(RefundRequest{OutTradeNo: "1066ORD001", OutRefundNo: "1066RFORD001", Total: 1580, Refund: 500})
+	if err != nil {
+		t.Fatalf("Refund failed: %v", err)
+	}
+	if refund.RefundID != "5030000001" || refund.Status != RefundStatusProcessing {
+		t.Errorf("unexpected refund %+v", refund)
+	}
+
+	if _, err := client.Refund(RefundRequest{OutTradeNo: "a", OutRefundNo: "b", Total: 100, Refund: 200}); err == nil {
+		t.Error("expected error when refund exceeds total")
+	}
+}
+
+func TestClient_APIError(t *testing.T) {
+	_, client := newStandIn(t, func(r *http.Request, body []byte) (int, interface{}) {
+		return http.StatusNotFound, map[string]string{"code": "ORDER_NOT_EXIST", "message": "订单不存在"}
+	})
+
+	_, err := client.QueryByOutTradeNo("1066ORD404")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Code != "ORDER_NOT_EXIST" {
+		t.Errorf("unexpected api error %+v", apiErr)
+	}
+}
+
+func TestClient_ForgedResponse(t *testing.T) {
+	s, client := newStandIn(t, func(r *http.Request, body []byte) (int, interface{}) {
+		return http.StatusOK, map[string]string{"trade_state": TradeStateSuccess}
+	})
+	// 用非平台证书私钥签名的应答不应被接受
+	s.platformKey = generateKey(t)
+	s.certPEM = selfSignedCert(t, generateKey(t))
+
+	if _, err := client.QueryByOutTradeNo("1066ORD001"); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func TestClient_ParseTransactionNotification(t *testing.T) {
+	s, client := newStandIn(t, func(r *http.Request, body []byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+
+	tx, _ := json.Marshal(map[string]interface{}{
+		"mchid":          "1900000001",
+		"out_trade_no":   "1066ORD001",
+		"transaction_id": "4200000001",
+		"trade_state":    TradeStateSuccess,
+		"attach":         "ORD001",
+		"amount":         map[string]interface{}{"total": 1580, "payer_total": 1580},
+	})
+	body, _ := json.Marshal(map[string]interface{}{
+		"id":            "EV-001",
+		"event_type":    EventTransactionSuccess,
+		"resource_type": "encrypt-resource",
+		"resource":      encryptResource(t, tx),
+	})
+	header := http.Header{}
+	s.sign(header, body)
+
+	parsed, err := client.ParseTransactionNotification(header, body)
+	if err != nil {
+		t.Fatalf("ParseTransactionNotification failed: %v", err)
+	}
+	if parsed.Attach != "ORD001" || parsed.Amount.Total != 1580 || parsed.TransactionID != "4200000001" {
+		t.Errorf("unexpected transaction %+v", parsed)
+	}
+
+	// 篡改通知内容后验签失败
+	tampered := append([]byte{}, body...)
+	tampered[len(tampered)-2] = ' '
+	if _, err := client.ParseTransactionNotification(header, tampered); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func TestClient_ParseRefundNotification(t *testing.T) {
+	s, client := newStandIn(t, func(r *http.Request, body []byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+
+	notify := func(eventType string, resource map[string]interface{}) (http.Header, []byte) {
+		plaintext, _ := json.Marshal(resource)
+		body, _ := json.Marshal(map[string]interface{}{
+			"id":            "EV-002",
+			"event_type":    eventType,
+			"resource_type": "encrypt-resource",
+			"resource":      encryptResource(t, plaintext),
+		})
+		header := http.Header{}
+		s.sign(header, body)
+		return header, body
+	}
+
+	header, body := notify(EventRefundSuccess, map[string]interface{}{
+		"mchid":         "1900000001",
+		"out_trade_no":  "1066ORD001",
+		"out_refund_no": "RFORD00101",
+		"refund_id":     "5030000001",
+		"refund_status": RefundStatusSuccess,
+		"success_time":  "2024-01-01T10:00:00+08:00",
+		"amount":        map[string]interface{}{"total": 1580, "refund": 500},
+	})
+	refund, err := client.ParseRefundNotification(header, body)
+	if err != nil {
+		t.Fatalf("ParseRefundNotification failed: %v", err)
+	}
+	if refund.OutRefundNo != "RFORD00101" || refund.Amount.Refund != 500 || refund.RefundStatus != RefundStatusSuccess {
+		t.Errorf("unexpected refund %+v", refund)
+	}
+	if _, ok := refund.RefundedAt(); !ok {
+		t.Error("expected refund success time")
+	}
+
+	// 支付成功通知不能作为退款结果
+	header, body = notify(EventTransactionSuccess, map[string]interface{}{"mchid": "1900000001"})
+	if _, err := client.ParseRefundNotification(header, body); err == nil {
+		t.Error("expected error for non-refund event")
+	}
+
+	// 其他商户的退款通知被拒绝
+	header, body = notify(EventRefundClosed, map[string]interface{}{"mchid": "1900000002"})
+	if _, err := client.ParseRefundNotification(header, body); err == nil {
+		t.Error("expected error for another merchant")
+	}
+}
+
+func TestNewClient_Validation(t *testing.T) {
+	if _, err := NewClient(Config{MchID: "1900000001"}); err == nil {
+		t.Error("expected error without credentials")
+	}
+	if _, err := NewClient(Config{
+		MchID: "1900000001", SerialNo: testMerchantSerial, PrivateKey: generateKey(t), APIv3Key: "short",
+	}); err == nil {
+		t.Error("expected error for short APIv3 key")
+	}
+}
+
+func TestClient_VerifyAccount(t *testing.T) {
+	s, client := newStandIn(t, nil)
+	if err := client.VerifyAccount(); err != nil {
+		t.Errorf("expected credentials to verify, got %v", err)
+	}
+
+	// APIv3密钥错误时无法解密平台证书
+	wrongKey, err := NewClient(Config{
+		BaseURL:    s.server.URL,
+		MchID:      "1900000001",
+		SerialNo:   testMerchantSerial,
+		PrivateKey: s.merchantKey,
+		APIv3Key:   "fedcba9876543210fedcba9876543210",
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if err := wrongKey.VerifyAccount(); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("expected ErrDecryptFailed, got %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+package wechatpay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// API paths
+const (
+	pathJSAPIPrepay = "/v3/pay/transactions/jsapi"
+	pathOutTradeNo  = "/v3/pay/transactions/out-trade-no/"
+	pathRefunds     = "/v3/refund/domestic/refunds"
+)
+
+// 交易状态（trade_state）
+const (
+	TradeStateSuccess    = "SUCCESS"    // 支付成功
+	TradeStateRefund     = "REFUND"     // 转入退款
+	TradeStateNotPay     = "NOTPAY"     // 未支付
+	TradeStateClosed     = "CLOSED"     // 已关闭
+	TradeStateRevoked    = "REVOKED"    // 已撤销
+	TradeStateUserPaying = "USERPAYING" // 用户支付中
+	TradeStatePayError   = "PAYERROR"   // 支付失败
+)
+
+// 退款状态
+const (
+	RefundStatusSuccess    = "SUCCESS"    // 退款成功
+	RefundStatusClosed     = "CLOSED"     // 退款关闭
+	RefundStatusProcessing = "PROCESSING" // 退款处理中
+	RefundStatusAbnormal   = "ABNORMAL"   // 退款异常
+)
+
+// EventTransactionSuccess is the event type of a successful payment notification
+const EventTransactionSuccess = "TRANSACTION.SUCCESS"
+
+// 退款结果通知事件类型
+const (
+	EventRefundSuccess  = "REFUND.SUCCESS"  // 退款成功
+	EventRefundAbnormal = "REFUND.ABNORMAL" // 退款异常
+	EventRefundClosed   = "REFUND.CLOSED"   // 退款关闭
+)
+
+// Amount is an order amount in fen
+type Amount struct {
+	Total      int64  `json:"total"`
+	PayerTotal int64  `json:"payer_total,omitempty"`
+	Currency   string `json:"currency,omitempty"`
+}
+
+// Payer identifies the paying user
+type Payer struct {
+	OpenID string `json:"openid"`
+}
+
+// PrepayRequest describes a JSAPI / mini program order
+type PrepayRequest struct {
+	OutTradeNo  string // 商户订单号
+	Description string // 商品描述
+	Amount      int64  // 金额(分)
+	OpenID      string // 用户在小程序下的OpenId
+	NotifyURL   string // 支付结果通知地址
+	Attach      string // 附加数据，通知及查询时原样返回
+}
+
+// MiniProgramPayParams carries the parameters for wx.requestPayment
+type MiniProgramPayParams struct {
+	AppID     string
+	TimeStamp string
+	NonceStr  string
+	Package   string
+	SignType  string
+	PaySign   string
+}
+
+// Transaction describes a payment order
+type Transaction struct {
+	AppID          string `json:"appid"`
+	MchID          string `json:"mchid"`
+	OutTradeNo     string `json:"out_trade_no"`
+	TransactionID  string `json:"transaction_id"`
+	TradeType      string `json:"trade_type"`
+	TradeState     string `json:"trade_state"`
+	TradeStateDesc string `json:"trade_state_desc"`
+	SuccessTime    string `json:"success_time"`
+	Attach         string `json:"attach"`
+	Amount         Amount `json:"amount"`
+	Payer          Payer  `json:"payer"`
+}
+
+// PaidAt parses the success time of a paid transaction
+func (t *Transaction) PaidAt() (time.Time, bool) {
+	paidAt, err := time.Parse(time.RFC3339, t.SuccessTime)
+	return paidAt, err == nil
+}
+
+// RefundRequest describes a (partial) refund
+type RefundRequest struct {
+	OutTradeNo  string // 原商户订单号
+	OutRefundNo string // 商户退款单号，重复提交同一退款单号不会重复退款
+	Reason      string // 退款原因
+	NotifyURL   string // 退款结果通知地址
+	Total       int64  // 原订单金额(分)
+	Refund      int64  // 退款金额(分)
+}
+
+// Refund describes an accepted refund
+type Refund struct {
+	RefundID    string `json:"refund_id"`
+	OutRefundNo string `json:"out_refund_no"`
+	OutTradeNo  string `json:"out_trade_no"`
+	Status      string `json:"status"`
+}
+
+// RefundAmount is the amount section of a refund notification in fen
+type RefundAmount struct {
+	Total       int64 `json:"total"`
+	Refund      int64 `json:"refund"`
+	PayerTotal  int64 `json:"payer_total"`
+	PayerRefund int64 `json:"payer_refund"`
+}
+
+// RefundResult is the decrypted resource of a refund notification
+type RefundResult struct {
+	MchID         string       `json:"mchid"`
+	OutTradeNo    string       `json:"out_trade_no"`
+	TransactionID string       `json:"transaction_id"`
+	OutRefundNo   string       `json:"out_refund_no"`
+	RefundID      string       `json:"refund_id"`
+	RefundStatus  string       `json:"refund_status"`
+	SuccessTime   string       `json:"success_time"`
+	Amount        RefundAmount `json:"amount"`
+}
+
+// RefundedAt returns the refund success time when present
+func (r *RefundResult) RefundedAt() (time.Time, bool) {
+	refundedAt, err := time.Parse(time.RFC3339, r.SuccessTime)
+	return refundedAt, err == nil
+}
+
+// PrepayMiniProgram places a JSAPI order and returns the signed mini program payment parameters
+func (c *Client) PrepayMiniProgram(req PrepayRequest) (*MiniProgramPayParams, error) {
+	if req.OutTradeNo == "" || req.Amount <= 0 || req.OpenID == "" {
+		return nil, errors.New("wechatpay: out trade no, positive amount and openid are required")
+	}
+
+	payload := map[string]interface{}{
+		"appid":        c.cfg.AppID,
+		"mchid":        c.cfg.MchID,
+		"description":  req.Description,
+		"out_trade_no": req.OutTradeNo,
+		"notify_url":   req.NotifyURL,
+		"amount":       Amount{Total: req.Amount, Currency: "CNY"},
+		"payer":        Payer{OpenID: req.OpenID},
+	}
+	if req.Attach != "" {
+		payload["attach"] = req.Attach
+	}
+
+	var resp struct {
+		PrepayID string `json:"prepay_id"`
+	}
+	if err := c.do(http.MethodPost, pathJSAPIPrepay, payload, &resp); err != nil {
+		return nil, err
+	}
+	if resp.PrepayID == "" {
+		return nil, errors.New("wechatpay: empty prepay id")
+	}
+
+	params := &MiniProgramPayParams{
+		AppID:     c.cfg.AppID,
+		TimeStamp: strconv.FormatInt(c.now().Unix(), 10),
+		NonceStr:  nonceString(),
+		Package:   "prepay_id=" + resp.PrepayID,
+		SignType:  "RSA",
+	}
+	paySign, err := signSHA256WithRSA(c.cfg.PrivateKey,
+		buildMessage(params.AppID, params.TimeStamp, params.NonceStr, params.Package))
+	if err != nil {
+		return nil, err
+	}
+	params.PaySign = paySign
+	return params, nil
+}
+
+// QueryByOutTradeNo looks up an order by merchant order number
+func (c *Client) QueryByOutTradeNo(outTradeNo string) (*Transaction, error) {
+	if outTradeNo == "" {
+		return nil, errors.New("wechatpay: out trade no is required")
+	}
+
+	path := pathOutTradeNo + url.PathEscape(outTradeNo) + "?mchid=" + url.QueryEscape(c.cfg.MchID)
+	var tx Transaction
+	if err := c.do(http.MethodGet, path, nil, &tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// Close closes an unpaid order so it can no longer be paid
+func (c *Client) Close(outTradeNo string) error {
+	if outTradeNo == "" {
+		return errors.New("wechatpay: out trade no is required")
+	}
+
+	path := pathOutTradeNo + url.PathEscape(outTradeNo) + "/close"
+	return c.do(http.MethodPost, path, map[string]string{"mchid": c.cfg.MchID}, nil)
+}
+
+// Refund refunds all or part of a paid order
+func (c *Client) Refund(req RefundRequest) (*Refund, error) {
+	if req.OutTradeNo == "" || req.OutRefundNo == "" {
+		return nil, errors.New("wechatpay: out trade no and out refund no are required")
+	}
+	if req.Refund <= 0 || req.Refund > req.Total {
+		return nil, errors.New("wechatpay: invalid refund amount")
+	}
+
+	payload := map[string]interface{}{
+		"out_trade_no":  req.OutTradeNo,
+		"out_refund_no": req.OutRefundNo,
+		"amount": map[string]interface{}{
+			"refund":   req.Refund,
+			"total":    req.Total,
+			"currency": "CNY",
+		},
+	}
+	if req.Reason != "" {
+		payload["reason"] = req.Reason
+	}
+	if req.NotifyURL != "" {
+		payload["notify_url"] = req.NotifyURL
+	}
+
+	var refund Refund
+	if err := c.do(http.MethodPost, pathRefunds, payload, &refund); err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// Notification is a verified and decrypted notification
+type Notification struct {
+	ID           string            `json:"id"`
+	CreateTime   string            `json:"create_time"`
+	EventType    string            `json:"event_type"`
+	ResourceType string            `json:"resource_type"`
+	Summary      string            `json:"summary"`
+	Resource     EncryptedResource `json:"resource"`
+	Plaintext    []byte            `json:"-"`
+}
+
+// ParseNotification verifies the signature of a notification and decrypts its resource
+func (c *Client) ParseNotification(header http.Header, body []byte) (*Notification, error) {
+	if err := c.verify(header, body); err != nil {
+		return nil, err
+	}
+
+	var notification Notification
+	if err := json.Unmarshal(body, &notification); err != nil {
+		return nil, fmt.Errorf("wechatpay: invalid notification: %w", err)
+	}
+	plaintext, err := DecryptResource(c.cfg.APIv3Key, notification.Resource)
+	if err != nil {
+		return nil, err
+	}
+	notification.Plaintext = plaintext
+	return &notification, nil
+}
+
+// ParseTransactionNotification parses a payment notification into the paid transaction
+func (c *Client) ParseTransactionNotification(header http.Header, body []byte) (*Transaction, error) {
+	notification, err := c.ParseNotification(header, body)
+	if err != nil {
+		return nil, err
+	}
+	if notification.EventType != EventTransactionSuccess {
+		return nil, fmt.Errorf("wechatpay: unexpected event type %s", notification.EventType)
+	}
+
+	var tx Transaction
+	if err := json.Unmarshal(notification.Plaintext, &tx); err != nil {
+		return nil, fmt.Errorf("wechatpay: invalid transaction resource: %w", err)
+	}
+	if tx.MchID != "" && tx.MchID != c.cfg.MchID {
+		return nil, fmt.Errorf("wechatpay: notification for merchant %s", tx.MchID)
+	}
+	return &tx, nil
+}
+
+// ParseRefundNotification parses a refund notification into the refund result
+func (c *Client) ParseRefundNotification(header http.Header, body []byte) (*RefundResult, error) {
+	notification, err := c.ParseNotification(header, body)
+	if err != nil {
+		return nil, err
+	}
+	switch notification.EventType {
+	case EventRefundSuccess, EventRefundAbnormal, EventRefundClosed:
+	default:
+		return nil, fmt.Errorf("wechatpay: unexpected event type %s", notification.EventType)
+	}
+
+	var refund RefundResult
+	if err := json.Unmarshal(notification.Plaintext, &refund); err != nil {
+		return nil, fmt.Errorf("wechatpay: invalid refund resource: %w", err)
+	}
+	if refund.MchID != "" && refund.MchID != c.cfg.MchID {
+		return nil, fmt.Errorf("wechatpay: notification for merchant %s", refund.MchID)
+	}
+	return &refund, nil
+}
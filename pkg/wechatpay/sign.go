@@ -0,0 +1,79 @@
+package wechatpay
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// authSchema is the Authorization scheme for API v3 requests
+const authSchema = "WECHATPAY2-SHA256-RSA2048"
+
+// buildMessage joins the parts of a signature message, each terminated by "\n"
+func buildMessage(parts ...string) string {
+	var b strings.Builder
+	for _, part := range parts {
+		b.WriteString(part)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// signSHA256WithRSA signs message with the merchant private key and returns the base64 signature
+func signSHA256WithRSA(key *rsa.PrivateKey, message string) (string, error) {
+	digest := sha256.Sum256([]byte(message))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("wechatpay: failed to sign message: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// verifySHA256WithRSA checks a base64 signature against message with a platform public key
+func verifySHA256WithRSA(key *rsa.PublicKey, message, signature string) error {
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	digest := sha256.Sum256([]byte(message))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], raw); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// ParsePrivateKey parses a PEM encoded merchant private key (PKCS#8 or PKCS#1)
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("wechatpay: invalid private key pem")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("wechatpay: private key is not RSA")
+		}
+		return rsaKey, nil
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("wechatpay: failed to parse private key: %w", err)
+	}
+	return key, nil
+}
+
+// nonceString returns a 32 character random nonce
+func nonceString() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return strings.ToUpper(hex.EncodeToString(buf))
+}